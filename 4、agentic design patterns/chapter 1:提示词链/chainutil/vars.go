@@ -0,0 +1,116 @@
+// Package chainutil 提供构建提示词链时的辅助校验，
+// 在 Compile 阶段就发现模板占位符与上游 Lambda 输出键不一致的问题，
+// 而不是等到运行时在模板格式化深处报错。
+package chainutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FStringVars: 解析 FString（Python str.format 风格）模板中的占位符，返回去重并排序后的变量名
+//   - "{{" 与 "}}" 视为转义的花括号，不算占位符
+//   - "{name:fmt}"、"{name!r}"、"{name.attr}"、"{name[0]}" 只取根变量名 name
+func FStringVars(tpl string) ([]string, error) {
+	seen := map[string]bool{}
+	for i := 0; i < len(tpl); i++ {
+		switch tpl[i] {
+		case '{':
+			if i+1 < len(tpl) && tpl[i+1] == '{' {
+				i++
+				continue
+			}
+			end := strings.IndexByte(tpl[i+1:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("模板第 %d 个字节处的 '{' 没有闭合", i)
+			}
+			field := tpl[i+1 : i+1+end]
+			if j := strings.IndexAny(field, ":!.["); j >= 0 {
+				field = field[:j]
+			}
+			field = strings.TrimSpace(field)
+			if field == "" {
+				return nil, fmt.Errorf("模板第 %d 个字节处存在空占位符 '{}'", i)
+			}
+			seen[field] = true
+			i += end + 1
+		case '}':
+			if i+1 < len(tpl) && tpl[i+1] == '}' {
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("模板第 %d 个字节处存在未配对的 '}'", i)
+		}
+	}
+
+	vars := make([]string, 0, len(seen))
+	for v := range seen {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	return vars, nil
+}
+
+// Keys: map 的所有键，排序后返回
+func Keys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CheckInput: 用上游构造模板输入的函数校验模板：以零值调用一次 input，取它实际输出的键与模板占位符比较，
+// 这样校验的是上游真正产生的 map，而不是另外手写的一份键列表
+func CheckInput[T any](tpls []string, input func(T) map[string]any) error {
+	var zero T
+	return CheckVars(tpls, Keys(input(zero)))
+}
+
+// CheckVars: 校验模板所需变量与上游提供的键是否完全一致
+// 缺失的键会导致运行时格式化失败，多余的键通常意味着拼写错误或键名冲突，两者都视为错误
+func CheckVars(tpls []string, provided []string) error {
+	required := map[string]bool{}
+	for _, tpl := range tpls {
+		vars, err := FStringVars(tpl)
+		if err != nil {
+			return err
+		}
+		for _, v := range vars {
+			required[v] = true
+		}
+	}
+
+	have := map[string]bool{}
+	for _, k := range provided {
+		have[k] = true
+	}
+
+	var missing, extra []string
+	for v := range required {
+		if !have[v] {
+			missing = append(missing, v)
+		}
+	}
+	for k := range have {
+		if !required[k] {
+			extra = append(extra, k)
+		}
+	}
+	if len(missing) == 0 && len(extra) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	sort.Strings(extra)
+	var parts []string
+	if len(missing) > 0 {
+		parts = append(parts, fmt.Sprintf("缺少键 %v", missing))
+	}
+	if len(extra) > 0 {
+		parts = append(parts, fmt.Sprintf("多余键 %v", extra))
+	}
+	return fmt.Errorf("模板变量与输入键不匹配: %s", strings.Join(parts, "，"))
+}
@@ -0,0 +1,86 @@
+package chainutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFStringVars(t *testing.T) {
+	tests := []struct {
+		name string
+		tpl  string
+		want []string
+	}{
+		{"无占位符", "纯文本", []string{}},
+		{"单个", "文本：{text_input}", []string{"text_input"}},
+		{"去重排序", "{b} {a} {b}", []string{"a", "b"}},
+		{"转义花括号", `{{"cpu": 1}} {specs}`, []string{"specs"}},
+		{"格式与属性只取根变量名", "{x:>10} {y!r} {z.attr} {w[0]}", []string{"w", "x", "y", "z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FStringVars(tt.tpl)
+			if err != nil {
+				t.Fatalf("FStringVars(%q) 返回错误: %v", tt.tpl, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FStringVars(%q) = %v, want %v", tt.tpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFStringVarsErrors(t *testing.T) {
+	for _, tpl := range []string{"{unclosed", "stray }", "empty {}"} {
+		if _, err := FStringVars(tpl); err == nil {
+			t.Errorf("FStringVars(%q) 应返回错误", tpl)
+		}
+	}
+}
+
+func TestCheckVars(t *testing.T) {
+	tests := []struct {
+		name     string
+		tpls     []string
+		provided []string
+		wantErr  []string // wantErr: 错误信息中应包含的片段，为空表示不应出错
+	}{
+		{"完全一致", []string{"{a} {b}"}, []string{"b", "a"}, nil},
+		{"多个模板合并", []string{"{a}", "{b}"}, []string{"a", "b"}, nil},
+		{"缺少键", []string{"{a} {b}"}, []string{"a"}, []string{"缺少键 [b]"}},
+		{"多余键", []string{"{a}"}, []string{"a", "typo"}, []string{"多余键 [typo]"}},
+		{"同时缺少和多余", []string{"{a}"}, []string{"b"}, []string{"缺少键 [a]", "多余键 [b]"}},
+		{"模板语法错误", []string{"{a"}, []string{"a"}, []string{"没有闭合"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckVars(tt.tpls, tt.provided)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("CheckVars 返回错误: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("CheckVars 应返回错误")
+			}
+			for _, part := range tt.wantErr {
+				if !strings.Contains(err.Error(), part) {
+					t.Errorf("错误 %q 中没有 %q", err, part)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckInput(t *testing.T) {
+	input := func(s string) map[string]any { return map[string]any{"specifications": s} }
+	if err := CheckInput([]string{"规格：{specifications}"}, input); err != nil {
+		t.Fatalf("CheckInput 返回错误: %v", err)
+	}
+	err := CheckInput([]string{"规格：{specs}"}, input)
+	if err == nil || !strings.Contains(err.Error(), "缺少键 [specs]") || !strings.Contains(err.Error(), "多余键 [specifications]") {
+		t.Fatalf("CheckInput 应报告键名不一致，得到 %v", err)
+	}
+}
@@ -23,21 +23,52 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 
+	"ch1/chainutil"
+
 	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
 
+// 模板文本与各模板所需的输入键，构建链时用 chainutil.CheckInput 校验上游实际输出的键与模板是否一致
+const (
+	extractTemplate   = "从以下文本中提取技术规格：\n\n{text_input}"
+	transformTemplate = "将以下规格转换为 JSON 对象，使用 'cpu'、'memory' 和 'storage' 作为键：\n\n{specifications}"
+
+	textInputKey      = "text_input"     // textInputKey: 提取链的输入键
+	specificationsKey = "specifications" // specificationsKey: wrapSpecifications 输出给转换链模板的键
+)
+
+// extractionInput: 提取链的输入 map，runChains 用它构造输入，buildExtractionChain 用它校验模板
+func extractionInput(text string) map[string]any {
+	return map[string]any{
+		textInputKey: text, // 键名必须与模板中的 {text_input} 占位符一致
+	}
+}
+
+// specificationsInput: wrapSpecifications 输出给转换链模板的 map，buildTransformChain 用它校验模板
+func specificationsInput(specifications string) map[string]any {
+	return map[string]any{
+		specificationsKey: specifications,
+	}
+}
+
 // float32Ptr: 辅助函数，将 float32 值转换为 *float32 指针
 func float32Ptr(f float32) *float32 {
 	return &f
 }
 
 func main() {
+	// repl: --repl 进入交互模式，循环读取标准输入的每一行描述并执行完整链路
+	repl := flag.Bool("repl", false, "交互模式：逐行读取描述并执行提示词链")
+	flag.Parse()
+
 	// ctx: 创建根上下文(非nil的空Context)，用于控制整个程序的执行流程
 	ctx := context.Background()
 
@@ -70,87 +101,119 @@ func main() {
 		os.Exit(1)
 	}
 
+	// usage: 累计两条链中所有模型调用的 Token 用量（REPL 的 :stats 命令使用）
+	usage := &tokenUsage{}
+
+	extractionChain, err := buildExtractionChain(ctx, llm, usage)
+	if err != nil {
+		fmt.Printf("编译提取链失败: %v\n", err)
+		os.Exit(1)
+	}
+	transformChain, err := buildTransformChain(ctx, llm, usage)
+	if err != nil {
+		fmt.Printf("编译转换链失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// ========== 交互模式 ==========
+	// 复用上面已编译好的两条链，避免每次输入都重新初始化模型和编译
+	if *repl {
+		runREPL(ctx, os.Stdin, os.Stdout, extractionChain, transformChain, usage)
+		return
+	}
+
+	// ========== 执行链 ==========
+	inputText := "新款笔记本电脑型号配备 3.5 GHz 八核处理器、16GB 内存和 1TB NVMe 固态硬盘。"
+
+	finalResult, err := runChains(ctx, extractionChain, transformChain, inputText)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\n--- 最终 JSON 输出 ---")
+	fmt.Println(finalResult)
+}
+
+// runChains: 依次执行提取链和转换链，返回最终 JSON 字符串
+func runChains(ctx context.Context, extractionChain compose.Runnable[map[string]any, string],
+	transformChain compose.Runnable[string, string], inputText string) (string, error) {
+	// 执行提取链
+	extractedSpecs, err := extractionChain.Invoke(ctx, extractionInput(inputText))
+	if err != nil {
+		return "", fmt.Errorf("提取链执行失败: %w", err)
+	}
+
+	// 执行转换链
+	finalResult, err := transformChain.Invoke(ctx, extractedSpecs)
+	if err != nil {
+		return "", fmt.Errorf("转换链执行失败: %w", err)
+	}
+	return finalResult, nil
+}
+
+// buildExtractionChain: 构建提取链，编译前用 extractionInput 实际输出的键校验模板占位符
+func buildExtractionChain(ctx context.Context, llm model.BaseChatModel, usage *tokenUsage) (compose.Runnable[map[string]any, string], error) {
+	if err := chainutil.CheckInput([]string{extractTemplate}, extractionInput); err != nil {
+		return nil, err
+	}
+
 	// --- 提示词 1：提取信息 ---
 	promptExtract := prompt.FromMessages(
 		schema.FString,
-		schema.UserMessage("从以下文本中提取技术规格：\n\n{text_input}"),
-	)
-
-	// --- 提示词 2：转换为 JSON ---
-	promptTransform := prompt.FromMessages(
-		schema.FString,
-		schema.UserMessage("将以下规格转换为 JSON 对象，使用 'cpu'、'memory' 和 'storage' 作为键：\n\n{specifications}"),
+		schema.UserMessage(extractTemplate),
 	)
 
 	// ========== Lambda 函数1: Message -> string ==========
 	// 作用: 从 Message 对象中提取 Content 字段，转换为字符串
 	// 对应 Python: StrOutputParser()
 	extractContent := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+		usage.add(msg)
 		return msg.Content, nil
 	})
 
-	// ========== 构建提取链 ==========
 	// 链结构: Template -> ChatModel -> Lambda
 	// 对应 Python: extraction_chain = prompt_extract | llm | StrOutputParser()
-	extractionChain, err := compose.NewChain[map[string]any, string]().
+	return compose.NewChain[map[string]any, string]().
 		AppendChatTemplate(promptExtract). // map -> []*Message
 		AppendChatModel(llm).              // []*Message -> *Message
 		AppendLambda(extractContent).      // *Message -> string
 		Compile(ctx)
-	if err != nil {
-		fmt.Printf("编译提取链失败: %v\n", err)
-		os.Exit(1)
+}
+
+// buildTransformChain: 构建转换链，编译前用 wrapSpecifications 实际输出的键（specificationsInput）校验模板占位符
+func buildTransformChain(ctx context.Context, llm model.BaseChatModel, usage *tokenUsage) (compose.Runnable[string, string], error) {
+	if err := chainutil.CheckInput([]string{transformTemplate}, specificationsInput); err != nil {
+		return nil, err
 	}
 
+	// --- 提示词 2：转换为 JSON ---
+	promptTransform := prompt.FromMessages(
+		schema.FString,
+		schema.UserMessage(transformTemplate),
+	)
+
 	// ========== Lambda 函数2: string -> map ==========
 	// 作用: 将字符串包装成 map，供第二个链的模板使用
 	// 对应 Python: {"specifications": extraction_chain}
 	wrapSpecifications := compose.InvokableLambda(func(ctx context.Context, specifications string) (map[string]any, error) {
-		return map[string]any{
-			"specifications": specifications,
-		}, nil
+		return specificationsInput(specifications), nil
 	})
 
 	// ========== Lambda 函数3: Message -> string ==========
 	// 作用: 从 Message 对象中提取 Content 字段
 	// 对应 Python: StrOutputParser()
 	extractFinalResult := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+		usage.add(msg)
 		return msg.Content, nil
 	})
 
-	// ========== 构建转换链 ==========
 	// 链结构: Lambda -> Template -> ChatModel -> Lambda
 	// 对应 Python: {"specifications": extraction_chain} | prompt_transform | llm | StrOutputParser()
-	transformChain, err := compose.NewChain[string, string]().
+	return compose.NewChain[string, string]().
 		AppendLambda(wrapSpecifications).    // string -> map
 		AppendChatTemplate(promptTransform). // map -> []*Message
 		AppendChatModel(llm).                // []*Message -> *Message
 		AppendLambda(extractFinalResult).    // *Message -> string
 		Compile(ctx)
-	if err != nil {
-		fmt.Printf("编译转换链失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	// ========== 执行链 ==========
-	inputText := "新款笔记本电脑型号配备 3.5 GHz 八核处理器、16GB 内存和 1TB NVMe 固态硬盘。"
-
-	// 执行提取链
-	extractedSpecs, err := extractionChain.Invoke(ctx, map[string]any{
-		"text_input": inputText, // 键名必须与模板中的 {text_input} 占位符一致
-	})
-	if err != nil {
-		fmt.Printf("提取链执行失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	// 执行转换链
-	finalResult, err := transformChain.Invoke(ctx, extractedSpecs)
-	if err != nil {
-		fmt.Printf("转换链执行失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Println("\n--- 最终 JSON 输出 ---")
-	fmt.Println(finalResult)
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeModel: 按调用顺序返回预设回复，并记录每次收到的用户消息
+type fakeModel struct {
+	replies []string
+	inputs  []string
+}
+
+func (m *fakeModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.inputs = append(m.inputs, in[len(in)-1].Content)
+	reply := m.replies[len(m.inputs)-1]
+	return &schema.Message{
+		Role:         schema.Assistant,
+		Content:      reply,
+		ResponseMeta: &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+	}, nil
+}
+
+func (m *fakeModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func TestRunChains(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{replies: []string{"CPU: 3.5 GHz 八核", `{"cpu": "3.5 GHz 八核"}`}}
+	usage := &tokenUsage{}
+	extraction, err := buildExtractionChain(ctx, llm, usage)
+	if err != nil {
+		t.Fatalf("buildExtractionChain: %v", err)
+	}
+	transform, err := buildTransformChain(ctx, llm, usage)
+	if err != nil {
+		t.Fatalf("buildTransformChain: %v", err)
+	}
+
+	got, err := runChains(ctx, extraction, transform, "新款笔记本")
+	if err != nil {
+		t.Fatalf("runChains: %v", err)
+	}
+	if got != `{"cpu": "3.5 GHz 八核"}` {
+		t.Errorf("runChains = %q", got)
+	}
+	if !strings.Contains(llm.inputs[0], "新款笔记本") {
+		t.Errorf("提取链的提示词没有包含输入文本: %q", llm.inputs[0])
+	}
+	if !strings.Contains(llm.inputs[1], "CPU: 3.5 GHz 八核") {
+		t.Errorf("转换链的提示词没有包含提取结果: %q", llm.inputs[1])
+	}
+	if want := "模型调用 2 次 | prompt: 20 | completion: 10 | total: 30"; usage.String() != want {
+		t.Errorf("usage = %q, want %q", usage.String(), want)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"shared/repl"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// tokenUsage: 累计模型调用的 Token 用量（并发安全）
+type tokenUsage struct {
+	mu               sync.Mutex
+	calls            int // calls: 模型调用次数
+	promptTokens     int
+	completionTokens int
+	totalTokens      int
+}
+
+// add: 从模型返回的 Message 中读取 ResponseMeta.Usage 并累加（模型未返回用量时只计调用次数）
+func (u *tokenUsage) add(msg *schema.Message) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.calls++
+	if msg == nil || msg.ResponseMeta == nil || msg.ResponseMeta.Usage == nil {
+		return
+	}
+	u.promptTokens += msg.ResponseMeta.Usage.PromptTokens
+	u.completionTokens += msg.ResponseMeta.Usage.CompletionTokens
+	u.totalTokens += msg.ResponseMeta.Usage.TotalTokens
+}
+
+// String: 格式化输出累计用量
+func (u *tokenUsage) String() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return fmt.Sprintf("模型调用 %d 次 | prompt: %d | completion: %d | total: %d",
+		u.calls, u.promptTokens, u.completionTokens, u.totalTokens)
+}
+
+// runREPL: 交互模式主循环，从 in 逐行读取产品描述，结果写入 out
+//   - 每行输入作为 text_input 执行完整链路，并打印 JSON 结果
+//   - 支持命令 :quit（退出）、:stats（累计 Token 用量）、:last（重新打印上一次结果）
+//   - Ctrl-C 和 EOF 的处理见 repl.Loop
+func runREPL(ctx context.Context, in io.Reader, out io.Writer, extractionChain compose.Runnable[map[string]any, string],
+	transformChain compose.Runnable[string, string], usage *tokenUsage) {
+	fmt.Fprintln(out, "进入交互模式：输入产品描述后回车执行链路，命令 :quit / :stats / :last")
+
+	var last string // last: 上一次成功执行的结果
+	repl.Loop(ctx, repl.Config{
+		In:     in,
+		Out:    out,
+		Prompt: "> ",
+		Commands: map[string]func(){
+			":stats": func() { fmt.Fprintln(out, usage) },
+			":last": func() {
+				if last == "" {
+					fmt.Fprintln(out, "暂无结果")
+				} else {
+					fmt.Fprintln(out, last)
+				}
+			},
+		},
+		Handle: func(ctx context.Context, line string) (string, error) {
+			return runChains(ctx, extractionChain, transformChain, line)
+		},
+		Done: func(line, result string, err error) {
+			if err != nil {
+				fmt.Fprintln(out, err)
+				return
+			}
+			last = result
+			fmt.Fprintln(out, result)
+		},
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// runTestREPL: 以 input 作为标准输入运行交互模式，返回输出和假模型
+func runTestREPL(t *testing.T, input string, replies ...string) (string, *fakeModel) {
+	t.Helper()
+	ctx := context.Background()
+	llm := &fakeModel{replies: replies}
+	usage := &tokenUsage{}
+	extraction, err := buildExtractionChain(ctx, llm, usage)
+	if err != nil {
+		t.Fatalf("buildExtractionChain: %v", err)
+	}
+	transform, err := buildTransformChain(ctx, llm, usage)
+	if err != nil {
+		t.Fatalf("buildTransformChain: %v", err)
+	}
+	var out bytes.Buffer
+	runREPL(ctx, strings.NewReader(input), &out, extraction, transform, usage)
+	return out.String(), llm
+}
+
+func TestREPLQuit(t *testing.T) {
+	out, llm := runTestREPL(t, ":last\n新款笔记本\n:last\n:stats\n:quit\n旧款笔记本\n", "CPU: 八核", `{"cpu": "八核"}`)
+	if len(llm.inputs) != 2 {
+		t.Errorf("模型调用了 %d 次，:quit 之后的输入不应执行", len(llm.inputs))
+	}
+	for _, want := range []string{"> 暂无结果\n", `> {"cpu": "八核"}` + "\n> " + `{"cpu": "八核"}` + "\n", "> 模型调用 2 次 | prompt: 20 | completion: 10 | total: 30\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("输出缺少 %q：\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "> ") {
+		t.Errorf(":quit 时不应再打印换行，输出以 %q 结尾", out[len(out)-4:])
+	}
+}
+
+func TestREPLEOF(t *testing.T) {
+	out, llm := runTestREPL(t, "\n新款笔记本", "CPU: 八核", `{"cpu": "八核"}`)
+	if len(llm.inputs) != 2 || !strings.Contains(llm.inputs[0], "新款笔记本") {
+		t.Errorf("最后一行没有换行时也应执行，模型收到 %q", llm.inputs)
+	}
+	if !strings.HasSuffix(out, `{"cpu": "八核"}`+"\n> \n") {
+		t.Errorf("EOF 时应换行后退出，输出：\n%s", out)
+	}
+}
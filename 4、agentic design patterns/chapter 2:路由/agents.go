@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+)
+
+// --- 真实子 Agent（--mock 关闭时使用）---
+
+// SearchFlightsTool: 模拟航班搜索工具（根据参数确定性地生成航班，不访问外部服务）
+type SearchFlightsTool struct{}
+
+func (t *SearchFlightsTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "search_flights",
+		Desc: "搜索指定日期从出发地到目的地的可预订航班",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"origin": {
+				Type:     schema.String,
+				Desc:     "出发城市，例如：上海",
+				Required: true,
+			},
+			"destination": {
+				Type:     schema.String,
+				Desc:     "目的地城市，例如：伦敦",
+				Required: true,
+			},
+			"date": {
+				Type:     schema.String,
+				Desc:     "出发日期，格式 YYYY-MM-DD",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+// flight: search_flights 返回的单个航班
+type flight struct {
+	FlightNo  string `json:"flight_no"`
+	Origin    string `json:"origin"`
+	Dest      string `json:"destination"`
+	Date      string `json:"date"`
+	DepartAt  string `json:"depart_at"`
+	PriceCNY  int    `json:"price_cny"`
+	SeatsLeft int    `json:"seats_left"`
+}
+
+func (t *SearchFlightsTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Origin      string `json:"origin"`
+		Destination string `json:"destination"`
+		Date        string `json:"date"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return "", fmt.Errorf("无效的参数: %w", err)
+	}
+
+	fmt.Printf("\n--- 🛠️ 工具调用：search_flights，参数：origin=%s, destination=%s, date=%s ---\n",
+		args.Origin, args.Destination, args.Date)
+
+	// 用参数哈希生成稳定的航班号和价格，保证同样的查询得到同样的结果
+	h := fnv.New32a()
+	h.Write([]byte(args.Origin + "|" + args.Destination + "|" + args.Date))
+	seed := int(h.Sum32() % 1000)
+
+	flights := []flight{
+		{FlightNo: fmt.Sprintf("MU%03d", seed), Origin: args.Origin, Dest: args.Destination, Date: args.Date, DepartAt: "09:35", PriceCNY: 4200 + seed, SeatsLeft: 9},
+		{FlightNo: fmt.Sprintf("CA%03d", (seed+137)%1000), Origin: args.Origin, Dest: args.Destination, Date: args.Date, DepartAt: "13:10", PriceCNY: 3800 + seed, SeatsLeft: 3},
+	}
+	result, err := json.Marshal(flights)
+	if err != nil {
+		return "", fmt.Errorf("序列化航班失败: %w", err)
+	}
+
+	fmt.Printf("--- 工具结果：%s ---\n", result)
+	return string(result), nil
+}
+
+// bookingAgentPrompt: 预订 Agent 的系统提示词
+const bookingAgentPrompt = `你是航班预订助手。
+先使用 search_flights 工具搜索航班，然后从结果中选择最合适的一班（优先价格低且有余座），
+给出模拟的预订确认：航班号、出发时间、价格和一个以 BK 开头的确认号。
+如果请求缺少出发地、目的地或日期，请直接说明需要补充的信息，不要编造。`
+
+// newBookingAgentHandler: 创建基于 ReAct Agent 的预订处理程序
+func newBookingAgentHandler(ctx context.Context, llm model.ToolCallingChatModel) (func(ctx context.Context, request string) (string, error), error) {
+	agent, err := react.NewAgent(ctx, &react.AgentConfig{
+		ToolCallingModel: llm,
+		ToolsConfig: compose.ToolsNodeConfig{
+			Tools: []tool.BaseTool{&SearchFlightsTool{}},
+		},
+		MaxStep: 10,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建预订 Agent 失败: %w", err)
+	}
+
+	return func(ctx context.Context, request string) (string, error) {
+		fmt.Println("\n--- 委托给预订 Agent（ReAct）---")
+		resp, err := agent.Generate(ctx, []*schema.Message{
+			schema.SystemMessage(bookingAgentPrompt),
+			schema.UserMessage(request),
+		})
+		if err != nil {
+			return "", fmt.Errorf("预订 Agent 执行失败: %w", err)
+		}
+		return resp.Content, nil
+	}, nil
+}
+
+// newInfoChainHandler: 创建直接由 LLM 回答问题的信息处理程序，同时返回普通调用和流式调用两个版本
+func newInfoChainHandler(ctx context.Context, llm model.BaseChatModel) (
+	invoke func(ctx context.Context, request string) (string, error),
+	stream func(ctx context.Context, request string) (*schema.StreamReader[string], error),
+	err error,
+) {
+	infoChain, err := compose.NewChain[map[string]any, *schema.Message]().
+		AppendChatTemplate(prompt.FromMessages(
+			schema.FString,
+			schema.SystemMessage("你是信息助手，请简洁准确地回答用户的问题，不确定时直接说明。"),
+			schema.UserMessage("{request}"),
+		)).
+		AppendChatModel(llm).
+		Compile(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("编译信息链失败: %w", err)
+	}
+
+	invoke = func(ctx context.Context, request string) (string, error) {
+		fmt.Println("\n--- 委托给信息链 ---")
+		msg, err := infoChain.Invoke(ctx, map[string]any{"request": request})
+		if err != nil {
+			return "", fmt.Errorf("信息链执行失败: %w", err)
+		}
+		return msg.Content, nil
+	}
+
+	stream = func(ctx context.Context, request string) (*schema.StreamReader[string], error) {
+		fmt.Println("\n--- 委托给信息链（流式）---")
+		sr, err := infoChain.Stream(ctx, map[string]any{"request": request})
+		if err != nil {
+			return nil, fmt.Errorf("信息链执行失败: %w", err)
+		}
+		// 只转发消息内容
+		return schema.StreamReaderWithConvert(sr, func(msg *schema.Message) (string, error) {
+			return msg.Content, nil
+		}), nil
+	}
+	return invoke, stream, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"ch2/router"
+	"shared/repl"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// runChat: 交互式路由对话，从 in 逐行读取请求，回复写入 out
+//   - 每行输入交给 coordinator 处理，打印路由决策（来源、置信度）和处理程序输出
+//   - 每轮对话写入 memory 的 sessionID 会话，下一轮从 memory 读取最近的历史，使追问也能正确路由
+//   - 支持命令 :routes（列出注册表）、:stats（路由指标）、:quit（退出）
+//   - Ctrl-C 和 EOF 的处理见 repl.Loop
+func runChat(ctx context.Context, in io.Reader, out io.Writer, registry *router.RouteRegistry, metrics *router.RouterMetrics,
+	memory router.ConversationMemory, sessionID string,
+	coordinator func(ctx context.Context, request string, history ...*schema.Message) (string, error)) {
+	fmt.Fprintln(out, "\n进入对话模式：输入请求后回车，命令 :routes / :stats / :quit")
+
+	repl.Loop(ctx, repl.Config{
+		In:     in,
+		Out:    out,
+		Prompt: "\n> ",
+		Commands: map[string]func(){
+			":routes": func() {
+				for _, route := range registry.Routes() {
+					fmt.Fprintf(out, "  %-8s %s\n", route.Name, route.Description)
+				}
+			},
+			":stats": func() { metrics.PrintSummary(out) },
+		},
+		Handle: func(ctx context.Context, line string) (string, error) {
+			history, err := memory.RecentMessages(ctx, sessionID, router.MaxHistoryTurns)
+			if err != nil {
+				fmt.Fprintf(out, "读取对话历史失败: %v\n", err)
+				history = nil
+			}
+			return coordinator(ctx, line, history...)
+		},
+		Done: func(line, reply string, err error) {
+			if err != nil {
+				fmt.Fprintf(out, "执行失败: %v\n", err)
+				return
+			}
+			fmt.Fprintf(out, "回复: %s\n", reply)
+			if err := recordTurn(ctx, memory, sessionID, line, reply); err != nil {
+				fmt.Fprintf(out, "保存对话历史失败: %v\n", err)
+			}
+		},
+	})
+}
+
+// recordTurn: 把一轮对话（用户请求和回复）写入会话记忆
+func recordTurn(ctx context.Context, memory router.ConversationMemory, sessionID, request, reply string) error {
+	if err := memory.AddMessage(ctx, sessionID, "user", request); err != nil {
+		return err
+	}
+	return memory.AddMessage(ctx, sessionID, "assistant", reply)
+}
@@ -6,16 +6,27 @@
 	LLM 路由    真人前台	      	  最聪明、懂暗语		       慢、费钱					     开发初期用（最容易实现）
 	嵌入路由	   图书管理员	  	      性价比之王、懂语义	       需要向量数据库支持				 生产环境推荐（平衡了速度和智能）
 	ML 路由	   专用分拣机	          快、量大时成本最低	       训练麻烦、难以冷启动		     巨头公司用（通常不做这个）
+
+	路由组件（规则、LLM、嵌入、组合与双路由，以及注册表、缓存、指标和评测）位于 router 包，本文件只负责组装组件并运行示例。
+	ROUTER_KIND 选择路由方式：rule、llm（默认）、embedding、hybrid 或 dual，其他环境变量在读取处说明；命令行参数见 go run . -h。
 */
 
 package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"ch2/router"
 
+	openaiEmbedding "github.com/cloudwego/eino-ext/components/embedding/openai"
 	"github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/compose"
@@ -27,7 +38,7 @@ func float32Ptr(f float32) *float32 {
 	return &f
 }
 
-// --- 定义模拟子 Agent 处理程序（相当于 ADK 的 sub_agents）---
+// --- 定义模拟子 Agent 处理程序（相当于 ADK 的 sub_agents，--mock 时使用）---
 
 // bookingHandler: 模拟预订 Agent 处理请求
 func bookingHandler(ctx context.Context, request string) (string, error) {
@@ -41,13 +52,101 @@ func infoHandler(ctx context.Context, request string) (string, error) {
 	return fmt.Sprintf("信息处理程序处理了请求：'%s'。结果：模拟信息检索。", request), nil
 }
 
+// refundHandler: 模拟退款 Agent 处理请求
+func refundHandler(ctx context.Context, request string) (string, error) {
+	fmt.Println("\n--- 委托给退款处理程序 ---")
+	return fmt.Sprintf("退款处理程序处理了请求：'%s'。结果：模拟退款申请。", request), nil
+}
+
 // unclearHandler: 处理无法委托的请求
-func unclearHandler(ctx context.Context, request string) (string, error) {
+// 路由给出了候选路由时，生成引用候选意图的澄清问题（如"您是想预订航班或酒店，还是查询一般信息？"）；
+// 只有理由时附上理由；两者都没有时保持简短的"请澄清"
+func unclearHandler(ctx context.Context, input router.DelegationInput) (string, error) {
 	fmt.Println("\n--- 处理不清楚的请求 ---")
-	return fmt.Sprintf("协调器无法委托请求：'%s'。请澄清。", request), nil
+	if len(input.Candidates) > 0 {
+		intents := make([]string, 0, len(input.Candidates))
+		for _, c := range input.Candidates {
+			intents = append(intents, c.Intent)
+		}
+		return fmt.Sprintf("我不太确定如何处理您的请求：'%s'。您是想%s？", input.Request, strings.Join(intents, "，还是")), nil
+	}
+	if input.Reason != "" {
+		return fmt.Sprintf("协调器无法委托请求：'%s'（%s）。请补充更多信息。", input.Request, input.Reason), nil
+	}
+	return fmt.Sprintf("协调器无法委托请求：'%s'。请澄清。", input.Request), nil
+}
+
+// defaultRoutes: 路由注册表（新增处理程序只需在这里追加一条）
+var defaultRoutes = []router.Route{
+	{
+		Name:        "booker",
+		Description: "与预订航班或酒店相关的请求",
+		Intent:      "预订航班或酒店",
+		Handler:     bookingHandler,
+		Exemplars: []string{
+			"给我预订去伦敦的航班",
+			"帮我订一张明天去上海的机票",
+			"预订下周五北京的酒店",
+			"我想订两晚的酒店房间",
+			"book a flight to Paris",
+		},
+	},
+	{
+		Name:        "info",
+		Description: "所有其他一般信息问题",
+		Intent:      "查询一般信息",
+		Handler:     infoHandler,
+		Exemplars: []string{
+			"意大利的首都是什么？",
+			"东京现在几点？",
+			"介绍一下长城的历史",
+			"伦敦今天天气怎么样？",
+			"what is the population of Japan",
+		},
+	},
+	{
+		Name:        "refund",
+		Description: "退票、退款或取消已有订单的请求",
+		Intent:      "退票或申请退款",
+		Handler:     refundHandler,
+		Exemplars: []string{
+			"我要退掉昨天订的机票",
+			"酒店订单取消后多久退款？",
+			"帮我申请退款",
+			"cancel my booking and refund me",
+		},
+	},
+	{
+		Name:         router.UnclearRoute,
+		Description:  "请求不清楚或不适合以上任一类别",
+		InputHandler: unclearHandler, // 需要决策理由和候选路由来生成澄清问题
+		Exemplars: []string{
+			"你好",
+			"嗯",
+			"随便",
+			"帮我弄一下",
+			"asdfgh",
+		},
+	},
 }
 
 func main() {
+	// mock: 使用模拟处理程序（不调用模型），便于离线演示委托流程
+	mock := flag.Bool("mock", false, "使用模拟的 booker/info 处理程序")
+	// noRouteCache: 关闭路由决策缓存
+	noRouteCache := flag.Bool("no-route-cache", false, "关闭路由决策的 LRU 缓存")
+	// metricsJSON: 运行结束后将路由指标写入该 JSON 文件（可选）
+	metricsJSON := flag.String("metrics-json", "", "将路由指标导出为 JSON 文件")
+	// chat: 交互式对话模式，逐行读取请求并路由
+	chat := flag.Bool("chat", false, "交互式对话模式")
+	// session: 对话模式使用的会话 ID，设置 REDIS_ADDR 时与第 8 章的会话共用
+	session := flag.String("session", "router-chat", "对话模式的会话 ID")
+	// evalPath: 评测模式，读取 JSONL 评测集评估当前 ROUTER_KIND 配置的路由准确率
+	evalPath := flag.String("eval", "", "评测集 JSONL 文件路径（如 eval_dataset.jsonl）")
+	evalReport := flag.String("eval-report", "eval_report.json", "评测报告输出路径")
+	evalConcurrency := flag.Int("eval-concurrency", 4, "评测时的最大并发数")
+	flag.Parse()
+
 	ctx := context.Background()
 
 	// --- 配置 ---
@@ -82,166 +181,429 @@ func main() {
 
 	fmt.Printf("语言模型已初始化: %s\n", config.Model)
 
-	// --- 定义协调器路由链（相当于 ADK 协调器的指令）---
-	// 此链决定应委托给哪个处理程序。
-	coordinatorRouterPrompt := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage(`分析用户的请求并确定哪个专家处理程序应处理它。
-     - 如果请求与预订航班或酒店相关，输出 'booker'。
-     - 对于所有其他一般信息问题，输出 'info'。
-     - 如果请求不清楚或不适合任一类别，输出 'unclear'。
-     只输出一个词：'booker'、'info' 或 'unclear'。`),
-		schema.UserMessage("{request}"),
-	)
-
-	// Lambda 函数：从 Message 中提取 Content 作为决策字符串
-	extractDecision := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
-		decision := strings.TrimSpace(msg.Content)
-		return decision, nil
-	})
+	// --- 路由注册表 ---
+	// 路由提示词、委托图节点、分支和边都由注册表生成
+	routes := defaultRoutes
+	if !*mock {
+		bookingAgent, err := newBookingAgentHandler(ctx, llm)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		infoChain, infoStream, err := newInfoChainHandler(ctx, llm)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
 
-	// 构建路由链：Template -> ChatModel -> Lambda (提取决策)
-	routerChain, err := compose.NewChain[map[string]any, string]().
-		AppendChatTemplate(coordinatorRouterPrompt). // map -> []*Message
-		AppendChatModel(llm).                        // []*Message -> *Message
-		AppendLambda(extractDecision).               // *Message -> string
-		Compile(ctx)
+		// 用真实子 Agent 替换注册表中的模拟处理程序
+		// 预订 Agent 包含工具调用，不提供流式版本，流式调用时退化为单个分片
+		realHandlers := map[string]func(ctx context.Context, request string) (string, error){
+			"booker": bookingAgent,
+			"info":   infoChain,
+		}
+		routes = make([]router.Route, len(defaultRoutes))
+		for i, route := range defaultRoutes {
+			if h, ok := realHandlers[route.Name]; ok {
+				route.Handler = h
+			}
+			if route.Name == "info" {
+				route.StreamHandler = infoStream
+			}
+			routes[i] = route
+		}
+	}
+
+	registry, err := router.NewRouteRegistry(routes...)
 	if err != nil {
-		fmt.Printf("编译路由链失败: %v\n", err)
+		fmt.Printf("初始化路由注册表失败: %v\n", err)
 		os.Exit(1)
 	}
 
-	// --- 定义委托逻辑（相当于 ADK 的基于 sub_agents 的自动流）---
-	// 使用 Graph 和 Branch 根据路由链的输出进行路由
+	// metrics: 路由指标（各路由请求数、缓存命中、无效标签、unclear 比例、处理程序耗时）
+	metrics := router.NewRouterMetrics()
+
+	// Lambda 函数：从 Message 中提取 Content（意图拆分链使用）
+	extractContent := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+		return strings.TrimSpace(msg.Content), nil
+	})
 
-	// 定义输入结构：包含原始请求和路由决策
-	type RouterInput struct {
-		Request  string
-		Decision string
+	// confidenceThreshold: LLM 路由的置信度阈值
+	confidenceThreshold := router.DefaultConfidenceThreshold
+	if v := os.Getenv("ROUTER_CONFIDENCE_THRESHOLD"); v != "" {
+		confidenceThreshold, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			fmt.Printf("ROUTER_CONFIDENCE_THRESHOLD 格式错误: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	// 定义输出结构：包含处理结果
-	type RouterOutput struct {
-		Output string
+	// --- 定义协调器路由（相当于 ADK 协调器的指令）---
+	// 路由提示词由注册表生成，输出无效时严格模式重试一次
+	llmRouter, err := router.NewLLMRouter(ctx, llm, registry, confidenceThreshold, metrics)
+	if err != nil {
+		fmt.Printf("初始化 LLM 路由失败: %v\n", err)
+		os.Exit(1)
 	}
 
-	// 创建 Graph
-	graph := compose.NewGraph[RouterInput, RouterOutput]()
+	// --- 规则路由 ---
+	// 明显的请求（命令前缀、关键词）直接由规则决定，避免一次模型往返
+	rules := router.DefaultRouteRules
+	if rulesFile := os.Getenv("ROUTER_RULES_FILE"); rulesFile != "" {
+		rules, err = router.LoadRouteRules(rulesFile)
+		if err != nil {
+			fmt.Printf("加载路由规则失败: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	ruleRouter, err := router.NewRuleRouter(rules)
+	if err != nil {
+		fmt.Printf("初始化规则路由失败: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Lambda 节点：booking 处理程序
-	bookingLambda := compose.InvokableLambda(func(ctx context.Context, input RouterInput) (RouterOutput, error) {
-		result, err := bookingHandler(ctx, input.Request)
+	// --- 选择路由方式 ---
+	// 所有路由方式都由 CompositeRouter 按阶段组合，前一阶段未命中或置信度不足时交给下一阶段：
+	//   llm       = rule -> llm
+	//   embedding = rule -> embedding
+	//   hybrid    = ROUTER_STAGES 指定的顺序（默认 rule -> embedding -> llm）
+	//   dual      = rule -> dual（llm 与 embedding 并发投票，不一致时仲裁）
+	routerKind := strings.ToLower(strings.TrimSpace(os.Getenv("ROUTER_KIND")))
+	var stageOrder []string
+	switch routerKind {
+	case "rule":
+		stageOrder = []string{"rule"}
+	case "", "llm":
+		stageOrder = []string{"rule", "llm"}
+	case "embedding":
+		stageOrder = []string{"rule", "embedding"}
+	case "dual":
+		stageOrder = []string{"rule", "dual"}
+	case "hybrid":
+		stageOrder = router.DefaultStageOrder
+		if v := os.Getenv("ROUTER_STAGES"); v != "" {
+			stageOrder, err = router.ParseStageOrder(v)
+			if err != nil {
+				fmt.Printf("ROUTER_STAGES 格式错误: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	default:
+		fmt.Printf("未知的 ROUTER_KIND: %s（可选 rule / llm / embedding / hybrid / dual）\n", routerKind)
+		os.Exit(1)
+	}
+
+	// ruleStage: 规则阶段，命中即置信度 1
+	ruleStage := router.Stage{Name: "rule", Threshold: 1, Router: ruleRouter}
+	// llmStage: LLM 路由阶段，置信度不足时交给 unclear
+	llmStage := router.Stage{Name: "llm", Threshold: confidenceThreshold, Router: llmRouter}
+
+	// newEmbeddingStage: 嵌入路由阶段（只看当前请求），仅在阶段列表包含 embedding 时初始化 Embedding 模型
+	newEmbeddingStage := func() router.Stage {
+		embeddingModel := os.Getenv("EMBEDDING_MODEL")
+		if embeddingModel == "" {
+			embeddingModel = "Qwen/Qwen3-Embedding-8B"
+		}
+		threshold := router.DefaultEmbeddingThreshold
+		if v := os.Getenv("EMBEDDING_THRESHOLD"); v != "" {
+			threshold, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				fmt.Printf("EMBEDDING_THRESHOLD 格式错误: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		embedder, err := openaiEmbedding.NewEmbedder(ctx, &openaiEmbedding.EmbeddingConfig{
+			APIKey:  apiKey,
+			Model:   embeddingModel,
+			Timeout: 30 * time.Second,
+			BaseURL: baseURL,
+		})
 		if err != nil {
-			return RouterOutput{}, err
+			fmt.Printf("初始化 Embedding 模型失败: %v\n", err)
+			os.Exit(1)
 		}
-		return RouterOutput{Output: result}, nil
-	})
 
-	// Lambda 节点：info 处理程序
-	infoLambda := compose.InvokableLambda(func(ctx context.Context, input RouterInput) (RouterOutput, error) {
-		result, err := infoHandler(ctx, input.Request)
+		// 启动时一次性向量化所有路由示例
+		embeddingRouter, err := router.NewEmbeddingRouter(ctx, embedder, registry.Exemplars(), threshold)
 		if err != nil {
-			return RouterOutput{}, err
+			fmt.Printf("初始化嵌入路由失败: %v\n", err)
+			os.Exit(1)
 		}
-		return RouterOutput{Output: result}, nil
-	})
+		fmt.Printf("嵌入路由已初始化: %s（阈值 %.2f）\n", embeddingModel, threshold)
 
-	// Lambda 节点：unclear 处理程序
-	unclearLambda := compose.InvokableLambda(func(ctx context.Context, input RouterInput) (RouterOutput, error) {
-		result, err := unclearHandler(ctx, input.Request)
+		return router.Stage{Name: "embedding", Threshold: threshold, Router: embeddingRouter}
+	}
+
+	// newDualStage: 双路由阶段，LLM 和嵌入路由并发投票，仅在 ROUTER_KIND=dual 时初始化仲裁模型
+	newDualStage := func() router.Stage {
+		arbiterConfig := *config
+		if m := os.Getenv("ARBITER_MODEL"); m != "" {
+			arbiterConfig.Model = m // 仲裁只需在两个候选中做选择，可以使用更便宜的模型
+		}
+		arbiterLLM, err := openai.NewChatModel(ctx, &arbiterConfig)
 		if err != nil {
-			return RouterOutput{}, err
+			fmt.Printf("初始化仲裁模型时出错: %v\n", err)
+			os.Exit(1)
+		}
+		arbiter, err := router.NewLLMArbiter(ctx, arbiterLLM, registry, metrics)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
 		}
-		return RouterOutput{Output: result}, nil
-	})
 
-	// 添加节点
-	if err := graph.AddLambdaNode("booking", bookingLambda); err != nil {
-		fmt.Printf("添加 booking 节点失败: %v\n", err)
-		os.Exit(1)
+		dualRouter, err := router.NewDualRouter(ctx, llmStage, newEmbeddingStage(), arbiter, confidenceThreshold, metrics)
+		if err != nil {
+			fmt.Printf("初始化双路由失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("仲裁模型已初始化: %s\n", arbiterConfig.Model)
+		// 阈值已在双路由内部处理，阶段阈值为 0
+		return router.Stage{Name: "dual", Router: dualRouter}
 	}
-	if err := graph.AddLambdaNode("info", infoLambda); err != nil {
-		fmt.Printf("添加 info 节点失败: %v\n", err)
-		os.Exit(1)
+
+	stages := make([]router.Stage, 0, len(stageOrder))
+	for _, name := range stageOrder {
+		switch name {
+		case "rule":
+			stages = append(stages, ruleStage)
+		case "llm":
+			stages = append(stages, llmStage)
+		case "embedding":
+			stages = append(stages, newEmbeddingStage())
+		case "dual":
+			stages = append(stages, newDualStage())
+		}
 	}
-	if err := graph.AddLambdaNode("unclear", unclearLambda); err != nil {
-		fmt.Printf("添加 unclear 节点失败: %v\n", err)
+	compositeRouter, err := router.NewCompositeRouter(stages...)
+	if err != nil {
+		fmt.Printf("初始化组合路由失败: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("路由阶段: %s\n", strings.Join(compositeRouter.StageNames(), " -> "))
 
-	// 创建分支：根据决策路由到不同的处理程序
-	branch := compose.NewGraphBranch(
-		func(ctx context.Context, input RouterInput) (string, error) {
-			decision := strings.TrimSpace(strings.ToLower(input.Decision))
-			switch decision {
-			case "booker":
-				return "booking", nil
-			case "info":
-				return "info", nil
-			default:
-				return "unclear", nil
-			}
-		},
-		map[string]bool{
-			"booking": true,
-			"info":    true,
-			"unclear": true,
-		},
-	)
-
-	// 从 START 添加分支
-	if err := graph.AddBranch(compose.START, branch); err != nil {
-		fmt.Printf("添加分支失败: %v\n", err)
-		os.Exit(1)
+	// --- 评测模式 ---
+	// 直接评测组合路由的决策（不经过缓存，也不执行处理程序）
+	if *evalPath != "" {
+		examples, err := router.LoadEvalExamples(*evalPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		report := router.RunEval(ctx, examples, *evalConcurrency, router.Func(func(ctx context.Context, req router.Request) (router.Decision, error) {
+			decision, err := compositeRouter.Route(ctx, req)
+			decision.Destination = registry.Resolve(decision.Destination)
+			return decision, err
+		}))
+		report.Print(os.Stdout)
+		if err := report.WriteJSON(*evalReport); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n评测报告已写入 %s\n", *evalReport)
+		return
 	}
 
-	// 所有处理程序节点都连接到 END
-	if err := graph.AddEdge("booking", compose.END); err != nil {
-		fmt.Printf("添加 booking->END 边失败: %v\n", err)
-		os.Exit(1)
+	// --- 路由超时 ---
+	// 路由模型调用卡住时，超时后取消调用，改用规则路由的部分匹配猜测或 unclear（ROUTER_TIMEOUT=0 表示不限时）
+	// 完整命中规则的请求在 rule 阶段就已决定，不会超时，因此兜底使用部分匹配模式
+	routeTimeout := router.DefaultRouteTimeout
+	if v := os.Getenv("ROUTER_TIMEOUT"); v != "" {
+		routeTimeout, err = time.ParseDuration(v)
+		if err != nil {
+			fmt.Printf("ROUTER_TIMEOUT 格式错误: %v\n", err)
+			os.Exit(1)
+		}
 	}
-	if err := graph.AddEdge("info", compose.END); err != nil {
-		fmt.Printf("添加 info->END 边失败: %v\n", err)
-		os.Exit(1)
+	var entryRouter router.Router = router.NewTimeoutRouter(compositeRouter, routeTimeout, ruleRouter.GuessRouter(router.DefaultGuessThreshold), metrics)
+
+	// --- 路由决策缓存 ---
+	// 规范化后的请求命中缓存时直接复用决策，避免重复的模型调用
+	var routeCache *router.RouteCache
+	if !*noRouteCache {
+		cacheSize := router.DefaultRouteCacheSize
+		if v := os.Getenv("ROUTE_CACHE_SIZE"); v != "" {
+			cacheSize, err = strconv.Atoi(v)
+			if err != nil {
+				fmt.Printf("ROUTE_CACHE_SIZE 格式错误: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		cacheTTL := router.DefaultRouteCacheTTL
+		if v := os.Getenv("ROUTE_CACHE_TTL"); v != "" {
+			cacheTTL, err = time.ParseDuration(v)
+			if err != nil {
+				fmt.Printf("ROUTE_CACHE_TTL 格式错误: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		routeCache = router.NewRouteCache(cacheSize, cacheTTL)
+		entryRouter = router.NewCachedRouter(entryRouter, routeCache)
 	}
-	if err := graph.AddEdge("unclear", compose.END); err != nil {
-		fmt.Printf("添加 unclear->END 边失败: %v\n", err)
-		os.Exit(1)
+
+	// routeRequest: 执行路由（先查缓存，未命中时在超时限制内交给组合路由），并打印诊断信息和决策轨迹
+	routeRequest := func(ctx context.Context, request string, history []*schema.Message) (router.Decision, error) {
+		decision, err := entryRouter.Route(ctx, router.Request{Text: request, History: history})
+		for _, note := range decision.Notes {
+			fmt.Printf("  %s\n", note)
+		}
+		if len(decision.Trail) > 0 {
+			steps := make([]string, 0, len(decision.Trail))
+			for _, t := range decision.Trail {
+				steps = append(steps, t.String())
+			}
+			fmt.Printf("  决策轨迹: %s\n", strings.Join(steps, " -> "))
+		}
+		return decision, err
 	}
 
-	// 编译 Graph
-	delegationGraph, err := graph.Compile(ctx)
+	// --- 定义委托逻辑（相当于 ADK 的基于 sub_agents 的自动流）---
+	// 使用 Graph 和 Branch 根据路由决策进行委托，节点和边由注册表自动生成
+	delegationGraph, err := registry.BuildDelegationGraph(ctx, metrics)
 	if err != nil {
 		fmt.Printf("编译委托图失败: %v\n", err)
 		os.Exit(1)
 	}
 
+	// --- 意图拆分链 ---
+	// 将包含多个意图的请求拆分为 JSON 字符串数组
+	intentSplitChain, err := compose.NewChain[map[string]any, string]().
+		AppendChatTemplate(prompt.FromMessages(
+			schema.FString,
+			schema.SystemMessage(intentSplitPrompt),
+			schema.UserMessage("{request}"),
+		)).
+		AppendChatModel(llm).
+		AppendLambda(extractContent).
+		Compile(ctx)
+	if err != nil {
+		fmt.Printf("编译意图拆分链失败: %v\n", err)
+		os.Exit(1)
+	}
+
 	// --- 组合路由链和委托图 ---
-	// 创建一个协调器函数，首先执行路由链获取决策，然后将决策和原始请求传递给委托图
-	coordinatorAgentFunc := func(ctx context.Context, request string) (string, error) {
-		// 步骤 1: 执行路由链获取决策
-		decision, err := routerChain.Invoke(ctx, map[string]any{
-			"request": request,
-		})
+	// decide: 执行路由获取决策，并记录指标
+	decide := func(ctx context.Context, request string, history []*schema.Message) (router.Decision, error) {
+		decision, err := routeRequest(ctx, request, history)
 		if err != nil {
-			return "", fmt.Errorf("路由链执行失败: %w", err)
+			metrics.RecordRouteError()
+			return router.Decision{}, fmt.Errorf("路由执行失败: %w", err)
+		}
+		decision.Destination = registry.Resolve(decision.Destination)
+		metrics.RecordDecision(decision)
+		fmt.Printf("路由决策: %s（来源: %s，置信度: %.2f，理由: %s）\n",
+			decision.Destination, decision.Source, decision.Confidence, decision.Reason)
+		if decision.Destination == router.UnclearRoute && len(decision.Candidates) > 0 {
+			fmt.Printf("  候选路由: %s\n", strings.Join(decision.Candidates, ", "))
+		}
+		return decision, nil
+	}
+
+	// routeAndDelegate: 处理单个意图，首先执行路由获取决策，然后将决策和原始请求传递给委托图
+	routeAndDelegate := func(ctx context.Context, request string, history []*schema.Message) (router.Decision, string, error) {
+		// 步骤 1: 执行路由获取决策
+		decision, err := decide(ctx, request, history)
+		if err != nil {
+			return router.Decision{}, "", err
 		}
 
 		// 步骤 2: 将决策和原始请求传递给委托图
-		result, err := delegationGraph.Invoke(ctx, RouterInput{
-			Request:  request,
-			Decision: decision,
+		result, err := delegationGraph.Invoke(ctx, router.DelegationInput{
+			Request:    request,
+			Decision:   decision.Destination,
+			History:    history,
+			Reason:     decision.Reason,
+			Candidates: registry.Candidates(decision.Candidates),
+		})
+		if err != nil {
+			return decision, "", fmt.Errorf("委托图执行失败: %w", err)
+		}
+
+		return decision, result.Output, nil
+	}
+
+	// coordinatorAgentFunc: 协调器入口，history 为可选的最近对话历史
+	// 不含连接词的请求直接按单意图处理；否则先拆分意图，并发路由每个子请求，再按原顺序合并结果
+	coordinatorAgentFunc := func(ctx context.Context, request string, history ...*schema.Message) (string, error) {
+		if looksMultiIntent(request) {
+			raw, err := intentSplitChain.Invoke(ctx, map[string]any{"request": request})
+			if err != nil {
+				return "", fmt.Errorf("意图拆分失败: %w", err)
+			}
+			intents, err := parseIntentList(raw)
+			if err != nil {
+				fmt.Printf("  %v，按单意图处理\n", err)
+			} else if len(intents) > 1 {
+				fmt.Printf("拆分出 %d 个意图: %q\n", len(intents), intents)
+				results := runIntents(ctx, intents, maxConcurrentIntents, func(ctx context.Context, intent string) (router.Decision, string, error) {
+					return routeAndDelegate(ctx, intent, history)
+				})
+				return mergeIntentResults(results), nil
+			}
+		}
+
+		_, output, err := routeAndDelegate(ctx, request, history)
+		return output, err
+	}
+
+	// streamCoordinatorFunc: 流式协调器，先确定路由，再以 Stream 方式调用委托图，
+	// 把处理程序的分片实时写入 w，返回拼接后的完整回复（不做多意图拆分）
+	streamCoordinatorFunc := func(ctx context.Context, request string, w io.Writer, history ...*schema.Message) (string, error) {
+		decision, err := decide(ctx, request, history)
+		if err != nil {
+			return "", err
+		}
+
+		stream, err := delegationGraph.Stream(ctx, router.DelegationInput{
+			Request:    request,
+			Decision:   decision.Destination,
+			History:    history,
+			Reason:     decision.Reason,
+			Candidates: registry.Candidates(decision.Candidates),
 		})
 		if err != nil {
 			return "", fmt.Errorf("委托图执行失败: %w", err)
 		}
+		defer stream.Close()
+
+		var sb strings.Builder
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return sb.String(), fmt.Errorf("读取处理程序输出失败: %w", err)
+			}
+			fmt.Fprint(w, chunk.Output)
+			sb.WriteString(chunk.Output)
+		}
+		return sb.String(), nil
+	}
+
+	// --- 会话记忆 ---
+	// 设置 REDIS_ADDR 时与第 8 章的 ShortTermMemory 共用 Redis 中的会话历史
+	var memory router.ConversationMemory = router.NewInMemoryHistory(router.MaxHistoryTurns)
+	if redisAddr := os.Getenv("REDIS_ADDR"); redisAddr != "" {
+		redisMemory, err := NewRedisShortTermMemory(ctx, redisAddr, os.Getenv("REDIS_PASSWORD"), 0)
+		if err != nil {
+			fmt.Printf("创建会话记忆失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer redisMemory.Close()
+		memory = redisMemory
+	}
 
-		return result.Output, nil
+	// --- 交互模式 ---
+	// 复用上面已编译好的链和委托图
+	if *chat {
+		runChat(ctx, os.Stdin, os.Stdout, registry, metrics, memory, *session, coordinatorAgentFunc)
+		return
 	}
 
 	// --- 示例用法 ---
 	fmt.Println("\n--- 运行预订请求 ---")
-	requestA := "给我预订去伦敦的航班。"
+	requestA := "给我预订 2025-12-01 从上海去伦敦的航班。"
 	resultA, err := coordinatorAgentFunc(ctx, requestA)
 	if err != nil {
 		fmt.Printf("执行失败: %v\n", err)
@@ -266,4 +628,89 @@ func main() {
 	} else {
 		fmt.Printf("最终结果 C: %s\n", resultC)
 	}
+
+	fmt.Println("\n--- 运行命令式请求（规则路由，无需模型调用）---")
+	requestD := "/book 明天去上海的机票"
+	resultD, err := coordinatorAgentFunc(ctx, requestD)
+	if err != nil {
+		fmt.Printf("执行失败: %v\n", err)
+	} else {
+		fmt.Printf("最终结果 D: %s\n", resultD)
+	}
+
+	fmt.Println("\n--- 运行退款请求（仅通过注册表新增的路由）---")
+	requestE := "我上周订的酒店不去了，能退钱吗？"
+	resultE, err := coordinatorAgentFunc(ctx, requestE)
+	if err != nil {
+		fmt.Printf("执行失败: %v\n", err)
+	} else {
+		fmt.Printf("最终结果 E: %s\n", resultE)
+	}
+
+	fmt.Println("\n--- 运行多意图请求 ---")
+	requestF := "帮我订 2025-12-01 从上海去伦敦的机票，顺便告诉我伦敦现在的天气。"
+	resultF, err := coordinatorAgentFunc(ctx, requestF)
+	if err != nil {
+		fmt.Printf("执行失败: %v\n", err)
+	} else {
+		fmt.Printf("最终结果 F:\n%s\n", resultF)
+	}
+
+	fmt.Println("\n--- 重复信息请求（命中路由缓存）---")
+	resultG, err := coordinatorAgentFunc(ctx, "  意大利的首都是什么？ ")
+	if err != nil {
+		fmt.Printf("执行失败: %v\n", err)
+	} else {
+		fmt.Printf("最终结果 G: %s\n", resultG)
+	}
+
+	fmt.Println("\n--- 运行依赖上下文的追问 ---")
+	// demoSession: 每次运行使用新的会话，避免读到之前运行留在 Redis 中的历史
+	demoSession := fmt.Sprintf("router-demo-%d", time.Now().UnixNano())
+	turn1 := "12 月 1 日从上海飞伦敦的航班大概多少钱？"
+	answer1, err := coordinatorAgentFunc(ctx, turn1)
+	if err != nil {
+		fmt.Printf("执行失败: %v\n", err)
+	} else {
+		fmt.Printf("第一轮回复: %s\n", answer1)
+		if err := recordTurn(ctx, memory, demoSession, turn1, answer1); err != nil {
+			fmt.Printf("保存对话历史失败: %v\n", err)
+		}
+	}
+	history, err := memory.RecentMessages(ctx, demoSession, router.MaxHistoryTurns)
+	if err != nil {
+		fmt.Printf("读取对话历史失败: %v\n", err)
+	}
+	turn2 := "那帮我订一下吧"
+	fmt.Println("\n不带历史：")
+	if _, err := coordinatorAgentFunc(ctx, turn2); err != nil {
+		fmt.Printf("执行失败: %v\n", err)
+	}
+	fmt.Println("\n带历史：")
+	answer2, err := coordinatorAgentFunc(ctx, turn2, history...)
+	if err != nil {
+		fmt.Printf("执行失败: %v\n", err)
+	} else {
+		fmt.Printf("第二轮回复: %s\n", answer2)
+	}
+
+	fmt.Println("\n--- 流式运行信息请求 ---")
+	if _, err := streamCoordinatorFunc(ctx, "简单介绍一下罗马斗兽场。", os.Stdout); err != nil {
+		fmt.Printf("\n执行失败: %v\n", err)
+	}
+	fmt.Println()
+
+	if routeCache != nil {
+		hits, misses := routeCache.Stats()
+		fmt.Printf("\n路由缓存: 命中 %d 次，未命中 %d 次\n", hits, misses)
+	}
+
+	metrics.PrintSummary(os.Stdout)
+	if *metricsJSON != "" {
+		if err := metrics.WriteJSON(*metricsJSON); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Printf("路由指标已写入 %s\n", *metricsJSON)
+		}
+	}
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ch2/router"
+)
+
+func TestUnclearHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		input router.DelegationInput
+		want  string
+	}{
+		{
+			name: "候选路由生成澄清问题",
+			input: router.DelegationInput{
+				Request:    "帮我弄一下",
+				Reason:     "置信度低",
+				Candidates: []router.Candidate{{Route: "booker", Intent: "预订航班或酒店"}, {Route: "info", Intent: "查询一般信息"}},
+			},
+			want: "我不太确定如何处理您的请求：'帮我弄一下'。您是想预订航班或酒店，还是查询一般信息？",
+		},
+		{
+			name:  "只有理由",
+			input: router.DelegationInput{Request: "asdfgh", Reason: "无法识别的输入"},
+			want:  "协调器无法委托请求：'asdfgh'（无法识别的输入）。请补充更多信息。",
+		},
+		{
+			name:  "都没有",
+			input: router.DelegationInput{Request: "嗯"},
+			want:  "协调器无法委托请求：'嗯'。请澄清。",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unclearHandler(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("unclearHandler: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("unclearHandler = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnclearRouteReceivesDecision: 委托图把决策的理由和候选意图交给 unclear 处理程序
+func TestUnclearRouteReceivesDecision(t *testing.T) {
+	ctx := context.Background()
+	registry, err := router.NewRouteRegistry(defaultRoutes...)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	graph, err := registry.BuildDelegationGraph(ctx, nil)
+	if err != nil {
+		t.Fatalf("BuildDelegationGraph: %v", err)
+	}
+	decision := router.Decision{Destination: router.UnclearRoute, Reason: "置信度低于阈值", Candidates: []string{"refund", "booker", "weather"}}
+	out, err := graph.Invoke(ctx, router.DelegationInput{
+		Request:    "订单的事",
+		Decision:   decision.Destination,
+		Reason:     decision.Reason,
+		Candidates: registry.Candidates(decision.Candidates),
+	})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if !strings.HasSuffix(out.Output, "您是想退票或申请退款，还是预订航班或酒店？") {
+		t.Errorf("澄清问题应按候选顺序引用意图（忽略未注册的路由）: %q", out.Output)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ch2/router"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/go-redis/redis/v8"
+)
+
+// --- 与第 8 章短期记忆的集成 ---
+
+// shortTermMessage: 第 8 章 ShortTermMemory 在 Redis 中保存的消息格式
+type shortTermMessage struct {
+	Role    string `json:"role"`    // "user" 或 "assistant"
+	Content string `json:"content"` // 消息内容
+	Time    int64  `json:"time"`    // 时间戳
+}
+
+// RedisShortTermMemory: 与第 8 章 ShortTermMemory 共用 Redis 键 session:{id}:messages 和消息格式的 ConversationMemory，
+// 第 8 章记录的会话可以直接用于路由，路由对话中的新消息第 8 章也能读到（总结由第 8 章负责，这里只读取最近的消息）
+type RedisShortTermMemory struct {
+	client *redis.Client
+}
+
+var _ router.ConversationMemory = (*RedisShortTermMemory)(nil)
+
+// NewRedisShortTermMemory: 连接 Redis 并检查连接，连接方式与第 8 章相同
+func NewRedisShortTermMemory(ctx context.Context, redisAddr, redisPassword string, db int) (*RedisShortTermMemory, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: redisPassword,
+		DB:       db,
+	})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("无法连接到 Redis: %w", err)
+	}
+	return &RedisShortTermMemory{client: rdb}, nil
+}
+
+// sessionMessagesKey: 会话消息列表的键，与第 8 章相同
+func sessionMessagesKey(sessionID string) string {
+	return fmt.Sprintf("session:%s:messages", sessionID)
+}
+
+// AddMessage: 把消息追加到会话历史（与第 8 章一样不设置过期时间）
+func (m *RedisShortTermMemory) AddMessage(ctx context.Context, sessionID, role, content string) error {
+	if _, err := router.NewHistoryMessage(role, content); err != nil {
+		return err
+	}
+	data, err := json.Marshal(shortTermMessage{Role: role, Content: content, Time: time.Now().Unix()})
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+	if err := m.client.RPush(ctx, sessionMessagesKey(sessionID), data).Err(); err != nil {
+		return fmt.Errorf("存储消息失败: %w", err)
+	}
+	return nil
+}
+
+// RecentMessages: 读取会话最近 maxTurns 轮对话，跳过无法解析或角色未知的消息
+func (m *RedisShortTermMemory) RecentMessages(ctx context.Context, sessionID string, maxTurns int) ([]*schema.Message, error) {
+	raw, err := m.client.LRange(ctx, sessionMessagesKey(sessionID), int64(-maxTurns*2), -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取消息历史失败: %w", err)
+	}
+	msgs := make([]*schema.Message, 0, len(raw))
+	for _, item := range raw {
+		var stored shortTermMessage
+		if err := json.Unmarshal([]byte(item), &stored); err != nil {
+			continue
+		}
+		if msg, err := router.NewHistoryMessage(stored.Role, stored.Content); err == nil {
+			msgs = append(msgs, msg)
+		}
+	}
+	return router.RecentHistory(msgs, maxTurns), nil
+}
+
+// Close: 关闭 Redis 连接
+func (m *RedisShortTermMemory) Close() error {
+	return m.client.Close()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"ch2/router"
+)
+
+// maxConcurrentIntents: 多意图请求中同时路由的子请求上限
+const maxConcurrentIntents = 3
+
+// intentSplitPrompt: 意图拆分提示词，输出 JSON 字符串数组
+const intentSplitPrompt = `把用户的请求拆分为相互独立、可以单独处理的子请求，保持原有顺序。
+     每个子请求必须是完整的一句话，补全被省略的主语或对象（例如"那里"要替换成具体地点）。
+     如果只有一个意图，返回只包含原请求的数组。
+     只输出一个 JSON 字符串数组，不要输出任何其他内容，例如：["子请求1", "子请求2"]`
+
+// conjunctionKeywords: 多意图的连接词，请求中不包含任何一个时跳过拆分，省去一次模型调用
+var conjunctionKeywords = []string{"顺便", "另外", "还有", "并且", "同时", "然后", "以及", "再帮我", "，再", " and ", " also "}
+
+// looksMultiIntent: 廉价的启发式判断，请求中出现连接词才认为可能包含多个意图
+func looksMultiIntent(request string) bool {
+	text := strings.ToLower(request)
+	for _, kw := range conjunctionKeywords {
+		if strings.Contains(text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIntentList: 解析意图拆分链输出的 JSON 字符串数组（允许外层 ``` 代码块），丢弃空白子请求
+func parseIntentList(raw string) ([]string, error) {
+	text := strings.TrimSpace(raw)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(text, "```")
+		text = strings.TrimSpace(text)
+	}
+
+	var items []string
+	if err := json.Unmarshal([]byte(text), &items); err != nil {
+		return nil, fmt.Errorf("意图列表解析失败: %w", err)
+	}
+	intents := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			intents = append(intents, item)
+		}
+	}
+	if len(intents) == 0 {
+		return nil, fmt.Errorf("意图列表为空")
+	}
+	return intents, nil
+}
+
+// intentResult: 单个子请求的处理结果
+type intentResult struct {
+	Request string
+	Route   string // Route: 处理该子请求的路由
+	Output  string
+	Err     error
+}
+
+// runIntents: 并发（有上限）路由并处理每个子请求，结果按原顺序返回
+func runIntents(ctx context.Context, intents []string, limit int,
+	handle func(ctx context.Context, request string) (router.Decision, string, error)) []intentResult {
+	results := make([]intentResult, len(intents))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for i, intent := range intents {
+		wg.Add(1)
+		go func(i int, intent string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			decision, output, err := handle(ctx, intent)
+			results[i] = intentResult{Request: intent, Route: decision.Destination, Output: output, Err: err}
+		}(i, intent)
+	}
+	wg.Wait()
+	return results
+}
+
+// mergeIntentResults: 将各子请求的结果合并为一个编号回复，并注明处理程序
+func mergeIntentResults(results []intentResult) string {
+	var sb strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		route := r.Route
+		if route == "" {
+			route = "未路由"
+		}
+		if r.Err != nil {
+			fmt.Fprintf(&sb, "%d. [%s] %s\n   处理失败: %v", i+1, route, r.Request, r.Err)
+			continue
+		}
+		fmt.Fprintf(&sb, "%d. [%s] %s\n   %s", i+1, route, r.Request, r.Output)
+	}
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultStageOrder: 混合路由的默认阶段顺序：规则优先，嵌入其次，LLM 兜底裁决
+var DefaultStageOrder = []string{"rule", "embedding", "llm"}
+
+// Stage: 组合路由中的一个阶段
+type Stage struct {
+	Name      string  // Name: 阶段名称（rule / embedding / llm / dual），同时作为决策来源
+	Threshold float64 // Threshold: 置信度低于该值时视为未决定，交给下一阶段
+	Router    Router  // Router: 该阶段的路由器，返回 ErrNoMatch 表示没有给出决策（如规则未命中）
+}
+
+// StageTrail: 决策轨迹中的一步
+type StageTrail struct {
+	Stage      string
+	Decision   string
+	Confidence float64
+	Accepted   bool
+	Note       string
+}
+
+// String: 格式化单步轨迹
+func (t StageTrail) String() string {
+	status := "跳过"
+	if t.Accepted {
+		status = "采纳"
+	}
+	if t.Decision == "" {
+		return fmt.Sprintf("%s: %s（%s）", t.Stage, status, t.Note)
+	}
+	return fmt.Sprintf("%s: %s %s（置信度 %.2f，%s）", t.Stage, status, t.Decision, t.Confidence, t.Note)
+}
+
+// CompositeRouter: 组合路由，按顺序执行各阶段，第一个给出足够置信决策的阶段胜出
+//   - 阶段未命中、置信度低于阈值或判断为 unclear 时，交给下一阶段
+//   - 所有阶段都没有采纳的决策时路由到 unclear
+//   - 各阶段的 Notes 按执行顺序合并到最终决策
+type CompositeRouter struct {
+	stages []Stage
+}
+
+// NewCompositeRouter: 创建组合路由
+func NewCompositeRouter(stages ...Stage) (*CompositeRouter, error) {
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("组合路由至少需要一个阶段")
+	}
+	return &CompositeRouter{stages: stages}, nil
+}
+
+// StageNames: 按顺序返回阶段名称
+func (c *CompositeRouter) StageNames() []string {
+	names := make([]string, 0, len(c.stages))
+	for _, s := range c.stages {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// Route: 依次执行各阶段，返回最终决策（决策轨迹记录在 Decision.Trail 中，失败时也会返回已执行的轨迹）
+func (c *CompositeRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	trail := make([]StageTrail, 0, len(c.stages))
+	var candidates []string // candidates: 各阶段考虑过的路由，全部未采纳时交给 unclear 生成澄清问题
+	lastReason := ""        // lastReason: 最后一个给出决策的阶段的理由
+	var notes []string
+	for _, stage := range c.stages {
+		decision, err := stage.Router.Route(ctx, req)
+		if errors.Is(err, ErrNoMatch) {
+			trail = append(trail, StageTrail{Stage: stage.Name, Note: "未命中"})
+			continue
+		}
+		if err != nil {
+			return Decision{Trail: trail}, fmt.Errorf("%s 阶段失败: %w", stage.Name, err)
+		}
+
+		step := StageTrail{Stage: stage.Name, Decision: decision.Destination, Confidence: decision.Confidence}
+		switch {
+		case decision.Confidence < stage.Threshold:
+			step.Note = fmt.Sprintf("低于阈值 %.2f", stage.Threshold)
+		case decision.Destination == UnclearRoute:
+			step.Note = "判断为 unclear"
+		default:
+			step.Accepted = true
+			step.Note = "决定"
+		}
+		trail = append(trail, step)
+		notes = append(notes, decision.Notes...)
+		candidates = mergeCandidates(candidates, []string{decision.Destination}, decision.Candidates)
+		if decision.Reason != "" {
+			lastReason = decision.Reason
+		}
+		if step.Accepted {
+			decision.Source = stage.Name
+			decision.Trail = trail
+			decision.Notes = notes
+			return decision, nil
+		}
+	}
+
+	// 没有任何阶段给出足够置信的决策，请用户澄清（各阶段详情见 Trail）
+	reason := "所有阶段均未给出足够置信的决策"
+	if lastReason != "" {
+		reason += "：" + lastReason
+	}
+	return Decision{
+		Destination: UnclearRoute,
+		Source:      c.stages[len(c.stages)-1].Name,
+		Reason:      reason,
+		Candidates:  candidates,
+		Trail:       trail,
+		Notes:       notes,
+	}, nil
+}
+
+// ParseStageOrder: 解析逗号分隔的阶段顺序（如 "rule,embedding,llm"），校验名称合法且不重复
+func ParseStageOrder(spec string) ([]string, error) {
+	valid := map[string]bool{"rule": true, "embedding": true, "llm": true}
+	seen := map[string]bool{}
+	var order []string
+	for _, part := range strings.Split(spec, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if !valid[name] {
+			return nil, fmt.Errorf("未知的路由阶段: %s（可选 rule / embedding / llm）", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("路由阶段重复: %s", name)
+		}
+		seen[name] = true
+		order = append(order, name)
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("路由阶段为空")
+	}
+	return order, nil
+}
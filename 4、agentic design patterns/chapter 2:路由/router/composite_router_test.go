@@ -0,0 +1,182 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixed: 总是返回 decision 的路由
+func fixed(decision Decision) Router {
+	return Func(func(ctx context.Context, req Request) (Decision, error) {
+		return decision, nil
+	})
+}
+
+// noMatch: 总是未命中的路由
+var noMatch = Func(func(ctx context.Context, req Request) (Decision, error) {
+	return Decision{}, ErrNoMatch
+})
+
+func TestCompositeRouter(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		stages     []Stage
+		wantDest   string
+		wantSource string
+		wantTrail  []bool // wantTrail: 每一步是否被采纳
+	}{
+		{
+			name: "规则命中直接返回",
+			stages: []Stage{
+				{Name: "rule", Router: fixed(Decision{Destination: "booker", Confidence: 1})},
+				{Name: "llm", Router: fixed(Decision{Destination: "info", Confidence: 1})},
+			},
+			wantDest: "booker", wantSource: "rule", wantTrail: []bool{true},
+		},
+		{
+			name: "规则未命中交给嵌入",
+			stages: []Stage{
+				{Name: "rule", Router: noMatch},
+				{Name: "embedding", Threshold: 0.8, Router: fixed(Decision{Destination: "info", Confidence: 0.9})},
+			},
+			wantDest: "info", wantSource: "embedding", wantTrail: []bool{false, true},
+		},
+		{
+			name: "低于阈值交给 LLM 裁决",
+			stages: []Stage{
+				{Name: "rule", Router: noMatch},
+				{Name: "embedding", Threshold: 0.8, Router: fixed(Decision{Destination: "info", Confidence: 0.7})},
+				{Name: "llm", Router: fixed(Decision{Destination: "booker", Confidence: 0.9})},
+			},
+			wantDest: "booker", wantSource: "llm", wantTrail: []bool{false, false, true},
+		},
+		{
+			name: "unclear 不被采纳",
+			stages: []Stage{
+				{Name: "embedding", Router: fixed(Decision{Destination: UnclearRoute, Confidence: 0.9})},
+				{Name: "llm", Router: fixed(Decision{Destination: "info", Confidence: 0.9})},
+			},
+			wantDest: "info", wantSource: "llm", wantTrail: []bool{false, true},
+		},
+		{
+			name: "全部未采纳时路由到 unclear",
+			stages: []Stage{
+				{Name: "rule", Router: noMatch},
+				{Name: "llm", Threshold: 0.6, Router: fixed(Decision{Destination: "booker", Confidence: 0.3})},
+			},
+			wantDest: UnclearRoute, wantSource: "llm", wantTrail: []bool{false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewCompositeRouter(tt.stages...)
+			if err != nil {
+				t.Fatalf("NewCompositeRouter: %v", err)
+			}
+			d, err := r.Route(ctx, Request{Text: "请求"})
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if d.Destination != tt.wantDest || d.Source != tt.wantSource {
+				t.Errorf("Route = %s（%s），want %s（%s）", d.Destination, d.Source, tt.wantDest, tt.wantSource)
+			}
+			var accepted []bool
+			for _, step := range d.Trail {
+				accepted = append(accepted, step.Accepted)
+			}
+			if !reflect.DeepEqual(accepted, tt.wantTrail) {
+				t.Errorf("Trail = %v, want %v", d.Trail, tt.wantTrail)
+			}
+		})
+	}
+}
+
+func TestCompositeRouterUnclearCollectsCandidatesAndReason(t *testing.T) {
+	r, err := NewCompositeRouter(
+		Stage{Name: "embedding", Threshold: 0.8, Router: fixed(Decision{Destination: "info", Confidence: 0.5, Reason: "相似度低", Candidates: []string{"info", "booker"}})},
+		Stage{Name: "llm", Threshold: 0.6, Router: fixed(Decision{Destination: "refund", Confidence: 0.4, Reason: "可能是退款", Notes: []string{"严格模式重试"}})},
+	)
+	if err != nil {
+		t.Fatalf("NewCompositeRouter: %v", err)
+	}
+	d, err := r.Route(context.Background(), Request{Text: "请求"})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if want := []string{"info", "booker", "refund"}; !reflect.DeepEqual(d.Candidates, want) {
+		t.Errorf("Candidates = %v, want %v", d.Candidates, want)
+	}
+	if d.Reason != "所有阶段均未给出足够置信的决策：可能是退款" {
+		t.Errorf("Reason = %q", d.Reason)
+	}
+	if !reflect.DeepEqual(d.Notes, []string{"严格模式重试"}) {
+		t.Errorf("Notes = %v，应合并各阶段的诊断信息", d.Notes)
+	}
+}
+
+func TestCompositeRouterStageError(t *testing.T) {
+	r, err := NewCompositeRouter(
+		Stage{Name: "rule", Router: noMatch},
+		Stage{Name: "llm", Router: Func(func(ctx context.Context, req Request) (Decision, error) {
+			return Decision{}, errors.New("模型不可用")
+		})},
+	)
+	if err != nil {
+		t.Fatalf("NewCompositeRouter: %v", err)
+	}
+	d, err := r.Route(context.Background(), Request{Text: "请求"})
+	if err == nil || !strings.Contains(err.Error(), "llm 阶段失败") {
+		t.Fatalf("Route 错误 = %v", err)
+	}
+	if len(d.Trail) != 1 || d.Trail[0].Stage != "rule" {
+		t.Errorf("失败时应返回已执行的轨迹: %v", d.Trail)
+	}
+}
+
+func TestNewCompositeRouterRequiresStages(t *testing.T) {
+	if _, err := NewCompositeRouter(); err == nil {
+		t.Error("没有阶段时应返回错误")
+	}
+}
+
+func TestStageTrailString(t *testing.T) {
+	if got := (StageTrail{Stage: "rule", Note: "未命中"}).String(); got != "rule: 跳过（未命中）" {
+		t.Errorf("String = %q", got)
+	}
+	got := StageTrail{Stage: "llm", Decision: "booker", Confidence: 0.9, Accepted: true, Note: "决定"}.String()
+	if got != "llm: 采纳 booker（置信度 0.90，决定）" {
+		t.Errorf("String = %q", got)
+	}
+}
+
+func TestParseStageOrder(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr string
+	}{
+		{"rule,embedding,llm", []string{"rule", "embedding", "llm"}, ""},
+		{" LLM , rule ,", []string{"llm", "rule"}, ""},
+		{"rule,dual", nil, "未知的路由阶段"},
+		{"rule,rule", nil, "路由阶段重复"},
+		{" , ", nil, "路由阶段为空"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseStageOrder(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseStageOrder 错误 = %v，want 包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStageOrder = %v, %v，want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// DualVote: 双路由中单个路由器的投票
+type DualVote struct {
+	Voter    string // Voter: 投票的阶段名称（llm / embedding）
+	Decision Decision
+	Matched  bool // Matched: 该阶段是否给出了决策（返回 ErrNoMatch 时为 false）
+}
+
+// String: 格式化投票，用于日志和仲裁提示词
+func (v DualVote) String() string {
+	if !v.Matched {
+		return fmt.Sprintf("%s: 未给出决策", v.Voter)
+	}
+	s := fmt.Sprintf("%s: %s（置信度 %.2f）", v.Voter, v.Decision.Destination, v.Decision.Confidence)
+	if v.Decision.Reason != "" {
+		s += " " + v.Decision.Reason
+	}
+	return s
+}
+
+// DualArbiter: 两个路由器投票不一致时做出最终决定
+type DualArbiter func(ctx context.Context, req Request, votes []DualVote) (Decision, error)
+
+// DualRouter: 双路由，两个路由器并发投票
+//   - 两票一致时直接采纳
+//   - 不一致（包括任一方未给出决策）时交给仲裁者，仲裁置信度低于阈值时路由到 unclear
+type DualRouter struct {
+	voters    []string
+	graph     compose.Runnable[Request, map[string]any]
+	arbiter   DualArbiter
+	threshold float64 // threshold: 仲裁结果的置信度阈值
+	metrics   *RouterMetrics
+}
+
+// NewDualRouter: 创建双路由，first 和 second 作为并行图中的两个节点并发执行（只使用阶段的名称和路由器）；metrics 可为 nil
+func NewDualRouter(ctx context.Context, first, second Stage, arbiter DualArbiter, threshold float64, metrics *RouterMetrics) (*DualRouter, error) {
+	if first.Name == second.Name {
+		return nil, fmt.Errorf("双路由的两个阶段不能同名: %s", first.Name)
+	}
+	if arbiter == nil {
+		return nil, fmt.Errorf("双路由缺少仲裁者")
+	}
+
+	// 与第 3 章相同的并行图：每个投票者一个节点，都从 START 出发、连到 END，由 WithOutputKey 合并输出
+	graph := compose.NewGraph[Request, map[string]any]()
+	for _, stage := range []Stage{first, second} {
+		stage := stage
+		vote := compose.InvokableLambda(func(ctx context.Context, req Request) (DualVote, error) {
+			decision, err := stage.Router.Route(ctx, req)
+			if errors.Is(err, ErrNoMatch) {
+				return DualVote{Voter: stage.Name}, nil
+			}
+			if err != nil {
+				return DualVote{}, fmt.Errorf("%s 投票失败: %w", stage.Name, err)
+			}
+			return DualVote{Voter: stage.Name, Decision: decision, Matched: true}, nil
+		})
+		if err := graph.AddLambdaNode(stage.Name, vote, compose.WithOutputKey(stage.Name)); err != nil {
+			return nil, fmt.Errorf("添加 %s 投票节点失败: %w", stage.Name, err)
+		}
+		if err := graph.AddEdge(compose.START, stage.Name); err != nil {
+			return nil, fmt.Errorf("添加 START->%s 边失败: %w", stage.Name, err)
+		}
+		if err := graph.AddEdge(stage.Name, compose.END); err != nil {
+			return nil, fmt.Errorf("添加 %s->END 边失败: %w", stage.Name, err)
+		}
+	}
+
+	// AllPredecessor 触发模式确保两票都完成后再返回
+	compiled, err := graph.Compile(ctx, compose.WithNodeTriggerMode(compose.AllPredecessor))
+	if err != nil {
+		return nil, fmt.Errorf("编译投票并行图失败: %w", err)
+	}
+
+	return &DualRouter{
+		voters:    []string{first.Name, second.Name},
+		graph:     compiled,
+		arbiter:   arbiter,
+		threshold: threshold,
+		metrics:   metrics,
+	}, nil
+}
+
+// Route: 并发收集两票，一致时采纳，否则交给仲裁者
+func (d *DualRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	out, err := d.graph.Invoke(ctx, req)
+	if err != nil {
+		return Decision{}, fmt.Errorf("投票并行图执行失败: %w", err)
+	}
+
+	votes := make([]DualVote, 0, len(d.voters))
+	notes := make([]string, 0, len(d.voters)+1)
+	for _, name := range d.voters {
+		vote, ok := out[name].(DualVote)
+		if !ok {
+			return Decision{}, fmt.Errorf("缺少 %s 的投票", name)
+		}
+		notes = append(notes, "投票 "+vote.String())
+		votes = append(votes, vote)
+	}
+
+	a, b := votes[0], votes[1]
+	agree := a.Matched && b.Matched && a.Decision.Destination == b.Decision.Destination
+	if d.metrics != nil {
+		d.metrics.RecordDualVote(agree)
+	}
+	if agree {
+		// 置信度取两票中较高者（两个路由器的置信度刻度不同，仅供参考）
+		confidence := a.Decision.Confidence
+		if b.Decision.Confidence > confidence {
+			confidence = b.Decision.Confidence
+		}
+		return Decision{
+			Destination: a.Decision.Destination,
+			Source:      "dual",
+			Confidence:  confidence,
+			Reason:      fmt.Sprintf("%s 与 %s 一致", a.Voter, b.Voter),
+			Notes:       notes,
+		}, nil
+	}
+
+	notes = append(notes, "投票不一致，交给仲裁者")
+	decision, err := d.arbiter(ctx, req, votes)
+	if err != nil {
+		return Decision{}, fmt.Errorf("仲裁失败: %w", err)
+	}
+	decision.Notes = append(notes, decision.Notes...)
+	if decision.Confidence < d.threshold {
+		decision.Reason = fmt.Sprintf("仲裁置信度 %.2f 低于阈值 %.2f（%s）", decision.Confidence, d.threshold, decision.Reason)
+		decision.Destination = UnclearRoute
+	}
+	decision.Source = "dual"
+	decision.Reason = "仲裁：" + decision.Reason
+	// 两票的目的地都是候选
+	decision.Candidates = mergeCandidates(decision.Candidates,
+		[]string{a.Decision.Destination, b.Decision.Destination}, a.Decision.Candidates, b.Decision.Candidates)
+	return decision, nil
+}
+
+// formatVotes: 将投票格式化为仲裁提示词中的列表
+func formatVotes(votes []DualVote) string {
+	lines := make([]string, 0, len(votes))
+	for _, v := range votes {
+		lines = append(lines, "     - "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,205 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingArbiter: 记录收到的投票并返回固定决策
+type recordingArbiter struct {
+	decision Decision
+	votes    []DualVote
+	calls    int
+}
+
+func (a *recordingArbiter) arbitrate(ctx context.Context, req Request, votes []DualVote) (Decision, error) {
+	a.calls++
+	a.votes = votes
+	return a.decision, nil
+}
+
+func TestDualRouter(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name        string
+		llm         Router
+		embedding   Router
+		arbiter     Decision
+		wantDest    string
+		wantArbiter bool
+	}{
+		{
+			name:      "两票一致直接采纳",
+			llm:       fixed(Decision{Destination: "booker", Confidence: 0.8}),
+			embedding: fixed(Decision{Destination: "booker", Confidence: 0.9}),
+			wantDest:  "booker",
+		},
+		{
+			name:        "不一致交给仲裁者",
+			llm:         fixed(Decision{Destination: "booker", Confidence: 0.8}),
+			embedding:   fixed(Decision{Destination: "info", Confidence: 0.7}),
+			arbiter:     Decision{Destination: "info", Confidence: 0.9, Reason: "问的是信息"},
+			wantDest:    "info",
+			wantArbiter: true,
+		},
+		{
+			name:        "一方未给出决策也交给仲裁者",
+			llm:         fixed(Decision{Destination: "booker", Confidence: 0.8}),
+			embedding:   noMatch,
+			arbiter:     Decision{Destination: "booker", Confidence: 0.9},
+			wantDest:    "booker",
+			wantArbiter: true,
+		},
+		{
+			name:        "仲裁置信度不足时路由到 unclear",
+			llm:         fixed(Decision{Destination: "booker", Confidence: 0.8}),
+			embedding:   fixed(Decision{Destination: "info", Confidence: 0.7}),
+			arbiter:     Decision{Destination: "info", Confidence: 0.3},
+			wantDest:    UnclearRoute,
+			wantArbiter: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := NewRouterMetrics()
+			arbiter := &recordingArbiter{decision: tt.arbiter}
+			r, err := NewDualRouter(ctx, Stage{Name: "llm", Router: tt.llm}, Stage{Name: "embedding", Router: tt.embedding},
+				arbiter.arbitrate, DefaultConfidenceThreshold, metrics)
+			if err != nil {
+				t.Fatalf("NewDualRouter: %v", err)
+			}
+			d, err := r.Route(ctx, Request{Text: "请求"})
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if d.Destination != tt.wantDest || d.Source != "dual" {
+				t.Errorf("Route = %s（%s），want %s", d.Destination, d.Source, tt.wantDest)
+			}
+			if (arbiter.calls == 1) != tt.wantArbiter {
+				t.Errorf("仲裁者调用 %d 次，want 调用: %v", arbiter.calls, tt.wantArbiter)
+			}
+			if snap := metrics.Snapshot(); snap.DualVotes != 1 || (snap.AgreementRate == 1) == tt.wantArbiter {
+				t.Errorf("投票指标 = %d 次，一致率 %v", snap.DualVotes, snap.AgreementRate)
+			}
+			if tt.wantArbiter {
+				if len(arbiter.votes) != 2 || arbiter.votes[0].Voter != "llm" || arbiter.votes[1].Voter != "embedding" {
+					t.Errorf("仲裁者收到的投票 = %+v", arbiter.votes)
+				}
+				if !strings.HasPrefix(d.Reason, "仲裁：") || d.Notes[len(d.Notes)-1] != "投票不一致，交给仲裁者" {
+					t.Errorf("Reason = %q, Notes = %v", d.Reason, d.Notes)
+				}
+			}
+		})
+	}
+}
+
+func TestDualRouterCandidates(t *testing.T) {
+	ctx := context.Background()
+	arbiter := &recordingArbiter{decision: Decision{Destination: UnclearRoute, Confidence: 0.9}}
+	r, err := NewDualRouter(ctx,
+		Stage{Name: "llm", Router: fixed(Decision{Destination: "booker", Confidence: 0.8, Candidates: []string{"refund"}})},
+		Stage{Name: "embedding", Router: fixed(Decision{Destination: "info", Confidence: 0.7})},
+		arbiter.arbitrate, DefaultConfidenceThreshold, nil)
+	if err != nil {
+		t.Fatalf("NewDualRouter: %v", err)
+	}
+	d, err := r.Route(ctx, Request{Text: "请求"})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if want := []string{"booker", "info", "refund"}; !reflect.DeepEqual(d.Candidates, want) {
+		t.Errorf("Candidates = %v, want %v（两票的目的地都是候选）", d.Candidates, want)
+	}
+}
+
+// TestDualRouterVotesConcurrently: 两个投票者互相等待对方开始，串行执行会超时
+func TestDualRouterVotesConcurrently(t *testing.T) {
+	ctx := context.Background()
+	llmStarted, embStarted := make(chan struct{}), make(chan struct{})
+	waitFor := func(mine, other chan struct{}, dest string) Router {
+		return Func(func(ctx context.Context, req Request) (Decision, error) {
+			close(mine)
+			select {
+			case <-other:
+				return Decision{Destination: dest, Confidence: 0.9}, nil
+			case <-time.After(time.Second):
+				return Decision{}, errors.New("另一个投票者没有并发执行")
+			}
+		})
+	}
+	arbiter := &recordingArbiter{}
+	r, err := NewDualRouter(ctx,
+		Stage{Name: "llm", Router: waitFor(llmStarted, embStarted, "info")},
+		Stage{Name: "embedding", Router: waitFor(embStarted, llmStarted, "info")},
+		arbiter.arbitrate, DefaultConfidenceThreshold, nil)
+	if err != nil {
+		t.Fatalf("NewDualRouter: %v", err)
+	}
+	if d, err := r.Route(ctx, Request{Text: "请求"}); err != nil || d.Destination != "info" {
+		t.Errorf("Route = %+v, %v", d, err)
+	}
+}
+
+func TestDualRouterErrors(t *testing.T) {
+	ctx := context.Background()
+	arbiter := &recordingArbiter{}
+	if _, err := NewDualRouter(ctx, Stage{Name: "llm", Router: noMatch}, Stage{Name: "llm", Router: noMatch}, arbiter.arbitrate, 0, nil); err == nil {
+		t.Error("同名阶段应返回错误")
+	}
+	if _, err := NewDualRouter(ctx, Stage{Name: "llm", Router: noMatch}, Stage{Name: "embedding", Router: noMatch}, nil, 0, nil); err == nil {
+		t.Error("缺少仲裁者应返回错误")
+	}
+
+	failing := Func(func(ctx context.Context, req Request) (Decision, error) {
+		return Decision{}, errors.New("嵌入服务不可用")
+	})
+	r, err := NewDualRouter(ctx, Stage{Name: "llm", Router: noMatch}, Stage{Name: "embedding", Router: failing}, arbiter.arbitrate, 0, nil)
+	if err != nil {
+		t.Fatalf("NewDualRouter: %v", err)
+	}
+	if _, err := r.Route(ctx, Request{Text: "请求"}); err == nil || !strings.Contains(err.Error(), "嵌入服务不可用") {
+		t.Errorf("Route 错误 = %v", err)
+	}
+}
+
+func TestLLMArbiter(t *testing.T) {
+	ctx := context.Background()
+	votes := []DualVote{
+		{Voter: "llm", Decision: Decision{Destination: "booker", Confidence: 0.8, Reason: "订票"}, Matched: true},
+		{Voter: "embedding"},
+	}
+
+	llm := &fakeChatModel{replies: []string{`{"destination": "booker", "confidence": 0.9, "reason": "采纳 llm"}`}}
+	arbiter, err := NewLLMArbiter(ctx, llm, newTestRegistry(t), nil)
+	if err != nil {
+		t.Fatalf("NewLLMArbiter: %v", err)
+	}
+	d, err := arbiter(ctx, Request{Text: "请求"}, votes)
+	if err != nil || d.Destination != "booker" || d.Confidence != 0.9 {
+		t.Fatalf("arbiter = %+v, %v", d, err)
+	}
+	if !strings.Contains(llm.systems[0], "- llm: booker（置信度 0.80） 订票") || !strings.Contains(llm.systems[0], "- embedding: 未给出决策") {
+		t.Errorf("仲裁提示词应列出双方投票:\n%s", llm.systems[0])
+	}
+
+	metrics := NewRouterMetrics()
+	invalid := &fakeChatModel{replies: []string{"我觉得是订票"}}
+	arbiter, err = NewLLMArbiter(ctx, invalid, newTestRegistry(t), metrics)
+	if err != nil {
+		t.Fatalf("NewLLMArbiter: %v", err)
+	}
+	d, err = arbiter(ctx, Request{Text: "请求"}, votes)
+	if err != nil || d.Destination != UnclearRoute || len(d.Notes) != 1 || !strings.Contains(d.Notes[0], "我觉得是订票") {
+		t.Errorf("无效的仲裁输出应交给 unclear 并记录原始输出: %+v, %v", d, err)
+	}
+	if invalid.callCount() != 1 {
+		t.Errorf("仲裁输出无效时不应重试，调用 %d 次", invalid.callCount())
+	}
+	if snap := metrics.Snapshot(); snap.InvalidLabels != 1 {
+		t.Errorf("无效标签应计入指标: %+v", snap)
+	}
+}
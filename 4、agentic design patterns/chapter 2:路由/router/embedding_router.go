@@ -0,0 +1,134 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// DefaultEmbeddingThreshold: 嵌入路由的默认相似度阈值，最高分低于该值时路由到 unclear
+const DefaultEmbeddingThreshold = 0.5
+
+// RouteScore: 单个路由的相似度得分（取该路由所有示例中的最高余弦相似度）
+type RouteScore struct {
+	Route string
+	Score float64
+}
+
+// EmbeddingRouter: 嵌入路由
+// 启动时一次性向量化所有路由示例，之后每个请求只需一次 Embedding 调用，
+// 选出余弦相似度最高且超过阈值的路由，否则归为 unclear（示例来自注册表中各路由的 Exemplars）
+type EmbeddingRouter struct {
+	embedder  embedding.Embedder
+	threshold float64
+	routes    []string               // routes: 按名称排序的路由列表，保证得分输出顺序稳定
+	vectors   map[string][][]float64 // vectors: 路由 -> 示例向量
+}
+
+// NewEmbeddingRouter: 创建嵌入路由，并向量化所有示例
+func NewEmbeddingRouter(ctx context.Context, embedder embedding.Embedder, exemplars map[string][]string, threshold float64) (*EmbeddingRouter, error) {
+	r := &EmbeddingRouter{
+		embedder:  embedder,
+		threshold: threshold,
+		vectors:   make(map[string][][]float64, len(exemplars)),
+	}
+	if len(exemplars) == 0 {
+		return nil, fmt.Errorf("嵌入路由至少需要一个带示例的路由")
+	}
+	for route := range exemplars {
+		r.routes = append(r.routes, route)
+	}
+	sort.Strings(r.routes)
+
+	for _, route := range r.routes {
+		texts := exemplars[route]
+		if len(texts) == 0 {
+			return nil, fmt.Errorf("路由 %s 没有示例语句", route)
+		}
+		vecs, err := embedder.EmbedStrings(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("向量化路由 %s 的示例失败: %w", route, err)
+		}
+		if len(vecs) != len(texts) {
+			return nil, fmt.Errorf("路由 %s: 期望 %d 个向量，实际返回 %d 个", route, len(texts), len(vecs))
+		}
+		r.vectors[route] = vecs
+	}
+	return r, nil
+}
+
+// Scores: 计算请求与每个路由的相似度，按得分降序返回
+func (r *EmbeddingRouter) Scores(ctx context.Context, request string) ([]RouteScore, error) {
+	vecs, err := r.embedder.EmbedStrings(ctx, []string{request})
+	if err != nil {
+		return nil, fmt.Errorf("向量化请求失败: %w", err)
+	}
+	if len(vecs) != 1 {
+		return nil, fmt.Errorf("期望 1 个请求向量，实际返回 %d 个", len(vecs))
+	}
+
+	scores := make([]RouteScore, 0, len(r.routes))
+	for _, route := range r.routes {
+		best := -1.0
+		for _, v := range r.vectors[route] {
+			if s := cosineSimilarity(vecs[0], v); s > best {
+				best = s
+			}
+		}
+		scores = append(scores, RouteScore{Route: route, Score: best})
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores, nil
+}
+
+// Route: 选出相似度最高的路由（只看当前请求，不看历史），各路由得分写入决策理由
+func (r *EmbeddingRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	scores, err := r.Scores(ctx, req.Text)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	parts := make([]string, 0, len(scores))
+	var candidates []string
+	for _, s := range scores {
+		parts = append(parts, fmt.Sprintf("%s %.4f", s.Route, s.Score))
+		// 候选取得分最高的两个非 unclear 路由
+		if s.Route != UnclearRoute && len(candidates) < 2 {
+			candidates = append(candidates, s.Route)
+		}
+	}
+	decision := Decision{
+		Destination: scores[0].Route,
+		Source:      "embedding",
+		Confidence:  scores[0].Score,
+		Reason:      "相似度 " + strings.Join(parts, " / "),
+		Candidates:  candidates,
+	}
+	// 最高分低于阈值时，说明请求与任何路由都不够接近，交给 unclear 处理
+	if decision.Confidence < r.threshold {
+		decision.Destination = UnclearRoute
+		decision.Reason = fmt.Sprintf("最高相似度低于阈值 %.2f，%s", r.threshold, decision.Reason)
+	}
+	return decision, nil
+}
+
+// cosineSimilarity: 计算两个向量的余弦相似度（维度不一致或零向量时返回 0）
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
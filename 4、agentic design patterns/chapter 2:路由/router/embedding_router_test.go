@@ -0,0 +1,219 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// fakeEmbedder: 按预设表返回向量，未登记的文本返回 fallback，并记录每次调用的文本数
+type fakeEmbedder struct {
+	vectors  map[string][]float64
+	fallback []float64
+	err      error
+	calls    []int
+}
+
+func (e *fakeEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	e.calls = append(e.calls, len(texts))
+	if e.err != nil {
+		return nil, e.err
+	}
+	out := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		if v, ok := e.vectors[text]; ok {
+			out = append(out, v)
+			continue
+		}
+		out = append(out, e.fallback)
+	}
+	return out, nil
+}
+
+// newTestEmbedder: booker 指向 x 轴，info 指向 y 轴，unclear 指向 z 轴
+func newTestEmbedder() *fakeEmbedder {
+	return &fakeEmbedder{
+		vectors: map[string][]float64{
+			"订机票":  {1, 0, 0},
+			"订酒店":  {0.9, 0.1, 0},
+			"首都是哪": {0, 1, 0},
+			"你好":   {0, 0, 1},
+			"帮我订票": {0.95, 0.05, 0},
+			"天气如何": {0.1, 0.9, 0},
+			"模棱两可": {0.3, 0.3, 0.3},
+		},
+		fallback: []float64{0, 0, 0},
+	}
+}
+
+var testExemplars = map[string][]string{
+	"booker":  {"订机票", "订酒店"},
+	"info":    {"首都是哪"},
+	"unclear": {"你好"},
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"相同方向", []float64{1, 2}, []float64{2, 4}, 1},
+		{"正交", []float64{1, 0}, []float64{0, 1}, 0},
+		{"相反方向", []float64{1, 0}, []float64{-1, 0}, -1},
+		{"维度不一致", []float64{1, 0}, []float64{1, 0, 0}, 0},
+		{"零向量", []float64{0, 0}, []float64{1, 0}, 0},
+		{"空向量", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cosineSimilarity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEmbeddingRouterVectorizesExemplarsOnce(t *testing.T) {
+	ctx := context.Background()
+	embedder := newTestEmbedder()
+	r, err := NewEmbeddingRouter(ctx, embedder, testExemplars, DefaultEmbeddingThreshold)
+	if err != nil {
+		t.Fatalf("NewEmbeddingRouter: %v", err)
+	}
+	// 每个路由一次批量调用
+	if len(embedder.calls) != len(testExemplars) {
+		t.Fatalf("启动时调用 %d 次，want %d", len(embedder.calls), len(testExemplars))
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.Route(ctx, Request{Text: "帮我订票"}); err != nil {
+			t.Fatalf("Route: %v", err)
+		}
+	}
+	// 之后每个请求只需一次单条调用
+	if got := embedder.calls[len(testExemplars):]; len(got) != 3 || got[0] != 1 {
+		t.Errorf("请求阶段的调用 = %v，want 3 次单条调用", got)
+	}
+}
+
+func TestNewEmbeddingRouterErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		embedder  embedding.Embedder
+		exemplars map[string][]string
+		wantErr   string
+	}{
+		{"没有示例", newTestEmbedder(), nil, "至少需要一个"},
+		{"路由示例为空", newTestEmbedder(), map[string][]string{"booker": {}}, "没有示例语句"},
+		{"向量化失败", &fakeEmbedder{err: errors.New("quota")}, testExemplars, "quota"},
+		{"向量数量不符", shortEmbedder{}, testExemplars, "期望 2 个向量"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEmbeddingRouter(ctx, tt.embedder, tt.exemplars, 0.5)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewEmbeddingRouter 错误 = %v，want 包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// shortEmbedder: 总是只返回一个向量
+type shortEmbedder struct{}
+
+func (shortEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	return [][]float64{{1, 0, 0}}, nil
+}
+
+func TestEmbeddingRouterRoute(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewEmbeddingRouter(ctx, newTestEmbedder(), testExemplars, DefaultEmbeddingThreshold)
+	if err != nil {
+		t.Fatalf("NewEmbeddingRouter: %v", err)
+	}
+
+	tests := []struct {
+		request        string
+		wantDest       string
+		wantCandidates []string
+	}{
+		{"帮我订票", "booker", []string{"booker", "info"}},
+		{"天气如何", "info", []string{"info", "booker"}},
+		{"未登记的请求", UnclearRoute, nil}, // 零向量：所有得分为 0，低于阈值
+	}
+	for _, tt := range tests {
+		t.Run(tt.request, func(t *testing.T) {
+			d, err := r.Route(ctx, Request{Text: tt.request})
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if d.Destination != tt.wantDest || d.Source != "embedding" {
+				t.Errorf("Route = %s（来源 %s），want %s", d.Destination, d.Source, tt.wantDest)
+			}
+			if tt.wantCandidates != nil && strings.Join(d.Candidates, ",") != strings.Join(tt.wantCandidates, ",") {
+				t.Errorf("Candidates = %v, want %v", d.Candidates, tt.wantCandidates)
+			}
+			if !strings.Contains(d.Reason, "相似度") {
+				t.Errorf("Reason 应列出各路由的相似度: %q", d.Reason)
+			}
+		})
+	}
+}
+
+func TestEmbeddingRouterThreshold(t *testing.T) {
+	ctx := context.Background()
+	// "模棱两可" 与 booker 的相似度约 0.57
+	strict, err := NewEmbeddingRouter(ctx, newTestEmbedder(), testExemplars, 0.9)
+	if err != nil {
+		t.Fatalf("NewEmbeddingRouter: %v", err)
+	}
+	d, err := strict.Route(ctx, Request{Text: "模棱两可"})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if d.Destination != UnclearRoute || !strings.Contains(d.Reason, "低于阈值 0.90") {
+		t.Errorf("高阈值下 Route = %s（%s），want unclear", d.Destination, d.Reason)
+	}
+	if d.Confidence <= 0 || d.Confidence >= 0.9 {
+		t.Errorf("Confidence = %v，应保留最高相似度", d.Confidence)
+	}
+}
+
+func TestEmbeddingRouterScoresSorted(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewEmbeddingRouter(ctx, newTestEmbedder(), testExemplars, DefaultEmbeddingThreshold)
+	if err != nil {
+		t.Fatalf("NewEmbeddingRouter: %v", err)
+	}
+	scores, err := r.Scores(ctx, "订酒店")
+	if err != nil {
+		t.Fatalf("Scores: %v", err)
+	}
+	if len(scores) != 3 || scores[0].Route != "booker" || math.Abs(scores[0].Score-1) > 1e-9 {
+		t.Fatalf("Scores = %v，want booker 得分 1 排第一", scores)
+	}
+	for i := 1; i < len(scores); i++ {
+		if scores[i].Score > scores[i-1].Score {
+			t.Errorf("Scores 未按降序排列: %v", scores)
+		}
+	}
+}
+
+func TestEmbeddingRouterRequestError(t *testing.T) {
+	ctx := context.Background()
+	embedder := newTestEmbedder()
+	r, err := NewEmbeddingRouter(ctx, embedder, testExemplars, DefaultEmbeddingThreshold)
+	if err != nil {
+		t.Fatalf("NewEmbeddingRouter: %v", err)
+	}
+	embedder.err = errors.New("timeout")
+	if _, err := r.Route(ctx, Request{Text: "订机票"}); err == nil || !strings.Contains(err.Error(), "向量化请求失败") {
+		t.Errorf("Route 错误 = %v，want 向量化请求失败", err)
+	}
+}
@@ -0,0 +1,186 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// EvalExample: 评测集中的一条样本
+type EvalExample struct {
+	Request       string `json:"request"`
+	ExpectedRoute string `json:"expected_route"`
+}
+
+// EvalResult: 单条样本的评测结果
+type EvalResult struct {
+	Request    string  `json:"request"`
+	Expected   string  `json:"expected"`
+	Predicted  string  `json:"predicted"`
+	Source     string  `json:"source,omitempty"`
+	Confidence float64 `json:"confidence"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// EvalReport: 评测报告
+type EvalReport struct {
+	Total     int                       `json:"total"`
+	Correct   int                       `json:"correct"`
+	Errors    int                       `json:"errors"` // Errors: 路由本身失败的样本数（计为错误预测）
+	Accuracy  float64                   `json:"accuracy"`
+	Labels    []string                  `json:"labels"`
+	Confusion map[string]map[string]int `json:"confusion"` // Confusion: 期望路由 -> 预测路由 -> 次数
+	Misrouted []EvalResult              `json:"misrouted"`
+	Results   []EvalResult              `json:"results"`
+}
+
+// LoadEvalExamples: 读取 JSONL 评测集（每行一个 {"request", "expected_route"}，空行忽略）
+func LoadEvalExamples(path string) ([]EvalExample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开评测集失败: %w", err)
+	}
+	defer f.Close()
+
+	var examples []EvalExample
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var ex EvalExample
+		if err := json.Unmarshal([]byte(line), &ex); err != nil {
+			return nil, fmt.Errorf("评测集第 %d 行解析失败: %w", lineNo, err)
+		}
+		if ex.Request == "" || ex.ExpectedRoute == "" {
+			return nil, fmt.Errorf("评测集第 %d 行缺少 request 或 expected_route", lineNo)
+		}
+		examples = append(examples, ex)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取评测集失败: %w", err)
+	}
+	return examples, nil
+}
+
+// RunEval: 以有限并发用 r 评测所有样本，结果按样本顺序排列
+// r 可以是任意路由实现（组合路由、单一路由或测试用的模拟路由），样本不带对话历史
+func RunEval(ctx context.Context, examples []EvalExample, concurrency int, r Router) EvalReport {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]EvalResult, len(examples))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, ex := range examples {
+		wg.Add(1)
+		go func(i int, ex EvalExample) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			res := EvalResult{Request: ex.Request, Expected: ex.ExpectedRoute}
+			decision, err := r.Route(ctx, Request{Text: ex.Request})
+			if err != nil {
+				res.Predicted = "error"
+				res.Error = err.Error()
+			} else {
+				res.Predicted = decision.Destination
+				res.Source = decision.Source
+				res.Confidence = decision.Confidence
+			}
+			results[i] = res
+		}(i, ex)
+	}
+	wg.Wait()
+
+	report := EvalReport{
+		Total:     len(results),
+		Confusion: make(map[string]map[string]int),
+		Results:   results,
+	}
+	labels := map[string]bool{}
+	for _, res := range results {
+		labels[res.Expected] = true
+		labels[res.Predicted] = true
+		if report.Confusion[res.Expected] == nil {
+			report.Confusion[res.Expected] = make(map[string]int)
+		}
+		report.Confusion[res.Expected][res.Predicted]++
+
+		switch {
+		case res.Error != "":
+			report.Errors++
+			report.Misrouted = append(report.Misrouted, res)
+		case res.Predicted == res.Expected:
+			report.Correct++
+		default:
+			report.Misrouted = append(report.Misrouted, res)
+		}
+	}
+	for l := range labels {
+		report.Labels = append(report.Labels, l)
+	}
+	sort.Strings(report.Labels)
+	if report.Total > 0 {
+		report.Accuracy = float64(report.Correct) / float64(report.Total)
+	}
+	return report
+}
+
+// Print: 打印准确率、混淆矩阵和错误路由的样本
+func (r EvalReport) Print(w io.Writer) {
+	fmt.Fprintln(w, "\n--- 路由评测结果 ---")
+	fmt.Fprintf(w, "样本数: %d | 正确: %d | 路由失败: %d | 准确率: %.1f%%\n",
+		r.Total, r.Correct, r.Errors, r.Accuracy*100)
+
+	fmt.Fprintln(w, "\n混淆矩阵（行: 期望，列: 预测）")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "\t"+strings.Join(r.Labels, "\t")+"\n")
+	for _, expected := range r.Labels {
+		row, ok := r.Confusion[expected]
+		if !ok {
+			continue
+		}
+		fmt.Fprint(tw, expected)
+		for _, predicted := range r.Labels {
+			fmt.Fprintf(tw, "\t%d", row[predicted])
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	if len(r.Misrouted) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n错误路由的样本:")
+	for _, res := range r.Misrouted {
+		if res.Error != "" {
+			fmt.Fprintf(w, "  - %q 期望 %s，路由失败: %s\n", res.Request, res.Expected, res.Error)
+			continue
+		}
+		fmt.Fprintf(w, "  - %q 期望 %s，预测 %s（来源 %s，置信度 %.2f）\n",
+			res.Request, res.Expected, res.Predicted, res.Source, res.Confidence)
+	}
+}
+
+// WriteJSON: 将评测报告写入 JSON 文件
+func (r EvalReport) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化评测报告失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("写入评测报告失败: %w", err)
+	}
+	return nil
+}
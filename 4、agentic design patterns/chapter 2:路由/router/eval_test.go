@@ -0,0 +1,147 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadEvalExamples(t *testing.T) {
+	examples, err := LoadEvalExamples(filepath.Join("..", "eval_dataset.jsonl"))
+	if err != nil {
+		t.Fatalf("LoadEvalExamples: %v", err)
+	}
+	if len(examples) == 0 || examples[0].ExpectedRoute != "booker" {
+		t.Errorf("示例评测集读取结果 = %v", examples)
+	}
+
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"空行忽略", "\n{\"request\": \"a\", \"expected_route\": \"info\"}\n\n", ""},
+		{"解析失败", "{\"request\": \"a\", \"expected_route\": \"info\"}\nnot json\n", "第 2 行解析失败"},
+		{"缺少字段", "{\"request\": \"a\"}\n", "第 1 行缺少 request 或 expected_route"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			examples, err := LoadEvalExamples(path)
+			if tt.wantErr == "" {
+				if err != nil || len(examples) != 1 {
+					t.Errorf("LoadEvalExamples = %v, %v", examples, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadEvalExamples 错误 = %v，want 包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// tableRouter: 按请求查表返回决策，"ERR" 返回错误
+type tableRouter map[string]string
+
+func (r tableRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	if req.Text == "ERR" {
+		return Decision{}, errors.New("路由失败")
+	}
+	return Decision{Destination: r[req.Text], Source: "llm", Confidence: 0.9}, nil
+}
+
+func TestRunEval(t *testing.T) {
+	examples := []EvalExample{
+		{"订机票", "booker"},
+		{"首都", "info"},
+		{"退款", "refund"},
+		{"ERR", "info"},
+	}
+	r := tableRouter{"订机票": "booker", "首都": "info", "退款": "booker"}
+	report := RunEval(context.Background(), examples, 2, r)
+
+	if report.Total != 4 || report.Correct != 2 || report.Errors != 1 || report.Accuracy != 0.5 {
+		t.Errorf("report = %d/%d（失败 %d，准确率 %v）", report.Correct, report.Total, report.Errors, report.Accuracy)
+	}
+	if !reflect.DeepEqual(report.Labels, []string{"booker", "error", "info", "refund"}) {
+		t.Errorf("Labels = %v", report.Labels)
+	}
+	if report.Confusion["refund"]["booker"] != 1 || report.Confusion["info"]["error"] != 1 || report.Confusion["booker"]["booker"] != 1 {
+		t.Errorf("Confusion = %v", report.Confusion)
+	}
+	if len(report.Misrouted) != 2 || report.Misrouted[0].Request != "退款" || report.Misrouted[1].Error == "" {
+		t.Errorf("Misrouted = %+v", report.Misrouted)
+	}
+	for i, res := range report.Results {
+		if res.Request != examples[i].Request {
+			t.Errorf("Results[%d] = %q，结果应按样本顺序排列", i, res.Request)
+		}
+	}
+}
+
+func TestRunEvalConcurrencyLimit(t *testing.T) {
+	var running, peak int32
+	r := Func(func(ctx context.Context, req Request) (Decision, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return Decision{Destination: "info"}, nil
+	})
+	examples := make([]EvalExample, 12)
+	for i := range examples {
+		examples[i] = EvalExample{Request: "问题", ExpectedRoute: "info"}
+	}
+	report := RunEval(context.Background(), examples, 3, r)
+	if report.Correct != 12 {
+		t.Errorf("Correct = %d", report.Correct)
+	}
+	if peak > 3 {
+		t.Errorf("最大并发 %d，超过限制 3", peak)
+	}
+}
+
+func TestEvalReportOutput(t *testing.T) {
+	report := RunEval(context.Background(), []EvalExample{{"订机票", "booker"}, {"退款", "refund"}}, 1,
+		tableRouter{"订机票": "booker", "退款": "booker"})
+
+	var buf bytes.Buffer
+	report.Print(&buf)
+	out := buf.String()
+	for _, want := range []string{"准确率: 50.0%", "混淆矩阵", `"退款" 期望 refund，预测 booker`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Print 输出缺少 %q:\n%s", want, out)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := report.WriteJSON(path); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded EvalReport
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Correct != 1 || decoded.Total != 2 {
+		t.Errorf("JSON 报告 = %+v, %v", decoded, err)
+	}
+}
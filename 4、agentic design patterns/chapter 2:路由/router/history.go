@@ -0,0 +1,104 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// MaxHistoryTurns: 路由提示词中最多携带的对话轮数（一轮 = 一条用户消息 + 一条助手回复），用于控制提示词长度
+const MaxHistoryTurns = 3
+
+// RecentHistory: 截取最近 maxTurns 轮对话（只保留用户和助手消息）
+func RecentHistory(history []*schema.Message, maxTurns int) []*schema.Message {
+	msgs := make([]*schema.Message, 0, len(history))
+	for _, msg := range history {
+		if msg != nil && (msg.Role == schema.User || msg.Role == schema.Assistant) {
+			msgs = append(msgs, msg)
+		}
+	}
+	if limit := maxTurns * 2; len(msgs) > limit {
+		msgs = msgs[len(msgs)-limit:]
+	}
+	return msgs
+}
+
+// FormatHistory: 将最近的对话历史格式化为带分隔标记的文本段，没有历史时返回空字符串
+// 该文本作为模板变量的值填入（不会被当作模板解析），因此内容中的花括号无需转义
+// 历史通常来自 ConversationMemory.RecentMessages
+func FormatHistory(history []*schema.Message, maxTurns int) string {
+	msgs := RecentHistory(history, maxTurns)
+	if len(msgs) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("以下是最近的对话历史，仅用于理解当前请求中的指代和省略：\n<history>\n")
+	for _, msg := range msgs {
+		role := "用户"
+		if msg.Role == schema.Assistant {
+			role = "助手"
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", role, strings.TrimSpace(msg.Content))
+	}
+	sb.WriteString("</history>\n\n当前请求：")
+	return sb.String()
+}
+
+// ConversationMemory: 会话的短期记忆，每轮对话结束后追加，路由前读取最近的历史
+// AddMessage 与第 8 章 ShortTermMemory.AddMessage 的签名相同，role 为 "user" 或 "assistant"；
+// 与第 8 章共用 Redis 的实现见 main 包的 RedisShortTermMemory，不需要持久化时使用 InMemoryHistory
+type ConversationMemory interface {
+	AddMessage(ctx context.Context, sessionID, role, content string) error
+	RecentMessages(ctx context.Context, sessionID string, maxTurns int) ([]*schema.Message, error)
+}
+
+// NewHistoryMessage: 按第 8 章的角色名（user / assistant）创建消息，其他角色返回错误
+func NewHistoryMessage(role, content string) (*schema.Message, error) {
+	switch role {
+	case "user":
+		return schema.UserMessage(content), nil
+	case "assistant":
+		return schema.AssistantMessage(content, nil), nil
+	}
+	return nil, fmt.Errorf("不支持的消息角色: %q", role)
+}
+
+// InMemoryHistory: 保存在进程内的 ConversationMemory（并发安全），每个会话最多保留 maxTurns 轮
+type InMemoryHistory struct {
+	mu       sync.Mutex
+	sessions map[string][]*schema.Message
+	maxTurns int
+}
+
+var _ ConversationMemory = (*InMemoryHistory)(nil)
+
+// NewInMemoryHistory: 创建进程内的会话记忆，maxTurns <= 0 时使用 MaxHistoryTurns
+func NewInMemoryHistory(maxTurns int) *InMemoryHistory {
+	if maxTurns <= 0 {
+		maxTurns = MaxHistoryTurns
+	}
+	return &InMemoryHistory{sessions: make(map[string][]*schema.Message), maxTurns: maxTurns}
+}
+
+// AddMessage: 追加一条消息，超出 maxTurns 轮的旧消息被丢弃
+func (h *InMemoryHistory) AddMessage(ctx context.Context, sessionID, role, content string) error {
+	msg, err := NewHistoryMessage(role, content)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions[sessionID] = RecentHistory(append(h.sessions[sessionID], msg), h.maxTurns)
+	return nil
+}
+
+// RecentMessages: 会话最近 maxTurns 轮对话，没有记录的会话返回空
+func (h *InMemoryHistory) RecentMessages(ctx context.Context, sessionID string, maxTurns int) ([]*schema.Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return RecentHistory(h.sessions[sessionID], maxTurns), nil
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// turns: 生成 n 轮对话（用户 i / 助手 i）
+func turns(n int) []*schema.Message {
+	var msgs []*schema.Message
+	for i := 1; i <= n; i++ {
+		msgs = append(msgs, schema.UserMessage(fmt.Sprintf("用户 %d", i)), schema.AssistantMessage(fmt.Sprintf("助手 %d", i), nil))
+	}
+	return msgs
+}
+
+func TestRecentHistory(t *testing.T) {
+	history := append([]*schema.Message{schema.SystemMessage("系统"), nil}, turns(4)...)
+	got := RecentHistory(history, 2)
+	if len(got) != 4 || got[0].Content != "用户 3" || got[3].Content != "助手 4" {
+		t.Errorf("RecentHistory 应只保留最近 2 轮用户和助手消息: %v", got)
+	}
+	if got := RecentHistory(turns(1), MaxHistoryTurns); len(got) != 2 {
+		t.Errorf("不足 maxTurns 时应全部保留: %v", got)
+	}
+}
+
+func TestFormatHistory(t *testing.T) {
+	if got := FormatHistory(nil, MaxHistoryTurns); got != "" {
+		t.Errorf("没有历史时应返回空字符串: %q", got)
+	}
+	got := FormatHistory([]*schema.Message{
+		schema.UserMessage(" 去伦敦的航班多少钱？ "),
+		schema.AssistantMessage("约 {4000} 元", nil),
+	}, MaxHistoryTurns)
+	want := "以下是最近的对话历史，仅用于理解当前请求中的指代和省略：\n<history>\n用户: 去伦敦的航班多少钱？\n助手: 约 {4000} 元\n</history>\n\n当前请求："
+	if got != want {
+		t.Errorf("FormatHistory =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestLLMRouterIncludesHistory(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeChatModel{replies: []string{`{"destination": "booker", "confidence": 0.9, "reason": "追问订票"}`}}
+	r, err := NewLLMRouter(ctx, llm, newTestRegistry(t), DefaultConfidenceThreshold, nil)
+	if err != nil {
+		t.Fatalf("NewLLMRouter: %v", err)
+	}
+	history := append(turns(4), schema.UserMessage("12 月 1 日飞伦敦多少钱"), schema.AssistantMessage("约 4000 元", nil))
+	if _, err := r.Route(ctx, Request{Text: "那帮我订一下吧", History: history}); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	input := llm.inputs[0]
+	if !strings.Contains(input, "<history>") || !strings.HasSuffix(input, "当前请求：那帮我订一下吧") {
+		t.Errorf("路由的用户消息应包含历史段和当前请求: %q", input)
+	}
+	if strings.Contains(input, "用户 1") || !strings.Contains(input, "用户 3") {
+		t.Errorf("只应携带最近 %d 轮对话: %q", MaxHistoryTurns, input)
+	}
+}
+
+func TestNewHistoryMessage(t *testing.T) {
+	if msg, err := NewHistoryMessage("user", "你好"); err != nil || msg.Role != schema.User {
+		t.Errorf("NewHistoryMessage(user) = %v, %v", msg, err)
+	}
+	if msg, err := NewHistoryMessage("assistant", "您好"); err != nil || msg.Role != schema.Assistant {
+		t.Errorf("NewHistoryMessage(assistant) = %v, %v", msg, err)
+	}
+	if _, err := NewHistoryMessage("system", "x"); err == nil {
+		t.Error("不支持的角色应返回错误")
+	}
+}
+
+func TestInMemoryHistory(t *testing.T) {
+	ctx := context.Background()
+	h := NewInMemoryHistory(2)
+	for i := 1; i <= 3; i++ {
+		if err := h.AddMessage(ctx, "s1", "user", fmt.Sprintf("问 %d", i)); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+		if err := h.AddMessage(ctx, "s1", "assistant", fmt.Sprintf("答 %d", i)); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+	}
+	if err := h.AddMessage(ctx, "s1", "tool", "x"); err == nil {
+		t.Error("不支持的角色应返回错误")
+	}
+
+	got, err := h.RecentMessages(ctx, "s1", MaxHistoryTurns)
+	if err != nil {
+		t.Fatalf("RecentMessages: %v", err)
+	}
+	if len(got) != 4 || got[0].Content != "问 2" {
+		t.Errorf("每个会话最多保留 2 轮: %v", got)
+	}
+	if got, _ := h.RecentMessages(ctx, "s1", 1); len(got) != 2 || got[0].Content != "问 3" {
+		t.Errorf("RecentMessages(1) = %v", got)
+	}
+	if got, _ := h.RecentMessages(ctx, "other", MaxHistoryTurns); len(got) != 0 {
+		t.Errorf("不同会话互不影响: %v", got)
+	}
+	if NewInMemoryHistory(0).maxTurns != MaxHistoryTurns {
+		t.Error("maxTurns <= 0 时应使用 MaxHistoryTurns")
+	}
+}
+
+func TestInMemoryHistoryConcurrent(t *testing.T) {
+	ctx := context.Background()
+	h := NewInMemoryHistory(100)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			h.AddMessage(ctx, "s", "user", fmt.Sprint(i))
+			h.RecentMessages(ctx, "s", 100)
+		}(i)
+	}
+	wg.Wait()
+	if got, _ := h.RecentMessages(ctx, "s", 100); len(got) != 20 {
+		t.Errorf("并发写入后应有 20 条消息，实际 %d", len(got))
+	}
+}
@@ -0,0 +1,229 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// DefaultConfidenceThreshold: LLM 路由的默认置信度阈值，低于该值时无论目的地是什么都路由到 unclear
+const DefaultConfidenceThreshold = 0.6
+
+// routerVerdict: LLM 路由的结构化输出
+type routerVerdict struct {
+	Destination string   `json:"destination"`
+	Confidence  float64  `json:"confidence"`
+	Reason      string   `json:"reason"`
+	Candidates  []string `json:"candidates,omitempty"`
+}
+
+// invalidLabelError: 路由决策不在注册表中（destination 不合法，或模型回答了一句话而不是 JSON）
+type invalidLabelError struct {
+	Label string
+}
+
+func (e *invalidLabelError) Error() string {
+	return fmt.Sprintf("无效的 destination: %q", e.Label)
+}
+
+// parseRouterVerdict: 严格解析路由输出
+//   - 允许外层包裹 ```json 代码块（模型常见行为），但内容必须是单个 JSON 对象
+//   - 不允许未知字段，destination 必须是注册表中的路由，confidence 必须在 [0, 1] 内
+func parseRouterVerdict(raw string, registry *RouteRegistry) (routerVerdict, error) {
+	text := strings.TrimSpace(raw)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(text, "```")
+		text = strings.TrimSpace(text)
+	}
+
+	// 模型直接回答了一句话（而不是 JSON），整句视为无效标签
+	if !strings.HasPrefix(text, "{") {
+		return routerVerdict{}, &invalidLabelError{Label: text}
+	}
+
+	var v routerVerdict
+	dec := json.NewDecoder(bytes.NewReader([]byte(text)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return routerVerdict{}, fmt.Errorf("JSON 解析失败: %w", err)
+	}
+	if dec.More() {
+		return routerVerdict{}, fmt.Errorf("JSON 对象之后存在多余内容")
+	}
+
+	v.Destination = strings.ToLower(strings.TrimSpace(v.Destination))
+	if !registry.Has(v.Destination) {
+		return routerVerdict{}, &invalidLabelError{Label: v.Destination}
+	}
+	if v.Confidence < 0 || v.Confidence > 1 {
+		return routerVerdict{}, fmt.Errorf("confidence 超出范围 [0, 1]: %v", v.Confidence)
+	}
+	// candidates 只是澄清时的参考，未注册的名称直接丢弃而不拒绝整个输出
+	candidates := make([]string, 0, len(v.Candidates))
+	for _, c := range v.Candidates {
+		if c = strings.ToLower(strings.TrimSpace(c)); registry.Has(c) {
+			candidates = append(candidates, c)
+		}
+	}
+	v.Candidates = mergeCandidates(candidates)
+	return v, nil
+}
+
+// LLMRouter: LLM 路由，由路由链输出 JSON 决策
+//   - 输出无法解析或标签无效时，用重申标签、引用原始输出的严格模式链重试一次，仍无效则路由到 unclear
+//   - 置信度低于阈值时无论目的地是什么都路由到 unclear，请用户澄清
+type LLMRouter struct {
+	chain       compose.Runnable[map[string]any, string]
+	repairChain compose.Runnable[map[string]any, string]
+	registry    *RouteRegistry
+	threshold   float64
+	metrics     *RouterMetrics
+}
+
+// NewLLMRouter: 创建 LLM 路由，路由提示词和严格模式提示词由注册表生成；metrics 可为 nil
+func NewLLMRouter(ctx context.Context, llm model.BaseChatModel, registry *RouteRegistry, threshold float64, metrics *RouterMetrics) (*LLMRouter, error) {
+	chain, err := newVerdictChain(ctx, llm, registry.RouterSystemPrompt())
+	if err != nil {
+		return nil, fmt.Errorf("编译路由链失败: %w", err)
+	}
+	// 严格模式链：路由输出无法解析或标签无效时，用重申标签、引用原始输出的系统提示词重新询问一次
+	repairChain, err := newVerdictChain(ctx, llm, registry.RouterStrictSystemPrompt())
+	if err != nil {
+		return nil, fmt.Errorf("编译路由修复链失败: %w", err)
+	}
+	return &LLMRouter{
+		chain:       chain,
+		repairChain: repairChain,
+		registry:    registry,
+		threshold:   threshold,
+		metrics:     metrics,
+	}, nil
+}
+
+// newVerdictChain: 构建 Template -> ChatModel -> Lambda 的路由链，输出原始 JSON 字符串
+// 用户消息为 {history}{request}，history 是带分隔标记的最近对话（可为空）
+func newVerdictChain(ctx context.Context, llm model.BaseChatModel, systemPrompt string) (compose.Runnable[map[string]any, string], error) {
+	return compose.NewChain[map[string]any, string]().
+		AppendChatTemplate(prompt.FromMessages(
+			schema.FString,
+			schema.SystemMessage(systemPrompt),
+			schema.UserMessage("{history}{request}"),
+		)).
+		AppendChatModel(llm).
+		AppendLambda(compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+			return strings.TrimSpace(msg.Content), nil
+		})).
+		Compile(ctx)
+}
+
+// checkVerdict: 解析路由输出，无效标签计入指标
+func (r *LLMRouter) checkVerdict(raw string) (routerVerdict, error) {
+	verdict, err := parseRouterVerdict(raw, r.registry)
+	if r.metrics != nil {
+		var labelErr *invalidLabelError
+		r.metrics.RecordLLMOutput(errors.As(err, &labelErr))
+	}
+	return verdict, err
+}
+
+// Route: 调用路由链获取决策，必要时严格模式重试一次
+func (r *LLMRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	historyText := FormatHistory(req.History, MaxHistoryTurns)
+	raw, err := r.chain.Invoke(ctx, map[string]any{
+		"request": req.Text,
+		"history": historyText,
+	})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	var notes []string
+	verdict, parseErr := r.checkVerdict(raw)
+	if parseErr != nil {
+		// 严格模式重试一次
+		notes = append(notes, fmt.Sprintf("路由输出无效（%v），严格模式重试", parseErr))
+		raw, err = r.repairChain.Invoke(ctx, map[string]any{
+			"request": req.Text,
+			"history": historyText,
+			"raw":     raw,
+			"error":   parseErr.Error(),
+		})
+		if err != nil {
+			return Decision{}, err
+		}
+		verdict, parseErr = r.checkVerdict(raw)
+		if parseErr != nil {
+			// 重试后仍无效：记录原始输出并交给 unclear
+			return Decision{
+				Destination: UnclearRoute,
+				Source:      "llm",
+				Reason:      fmt.Sprintf("路由输出无效: %v", parseErr),
+				Notes:       append(notes, fmt.Sprintf("路由输出重试后仍无效（%v），原始输出: %q", parseErr, raw)),
+			}, nil
+		}
+	}
+
+	decision := Decision{
+		Destination: verdict.Destination,
+		Source:      "llm",
+		Confidence:  verdict.Confidence,
+		Reason:      verdict.Reason,
+		Candidates:  verdict.Candidates,
+		Notes:       notes,
+	}
+	if decision.Confidence < r.threshold {
+		decision.Reason = fmt.Sprintf("置信度低于阈值 %.2f（原判断 %s：%s）", r.threshold, decision.Destination, decision.Reason)
+		// 原判断的目的地也是候选之一
+		decision.Candidates = mergeCandidates([]string{decision.Destination}, decision.Candidates)
+		decision.Destination = UnclearRoute
+	}
+	return decision, nil
+}
+
+// NewLLMArbiter: 创建由 LLM 仲裁的双路由仲裁者（可以使用比路由更便宜的模型），输出与路由链相同的 JSON 决策
+// 仲裁输出无效时不再重试，直接交给 unclear；是否达到置信度阈值由 DualRouter 判断
+func NewLLMArbiter(ctx context.Context, llm model.BaseChatModel, registry *RouteRegistry, metrics *RouterMetrics) (DualArbiter, error) {
+	chain, err := newVerdictChain(ctx, llm, registry.RouterArbiterSystemPrompt())
+	if err != nil {
+		return nil, fmt.Errorf("编译仲裁链失败: %w", err)
+	}
+
+	return func(ctx context.Context, req Request, votes []DualVote) (Decision, error) {
+		raw, err := chain.Invoke(ctx, map[string]any{
+			"request": req.Text,
+			"history": FormatHistory(req.History, MaxHistoryTurns),
+			"votes":   formatVotes(votes),
+		})
+		if err != nil {
+			return Decision{}, err
+		}
+		verdict, err := parseRouterVerdict(raw, registry)
+		if metrics != nil {
+			var labelErr *invalidLabelError
+			metrics.RecordLLMOutput(errors.As(err, &labelErr))
+		}
+		if err != nil {
+			return Decision{
+				Destination: UnclearRoute,
+				Reason:      fmt.Sprintf("仲裁输出无效: %v", err),
+				Notes:       []string{fmt.Sprintf("仲裁输出无效（%v），原始输出: %q", err, raw)},
+			}, nil
+		}
+		return Decision{
+			Destination: verdict.Destination,
+			Confidence:  verdict.Confidence,
+			Reason:      verdict.Reason,
+			Candidates:  verdict.Candidates,
+		}, nil
+	}, nil
+}
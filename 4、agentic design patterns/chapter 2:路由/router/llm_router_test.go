@@ -0,0 +1,190 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeChatModel: 按调用顺序返回预设回复（超出时重复最后一条），记录每次调用的系统提示词和用户消息
+// delay 大于 0 时先等待 delay，期间上下文取消则返回上下文错误
+type fakeChatModel struct {
+	mu      sync.Mutex
+	replies []string
+	err     error
+	delay   time.Duration
+	systems []string
+	inputs  []string
+}
+
+func (m *fakeChatModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.mu.Lock()
+	m.systems = append(m.systems, in[0].Content)
+	m.inputs = append(m.inputs, in[len(in)-1].Content)
+	i := len(m.inputs) - 1
+	m.mu.Unlock()
+
+	if m.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(m.delay):
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	if i >= len(m.replies) {
+		i = len(m.replies) - 1
+	}
+	return schema.AssistantMessage(m.replies[i], nil), nil
+}
+
+func (m *fakeChatModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+// callCount: 模型被调用的次数
+func (m *fakeChatModel) callCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.inputs)
+}
+
+// echoHandler: 返回 "路由名: 请求" 的处理程序
+func echoHandler(name string) func(ctx context.Context, request string) (string, error) {
+	return func(ctx context.Context, request string) (string, error) {
+		return name + ": " + request, nil
+	}
+}
+
+// newTestRegistry: booker / info / unclear 三条路由的注册表
+func newTestRegistry(t *testing.T) *RouteRegistry {
+	t.Helper()
+	registry, err := NewRouteRegistry(
+		Route{Name: "booker", Description: "预订航班或酒店", Intent: "预订航班或酒店", Handler: echoHandler("booker")},
+		Route{Name: "info", Description: "一般信息问题", Intent: "查询一般信息", Handler: echoHandler("info")},
+		Route{Name: UnclearRoute, Description: "请求不清楚", Handler: echoHandler(UnclearRoute)},
+	)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	return registry
+}
+
+func TestParseRouterVerdict(t *testing.T) {
+	registry := newTestRegistry(t)
+	tests := []struct {
+		name           string
+		raw            string
+		wantDest       string
+		wantConfidence float64
+		wantCandidates []string
+	}{
+		{"标准输出", `{"destination": "booker", "confidence": 0.9, "reason": "订票"}`, "booker", 0.9, nil},
+		{"代码块包裹", "```json\n{\"destination\": \"info\", \"confidence\": 0.8, \"reason\": \"问答\"}\n```", "info", 0.8, nil},
+		{"大小写和空白", `{"destination": " Booker ", "confidence": 1, "reason": ""}`, "booker", 1, nil},
+		{"候选过滤未注册名称和 unclear", `{"destination": "unclear", "confidence": 0.3, "reason": "", "candidates": ["info", "weather", "unclear", "INFO", "booker"]}`, UnclearRoute, 0.3, []string{"info", "booker"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := parseRouterVerdict(tt.raw, registry)
+			if err != nil {
+				t.Fatalf("parseRouterVerdict: %v", err)
+			}
+			if v.Destination != tt.wantDest || v.Confidence != tt.wantConfidence {
+				t.Errorf("verdict = %+v，want %s（%v）", v, tt.wantDest, tt.wantConfidence)
+			}
+			if strings.Join(v.Candidates, ",") != strings.Join(tt.wantCandidates, ",") {
+				t.Errorf("Candidates = %v, want %v", v.Candidates, tt.wantCandidates)
+			}
+		})
+	}
+}
+
+func TestParseRouterVerdictRejects(t *testing.T) {
+	registry := newTestRegistry(t)
+	tests := []struct {
+		name         string
+		raw          string
+		invalidLabel bool // invalidLabel: 期望返回 invalidLabelError（计入无效标签指标）
+	}{
+		{"一句话回答", "这是一个预订请求", true},
+		{"未注册的标签", `{"destination": "weather", "confidence": 0.9, "reason": ""}`, true},
+		{"未知字段", `{"destination": "booker", "confidence": 0.9, "reason": "", "extra": 1}`, false},
+		{"多余内容", `{"destination": "booker", "confidence": 0.9, "reason": ""} {"x": 1}`, false},
+		{"置信度超出范围", `{"destination": "booker", "confidence": 1.5, "reason": ""}`, false},
+		{"负置信度", `{"destination": "booker", "confidence": -0.1, "reason": ""}`, false},
+		{"JSON 不完整", `{"destination": "booker"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRouterVerdict(tt.raw, registry)
+			if err == nil {
+				t.Fatal("parseRouterVerdict 应返回错误")
+			}
+			var labelErr *invalidLabelError
+			if got := errors.As(err, &labelErr); got != tt.invalidLabel {
+				t.Errorf("invalidLabelError = %v, want %v（err: %v）", got, tt.invalidLabel, err)
+			}
+		})
+	}
+}
+
+func TestLLMRouterConfidenceThreshold(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name           string
+		reply          string
+		wantDest       string
+		wantCandidates []string
+	}{
+		{"达到阈值", `{"destination": "booker", "confidence": 0.6, "reason": "订票"}`, "booker", nil},
+		{"低于阈值改为 unclear", `{"destination": "booker", "confidence": 0.4, "reason": "可能是订票", "candidates": ["info"]}`, UnclearRoute, []string{"booker", "info"}},
+		{"模型判断为 unclear", `{"destination": "unclear", "confidence": 0.9, "reason": "无关"}`, UnclearRoute, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &fakeChatModel{replies: []string{tt.reply}}
+			r, err := NewLLMRouter(ctx, llm, newTestRegistry(t), DefaultConfidenceThreshold, nil)
+			if err != nil {
+				t.Fatalf("NewLLMRouter: %v", err)
+			}
+			d, err := r.Route(ctx, Request{Text: "请求"})
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if d.Destination != tt.wantDest || d.Source != "llm" {
+				t.Errorf("Route = %s（%s），want %s", d.Destination, d.Source, tt.wantDest)
+			}
+			if strings.Join(d.Candidates, ",") != strings.Join(tt.wantCandidates, ",") {
+				t.Errorf("Candidates = %v, want %v", d.Candidates, tt.wantCandidates)
+			}
+			if tt.wantDest == UnclearRoute && tt.wantCandidates != nil && !strings.Contains(d.Reason, "置信度低于阈值 0.60（原判断 booker") {
+				t.Errorf("Reason = %q，应说明原判断和阈值", d.Reason)
+			}
+		})
+	}
+}
+
+func TestLLMRouterModelError(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeChatModel{err: errors.New("服务不可用")}
+	r, err := NewLLMRouter(ctx, llm, newTestRegistry(t), DefaultConfidenceThreshold, nil)
+	if err != nil {
+		t.Fatalf("NewLLMRouter: %v", err)
+	}
+	if _, err := r.Route(ctx, Request{Text: "请求"}); err == nil || !strings.Contains(err.Error(), "服务不可用") {
+		t.Errorf("Route 错误 = %v，want 模型错误", err)
+	}
+}
@@ -0,0 +1,280 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// latencyBuckets: 处理程序耗时直方图的桶上界（最后一个桶收纳所有更大的值）
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// latencyHistogram: 简单的固定桶直方图，分位数取所在桶的上界（近似值）
+type latencyHistogram struct {
+	counts []int // counts: 长度为 len(latencyBuckets)+1，最后一个是溢出桶
+	total  int
+	max    time.Duration
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]int, len(latencyBuckets)+1)}
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	h.counts[i]++
+	h.total++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// quantile: 返回 q（0-1）分位数所在桶的上界，溢出桶返回观测到的最大值
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := int(q*float64(h.total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	seen := 0
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			if i < len(latencyBuckets) {
+				return latencyBuckets[i]
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// routeStats: 单个路由的统计
+type routeStats struct {
+	requests      int // requests: 路由到该处理程序的请求数
+	handlerErrors int
+	latency       *latencyHistogram
+}
+
+// RouterMetrics: 路由指标收集器（互斥锁保护，多意图并发模式下安全）
+type RouterMetrics struct {
+	mu        sync.Mutex
+	total     int            // total: 路由决策总数
+	bySource  map[string]int // bySource: 决策来源 -> 次数（rule / llm / embedding / cache）
+	byRoute   map[string]*routeStats
+	routeErrs int // routeErrs: 路由本身失败的次数
+	timeouts  int // timeouts: 路由超时、改用兜底决策的次数
+	llmOutput int // llmOutput: LLM 路由输出的次数（包括严格模式重试）
+	invalid   int // invalid: 其中标签无效（不在注册表中）的次数
+	dualVotes int // dualVotes: 双路由投票的次数
+	dualAgree int // dualAgree: 其中两票一致（无需仲裁）的次数
+}
+
+// NewRouterMetrics: 创建指标收集器
+func NewRouterMetrics() *RouterMetrics {
+	return &RouterMetrics{
+		bySource: make(map[string]int),
+		byRoute:  make(map[string]*routeStats),
+	}
+}
+
+// stats: 获取（必要时创建）路由的统计项（调用方持有锁）
+func (m *RouterMetrics) stats(route string) *routeStats {
+	s, ok := m.byRoute[route]
+	if !ok {
+		s = &routeStats{latency: newLatencyHistogram()}
+		m.byRoute[route] = s
+	}
+	return s
+}
+
+// RecordDecision: 记录一次路由决策
+func (m *RouterMetrics) RecordDecision(decision Decision) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.total++
+	m.bySource[decision.Source]++
+	m.stats(decision.Destination).requests++
+}
+
+// RecordRouteError: 记录一次路由失败
+func (m *RouterMetrics) RecordRouteError() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routeErrs++
+}
+
+// RecordRouteTimeout: 记录一次路由超时
+func (m *RouterMetrics) RecordRouteTimeout() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timeouts++
+}
+
+// RecordLLMOutput: 记录一次 LLM 路由输出，invalidLabel 表示其标签不在注册表中
+func (m *RouterMetrics) RecordLLMOutput(invalidLabel bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.llmOutput++
+	if invalidLabel {
+		m.invalid++
+	}
+}
+
+// RecordDualVote: 记录一次双路由投票，agree 表示两票一致
+func (m *RouterMetrics) RecordDualVote(agree bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dualVotes++
+	if agree {
+		m.dualAgree++
+	}
+}
+
+// RecordHandler: 记录一次处理程序执行的耗时和结果（由委托图节点调用）
+func (m *RouterMetrics) RecordHandler(route string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats(route)
+	s.latency.observe(d)
+	if err != nil {
+		s.handlerErrors++
+	}
+}
+
+// RouteMetricsSnapshot: 单个路由的指标快照
+type RouteMetricsSnapshot struct {
+	Route         string  `json:"route"`
+	Requests      int     `json:"requests"`
+	Share         float64 `json:"share"`
+	HandlerErrors int     `json:"handler_errors"`
+	P50Ms         int64   `json:"p50_ms"`
+	P95Ms         int64   `json:"p95_ms"`
+}
+
+// MetricsSnapshot: 指标快照（用于打印和 JSON 导出）
+type MetricsSnapshot struct {
+	TotalDecisions   int                    `json:"total_decisions"`
+	RouteErrors      int                    `json:"route_errors"`
+	RouteTimeouts    int                    `json:"route_timeouts"`
+	CacheHits        int                    `json:"cache_hits"`
+	InvalidLabels    int                    `json:"invalid_labels"`
+	InvalidLabelRate float64                `json:"invalid_label_rate"`
+	UnclearRate      float64                `json:"unclear_rate"`
+	DualVotes        int                    `json:"dual_votes"`
+	AgreementRate    float64                `json:"agreement_rate"`
+	BySource         map[string]int         `json:"by_source"`
+	Routes           []RouteMetricsSnapshot `json:"routes"`
+}
+
+// Snapshot: 生成当前指标的快照（路由按请求数降序）
+func (m *RouterMetrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snap := MetricsSnapshot{
+		TotalDecisions: m.total,
+		RouteErrors:    m.routeErrs,
+		RouteTimeouts:  m.timeouts,
+		CacheHits:      m.bySource["cache"],
+		InvalidLabels:  m.invalid,
+		DualVotes:      m.dualVotes,
+		BySource:       make(map[string]int, len(m.bySource)),
+	}
+	for k, v := range m.bySource {
+		snap.BySource[k] = v
+	}
+	for route, s := range m.byRoute {
+		rs := RouteMetricsSnapshot{
+			Route:         route,
+			Requests:      s.requests,
+			HandlerErrors: s.handlerErrors,
+			P50Ms:         s.latency.quantile(0.50).Milliseconds(),
+			P95Ms:         s.latency.quantile(0.95).Milliseconds(),
+		}
+		if m.total > 0 {
+			rs.Share = float64(s.requests) / float64(m.total)
+		}
+		snap.Routes = append(snap.Routes, rs)
+	}
+	sort.Slice(snap.Routes, func(i, j int) bool {
+		if snap.Routes[i].Requests != snap.Routes[j].Requests {
+			return snap.Routes[i].Requests > snap.Routes[j].Requests
+		}
+		return snap.Routes[i].Route < snap.Routes[j].Route
+	})
+	if m.llmOutput > 0 {
+		snap.InvalidLabelRate = float64(m.invalid) / float64(m.llmOutput)
+	}
+	if m.dualVotes > 0 {
+		snap.AgreementRate = float64(m.dualAgree) / float64(m.dualVotes)
+	}
+	if s, ok := m.byRoute[UnclearRoute]; ok && m.total > 0 {
+		snap.UnclearRate = float64(s.requests) / float64(m.total)
+	}
+	return snap
+}
+
+// PrintSummary: 以表格形式打印指标汇总
+func (m *RouterMetrics) PrintSummary(w io.Writer) {
+	snap := m.Snapshot()
+
+	fmt.Fprintln(w, "\n--- 路由指标汇总 ---")
+	fmt.Fprintf(w, "决策总数: %d | 路由失败: %d | 路由超时: %d | 缓存命中: %d | 无效标签: %d（%.1f%%）| unclear 比例: %.1f%%\n",
+		snap.TotalDecisions, snap.RouteErrors, snap.RouteTimeouts, snap.CacheHits, snap.InvalidLabels, snap.InvalidLabelRate*100, snap.UnclearRate*100)
+
+	if snap.DualVotes > 0 {
+		fmt.Fprintf(w, "双路由投票: %d | 一致率: %.1f%%\n", snap.DualVotes, snap.AgreementRate*100)
+	}
+
+	sources := make([]string, 0, len(snap.BySource))
+	for k := range snap.BySource {
+		sources = append(sources, k)
+	}
+	sort.Strings(sources)
+	fmt.Fprint(w, "决策来源:")
+	for _, k := range sources {
+		fmt.Fprintf(w, " %s=%d", k, snap.BySource[k])
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "路由\t请求数\t占比\t处理失败\tp50\tp95")
+	for _, r := range snap.Routes {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%d\t%dms\t%dms\n",
+			r.Route, r.Requests, r.Share*100, r.HandlerErrors, r.P50Ms, r.P95Ms)
+	}
+	tw.Flush()
+}
+
+// WriteJSON: 将指标快照写入 JSON 文件
+func (m *RouterMetrics) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(m.Snapshot(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化指标失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("写入指标文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// DelegationInput: 委托图的输入，包含原始请求、路由决策和可选的对话历史
+type DelegationInput struct {
+	Request    string
+	Decision   string
+	History    []*schema.Message
+	Reason     string      // Reason: 路由决策的理由（可为空）
+	Candidates []Candidate // Candidates: 路由器考虑过的候选路由，路由到 unclear 时用于生成澄清问题
+}
+
+// Candidate: 候选路由（由 RouteRegistry.Candidates 根据决策中的候选名称生成）
+type Candidate struct {
+	Route  string // Route: 路由名称
+	Intent string // Intent: 面向用户的意图短语
+}
+
+// DelegationOutput: 委托图的输出，包含处理结果
+type DelegationOutput struct {
+	Output string
+}
+
+// Route: 一条路由的定义
+// 新增处理程序只需在注册表中追加一条 Route，路由提示词、图节点、分支和边都会自动生成
+type Route struct {
+	Name        string                                                    // Name: 路由名称，同时是 LLM 输出的决策标签和图节点名
+	Description string                                                    // Description: 路由说明，写入路由系统提示词
+	Handler     func(ctx context.Context, request string) (string, error) // Handler: 处理程序
+	Exemplars   []string                                                  // Exemplars: 嵌入路由使用的示例语句（可选）
+	Intent      string                                                    // Intent: 澄清问题中的意图短语（如"预订航班或酒店"，可选，默认使用 Description）
+	// StreamHandler: 流式处理程序（可选），未设置时流式调用退化为 Handler 的单个分片
+	StreamHandler func(ctx context.Context, request string) (*schema.StreamReader[string], error)
+	// InputHandler: 需要完整委托输入（理由、候选路由、历史）的处理程序（可选），设置时优先于 Handler
+	InputHandler func(ctx context.Context, input DelegationInput) (string, error)
+}
+
+// RouteRegistry: 路由注册表
+type RouteRegistry struct {
+	routes []Route
+	index  map[string]int
+}
+
+// NewRouteRegistry: 创建路由注册表，校验名称唯一、处理程序非空，并要求包含 unclear 路由
+func NewRouteRegistry(routes ...Route) (*RouteRegistry, error) {
+	r := &RouteRegistry{index: make(map[string]int, len(routes))}
+	for _, route := range routes {
+		route.Name = strings.ToLower(strings.TrimSpace(route.Name))
+		if route.Name == "" {
+			return nil, fmt.Errorf("路由名称不能为空")
+		}
+		if route.Handler == nil && route.InputHandler == nil {
+			return nil, fmt.Errorf("路由 %s 缺少处理程序", route.Name)
+		}
+		if _, ok := r.index[route.Name]; ok {
+			return nil, fmt.Errorf("路由 %s 重复注册", route.Name)
+		}
+		r.index[route.Name] = len(r.routes)
+		r.routes = append(r.routes, route)
+	}
+	if _, ok := r.index[UnclearRoute]; !ok {
+		return nil, fmt.Errorf("注册表必须包含 %s 路由", UnclearRoute)
+	}
+	return r, nil
+}
+
+// Routes: 按注册顺序返回所有路由
+func (r *RouteRegistry) Routes() []Route {
+	return r.routes
+}
+
+// Names: 按注册顺序返回所有路由名称
+func (r *RouteRegistry) Names() []string {
+	names := make([]string, 0, len(r.routes))
+	for _, route := range r.routes {
+		names = append(names, route.Name)
+	}
+	return names
+}
+
+// Has: 判断决策是否为已注册的路由
+func (r *RouteRegistry) Has(name string) bool {
+	_, ok := r.index[name]
+	return ok
+}
+
+// Resolve: 将决策规范化为已注册的路由名称，未注册的决策归为 unclear
+func (r *RouteRegistry) Resolve(decision string) string {
+	name := strings.ToLower(strings.TrimSpace(decision))
+	if r.Has(name) {
+		return name
+	}
+	return UnclearRoute
+}
+
+// Exemplars: 收集各路由的嵌入示例（没有示例的路由不参与嵌入路由）
+func (r *RouteRegistry) Exemplars() map[string][]string {
+	exemplars := make(map[string][]string)
+	for _, route := range r.routes {
+		if len(route.Exemplars) > 0 {
+			exemplars[route.Name] = route.Exemplars
+		}
+	}
+	return exemplars
+}
+
+// Candidates: 将决策中的候选路由名称转换为带意图短语的候选列表（忽略未注册的名称和 unclear）
+func (r *RouteRegistry) Candidates(names []string) []Candidate {
+	var candidates []Candidate
+	for _, name := range mergeCandidates(names) {
+		i, ok := r.index[name]
+		if !ok {
+			continue
+		}
+		route := r.routes[i]
+		intent := route.Intent
+		if intent == "" {
+			intent = route.Description
+		}
+		candidates = append(candidates, Candidate{Route: route.Name, Intent: intent})
+	}
+	return candidates
+}
+
+// RouterSystemPrompt: 根据注册表生成路由系统提示词（FString 模板，字面量花括号已转义）
+func (r *RouteRegistry) RouterSystemPrompt() string {
+	var sb strings.Builder
+	sb.WriteString("分析用户的请求并确定哪个专家处理程序应处理它。可选的 destination：\n")
+	for _, route := range r.routes {
+		fmt.Fprintf(&sb, "     - '%s'：%s\n", route.Name, escapeFString(route.Description))
+	}
+	sb.WriteString("     confidence 为 0 到 1 之间的小数，表示你对该判断的把握；reason 用一句话说明理由。\n")
+	sb.WriteString("     candidates 列出你考虑过的路由（不含 unclear，最多 3 个，可为空数组），判断为 unclear 时用于向用户提出澄清问题。\n")
+	sb.WriteString("     只输出一个 JSON 对象，不要输出任何其他内容：\n")
+	fmt.Fprintf(&sb, `     {{"destination": "%s", "confidence": 0.0, "reason": "...", "candidates": ["..."]}}`, strings.Join(r.Names(), "|"))
+	return sb.String()
+}
+
+// RouterStrictSystemPrompt: 根据注册表生成严格模式的系统提示词（模板变量 {error}、{raw}）
+// 路由输出无法解析或标签无效时使用：重申允许的标签，并引用被拒绝的原始输出
+func (r *RouteRegistry) RouterStrictSystemPrompt() string {
+	quoted := make([]string, 0, len(r.routes))
+	for _, name := range r.Names() {
+		quoted = append(quoted, "'"+name+"'")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("严格模式：你上一次的路由输出不符合要求，已被拒绝。\n")
+	sb.WriteString("     被拒绝的输出：「{raw}」\n")
+	sb.WriteString("     拒绝原因：{error}\n")
+	fmt.Fprintf(&sb, "     destination 只能原样取以下标签之一，不能是句子、解释或其他词：%s\n", strings.Join(quoted, "、"))
+	for _, route := range r.routes {
+		fmt.Fprintf(&sb, "     - '%s'：%s\n", route.Name, escapeFString(route.Description))
+	}
+	sb.WriteString("     confidence 为 0 到 1 之间的小数，reason 用一句话说明理由。\n")
+	sb.WriteString("     candidates 列出你考虑过的路由（不含 unclear，最多 3 个，可为空数组），判断为 unclear 时用于向用户提出澄清问题。\n")
+	sb.WriteString("     只输出一个 JSON 对象，不要输出任何其他内容：\n")
+	fmt.Fprintf(&sb, `     {{"destination": "%s", "confidence": 0.0, "reason": "...", "candidates": ["..."]}}`, strings.Join(r.Names(), "|"))
+	return sb.String()
+}
+
+// RouterArbiterSystemPrompt: 根据注册表生成双路由仲裁的系统提示词（模板变量 {votes}）
+// 两个路由的投票不一致时，由仲裁链参考双方投票和原始请求做出最终决定
+func (r *RouteRegistry) RouterArbiterSystemPrompt() string {
+	var sb strings.Builder
+	sb.WriteString("两个路由器对同一请求给出了不同的判断，请你作为仲裁者做出最终决定。可选的 destination：\n")
+	for _, route := range r.routes {
+		fmt.Fprintf(&sb, "     - '%s'：%s\n", route.Name, escapeFString(route.Description))
+	}
+	sb.WriteString("     各路由器的投票（仅供参考，可以都不采纳）：\n{votes}\n")
+	sb.WriteString("     confidence 为 0 到 1 之间的小数，reason 用一句话说明你采纳或否决投票的理由。\n")
+	sb.WriteString("     candidates 列出你考虑过的路由（不含 unclear，最多 3 个，可为空数组），判断为 unclear 时用于向用户提出澄清问题。\n")
+	sb.WriteString("     只输出一个 JSON 对象，不要输出任何其他内容：\n")
+	fmt.Fprintf(&sb, `     {{"destination": "%s", "confidence": 0.0, "reason": "...", "candidates": ["..."]}}`, strings.Join(r.Names(), "|"))
+	return sb.String()
+}
+
+// BuildDelegationGraph: 根据注册表构建委托图
+// 每条路由一个 Lambda 节点，START 通过分支按决策选择节点，所有节点连接到 END
+// metrics 不为 nil 时，每个节点记录处理程序的耗时和结果
+func (r *RouteRegistry) BuildDelegationGraph(ctx context.Context, metrics *RouterMetrics) (compose.Runnable[DelegationInput, DelegationOutput], error) {
+	graph := compose.NewGraph[DelegationInput, DelegationOutput]()
+
+	endNodes := make(map[string]bool, len(r.routes))
+	for _, route := range r.routes {
+		lambda, err := r.routeLambda(route, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("创建 %s 节点失败: %w", route.Name, err)
+		}
+		if err := graph.AddLambdaNode(route.Name, lambda); err != nil {
+			return nil, fmt.Errorf("添加 %s 节点失败: %w", route.Name, err)
+		}
+		if err := graph.AddEdge(route.Name, compose.END); err != nil {
+			return nil, fmt.Errorf("添加 %s->END 边失败: %w", route.Name, err)
+		}
+		endNodes[route.Name] = true
+	}
+
+	// 分支：根据决策路由到对应节点，未注册的决策落到 unclear
+	branch := compose.NewGraphBranch(
+		func(ctx context.Context, input DelegationInput) (string, error) {
+			return r.Resolve(input.Decision), nil
+		},
+		endNodes,
+	)
+	if err := graph.AddBranch(compose.START, branch); err != nil {
+		return nil, fmt.Errorf("添加分支失败: %w", err)
+	}
+
+	return graph.Compile(ctx)
+}
+
+// routeLambda: 为路由创建图节点
+// 有 StreamHandler 的路由同时支持 Invoke 和 Stream，委托图以 Stream 方式调用时逐片输出；
+// 只有 Handler 的路由在 Stream 调用时由框架把结果包装为单个分片
+func (r *RouteRegistry) routeLambda(route Route, metrics *RouterMetrics) (*compose.Lambda, error) {
+	name, handler, inputHandler, streamHandler := route.Name, route.Handler, route.InputHandler, route.StreamHandler
+
+	invoke := func(ctx context.Context, input DelegationInput, _ ...any) (DelegationOutput, error) {
+		start := time.Now()
+		var result string
+		var err error
+		if inputHandler != nil {
+			result, err = inputHandler(ctx, input)
+		} else {
+			// 有历史时把历史段拼到请求前，让处理程序也能理解追问中的指代
+			result, err = handler(ctx, FormatHistory(input.History, MaxHistoryTurns)+input.Request)
+		}
+		if metrics != nil {
+			metrics.RecordHandler(name, time.Since(start), err)
+		}
+		if err != nil {
+			return DelegationOutput{}, err
+		}
+		return DelegationOutput{Output: result}, nil
+	}
+	if streamHandler == nil {
+		return compose.AnyLambda[DelegationInput, DelegationOutput, any](invoke, nil, nil, nil)
+	}
+
+	stream := func(ctx context.Context, input DelegationInput, _ ...any) (*schema.StreamReader[DelegationOutput], error) {
+		start := time.Now()
+		src, err := streamHandler(ctx, FormatHistory(input.History, MaxHistoryTurns)+input.Request)
+		if err != nil {
+			if metrics != nil {
+				metrics.RecordHandler(name, time.Since(start), err)
+			}
+			return nil, err
+		}
+
+		// 转发分片，在流结束时记录完整耗时
+		out, w := schema.Pipe[DelegationOutput](1)
+		go func() {
+			defer src.Close()
+			defer w.Close()
+			for {
+				chunk, err := src.Recv()
+				if errors.Is(err, io.EOF) {
+					if metrics != nil {
+						metrics.RecordHandler(name, time.Since(start), nil)
+					}
+					return
+				}
+				if err != nil {
+					if metrics != nil {
+						metrics.RecordHandler(name, time.Since(start), err)
+					}
+					w.Send(DelegationOutput{}, err)
+					return
+				}
+				if closed := w.Send(DelegationOutput{Output: chunk}, nil); closed {
+					return
+				}
+			}
+		}()
+		return out, nil
+	}
+	return compose.AnyLambda[DelegationInput, DelegationOutput, any](invoke, stream, nil, nil)
+}
+
+// escapeFString: 转义 FString 模板中的花括号，避免说明文字被当成占位符
+func escapeFString(s string) string {
+	return strings.NewReplacer("{", "{{", "}", "}}").Replace(s)
+}
@@ -0,0 +1,320 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestNewRouteRegistryErrors(t *testing.T) {
+	unclear := Route{Name: UnclearRoute, Handler: echoHandler(UnclearRoute)}
+	tests := []struct {
+		name    string
+		routes  []Route
+		wantErr string
+	}{
+		{"名称为空", []Route{{Name: " ", Handler: echoHandler("x")}, unclear}, "名称不能为空"},
+		{"缺少处理程序", []Route{{Name: "booker"}, unclear}, "缺少处理程序"},
+		{"名称重复（忽略大小写）", []Route{{Name: "booker", Handler: echoHandler("a")}, {Name: "Booker", Handler: echoHandler("b")}, unclear}, "重复注册"},
+		{"缺少 unclear", []Route{{Name: "booker", Handler: echoHandler("booker")}}, "必须包含 unclear"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouteRegistry(tt.routes...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewRouteRegistry 错误 = %v，want 包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRouteRegistryLookup(t *testing.T) {
+	registry := newTestRegistry(t)
+	if got := registry.Names(); !reflect.DeepEqual(got, []string{"booker", "info", UnclearRoute}) {
+		t.Errorf("Names = %v，应保持注册顺序", got)
+	}
+	for decision, want := range map[string]string{
+		"booker":   "booker",
+		" INFO ":   "info",
+		"weather":  UnclearRoute,
+		"":         UnclearRoute,
+		"unclear":  UnclearRoute,
+		"booker.":  UnclearRoute,
+		"Booker\n": "booker",
+	} {
+		if got := registry.Resolve(decision); got != want {
+			t.Errorf("Resolve(%q) = %s, want %s", decision, got, want)
+		}
+	}
+}
+
+func TestRouteRegistryCandidates(t *testing.T) {
+	registry, err := NewRouteRegistry(
+		Route{Name: "booker", Description: "预订相关", Intent: "预订航班或酒店", Handler: echoHandler("booker")},
+		Route{Name: "info", Description: "一般信息问题", Handler: echoHandler("info")},
+		Route{Name: UnclearRoute, Handler: echoHandler(UnclearRoute)},
+	)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	got := registry.Candidates([]string{"info", "weather", UnclearRoute, "booker", "info"})
+	want := []Candidate{
+		{Route: "info", Intent: "一般信息问题"}, // 没有 Intent 时使用 Description
+		{Route: "booker", Intent: "预订航班或酒店"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Candidates = %+v, want %+v", got, want)
+	}
+}
+
+func TestRouteRegistryExemplars(t *testing.T) {
+	registry, err := NewRouteRegistry(
+		Route{Name: "booker", Handler: echoHandler("booker"), Exemplars: []string{"订机票"}},
+		Route{Name: "info", Handler: echoHandler("info")},
+		Route{Name: UnclearRoute, Handler: echoHandler(UnclearRoute), Exemplars: []string{"你好"}},
+	)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	want := map[string][]string{"booker": {"订机票"}, UnclearRoute: {"你好"}}
+	if got := registry.Exemplars(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Exemplars = %v, want %v（没有示例的路由不参与）", got, want)
+	}
+}
+
+// TestRouterPromptsAreValidTemplates: 生成的提示词列出所有路由，说明中的花括号被转义，可以正常渲染
+func TestRouterPromptsAreValidTemplates(t *testing.T) {
+	registry, err := NewRouteRegistry(
+		Route{Name: "booker", Description: "预订（参数如 {date}）", Handler: echoHandler("booker")},
+		Route{Name: UnclearRoute, Description: "请求不清楚", Handler: echoHandler(UnclearRoute)},
+	)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	vars := map[string]any{"raw": "原始输出", "error": "解析失败", "votes": "     - llm: booker"}
+	for name, system := range map[string]string{
+		"路由":   registry.RouterSystemPrompt(),
+		"严格模式": registry.RouterStrictSystemPrompt(),
+		"仲裁":   registry.RouterArbiterSystemPrompt(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			msgs, err := prompt.FromMessages(schema.FString, schema.SystemMessage(system)).Format(context.Background(), vars)
+			if err != nil {
+				t.Fatalf("渲染提示词失败: %v", err)
+			}
+			got := msgs[0].Content
+			for _, want := range []string{"'booker'", "'unclear'", "预订（参数如 {date}）", `{"destination": "booker|unclear"`} {
+				if !strings.Contains(got, want) {
+					t.Errorf("提示词缺少 %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDelegationGraphDispatch(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewRouterMetrics()
+	graph, err := newTestRegistry(t).BuildDelegationGraph(ctx, metrics)
+	if err != nil {
+		t.Fatalf("BuildDelegationGraph: %v", err)
+	}
+
+	tests := []struct {
+		decision string
+		want     string
+	}{
+		{"booker", "booker: 订机票"},
+		{"INFO", "info: 订机票"},
+		{"weather", "unclear: 订机票"}, // 未注册的决策落到 unclear
+	}
+	for _, tt := range tests {
+		out, err := graph.Invoke(ctx, DelegationInput{Request: "订机票", Decision: tt.decision})
+		if err != nil {
+			t.Fatalf("Invoke(%s): %v", tt.decision, err)
+		}
+		if out.Output != tt.want {
+			t.Errorf("Invoke(%s) = %q, want %q", tt.decision, out.Output, tt.want)
+		}
+	}
+	snap := metrics.Snapshot()
+	if len(snap.Routes) != 3 {
+		t.Errorf("每个执行过的处理程序都应记录指标: %+v", snap.Routes)
+	}
+}
+
+func TestDelegationGraphHistoryAndInputHandler(t *testing.T) {
+	ctx := context.Background()
+	var got DelegationInput
+	registry, err := NewRouteRegistry(
+		Route{Name: "booker", Handler: echoHandler("booker")},
+		Route{Name: UnclearRoute, InputHandler: func(ctx context.Context, input DelegationInput) (string, error) {
+			got = input
+			return "请澄清", nil
+		}},
+	)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	graph, err := registry.BuildDelegationGraph(ctx, nil)
+	if err != nil {
+		t.Fatalf("BuildDelegationGraph: %v", err)
+	}
+
+	history := []*schema.Message{schema.UserMessage("去伦敦的航班多少钱"), schema.AssistantMessage("约 4000 元", nil)}
+	out, err := graph.Invoke(ctx, DelegationInput{Request: "那帮我订一下吧", Decision: "booker", History: history})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	// Handler 收到的请求前拼接了历史段
+	if !strings.HasPrefix(out.Output, "booker: 以下是最近的对话历史") || !strings.HasSuffix(out.Output, "当前请求：那帮我订一下吧") {
+		t.Errorf("Handler 收到的请求 = %q", out.Output)
+	}
+
+	input := DelegationInput{Request: "嗯", Decision: UnclearRoute, Reason: "太短", Candidates: []Candidate{{Route: "booker", Intent: "订票"}}}
+	if _, err := graph.Invoke(ctx, input); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if !reflect.DeepEqual(got, input) {
+		t.Errorf("InputHandler 收到 %+v, want 完整的委托输入 %+v", got, input)
+	}
+}
+
+func TestDelegationGraphHandlerError(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewRouterMetrics()
+	registry, err := NewRouteRegistry(
+		Route{Name: "booker", Handler: func(ctx context.Context, request string) (string, error) {
+			return "", errors.New("预订系统维护中")
+		}},
+		Route{Name: UnclearRoute, Handler: echoHandler(UnclearRoute)},
+	)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	graph, err := registry.BuildDelegationGraph(ctx, metrics)
+	if err != nil {
+		t.Fatalf("BuildDelegationGraph: %v", err)
+	}
+	if _, err := graph.Invoke(ctx, DelegationInput{Request: "订票", Decision: "booker"}); err == nil || !strings.Contains(err.Error(), "预订系统维护中") {
+		t.Errorf("Invoke 错误 = %v", err)
+	}
+	if snap := metrics.Snapshot(); len(snap.Routes) != 1 || snap.Routes[0].HandlerErrors != 1 {
+		t.Errorf("处理失败应计入指标: %+v", snap.Routes)
+	}
+}
+
+// collectStream: 读取委托图的流式输出，返回所有分片
+func collectStream(t *testing.T, sr *schema.StreamReader[DelegationOutput]) ([]string, error) {
+	t.Helper()
+	defer sr.Close()
+	var chunks []string
+	for {
+		chunk, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			return chunks, nil
+		}
+		if err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, chunk.Output)
+	}
+}
+
+func TestDelegationGraphStream(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewRouterMetrics()
+	registry, err := NewRouteRegistry(
+		Route{Name: "booker", Handler: echoHandler("booker")},
+		Route{
+			Name:    "info",
+			Handler: echoHandler("info"),
+			StreamHandler: func(ctx context.Context, request string) (*schema.StreamReader[string], error) {
+				return schema.StreamReaderFromArray([]string{"罗马", "斗兽场", "建于公元 80 年"}), nil
+			},
+		},
+		Route{Name: UnclearRoute, Handler: echoHandler(UnclearRoute)},
+	)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	graph, err := registry.BuildDelegationGraph(ctx, metrics)
+	if err != nil {
+		t.Fatalf("BuildDelegationGraph: %v", err)
+	}
+
+	sr, err := graph.Stream(ctx, DelegationInput{Request: "介绍斗兽场", Decision: "info"})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	chunks, err := collectStream(t, sr)
+	if err != nil {
+		t.Fatalf("读取流失败: %v", err)
+	}
+	if !reflect.DeepEqual(chunks, []string{"罗马", "斗兽场", "建于公元 80 年"}) {
+		t.Errorf("info 路由应逐片输出: %v", chunks)
+	}
+
+	// 没有 StreamHandler 的路由退化为单个分片
+	sr, err = graph.Stream(ctx, DelegationInput{Request: "订票", Decision: "booker"})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if chunks, err := collectStream(t, sr); err != nil || strings.Join(chunks, "") != "booker: 订票" {
+		t.Errorf("booker 流式输出 = %v, %v", chunks, err)
+	}
+
+	// 流结束时记录处理程序指标
+	snap := metrics.Snapshot()
+	if len(snap.Routes) != 2 {
+		t.Errorf("流式调用也应记录处理程序指标: %+v", snap.Routes)
+	}
+}
+
+func TestDelegationGraphStreamError(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewRouterMetrics()
+	registry, err := NewRouteRegistry(
+		Route{
+			Name:    "info",
+			Handler: echoHandler("info"),
+			StreamHandler: func(ctx context.Context, request string) (*schema.StreamReader[string], error) {
+				sr, sw := schema.Pipe[string](2)
+				go func() {
+					defer sw.Close()
+					sw.Send("第一片", nil)
+					sw.Send("", errors.New("连接中断"))
+				}()
+				return sr, nil
+			},
+		},
+		Route{Name: UnclearRoute, Handler: echoHandler(UnclearRoute)},
+	)
+	if err != nil {
+		t.Fatalf("NewRouteRegistry: %v", err)
+	}
+	graph, err := registry.BuildDelegationGraph(ctx, metrics)
+	if err != nil {
+		t.Fatalf("BuildDelegationGraph: %v", err)
+	}
+	sr, err := graph.Stream(ctx, DelegationInput{Request: "问题", Decision: "info"})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	chunks, err := collectStream(t, sr)
+	if err == nil || !strings.Contains(err.Error(), "连接中断") {
+		t.Fatalf("应返回流中的错误，实际 %v", err)
+	}
+	if !reflect.DeepEqual(chunks, []string{"第一片"}) {
+		t.Errorf("出错前的分片 = %v", chunks)
+	}
+	if snap := metrics.Snapshot(); len(snap.Routes) != 1 || snap.Routes[0].HandlerErrors != 1 {
+		t.Errorf("流中的错误应计入处理失败: %+v", snap.Routes)
+	}
+}
@@ -0,0 +1,143 @@
+package router
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRouteCacheSize = 256              // DefaultRouteCacheSize: 默认最多缓存的决策数
+	DefaultRouteCacheTTL  = 10 * time.Minute // DefaultRouteCacheTTL: 默认缓存有效期
+)
+
+// normalizeRequest: 规范化请求作为缓存键（去首尾空白、转小写、合并连续空白）
+func normalizeRequest(request string) string {
+	return strings.Join(strings.Fields(strings.ToLower(request)), " ")
+}
+
+// routeCacheEntry: 缓存项
+type routeCacheEntry struct {
+	key       string
+	decision  Decision
+	expiresAt time.Time
+}
+
+// RouteCache: 路由决策的 LRU 缓存（并发安全）
+// 近似相同的请求（大小写、空白不同）只需一次模型调用
+type RouteCache struct {
+	mu     sync.Mutex
+	size   int
+	ttl    time.Duration
+	ll     *list.List               // ll: 最近使用的在表头
+	items  map[string]*list.Element // items: 键 -> 链表节点
+	hits   int
+	misses int
+}
+
+// NewRouteCache: 创建 LRU 缓存，size 为容量上限，ttl 为每项的有效期（<=0 表示永不过期）
+func NewRouteCache(size int, ttl time.Duration) *RouteCache {
+	if size <= 0 {
+		size = DefaultRouteCacheSize
+	}
+	return &RouteCache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// Get: 查找请求的缓存决策，过期项视为未命中并被移除
+func (c *RouteCache) Get(request string) (Decision, bool) {
+	key := normalizeRequest(request)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*routeCacheEntry)
+		if c.ttl <= 0 || time.Now().Before(entry.expiresAt) {
+			c.ll.MoveToFront(el)
+			c.hits++
+			return entry.decision, true
+		}
+		c.removeElement(el)
+	}
+	c.misses++
+	return Decision{}, false
+}
+
+// Put: 写入决策，超出容量时淘汰最久未使用的项
+func (c *RouteCache) Put(request string, decision Decision) {
+	key := normalizeRequest(request)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*routeCacheEntry)
+		entry.decision = decision
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&routeCacheEntry{key: key, decision: decision, expiresAt: expiresAt})
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Stats: 返回命中和未命中次数
+func (c *RouteCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// removeElement: 从链表和索引中移除节点（调用方持有锁）
+func (c *RouteCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*routeCacheEntry).key)
+}
+
+// CachedRouter: 带决策缓存的路由，规范化后的请求命中缓存时直接复用决策，避免重复的模型调用
+//   - 带历史的请求不读写缓存：同一句话（如"那帮我订一下吧"）在不同上下文中的决策不同
+//   - 规则决策本身没有模型开销，也不写入缓存；超时的兜底决策不代表真实判断，同样不写入
+type CachedRouter struct {
+	inner Router
+	cache *RouteCache
+}
+
+// NewCachedRouter: 用 cache 包装 inner
+func NewCachedRouter(inner Router, cache *RouteCache) *CachedRouter {
+	return &CachedRouter{inner: inner, cache: cache}
+}
+
+// Route: 先查缓存，未命中时交给内部路由
+func (c *CachedRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	useCache := len(req.History) == 0
+	if useCache {
+		if cached, ok := c.cache.Get(req.Text); ok {
+			cached.Reason = fmt.Sprintf("缓存命中（原来源 %s）：%s", cached.Source, cached.Reason)
+			cached.Source = "cache"
+			cached.Trail = nil
+			cached.Notes = nil
+			return cached, nil
+		}
+	}
+
+	decision, err := c.inner.Route(ctx, req)
+	if err != nil {
+		return decision, err
+	}
+	if useCache && decision.Source != "rule" && decision.Source != "timeout" {
+		c.cache.Put(req.Text, decision)
+	}
+	return decision, nil
+}
@@ -0,0 +1,138 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestNormalizeRequest(t *testing.T) {
+	tests := map[string]string{
+		"  意大利的首都是什么？ ":     "意大利的首都是什么？",
+		"Book  a\tFlight\n": "book a flight",
+		"":                  "",
+	}
+	for in, want := range tests {
+		if got := normalizeRequest(in); got != want {
+			t.Errorf("normalizeRequest(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRouteCacheLRU(t *testing.T) {
+	c := NewRouteCache(2, 0)
+	c.Put("a", Decision{Destination: "booker"})
+	c.Put("b", Decision{Destination: "info"})
+	if _, ok := c.Get("a"); !ok { // a 变为最近使用
+		t.Fatal("a 应命中")
+	}
+	c.Put("c", Decision{Destination: "refund"}) // 淘汰最久未使用的 b
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b 应被淘汰")
+	}
+	if d, ok := c.Get(" A "); !ok || d.Destination != "booker" {
+		t.Errorf("Get(\" A \") = %+v, %v，规范化后应命中 a", d, ok)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("c 应命中")
+	}
+	if hits, misses := c.Stats(); hits != 3 || misses != 1 {
+		t.Errorf("Stats = %d/%d, want 3/1", hits, misses)
+	}
+}
+
+func TestRouteCachePutOverwrites(t *testing.T) {
+	c := NewRouteCache(2, 0)
+	c.Put("a", Decision{Destination: "booker"})
+	c.Put("A", Decision{Destination: "info"})
+	if d, _ := c.Get("a"); d.Destination != "info" {
+		t.Errorf("Get = %s，同一键再次写入应覆盖", d.Destination)
+	}
+	if c.ll.Len() != 1 {
+		t.Errorf("覆盖不应新增缓存项，当前 %d 项", c.ll.Len())
+	}
+}
+
+func TestRouteCacheTTL(t *testing.T) {
+	c := NewRouteCache(4, 20*time.Millisecond)
+	c.Put("a", Decision{Destination: "booker"})
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("有效期内应命中")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("过期项不应命中")
+	}
+	if len(c.items) != 0 {
+		t.Error("过期项应被移除")
+	}
+}
+
+func TestNewRouteCacheDefaultSize(t *testing.T) {
+	if c := NewRouteCache(0, 0); c.size != DefaultRouteCacheSize {
+		t.Errorf("size = %d, want %d", c.size, DefaultRouteCacheSize)
+	}
+}
+
+// countingRouter: 返回固定决策并记录调用次数
+type countingRouter struct {
+	decision Decision
+	err      error
+	calls    int
+}
+
+func (r *countingRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	r.calls++
+	return r.decision, r.err
+}
+
+func TestCachedRouter(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRouter{decision: Decision{Destination: "info", Source: "llm", Reason: "问答", Notes: []string{"严格模式重试"}}}
+	r := NewCachedRouter(inner, NewRouteCache(8, time.Minute))
+
+	if _, err := r.Route(ctx, Request{Text: "意大利的首都是什么？"}); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	d, err := r.Route(ctx, Request{Text: "  意大利的首都是什么？ "})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("内部路由调用 %d 次，近似相同的请求应命中缓存", inner.calls)
+	}
+	if d.Source != "cache" || d.Destination != "info" || d.Reason != "缓存命中（原来源 llm）：问答" || d.Notes != nil {
+		t.Errorf("缓存决策 = %+v", d)
+	}
+
+	// 带历史的请求不读写缓存
+	history := []*schema.Message{schema.UserMessage("上一轮")}
+	if d, _ := r.Route(ctx, Request{Text: "意大利的首都是什么？", History: history}); d.Source != "llm" || inner.calls != 2 {
+		t.Errorf("带历史的请求不应使用缓存: %+v（调用 %d 次）", d, inner.calls)
+	}
+}
+
+func TestCachedRouterSkipsRuleTimeoutAndErrors(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name  string
+		inner *countingRouter
+	}{
+		{"规则决策", &countingRouter{decision: Decision{Destination: "booker", Source: "rule"}}},
+		{"超时兜底", &countingRouter{decision: Decision{Destination: UnclearRoute, Source: "timeout"}}},
+		{"路由失败", &countingRouter{err: errors.New("失败")}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewCachedRouter(tt.inner, NewRouteCache(8, 0))
+			r.Route(ctx, Request{Text: "请求"})
+			r.Route(ctx, Request{Text: "请求"})
+			if tt.inner.calls != 2 {
+				t.Errorf("内部路由调用 %d 次，该决策不应写入缓存", tt.inner.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package router: 可复用的路由组件
+//
+// 所有路由实现（规则、LLM、嵌入、组合、双路由、缓存）都实现 Router 接口，
+// 由 RouteRegistry 描述可选的路由和处理程序，RouterMetrics 统计决策和处理程序指标。
+// 包内不向标准输出打印任何内容，诊断信息通过 Decision.Notes 和 RouterMetrics 返回给调用方。
+// 第 2 章的 main 只负责组装这些组件并运行示例，其他章节（如监督者、分诊）可以直接复用。
+package router
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// UnclearRoute: 必须存在的兜底路由，任何无法识别的决策都会落到这里
+const UnclearRoute = "unclear"
+
+// ErrNoMatch: 路由器没有给出决策（如规则未命中），组合路由会交给下一阶段
+var ErrNoMatch = errors.New("路由器未命中")
+
+// Request: 路由请求
+type Request struct {
+	Text    string            // Text: 当前请求
+	History []*schema.Message // History: 最近的对话历史（可选），用于理解追问
+}
+
+// Decision: 路由决策及其来源
+type Decision struct {
+	Destination string       // Destination: 注册表中的路由名称（如 booker / info / refund / unclear）
+	Source      string       // Source: 决策来源 rule / llm / embedding / dual / cache
+	Confidence  float64      // Confidence: 置信度（规则命中为 1，嵌入路由为最高相似度）
+	Reason      string       // Reason: 决策理由
+	Candidates  []string     // Candidates: 路由器考虑过的候选路由（不含 unclear），路由到 unclear 时用于生成澄清问题
+	Trail       []StageTrail // Trail: 组合路由的决策轨迹（其他路由为空）
+	Notes       []string     // Notes: 路由过程中的诊断信息（如严格模式重试、双路由的投票），router 包不打印，由调用方决定如何输出
+}
+
+// Router: 路由器，根据请求给出路由决策
+// 返回 ErrNoMatch 表示该路由器没有给出决策，其他错误表示路由本身失败
+type Router interface {
+	Route(ctx context.Context, req Request) (Decision, error)
+}
+
+// mergeCandidates: 按出现顺序合并候选路由，去重并忽略空值和 unclear
+func mergeCandidates(lists ...[]string) []string {
+	seen := map[string]bool{}
+	var merged []string
+	for _, list := range lists {
+		for _, name := range list {
+			if name == "" || name == UnclearRoute || seen[name] {
+				continue
+			}
+			seen[name] = true
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
+
+// Func: 函数适配器，使普通函数可以作为 Router 使用
+type Func func(ctx context.Context, req Request) (Decision, error)
+
+// Route: 调用 f
+func (f Func) Route(ctx context.Context, req Request) (Decision, error) {
+	return f(ctx, req)
+}
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeCandidates(t *testing.T) {
+	got := mergeCandidates([]string{"info", "", UnclearRoute}, nil, []string{"booker", "info"}, []string{"refund"})
+	if want := []string{"info", "booker", "refund"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeCandidates = %v, want %v", got, want)
+	}
+}
+
+func TestLLMRouterStrictRetry(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewRouterMetrics()
+	llm := &fakeChatModel{replies: []string{
+		"这是一个预订请求",
+		`{"destination": "booker", "confidence": 0.9, "reason": "订票"}`,
+	}}
+	r, err := NewLLMRouter(ctx, llm, newTestRegistry(t), DefaultConfidenceThreshold, metrics)
+	if err != nil {
+		t.Fatalf("NewLLMRouter: %v", err)
+	}
+	d, err := r.Route(ctx, Request{Text: "订机票"})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if d.Destination != "booker" || len(d.Notes) != 1 || !strings.Contains(d.Notes[0], "严格模式重试") {
+		t.Errorf("重试成功后的决策 = %+v", d)
+	}
+	// 严格模式提示词引用了被拒绝的原始输出
+	if len(llm.systems) != 2 || !strings.Contains(llm.systems[1], "「这是一个预订请求」") {
+		t.Errorf("严格模式提示词 = %q", llm.systems)
+	}
+	if snap := metrics.Snapshot(); snap.InvalidLabels != 1 || snap.InvalidLabelRate != 0.5 {
+		t.Errorf("无效标签指标 = %d（%v）", snap.InvalidLabels, snap.InvalidLabelRate)
+	}
+}
+
+func TestLLMRouterStrictRetryStillInvalid(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeChatModel{replies: []string{"不知道", `{"destination": "weather"}`}}
+	r, err := NewLLMRouter(ctx, llm, newTestRegistry(t), DefaultConfidenceThreshold, nil)
+	if err != nil {
+		t.Fatalf("NewLLMRouter: %v", err)
+	}
+	d, err := r.Route(ctx, Request{Text: "请求"})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if d.Destination != UnclearRoute || llm.callCount() != 2 {
+		t.Errorf("重试后仍无效应路由到 unclear: %+v（调用 %d 次）", d, llm.callCount())
+	}
+	if len(d.Notes) != 2 || !strings.Contains(d.Notes[1], `原始输出: "{\"destination\": \"weather\"}"`) {
+		t.Errorf("Notes 应记录原始输出: %q", d.Notes)
+	}
+}
+
+// TestRouterPipeline: 与第 2 章 main 相同的组装方式（缓存 -> 超时 -> 组合路由 -> 委托图），全部使用模拟模型
+func TestRouterPipeline(t *testing.T) {
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	metrics := NewRouterMetrics()
+
+	rules, err := NewRuleRouter(DefaultRouteRules)
+	if err != nil {
+		t.Fatalf("NewRuleRouter: %v", err)
+	}
+	embedder := newTestEmbedder()
+	embeddingRouter, err := NewEmbeddingRouter(ctx, embedder, testExemplars, DefaultEmbeddingThreshold)
+	if err != nil {
+		t.Fatalf("NewEmbeddingRouter: %v", err)
+	}
+	llm := &fakeChatModel{replies: []string{`{"destination": "info", "confidence": 0.9, "reason": "一般问题"}`}}
+	llmRouter, err := NewLLMRouter(ctx, llm, registry, DefaultConfidenceThreshold, metrics)
+	if err != nil {
+		t.Fatalf("NewLLMRouter: %v", err)
+	}
+	composite, err := NewCompositeRouter(
+		Stage{Name: "rule", Router: rules},
+		Stage{Name: "embedding", Threshold: 0.8, Router: embeddingRouter},
+		Stage{Name: "llm", Threshold: DefaultConfidenceThreshold, Router: llmRouter},
+	)
+	if err != nil {
+		t.Fatalf("NewCompositeRouter: %v", err)
+	}
+	var entry Router = NewTimeoutRouter(composite, time.Second, rules.GuessRouter(DefaultGuessThreshold), metrics)
+	entry = NewCachedRouter(entry, NewRouteCache(16, time.Minute))
+	graph, err := registry.BuildDelegationGraph(ctx, metrics)
+	if err != nil {
+		t.Fatalf("BuildDelegationGraph: %v", err)
+	}
+
+	tests := []struct {
+		request    string
+		wantSource string
+		wantOutput string
+	}{
+		{"/book 明天去上海", "rule", "booker: /book 明天去上海"},
+		{"订酒店", "embedding", "booker: 订酒店"},
+		{"模棱两可", "llm", "info: 模棱两可"},
+		{"模棱两可 ", "cache", "info: 模棱两可 "},
+	}
+	for _, tt := range tests {
+		d, err := entry.Route(ctx, Request{Text: tt.request})
+		if err != nil {
+			t.Fatalf("Route(%q): %v", tt.request, err)
+		}
+		metrics.RecordDecision(d)
+		if d.Source != tt.wantSource {
+			t.Errorf("Route(%q) 来源 = %s, want %s（轨迹 %v）", tt.request, d.Source, tt.wantSource, d.Trail)
+		}
+		out, err := graph.Invoke(ctx, DelegationInput{Request: tt.request, Decision: d.Destination})
+		if err != nil {
+			t.Fatalf("Invoke(%q): %v", tt.request, err)
+		}
+		if out.Output != tt.wantOutput {
+			t.Errorf("处理结果 = %q, want %q", out.Output, tt.wantOutput)
+		}
+	}
+	if llm.callCount() != 1 {
+		t.Errorf("LLM 调用 %d 次，只有未被规则和嵌入决定、且未命中缓存的请求才调用模型", llm.callCount())
+	}
+	if snap := metrics.Snapshot(); snap.TotalDecisions != 4 || snap.CacheHits != 1 {
+		t.Errorf("指标 = %+v", snap)
+	}
+}
@@ -0,0 +1,159 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// RouteRule: 一条路由规则，Pattern（正则）和 Keywords（关键词列表）任一命中即视为匹配
+type RouteRule struct {
+	Name        string   `json:"name"`               // Name: 规则名称，用于日志输出
+	Destination string   `json:"destination"`        // Destination: 命中后的路由决策（booker / info / unclear）
+	Pattern     string   `json:"pattern,omitempty"`  // Pattern: 正则表达式（可选）
+	Keywords    []string `json:"keywords,omitempty"` // Keywords: 关键词列表，包含任一关键词即命中（可选）
+
+	re *regexp.Regexp // re: 编译后的 Pattern
+}
+
+// DefaultRouteRules: 代码内置的规则（按顺序匹配，先命中者优先）
+var DefaultRouteRules = []RouteRule{
+	{Name: "book-command", Destination: "booker", Pattern: `^/book\b`},
+	{Name: "info-command", Destination: "info", Pattern: `^/info\b`},
+	{Name: "booking-keywords", Destination: "booker", Keywords: []string{"预订", "订票"}},
+}
+
+// RuleRouter: 规则路由
+// 在调用 LLM 之前按顺序评估规则，命中时立即返回决策（零模型调用），未命中时交给后续路由
+type RuleRouter struct {
+	rules []RouteRule
+}
+
+// NewRuleRouter: 创建规则路由，预编译所有正则表达式
+func NewRuleRouter(rules []RouteRule) (*RuleRouter, error) {
+	compiled := make([]RouteRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Destination == "" {
+			return nil, fmt.Errorf("第 %d 条规则 %q 缺少 destination", i+1, rule.Name)
+		}
+		if rule.Pattern == "" && len(rule.Keywords) == 0 {
+			return nil, fmt.Errorf("第 %d 条规则 %q 必须设置 pattern 或 keywords", i+1, rule.Name)
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 条规则 %q 的正则表达式无效: %w", i+1, rule.Name, err)
+			}
+			rule.re = re
+		}
+		compiled = append(compiled, rule)
+	}
+	return &RuleRouter{rules: compiled}, nil
+}
+
+// LoadRouteRules: 从 JSON 文件加载规则（数组格式，顺序即优先级）
+func LoadRouteRules(path string) ([]RouteRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取规则文件失败: %w", err)
+	}
+	var rules []RouteRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("解析规则文件失败: %w", err)
+	}
+	return rules, nil
+}
+
+// Match: 按顺序评估规则，返回第一条命中的规则；未命中时 ok 为 false
+func (r *RuleRouter) Match(request string) (rule RouteRule, ok bool) {
+	text := strings.TrimSpace(request)
+	for _, rule := range r.rules {
+		if rule.re != nil && rule.re.MatchString(text) {
+			return rule, true
+		}
+		for _, kw := range rule.Keywords {
+			if kw != "" && strings.Contains(text, kw) {
+				return rule, true
+			}
+		}
+	}
+	return RouteRule{}, false
+}
+
+// DefaultGuessThreshold: 部分匹配猜测的最低得分，低于该值时不给出猜测
+const DefaultGuessThreshold = 0.5
+
+// Guess: 部分匹配模式，为路由超时等场景给出最佳猜测
+//   - 完整命中规则时与 Match 相同，置信度为 1
+//   - 否则按关键词打分：关键词中出现在请求里的字符比例（如"帮我订一下"对"预订"得 0.5），取最高分的规则，并列时先定义的优先
+//   - 正则规则（命令前缀等）只参与完整匹配；最高分低于 threshold 时 ok 为 false
+func (r *RuleRouter) Guess(request string, threshold float64) (rule RouteRule, score float64, ok bool) {
+	if rule, ok := r.Match(request); ok {
+		return rule, 1, true
+	}
+	text := strings.ToLower(strings.TrimSpace(request))
+	for _, candidate := range r.rules {
+		for _, kw := range candidate.Keywords {
+			if s := keywordOverlap(text, strings.ToLower(kw)); s > score {
+				rule, score = candidate, s
+			}
+		}
+	}
+	if score == 0 || score < threshold {
+		return RouteRule{}, score, false
+	}
+	return rule, score, true
+}
+
+// keywordOverlap: 关键词中（去重后）出现在文本里的字符比例
+func keywordOverlap(text, keyword string) float64 {
+	seen := map[rune]bool{}
+	hits := 0
+	for _, c := range keyword {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		if strings.ContainsRune(text, c) {
+			hits++
+		}
+	}
+	if len(seen) == 0 {
+		return 0
+	}
+	return float64(hits) / float64(len(seen))
+}
+
+// GuessRouter: 以部分匹配模式工作的 Router（来源为 rule-guess，置信度为得分），没有足够好的猜测时返回 ErrNoMatch；
+// 用作 TimeoutRouter 的兜底：规则完整命中的请求不会走到超时，兜底需要的是部分匹配
+func (r *RuleRouter) GuessRouter(threshold float64) Router {
+	return Func(func(ctx context.Context, req Request) (Decision, error) {
+		rule, score, ok := r.Guess(req.Text, threshold)
+		if !ok {
+			return Decision{}, ErrNoMatch
+		}
+		return Decision{
+			Destination: rule.Destination,
+			Source:      "rule-guess",
+			Confidence:  score,
+			Reason:      fmt.Sprintf("部分匹配规则 %s（得分 %.2f）", rule.Name, score),
+		}, nil
+	})
+}
+
+// Route: 命中规则时返回置信度为 1 的决策，未命中时返回 ErrNoMatch
+func (r *RuleRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	rule, ok := r.Match(req.Text)
+	if !ok {
+		return Decision{}, ErrNoMatch
+	}
+	return Decision{
+		Destination: rule.Destination,
+		Source:      "rule",
+		Confidence:  1,
+		Reason:      "命中规则 " + rule.Name,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newDefaultRuleRouter(t *testing.T) *RuleRouter {
+	t.Helper()
+	r, err := NewRuleRouter(DefaultRouteRules)
+	if err != nil {
+		t.Fatalf("NewRuleRouter: %v", err)
+	}
+	return r
+}
+
+func TestRuleRouterRoute(t *testing.T) {
+	r := newDefaultRuleRouter(t)
+	tests := []struct {
+		request  string
+		wantDest string // wantDest: 为空表示未命中
+		wantRule string
+	}{
+		{"/book 明天去上海的机票", "booker", "book-command"},
+		{"  /info 意大利的首都", "info", "info-command"}, // 首尾空白不影响命令前缀
+		{"我想预订一间酒店", "booker", "booking-keywords"},
+		{"帮我订票", "booker", "booking-keywords"},
+		{"/booking 不是命令", "", ""}, // \b 要求完整的命令词
+		{"意大利的首都是什么？", "", ""},
+		{"请 /book 一张票", "", ""}, // 命令必须在开头
+	}
+	for _, tt := range tests {
+		t.Run(tt.request, func(t *testing.T) {
+			d, err := r.Route(context.Background(), Request{Text: tt.request})
+			if tt.wantDest == "" {
+				if !errors.Is(err, ErrNoMatch) {
+					t.Errorf("Route = %+v, %v，want ErrNoMatch", d, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if d.Destination != tt.wantDest || d.Source != "rule" || d.Confidence != 1 {
+				t.Errorf("Route = %+v，want %s（rule，置信度 1）", d, tt.wantDest)
+			}
+			if d.Reason != "命中规则 "+tt.wantRule {
+				t.Errorf("Reason = %q，want 命中规则 %s", d.Reason, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestRuleRouterFirstMatchWins(t *testing.T) {
+	r, err := NewRuleRouter([]RouteRule{
+		{Name: "first", Destination: "info", Keywords: []string{"天气"}},
+		{Name: "second", Destination: "booker", Keywords: []string{"天气", "预订"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRuleRouter: %v", err)
+	}
+	rule, ok := r.Match("预订前先看看天气")
+	if !ok || rule.Name != "first" {
+		t.Errorf("Match = %s, %v，want 先定义的 first", rule.Name, ok)
+	}
+}
+
+func TestNewRuleRouterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    RouteRule
+		wantErr string
+	}{
+		{"缺少 destination", RouteRule{Name: "x", Keywords: []string{"a"}}, "缺少 destination"},
+		{"缺少 pattern 和 keywords", RouteRule{Name: "x", Destination: "info"}, "必须设置 pattern 或 keywords"},
+		{"正则无效", RouteRule{Name: "x", Destination: "info", Pattern: "("}, "正则表达式无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRuleRouter([]RouteRule{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewRuleRouter 错误 = %v，want 包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleRouterIgnoresEmptyKeyword(t *testing.T) {
+	r, err := NewRuleRouter([]RouteRule{{Name: "empty", Destination: "info", Keywords: []string{""}}})
+	if err != nil {
+		t.Fatalf("NewRuleRouter: %v", err)
+	}
+	if _, ok := r.Match("任何请求"); ok {
+		t.Error("空关键词不应匹配任何请求")
+	}
+}
+
+func TestLoadRouteRules(t *testing.T) {
+	rules, err := LoadRouteRules(filepath.Join("..", "rules.example.json"))
+	if err != nil {
+		t.Fatalf("LoadRouteRules: %v", err)
+	}
+	r, err := NewRuleRouter(rules)
+	if err != nil {
+		t.Fatalf("NewRuleRouter: %v", err)
+	}
+	if rule, ok := r.Match("伦敦今天天气怎么样"); !ok || rule.Destination != "info" {
+		t.Errorf("Match = %+v, %v，want 示例文件中的 weather-keywords", rule, ok)
+	}
+
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"name": "not an array"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRouteRules(bad); err == nil || !strings.Contains(err.Error(), "解析规则文件失败") {
+		t.Errorf("LoadRouteRules(bad) 错误 = %v", err)
+	}
+	if _, err := LoadRouteRules(filepath.Join(dir, "missing.json")); err == nil || !strings.Contains(err.Error(), "读取规则文件失败") {
+		t.Errorf("LoadRouteRules(missing) 错误 = %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultRouteTimeout: 单次路由的默认超时时间
+const DefaultRouteTimeout = 15 * time.Second
+
+// TimeoutRouter: 带超时的路由，防止路由模型调用卡住导致没有任何处理程序执行
+//   - 超时后取消内部路由的上下文（链中的模型调用随之中止），不会遗留 goroutine
+//   - 超时时优先采用 fallback（通常是 RuleRouter.GuessRouter 的部分匹配猜测）的决策，没有猜测时路由到 unclear，并计入指标
+//   - 调用方自己的上下文被取消时直接返回错误，不做兜底
+type TimeoutRouter struct {
+	inner    Router
+	fallback Router // fallback: 超时后的兜底路由（可为 nil）
+	timeout  time.Duration
+	metrics  *RouterMetrics
+}
+
+// NewTimeoutRouter: 用 timeout 包装 inner（timeout <= 0 表示不限时）；fallback 和 metrics 可为 nil
+func NewTimeoutRouter(inner Router, timeout time.Duration, fallback Router, metrics *RouterMetrics) *TimeoutRouter {
+	return &TimeoutRouter{inner: inner, fallback: fallback, timeout: timeout, metrics: metrics}
+}
+
+// Route: 在超时时间内执行内部路由，超时后返回兜底决策
+func (t *TimeoutRouter) Route(ctx context.Context, req Request) (Decision, error) {
+	if t.timeout <= 0 {
+		return t.inner.Route(ctx, req)
+	}
+
+	routeCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	type result struct {
+		decision Decision
+		err      error
+	}
+	// done 的缓冲为 1：超时返回后，内部路由收到取消再结束时也不会阻塞在发送上
+	done := make(chan result, 1)
+	go func() {
+		decision, err := t.inner.Route(routeCtx, req)
+		done <- result{decision, err}
+	}()
+
+	select {
+	case res := <-done:
+		// 内部路由因截止时间而失败（模型客户端通常会很快返回上下文错误），同样视为超时
+		if res.err == nil || ctx.Err() != nil || !errors.Is(routeCtx.Err(), context.DeadlineExceeded) {
+			return res.decision, res.err
+		}
+	case <-routeCtx.Done():
+		if ctx.Err() != nil {
+			return Decision{}, ctx.Err()
+		}
+	}
+	return t.fallbackDecision(ctx, req), nil
+}
+
+// fallbackDecision: 记录超时并给出兜底决策（来源为 timeout）
+func (t *TimeoutRouter) fallbackDecision(ctx context.Context, req Request) Decision {
+	if t.metrics != nil {
+		t.metrics.RecordRouteTimeout()
+	}
+
+	if t.fallback != nil {
+		if decision, err := t.fallback.Route(ctx, req); err == nil {
+			decision.Reason = fmt.Sprintf("路由超时（%s），采用 %s 的猜测：%s", t.timeout, decision.Source, decision.Reason)
+			decision.Source = "timeout"
+			decision.Trail = nil
+			decision.Notes = nil
+			return decision
+		}
+	}
+	return Decision{
+		Destination: UnclearRoute,
+		Source:      "timeout",
+		Reason:      fmt.Sprintf("路由超时（%s），没有可用的兜底猜测", t.timeout),
+	}
+}
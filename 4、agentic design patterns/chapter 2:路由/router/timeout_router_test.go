@@ -0,0 +1,172 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuleRouterGuess(t *testing.T) {
+	r := newDefaultRuleRouter(t)
+	tests := []struct {
+		request   string
+		threshold float64
+		wantDest  string // wantDest: 为空表示没有猜测
+		wantScore float64
+	}{
+		{"/book 明天去上海", DefaultGuessThreshold, "booker", 1}, // 完整命中
+		{"帮我订一下", DefaultGuessThreshold, "booker", 0.5},     // "订" 命中 "预订" / "订票" 的一半字符
+		{"帮我订一下", 0.6, "", 0.5},                             // 得分低于阈值
+		{"意大利的首都是什么", DefaultGuessThreshold, "", 0},
+		{"/inf 命令写错了", DefaultGuessThreshold, "", 0}, // 正则规则只参与完整匹配
+	}
+	for _, tt := range tests {
+		t.Run(tt.request, func(t *testing.T) {
+			rule, score, ok := r.Guess(tt.request, tt.threshold)
+			if score != tt.wantScore {
+				t.Errorf("score = %v, want %v", score, tt.wantScore)
+			}
+			if tt.wantDest == "" {
+				if ok {
+					t.Errorf("Guess = %+v，want 没有猜测", rule)
+				}
+				return
+			}
+			if !ok || rule.Destination != tt.wantDest {
+				t.Errorf("Guess = %+v, %v，want %s", rule, ok, tt.wantDest)
+			}
+		})
+	}
+}
+
+func TestRuleRouterGuessTieKeepsFirstRule(t *testing.T) {
+	r, err := NewRuleRouter([]RouteRule{
+		{Name: "weather", Destination: "info", Keywords: []string{"天气"}},
+		{Name: "hotel", Destination: "booker", Keywords: []string{"气球"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRuleRouter: %v", err)
+	}
+	if rule, score, ok := r.Guess("气", DefaultGuessThreshold); !ok || rule.Name != "weather" || score != 0.5 {
+		t.Errorf("Guess = %s（%v）, %v，并列时先定义的规则优先", rule.Name, score, ok)
+	}
+}
+
+func TestGuessRouter(t *testing.T) {
+	g := newDefaultRuleRouter(t).GuessRouter(DefaultGuessThreshold)
+	d, err := g.Route(context.Background(), Request{Text: "帮我订一下"})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if d.Destination != "booker" || d.Source != "rule-guess" || d.Confidence != 0.5 || !strings.Contains(d.Reason, "booking-keywords") {
+		t.Errorf("Route = %+v", d)
+	}
+	if _, err := g.Route(context.Background(), Request{Text: "你好"}); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("没有猜测时应返回 ErrNoMatch，实际 %v", err)
+	}
+}
+
+// newSlowLLMRouter: 模型调用需要 delay 的 LLM 路由
+func newSlowLLMRouter(t *testing.T, delay time.Duration) (*LLMRouter, *fakeChatModel) {
+	t.Helper()
+	llm := &fakeChatModel{replies: []string{`{"destination": "info", "confidence": 0.9, "reason": "问答"}`}, delay: delay}
+	r, err := NewLLMRouter(context.Background(), llm, newTestRegistry(t), DefaultConfidenceThreshold, nil)
+	if err != nil {
+		t.Fatalf("NewLLMRouter: %v", err)
+	}
+	return r, llm
+}
+
+func TestTimeoutRouterFallback(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		request    string
+		wantDest   string
+		wantReason string
+	}{
+		{"帮我订一下", "booker", "采用 rule-guess 的猜测"},
+		{"你好", UnclearRoute, "没有可用的兜底猜测"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.request, func(t *testing.T) {
+			metrics := NewRouterMetrics()
+			inner, _ := newSlowLLMRouter(t, time.Second)
+			r := NewTimeoutRouter(inner, 20*time.Millisecond, newDefaultRuleRouter(t).GuessRouter(DefaultGuessThreshold), metrics)
+
+			start := time.Now()
+			d, err := r.Route(ctx, Request{Text: tt.request})
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("超时后应立即返回，实际耗时 %s", elapsed)
+			}
+			if d.Destination != tt.wantDest || d.Source != "timeout" || !strings.Contains(d.Reason, tt.wantReason) {
+				t.Errorf("Route = %+v", d)
+			}
+			if snap := metrics.Snapshot(); snap.RouteTimeouts != 1 {
+				t.Errorf("超时次数 = %d, want 1", snap.RouteTimeouts)
+			}
+		})
+	}
+}
+
+func TestTimeoutRouterInTime(t *testing.T) {
+	inner, _ := newSlowLLMRouter(t, 0)
+	metrics := NewRouterMetrics()
+	r := NewTimeoutRouter(inner, time.Second, nil, metrics)
+	d, err := r.Route(context.Background(), Request{Text: "意大利的首都"})
+	if err != nil || d.Destination != "info" || d.Source != "llm" {
+		t.Errorf("Route = %+v, %v", d, err)
+	}
+	if metrics.Snapshot().RouteTimeouts != 0 {
+		t.Error("未超时不应计入超时指标")
+	}
+
+	// timeout <= 0 表示不限时
+	if d, err := NewTimeoutRouter(inner, 0, nil, nil).Route(context.Background(), Request{Text: "问题"}); err != nil || d.Source != "llm" {
+		t.Errorf("不限时 Route = %+v, %v", d, err)
+	}
+}
+
+func TestTimeoutRouterCallerCancel(t *testing.T) {
+	inner, _ := newSlowLLMRouter(t, time.Second)
+	r := NewTimeoutRouter(inner, 500*time.Millisecond, newDefaultRuleRouter(t).GuessRouter(DefaultGuessThreshold), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.Route(ctx, Request{Text: "帮我订一下"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("调用方取消时应直接返回错误，实际 %v", err)
+	}
+}
+
+func TestTimeoutRouterInnerError(t *testing.T) {
+	failing := Func(func(ctx context.Context, req Request) (Decision, error) {
+		return Decision{}, errors.New("鉴权失败")
+	})
+	r := NewTimeoutRouter(failing, time.Second, newDefaultRuleRouter(t).GuessRouter(DefaultGuessThreshold), nil)
+	if _, err := r.Route(context.Background(), Request{Text: "帮我订一下"}); err == nil || !strings.Contains(err.Error(), "鉴权失败") {
+		t.Errorf("非超时的错误应原样返回，实际 %v", err)
+	}
+}
+
+// TestTimeoutRouterCancelsInner: 超时后内部路由的上下文被取消，不会遗留 goroutine
+func TestTimeoutRouterCancelsInner(t *testing.T) {
+	before := runtime.NumGoroutine()
+	inner, _ := newSlowLLMRouter(t, 10*time.Second)
+	r := NewTimeoutRouter(inner, 10*time.Millisecond, nil, nil)
+	for i := 0; i < 5; i++ {
+		if _, err := r.Route(context.Background(), Request{Text: "问题"}); err != nil {
+			t.Fatalf("Route: %v", err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before+2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before+2 {
+		t.Errorf("超时后仍有 %d 个 goroutine（之前 %d 个）", n, before)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultBatchConcurrency = 4                // defaultBatchConcurrency: 批处理默认的并发主题数
+	defaultBatchGrace       = 30 * time.Second // defaultBatchGrace: Ctrl-C 后等待进行中主题完成的宽限期
+	maxSlugRunes            = 60               // maxSlugRunes: 输出文件名（不含扩展名）的最大字符数
+)
+
+// loadTopics: 读取主题列表，文件内容以 [ 开头时按 JSON 字符串数组解析，否则每行一个主题（忽略空行和 # 注释）
+func loadTopics(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取主题文件失败: %w", err)
+	}
+
+	var topics []string
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, "[") {
+		var raw []string
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("解析主题 JSON 数组失败: %w", err)
+		}
+		for _, t := range raw {
+			if t = strings.TrimSpace(t); t != "" {
+				topics = append(topics, t)
+			}
+		}
+	} else {
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			topics = append(topics, line)
+		}
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("主题文件 %s 中没有主题", path)
+	}
+	return topics, nil
+}
+
+// slugify: 将主题转换为文件名：保留字母（含中文）和数字，其余字符合并为 "-"
+func slugify(topic string) string {
+	var sb strings.Builder
+	dash := false
+	n := 0
+	for _, r := range strings.ToLower(topic) {
+		if n >= maxSlugRunes {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			dash = false
+			n++
+			continue
+		}
+		if !dash && sb.Len() > 0 {
+			sb.WriteRune('-')
+			dash = true
+			n++
+		}
+	}
+	slug := strings.Trim(sb.String(), "-")
+	if slug == "" {
+		slug = "topic"
+	}
+	return slug
+}
+
+// batchResult: 单个主题的处理结果
+type batchResult struct {
+	Topic   string
+	Path    string // Path: 输出文件路径（失败或未执行时为空）
+	Err     error
+	Elapsed time.Duration
+	Skipped bool // Skipped: 因 Ctrl-C 停止调度而未执行
+}
+
+// batchSummary: 批处理汇总
+type batchSummary struct {
+	Results   []batchResult // Results: 按主题在文件中的顺序排列
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Elapsed   time.Duration
+}
+
+// Print: 打印汇总和失败的主题
+func (s batchSummary) Print() {
+	fmt.Printf("\n--- 批处理汇总 ---\n共 %d 个主题 | 成功 %d | 失败 %d | 未执行 %d | 总耗时 %s\n",
+		len(s.Results), s.Succeeded, s.Failed, s.Skipped, s.Elapsed.Round(time.Millisecond))
+	for _, r := range s.Results {
+		if r.Err != nil {
+			fmt.Printf("  ❌ %s: %v\n", r.Topic, r.Err)
+		}
+	}
+}
+
+// runBatch: 用有限的 worker 池对每个主题执行 run，结果写入 outDir/<slug>.md
+//   - 单个主题失败不影响其他主题，失败计入汇总
+//   - 第一次 Ctrl-C 停止调度新主题，进行中的主题在 grace 宽限期内继续完成，超过宽限期或再按一次 Ctrl-C 时取消
+func runBatch(ctx context.Context, topics []string, concurrency int, outDir string, grace time.Duration,
+	run func(ctx context.Context, topic string) (string, error)) (batchSummary, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return batchSummary{}, fmt.Errorf("创建输出目录失败: %w", err)
+	}
+
+	// 预先分配文件名，重复的 slug 追加序号，避免并发写入同一文件
+	paths := make([]string, len(topics))
+	seen := map[string]int{}
+	for i, topic := range topics {
+		slug := slugify(topic)
+		seen[slug]++
+		if seen[slug] > 1 {
+			slug = fmt.Sprintf("%s-%d", slug, seen[slug])
+		}
+		paths[i] = filepath.Join(outDir, slug+".md")
+	}
+
+	// runCtx: 进行中主题的上下文，宽限期结束或第二次 Ctrl-C 时取消
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	// stop: 第一次 Ctrl-C 时关闭，停止调度新主题
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+		case <-done:
+			return
+		}
+		close(stop)
+		fmt.Printf("\n停止调度新主题，等待进行中的主题完成（宽限期 %s，再按一次 Ctrl-C 立即取消）\n", grace)
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			fmt.Println("宽限期已到，取消进行中的主题")
+		case <-sigCh:
+			fmt.Println("取消进行中的主题")
+		case <-done:
+			return
+		}
+		cancelRun()
+	}()
+
+	start := time.Now()
+	results := make([]batchResult, len(topics))
+	for i, topic := range topics {
+		results[i] = batchResult{Topic: topic, Skipped: true}
+	}
+
+	jobs := make(chan int)
+	var mu sync.Mutex // mu: 保护进度计数和输出
+	finished := 0
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				topicStart := time.Now()
+				res := batchResult{Topic: topics[i]}
+				output, err := run(runCtx, topics[i])
+				if err == nil {
+					content := fmt.Sprintf("# %s\n\n%s\n", topics[i], output)
+					if err = os.WriteFile(paths[i], []byte(content), 0o644); err != nil {
+						err = fmt.Errorf("写入结果失败: %w", err)
+					}
+				}
+				res.Elapsed = time.Since(topicStart)
+				if err != nil {
+					res.Err = err
+				} else {
+					res.Path = paths[i]
+				}
+				results[i] = res
+
+				mu.Lock()
+				finished++
+				if err != nil {
+					fmt.Printf("[%d/%d] ❌ %s（%s）: %v\n", finished, len(topics), topics[i], res.Elapsed.Round(time.Millisecond), err)
+				} else {
+					fmt.Printf("[%d/%d] ✅ %s -> %s（%s）\n", finished, len(topics), topics[i], res.Path, res.Elapsed.Round(time.Millisecond))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+schedule:
+	for i := range topics {
+		select {
+		case jobs <- i:
+		case <-stop:
+			break schedule
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	summary := batchSummary{Results: results, Elapsed: time.Since(start)}
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			summary.Skipped++
+		case r.Err != nil:
+			summary.Failed++
+		default:
+			summary.Succeeded++
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBranchTimeout    = 45 * time.Second // defaultBranchTimeout: 单个分支的默认超时时间
+	defaultDeadline         = 90 * time.Second // defaultDeadline: 整个并行处理（并行图 + 综合链）的默认截止时间
+	defaultSynthesisReserve = 20 * time.Second // defaultSynthesisReserve: 为综合链预留的时间，并行图必须在此之前结束
+
+	defaultRPS         = 2.0 // defaultRPS: 默认每秒模型请求数
+	defaultBurst       = 4   // defaultBurst: 默认令牌桶容量，一个主题的所有分支可以立即发出
+	defaultMaxInFlight = 8   // defaultMaxInFlight: 默认同时进行的模型请求数
+)
+
+// timeoutPlaceholder: 分支超时时写入结果的占位值，综合提示词会据此忽略缺失的部分
+func timeoutPlaceholder(label string) string {
+	return label + "不可用（超时）"
+}
+
+// failurePlaceholder: 非关键分支失败时写入结果的占位值
+func failurePlaceholder(label string) string {
+	return label + "不可用（失败）"
+}
+
+// 分支失败的哨兵错误，调用方可用 errors.Is 判断失败原因
+var (
+	ErrBranchTimeout     = errors.New("分支超时")  // ErrBranchTimeout: 分支超过自己的超时时间
+	ErrBranchRateLimited = errors.New("分支被限流") // ErrBranchRateLimited: 重试用尽后仍被服务商限流（429）
+)
+
+// BranchError: 单个分支的失败，记录分支和失败前的耗时
+type BranchError struct {
+	Key     string        // Key: 分支名称
+	Label   string        // Label: 分支中文标题
+	Elapsed time.Duration // Elapsed: 分支开始到失败的耗时
+	Err     error         // Err: 分支的错误
+}
+
+func (e *BranchError) Error() string {
+	return fmt.Sprintf("分支 %s（%s）耗时 %s 后失败: %v", e.Key, e.Label, e.Elapsed.Round(time.Millisecond), e.Err)
+}
+
+func (e *BranchError) Unwrap() error {
+	return e.Err
+}
+
+// CriticalBranchError: 关键分支失败（包括超时），整个并行图随之取消
+type CriticalBranchError struct {
+	Key   string // Key: 分支名称
+	Label string // Label: 分支中文标题
+	Err   error  // Err: 分支的错误（*BranchError）
+}
+
+func (e *CriticalBranchError) Error() string {
+	return fmt.Sprintf("关键分支失败: %v", e.Err)
+}
+
+func (e *CriticalBranchError) Unwrap() error {
+	return e.Err
+}
+
+// PartialResultError: 有非关键分支失败或超时，并行图仍返回结果（失败的分支为占位值）
+type PartialResultError struct {
+	Err error // Err: errors.Join 合并的各分支错误
+}
+
+func (e *PartialResultError) Error() string {
+	return fmt.Sprintf("%d 个分支失败: %v", len(splitErrors(e.Err)), e.Err)
+}
+
+func (e *PartialResultError) Unwrap() error {
+	return e.Err
+}
+
+// isPartialResult: 错误是否只是非关键分支失败（结果仍可用）
+func isPartialResult(err error) bool {
+	var partial *PartialResultError
+	return errors.As(err, &partial)
+}
+
+// splitErrors: 展开 errors.Join 合并的错误（只展开一层）
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// printBranchErrors: 打印多分支失败的摘要，每个分支一行
+func printBranchErrors(w io.Writer, err error) {
+	errs := splitErrors(err)
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "⚠ %d 个分支失败:\n", len(errs))
+	for _, e := range errs {
+		fmt.Fprintf(w, "  - %v\n", e)
+	}
+}
+
+// branchErrors: 一次并行图执行中收集的分支错误，按分支 Key 记录
+type branchErrors struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+// record: 记录分支的错误
+func (b *branchErrors) record(key string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.errs == nil {
+		b.errs = map[string]error{}
+	}
+	b.errs[key] = err
+}
+
+// ordered: 按分支定义的顺序返回已记录的错误
+func (b *branchErrors) ordered(specs []BranchSpec) []error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var errs []error
+	for _, spec := range specs {
+		if err, ok := b.errs[spec.Key]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// branchErrorsKey: 上下文中分支错误收集器的键
+type branchErrorsKey struct{}
+
+// withBranchErrors: 在上下文上挂错误收集器，分支失败时记录到其中
+func withBranchErrors(ctx context.Context, errs *branchErrors) context.Context {
+	return context.WithValue(ctx, branchErrorsKey{}, errs)
+}
+
+// recordBranchError: 把分支错误记录到上下文中的收集器（没有收集器时什么都不做）
+func recordBranchError(ctx context.Context, key string, err error) {
+	if errs, ok := ctx.Value(branchErrorsKey{}).(*branchErrors); ok {
+		errs.record(key, err)
+	}
+}
+
+// abortKey: 上下文中并行图取消函数的键
+type abortKey struct{}
+
+// withAbort: 在上下文上挂取消函数，关键分支失败时用它取消所有兄弟分支
+func withAbort(ctx context.Context, cancel context.CancelCauseFunc) context.Context {
+	return context.WithValue(ctx, abortKey{}, cancel)
+}
+
+// abortGraph: 以 cause 取消整个并行图（上下文中没有取消函数时什么都不做）
+func abortGraph(ctx context.Context, cause error) {
+	if cancel, ok := ctx.Value(abortKey{}).(context.CancelCauseFunc); ok {
+		cancel(cause)
+	}
+}
+
+// runBranch: 在超时限制内执行分支
+//   - 超时（包括并行图整体的截止时间先到）时返回占位值而不是让整个图失败
+//   - 超时后取消分支的上下文，模型调用随之中止；即使 run 不理会取消，也会按时返回
+//   - 调用方主动取消时返回错误
+func runBranch(ctx context.Context, label string, timeout time.Duration, run func(ctx context.Context) (string, error)) (string, error) {
+	branchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		out string
+		err error
+	}
+	// done 的缓冲为 1：超时返回后，分支收到取消再结束时也不会阻塞在发送上
+	done := make(chan result, 1)
+	go func() {
+		out, err := run(branchCtx)
+		done <- result{out, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err == nil || !errors.Is(branchCtx.Err(), context.DeadlineExceeded) {
+			return res.out, res.err
+		}
+	case <-branchCtx.Done():
+		if !errors.Is(branchCtx.Err(), context.DeadlineExceeded) {
+			return "", branchCtx.Err()
+		}
+	}
+	fmt.Printf("⏱ %s分支超时，使用占位值\n", label)
+	return timeoutPlaceholder(label), nil
+}
+
+// parseBranchTimeouts: 解析逗号分隔的分支超时配置（如 "summarize=30s,terms=10s"）
+func parseBranchTimeouts(spec string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("分支超时配置格式错误: %q（应为 名称=时长）", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("分支 %s 的超时时长无效: %w", name, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("分支 %s 的超时时长必须为正数", name)
+		}
+		timeouts[strings.TrimSpace(name)] = d
+	}
+	return timeouts, nil
+}
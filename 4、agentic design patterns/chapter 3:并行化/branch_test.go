@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBranch(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		run     func(ctx context.Context) (string, error)
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "按时完成",
+			timeout: time.Second,
+			run:     func(ctx context.Context) (string, error) { return "摘要内容", nil },
+			want:    "摘要内容",
+		},
+		{
+			name:    "分支自己的错误原样返回",
+			timeout: time.Second,
+			run:     func(ctx context.Context) (string, error) { return "", errors.New("模型错误") },
+			wantErr: "模型错误",
+		},
+		{
+			name:    "超时返回占位值",
+			timeout: 20 * time.Millisecond,
+			run: func(ctx context.Context) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			},
+			want: timeoutPlaceholder("摘要"),
+		},
+		{
+			name:    "不理会取消的分支也按时返回",
+			timeout: 20 * time.Millisecond,
+			run: func(ctx context.Context) (string, error) {
+				time.Sleep(time.Second)
+				return "太晚了", nil
+			},
+			want: timeoutPlaceholder("摘要"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			got, err := runBranch(ctx, "摘要", tt.timeout, tt.run)
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("runBranch 耗时 %s，超时后应立即返回", elapsed)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("runBranch 错误 = %v，want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("runBranch = %q, %v，want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunBranchOverallDeadline: 整体截止时间先于分支超时到达时同样返回占位值
+func TestRunBranchOverallDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	got, err := runBranch(ctx, "问题", time.Minute, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if err != nil || got != timeoutPlaceholder("问题") {
+		t.Errorf("runBranch = %q, %v", got, err)
+	}
+}
+
+func TestRunBranchCallerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := runBranch(ctx, "摘要", time.Minute, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("调用方取消时应返回错误，实际 %v", err)
+	}
+}
+
+func TestParseBranchTimeouts(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    map[string]time.Duration
+		wantErr string
+	}{
+		{"", map[string]time.Duration{}, ""},
+		{"summarize=30s, terms=500ms ,", map[string]time.Duration{"summarize": 30 * time.Second, "terms": 500 * time.Millisecond}, ""},
+		{"summarize", nil, "格式错误"},
+		{"summarize=abc", nil, "超时时长无效"},
+		{"summarize=0s", nil, "必须为正数"},
+		{"terms=-1s", nil, "必须为正数"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseBranchTimeouts(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseBranchTimeouts 错误 = %v，want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || len(got) != len(tt.want) {
+				t.Fatalf("parseBranchTimeouts = %v, %v", got, err)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %s, want %s", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// TestBranchErrorsAggregated: 多个分支失败时用 errors.Join 按分支定义顺序合并，哨兵错误可用 errors.Is 判断
+func TestBranchErrorsAggregated(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		switch spec.Key {
+		case "counterarguments":
+			<-ctx.Done()
+			return "", ctx.Err()
+		case "terms":
+			return "", errors.New("429 too many requests")
+		case "questions":
+			time.Sleep(30 * time.Millisecond) // 晚于其他分支失败，但合并后仍排在前面
+			return "", errors.New("模型故障")
+		}
+		return spec.Key, nil
+	})}
+	timeouts := func(key string) time.Duration {
+		if key == "counterarguments" {
+			return 10 * time.Millisecond
+		}
+		return time.Second
+	}
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, timeouts, nil, noRetry, nil)
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	result, err := graph.Invoke(ctx, ParallelInput{Topic: "主题"})
+	if result == nil || !isPartialResult(err) {
+		t.Fatalf("结果 = %v，错误 = %v，want 部分结果", result, err)
+	}
+
+	var partial *PartialResultError
+	errors.As(err, &partial)
+	errs := splitErrors(partial.Err)
+	var keys []string
+	for _, e := range errs {
+		var branchErr *BranchError
+		if !errors.As(e, &branchErr) {
+			t.Fatalf("%v 不是 *BranchError", e)
+		}
+		keys = append(keys, branchErr.Key)
+	}
+	if got := strings.Join(keys, ","); got != "questions,terms,counterarguments" {
+		t.Errorf("错误顺序 = %s，want 按分支定义顺序", got)
+	}
+	if !strings.HasPrefix(err.Error(), "3 个分支失败") {
+		t.Errorf("错误信息 = %q", err)
+	}
+	if !errors.Is(errs[1], ErrBranchRateLimited) || errors.Is(errs[0], ErrBranchRateLimited) {
+		t.Errorf("限流哨兵错误判断错误: %v", errs)
+	}
+	if !errors.Is(errs[2], ErrBranchTimeout) || !errors.Is(errs[2], context.DeadlineExceeded) {
+		t.Errorf("超时分支的错误应同时匹配 ErrBranchTimeout 和 context.DeadlineExceeded: %v", errs[2])
+	}
+	if got := result["counterarguments"]; got != timeoutPlaceholder("反方观点") {
+		t.Errorf("超时分支结果 = %v", got)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	a, b := errors.New("a"), errors.New("b")
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"单个错误", a, 1},
+		{"合并的错误", errors.Join(a, b), 2},
+		{"只展开一层", errors.Join(a, errors.Join(a, b)), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(splitErrors(tt.err)); got != tt.want {
+				t.Errorf("splitErrors 数量 = %d，want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintBranchErrors(t *testing.T) {
+	var sb strings.Builder
+	printBranchErrors(&sb, nil)
+	if sb.Len() != 0 {
+		t.Errorf("没有错误时不应输出: %q", sb.String())
+	}
+
+	err := errors.Join(
+		&BranchError{Key: "questions", Label: "相关问题", Elapsed: 1500 * time.Millisecond, Err: errors.New("模型故障")},
+		&CriticalBranchError{Key: "summarize", Label: "摘要", Err: &BranchError{Key: "summarize", Label: "摘要", Err: ErrBranchTimeout}},
+	)
+	printBranchErrors(&sb, err)
+	want := "⚠ 2 个分支失败:\n" +
+		"  - 分支 questions（相关问题）耗时 1.5s 后失败: 模型故障\n" +
+		"  - 关键分支失败: 分支 summarize（摘要）耗时 0s 后失败: 分支超时\n"
+	if sb.String() != want {
+		t.Errorf("输出 = %q\nwant %q", sb.String(), want)
+	}
+}
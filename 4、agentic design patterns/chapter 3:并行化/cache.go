@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultCacheDir = ".cache/parallel" // defaultCacheDir: 默认的结果缓存目录
+	defaultCacheTTL = 24 * time.Hour    // defaultCacheTTL: 默认的缓存有效期
+)
+
+// cacheEntry: 一次完整并行处理的缓存结果
+type cacheEntry struct {
+	Topic     string         `json:"topic"`
+	Model     string         `json:"model"`
+	CreatedAt time.Time      `json:"created_at"`
+	Plan      []string       `json:"plan"`     // Plan: 实际运行的分支 Key
+	Branches  map[string]any `json:"branches"` // Branches: 并行图的输出（各分支结果和主题）
+	Answer    string         `json:"answer"`   // Answer: 综合链的最终答案
+}
+
+// resultCache: 磁盘上的结果缓存，每个键一个 JSON 文件；nil *resultCache 表示不使用缓存
+type resultCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// newResultCache: 创建结果缓存
+func newResultCache(dir string, ttl time.Duration) *resultCache {
+	return &resultCache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// cacheKey: 缓存键，由主题、分支定义、模型名称和是否启用规划共同决定，任一变化都不会命中旧结果
+func cacheKey(topic string, specs []BranchSpec, modelName string, planned bool) string {
+	data, _ := json.Marshal(struct {
+		Topic    string
+		Branches []BranchSpec
+		Model    string
+		Planned  bool
+	}{topic, specs, modelName, planned})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// path: 键对应的缓存文件路径
+func (c *resultCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get: 读取缓存，文件不存在、已过期或内容损坏时视为未命中（损坏的条目会打印提示并被忽略）
+func (c *resultCache) Get(key string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("⚠ 读取缓存失败，已忽略: %v\n", err)
+		}
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Branches == nil {
+		fmt.Printf("⚠ 缓存条目 %s 已损坏，已忽略\n", c.path(key))
+		return cacheEntry{}, false
+	}
+	if c.ttl > 0 && c.now().Sub(entry.CreatedAt) > c.ttl {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// Put: 原子写入缓存：先写同目录下的临时文件再重命名，中断的写入不会留下半个条目
+func (c *resultCache) Put(key string, entry cacheEntry) error {
+	if c == nil {
+		return nil
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = c.now()
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化缓存条目失败: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建缓存临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后删除会失败，忽略即可
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("保存缓存失败: %w", err)
+	}
+	return nil
+}
+
+// cacheable: 只有所有分支都给出真实结果时才缓存，含超时或失败占位值的结果不缓存
+func cacheable(specs []BranchSpec, branches map[string]any) bool {
+	for _, spec := range specs {
+		value, ok := branches[spec.OutputKey].(string)
+		if !ok || value == timeoutPlaceholder(spec.Label) || value == failurePlaceholder(spec.Label) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCache: 在临时目录创建缓存，now 返回 *clock
+func newTestCache(t *testing.T, ttl time.Duration, clock *time.Time) *resultCache {
+	t.Helper()
+	c := newResultCache(filepath.Join(t.TempDir(), "cache"), ttl)
+	c.now = func() time.Time { return *clock }
+	return c
+}
+
+func TestCacheKey(t *testing.T) {
+	base := cacheKey("太空探索", defaultBranches, "gpt", false)
+	if base != cacheKey("太空探索", defaultBranches, "gpt", false) {
+		t.Fatalf("相同输入的键应相同")
+	}
+	changedSpec := append([]BranchSpec(nil), defaultBranches...)
+	changedSpec[0].SystemPrompt = "换一个提示词"
+	for name, key := range map[string]string{
+		"主题":   cacheKey("深海探索", defaultBranches, "gpt", false),
+		"分支定义": cacheKey("太空探索", changedSpec, "gpt", false),
+		"分支子集": cacheKey("太空探索", defaultBranches[:2], "gpt", false),
+		"模型":   cacheKey("太空探索", defaultBranches, "claude", false),
+		"启用规划": cacheKey("太空探索", defaultBranches, "gpt", true),
+	} {
+		if key == base {
+			t.Errorf("%s变化后键不应相同", name)
+		}
+	}
+}
+
+func TestResultCacheRoundTrip(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newTestCache(t, time.Hour, &now)
+
+	if _, ok := c.Get("k"); ok {
+		t.Fatalf("空缓存不应命中")
+	}
+	entry := cacheEntry{Topic: "主题", Model: "gpt", Plan: []string{"summarize"}, Branches: map[string]any{"summary": "S"}, Answer: "答案"}
+	if err := c.Put("k", entry); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, ok := c.Get("k")
+	if !ok {
+		t.Fatalf("写入后应命中")
+	}
+	if got.Answer != "答案" || got.Branches["summary"] != "S" || !got.CreatedAt.Equal(now) {
+		t.Errorf("读取结果 = %+v", got)
+	}
+
+	// 写入后目录中只有正式文件，没有残留的临时文件
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		t.Fatalf("读取缓存目录: %v", err)
+	}
+	if len(files) != 1 || files[0].Name() != "k.json" {
+		t.Errorf("缓存目录内容 = %v", files)
+	}
+}
+
+func TestResultCacheTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newTestCache(t, time.Hour, &now)
+	if err := c.Put("k", cacheEntry{Branches: map[string]any{}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	now = now.Add(59 * time.Minute)
+	if _, ok := c.Get("k"); !ok {
+		t.Errorf("有效期内应命中")
+	}
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("k"); ok {
+		t.Errorf("过期后不应命中")
+	}
+
+	// TTL 为 0 表示永不过期
+	c.ttl = 0
+	if _, ok := c.Get("k"); !ok {
+		t.Errorf("TTL 为 0 时应命中")
+	}
+}
+
+func TestResultCacheCorruptEntry(t *testing.T) {
+	now := time.Now()
+	c := newTestCache(t, time.Hour, &now)
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for key, content := range map[string]string{
+		"truncated":   `{"topic": "主`,
+		"no-branches": `{"topic": "主题", "answer": "答案"}`,
+	} {
+		if err := os.WriteFile(c.path(key), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := c.Get(key); ok {
+			t.Errorf("%s: 损坏的条目不应命中", key)
+		}
+	}
+}
+
+func TestNilResultCache(t *testing.T) {
+	var c *resultCache
+	if err := c.Put("k", cacheEntry{}); err != nil {
+		t.Errorf("nil 缓存 Put: %v", err)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Errorf("nil 缓存不应命中")
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	specs := defaultBranches[:2]
+	full := map[string]any{"summary": "S", "questions": "Q"}
+	tests := []struct {
+		name     string
+		branches map[string]any
+		want     bool
+	}{
+		{"全部成功", full, true},
+		{"有超时占位值", map[string]any{"summary": "S", "questions": timeoutPlaceholder("相关问题")}, false},
+		{"有失败占位值", map[string]any{"summary": "S", "questions": failurePlaceholder("相关问题")}, false},
+		{"缺少分支", map[string]any{"summary": "S"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cacheable(specs, tt.branches); got != tt.want {
+				t.Errorf("cacheable = %v，want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"shared/ratelimit"
+	"shared/retry"
+	"shared/stats"
+
+	"github.com/cloudwego/eino/components/model"
+)
+
+// branchRecorder: 收集分支完成事件（buildParallelGraph 会串行化回调，无需加锁）
+type branchRecorder map[string]BranchEvent
+
+// record: 作为 ProgressFunc 使用
+func (r branchRecorder) record(event BranchEvent) {
+	r[event.Key] = event
+}
+
+// measurements: 按分支定义的顺序生成测量结果
+func (r branchRecorder) measurements(specs []BranchSpec) []stats.Measurement {
+	measurements := make([]stats.Measurement, 0, len(specs))
+	for _, spec := range specs {
+		event := r[spec.Key]
+		measurements = append(measurements, stats.Measurement{
+			Name:    spec.OutputKey,
+			Elapsed: event.Elapsed,
+			Usage:   event.Usage,
+		})
+	}
+	return measurements
+}
+
+// runCompare: 用同一主题分别串行（逐个执行单分支图）和并行（一次执行完整并行图）运行所有分支，
+// 测量每个分支的耗时、整体耗时和 token 用量（不含综合链）
+// 两种方式各用一个新的限流器（newLimiter），避免前一种方式消耗的令牌影响后一种的耗时
+func runCompare(ctx context.Context, llm model.BaseChatModel, specs []BranchSpec, branchTimeout func(key string) time.Duration,
+	newLimiter func() *ratelimit.Limiter, retryPolicy retry.Policy, topic string) (serial, parallel stats.Run, err error) {
+	counting := stats.NewCountingModel(llm)
+	input := ParallelInput{Topic: topic}
+
+	// 串行：每个分支单独构建一个只含该分支的图，依次执行
+	serialEvents := branchRecorder{}
+	serialLimiter := newLimiter()
+	graphs := make([]func(ctx context.Context) error, 0, len(specs))
+	for _, spec := range specs {
+		g, err := buildParallelGraph(ctx, counting, []BranchSpec{spec}, branchTimeout, serialLimiter, retryPolicy, serialEvents.record)
+		if err != nil {
+			return serial, parallel, fmt.Errorf("构建%s单分支图失败: %w", spec.Label, err)
+		}
+		graphs = append(graphs, func(ctx context.Context) error {
+			_, err := g.Invoke(ctx, input)
+			if isPartialResult(err) {
+				return nil // 非关键分支失败不影响对比（以占位值计入）
+			}
+			return err
+		})
+	}
+	start := time.Now()
+	for i, run := range graphs {
+		if err := run(ctx); err != nil {
+			return serial, parallel, fmt.Errorf("串行执行%s分支失败: %w", specs[i].Label, err)
+		}
+	}
+	serial = stats.Run{Mode: "串行", Branches: serialEvents.measurements(specs), Total: time.Since(start)}
+
+	// 并行：完整并行图一次执行所有分支
+	parallelEvents := branchRecorder{}
+	g, err := buildParallelGraph(ctx, counting, specs, branchTimeout, newLimiter(), retryPolicy, parallelEvents.record)
+	if err != nil {
+		return serial, parallel, fmt.Errorf("构建并行图失败: %w", err)
+	}
+	start = time.Now()
+	if _, err := g.Invoke(ctx, input); err != nil && !isPartialResult(err) {
+		return serial, parallel, fmt.Errorf("并行执行失败: %w", err)
+	}
+	parallel = stats.Run{Mode: "并行", Branches: parallelEvents.measurements(specs), Total: time.Since(start)}
+
+	return serial, parallel, nil
+}
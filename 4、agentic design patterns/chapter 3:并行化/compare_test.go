@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shared/ratelimit"
+)
+
+// noLimiter: 不限流的 newLimiter
+func noLimiter() *ratelimit.Limiter { return nil }
+
+func TestRunCompare(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		time.Sleep(50 * time.Millisecond)
+		return spec.Key, nil
+	})}
+
+	serial, parallel, err := runCompare(ctx, llm, defaultBranches, fixedTimeout(time.Second), noLimiter, noRetry, "主题")
+	if err != nil {
+		t.Fatalf("runCompare: %v", err)
+	}
+	for _, run := range []struct {
+		name string
+		got  int
+	}{{"串行", len(serial.Branches)}, {"并行", len(parallel.Branches)}} {
+		if run.got != len(defaultBranches) {
+			t.Errorf("%s分支数 = %d，want %d", run.name, run.got, len(defaultBranches))
+		}
+	}
+	for i, spec := range defaultBranches {
+		m := serial.Branches[i]
+		if m.Name != spec.OutputKey || m.Elapsed < 50*time.Millisecond {
+			t.Errorf("串行第 %d 个分支 = %+v", i+1, m)
+		}
+		// fakeModel 每次调用返回 10 + 5 个 token，经 CountingModel 计入分支用量
+		if m.Usage.Total() != 15 {
+			t.Errorf("%s 用量 = %+v，want 15", m.Name, m.Usage)
+		}
+	}
+	if serial.Total < serial.SumBranches() {
+		t.Errorf("串行总耗时 %s 小于各分支之和 %s", serial.Total, serial.SumBranches())
+	}
+	if parallel.Total >= serial.Total {
+		t.Errorf("并行耗时 %s 不应超过串行 %s", parallel.Total, serial.Total)
+	}
+	if got := llm.callsWith(defaultBranches[0].SystemPrompt); got != 2 {
+		t.Errorf("摘要分支调用 %d 次，want 串行、并行各 1 次", got)
+	}
+}
+
+// TestRunCompareTolerantOfNonCriticalFailures: 非关键分支失败以占位值计入对比，关键分支失败时返回错误
+func TestRunCompareTolerantOfNonCriticalFailures(t *testing.T) {
+	ctx := context.Background()
+	failing := func(key string) *fakeModel {
+		return &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+			if spec.Key == key {
+				return "", errors.New("模型故障")
+			}
+			return spec.Key, nil
+		})}
+	}
+
+	if _, _, err := runCompare(ctx, failing("terms"), defaultBranches, fixedTimeout(time.Second), noLimiter, noRetry, "主题"); err != nil {
+		t.Errorf("非关键分支失败时 runCompare: %v", err)
+	}
+	if _, _, err := runCompare(ctx, failing("summarize"), defaultBranches, fixedTimeout(time.Second), noLimiter, noRetry, "主题"); err == nil {
+		t.Errorf("关键分支失败时 runCompare 应返回错误")
+	}
+}
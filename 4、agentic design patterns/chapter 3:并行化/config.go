@@ -0,0 +1,219 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"shared/ratelimit"
+	"shared/retry"
+)
+
+const (
+	defaultTopic       = "太空探索的历史"                   // defaultTopic: 单主题模式的默认主题
+	defaultModel       = "deepseek-ai/DeepSeek-V3.1" // defaultModel: 默认模型名称
+	defaultTemperature = 0.7                         // defaultTemperature: 默认采样温度，0.7 输出更自然
+)
+
+// cliConfig: 命令行参数和环境变量解析后的运行配置
+type cliConfig struct {
+	Topic       string       // Topic: 单主题模式的主题
+	Model       string       // Model: 模型名称
+	Temperature float64      // Temperature: 采样温度
+	Branches    []BranchSpec // Branches: 要运行的分支（按注册顺序），未指定时为所有注册的分支
+
+	BranchTimeout  time.Duration            // BranchTimeout: 分支的默认超时（BRANCH_TIMEOUT）
+	BranchTimeouts map[string]time.Duration // BranchTimeouts: 按分支 Key 覆盖的超时（BRANCH_TIMEOUTS）
+	Deadline       time.Duration            // Deadline: 整个并行处理的截止时间（-timeout，未指定时取 PARALLEL_DEADLINE）
+
+	BatchPath   string
+	Concurrency int
+	OutDir      string
+	Grace       time.Duration
+
+	Limiter     ratelimit.Config
+	MaxAttempts int
+
+	Compare bool
+
+	SummarizePath string
+	MapReduce     mapReduceConfig
+
+	NoCache  bool
+	CacheDir string
+	CacheTTL time.Duration
+
+	Format  string
+	OutPath string
+
+	PipelinePath string
+	StageWorkers [3]int
+
+	Plan   bool
+	Stream bool
+
+	RecordPath string // RecordPath: 单主题运行后把各分支原始输出录制到该文件
+	ReplayPath string // ReplayPath: 从该文件回放分支输出，只执行综合链
+}
+
+// branchTimeout: 返回分支的超时时间（未单独配置时使用默认值）
+func (c cliConfig) branchTimeout(key string) time.Duration {
+	if d, ok := c.BranchTimeouts[key]; ok {
+		return d
+	}
+	return c.BranchTimeout
+}
+
+// newCLIConfig: 解析命令行参数（不含程序名）和环境变量，参数无效时返回错误；-h 时返回 flag.ErrHelp
+//   - registry: 注册的分支，-branches 只能从中选择
+//   - getenv: 读取环境变量（通常是 os.Getenv）
+//   - output: 帮助和解析错误的输出位置
+func newCLIConfig(args []string, registry []BranchSpec, getenv func(string) string, output io.Writer) (cliConfig, error) {
+	var c cliConfig
+	fs := flag.NewFlagSet("parallel", flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	// topic / model / temperature / branches / timeout: 单主题运行的基本参数
+	fs.StringVar(&c.Topic, "topic", defaultTopic, "单主题模式的主题")
+	fs.StringVar(&c.Model, "model", defaultModel, "模型名称")
+	fs.Float64Var(&c.Temperature, "temperature", defaultTemperature, "采样温度（0-2）")
+	branches := fs.String("branches", "", "要运行的分支 Key，逗号分隔（为空时运行所有分支，可选 "+strings.Join(branchKeys(registry), ", ")+"）")
+	timeout := fs.Duration("timeout", 0, "整个并行处理（并行图 + 综合链）的截止时间（为空时取 PARALLEL_DEADLINE 或默认值）")
+	// batch: 批处理模式，从文件读取主题（每行一个或 JSON 字符串数组），逐个执行并行处理
+	fs.StringVar(&c.BatchPath, "batch", "", "主题文件路径（每行一个主题或 JSON 字符串数组）")
+	fs.IntVar(&c.Concurrency, "concurrency", defaultBatchConcurrency, "批处理时同时处理的主题数")
+	fs.StringVar(&c.OutDir, "out-dir", "outputs", "批处理结果的输出目录，每个主题写入 <slug>.md")
+	// grace: Ctrl-C 后进行中主题的宽限期，超过后取消
+	fs.DurationVar(&c.Grace, "grace", defaultBatchGrace, "Ctrl-C 后等待进行中主题完成的宽限期")
+	// rps / burst / max-in-flight / aging: 所有分支（包括批处理中的所有主题）共享的模型调用限流
+	fs.Float64Var(&c.Limiter.RPS, "rps", defaultRPS, "每秒最多发起的模型请求数（<=0 不限速）")
+	fs.IntVar(&c.Limiter.Burst, "burst", defaultBurst, "限流令牌桶容量（允许的突发请求数）")
+	fs.IntVar(&c.Limiter.MaxInFlight, "max-in-flight", defaultMaxInFlight, "同时进行的最大模型请求数（<=0 不限制）")
+	fs.DurationVar(&c.Limiter.AgingInterval, "aging", ratelimit.DefaultAgingInterval, "排队请求每等待该时长提升一级优先级")
+	// max-attempts: 分支遇到瞬时错误（如 429）时的最多尝试次数
+	fs.IntVar(&c.MaxAttempts, "max-attempts", retry.DefaultMaxAttempts, "分支模型调用的最多尝试次数（含第一次，1 表示不重试）")
+	// compare: 对比串行和并行执行分支的耗时与 token 用量
+	fs.BoolVar(&c.Compare, "compare", false, "先串行、再并行执行所有分支，打印耗时和 token 用量对比表")
+	// summarize: 长文本分片摘要模式（数据并行 + 分层归约）
+	fs.StringVar(&c.SummarizePath, "summarize", "", "长文本文件路径，分片并发摘要后逐层归约为一个摘要")
+	fs.IntVar(&c.MapReduce.ChunkSize, "chunk-size", defaultChunkSize, "分片摘要的片段长度（字符数）")
+	fs.IntVar(&c.MapReduce.Overlap, "chunk-overlap", defaultOverlap, "相邻片段的重叠字符数")
+	fs.IntVar(&c.MapReduce.GroupSize, "reduce-group", defaultReduceGroup, "归约时每组合并的摘要数")
+	// no-cache / cache-dir / cache-ttl: 磁盘结果缓存
+	fs.BoolVar(&c.NoCache, "no-cache", false, "不读取也不写入结果缓存")
+	fs.StringVar(&c.CacheDir, "cache-dir", defaultCacheDir, "结果缓存目录")
+	fs.DurationVar(&c.CacheTTL, "cache-ttl", defaultCacheTTL, "结果缓存的有效期（<=0 表示永不过期）")
+	// format / out: 单主题模式的输出格式，json 输出结构化结果（摘要、问题列表、术语列表、综合答案和统计）
+	fs.StringVar(&c.Format, "format", "prose", "输出格式：prose（默认，文字）或 json")
+	fs.StringVar(&c.OutPath, "out", "", "JSON 输出文件路径（为空时输出到标准输出）")
+	// pipeline: 流水线并行示例，段落依次经过 预处理 -> LLM 转换 -> 后处理 三个阶段
+	fs.StringVar(&c.PipelinePath, "pipeline", "", "文本文件路径（段落以空行分隔），以三阶段流水线逐段处理")
+	stageWorkers := fs.String("stage-workers", defaultStageWorkers, "流水线三个阶段的 worker 数（预处理,LLM,后处理）")
+	// plan: 由规划链按主题选择要运行的分支
+	fs.BoolVar(&c.Plan, "plan", false, "启用规划链，按主题选择值得运行的分支")
+	// stream: 单主题模式下逐块输出综合结果
+	fs.BoolVar(&c.Stream, "stream", false, "流式输出最终综合结果")
+	// record / replay: 录制分支输出，之后只重跑综合链，便于迭代综合提示词
+	fs.StringVar(&c.RecordPath, "record", "", "把各分支的原始输出（及主题、模型）录制到该回放文件")
+	fs.StringVar(&c.ReplayPath, "replay", "", "从回放文件加载分支输出，跳过分支执行，只运行综合链")
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+	if fs.NArg() > 0 {
+		return c, fmt.Errorf("多余的参数: %s", strings.Join(fs.Args(), " "))
+	}
+	c.MapReduce.Concurrency = c.Concurrency
+
+	if c.Topic = strings.TrimSpace(c.Topic); c.Topic == "" {
+		return c, fmt.Errorf("-topic 不能为空")
+	}
+	if c.Model = strings.TrimSpace(c.Model); c.Model == "" {
+		return c, fmt.Errorf("-model 不能为空")
+	}
+	if c.Temperature < 0 || c.Temperature > 2 {
+		return c, fmt.Errorf("-temperature 超出范围（0-2）: %g", c.Temperature)
+	}
+	if c.Format != "prose" && c.Format != "json" {
+		return c, fmt.Errorf("未知的输出格式: %s（可选 prose、json）", c.Format)
+	}
+	if c.RecordPath != "" && c.ReplayPath != "" {
+		return c, fmt.Errorf("-record 和 -replay 不能同时使用")
+	}
+	if (c.RecordPath != "" || c.ReplayPath != "") && (c.BatchPath != "" || c.Compare || c.SummarizePath != "" || c.PipelinePath != "") {
+		return c, fmt.Errorf("-record 和 -replay 只用于单主题模式")
+	}
+	if c.ReplayPath != "" && c.Plan {
+		return c, fmt.Errorf("-replay 不运行分支，不能与 -plan 同时使用")
+	}
+	var err error
+	if c.Branches, err = parseBranchSelection(*branches, registry); err != nil {
+		return c, err
+	}
+	if c.StageWorkers, err = parseStageWorkers(*stageWorkers); err != nil {
+		return c, fmt.Errorf("-stage-workers 格式错误: %w", err)
+	}
+
+	// BRANCH_TIMEOUT: 所有分支的默认超时；BRANCH_TIMEOUTS: 按分支 Key 覆盖（如 "summarize=30s,terms=10s"）
+	// PARALLEL_DEADLINE: 整个并行处理的截止时间，-timeout 优先
+	c.BranchTimeout = defaultBranchTimeout
+	if v := getenv("BRANCH_TIMEOUT"); v != "" {
+		if c.BranchTimeout, err = time.ParseDuration(v); err != nil {
+			return c, fmt.Errorf("BRANCH_TIMEOUT 格式错误: %w", err)
+		}
+	}
+	if c.BranchTimeouts, err = parseBranchTimeouts(getenv("BRANCH_TIMEOUTS")); err != nil {
+		return c, fmt.Errorf("BRANCH_TIMEOUTS 格式错误: %w", err)
+	}
+	c.Deadline = defaultDeadline
+	if v := getenv("PARALLEL_DEADLINE"); v != "" {
+		if c.Deadline, err = time.ParseDuration(v); err != nil {
+			return c, fmt.Errorf("PARALLEL_DEADLINE 格式错误: %w", err)
+		}
+	}
+	if *timeout < 0 {
+		return c, fmt.Errorf("-timeout 不能为负数: %s", *timeout)
+	}
+	if *timeout > 0 {
+		c.Deadline = *timeout
+	}
+	return c, nil
+}
+
+// parseBranchSelection: 解析逗号分隔的分支 Key，返回按注册顺序排列的分支；为空时返回所有分支
+// 未注册的 Key 返回错误，并列出可用的 Key
+func parseBranchSelection(spec string, registry []BranchSpec) ([]BranchSpec, error) {
+	if strings.TrimSpace(spec) == "" {
+		return registry, nil
+	}
+	known := make(map[string]bool, len(registry))
+	for _, s := range registry {
+		known[s.Key] = true
+	}
+	wanted := map[string]bool{}
+	var unknown []string
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+		wanted[key] = true
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("未知的分支: %s（可用的分支: %s）", strings.Join(unknown, ", "), strings.Join(branchKeys(registry), ", "))
+	}
+	var selected []BranchSpec
+	for _, s := range registry {
+		if wanted[s.Key] {
+			selected = append(selected, s)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("-branches 没有指定分支（可用的分支: %s）", strings.Join(branchKeys(registry), ", "))
+	}
+	return selected, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"shared/retry"
+)
+
+// envOf: 用 map 模拟 os.Getenv
+func envOf(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestNewCLIConfigDefaults(t *testing.T) {
+	c, err := newCLIConfig(nil, defaultBranches, envOf(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Topic != defaultTopic || c.Model != defaultModel || c.Temperature != defaultTemperature || c.Format != "prose" {
+		t.Errorf("默认配置 = %+v", c)
+	}
+	if len(c.Branches) != len(defaultBranches) || c.MaxAttempts != retry.DefaultMaxAttempts || c.StageWorkers != [3]int{1, 3, 1} {
+		t.Errorf("分支 = %d，尝试次数 = %d，流水线 worker = %v", len(c.Branches), c.MaxAttempts, c.StageWorkers)
+	}
+	if c.BranchTimeout != defaultBranchTimeout || c.Deadline != defaultDeadline || len(c.BranchTimeouts) != 0 {
+		t.Errorf("超时 = %s，截止时间 = %s，按分支覆盖 = %v", c.BranchTimeout, c.Deadline, c.BranchTimeouts)
+	}
+	if c.MapReduce.Concurrency != c.Concurrency {
+		t.Errorf("分片摘要的并发数应与 -concurrency 相同，得到 %d", c.MapReduce.Concurrency)
+	}
+}
+
+func TestNewCLIConfigEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		wantTimeout  time.Duration
+		wantDeadline time.Duration
+	}{
+		{"环境变量", nil, map[string]string{"BRANCH_TIMEOUT": "20s", "PARALLEL_DEADLINE": "1m"}, 20 * time.Second, time.Minute},
+		{"-timeout 优先于环境变量", []string{"-timeout", "2m"}, map[string]string{"PARALLEL_DEADLINE": "1m"}, defaultBranchTimeout, 2 * time.Minute},
+		{"空值使用默认值", nil, map[string]string{"BRANCH_TIMEOUT": "", "PARALLEL_DEADLINE": ""}, defaultBranchTimeout, defaultDeadline},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newCLIConfig(tt.args, defaultBranches, envOf(tt.env), io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.BranchTimeout != tt.wantTimeout || c.Deadline != tt.wantDeadline {
+				t.Errorf("超时 = %s，截止时间 = %s", c.BranchTimeout, c.Deadline)
+			}
+		})
+	}
+}
+
+func TestNewCLIConfigBranchTimeouts(t *testing.T) {
+	env := map[string]string{"BRANCH_TIMEOUT": "20s", "BRANCH_TIMEOUTS": "summarize=30s, terms=5s"}
+	c, err := newCLIConfig(nil, defaultBranches, envOf(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]time.Duration{"summarize": 30 * time.Second, "terms": 5 * time.Second, "questions": 20 * time.Second} {
+		if got := c.branchTimeout(key); got != want {
+			t.Errorf("branchTimeout(%s) = %s，want %s", key, got, want)
+		}
+	}
+}
+
+func TestParseBranchSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    string
+		wantErr string
+	}{
+		{"为空时运行所有分支", " ", "summarize,questions,terms,counterarguments", ""},
+		{"按注册顺序排列", "terms, summarize", "summarize,terms", ""},
+		{"重复的 Key 只运行一次", "terms,summarize,terms", "summarize,terms", ""},
+		{"忽略空项", "questions,,", "questions", ""},
+		{"未知的分支", "terms,foo,bar", "", "未知的分支: foo, bar（可用的分支: summarize, questions, terms, counterarguments）"},
+		{"只有逗号", ",", "", "-branches 没有指定分支"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBranchSelection(tt.spec, defaultBranches)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseBranchSelection 错误 = %v，want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keys := strings.Join(branchKeys(got), ","); keys != tt.want {
+				t.Errorf("parseBranchSelection = %s，want %s", keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCLIConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		{"多余的参数", []string{"太空"}, nil, "多余的参数: 太空"},
+		{"主题为空", []string{"-topic", " "}, nil, "-topic 不能为空"},
+		{"模型为空", []string{"-model", ""}, nil, "-model 不能为空"},
+		{"温度超出范围", []string{"-temperature", "2.5"}, nil, "-temperature 超出范围"},
+		{"未知的输出格式", []string{"-format", "yaml"}, nil, "未知的输出格式: yaml"},
+		{"同时录制和回放", []string{"-record", "a.json", "-replay", "b.json"}, nil, "不能同时使用"},
+		{"批处理时回放", []string{"-replay", "b.json", "-batch", "topics.txt"}, nil, "只用于单主题模式"},
+		{"回放时规划", []string{"-replay", "b.json", "-plan"}, nil, "不能与 -plan 同时使用"},
+		{"未知的分支", []string{"-branches", "foo"}, nil, "未知的分支: foo"},
+		{"流水线 worker 格式错误", []string{"-stage-workers", "1,2"}, nil, "-stage-workers 格式错误"},
+		{"负的截止时间", []string{"-timeout", "-1s"}, nil, "-timeout 不能为负数"},
+		{"BRANCH_TIMEOUT 无效", nil, map[string]string{"BRANCH_TIMEOUT": "soon"}, "BRANCH_TIMEOUT 格式错误"},
+		{"BRANCH_TIMEOUTS 无效", nil, map[string]string{"BRANCH_TIMEOUTS": "summarize"}, "BRANCH_TIMEOUTS 格式错误"},
+		{"PARALLEL_DEADLINE 无效", nil, map[string]string{"PARALLEL_DEADLINE": "1 分钟"}, "PARALLEL_DEADLINE 格式错误"},
+		{"未定义的参数", []string{"-verbose"}, nil, "flag provided but not defined"},
+		{"参数值类型错误", []string{"-concurrency", "many"}, nil, "invalid value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newCLIConfig(tt.args, defaultBranches, envOf(tt.env), io.Discard); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newCLIConfig 错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := newCLIConfig([]string{"-h"}, defaultBranches, envOf(nil), io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h 应返回 flag.ErrHelp，得到 %v", err)
+	}
+}
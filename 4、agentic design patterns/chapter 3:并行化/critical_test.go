@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCriticalFailureCancelsSiblings: 关键分支失败时立即取消其余分支，Invoke 返回 *CriticalBranchError
+func TestCriticalFailureCancelsSiblings(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	cancelled := map[string]bool{}
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		if spec.Critical {
+			time.Sleep(10 * time.Millisecond)
+			return "", errors.New("模型故障")
+		}
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			cancelled[spec.Key] = true
+			mu.Unlock()
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+			return spec.Key, nil
+		}
+	})}
+
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, fixedTimeout(10*time.Second), nil, noRetry, nil)
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	start := time.Now()
+	result, err := graph.Invoke(ctx, ParallelInput{Topic: "主题"})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("关键分支失败后仍等待了 %s，其余分支应被取消", elapsed)
+	}
+	if result != nil {
+		t.Errorf("关键分支失败时结果应为 nil: %v", result)
+	}
+
+	var critical *CriticalBranchError
+	if !errors.As(err, &critical) || critical.Key != "summarize" {
+		t.Fatalf("错误 = %v，want summarize 的 *CriticalBranchError", err)
+	}
+	var branchErr *BranchError
+	if !errors.As(critical, &branchErr) || branchErr.Key != "summarize" {
+		t.Errorf("关键分支错误应包装 *BranchError: %v", critical.Err)
+	}
+	if isPartialResult(err) {
+		t.Errorf("关键分支失败不应是部分结果: %v", err)
+	}
+
+	// runBranch 在取消后立即返回，不等模型调用退出，因此轮询等待各分支观察到取消
+	deadline := time.Now().Add(time.Second)
+	for _, spec := range defaultBranches[1:] {
+		for {
+			mu.Lock()
+			done := cancelled[spec.Key]
+			mu.Unlock()
+			if done {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s 分支没有被取消", spec.Key)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// TestCriticalTimeout: 关键分支超时同样取消整个并行图
+func TestCriticalTimeout(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		if spec.Critical {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return spec.Key, nil
+	})}
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, fixedTimeout(20*time.Millisecond), nil, noRetry, nil)
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	_, err = graph.Invoke(ctx, ParallelInput{Topic: "主题"})
+	var critical *CriticalBranchError
+	if !errors.As(err, &critical) || !errors.Is(err, ErrBranchTimeout) {
+		t.Errorf("错误 = %v，want 关键分支超时", err)
+	}
+}
+
+// TestNonCriticalFailureKeepsSiblings: 非关键分支失败不影响其余分支，结果中以占位值代替
+func TestNonCriticalFailureKeepsSiblings(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		if spec.Key == "questions" {
+			return "", errors.New("模型故障")
+		}
+		time.Sleep(20 * time.Millisecond)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return spec.Key, nil
+	})}
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, fixedTimeout(time.Second), nil, noRetry, nil)
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	result, err := graph.Invoke(ctx, ParallelInput{Topic: "主题"})
+	if !isPartialResult(err) {
+		t.Fatalf("错误 = %v，want *PartialResultError", err)
+	}
+	if got := result["questions"]; got != failurePlaceholder("相关问题") {
+		t.Errorf("失败分支结果 = %v，want 占位值", got)
+	}
+	if got := result["summary"]; got != "summarize" {
+		t.Errorf("其余分支结果 = %v，不应被取消", got)
+	}
+}
+
+// TestInvokeCallerCancel: 调用方取消时返回上下文错误，而不是部分结果
+func TestInvokeCallerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		cancel()
+		<-ctx.Done()
+		return "", ctx.Err()
+	})}
+	graph, err := buildParallelGraph(context.Background(), llm, defaultBranches, fixedTimeout(time.Second), nil, noRetry, nil)
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	result, err := graph.Invoke(ctx, ParallelInput{Topic: "主题"})
+	if err == nil || isPartialResult(err) || result != nil {
+		t.Errorf("调用方取消: result=%v err=%v", result, err)
+	}
+}
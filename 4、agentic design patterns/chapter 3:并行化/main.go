@@ -7,6 +7,9 @@
 	流水线并行   任务有依赖关系	 提高资源利用率	     需要任务分段和缓冲			     复杂工作流（如预处理→处理→后处理）
 	混合并行	   复杂场景		   最大化性能		         实现复杂度高				     生产环境优化（结合多种并行策略）
 
+	本示例以 defaultBranches 中注册的分支演示任务并行，并提供批处理、分片摘要和流水线三种模式，
+	可叠加分支超时、限流、重试、结果缓存和规划链；环境变量和命令行参数由 newCLIConfig 解析，用法见 go run . -h。
+
 	此代码根据 MIT 许可证授权。
 	请参阅仓库中的 LICENSE 文件以获取完整许可文本。
 */
@@ -15,13 +18,20 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
+
+	"shared/ratelimit"
+	"shared/retry"
+	"shared/stats"
 
 	"github.com/cloudwego/eino-ext/components/model/openai"
-	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/compose"
-	"github.com/cloudwego/eino/schema"
 )
 
 // float32Ptr: 辅助函数，将 float32 值转换为 *float32 指针
@@ -30,6 +40,15 @@ func float32Ptr(f float32) *float32 {
 }
 
 func main() {
+	cfg, err := newCLIConfig(os.Args[1:], defaultBranches, os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("参数错误: %v\n", err)
+		os.Exit(1)
+	}
+
 	ctx := context.Background()
 
 	// --- 配置 ---
@@ -45,9 +64,9 @@ func main() {
 
 	// 创建 OpenAI ChatModel 配置
 	config := &openai.ChatModelConfig{
-		Model:       "deepseek-ai/DeepSeek-V3.1", // 或您想要使用的模型
+		Model:       cfg.Model,
 		APIKey:      apiKey,
-		Temperature: float32Ptr(0.7), // 设置为 0.7 以获得更自然的输出
+		Temperature: float32Ptr(float32(cfg.Temperature)),
 	}
 
 	// 如果设置了自定义 BaseURL，则使用它（支持代理或兼容 API）
@@ -64,243 +83,319 @@ func main() {
 
 	fmt.Printf("语言模型已初始化: %s\n", config.Model)
 
-	// --- 定义独立链 ---
-	// 这三个链代表可以并行执行的不同任务。
-
-	// 1. 摘要链：简洁地总结主题
-	summarizePrompt := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage("简洁地总结以下主题："),
-		schema.UserMessage("{topic}"),
-	)
-
-	// Lambda 函数：从 Message 中提取 Content
-	extractContent := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
-		return msg.Content, nil
-	})
-
-	// 构建摘要链：Template -> ChatModel -> Lambda
-	summarizeChain, err := compose.NewChain[map[string]any, string]().
-		AppendChatTemplate(summarizePrompt).
-		AppendChatModel(llm).
-		AppendLambda(extractContent).
-		Compile(ctx)
-	if err != nil {
-		fmt.Printf("编译摘要链失败: %v\n", err)
-		os.Exit(1)
+	// --- 超时配置 ---
+	// 分支超时来自 BRANCH_TIMEOUT / BRANCH_TIMEOUTS，整体截止时间来自 -timeout 或 PARALLEL_DEADLINE
+	branchTimeout := cfg.branchTimeout
+	deadline := cfg.Deadline
+
+	// --- 限流 ---
+	// 并行分支和批处理会同时发起大量请求，共享的限流器避免超出服务商的每分钟请求数限制
+	limiterConfig := cfg.Limiter
+	limiter := ratelimit.New(limiterConfig)
+	// 瞬时错误（如 429）的重试策略
+	retryPolicy := retry.DefaultPolicy()
+	retryPolicy.MaxAttempts = cfg.MaxAttempts
+
+	// --- 对比模式 ---
+	if cfg.Compare {
+		fmt.Printf("\n--- 对比串行与并行执行：'%s' ---\n", cfg.Topic)
+		serial, parallel, err := runCompare(ctx, llm, cfg.Branches, branchTimeout, func() *ratelimit.Limiter {
+			return ratelimit.New(limiterConfig)
+		}, retryPolicy, cfg.Topic)
+		if err != nil {
+			fmt.Printf("对比执行失败: %v\n", err)
+			os.Exit(1)
+		}
+		stats.PrintComparison(os.Stdout, serial, parallel)
+		return
 	}
 
-	// 2. 问题链：生成关于主题的三个有趣问题
-	questionsPrompt := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage("生成关于以下主题的三个有趣问题："),
-		schema.UserMessage("{topic}"),
-	)
-
-	questionsChain, err := compose.NewChain[map[string]any, string]().
-		AppendChatTemplate(questionsPrompt).
-		AppendChatModel(llm).
-		AppendLambda(extractContent).
-		Compile(ctx)
-	if err != nil {
-		fmt.Printf("编译问题链失败: %v\n", err)
-		os.Exit(1)
+	// --- 分片摘要模式 ---
+	if cfg.SummarizePath != "" {
+		data, err := os.ReadFile(cfg.SummarizePath)
+		if err != nil {
+			fmt.Printf("读取文本失败: %v\n", err)
+			os.Exit(1)
+		}
+		summarizeChunk, reduce, err := buildSummarizers(ctx, llm, limiter, retryPolicy)
+		if err != nil {
+			fmt.Printf("构建摘要链失败: %v\n", err)
+			os.Exit(1)
+		}
+		result, err := runMapReduce(ctx, string(data), cfg.MapReduce, summarizeChunk, reduce)
+		if err != nil {
+			fmt.Printf("分片摘要失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n--- 最终摘要（%d 个片段，归约 %d 轮）---\n", result.Chunks, len(result.Levels))
+		fmt.Println(result.Summary)
+		fmt.Println("\n" + limiter.Stats().String())
+		return
 	}
 
-	// 3. 术语链：识别关键术语
-	termsPrompt := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage("从以下主题中识别 5-10 个关键术语，用逗号分隔："),
-		schema.UserMessage("{topic}"),
-	)
-
-	termsChain, err := compose.NewChain[map[string]any, string]().
-		AppendChatTemplate(termsPrompt).
-		AppendChatModel(llm).
-		AppendLambda(extractContent).
-		Compile(ctx)
-	if err != nil {
-		fmt.Printf("编译术语链失败: %v\n", err)
-		os.Exit(1)
+	// --- 流水线并行模式 ---
+	if cfg.PipelinePath != "" {
+		if err := runParagraphPipeline(ctx, llm, limiter, retryPolicy, cfg.PipelinePath, cfg.StageWorkers); err != nil {
+			fmt.Printf("流水线处理失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(limiter.Stats())
+		return
 	}
 
 	// --- 构建并行图 ---
-	// 使用 Graph 实现并行执行，每个链作为一个节点
-	// 定义输入类型：包含主题
-	type ParallelInput struct {
-		Topic string
-	}
-
-	// 创建并行图，输出类型为 map[string]any 以便访问各个节点的输出
-	parallelGraph := compose.NewGraph[ParallelInput, map[string]any]()
-
-	// Lambda 节点：执行摘要链
-	summarizeLambda := compose.InvokableLambda(func(ctx context.Context, input ParallelInput) (string, error) {
-		result, err := summarizeChain.Invoke(ctx, map[string]any{
-			"topic": input.Topic,
-		})
-		if err != nil {
-			return "", fmt.Errorf("摘要链执行失败: %w", err)
+	// 每个分支（摘要、问题、术语、反方观点）是一条独立子链，作为并行图中的一个节点，
+	// 子链、节点和边都由 cfg.Branches（默认为 defaultBranches）生成，新增并行任务只需追加一条 BranchSpec
+	// 单主题模式下每个分支完成时立即打印进度（并记录事件供 JSON 统计使用）；批处理模式只报告主题级进度
+	var progress ProgressFunc
+	events := branchRecorder{}
+	if cfg.BatchPath == "" {
+		progress = func(event BranchEvent) {
+			printProgress(event)
+			events.record(event)
 		}
-		return result, nil
-	})
-
-	// Lambda 节点：执行问题链
-	questionsLambda := compose.InvokableLambda(func(ctx context.Context, input ParallelInput) (string, error) {
-		result, err := questionsChain.Invoke(ctx, map[string]any{
-			"topic": input.Topic,
-		})
+	}
+	// 并行图和综合链按分支组合编译并缓存：不启用规划时只用到全部分支这一种组合，
+	// 启用规划时每种计划首次出现时编译；综合提示词由同一组分支定义生成，占位符与分支输出键始终一致
+	pipelines := newPlanPipelines(func(specs []BranchSpec) (planPipeline, error) {
+		graph, err := buildParallelGraph(ctx, llm, specs, branchTimeout, limiter, retryPolicy, progress)
 		if err != nil {
-			return "", fmt.Errorf("问题链执行失败: %w", err)
+			return planPipeline{}, fmt.Errorf("构建并行图失败: %w", err)
 		}
-		return result, nil
-	})
-
-	// Lambda 节点：执行术语链
-	termsLambda := compose.InvokableLambda(func(ctx context.Context, input ParallelInput) (string, error) {
-		result, err := termsChain.Invoke(ctx, map[string]any{
-			"topic": input.Topic,
-		})
+		synthesis, err := buildSynthesisChain(ctx, llm, specs)
 		if err != nil {
-			return "", fmt.Errorf("术语链执行失败: %w", err)
+			return planPipeline{}, fmt.Errorf("编译综合链失败: %w", err)
 		}
-		return result, nil
-	})
-
-	// Lambda 节点：传递原始主题
-	topicLambda := compose.InvokableLambda(func(ctx context.Context, input ParallelInput) (string, error) {
-		return input.Topic, nil
+		return planPipeline{graph: graph, synthesis: synthesis}, nil
 	})
-
-	// 添加节点，使用 WithOutputKey 指定输出键名
-	if err := parallelGraph.AddLambdaNode("summarize", summarizeLambda, compose.WithOutputKey("summary")); err != nil {
-		fmt.Printf("添加 summarize 节点失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddLambdaNode("questions", questionsLambda, compose.WithOutputKey("questions")); err != nil {
-		fmt.Printf("添加 questions 节点失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddLambdaNode("terms", termsLambda, compose.WithOutputKey("key_terms")); err != nil {
-		fmt.Printf("添加 terms 节点失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddLambdaNode("topic", topicLambda, compose.WithOutputKey("topic")); err != nil {
-		fmt.Printf("添加 topic 节点失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	// 所有并行节点从 START 开始（实现并行执行）
-	if err := parallelGraph.AddEdge(compose.START, "summarize"); err != nil {
-		fmt.Printf("添加 START->summarize 边失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddEdge(compose.START, "questions"); err != nil {
-		fmt.Printf("添加 START->questions 边失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddEdge(compose.START, "terms"); err != nil {
-		fmt.Printf("添加 START->terms 边失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddEdge(compose.START, "topic"); err != nil {
-		fmt.Printf("添加 START->topic 边失败: %v\n", err)
+	if _, err := pipelines.get(cfg.Branches); err != nil {
+		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
 
-	// 所有并行节点直接连接到 END（图会自动合并所有 WithOutputKey 的输出）
-	if err := parallelGraph.AddEdge("summarize", compose.END); err != nil {
-		fmt.Printf("添加 summarize->END 边失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddEdge("questions", compose.END); err != nil {
-		fmt.Printf("添加 questions->END 边失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddEdge("terms", compose.END); err != nil {
-		fmt.Printf("添加 terms->END 边失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := parallelGraph.AddEdge("topic", compose.END); err != nil {
-		fmt.Printf("添加 topic->END 边失败: %v\n", err)
-		os.Exit(1)
+	// --- 规划链（可选）---
+	// 规划链返回分支 Key 列表，未注册的 Key 被忽略，空计划或规划失败时运行所有分支
+	var planner planBranchKeys
+	if cfg.Plan {
+		planner, err = buildPlanner(ctx, llm, cfg.Branches)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	// 编译并行图，使用 AllPredecessor 触发模式确保所有节点完成后再返回结果
-	compiledParallelGraph, err := parallelGraph.Compile(ctx, compose.WithNodeTriggerMode(compose.AllPredecessor))
-	if err != nil {
-		fmt.Printf("编译并行图失败: %v\n", err)
-		os.Exit(1)
+	// --- 结果缓存 ---
+	// 相同主题、分支定义和模型的结果缓存在磁盘上，-no-cache 时不读也不写
+	var cache *resultCache
+	if !cfg.NoCache {
+		cache = newResultCache(cfg.CacheDir, cfg.CacheTTL)
 	}
 
-	// --- 构建综合链 ---
-	// 定义将组合并行结果的最终综合提示词
-	synthesisPrompt := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage(`基于以下信息：
-    摘要：{summary}
-    相关问题：{questions}
-    关键术语：{key_terms}
-    综合一个全面的答案。`),
-		schema.UserMessage("原始主题：{topic}"),
-	)
-
-	// Lambda 函数：将 map 结果转换为综合提示词的输入
-	prepareSynthesis := compose.InvokableLambda(func(ctx context.Context, results map[string]any) (map[string]any, error) {
-		// 从结果中提取各个字段
-		summary, _ := results["summary"].(string)
-		questions, _ := results["questions"].(string)
-		keyTerms, _ := results["key_terms"].(string)
-		topic, _ := results["topic"].(string)
-
-		return map[string]any{
-			"summary":   summary,
-			"questions": questions,
-			"key_terms": keyTerms,
-			"topic":     topic,
-		}, nil
-	})
+	// synthesize: 执行综合链，out 不为 nil 时流式执行，边生成边写入 out，同时返回完整结果
+	synthesize := func(ctx context.Context, synthesisChain compose.Runnable[map[string]any, string], parallelResult map[string]any, out io.Writer) (string, error) {
+		if out == nil {
+			finalResult, err := synthesisChain.Invoke(ctx, parallelResult)
+			if err != nil {
+				return "", fmt.Errorf("综合链执行失败: %w", err)
+			}
+			return finalResult, nil
+		}
 
-	// 构建综合链：Lambda -> Template -> ChatModel -> Lambda
-	synthesisChain, err := compose.NewChain[map[string]any, string]().
-		AppendLambda(prepareSynthesis).
-		AppendChatTemplate(synthesisPrompt).
-		AppendChatModel(llm).
-		AppendLambda(extractContent).
-		Compile(ctx)
-	if err != nil {
-		fmt.Printf("编译综合链失败: %v\n", err)
-		os.Exit(1)
+		chunks, err := synthesisChain.Stream(ctx, parallelResult)
+		if err != nil {
+			return "", fmt.Errorf("综合链执行失败: %w", err)
+		}
+		defer chunks.Close()
+		var sb strings.Builder
+		for {
+			chunk, err := chunks.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return sb.String(), fmt.Errorf("综合链流式输出失败: %w", err)
+			}
+			sb.WriteString(chunk)
+			fmt.Fprint(out, chunk)
+		}
+		return sb.String(), nil
 	}
 
 	// --- 组合完整链 ---
 	// 创建完整的并行处理函数
-	fullParallelChainFunc := func(ctx context.Context, topic string) (string, error) {
-		// 步骤 1: 执行并行图
-		parallelResult, err := compiledParallelGraph.Invoke(ctx, ParallelInput{Topic: topic})
+	// 整体截止时间覆盖并行图和综合链，并行图需在预留给综合链的时间之前结束，超时的分支以占位值参与综合；
+	// out 不为 nil 时流式执行综合链，边生成边写入 out，同时返回完整结果
+	fullParallelChainFunc := func(ctx context.Context, topic string, out io.Writer) (pipelineResult, error) {
+		start := time.Now()
+		result := pipelineResult{Topic: topic}
+
+		// 步骤 0: 查询结果缓存，命中时跳过并行图和综合链
+		key := cacheKey(topic, cfg.Branches, config.Model, planner != nil)
+		if entry, ok := cache.Get(key); ok {
+			fmt.Printf("♻ '%s' 的结果来自缓存（生成于 %s）\n", topic, entry.CreatedAt.Format(time.DateTime))
+			if out != nil {
+				fmt.Fprint(out, entry.Answer)
+			}
+			result.Plan, result.Branches, result.Answer, result.FromCache = entry.Plan, entry.Branches, entry.Answer, true
+			result.Elapsed = time.Since(start)
+			return result, nil
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, deadline)
+		defer cancel()
+
+		// 步骤 1: 规划要运行的分支（未启用规划时运行所有分支）
+		specs := cfg.Branches
+		if planner != nil {
+			keys, err := planner(ctx, topic)
+			if err != nil {
+				fmt.Printf("⚠ 规划失败，运行所有分支: %v\n", err)
+			}
+			specs = selectBranches(cfg.Branches, keys)
+			fmt.Printf("📋 '%s' 计划运行的分支: %s\n", topic, strings.Join(branchKeys(specs), ", "))
+		}
+		result.Plan = branchKeys(specs)
+		pipeline, err := pipelines.get(specs)
 		if err != nil {
-			return "", fmt.Errorf("并行图执行失败: %w", err)
+			return result, err
 		}
 
-		// 步骤 2: 执行综合链
-		finalResult, err := synthesisChain.Invoke(ctx, parallelResult)
+		graphTimeout := deadline - defaultSynthesisReserve
+		if graphTimeout <= 0 {
+			graphTimeout = deadline / 2
+		}
+		graphCtx, cancelGraph := context.WithTimeout(ctx, graphTimeout)
+		defer cancelGraph()
+
+		// 步骤 2: 执行并行图
+		// 非关键分支失败或超时时打印失败摘要，继续用部分结果综合
+		parallelResult, err := pipeline.graph.Invoke(graphCtx, ParallelInput{Topic: topic})
+		var partial *PartialResultError
+		if errors.As(err, &partial) {
+			printBranchErrors(os.Stdout, partial.Err)
+		} else if err != nil {
+			return result, fmt.Errorf("并行图执行失败: %w", err)
+		}
+		result.Branches = parallelResult
+
+		// 步骤 3: 执行综合链
+		finalResult, err := synthesize(ctx, pipeline.synthesis, parallelResult, out)
 		if err != nil {
-			return "", fmt.Errorf("综合链执行失败: %w", err)
+			return result, err
+		}
+		result.Answer = finalResult
+		result.Elapsed = time.Since(start)
+
+		// 步骤 4: 写入结果缓存（含占位值的结果不缓存），写入失败不影响本次结果
+		if cacheable(specs, parallelResult) {
+			entry := cacheEntry{Topic: topic, Model: config.Model, Plan: result.Plan, Branches: parallelResult, Answer: finalResult}
+			if err := cache.Put(key, entry); err != nil {
+				fmt.Printf("⚠ %v\n", err)
+			}
 		}
+		return result, nil
+	}
+
+	// --- 批处理模式 ---
+	if cfg.BatchPath != "" {
+		topics, err := loadTopics(cfg.BatchPath)
+		if err != nil {
+			fmt.Printf("加载主题失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n--- 批处理 %d 个主题（并发 %d，输出到 %s）---\n", len(topics), cfg.Concurrency, cfg.OutDir)
+		summary, err := runBatch(ctx, topics, cfg.Concurrency, cfg.OutDir, cfg.Grace, func(ctx context.Context, topic string) (string, error) {
+			result, err := fullParallelChainFunc(ctx, topic, nil)
+			return result.Answer, err
+		})
+		if err != nil {
+			fmt.Printf("批处理失败: %v\n", err)
+			os.Exit(1)
+		}
+		summary.Print()
+		fmt.Println(limiter.Stats())
+		return
+	}
 
-		return finalResult, nil
+	// --- 回放模式 ---
+	// 从回放文件还原各分支输出，跳过分支执行，只运行综合链（主题取自回放文件），便于反复调整综合提示词
+	topic, runTopic := cfg.Topic, fullParallelChainFunc
+	if cfg.ReplayPath != "" {
+		recorded, err := loadReplay(cfg.ReplayPath, cfg.Branches)
+		if err != nil {
+			fmt.Printf("加载回放文件失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("▶ 回放 '%s' 的分支输出（模型 %s，录制于 %s），只运行综合链\n",
+			recorded.Topic, recorded.Model, recorded.RecordedAt.Format(time.DateTime))
+		topic = recorded.Topic
+		runTopic = func(ctx context.Context, topic string, out io.Writer) (pipelineResult, error) {
+			start := time.Now()
+			pipeline, err := pipelines.get(cfg.Branches)
+			if err != nil {
+				return pipelineResult{}, err
+			}
+			result := pipelineResult{Topic: topic, Plan: branchKeys(cfg.Branches), Branches: recorded.outputs()}
+			result.Answer, err = synthesize(ctx, pipeline.synthesis, result.Branches, out)
+			result.Elapsed = time.Since(start)
+			return result, err
+		}
 	}
 
 	// --- 运行链 ---
-	testTopic := "太空探索的历史"
-	fmt.Printf("\n--- 运行主题的并行处理示例：'%s' ---\n", testTopic)
+	fmt.Printf("\n--- 运行主题的并行处理示例：'%s' ---\n", topic)
 
-	response, err := fullParallelChainFunc(ctx, testTopic)
-	if err != nil {
-		fmt.Printf("\n链执行期间发生错误：%v\n", err)
-		os.Exit(1)
+	var result pipelineResult
+	switch {
+	case cfg.Format == "json":
+		// JSON 模式：结构化输出，不流式
+		result, err = runTopic(ctx, topic, nil)
+		if err != nil {
+			fmt.Printf("\n链执行期间发生错误：%v\n", err)
+			os.Exit(1)
+		}
+		if err := writeJSONOutput(buildJSONOutput(result, events, limiter.Stats()), cfg.OutPath); err != nil {
+			fmt.Printf("输出 JSON 失败: %v\n", err)
+			os.Exit(1)
+		}
+		if cfg.OutPath != "" {
+			fmt.Printf("\nJSON 结果已写入 %s\n", cfg.OutPath)
+		}
+	case cfg.Stream:
+		// 流式模式：分支进度打印完后，综合结果逐块输出
+		fmt.Println("\n--- 最终响应（流式）---")
+		result, err = runTopic(ctx, topic, os.Stdout)
+		if err != nil {
+			fmt.Printf("\n链执行期间发生错误：%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println()
+	default:
+		result, err = runTopic(ctx, topic, nil)
+		if err != nil {
+			fmt.Printf("\n链执行期间发生错误：%v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("\n--- 最终响应 ---")
+		fmt.Println(result.Answer)
 	}
 
-	fmt.Println("\n--- 最终响应 ---")
-	fmt.Println(response)
+	// --- 录制 ---
+	// 保存实际运行的分支的原始输出，之后可用 -replay 只重跑综合链
+	if cfg.RecordPath != "" {
+		recording, err := newReplayFile(result, cfg.Branches, config.Model)
+		if err == nil {
+			err = writeReplay(cfg.RecordPath, recording)
+		}
+		if err != nil {
+			fmt.Printf("录制分支输出失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n分支输出已录制到 %s（%d 个分支），可用 -replay %s 只重跑综合链\n",
+			cfg.RecordPath, len(recording.Branches), cfg.RecordPath)
+	}
+	if cfg.Format != "json" {
+		fmt.Println("\n" + limiter.Stats().String())
+	}
 }
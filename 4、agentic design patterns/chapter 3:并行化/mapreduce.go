@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"shared/ratelimit"
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	defaultChunkSize   = 2000 // defaultChunkSize: 默认片段长度（字符数）
+	defaultOverlap     = 200  // defaultOverlap: 默认相邻片段的重叠字符数
+	defaultReduceGroup = 4    // defaultReduceGroup: 归约时每组合并的摘要数
+)
+
+// mapReduceConfig: 长文本分片摘要的配置
+type mapReduceConfig struct {
+	ChunkSize   int // ChunkSize: 片段长度（字符数）
+	Overlap     int // Overlap: 相邻片段的重叠字符数，必须小于 ChunkSize
+	GroupSize   int // GroupSize: 归约时每组合并的摘要数，至少为 2
+	Concurrency int // Concurrency: 同时执行的摘要数
+}
+
+// mapReduceResult: 分片摘要的结果
+type mapReduceResult struct {
+	Summary string // Summary: 最终摘要
+	Chunks  int    // Chunks: 片段数
+	Levels  []int  // Levels: 每一轮归约后剩余的摘要数（单片段时为空）
+}
+
+// splitText: 按字符（rune）把文本切成长度为 size、相邻重叠 overlap 的片段，最后一个片段可能较短
+func splitText(text string, size, overlap int) ([]string, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("片段长度必须为正数: %d", size)
+	}
+	if overlap < 0 || overlap >= size {
+		return nil, fmt.Errorf("重叠长度必须在 [0, %d) 之间: %d", size, overlap)
+	}
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) == 0 {
+		return nil, fmt.Errorf("文本为空")
+	}
+
+	var chunks []string
+	step := size - overlap
+	for start := 0; ; start += step {
+		end := start + size
+		if end >= len(runes) {
+			chunks = append(chunks, string(runes[start:]))
+			break
+		}
+		chunks = append(chunks, string(runes[start:end]))
+	}
+	return chunks, nil
+}
+
+// mapConcurrent: 用最多 concurrency 个 goroutine 对每一项执行 fn，结果按输入顺序返回
+// 任一项失败时取消其余项并返回第一个错误
+func mapConcurrent[I, O any](ctx context.Context, items []I, concurrency int,
+	fn func(ctx context.Context, index int, item I) (O, error)) ([]O, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]O, len(items))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, item I) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			out, err := fn(ctx, i, item)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = out
+		}(i, item)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// runMapReduce: 长文本分片摘要
+//   - map：文本切成重叠片段，用有限并发逐片摘要
+//   - reduce：按顺序每 GroupSize 个摘要合并为一个，逐层归约直到只剩一个；只有一个片段时不归约
+func runMapReduce(ctx context.Context, text string, cfg mapReduceConfig,
+	summarizeChunk func(ctx context.Context, index, total int, chunk string) (string, error),
+	reduce func(ctx context.Context, summaries []string) (string, error)) (mapReduceResult, error) {
+	if cfg.GroupSize < 2 {
+		return mapReduceResult{}, fmt.Errorf("归约分组大小至少为 2: %d", cfg.GroupSize)
+	}
+	chunks, err := splitText(text, cfg.ChunkSize, cfg.Overlap)
+	if err != nil {
+		return mapReduceResult{}, err
+	}
+	result := mapReduceResult{Chunks: len(chunks)}
+
+	summaries, err := mapConcurrent(ctx, chunks, cfg.Concurrency, func(ctx context.Context, i int, chunk string) (string, error) {
+		summary, err := summarizeChunk(ctx, i, len(chunks), chunk)
+		if err != nil {
+			return "", fmt.Errorf("片段 %d/%d 摘要失败: %w", i+1, len(chunks), err)
+		}
+		fmt.Printf("✅ 片段 %d/%d 摘要完成\n", i+1, len(chunks))
+		return summary, nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for level := 1; len(summaries) > 1; level++ {
+		groups := groupSummaries(summaries, cfg.GroupSize)
+		summaries, err = mapConcurrent(ctx, groups, cfg.Concurrency, func(ctx context.Context, i int, group []string) (string, error) {
+			// 最后一组只剩一个摘要时直接进入下一轮，不再调用模型
+			if len(group) == 1 {
+				return group[0], nil
+			}
+			summary, err := reduce(ctx, group)
+			if err != nil {
+				return "", fmt.Errorf("第 %d 轮归约第 %d 组失败: %w", level, i+1, err)
+			}
+			return summary, nil
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Levels = append(result.Levels, len(summaries))
+		fmt.Printf("🔽 第 %d 轮归约：%d 组 → %d 个摘要\n", level, len(groups), len(summaries))
+	}
+	result.Summary = summaries[0]
+	return result, nil
+}
+
+// groupSummaries: 按原顺序把摘要每 size 个一组，最后一组可能不足 size 个
+func groupSummaries(summaries []string, size int) [][]string {
+	groups := make([][]string, 0, (len(summaries)+size-1)/size)
+	for start := 0; start < len(summaries); start += size {
+		end := start + size
+		if end > len(summaries) {
+			end = len(summaries)
+		}
+		groups = append(groups, summaries[start:end])
+	}
+	return groups
+}
+
+// buildSummarizers: 构建片段摘要和归约摘要函数，每次模型调用都经过限流和重试
+func buildSummarizers(ctx context.Context, llm model.BaseChatModel, limiter *ratelimit.Limiter, retryPolicy retry.Policy) (
+	summarizeChunk func(ctx context.Context, index, total int, chunk string) (string, error),
+	reduce func(ctx context.Context, summaries []string) (string, error), err error) {
+	newChain := func(systemPrompt string) (compose.Runnable[map[string]any, string], error) {
+		return compose.NewChain[map[string]any, string]().
+			AppendChatTemplate(prompt.FromMessages(
+				schema.FString,
+				schema.SystemMessage(systemPrompt),
+				schema.UserMessage("{text}"),
+			)).
+			AppendChatModel(llm).
+			AppendLambda(compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+				return msg.Content, nil
+			})).
+			Compile(ctx)
+	}
+	mapChain, err := newChain("你将看到一篇长文按顺序切分后的第 {index}/{total} 个片段（相邻片段有少量重叠）。简洁地总结该片段的要点，不要补充片段之外的内容。")
+	if err != nil {
+		return nil, nil, fmt.Errorf("编译片段摘要链失败: %w", err)
+	}
+	reduceChain, err := newChain("以下是同一篇长文中按原文顺序排列的若干部分摘要。将它们合并为一个连贯、简洁的摘要，保持原文的叙述顺序，去掉重复内容。")
+	if err != nil {
+		return nil, nil, fmt.Errorf("编译归约摘要链失败: %w", err)
+	}
+
+	// invoke: 限流并重试一次链调用
+	invoke := func(ctx context.Context, chain compose.Runnable[map[string]any, string], vars map[string]any) (string, error) {
+		out, _, err := retry.Do(ctx, retryPolicy, func(ctx context.Context, attempt int) (string, error) {
+			release, err := limiter.Acquire(ctx)
+			if err != nil {
+				return "", fmt.Errorf("等待限流失败: %w", err)
+			}
+			defer release()
+			return chain.Invoke(ctx, vars)
+		})
+		return out, err
+	}
+
+	summarizeChunk = func(ctx context.Context, index, total int, chunk string) (string, error) {
+		return invoke(ctx, mapChain, map[string]any{"index": index + 1, "total": total, "text": chunk})
+	}
+	reduce = func(ctx context.Context, summaries []string) (string, error) {
+		var sb strings.Builder
+		for i, summary := range summaries {
+			fmt.Fprintf(&sb, "[第 %d 部分]\n%s\n\n", i+1, summary)
+		}
+		return invoke(ctx, reduceChain, map[string]any{"text": strings.TrimSpace(sb.String())})
+	}
+	return summarizeChunk, reduce, nil
+}
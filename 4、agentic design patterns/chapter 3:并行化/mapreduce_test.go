@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		size, overlap int
+		want          []string
+		wantErr       string
+	}{
+		{"短文本一个片段", "abc", 5, 1, []string{"abc"}, ""},
+		{"恰好一个片段", "abcde", 5, 1, []string{"abcde"}, ""},
+		{"相邻片段重叠", "abcdefgh", 4, 1, []string{"abcd", "defg", "gh"}, ""},
+		{"无重叠", "abcdef", 3, 0, []string{"abc", "def"}, ""},
+		{"按字符而不是字节切分", "一二三四五", 3, 1, []string{"一二三", "三四五"}, ""},
+		{"去掉首尾空白", "  abc \n", 5, 0, []string{"abc"}, ""},
+		{"空文本", " \n", 5, 0, nil, "文本为空"},
+		{"片段长度非正", "abc", 0, 0, nil, "片段长度必须为正数"},
+		{"重叠为负", "abc", 3, -1, nil, "重叠长度必须在"},
+		{"重叠不小于片段", "abc", 3, 3, nil, "重叠长度必须在"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitText(tt.text, tt.size, tt.overlap)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitText: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("片段 = %q，want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupSummaries(t *testing.T) {
+	got := groupSummaries([]string{"a", "b", "c", "d", "e"}, 2)
+	if fmt.Sprint(got) != "[[a b] [c d] [e]]" {
+		t.Errorf("分组 = %v", got)
+	}
+}
+
+func TestMapConcurrent(t *testing.T) {
+	var active, maxActive atomic.Int32
+	items := []int{1, 2, 3, 4, 5, 6}
+	got, err := mapConcurrent(context.Background(), items, 2, func(ctx context.Context, i, item int) (int, error) {
+		n := active.Add(1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		// 后面的项先完成，结果仍按输入顺序返回
+		time.Sleep(time.Duration(len(items)-i) * time.Millisecond)
+		active.Add(-1)
+		return item * 10, nil
+	})
+	if err != nil {
+		t.Fatalf("mapConcurrent: %v", err)
+	}
+	if fmt.Sprint(got) != "[10 20 30 40 50 60]" {
+		t.Errorf("结果 = %v", got)
+	}
+	if m := maxActive.Load(); m > 2 {
+		t.Errorf("最大并发 = %d，超过上限 2", m)
+	}
+}
+
+func TestMapConcurrentStopsOnError(t *testing.T) {
+	boom := errors.New("故障")
+	var started atomic.Int32
+	_, err := mapConcurrent(context.Background(), make([]int, 20), 1, func(ctx context.Context, i, item int) (int, error) {
+		started.Add(1)
+		if i == 2 {
+			return 0, boom
+		}
+		return 0, nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("错误 = %v，want 第一个失败项的错误", err)
+	}
+	if n := started.Load(); n > 4 {
+		t.Errorf("失败后又启动了 %d 项", n-3)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := mapConcurrent(ctx, []int{1}, 1, func(ctx context.Context, i, item int) (int, error) { return item, nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("已取消的上下文: 错误 = %v", err)
+	}
+}
+
+// joinReducer: 把摘要用 + 连接，记录每次归约的输入
+type joinReducer struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (r *joinReducer) reduce(ctx context.Context, summaries []string) (string, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, summaries)
+	r.mu.Unlock()
+	return "(" + strings.Join(summaries, "+") + ")", nil
+}
+
+// indexChunk: 片段摘要为 "<序号>"
+func indexChunk(ctx context.Context, index, total int, chunk string) (string, error) {
+	return fmt.Sprint(index + 1), nil
+}
+
+func TestRunMapReduce(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		cfg         mapReduceConfig
+		wantSummary string
+		wantChunks  int
+		wantLevels  []int
+		wantReduces int
+	}{
+		{"单片段不归约", "abc", mapReduceConfig{ChunkSize: 10, GroupSize: 2, Concurrency: 2}, "1", 1, nil, 0},
+		{"一轮归约", "abcdef", mapReduceConfig{ChunkSize: 2, GroupSize: 4, Concurrency: 2}, "(1+2+3)", 3, []int{1}, 1},
+		{"多轮归约保持顺序", "abcdefghij", mapReduceConfig{ChunkSize: 2, GroupSize: 2, Concurrency: 3}, "(((1+2)+(3+4))+5)", 5, []int{3, 2, 1}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &joinReducer{}
+			result, err := runMapReduce(context.Background(), tt.text, tt.cfg, indexChunk, r.reduce)
+			if err != nil {
+				t.Fatalf("runMapReduce: %v", err)
+			}
+			if result.Summary != tt.wantSummary || result.Chunks != tt.wantChunks {
+				t.Errorf("结果 = %+v，want 摘要 %q、%d 个片段", result, tt.wantSummary, tt.wantChunks)
+			}
+			if fmt.Sprint(result.Levels) != fmt.Sprint(tt.wantLevels) {
+				t.Errorf("各轮剩余 = %v，want %v", result.Levels, tt.wantLevels)
+			}
+			// 只剩一个摘要的组直接进入下一轮，不调用模型
+			if len(r.calls) != tt.wantReduces {
+				t.Errorf("归约调用 %d 次，want %d: %v", len(r.calls), tt.wantReduces, r.calls)
+			}
+		})
+	}
+}
+
+func TestRunMapReduceErrors(t *testing.T) {
+	ctx := context.Background()
+	r := &joinReducer{}
+	if _, err := runMapReduce(ctx, "abc", mapReduceConfig{ChunkSize: 2, GroupSize: 1}, indexChunk, r.reduce); err == nil || !strings.Contains(err.Error(), "至少为 2") {
+		t.Errorf("分组过小: 错误 = %v", err)
+	}
+
+	failChunk := func(ctx context.Context, index, total int, chunk string) (string, error) {
+		if index == 1 {
+			return "", errors.New("模型故障")
+		}
+		return chunk, nil
+	}
+	if _, err := runMapReduce(ctx, "abcdef", mapReduceConfig{ChunkSize: 2, GroupSize: 2, Concurrency: 1}, failChunk, r.reduce); err == nil || !strings.Contains(err.Error(), "片段 2/3 摘要失败") {
+		t.Errorf("片段失败: 错误 = %v", err)
+	}
+
+	failReduce := func(ctx context.Context, summaries []string) (string, error) { return "", errors.New("模型故障") }
+	if _, err := runMapReduce(ctx, "abcd", mapReduceConfig{ChunkSize: 2, GroupSize: 2, Concurrency: 1}, indexChunk, failReduce); err == nil || !strings.Contains(err.Error(), "第 1 轮归约第 1 组失败") {
+		t.Errorf("归约失败: 错误 = %v", err)
+	}
+}
+
+// TestBuildSummarizers: 片段摘要和归约通过模型完成，提示词包含片段序号和按顺序编号的部分摘要
+func TestBuildSummarizers(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var users []string
+	llm := &fakeModel{respond: func(ctx context.Context, system, user string) (string, error) {
+		mu.Lock()
+		users = append(users, user)
+		mu.Unlock()
+		return "摘要", nil
+	}}
+	summarizeChunk, reduce, err := buildSummarizers(ctx, llm, nil, noRetry)
+	if err != nil {
+		t.Fatalf("buildSummarizers: %v", err)
+	}
+	if _, err := summarizeChunk(ctx, 1, 3, "片段内容"); err != nil {
+		t.Fatalf("summarizeChunk: %v", err)
+	}
+	if got := llm.callsWith("你将看到一篇长文按顺序切分后的第 2/3 个片段"); got != 1 {
+		t.Errorf("片段提示词应包含序号 2/3")
+	}
+	if _, err := reduce(ctx, []string{"甲", "乙"}); err != nil {
+		t.Fatalf("reduce: %v", err)
+	}
+	if want := "[第 1 部分]\n甲\n\n[第 2 部分]\n乙"; len(users) != 2 || users[1] != want {
+		t.Errorf("归约输入 = %q，want %q", users, want)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"shared/ratelimit"
+)
+
+// pipelineResult: 一次完整并行处理的结果
+type pipelineResult struct {
+	Topic     string
+	Plan      []string       // Plan: 实际运行的分支 Key
+	Branches  map[string]any // Branches: 并行图的输出（各分支结果和主题）
+	Answer    string         // Answer: 综合链的最终答案
+	FromCache bool           // FromCache: 结果来自磁盘缓存
+	Elapsed   time.Duration
+}
+
+// branchStats: JSON 输出中单个分支的统计
+type branchStats struct {
+	ElapsedMS int64 `json:"elapsed_ms"`
+	Attempts  int   `json:"attempts"`
+	Tokens    int   `json:"tokens"`
+	TimedOut  bool  `json:"timed_out,omitempty"`
+	Failed    bool  `json:"failed,omitempty"`
+}
+
+// runStats: JSON 输出中的运行统计
+type runStats struct {
+	ElapsedMS     int64                  `json:"elapsed_ms"`
+	FromCache     bool                   `json:"from_cache"`
+	Branches      map[string]branchStats `json:"branches,omitempty"` // Branches: 按输出键索引，命中缓存时为空
+	RateLimitWait int64                  `json:"rate_limit_wait_ms"`
+	MaxQueueDepth map[string]int         `json:"max_queue_depth"` // MaxQueueDepth: 限流器各优先级（high/normal/low）的最大排队数
+}
+
+// jsonOutput: --format json 的输出结构
+// questions / key_terms 解析失败时为空，原始文本保存在 raw 中
+type jsonOutput struct {
+	Topic            string            `json:"topic"`
+	Plan             []string          `json:"plan"`
+	Summary          string            `json:"summary"`
+	Questions        []string          `json:"questions"`
+	KeyTerms         []string          `json:"key_terms"`
+	Counterarguments string            `json:"counterarguments,omitempty"`
+	Synthesis        string            `json:"synthesis"`
+	Raw              map[string]string `json:"raw,omitempty"`
+	Stats            runStats          `json:"stats"`
+}
+
+// buildJSONOutput: 由处理结果、分支事件和限流统计生成 JSON 输出
+func buildJSONOutput(result pipelineResult, events map[string]BranchEvent, limit ratelimit.Stats) jsonOutput {
+	branch := func(key string) string {
+		value, _ := result.Branches[key].(string)
+		return value
+	}
+	out := jsonOutput{
+		Topic:            result.Topic,
+		Plan:             result.Plan,
+		Summary:          branch("summary"),
+		Counterarguments: branch("counterarguments"),
+		Synthesis:        result.Answer,
+		Questions:        []string{},
+		KeyTerms:         []string{},
+		Stats: runStats{
+			ElapsedMS:     result.Elapsed.Milliseconds(),
+			FromCache:     result.FromCache,
+			RateLimitWait: limit.TotalWait.Milliseconds(),
+			MaxQueueDepth: map[string]int{
+				"high":   limit.MaxQueueDepth[0],
+				"normal": limit.MaxQueueDepth[1],
+				"low":    limit.MaxQueueDepth[2],
+			},
+		},
+	}
+
+	// 未运行的分支（规划跳过）保持空列表，不记入 raw
+	raw := map[string]string{}
+	if text := branch("questions"); text != "" {
+		if questions, ok := parseQuestions(text); ok {
+			out.Questions = questions
+		} else {
+			raw["questions"] = text
+		}
+	}
+	if text := branch("key_terms"); text != "" {
+		if terms, ok := parseKeyTerms(text); ok {
+			out.KeyTerms = terms
+		} else {
+			raw["key_terms"] = text
+		}
+	}
+	if len(raw) > 0 {
+		out.Raw = raw
+	}
+
+	if len(events) > 0 {
+		out.Stats.Branches = make(map[string]branchStats, len(events))
+		for _, event := range events {
+			out.Stats.Branches[event.OutputKey] = branchStats{
+				ElapsedMS: event.Elapsed.Milliseconds(),
+				Attempts:  event.Attempts,
+				Tokens:    event.Usage.Total(),
+				TimedOut:  event.TimedOut,
+				Failed:    event.Err != nil,
+			}
+		}
+	}
+	return out
+}
+
+// writeJSONOutput: 将 JSON 输出写入 path，path 为空时写到标准输出
+func writeJSONOutput(out jsonOutput, path string) error {
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 JSON 输出失败: %w", err)
+	}
+	if path == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+var (
+	// numberedLine: 编号行，如 "1. "、"2）"、"(3)"、"第4个问题："、"- "、"* "、"• "
+	numberedLine = regexp.MustCompile(`^\s*(?:\d+\s*[.、)）:：]|[(（]\d+[)）]|第\s*\d+\s*(?:个)?(?:问题)?\s*[:：.、]?|[-*•])\s*`)
+	// inlineNumber: 同一行内紧跟在句末标点之后的编号（如 "（1）甲？（2）乙？"），需要拆成多行
+	inlineNumber = regexp.MustCompile(`([？?。!！])\s*(\d+\s*[.、)）]|[(（]\d+[)）])`)
+	// markdownNoise: Markdown 加粗、标题等不属于内容的标记
+	markdownNoise = regexp.MustCompile(`\*\*|__|^#+\s*`)
+	// termSeparators: 关键术语的分隔符（中英文逗号、顿号、分号、换行）
+	termSeparators = regexp.MustCompile(`[,，、;；\n]+`)
+)
+
+// parseQuestions: 按编号行（或列表项）拆分问题
+// 同一行内的多个编号先拆开；编号行之前的引导语被忽略，编号行之后未编号的行视为上一个问题的续行；没有任何编号行时解析失败
+func parseQuestions(text string) ([]string, bool) {
+	var questions []string
+	text = inlineNumber.ReplaceAllString(text, "$1\n$2")
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(markdownNoise.ReplaceAllString(line, ""))
+		if line == "" {
+			continue
+		}
+		if loc := numberedLine.FindStringIndex(line); loc != nil {
+			if q := strings.TrimSpace(line[loc[1]:]); q != "" {
+				questions = append(questions, q)
+			}
+			continue
+		}
+		if len(questions) > 0 {
+			questions[len(questions)-1] += " " + line
+		}
+	}
+	return questions, len(questions) > 0
+}
+
+// parseKeyTerms: 按逗号、顿号、分号或换行拆分关键术语
+// 去掉 "关键术语：" 之类的引导语、编号、引号和句末标点，并去重；拆出的术语少于两个时解析失败
+func parseKeyTerms(text string) ([]string, bool) {
+	text = strings.TrimSpace(markdownNoise.ReplaceAllString(text, ""))
+	// 第一行包含冒号时，冒号之前是引导语
+	first, rest, _ := strings.Cut(text, "\n")
+	if i := strings.IndexAny(first, ":："); i >= 0 {
+		_, size := utf8.DecodeRuneInString(first[i:])
+		text = first[i+size:] + "\n" + rest
+	}
+
+	seen := map[string]bool{}
+	var terms []string
+	for _, part := range termSeparators.Split(text, -1) {
+		part = numberedLine.ReplaceAllString(part, "")
+		part = strings.Trim(strings.TrimSpace(part), "\"'“”‘’「」`。.")
+		if part == "" || seen[part] {
+			continue
+		}
+		seen[part] = true
+		terms = append(terms, part)
+	}
+	return terms, len(terms) >= 2
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseQuestions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "编号列表",
+			text: "1. 谁最先登月？\n2) 火星上有水吗？\n（3）空间站如何供氧？",
+			want: []string{"谁最先登月？", "火星上有水吗？", "空间站如何供氧？"},
+		},
+		{
+			name: "同一行内的编号",
+			text: "1. 谁最先登月？ 2. 火星上有水吗？（3）空间站如何供氧？",
+			want: []string{"谁最先登月？", "火星上有水吗？", "空间站如何供氧？"},
+		},
+		{
+			name: "Markdown 加粗和标题",
+			text: "### 相关问题\n1. **谁最先登月？**\n2. __火星上有水吗？__",
+			want: []string{"谁最先登月？", "火星上有水吗？"},
+		},
+		{
+			name: "忽略编号之前的引导语",
+			text: "以下是三个有趣的问题：\n\n第1个问题：谁最先登月？\n第2个问题：火星上有水吗？",
+			want: []string{"谁最先登月？", "火星上有水吗？"},
+		},
+		{
+			name: "续行并入上一个问题",
+			text: "- 为什么登月计划\n  在 1972 年之后停止了？\n- 火星上有水吗？",
+			want: []string{"为什么登月计划 在 1972 年之后停止了？", "火星上有水吗？"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseQuestions(tt.text)
+			if !ok || !slices.Equal(got, tt.want) {
+				t.Errorf("parseQuestions = %q, %v，want %q", got, ok, tt.want)
+			}
+		})
+	}
+
+	if got, ok := parseQuestions("太空探索引出了很多问题，比如登月和火星。"); ok {
+		t.Errorf("没有编号行时应解析失败，得到 %q", got)
+	}
+}
+
+func TestParseKeyTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "中英文逗号",
+			text: "阿波罗计划, 空间站，火箭",
+			want: []string{"阿波罗计划", "空间站", "火箭"},
+		},
+		{
+			name: "顿号分号和换行混用",
+			text: "阿波罗计划、空间站；火箭\n卫星;探测器",
+			want: []string{"阿波罗计划", "空间站", "火箭", "卫星", "探测器"},
+		},
+		{
+			name: "去掉引导语和 Markdown",
+			text: "**关键术语：** 阿波罗计划，空间站。",
+			want: []string{"阿波罗计划", "空间站"},
+		},
+		{
+			name: "编号列表",
+			text: "## 关键术语：\n1. 阿波罗计划\n2. 空间站\n- 火箭",
+			want: []string{"阿波罗计划", "空间站", "火箭"},
+		},
+		{
+			name: "去掉引号并去重",
+			text: "“阿波罗计划”，\"空间站\"，「火箭」，阿波罗计划，'空间站'",
+			want: []string{"阿波罗计划", "空间站", "火箭"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseKeyTerms(tt.text)
+			if !ok || !slices.Equal(got, tt.want) {
+				t.Errorf("parseKeyTerms = %q, %v，want %q", got, ok, tt.want)
+			}
+		})
+	}
+
+	// 拆出的术语少于两个时解析失败，交给调用方使用原文
+	for _, text := range []string{"", "关键术语：阿波罗计划", "阿波罗计划，阿波罗计划。", "术语：\n，、；"} {
+		if got, ok := parseKeyTerms(text); ok {
+			t.Errorf("parseKeyTerms(%q) 应解析失败，得到 %q", text, got)
+		}
+	}
+}
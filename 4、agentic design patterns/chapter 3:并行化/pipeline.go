@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"shared/ratelimit"
+	"shared/retry"
+	"shared/stats"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// topicKey: 并行图中传递原始主题的节点名和输出键
+const topicKey = "topic"
+
+// ParallelInput: 并行图的输入，包含主题
+type ParallelInput struct {
+	Topic string
+}
+
+// BranchSpec: 一个并行分支的定义
+// 新增并行任务只需在 defaultBranches 中追加一条，子链、图节点、边和综合提示词都会自动生成
+type BranchSpec struct {
+	Key          string             // Key: 分支名称，同时是图节点名和超时配置的键
+	Label        string             // Label: 分支结果的中文标题，用于综合提示词和超时占位值
+	SystemPrompt string             // SystemPrompt: 子链的系统提示词，主题作为用户消息传入
+	OutputKey    string             // OutputKey: 分支结果在并行图输出 map 中的键
+	Critical     bool               // Critical: 关键分支，失败或超时时立即取消其余分支并让整个并行图失败
+	Priority     ratelimit.Priority // Priority: 限流器饱和时获取令牌的优先级，零值为普通
+}
+
+// defaultBranches: 默认的并行分支
+var defaultBranches = []BranchSpec{
+	{Key: "summarize", Label: "摘要", SystemPrompt: "简洁地总结以下主题：", OutputKey: "summary", Critical: true, Priority: ratelimit.High},
+	{Key: "questions", Label: "相关问题", SystemPrompt: "生成关于以下主题的三个有趣问题：", OutputKey: "questions"},
+	{Key: "terms", Label: "关键术语", SystemPrompt: "从以下主题中识别 5-10 个关键术语，用逗号分隔：", OutputKey: "key_terms", Priority: ratelimit.Low},
+	{Key: "counterarguments", Label: "反方观点", SystemPrompt: "针对以下主题，提出 2-3 个有代表性的反方观点或争议：", OutputKey: "counterarguments"},
+}
+
+// validateBranches: 校验分支名称和输出键非空且不重复，并且不与主题键冲突
+func validateBranches(specs []BranchSpec) error {
+	if len(specs) == 0 {
+		return fmt.Errorf("至少需要一个并行分支")
+	}
+	keys := map[string]bool{topicKey: true}
+	outputs := map[string]bool{topicKey: true}
+	for _, spec := range specs {
+		if spec.Key == "" || spec.OutputKey == "" || spec.SystemPrompt == "" {
+			return fmt.Errorf("分支 %q 缺少 Key、OutputKey 或 SystemPrompt", spec.Key)
+		}
+		if keys[spec.Key] {
+			return fmt.Errorf("分支名称重复或与保留名冲突: %s", spec.Key)
+		}
+		if outputs[spec.OutputKey] {
+			return fmt.Errorf("分支输出键重复或与保留键冲突: %s", spec.OutputKey)
+		}
+		keys[spec.Key] = true
+		outputs[spec.OutputKey] = true
+	}
+	return nil
+}
+
+// parallelGraph: 编译后的并行图，每次执行时挂上取消函数和错误收集器，关键分支失败时取消其余分支
+type parallelGraph struct {
+	runnable compose.Runnable[ParallelInput, map[string]any]
+	specs    []BranchSpec
+}
+
+// Invoke: 执行并行图，收集所有分支的错误（按分支定义顺序，用 errors.Join 合并）
+//   - 全部成功：返回结果和 nil
+//   - 只有非关键分支失败或超时：返回部分结果和 *PartialResultError（失败的分支为占位值）
+//   - 关键分支失败：返回 nil 和合并的错误，其中包含 *CriticalBranchError
+func (g *parallelGraph) Invoke(ctx context.Context, input ParallelInput) (map[string]any, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	failures := &branchErrors{}
+	result, err := g.runnable.Invoke(withBranchErrors(withAbort(ctx, cancel), failures), input)
+	errs := failures.ordered(g.specs)
+	var critical *CriticalBranchError
+	if errors.As(context.Cause(ctx), &critical) {
+		return nil, errors.Join(errs...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return result, &PartialResultError{Err: errors.Join(errs...)}
+	}
+	return result, nil
+}
+
+// buildParallelGraph: 根据分支定义构建并行图
+// 每个分支编译一条 Template -> ChatModel -> Lambda 子链，作为从 START 出发、连到 END 的 Lambda 节点，
+// 另有一个节点传递原始主题；图会自动合并所有 WithOutputKey 的输出。
+// 每个分支调用模型前先从 limiter 获取令牌和在途名额（等待时间计入分支超时），limiter 为 nil 时不限流；
+// 瞬时错误（如 429）按 retryPolicy 重试，每次重试重新获取限流令牌，所有重试都在分支超时内完成；
+// 非关键分支失败时以占位值参与综合，关键分支失败或超时时取消其余分支；失败和超时都记录为 *BranchError 供 Invoke 汇总；
+// 每个分支完成时立即调用 progress（可为 nil）
+func buildParallelGraph(ctx context.Context, llm model.BaseChatModel, specs []BranchSpec,
+	branchTimeout func(key string) time.Duration, limiter *ratelimit.Limiter, retryPolicy retry.Policy,
+	progress ProgressFunc) (*parallelGraph, error) {
+	if err := validateBranches(specs); err != nil {
+		return nil, err
+	}
+
+	// Lambda 函数：从 Message 中提取 Content
+	extractContent := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+		return msg.Content, nil
+	})
+
+	progress = progress.serialize()
+	graph := compose.NewGraph[ParallelInput, map[string]any]()
+	for _, spec := range specs {
+		spec := spec
+		// 构建子链：Template -> ChatModel -> Lambda
+		chain, err := compose.NewChain[map[string]any, string]().
+			AppendChatTemplate(prompt.FromMessages(
+				schema.FString,
+				schema.SystemMessage(spec.SystemPrompt),
+				schema.UserMessage("{topic}"),
+			)).
+			AppendChatModel(llm).
+			AppendLambda(extractContent).
+			Compile(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("编译%s链失败: %w", spec.Label, err)
+		}
+
+		// Lambda 节点：在分支超时限制内限流、重试并执行子链，完成后报告进度
+		lambda := compose.InvokableLambda(func(ctx context.Context, input ParallelInput) (string, error) {
+			start := time.Now()
+			// usage: 分支所有尝试的 token 用量（模型为 stats.CountingModel 时才有数据）
+			ctx, usage := stats.WithUsage(ctx)
+			// attempts: 分支超时返回后重试的 goroutine 可能仍在运行，因此用原子计数
+			var attempts atomic.Int32
+			out, err := runBranch(ctx, spec.Label, branchTimeout(spec.Key), func(ctx context.Context) (string, error) {
+				result, _, err := retry.Do(ctx, retryPolicy, func(ctx context.Context, attempt int) (string, error) {
+					attempts.Store(int32(attempt))
+					if attempt > 1 {
+						fmt.Printf("🔁 %s分支第 %d 次尝试\n", spec.Label, attempt)
+					}
+
+					// 每次尝试都重新获取限流令牌
+					release, err := limiter.AcquireWithPriority(ctx, spec.Priority)
+					if err != nil {
+						return "", fmt.Errorf("%s分支等待限流失败: %w", spec.Label, err)
+					}
+					defer release()
+
+					result, err := chain.Invoke(ctx, map[string]any{
+						"topic": input.Topic,
+					})
+					if err != nil {
+						return "", fmt.Errorf("%s链执行失败: %w", spec.Label, err)
+					}
+					return result, nil
+				})
+				return result, err
+			})
+			timedOut := err == nil && out == timeoutPlaceholder(spec.Label)
+			if err != nil && ctx.Err() != nil {
+				// 整个并行图已被取消（关键分支失败、截止时间到或调用方取消），不再降级
+				return "", err
+			}
+			elapsed := time.Since(start)
+			// 失败和超时都记录为 *BranchError，用哨兵错误标明超时和限流
+			var branchErr error
+			switch {
+			case timedOut:
+				branchErr = &BranchError{Key: spec.Key, Label: spec.Label, Elapsed: elapsed,
+					Err: fmt.Errorf("%w（超过 %s）: %w", ErrBranchTimeout, branchTimeout(spec.Key), context.DeadlineExceeded)}
+			case retry.IsRateLimited(err):
+				branchErr = &BranchError{Key: spec.Key, Label: spec.Label, Elapsed: elapsed, Err: fmt.Errorf("%w: %w", ErrBranchRateLimited, err)}
+			case err != nil:
+				branchErr = &BranchError{Key: spec.Key, Label: spec.Label, Elapsed: elapsed, Err: err}
+			}
+			if branchErr != nil && spec.Critical {
+				err = &CriticalBranchError{Key: spec.Key, Label: spec.Label, Err: branchErr}
+				branchErr = err
+			}
+			if branchErr != nil {
+				// 先记录再取消，保证 Invoke 返回时关键分支的错误已在收集器中
+				recordBranchError(ctx, spec.Key, branchErr)
+			}
+			if spec.Critical && err != nil {
+				abortGraph(ctx, err)
+			}
+			progress.emit(BranchEvent{
+				Key:       spec.Key,
+				Label:     spec.Label,
+				OutputKey: spec.OutputKey,
+				Elapsed:   elapsed,
+				Attempts:  int(attempts.Load()),
+				Usage:     usage.Usage(),
+				TimedOut:  timedOut,
+				Err:       err,
+			})
+			if err != nil && !spec.Critical {
+				return failurePlaceholder(spec.Label), nil
+			}
+			return out, err
+		})
+		if err := graph.AddLambdaNode(spec.Key, lambda, compose.WithOutputKey(spec.OutputKey)); err != nil {
+			return nil, fmt.Errorf("添加 %s 节点失败: %w", spec.Key, err)
+		}
+	}
+
+	// Lambda 节点：传递原始主题
+	topicLambda := compose.InvokableLambda(func(ctx context.Context, input ParallelInput) (string, error) {
+		return input.Topic, nil
+	})
+	if err := graph.AddLambdaNode(topicKey, topicLambda, compose.WithOutputKey(topicKey)); err != nil {
+		return nil, fmt.Errorf("添加 %s 节点失败: %w", topicKey, err)
+	}
+
+	// 所有节点从 START 开始（实现并行执行），直接连接到 END
+	nodes := []string{topicKey}
+	for _, spec := range specs {
+		nodes = append(nodes, spec.Key)
+	}
+	for _, node := range nodes {
+		if err := graph.AddEdge(compose.START, node); err != nil {
+			return nil, fmt.Errorf("添加 START->%s 边失败: %w", node, err)
+		}
+		if err := graph.AddEdge(node, compose.END); err != nil {
+			return nil, fmt.Errorf("添加 %s->END 边失败: %w", node, err)
+		}
+	}
+
+	// 编译并行图，使用 AllPredecessor 触发模式确保所有节点完成后再返回结果
+	runnable, err := graph.Compile(ctx, compose.WithNodeTriggerMode(compose.AllPredecessor))
+	if err != nil {
+		return nil, err
+	}
+	return &parallelGraph{runnable: runnable, specs: specs}, nil
+}
+
+// synthesisSystemPrompt: 根据分支定义生成综合提示词（FString 模板），保证占位符与分支输出键一致
+func synthesisSystemPrompt(specs []BranchSpec) string {
+	var sb strings.Builder
+	sb.WriteString("基于以下信息：\n")
+	for _, spec := range specs {
+		fmt.Fprintf(&sb, "    %s：{%s}\n", spec.Label, spec.OutputKey)
+	}
+	sb.WriteString("    综合一个全面的答案。\n")
+	sb.WriteString(`    如果某部分显示为"不可用（超时）"或"不可用（失败）"，忽略该部分，只基于其余信息综合，不要编造缺失的内容。`)
+	return sb.String()
+}
+
+// buildSynthesisChain: 构建综合链：Lambda -> Template -> ChatModel -> Lambda
+// 最后一个 Lambda 是流式转换，既支持 Invoke 得到完整文本，也支持 Stream 逐块输出
+func buildSynthesisChain(ctx context.Context, llm model.BaseChatModel, specs []BranchSpec) (compose.Runnable[map[string]any, string], error) {
+	synthesisPrompt := prompt.FromMessages(
+		schema.FString,
+		schema.SystemMessage(synthesisSystemPrompt(specs)),
+		schema.UserMessage("原始主题：{topic}"),
+	)
+
+	// Lambda 函数：从并行结果中取出综合提示词需要的字段（缺失的字段填空字符串）
+	prepareSynthesis := compose.InvokableLambda(func(ctx context.Context, results map[string]any) (map[string]any, error) {
+		vars := make(map[string]any, len(specs)+1)
+		for _, spec := range specs {
+			value, _ := results[spec.OutputKey].(string)
+			vars[spec.OutputKey] = value
+		}
+		topic, _ := results[topicKey].(string)
+		vars[topicKey] = topic
+		return vars, nil
+	})
+
+	return compose.NewChain[map[string]any, string]().
+		AppendLambda(prepareSynthesis).
+		AppendChatTemplate(synthesisPrompt).
+		AppendChatModel(llm).
+		AppendLambda(compose.TransformableLambda(func(ctx context.Context, msgs *schema.StreamReader[*schema.Message]) (*schema.StreamReader[string], error) {
+			return schema.StreamReaderWithConvert(msgs, func(msg *schema.Message) (string, error) {
+				return msg.Content, nil
+			}), nil
+		})).
+		Compile(ctx)
+}
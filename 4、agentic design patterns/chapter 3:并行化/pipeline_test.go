@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeModel: 由 respond 根据系统提示词和用户消息生成回复，记录每次调用的系统提示词
+// 每次回复带固定的 token 用量；Stream 把回复拆成两个分块
+type fakeModel struct {
+	respond func(ctx context.Context, system, user string) (string, error)
+
+	mu      sync.Mutex
+	systems []string
+}
+
+func (m *fakeModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	system := in[0].Content
+	m.mu.Lock()
+	m.systems = append(m.systems, system)
+	m.mu.Unlock()
+
+	out, err := m.respond(ctx, system, in[len(in)-1].Content)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.Message{
+		Role:         schema.Assistant,
+		Content:      out,
+		ResponseMeta: &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+	}, nil
+}
+
+func (m *fakeModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(msg.Content)
+	half := len(runes) / 2
+	return schema.StreamReaderFromArray([]*schema.Message{
+		schema.AssistantMessage(string(runes[:half]), nil),
+		{Role: schema.Assistant, Content: string(runes[half:]), ResponseMeta: msg.ResponseMeta},
+	}), nil
+}
+
+// callsWith: 系统提示词以 prefix 开头的调用次数
+func (m *fakeModel) callsWith(prefix string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, s := range m.systems {
+		if strings.HasPrefix(s, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// bySpec: 按系统提示词找到对应的分支再调用 fn，其他调用（如综合链）返回 "综合: <用户消息>"
+func bySpec(specs []BranchSpec, fn func(ctx context.Context, spec BranchSpec, topic string) (string, error)) func(ctx context.Context, system, user string) (string, error) {
+	return func(ctx context.Context, system, user string) (string, error) {
+		for _, spec := range specs {
+			if system == spec.SystemPrompt {
+				return fn(ctx, spec, user)
+			}
+		}
+		return "综合: " + user, nil
+	}
+}
+
+// echoBranch: 返回 "<Key>:<主题>" 的分支回复
+func echoBranch(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+	return spec.Key + ":" + topic, nil
+}
+
+// fixedTimeout: 所有分支使用相同的超时
+func fixedTimeout(d time.Duration) func(string) time.Duration {
+	return func(string) time.Duration { return d }
+}
+
+// noRetry: 不重试的策略
+var noRetry = retry.Policy{MaxAttempts: 1}
+
+func TestValidateBranches(t *testing.T) {
+	valid := BranchSpec{Key: "a", OutputKey: "out_a", SystemPrompt: "p"}
+	tests := []struct {
+		name    string
+		specs   []BranchSpec
+		wantErr string
+	}{
+		{"默认分支", defaultBranches, ""},
+		{"没有分支", nil, "至少需要一个"},
+		{"缺少字段", []BranchSpec{{Key: "a", OutputKey: "out_a"}}, "缺少 Key、OutputKey 或 SystemPrompt"},
+		{"名称重复", []BranchSpec{valid, {Key: "a", OutputKey: "out_b", SystemPrompt: "p"}}, "分支名称重复"},
+		{"输出键重复", []BranchSpec{valid, {Key: "b", OutputKey: "out_a", SystemPrompt: "p"}}, "输出键重复"},
+		{"名称与主题键冲突", []BranchSpec{{Key: topicKey, OutputKey: "x", SystemPrompt: "p"}}, "保留名"},
+		{"输出键与主题键冲突", []BranchSpec{{Key: "x", OutputKey: topicKey, SystemPrompt: "p"}}, "保留键"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBranches(tt.specs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateBranches: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateBranches 错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParallelGraphRunsAllBranches(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{respond: bySpec(defaultBranches, echoBranch)}
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, fixedTimeout(time.Second), nil, noRetry, nil)
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	result, err := graph.Invoke(ctx, ParallelInput{Topic: "太空探索"})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if result[topicKey] != "太空探索" {
+		t.Errorf("主题 = %v", result[topicKey])
+	}
+	for _, spec := range defaultBranches {
+		if got := result[spec.OutputKey]; got != spec.Key+":太空探索" {
+			t.Errorf("%s = %v", spec.OutputKey, got)
+		}
+	}
+	if len(result) != len(defaultBranches)+1 {
+		t.Errorf("输出 = %v，应只包含各分支结果和主题", result)
+	}
+}
+
+// TestParallelGraphIsParallel: 每个分支耗时 100ms，并行执行的总耗时应接近单个分支
+func TestParallelGraphIsParallel(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		time.Sleep(100 * time.Millisecond)
+		return spec.Key, nil
+	})}
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, fixedTimeout(time.Second), nil, noRetry, nil)
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	start := time.Now()
+	if _, err := graph.Invoke(ctx, ParallelInput{Topic: "主题"}); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("%d 个分支耗时 %s，应并行执行", len(defaultBranches), elapsed)
+	}
+}
+
+func TestSynthesisPromptFollowsBranches(t *testing.T) {
+	specs := []BranchSpec{
+		{Key: "summarize", Label: "摘要", OutputKey: "summary", SystemPrompt: "总结"},
+		{Key: "risks", Label: "风险", OutputKey: "risks", SystemPrompt: "列出风险"},
+	}
+	system := synthesisSystemPrompt(specs)
+	for _, want := range []string{"摘要：{summary}", "风险：{risks}", "不可用（超时）"} {
+		if !strings.Contains(system, want) {
+			t.Errorf("综合提示词缺少 %q:\n%s", want, system)
+		}
+	}
+
+	// 占位符与输出键一致，模板可以用并行图的输出渲染
+	msgs, err := prompt.FromMessages(schema.FString, schema.SystemMessage(system)).
+		Format(context.Background(), map[string]any{"summary": "S", "risks": "R"})
+	if err != nil {
+		t.Fatalf("渲染综合提示词失败: %v", err)
+	}
+	if !strings.Contains(msgs[0].Content, "摘要：S") || !strings.Contains(msgs[0].Content, "风险：R") {
+		t.Errorf("渲染结果 = %q", msgs[0].Content)
+	}
+}
+
+func TestSynthesisChain(t *testing.T) {
+	ctx := context.Background()
+	var gotSystem string
+	llm := &fakeModel{respond: func(ctx context.Context, system, user string) (string, error) {
+		gotSystem = system
+		return "最终答案（" + user + "）", nil
+	}}
+	chain, err := buildSynthesisChain(ctx, llm, defaultBranches)
+	if err != nil {
+		t.Fatalf("buildSynthesisChain: %v", err)
+	}
+	// 缺失的分支结果填空字符串，多余的键被忽略
+	out, err := chain.Invoke(ctx, map[string]any{topicKey: "太空探索", "summary": "摘要内容", "unrelated": 1})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if out != "最终答案（原始主题：太空探索）" {
+		t.Errorf("综合结果 = %q", out)
+	}
+	if !strings.Contains(gotSystem, "摘要：摘要内容") || !strings.Contains(gotSystem, "关键术语：\n") {
+		t.Errorf("综合提示词 = %q", gotSystem)
+	}
+}
+
+// TestParallelGraphRetriesTransientErrors: 分支遇到瞬时错误时按策略重试，通过 Sleep 假实现不真正等待
+func TestParallelGraphRetriesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	calls := map[string]int{}
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[spec.Key]++
+		if spec.Key == "questions" && calls[spec.Key] < 3 {
+			return "", errors.New("429 too many requests")
+		}
+		return spec.Key, nil
+	})}
+	var slept []time.Duration
+	policy := retry.Policy{MaxAttempts: 3, BaseDelay: time.Second, Sleep: func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		slept = append(slept, d)
+		return nil
+	}}
+
+	attempts := map[string]int{}
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, fixedTimeout(time.Second), nil, policy, func(event BranchEvent) {
+		attempts[event.Key] = event.Attempts
+	})
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	result, err := graph.Invoke(ctx, ParallelInput{Topic: "主题"})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if result["questions"] != "questions" {
+		t.Errorf("重试后的结果 = %v", result["questions"])
+	}
+	if attempts["questions"] != 3 || attempts["summarize"] != 1 {
+		t.Errorf("尝试次数 = %v", attempts)
+	}
+	if fmt.Sprint(slept) != "[1s 2s]" {
+		t.Errorf("重试等待 = %v，want [1s 2s]", slept)
+	}
+}
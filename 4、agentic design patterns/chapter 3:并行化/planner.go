@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// planBranchKeys: 规划链，返回主题值得运行的分支 Key 列表（可能包含未注册的 Key，由 selectBranches 过滤）
+type planBranchKeys func(ctx context.Context, topic string) ([]string, error)
+
+// plannerSystemPrompt: 根据分支定义生成规划提示词（FString 模板，内容中不能出现未转义的花括号）
+func plannerSystemPrompt(specs []BranchSpec) string {
+	var sb strings.Builder
+	sb.WriteString("你负责为一个并行分析流程做规划。可用的分析分支如下（Key：说明）：\n")
+	for _, spec := range specs {
+		fmt.Fprintf(&sb, "- %s：%s（%s）\n", spec.Key, spec.Label, spec.SystemPrompt)
+	}
+	sb.WriteString("根据用户给出的主题，选出值得运行的分支（例如叙事性主题可以跳过关键术语）。\n")
+	sb.WriteString(`只输出一个 JSON 字符串数组，元素为分支 Key，例如 ["summarize","questions"]，不要输出其他内容。`)
+	return sb.String()
+}
+
+// buildPlanner: 构建规划链：Template -> ChatModel -> Lambda（解析 JSON 数组）
+func buildPlanner(ctx context.Context, llm model.BaseChatModel, specs []BranchSpec) (planBranchKeys, error) {
+	chain, err := compose.NewChain[map[string]any, []string]().
+		AppendChatTemplate(prompt.FromMessages(
+			schema.FString,
+			schema.SystemMessage(plannerSystemPrompt(specs)),
+			schema.UserMessage("主题：{topic}"),
+		)).
+		AppendChatModel(llm).
+		AppendLambda(compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) ([]string, error) {
+			return parsePlan(msg.Content)
+		})).
+		Compile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("编译规划链失败: %w", err)
+	}
+	return func(ctx context.Context, topic string) ([]string, error) {
+		return chain.Invoke(ctx, map[string]any{"topic": topic})
+	}, nil
+}
+
+// parsePlan: 从模型输出中解析分支 Key 数组，容忍 ```json 代码块和数组前后的说明文字
+func parsePlan(text string) ([]string, error) {
+	start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("规划结果中没有 JSON 数组: %q", text)
+	}
+	var keys []string
+	if err := json.Unmarshal([]byte(text[start:end+1]), &keys); err != nil {
+		return nil, fmt.Errorf("解析规划结果失败: %w", err)
+	}
+	return keys, nil
+}
+
+// selectBranches: 按计划从注册的分支中选出要运行的分支
+//   - 未注册的 Key 被忽略，结果保持注册顺序（与计划中的顺序无关）
+//   - 关键分支（Critical）总是运行
+//   - 计划中没有任何已注册的 Key 时回退为运行所有分支
+func selectBranches(specs []BranchSpec, plan []string) []BranchSpec {
+	wanted := make(map[string]bool, len(plan))
+	for _, key := range plan {
+		wanted[strings.TrimSpace(key)] = true
+	}
+	var selected []BranchSpec
+	planned := false
+	for _, spec := range specs {
+		if wanted[spec.Key] {
+			planned = true
+		}
+		if wanted[spec.Key] || spec.Critical {
+			selected = append(selected, spec)
+		}
+	}
+	if !planned {
+		return specs
+	}
+	return selected
+}
+
+// branchKeys: 分支 Key 列表
+func branchKeys(specs []BranchSpec) []string {
+	keys := make([]string, len(specs))
+	for i, spec := range specs {
+		keys[i] = spec.Key
+	}
+	return keys
+}
+
+// planPipeline: 一组分支对应的并行图和综合链
+type planPipeline struct {
+	graph     *parallelGraph
+	synthesis compose.Runnable[map[string]any, string]
+}
+
+// planPipelines: 按分支组合缓存编译好的并行图和综合链，相同计划的调用复用同一份编译结果
+type planPipelines struct {
+	build func(specs []BranchSpec) (planPipeline, error)
+
+	mu      sync.Mutex
+	entries map[string]planPipeline
+}
+
+// newPlanPipelines: 创建按计划编译的流水线缓存
+func newPlanPipelines(build func(specs []BranchSpec) (planPipeline, error)) *planPipelines {
+	return &planPipelines{build: build, entries: map[string]planPipeline{}}
+}
+
+// get: 返回分支组合对应的流水线，首次使用时编译
+func (p *planPipelines) get(specs []BranchSpec) (planPipeline, error) {
+	key := strings.Join(branchKeys(specs), ",")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pipeline, ok := p.entries[key]; ok {
+		return pipeline, nil
+	}
+	pipeline, err := p.build(specs)
+	if err != nil {
+		return planPipeline{}, err
+	}
+	p.entries[key] = pipeline
+	return pipeline, nil
+}
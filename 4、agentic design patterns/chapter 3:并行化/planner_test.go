@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr string
+	}{
+		{"纯 JSON", `["summarize","questions"]`, []string{"summarize", "questions"}, ""},
+		{"代码块", "```json\n[\"terms\"]\n```", []string{"terms"}, ""},
+		{"前后有说明文字", `建议运行：["summarize"]。以上。`, []string{"summarize"}, ""},
+		{"空数组", `[]`, []string{}, ""},
+		{"没有数组", "全部运行", nil, "没有 JSON 数组"},
+		{"元素不是字符串", `[1, 2]`, nil, "解析规划结果失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePlan(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePlan: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("计划 = %q，want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectBranches(t *testing.T) {
+	all := "summarize,questions,terms,counterarguments"
+	tests := []struct {
+		name string
+		plan []string
+		want string
+	}{
+		{"按注册顺序返回", []string{"terms", "questions"}, "summarize,questions,terms"},
+		{"关键分支总是运行", []string{"counterarguments"}, "summarize,counterarguments"},
+		{"忽略未注册的 Key 并去掉空白", []string{" questions ", "unknown"}, "summarize,questions"},
+		{"只选关键分支", []string{"summarize"}, "summarize"},
+		{"全是未注册的 Key 时回退为全部", []string{"unknown"}, all},
+		{"空计划回退为全部", nil, all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(branchKeys(selectBranches(defaultBranches, tt.plan)), ",")
+			if got != tt.want {
+				t.Errorf("选中 = %s，want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlannerSystemPrompt(t *testing.T) {
+	system := plannerSystemPrompt(defaultBranches)
+	for _, spec := range defaultBranches {
+		if !strings.Contains(system, "- "+spec.Key+"："+spec.Label) {
+			t.Errorf("规划提示词缺少分支 %s:\n%s", spec.Key, system)
+		}
+	}
+}
+
+func TestBuildPlanner(t *testing.T) {
+	ctx := context.Background()
+	var gotUser string
+	llm := &fakeModel{respond: func(ctx context.Context, system, user string) (string, error) {
+		gotUser = user
+		return "```json\n[\"questions\"]\n```", nil
+	}}
+	plan, err := buildPlanner(ctx, llm, defaultBranches)
+	if err != nil {
+		t.Fatalf("buildPlanner: %v", err)
+	}
+	keys, err := plan(ctx, "一个故事")
+	if err != nil {
+		t.Fatalf("规划: %v", err)
+	}
+	if fmt.Sprint(keys) != "[questions]" || gotUser != "主题：一个故事" {
+		t.Errorf("计划 = %v，用户消息 = %q", keys, gotUser)
+	}
+
+	// JSON 数组示例中的方括号不是 FString 占位符，提示词可以正常渲染；模型输出无法解析时返回错误
+	llm.respond = func(ctx context.Context, system, user string) (string, error) { return "都行", nil }
+	if _, err := plan(ctx, "主题"); err == nil {
+		t.Errorf("无法解析的计划应返回错误")
+	}
+}
+
+func TestPlanPipelines(t *testing.T) {
+	builds := 0
+	pipelines := newPlanPipelines(func(specs []BranchSpec) (planPipeline, error) {
+		builds++
+		if len(specs) == 1 {
+			return planPipeline{}, errors.New("编译失败")
+		}
+		return planPipeline{}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := pipelines.get(defaultBranches[:2]); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+	if _, err := pipelines.get(defaultBranches); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if builds != 2 {
+		t.Errorf("编译 %d 次，want 每种分支组合只编译 1 次", builds)
+	}
+
+	// 编译失败不缓存，下次仍会重试
+	for i := 0; i < 2; i++ {
+		if _, err := pipelines.get(defaultBranches[:1]); err == nil {
+			t.Errorf("编译失败应返回错误")
+		}
+	}
+	if builds != 4 {
+		t.Errorf("编译 %d 次，want 4", builds)
+	}
+}
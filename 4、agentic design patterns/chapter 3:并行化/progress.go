@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"shared/stats"
+)
+
+// BranchEvent: 分支完成事件
+type BranchEvent struct {
+	Key       string        // Key: 分支名称
+	Label     string        // Label: 分支中文标题
+	OutputKey string        // OutputKey: 分支结果的输出键
+	Elapsed   time.Duration // Elapsed: 分支耗时（含限流等待和重试）
+	Attempts  int           // Attempts: 模型调用的尝试次数（含第一次）
+	Usage     stats.Usage   // Usage: 分支的 token 用量
+	TimedOut  bool          // TimedOut: 分支超时，结果为占位值
+	Err       error         // Err: 分支失败的错误
+}
+
+// ProgressFunc: 分支完成回调，在分支完成时立即调用（按完成顺序，而不是定义顺序）
+type ProgressFunc func(event BranchEvent)
+
+// serialize: 返回串行化的回调，多个分支并发完成时回调不会并发执行，回调实现无需自己加锁
+// p 为 nil 时返回 nil
+func (p ProgressFunc) serialize() ProgressFunc {
+	if p == nil {
+		return nil
+	}
+	var mu sync.Mutex
+	return func(event BranchEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		p(event)
+	}
+}
+
+// emit: 调用回调，p 为 nil 时什么都不做
+func (p ProgressFunc) emit(event BranchEvent) {
+	if p != nil {
+		p(event)
+	}
+}
+
+// printProgress: 默认的进度回调，每个分支完成时打印一行（重试过的分支附带尝试次数）
+func printProgress(event BranchEvent) {
+	detail := event.Elapsed.Round(100 * time.Millisecond).String()
+	if event.Attempts > 1 {
+		detail = fmt.Sprintf("%s，尝试 %d 次", detail, event.Attempts)
+	}
+	switch {
+	case event.Err != nil:
+		fmt.Printf("❌ %s（%s）失败（%s）: %v\n", event.OutputKey, event.Label, detail, event.Err)
+	case event.TimedOut:
+		fmt.Printf("⏱ %s（%s）超时（%s）\n", event.OutputKey, event.Label, detail)
+	default:
+		fmt.Printf("✅ %s（%s）已完成（%s）\n", event.OutputKey, event.Label, detail)
+	}
+}
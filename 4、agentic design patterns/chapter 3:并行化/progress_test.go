@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestProgressInCompletionOrder: 分支完成时立即报告，顺序按完成先后而不是定义顺序
+func TestProgressInCompletionOrder(t *testing.T) {
+	ctx := context.Background()
+	delays := map[string]time.Duration{"summarize": 90 * time.Millisecond, "questions": 10 * time.Millisecond, "terms": 50 * time.Millisecond, "counterarguments": 70 * time.Millisecond}
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		time.Sleep(delays[spec.Key])
+		return spec.Key, nil
+	})}
+
+	var events []BranchEvent
+	progress := func(event BranchEvent) { events = append(events, event) }
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, fixedTimeout(time.Second), nil, noRetry, progress)
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	if _, err := graph.Invoke(ctx, ParallelInput{Topic: "主题"}); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	var order []string
+	for _, event := range events {
+		order = append(order, event.Key)
+		if event.Err != nil || event.TimedOut || event.Attempts != 1 {
+			t.Errorf("%s 事件 = %+v，want 一次尝试成功", event.Key, event)
+		}
+		if event.Usage.Total() != 0 {
+			t.Errorf("%s 用量 = %+v，未包装 CountingModel 时应为零", event.Key, event.Usage)
+		}
+	}
+	if got := strings.Join(order, ","); got != "questions,terms,counterarguments,summarize" {
+		t.Errorf("完成顺序 = %s，want questions,terms,counterarguments,summarize", got)
+	}
+}
+
+func TestProgressReportsTimeoutAndFailure(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{respond: bySpec(defaultBranches, func(ctx context.Context, spec BranchSpec, topic string) (string, error) {
+		switch spec.Key {
+		case "questions":
+			<-ctx.Done()
+			return "", ctx.Err()
+		case "terms":
+			return "", errors.New("模型故障")
+		}
+		return spec.Key, nil
+	})}
+	timeouts := func(key string) time.Duration {
+		if key == "questions" {
+			return 20 * time.Millisecond
+		}
+		return time.Second
+	}
+
+	events := map[string]BranchEvent{}
+	graph, err := buildParallelGraph(ctx, llm, defaultBranches, timeouts, nil, noRetry, func(event BranchEvent) {
+		events[event.Key] = event
+	})
+	if err != nil {
+		t.Fatalf("buildParallelGraph: %v", err)
+	}
+	// 非关键分支失败时 Invoke 以部分结果返回，这里只关心进度事件
+	_, _ = graph.Invoke(ctx, ParallelInput{Topic: "主题"})
+
+	if e := events["questions"]; !e.TimedOut || e.Err != nil {
+		t.Errorf("超时分支事件 = %+v", e)
+	}
+	if e := events["terms"]; e.TimedOut || e.Err == nil || !strings.Contains(e.Err.Error(), "模型故障") {
+		t.Errorf("失败分支事件 = %+v", e)
+	}
+	if e := events["summarize"]; e.TimedOut || e.Err != nil {
+		t.Errorf("成功分支事件 = %+v", e)
+	}
+}
+
+func TestProgressSerialize(t *testing.T) {
+	var nilProgress ProgressFunc
+	if nilProgress.serialize() != nil {
+		t.Fatalf("nil 回调串行化后应仍为 nil")
+	}
+	nilProgress.emit(BranchEvent{}) // 不应 panic
+
+	var active, maxActive atomic.Int32
+	serialized := ProgressFunc(func(BranchEvent) {
+		n := active.Add(1)
+		if n > maxActive.Load() {
+			maxActive.Store(n)
+		}
+		time.Sleep(time.Millisecond)
+		active.Add(-1)
+	}).serialize()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serialized.emit(BranchEvent{})
+		}()
+	}
+	wg.Wait()
+	if got := maxActive.Load(); got != 1 {
+		t.Errorf("回调最大并发数 = %d，want 1", got)
+	}
+}
+
+// TestSynthesisStream: 综合链的 Stream 逐块输出，拼接后与 Invoke 结果一致
+func TestSynthesisStream(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeModel{respond: func(ctx context.Context, system, user string) (string, error) {
+		return "综合后的答案", nil
+	}}
+	chain, err := buildSynthesisChain(ctx, llm, defaultBranches)
+	if err != nil {
+		t.Fatalf("buildSynthesisChain: %v", err)
+	}
+	stream, err := chain.Stream(ctx, map[string]any{topicKey: "主题"})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	defer stream.Close()
+
+	var chunks []string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) < 2 {
+		t.Errorf("分块 = %q，应逐块输出", chunks)
+	}
+	if got := strings.Join(chunks, ""); got != "综合后的答案" {
+		t.Errorf("拼接结果 = %q", got)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// replayVersion: 回放文件格式版本，格式不兼容地变化时递增
+const replayVersion = 1
+
+// recordedBranch: 一个分支录制的原始输出
+type recordedBranch struct {
+	Key       string `json:"key"`
+	OutputKey string `json:"output_key"`
+	Output    string `json:"output"`
+}
+
+// replayFile: -record 写入、-replay 读取的回放文件
+type replayFile struct {
+	Version    int              `json:"version"`
+	Topic      string           `json:"topic"`
+	Model      string           `json:"model"`
+	RecordedAt time.Time        `json:"recorded_at"`
+	Branches   []recordedBranch `json:"branches"` // Branches: 按分支定义顺序排列
+}
+
+// newReplayFile: 从一次运行的结果生成回放文件，只录制实际运行（result.Plan 中）的分支
+func newReplayFile(result pipelineResult, specs []BranchSpec, modelName string) (replayFile, error) {
+	planned := make(map[string]bool, len(result.Plan))
+	for _, key := range result.Plan {
+		planned[key] = true
+	}
+	file := replayFile{Version: replayVersion, Topic: result.Topic, Model: modelName, RecordedAt: time.Now()}
+	for _, spec := range specs {
+		if !planned[spec.Key] {
+			continue
+		}
+		output, ok := result.Branches[spec.OutputKey].(string)
+		if !ok {
+			return replayFile{}, fmt.Errorf("分支 %s 没有输出，无法录制", spec.Key)
+		}
+		file.Branches = append(file.Branches, recordedBranch{Key: spec.Key, OutputKey: spec.OutputKey, Output: output})
+	}
+	return file, nil
+}
+
+// writeReplay: 把回放文件写入 path
+func writeReplay(path string, file replayFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化回放文件失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("写入回放文件失败: %w", err)
+	}
+	return nil
+}
+
+// loadReplay: 读取回放文件并校验版本和分支组合（录制的分支 Key 必须与 specs 完全一致）
+func loadReplay(path string, specs []BranchSpec) (replayFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return replayFile{}, fmt.Errorf("读取回放文件失败: %w", err)
+	}
+	var file replayFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return replayFile{}, fmt.Errorf("解析回放文件失败: %w", err)
+	}
+	if file.Version != replayVersion {
+		return replayFile{}, fmt.Errorf("不支持的回放文件版本 %d（当前版本 %d），请重新录制", file.Version, replayVersion)
+	}
+
+	recorded := make([]string, len(file.Branches))
+	for i, branch := range file.Branches {
+		recorded[i] = branch.Key
+	}
+	current := branchKeys(specs)
+	sort.Strings(recorded)
+	sort.Strings(current)
+	if strings.Join(recorded, ",") != strings.Join(current, ",") {
+		return replayFile{}, fmt.Errorf("回放文件的分支（%s）与当前分支（%s）不一致，可用 -branches %s 指定相同的分支",
+			strings.Join(recorded, ", "), strings.Join(current, ", "), strings.Join(recorded, ","))
+	}
+	// 输出键以当前分支定义为准，综合提示词的占位符与之一致
+	outputKeys := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		outputKeys[spec.Key] = spec.OutputKey
+	}
+	for i := range file.Branches {
+		file.Branches[i].OutputKey = outputKeys[file.Branches[i].Key]
+	}
+	return file, nil
+}
+
+// outputs: 还原并行图的输出（各分支结果和主题），作为综合链的输入
+func (f replayFile) outputs() map[string]any {
+	outputs := map[string]any{topicKey: f.Topic}
+	for _, branch := range f.Branches {
+		outputs[branch.OutputKey] = branch.Output
+	}
+	return outputs
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordedResult: 运行了摘要和相关问题两个分支的结果
+func recordedResult() pipelineResult {
+	return pipelineResult{
+		Topic:    "太空探索",
+		Plan:     []string{"summarize", "questions"},
+		Branches: map[string]any{topicKey: "太空探索", "summary": "摘要内容", "questions": "问题内容"},
+		Answer:   "答案",
+	}
+}
+
+func TestNewReplayFile(t *testing.T) {
+	file, err := newReplayFile(recordedResult(), defaultBranches, "gpt")
+	if err != nil {
+		t.Fatalf("newReplayFile: %v", err)
+	}
+	if file.Version != replayVersion || file.Topic != "太空探索" || file.Model != "gpt" {
+		t.Errorf("回放文件 = %+v", file)
+	}
+	// 只录制计划中的分支，按分支定义顺序排列
+	if len(file.Branches) != 2 || file.Branches[0].Key != "summarize" || file.Branches[1].Output != "问题内容" {
+		t.Errorf("录制的分支 = %+v", file.Branches)
+	}
+
+	missing := recordedResult()
+	delete(missing.Branches, "questions")
+	if _, err := newReplayFile(missing, defaultBranches, "gpt"); err == nil || !strings.Contains(err.Error(), "questions 没有输出") {
+		t.Errorf("缺少分支输出: 错误 = %v", err)
+	}
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.json")
+	file, err := newReplayFile(recordedResult(), defaultBranches, "gpt")
+	if err != nil {
+		t.Fatalf("newReplayFile: %v", err)
+	}
+	if err := writeReplay(path, file); err != nil {
+		t.Fatalf("writeReplay: %v", err)
+	}
+
+	// 当前分支顺序与录制时不同也可以回放
+	specs := []BranchSpec{defaultBranches[1], defaultBranches[0]}
+	loaded, err := loadReplay(path, specs)
+	if err != nil {
+		t.Fatalf("loadReplay: %v", err)
+	}
+	outputs := loaded.outputs()
+	want := recordedResult().Branches
+	if len(outputs) != len(want) {
+		t.Errorf("还原的输出 = %v，want %v", outputs, want)
+	}
+	for key, value := range want {
+		if outputs[key] != value {
+			t.Errorf("%s = %v，want %v", key, outputs[key], value)
+		}
+	}
+}
+
+// TestReplayUsesCurrentOutputKeys: 输出键以当前分支定义为准，与综合提示词的占位符一致
+func TestReplayUsesCurrentOutputKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.json")
+	file, err := newReplayFile(recordedResult(), defaultBranches, "gpt")
+	if err != nil {
+		t.Fatalf("newReplayFile: %v", err)
+	}
+	if err := writeReplay(path, file); err != nil {
+		t.Fatalf("writeReplay: %v", err)
+	}
+	specs := []BranchSpec{defaultBranches[0], defaultBranches[1]}
+	specs[1].OutputKey = "related_questions"
+	loaded, err := loadReplay(path, specs)
+	if err != nil {
+		t.Fatalf("loadReplay: %v", err)
+	}
+	if got := loaded.outputs()["related_questions"]; got != "问题内容" {
+		t.Errorf("新输出键的值 = %v", got)
+	}
+}
+
+func TestLoadReplayErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"文件不存在", filepath.Join(dir, "missing.json"), "读取回放文件失败"},
+		{"格式错误", write("bad.json", "{"), "解析回放文件失败"},
+		{"版本不符", write("old.json", `{"version": 0}`), "不支持的回放文件版本 0"},
+		{"分支不一致", write("branches.json", `{"version": 1, "branches": [{"key": "summarize"}, {"key": "terms"}]}`),
+			"回放文件的分支（summarize, terms）与当前分支（questions, summarize）不一致，可用 -branches summarize,terms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadReplay(tt.path, defaultBranches[:2])
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestReplaySynthesis: 回放的输出可以直接作为综合链的输入，不调用分支模型
+func TestReplaySynthesis(t *testing.T) {
+	ctx := context.Background()
+	file, err := newReplayFile(recordedResult(), defaultBranches, "gpt")
+	if err != nil {
+		t.Fatalf("newReplayFile: %v", err)
+	}
+	var gotSystem string
+	llm := &fakeModel{respond: func(ctx context.Context, system, user string) (string, error) {
+		gotSystem = system
+		return "综合", nil
+	}}
+	specs := defaultBranches[:2]
+	chain, err := buildSynthesisChain(ctx, llm, specs)
+	if err != nil {
+		t.Fatalf("buildSynthesisChain: %v", err)
+	}
+	if _, err := chain.Invoke(ctx, file.outputs()); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if !strings.Contains(gotSystem, "摘要：摘要内容") || !strings.Contains(gotSystem, "相关问题：问题内容") {
+		t.Errorf("综合提示词 = %q", gotSystem)
+	}
+	for _, spec := range specs {
+		if llm.callsWith(spec.SystemPrompt) != 0 {
+			t.Errorf("回放不应调用 %s 分支", spec.Key)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ch3/stages"
+	"shared/ratelimit"
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// defaultStageWorkers: 流水线三个阶段（预处理、LLM 转换、后处理）的默认 worker 数
+const defaultStageWorkers = "1,3,1"
+
+// blankLines: 段落分隔（一个或多个空行）
+var blankLines = regexp.MustCompile(`\n\s*\n`)
+
+// parseStageWorkers: 解析逗号分隔的三个阶段 worker 数（如 "1,3,1"）
+func parseStageWorkers(spec string) ([3]int, error) {
+	var workers [3]int
+	parts := strings.Split(spec, ",")
+	if len(parts) != 3 {
+		return workers, fmt.Errorf("需要 3 个阶段的 worker 数（如 1,3,1）: %q", spec)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			return workers, fmt.Errorf("第 %d 个阶段的 worker 数无效: %q", i+1, part)
+		}
+		workers[i] = n
+	}
+	return workers, nil
+}
+
+// splitParagraphs: 按空行把文本拆成段落
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	for _, p := range blankLines.Split(strings.ReplaceAll(text, "\r\n", "\n"), -1) {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// rewrittenParagraph: 流水线中间结果
+type rewrittenParagraph struct {
+	Original string
+	Points   string
+}
+
+// buildParagraphStages: 构建段落流水线的三个阶段
+//   - 预处理（Lambda）：合并段内换行和多余空白
+//   - LLM 转换：把段落提炼为一句话要点（经过限流和重试）
+//   - 后处理：整理格式并附上原文长度
+func buildParagraphStages(ctx context.Context, llm model.BaseChatModel, limiter *ratelimit.Limiter, retryPolicy retry.Policy, workers [3]int) (
+	stages.Stage[string, string], stages.Stage[string, rewrittenParagraph], stages.Stage[rewrittenParagraph, string], error) {
+	chain, err := compose.NewChain[map[string]any, string]().
+		AppendChatTemplate(prompt.FromMessages(
+			schema.FString,
+			schema.SystemMessage("用一句话提炼以下段落的要点，只输出这句话："),
+			schema.UserMessage("{paragraph}"),
+		)).
+		AppendChatModel(llm).
+		AppendLambda(compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+			return msg.Content, nil
+		})).
+		Compile(ctx)
+	if err != nil {
+		return stages.Stage[string, string]{}, stages.Stage[string, rewrittenParagraph]{}, stages.Stage[rewrittenParagraph, string]{},
+			fmt.Errorf("编译段落转换链失败: %w", err)
+	}
+
+	preprocess := stages.Stage[string, string]{
+		Name:    "预处理",
+		Workers: workers[0],
+		Fn: func(ctx context.Context, paragraph string) (string, error) {
+			return strings.Join(strings.Fields(paragraph), " "), nil
+		},
+	}
+	transform := stages.Stage[string, rewrittenParagraph]{
+		Name:    "LLM 转换",
+		Workers: workers[1],
+		Fn: func(ctx context.Context, paragraph string) (rewrittenParagraph, error) {
+			points, _, err := retry.Do(ctx, retryPolicy, func(ctx context.Context, attempt int) (string, error) {
+				release, err := limiter.Acquire(ctx)
+				if err != nil {
+					return "", fmt.Errorf("等待限流失败: %w", err)
+				}
+				defer release()
+				return chain.Invoke(ctx, map[string]any{"paragraph": paragraph})
+			})
+			if err != nil {
+				return rewrittenParagraph{}, err
+			}
+			return rewrittenParagraph{Original: paragraph, Points: points}, nil
+		},
+	}
+	postprocess := stages.Stage[rewrittenParagraph, string]{
+		Name:    "后处理",
+		Workers: workers[2],
+		Fn: func(ctx context.Context, p rewrittenParagraph) (string, error) {
+			return fmt.Sprintf("%s（原文 %d 字）", strings.TrimSpace(p.Points), len([]rune(p.Original))), nil
+		},
+	}
+	return preprocess, transform, postprocess, nil
+}
+
+// runParagraphPipeline: 流水线并行示例：逐段落经过 预处理 -> LLM 转换 -> 后处理，结果按段落顺序边完成边输出
+func runParagraphPipeline(ctx context.Context, llm model.BaseChatModel, limiter *ratelimit.Limiter, retryPolicy retry.Policy,
+	path string, workers [3]int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取文本失败: %w", err)
+	}
+	paragraphs := splitParagraphs(string(data))
+	if len(paragraphs) == 0 {
+		return fmt.Errorf("文件 %s 中没有段落", path)
+	}
+	preprocess, transform, postprocess, err := buildParagraphStages(ctx, llm, limiter, retryPolicy, workers)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n--- 流水线处理 %d 个段落（worker 数 预处理 %d / LLM %d / 后处理 %d）---\n",
+		len(paragraphs), workers[0], workers[1], workers[2])
+	start := time.Now()
+	_, err = stages.Run(ctx, paragraphs, preprocess, transform, postprocess, stages.DefaultBuffer, func(seq int, out string) {
+		fmt.Printf("%d. %s\n", seq+1, out)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n完成 %d 个段落，总耗时 %s\n", len(paragraphs), time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseStageWorkers(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    [3]int
+		wantErr string
+	}{
+		{"1,3,1", [3]int{1, 3, 1}, ""},
+		{" 2 , 4 ,1 ", [3]int{2, 4, 1}, ""},
+		{"1,3", [3]int{}, "需要 3 个阶段"},
+		{"1,3,1,1", [3]int{}, "需要 3 个阶段"},
+		{"1,0,1", [3]int{}, "第 2 个阶段的 worker 数无效"},
+		{"1,2,x", [3]int{}, "第 3 个阶段的 worker 数无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseStageWorkers(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseStageWorkers(%q) = %v, %v，want %v", tt.spec, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	text := "第一段\n第一段续行\r\n\r\n第二段\n  \n\n\n第三段\n\n"
+	got := splitParagraphs(text)
+	if want := []string{"第一段\n第一段续行", "第二段", "第三段"}; fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+		t.Errorf("段落 = %q，want %q", got, want)
+	}
+	if got := splitParagraphs(" \n\n "); len(got) != 0 {
+		t.Errorf("空白文本段落 = %q", got)
+	}
+}
+
+func TestParagraphStages(t *testing.T) {
+	ctx := context.Background()
+	var inputs []string
+	llm := &fakeModel{respond: func(ctx context.Context, system, user string) (string, error) {
+		inputs = append(inputs, user)
+		return " 要点 \n", nil
+	}}
+	preprocess, transform, postprocess, err := buildParagraphStages(ctx, llm, nil, noRetry, [3]int{1, 1, 1})
+	if err != nil {
+		t.Fatalf("buildParagraphStages: %v", err)
+	}
+
+	cleaned, err := preprocess.Fn(ctx, "第一行\n  第二行\t结尾")
+	if err != nil || cleaned != "第一行 第二行 结尾" {
+		t.Fatalf("预处理 = %q, %v", cleaned, err)
+	}
+	rewritten, err := transform.Fn(ctx, cleaned)
+	if err != nil {
+		t.Fatalf("LLM 转换: %v", err)
+	}
+	if len(inputs) != 1 || inputs[0] != cleaned {
+		t.Errorf("模型输入 = %q", inputs)
+	}
+	out, err := postprocess.Fn(ctx, rewritten)
+	if err != nil || out != "要点（原文 10 字）" {
+		t.Errorf("后处理 = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,118 @@
+// Package stages: 三阶段流水线并行
+//
+// 每个阶段有自己的 worker 池，阶段之间用有界 channel 连接：阶段 N+1 处理第 k 项的同时，阶段 N 已经在处理第 k+1 项，
+// 整体耗时接近"最慢阶段 × 项数"而不是"所有阶段之和 × 项数"。
+// 每一项带序号流过各阶段，输出按输入顺序返回（并按顺序回调）；任一阶段出错时取消其余阶段并返回该错误。
+package stages
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultBuffer: 阶段之间 channel 的默认容量
+const DefaultBuffer = 4
+
+// Stage: 流水线的一个阶段
+type Stage[I, O any] struct {
+	Name    string                                     // Name: 阶段名称，用于错误信息
+	Workers int                                        // Workers: 该阶段同时处理的项数，<=0 时取 1
+	Fn      func(ctx context.Context, in I) (O, error) // Fn: 处理一项
+}
+
+// item: 带序号的流水线数据
+type item[T any] struct {
+	seq int
+	val T
+}
+
+// Run: 让 inputs 依次流过三个阶段，返回按输入顺序排列的结果
+//   - buffer: 阶段之间 channel 的容量（<=0 时取 DefaultBuffer），限制在途的项数
+//   - emit: 可选，每当下一个序号的结果就绪时按输入顺序调用（不必等全部完成）
+func Run[A, B, C, D any](ctx context.Context, inputs []A, s1 Stage[A, B], s2 Stage[B, C], s3 Stage[C, D],
+	buffer int, emit func(seq int, out D)) ([]D, error) {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// fail: 记录第一个错误并取消所有阶段
+	fail := func(err error) { cancel(err) }
+
+	source := make(chan item[A], buffer)
+	go func() {
+		defer close(source)
+		for i, in := range inputs {
+			select {
+			case source <- item[A]{seq: i, val: in}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	out1 := runStage(ctx, s1, source, buffer, fail)
+	out2 := runStage(ctx, s2, out1, buffer, fail)
+	out3 := runStage(ctx, s3, out2, buffer, fail)
+
+	// 汇总：乱序到达的结果暂存，按序号依次放入结果并回调
+	results := make([]D, len(inputs))
+	pending := map[int]D{}
+	next := 0
+	for it := range out3 {
+		pending[it.seq] = it.val
+		for {
+			val, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			results[next] = val
+			if emit != nil {
+				emit(next, val)
+			}
+			next++
+		}
+	}
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// runStage: 启动一个阶段的 worker 池，所有 worker 结束后关闭输出 channel
+func runStage[I, O any](ctx context.Context, stage Stage[I, O], in <-chan item[I], buffer int, fail func(error)) <-chan item[O] {
+	workers := stage.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	out := make(chan item[O], buffer)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range in {
+				if ctx.Err() != nil {
+					continue // 已取消：丢弃剩余输入，让上游尽快结束
+				}
+				val, err := stage.Fn(ctx, it.val)
+				if err != nil {
+					fail(fmt.Errorf("阶段 %s 处理第 %d 项失败: %w", stage.Name, it.seq+1, err))
+					continue
+				}
+				select {
+				case out <- item[O]{seq: it.seq, val: val}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
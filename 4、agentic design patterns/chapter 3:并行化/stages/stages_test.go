@@ -0,0 +1,133 @@
+package stages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// identity: 原样返回的阶段
+func identity[T any](name string) Stage[T, T] {
+	return Stage[T, T]{Name: name, Fn: func(ctx context.Context, in T) (T, error) { return in, nil }}
+}
+
+func TestRunOrdered(t *testing.T) {
+	inputs := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	double := Stage[int, int]{Name: "翻倍", Workers: 4, Fn: func(ctx context.Context, in int) (int, error) {
+		// 前面的项处理得更慢，多个 worker 下结果会乱序到达
+		time.Sleep(time.Duration(len(inputs)-in) * time.Millisecond)
+		return in * 2, nil
+	}}
+	format := Stage[int, string]{Name: "格式化", Workers: 2, Fn: func(ctx context.Context, in int) (string, error) {
+		return strconv.Itoa(in), nil
+	}}
+
+	var emitted []string
+	got, err := Run(context.Background(), inputs, identity[int]("原样"), double, format, 2, func(seq int, out string) {
+		emitted = append(emitted, fmt.Sprintf("%d:%s", seq, out))
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := "[2 4 6 8 10 12 14 16]"; fmt.Sprint(got) != want {
+		t.Errorf("结果 = %v，want %s", got, want)
+	}
+	if want := "0:2,1:4,2:6,3:8,4:10,5:12,6:14,7:16"; strings.Join(emitted, ",") != want {
+		t.Errorf("回调顺序 = %v，want %s", emitted, want)
+	}
+}
+
+func TestRunEmpty(t *testing.T) {
+	got, err := Run(context.Background(), nil, identity[int]("a"), identity[int]("b"), identity[int]("c"), 0, nil)
+	if err != nil || len(got) != 0 {
+		t.Errorf("空输入: %v, %v", got, err)
+	}
+}
+
+// TestRunPipelined: 三个阶段同时工作，总耗时接近"最慢阶段 × 项数"而不是"所有阶段之和 × 项数"
+func TestRunPipelined(t *testing.T) {
+	slow := func(name string) Stage[int, int] {
+		return Stage[int, int]{Name: name, Fn: func(ctx context.Context, in int) (int, error) {
+			time.Sleep(20 * time.Millisecond)
+			return in, nil
+		}}
+	}
+	start := time.Now()
+	if _, err := Run(context.Background(), make([]int, 6), slow("a"), slow("b"), slow("c"), 0, nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// 串行需要 3 × 6 × 20ms = 360ms，流水线约 (6 + 2) × 20ms = 160ms
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("耗时 %s，阶段之间没有重叠", elapsed)
+	}
+}
+
+func TestRunWorkerLimit(t *testing.T) {
+	var active, maxActive atomic.Int32
+	var mu sync.Mutex
+	limited := Stage[int, int]{Name: "限并发", Workers: 3, Fn: func(ctx context.Context, in int) (int, error) {
+		n := active.Add(1)
+		mu.Lock()
+		if n > maxActive.Load() {
+			maxActive.Store(n)
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		active.Add(-1)
+		return in, nil
+	}}
+	if _, err := Run(context.Background(), make([]int, 12), identity[int]("a"), limited, identity[int]("c"), 8, nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if m := maxActive.Load(); m > 3 || m < 2 {
+		t.Errorf("最大并发 = %d，want 2~3", m)
+	}
+}
+
+func TestRunStageError(t *testing.T) {
+	boom := errors.New("故障")
+	var processed atomic.Int32
+	failing := Stage[int, int]{Name: "转换", Fn: func(ctx context.Context, in int) (int, error) {
+		if in == 3 {
+			return 0, boom
+		}
+		return in, nil
+	}}
+	counting := Stage[int, int]{Name: "计数", Fn: func(ctx context.Context, in int) (int, error) {
+		processed.Add(1)
+		return in, nil
+	}}
+
+	inputs := make([]int, 100)
+	for i := range inputs {
+		inputs[i] = i
+	}
+	got, err := Run(context.Background(), inputs, identity[int]("原样"), failing, counting, 1, nil)
+	if !errors.Is(err, boom) || got != nil {
+		t.Fatalf("结果 = %v，错误 = %v，want 阶段错误", got, err)
+	}
+	if !strings.Contains(err.Error(), "阶段 转换 处理第 4 项失败") {
+		t.Errorf("错误信息 = %q", err)
+	}
+	if n := processed.Load(); n > 10 {
+		t.Errorf("出错后下游仍处理了 %d 项", n)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	blocking := Stage[int, int]{Name: "阻塞", Fn: func(ctx context.Context, in int) (int, error) {
+		cancel()
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}}
+	if _, err := Run(ctx, []int{1, 2, 3}, identity[int]("a"), blocking, identity[int]("c"), 0, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("取消后错误 = %v", err)
+	}
+}
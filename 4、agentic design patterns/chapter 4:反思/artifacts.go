@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ch4/usage"
+)
+
+const (
+	defaultArtifactRoot = "outputs"         // defaultArtifactRoot: 运行产物的默认根目录
+	runDirPrefix        = "reflection-"     // runDirPrefix: 每次运行的目录名前缀，后接时间戳
+	runDirLayout        = "20060102-150405" // runDirLayout: 目录名中的时间戳格式，按字典序即按时间排序
+)
+
+// ArtifactWriter: 把每轮的代码、批评、diff 和整次运行的摘要写入 <root>/reflection-<时间戳>/
+type ArtifactWriter struct {
+	Dir string // Dir: 本次运行的产物目录
+}
+
+// NewArtifactWriter: 在 root 下创建本次运行的目录（同一秒内重复创建时追加序号）
+func NewArtifactWriter(root string, now time.Time) (*ArtifactWriter, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("创建产物根目录失败: %w", err)
+	}
+	base := filepath.Join(root, runDirPrefix+now.Format(runDirLayout))
+	dir := base
+	for n := 2; ; n++ {
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return &ArtifactWriter{Dir: dir}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("创建产物目录失败: %w", err)
+		}
+		dir = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// WriteIteration: 写入一轮的 iter-<n><ext>（如 .py）、iter-<n>.critique.txt，内容有变化时还写入 iter-<n>.diff，
+// 启用多候选时首轮还写入每个候选 iter-1.candidate-<k><ext>
+// 某个文件写入失败不影响其余文件，所有错误合并返回
+func (w *ArtifactWriter) WriteIteration(it Iteration, ext string) error {
+	prefix := filepath.Join(w.Dir, fmt.Sprintf("iter-%d", it.Number))
+	errs := []error{
+		writeArtifact(prefix+ext, strings.TrimRight(it.Code, "\n")+"\n"),
+		writeArtifact(prefix+".critique.txt", strings.TrimRight(it.Critique, "\n")+"\n"),
+	}
+	if it.Diff != "" {
+		errs = append(errs, writeArtifact(prefix+".diff", it.Diff))
+	}
+	for _, candidate := range it.Candidates {
+		if candidate.Error == "" {
+			errs = append(errs, writeArtifact(fmt.Sprintf("%s.candidate-%d%s", prefix, candidate.Index, ext), strings.TrimRight(candidate.Code, "\n")+"\n"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteRun: 写入 run.json
+func (w *ArtifactWriter) WriteRun(record runRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 run.json 失败: %w", err)
+	}
+	return writeArtifact(filepath.Join(w.Dir, "run.json"), string(data)+"\n")
+}
+
+// writeArtifact: 写入一个产物文件
+func writeArtifact(path, content string) error {
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// pruneRuns: 只保留 root 下最新的 keep 个运行目录，删除更早的；keep<=0 时不清理
+func pruneRuns(root string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("读取产物根目录失败: %w", err)
+	}
+	var runs []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), runDirPrefix) {
+			runs = append(runs, entry.Name())
+		}
+	}
+	if len(runs) <= keep {
+		return nil, nil
+	}
+	// 按时间戳排序，同一秒内的 -2、-3 后缀排在原目录之后
+	sort.Slice(runs, func(i, j int) bool {
+		si, ni := splitRunDir(runs[i])
+		sj, nj := splitRunDir(runs[j])
+		if si != sj {
+			return si < sj
+		}
+		return ni < nj
+	})
+	var removed []string
+	var errs []error
+	for _, name := range runs[:len(runs)-keep] {
+		if err := os.RemoveAll(filepath.Join(root, name)); err != nil {
+			errs = append(errs, fmt.Errorf("删除 %s 失败: %w", name, err))
+			continue
+		}
+		removed = append(removed, name)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// splitRunDir: 把运行目录名拆成时间戳和序号（无后缀时序号为 1）
+func splitRunDir(name string) (string, int) {
+	stamp := strings.TrimPrefix(name, runDirPrefix)
+	if len(stamp) <= len(runDirLayout) {
+		return stamp, 1
+	}
+	seq, err := strconv.Atoi(strings.TrimPrefix(stamp[len(runDirLayout):], "-"))
+	if err != nil {
+		return stamp, 1
+	}
+	return stamp[:len(runDirLayout)], seq
+}
+
+// runRecord: run.json 的内容：配置、各轮耗时和 token 用量
+type runRecord struct {
+	GeneratorModel string            `json:"generator_model"`
+	CriticModel    string            `json:"critic_model"`
+	TaskType       string            `json:"task_type"`
+	Task           string            `json:"task"`
+	MaxIterations  int               `json:"max_iterations"`
+	Critics        []string          `json:"critics,omitempty"` // Critics: 多审查者模式下的审查者名称
+	ScoreThreshold float64           `json:"score_threshold,omitempty"`
+	MinImprovement float64           `json:"min_improvement,omitempty"`
+	TokenBudget    int               `json:"token_budget,omitempty"`
+	HistoryWindow  int               `json:"history_window,omitempty"`
+	Execute        bool              `json:"execute"`
+	StartedAt      time.Time         `json:"started_at"`
+	ElapsedMS      int64             `json:"elapsed_ms"`
+	Status         StopStatus        `json:"status"`
+	Error          string            `json:"error,omitempty"` // Error: 循环中途失败时的错误，已完成的轮次仍然记录
+	Failure        *failureRecord    `json:"failure,omitempty"`
+	Outstanding    string            `json:"outstanding_critique,omitempty"` // Outstanding: 代码收敛时未被采纳的批评
+	Usage          usageRecord       `json:"usage"`
+	GeneratorUsage usageRecord       `json:"generator_usage"`
+	CriticUsage    usageRecord       `json:"critic_usage"`
+	Iterations     []iterationRecord `json:"iterations"`
+	Issues         []IssueRecord     `json:"issues,omitempty"` // Issues: 问题的生命周期（启用结构化问题列表时）
+}
+
+// failureRecord: 某一阶段重试用尽的位置和检查点
+type failureRecord struct {
+	Phase      phase  `json:"phase"`
+	Iteration  int    `json:"iteration"`
+	Attempts   int    `json:"attempts"`
+	Checkpoint string `json:"checkpoint,omitempty"`
+}
+
+// iterationRecord: run.json 中一轮的摘要（代码和批评见 iter-<n>.* 文件）
+type iterationRecord struct {
+	Number           int         `json:"number"`
+	ElapsedMS        int64       `json:"elapsed_ms"`
+	Usage            usageRecord `json:"usage"`
+	Generator        int         `json:"generator_tokens"`
+	Critic           int         `json:"critic_tokens"`
+	Total            int         `json:"cumulative_tokens"` // Total: 截至本轮的累计 token 数
+	Score            float64     `json:"score,omitempty"`
+	ExitCode         *int        `json:"exit_code,omitempty"` // ExitCode: 启用执行阶段且实际运行时的退出码
+	Unchanged        bool        `json:"unchanged,omitempty"`
+	Stopped          bool        `json:"stopped,omitempty"`
+	GenerateAttempts int         `json:"generate_attempts"`
+	CritiqueAttempts int         `json:"critique_attempts"`
+	Candidates       []Candidate `json:"candidates,omitempty"` // Candidates: 首轮各候选的评分和是否入选
+}
+
+// usageRecord: token 用量
+type usageRecord struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func newUsageRecord(u usage.Usage) usageRecord {
+	return usageRecord{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.Total()}
+}
+
+// newRunRecord: 由配置和（可能不完整的）结果生成 run.json 的内容
+func newRunRecord(cfg ReflectionConfig, result ReflectionResult, started time.Time, runErr error) runRecord {
+	record := runRecord{
+		GeneratorModel: cfg.GeneratorModelName,
+		CriticModel:    cfg.CriticModelName,
+		Task:           strings.TrimSpace(cfg.TaskPrompt),
+		TaskType:       cfg.Profile.Name(),
+		MaxIterations:  cfg.MaxIterations,
+		ScoreThreshold: cfg.ScoreThreshold,
+		TokenBudget:    cfg.TokenBudget,
+		HistoryWindow:  cfg.HistoryWindow,
+		Execute:        cfg.Execute != nil,
+		StartedAt:      started,
+		ElapsedMS:      time.Since(started).Milliseconds(),
+		Status:         result.Status,
+		Outstanding:    result.Outstanding,
+		Usage:          newUsageRecord(result.Usage),
+		GeneratorUsage: newUsageRecord(result.Roles.Generator),
+		CriticUsage:    newUsageRecord(result.Roles.Critic),
+		Iterations:     []iterationRecord{},
+		Issues:         result.Issues,
+	}
+	if cfg.ScoreThreshold > 0 {
+		record.MinImprovement = cfg.MinImprovement
+	}
+	for _, critic := range cfg.Critics {
+		record.Critics = append(record.Critics, critic.Key)
+	}
+	if runErr != nil {
+		record.Error = runErr.Error()
+		var phaseErr *PhaseError
+		if errors.As(runErr, &phaseErr) {
+			record.Failure = &failureRecord{Phase: phaseErr.Phase, Iteration: phaseErr.Iteration, Attempts: phaseErr.Attempts, Checkpoint: phaseErr.Checkpoint}
+		}
+	}
+	for _, it := range result.Iterations {
+		item := iterationRecord{
+			Number:           it.Number,
+			ElapsedMS:        it.Elapsed.Milliseconds(),
+			Usage:            newUsageRecord(it.Usage),
+			Generator:        it.Roles.Generator.Total(),
+			Critic:           it.Roles.Critic.Total(),
+			Total:            it.Total.Total(),
+			Score:            it.Score,
+			Unchanged:        it.Unchanged,
+			Stopped:          it.Stopped,
+			GenerateAttempts: it.GenerateAttempts,
+			CritiqueAttempts: it.CritiqueAttempts,
+			Candidates:       it.Candidates,
+		}
+		if it.Exec != nil && !it.Exec.Skipped {
+			item.ExitCode = &it.Exec.ExitCode
+		}
+		record.Iterations = append(record.Iterations, item)
+	}
+	return record
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"ch4/fakemodel"
+	"ch4/usage"
+)
+
+// readArtifact: 读取产物文件
+func readArtifact(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 %s: %v", filepath.Base(path), err)
+	}
+	return string(data)
+}
+
+// dirNames: 目录下的文件名（排序后）
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("读取目录: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestNewArtifactWriterUniqueDirs(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "outputs")
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	var dirs []string
+	for i := 0; i < 3; i++ {
+		w, err := NewArtifactWriter(root, now)
+		if err != nil {
+			t.Fatalf("NewArtifactWriter: %v", err)
+		}
+		dirs = append(dirs, filepath.Base(w.Dir))
+	}
+	want := "reflection-20240501-093000,reflection-20240501-093000-2,reflection-20240501-093000-3"
+	if got := strings.Join(dirs, ","); got != want {
+		t.Errorf("目录 = %s，want %s", got, want)
+	}
+}
+
+func TestWriteIteration(t *testing.T) {
+	w, err := NewArtifactWriter(t.TempDir(), time.Now())
+	if err != nil {
+		t.Fatalf("NewArtifactWriter: %v", err)
+	}
+	first := Iteration{Number: 1, Code: "print(1)", Critique: "- 问题\n\n", Candidates: []Candidate{
+		{Index: 1, Code: "a"}, {Index: 2, Error: "生成失败"}, {Index: 3, Code: "c\n"},
+	}}
+	second := Iteration{Number: 2, Code: "print(2)\n", Critique: "CODE_IS_PERFECT", Diff: "-print(1)\n+print(2)\n"}
+	for _, it := range []Iteration{first, second} {
+		if err := w.WriteIteration(it, ".py"); err != nil {
+			t.Fatalf("WriteIteration: %v", err)
+		}
+	}
+
+	want := []string{"iter-1.candidate-1.py", "iter-1.candidate-3.py", "iter-1.critique.txt", "iter-1.py", "iter-2.critique.txt", "iter-2.diff", "iter-2.py"}
+	if got := dirNames(t, w.Dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("产物 = %v，want %v", got, want)
+	}
+	// 代码和批评统一以单个换行结尾，diff 原样写入
+	for name, content := range map[string]string{
+		"iter-1.py":             "print(1)\n",
+		"iter-1.critique.txt":   "- 问题\n",
+		"iter-1.candidate-3.py": "c\n",
+		"iter-2.diff":           "-print(1)\n+print(2)\n",
+	} {
+		if got := readArtifact(t, filepath.Join(w.Dir, name)); got != content {
+			t.Errorf("%s = %q，want %q", name, got, content)
+		}
+	}
+}
+
+func TestWriteIterationJoinsErrors(t *testing.T) {
+	w := &ArtifactWriter{Dir: filepath.Join(t.TempDir(), "missing")}
+	err := w.WriteIteration(Iteration{Number: 1, Diff: "d"}, ".py")
+	if err == nil || len(splitJoined(err)) != 3 {
+		t.Errorf("错误 = %v，want 三个文件各自的错误", err)
+	}
+}
+
+// splitJoined: 展开 errors.Join 合并的错误
+func splitJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func TestPruneRuns(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"reflection-20240101-000000",
+		"reflection-20240102-000000-2",
+		"reflection-20240102-000000",
+		"reflection-20240102-000000-10",
+		"reflection-20240103-000000",
+		"notes",
+	} {
+		if err := os.Mkdir(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "reflection-file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := pruneRuns(root, 2)
+	if err != nil {
+		t.Fatalf("pruneRuns: %v", err)
+	}
+	if want := "reflection-20240101-000000,reflection-20240102-000000,reflection-20240102-000000-2"; strings.Join(removed, ",") != want {
+		t.Errorf("删除 = %v，want %s", removed, want)
+	}
+	want := "notes,reflection-20240102-000000-10,reflection-20240103-000000,reflection-file"
+	if got := strings.Join(dirNames(t, root), ","); got != want {
+		t.Errorf("剩余 = %s，want %s", got, want)
+	}
+
+	if removed, err := pruneRuns(root, 0); err != nil || removed != nil {
+		t.Errorf("keep=0 时不应清理: %v, %v", removed, err)
+	}
+	if _, err := pruneRuns(filepath.Join(root, "missing"), 1); err == nil {
+		t.Errorf("根目录不存在时应返回错误")
+	}
+}
+
+func TestNewRunRecord(t *testing.T) {
+	cfg := ReflectionConfig{
+		Profile: pythonCodeProfile, TaskPrompt: "\n任务\n", MaxIterations: 3, GeneratorModelName: "gen", CriticModelName: "critic",
+		Critics: defaultCritics[:2], ScoreThreshold: 8, MinImprovement: 1,
+	}
+	result := ReflectionResult{
+		Status: StatusThreshold,
+		Usage:  usage.Usage{PromptTokens: 30, CompletionTokens: 10},
+		Roles:  RoleUsage{Generator: usage.Usage{PromptTokens: 10, CompletionTokens: 8}, Critic: usage.Usage{PromptTokens: 20, CompletionTokens: 2}},
+		Iterations: []Iteration{
+			{Number: 1, Exec: &ExecResult{ExitCode: 1}, Usage: usage.Usage{PromptTokens: 30, CompletionTokens: 10}, Total: usage.Usage{PromptTokens: 30, CompletionTokens: 10}, Score: 8, Stopped: true},
+		},
+	}
+	record := newRunRecord(cfg, result, time.Now(), &PhaseError{Phase: phaseCritique, Iteration: 2, Attempts: 3, Err: errors.New("503")})
+	if record.Task != "任务" || record.TaskType != "python-code" || strings.Join(record.Critics, ",") != "correctness,style" {
+		t.Errorf("配置记录 = %+v", record)
+	}
+	if record.MinImprovement != 1 || record.Usage.TotalTokens != 40 || record.CriticUsage.TotalTokens != 22 {
+		t.Errorf("用量记录 = %+v", record)
+	}
+	if record.Failure == nil || record.Failure.Phase != phaseCritique || record.Failure.Iteration != 2 || record.Error == "" {
+		t.Errorf("失败记录 = %+v, %q", record.Failure, record.Error)
+	}
+	it := record.Iterations[0]
+	if it.ExitCode == nil || *it.ExitCode != 1 || it.Total != 40 || !it.Stopped {
+		t.Errorf("迭代记录 = %+v", it)
+	}
+}
+
+// TestRunWritesArtifacts: 配置了 Artifacts 时每轮结束后写入产物，run.json 可以被解析
+func TestRunWritesArtifacts(t *testing.T) {
+	w, err := NewArtifactWriter(t.TempDir(), time.Now())
+	if err != nil {
+		t.Fatalf("NewArtifactWriter: %v", err)
+	}
+	llm := fakemodel.New("```python\nx = 1\n```", "- 改名", "y = 1", "CODE_IS_PERFECT")
+	runner := newTestRunner(t, llm, ReflectionConfig{TaskPrompt: "任务", Artifacts: w})
+	started := time.Now()
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := w.WriteRun(newRunRecord(runner.Config(), result, started, nil)); err != nil {
+		t.Fatalf("WriteRun: %v", err)
+	}
+
+	want := "iter-1.critique.txt,iter-1.py,iter-2.critique.txt,iter-2.diff,iter-2.py,run.json"
+	if got := strings.Join(dirNames(t, w.Dir), ","); got != want {
+		t.Errorf("产物 = %s，want %s", got, want)
+	}
+	if got := readArtifact(t, filepath.Join(w.Dir, "iter-1.py")); got != "x = 1\n" {
+		t.Errorf("iter-1.py = %q，代码块标记应已去掉", got)
+	}
+	var record runRecord
+	if err := json.Unmarshal([]byte(readArtifact(t, filepath.Join(w.Dir, "run.json"))), &record); err != nil {
+		t.Fatalf("解析 run.json: %v", err)
+	}
+	if record.Status != StatusPassed || len(record.Iterations) != 2 {
+		t.Errorf("run.json = %+v", record)
+	}
+}
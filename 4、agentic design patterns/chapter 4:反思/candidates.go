@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// rankingSystemPrompt: 排序链的系统提示词（FString 模板，字面花括号写成 {{ }}）
+const rankingSystemPrompt = `你是一名严格的评审，需要根据原始任务比较同一任务的多个候选方案。
+逐一评估每个候选方案满足任务要求的程度（正确性、完整性、质量），给出 1-10 的评分。
+只输出一个 JSON 对象，scores 数组按候选编号顺序给出评分，例如 {{"scores": [7, 9, 5]}}。`
+
+// Candidate: 首轮生成的一个候选方案
+type Candidate struct {
+	Index    int     `json:"index"`           // Index: 候选编号（从 1 开始）
+	Code     string  `json:"-"`               // Code: 后处理后的候选内容（写入 iter-1.candidate-<k> 文件）
+	Score    float64 `json:"score,omitempty"` // Score: 排序链给出的评分，排序失败时为 0
+	Selected bool    `json:"selected,omitempty"`
+	Error    string  `json:"error,omitempty"` // Error: 生成失败的原因，失败的候选不参与排序
+}
+
+// rankedScore: 排序链输出中的 "候选 2: 8"、"Candidate 2 - 8/10" 等形式
+var rankedScore = regexp.MustCompile(`(?i)(?:候选|candidate)\s*#?\s*(\d+)[^\d\n]{0,8}?(\d+(?:\.\d+)?)`)
+
+// buildRankingChain: 构建排序链：Lambda（列出候选）-> Template -> ChatModel -> Lambda（提取文本）
+func buildRankingChain(ctx context.Context, llm model.BaseChatModel, taskPrompt string) (compose.Runnable[[]string, string], error) {
+	rankingPrompt := prompt.FromMessages(
+		schema.FString,
+		schema.SystemMessage(rankingSystemPrompt),
+		schema.UserMessage("原始任务：\n{task_prompt}\n\n候选方案：\n{candidates}"),
+	)
+	prepareRanking := compose.InvokableLambda(func(ctx context.Context, candidates []string) (map[string]any, error) {
+		var sb strings.Builder
+		for i, candidate := range candidates {
+			fmt.Fprintf(&sb, "\n### 候选 %d\n%s\n", i+1, candidate)
+		}
+		return map[string]any{"task_prompt": taskPrompt, "candidates": sb.String()}, nil
+	})
+	extractContent := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+		return msg.Content, nil
+	})
+	return compose.NewChain[[]string, string]().
+		AppendLambda(prepareRanking).
+		AppendChatTemplate(rankingPrompt).
+		AppendChatModel(llm).
+		AppendLambda(extractContent).
+		Compile(ctx)
+}
+
+// parseRanking: 解析排序链的评分，优先 {"scores": [...]}，其次逐行的 "候选 k: s"；评分个数必须等于 n
+func parseRanking(text string, n int) ([]float64, error) {
+	for i := strings.IndexByte(text, '{'); i >= 0; {
+		var ranking struct {
+			Scores []float64 `json:"scores"`
+		}
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&ranking); err == nil && len(ranking.Scores) > 0 {
+			if len(ranking.Scores) != n {
+				return nil, fmt.Errorf("评分个数 %d 与候选个数 %d 不一致", len(ranking.Scores), n)
+			}
+			return ranking.Scores, nil
+		}
+		next := strings.IndexByte(text[i+1:], '{')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	scores := make([]float64, n)
+	found := 0
+	for _, m := range rankedScore.FindAllStringSubmatch(text, -1) {
+		index, err1 := strconv.Atoi(m[1])
+		score, err2 := strconv.ParseFloat(m[2], 64)
+		if err1 != nil || err2 != nil || index < 1 || index > n || scores[index-1] != 0 {
+			continue
+		}
+		scores[index-1] = score
+		found++
+	}
+	if found != n {
+		return nil, fmt.Errorf("无法从排序结果中解析出 %d 个评分", n)
+	}
+	return scores, nil
+}
+
+// bestCandidate: 评分最高的候选下标（并列时取编号小的）
+func bestCandidate(scores []float64) int {
+	best := 0
+	for i, score := range scores {
+		if score > scores[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// generateCandidates: 并发生成 n 个候选（模型调用经过共享限流器），用排序链打分，返回评分最高的候选
+// 部分候选失败时只在成功的候选中选择；排序失败时退回第一个成功的候选
+func (r *ReflectionRunner) generateCandidates(generateCtx, rankCtx context.Context, history []*schema.Message) (string, []Candidate, error) {
+	n := r.cfg.Candidates
+	fmt.Fprintf(r.log, "\n--- 并发生成 %d 个候选 ---\n", n)
+	candidates := make([]Candidate, n)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			candidates[i].Index = i + 1
+			output, err := r.generate.Invoke(generateCtx, history)
+			if err != nil {
+				candidates[i].Error = err.Error()
+				return
+			}
+			candidates[i].Code = r.cfg.Profile.PostProcess(output)
+		}(i)
+	}
+	wg.Wait()
+
+	var ok []int // ok: 生成成功的候选下标
+	var errs []error
+	for i, candidate := range candidates {
+		if candidate.Error != "" {
+			fmt.Fprintf(r.log, "⚠ 候选 %d 生成失败: %s\n", candidate.Index, candidate.Error)
+			errs = append(errs, fmt.Errorf("候选 %d: %s", candidate.Index, candidate.Error))
+			continue
+		}
+		ok = append(ok, i)
+	}
+	if len(ok) == 0 {
+		return "", candidates, fmt.Errorf("所有候选都生成失败: %w", errors.Join(errs...))
+	}
+
+	selected := ok[0]
+	if len(ok) > 1 {
+		codes := make([]string, len(ok))
+		for j, i := range ok {
+			codes[j] = candidates[i].Code
+		}
+		scores, err := r.rankCandidates(rankCtx, codes)
+		if err != nil {
+			fmt.Fprintf(r.log, "⚠ 候选排序失败，使用候选 %d: %v\n", candidates[selected].Index, err)
+		} else {
+			for j, i := range ok {
+				candidates[i].Score = scores[j]
+				fmt.Fprintf(r.log, "候选 %d：评分 %s\n", candidates[i].Index, formatScore(scores[j]))
+			}
+			selected = ok[bestCandidate(scores)]
+		}
+	}
+	candidates[selected].Selected = true
+	fmt.Fprintf(r.log, "选择候选 %d\n", candidates[selected].Index)
+	return candidates[selected].Code, candidates, nil
+}
+
+// rankCandidates: 调用排序链并解析评分
+func (r *ReflectionRunner) rankCandidates(ctx context.Context, codes []string) ([]float64, error) {
+	text, err := r.rank.Invoke(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	return parseRanking(text, len(codes))
+}
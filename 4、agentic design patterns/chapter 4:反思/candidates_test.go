@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ch4/fakemodel"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestParseRanking(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		n       int
+		want    []float64
+		wantErr string
+	}{
+		{"JSON", `{"scores": [7, 9, 5]}`, 3, []float64{7, 9, 5}, ""},
+		{"JSON 前有说明和其他花括号", "说明 {无关} 结果：\n```json\n{\"scores\": [6.5, 8]}\n```", 2, []float64{6.5, 8}, ""},
+		{"逐行评分", "候选 2: 8\n候选 1：6/10", 2, []float64{6, 8}, ""},
+		{"英文逐行", "Candidate #1 - 9\nCandidate 2 scored 4", 2, []float64{9, 4}, ""},
+		{"JSON 个数不符", `{"scores": [7, 9]}`, 3, nil, "评分个数 2 与候选个数 3 不一致"},
+		{"缺少某个候选", "候选 1: 8\n候选 1: 9", 2, nil, "无法从排序结果中解析出 2 个评分"},
+		{"编号越界", "候选 3: 8\n候选 1: 9", 2, nil, "无法从排序结果中解析出 2 个评分"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRanking(tt.text, tt.n)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("parseRanking = %v, %v，want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestCandidate(t *testing.T) {
+	if got := bestCandidate([]float64{7, 9, 9, 3}); got != 1 {
+		t.Errorf("bestCandidate = %d，want 并列时取编号小的 1", got)
+	}
+}
+
+// candidateHeader: 排序提示词中的候选小节
+var candidateHeader = regexp.MustCompile(`### 候选 (\d+)\n(.*)\n`)
+
+// rankingModel: 生成者调用依次返回 codes 中的内容；排序链调用时按候选内容查 scores 给分
+// 候选并发生成，先后顺序不确定，所以按内容而不是编号打分
+type rankingModel struct {
+	generator *fakemodel.Model
+	scores    map[string]float64
+	rankErr   error
+	delay     time.Duration
+
+	mu          sync.Mutex
+	rankInputs  []string
+	active, max atomic.Int32
+}
+
+func (m *rankingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if input[0].Role == schema.System && strings.HasPrefix(input[0].Content, "你是一名严格的评审") {
+		user := input[len(input)-1].Content
+		m.mu.Lock()
+		m.rankInputs = append(m.rankInputs, user)
+		m.mu.Unlock()
+		if m.rankErr != nil {
+			return nil, m.rankErr
+		}
+		var lines []string
+		for _, match := range candidateHeader.FindAllStringSubmatch(user, -1) {
+			lines = append(lines, fmt.Sprintf("候选 %s: %v", match[1], m.scores[match[2]]))
+		}
+		return schema.AssistantMessage(strings.Join(lines, "\n"), nil), nil
+	}
+	n := m.active.Add(1)
+	defer m.active.Add(-1)
+	for {
+		old := m.max.Load()
+		if n <= old || m.max.CompareAndSwap(old, n) {
+			break
+		}
+	}
+	time.Sleep(m.delay)
+	return m.generator.Generate(ctx, input, opts...)
+}
+
+func (m *rankingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+// selected: 入选的候选
+func selected(candidates []Candidate) (Candidate, int) {
+	var pick Candidate
+	n := 0
+	for _, c := range candidates {
+		if c.Selected {
+			pick = c
+			n++
+		}
+	}
+	return pick, n
+}
+
+func TestCandidatesPickBest(t *testing.T) {
+	llm := &rankingModel{
+		generator: fakemodel.New("alpha", "beta", "gamma"),
+		scores:    map[string]float64{"alpha": 5, "beta": 9, "gamma": 7},
+	}
+	runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{TaskPrompt: "任务", Candidates: 3, MaxIterations: 1,
+		ShouldStop: func(string) bool { return true }})
+	if err != nil {
+		t.Fatalf("NewReflectionRunner: %v", err)
+	}
+	// 审查者调用也走生成者的脚本
+	llm.generator.Push("LGTM")
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.FinalCode != "beta" {
+		t.Errorf("最终代码 = %q，want 评分最高的 beta", result.FinalCode)
+	}
+	candidates := result.Iterations[0].Candidates
+	pick, n := selected(candidates)
+	if len(candidates) != 3 || n != 1 || pick.Code != "beta" || pick.Score != 9 {
+		t.Errorf("候选 = %+v", candidates)
+	}
+	if len(llm.rankInputs) != 1 || !strings.Contains(llm.rankInputs[0], "原始任务：\n任务") {
+		t.Errorf("排序输入 = %q", llm.rankInputs)
+	}
+}
+
+func TestCandidatesPartialFailure(t *testing.T) {
+	llm := &rankingModel{generator: fakemodel.New("only").PushError(errors.New("503"))}
+	runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{TaskPrompt: "任务", Candidates: 2, MaxIterations: 1})
+	if err != nil {
+		t.Fatalf("NewReflectionRunner: %v", err)
+	}
+	code, candidates, err := runner.generateCandidates(context.Background(), context.Background(), runner.initialHistory())
+	if err != nil {
+		t.Fatalf("generateCandidates: %v", err)
+	}
+	// 只剩一个成功的候选时不排序
+	if code != "only" || len(llm.rankInputs) != 0 {
+		t.Errorf("代码 = %q，排序调用 %d 次", code, len(llm.rankInputs))
+	}
+	failed := 0
+	for _, c := range candidates {
+		if c.Error != "" {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("候选 = %+v，want 1 个失败", candidates)
+	}
+}
+
+func TestCandidatesAllFail(t *testing.T) {
+	llm := &rankingModel{generator: fakemodel.New().PushError(errors.New("503")).PushError(errors.New("502"))}
+	runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{TaskPrompt: "任务", Candidates: 2})
+	if err != nil {
+		t.Fatalf("NewReflectionRunner: %v", err)
+	}
+	_, _, err = runner.generateCandidates(context.Background(), context.Background(), runner.initialHistory())
+	if err == nil || !strings.Contains(err.Error(), "所有候选都生成失败") {
+		t.Errorf("错误 = %v", err)
+	}
+}
+
+// TestCandidatesRankingFailure: 排序失败时退回第一个成功的候选
+func TestCandidatesRankingFailure(t *testing.T) {
+	llm := &rankingModel{generator: fakemodel.New("x", "y"), rankErr: errors.New("排序模型故障")}
+	runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{TaskPrompt: "任务", Candidates: 2})
+	if err != nil {
+		t.Fatalf("NewReflectionRunner: %v", err)
+	}
+	code, candidates, err := runner.generateCandidates(context.Background(), context.Background(), runner.initialHistory())
+	if err != nil {
+		t.Fatalf("generateCandidates: %v", err)
+	}
+	if pick, _ := selected(candidates); code != candidates[0].Code || pick.Index != 1 || pick.Score != 0 {
+		t.Errorf("代码 = %q，候选 = %+v，want 第一个候选", code, candidates)
+	}
+}
+
+// TestCandidatesShareLimiter: 并发的候选共享限流器，同时进行的模型调用不超过上限
+func TestCandidatesShareLimiter(t *testing.T) {
+	llm := &rankingModel{
+		generator: fakemodel.New("a", "b", "c", "d"),
+		scores:    map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4},
+		delay:     20 * time.Millisecond,
+	}
+	runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{TaskPrompt: "任务", Candidates: 4, Limiter: newConcurrencyLimiter(2)})
+	if err != nil {
+		t.Fatalf("NewReflectionRunner: %v", err)
+	}
+	code, _, err := runner.generateCandidates(context.Background(), context.Background(), runner.initialHistory())
+	if err != nil || code != "d" {
+		t.Fatalf("generateCandidates = %q, %v", code, err)
+	}
+	if got := llm.max.Load(); got != 2 {
+		t.Errorf("最大并发 = %d，want 2", got)
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	if newConcurrencyLimiter(0) != nil {
+		t.Errorf("n<=0 时应返回 nil")
+	}
+	l := newConcurrencyLimiter(1)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("名额用完时 Acquire = %v，want 超时", err)
+	}
+	release()
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Errorf("归还后 Acquire: %v", err)
+	}
+}
+
+// TestLimitedModelStreamHoldsSlot: 流式调用在流读完之前一直占用名额
+func TestLimitedModelStreamHoldsSlot(t *testing.T) {
+	l := newConcurrencyLimiter(1)
+	m := &limitedModel{inner: &fakemodel.Model{ChunkSize: 1}, limiter: l}
+	m.inner.(*fakemodel.Model).Push("abc")
+	sr, err := m.Stream(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); err == nil {
+		t.Fatalf("流未读完时名额应仍被占用")
+	}
+	var sb strings.Builder
+	for {
+		msg, err := sr.Recv()
+		if err != nil {
+			break
+		}
+		sb.WriteString(msg.Content)
+	}
+	if sb.String() != "abc" {
+		t.Errorf("流内容 = %q", sb.String())
+	}
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("流读完后 Acquire: %v", err)
+	}
+	release()
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"shared/retry"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// checkpointVersion: 检查点文件格式版本，格式不兼容地变化时递增
+const checkpointVersion = 1
+
+// phase: 反思循环中调用模型的阶段
+type phase string
+
+const (
+	phaseGenerate phase = "generate" // phaseGenerate: 生成/完善阶段
+	phaseCritique phase = "critique" // phaseCritique: 反思（审查）阶段
+)
+
+// String: 阶段的中文名称
+func (p phase) String() string {
+	switch p {
+	case phaseGenerate:
+		return "生成"
+	case phaseCritique:
+		return "反思"
+	default:
+		return string(p)
+	}
+}
+
+// Checkpoint: 重试用尽时保存的进度，-resume 从失败的轮次和阶段继续
+type Checkpoint struct {
+	Version    int               `json:"version"`
+	TaskType   string            `json:"task_type"`
+	Task       string            `json:"task"`
+	SavedAt    time.Time         `json:"saved_at"`
+	Iteration  int               `json:"iteration"` // Iteration: 失败的轮次，从这一轮继续
+	Phase      phase             `json:"phase"`     // Phase: 失败的阶段；反思阶段失败时沿用已生成的代码，不重新生成
+	Error      string            `json:"error"`
+	History    []*schema.Message `json:"history"`
+	Code       string            `json:"code"`
+	Scores     []float64         `json:"scores,omitempty"`
+	Unchanged  int               `json:"unchanged,omitempty"`
+	OpenIssues []Issue           `json:"open_issues,omitempty"`
+	Issues     []IssueRecord     `json:"issues,omitempty"`
+	Pending    *Iteration        `json:"pending,omitempty"` // Pending: 反思阶段失败时本轮已生成的部分（代码、diff、候选、生成者用量）
+	Result     ReflectionResult  `json:"result"`            // Result: 已完成的轮次
+}
+
+// PhaseError: 某一阶段重试用尽后的错误，带上失败的位置和检查点路径
+type PhaseError struct {
+	Phase      phase
+	Iteration  int
+	Attempts   int
+	Checkpoint string // Checkpoint: 已保存的检查点路径，保存失败或未配置时为空
+	Err        error
+}
+
+func (e *PhaseError) Error() string {
+	msg := fmt.Sprintf("第 %d 轮%s阶段失败（尝试 %d 次）: %v", e.Iteration, e.Phase, e.Attempts, e.Err)
+	if e.Checkpoint != "" {
+		msg += fmt.Sprintf("；检查点已保存到 %s，可用 -resume %s 从此处继续", e.Checkpoint, e.Checkpoint)
+	}
+	return msg
+}
+
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// withRetry: 按 ReflectionConfig.Retry 重试一个阶段，重试过程写入日志，返回实际尝试次数
+// 运行截止时间通过 ctx 生效：等待重试时到期会立即返回
+func withRetry[T any](ctx context.Context, r *ReflectionRunner, p phase, fn func(ctx context.Context) (T, error)) (T, int, error) {
+	var lastErr error
+	out, res, err := retry.Do(ctx, r.cfg.Retry, func(ctx context.Context, attempt int) (T, error) {
+		if attempt > 1 {
+			fmt.Fprintf(r.log, "↻ %s阶段第 %d 次尝试（上次失败: %v）\n", p, attempt, lastErr)
+		}
+		out, err := fn(ctx)
+		lastErr = err
+		return out, err
+	})
+	return out, res.Attempts, err
+}
+
+// saveCheckpoint: 写入检查点文件
+func saveCheckpoint(path string, cp Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化检查点失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("写入检查点失败: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint: 读取检查点文件
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取检查点失败: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("解析检查点失败: %w", err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("不支持的检查点版本 %d（当前版本 %d）", cp.Version, checkpointVersion)
+	}
+	if cp.Phase != phaseGenerate && cp.Phase != phaseCritique {
+		return nil, fmt.Errorf("检查点的阶段无效: %q", cp.Phase)
+	}
+	if cp.Phase == phaseCritique && cp.Pending == nil {
+		return nil, fmt.Errorf("检查点缺少反思阶段失败时已生成的代码")
+	}
+	return &cp, nil
+}
+
+// fail: 阶段重试用尽：保存检查点（配置了路径时）并返回 PhaseError
+func (r *ReflectionRunner) fail(p phase, attempts int, err error, state ReflectionState, result ReflectionResult,
+	tracker *issueTracker, pending *Iteration) error {
+	phaseErr := &PhaseError{Phase: p, Iteration: state.Iteration, Attempts: attempts, Err: err}
+	if r.cfg.CheckpointPath == "" {
+		return phaseErr
+	}
+	cp := Checkpoint{
+		Version:    checkpointVersion,
+		TaskType:   r.cfg.Profile.Name(),
+		Task:       r.cfg.TaskPrompt,
+		SavedAt:    time.Now(),
+		Iteration:  state.Iteration,
+		Phase:      p,
+		Error:      err.Error(),
+		History:    state.MessageHistory,
+		Code:       state.CurrentCode,
+		Scores:     state.Scores,
+		Unchanged:  state.Unchanged,
+		OpenIssues: state.OpenIssues,
+		Issues:     tracker.lifecycle(),
+		Pending:    pending,
+		Result:     result,
+	}
+	if saveErr := saveCheckpoint(r.cfg.CheckpointPath, cp); saveErr != nil {
+		fmt.Fprintf(r.log, "⚠ %v\n", saveErr)
+		return phaseErr
+	}
+	phaseErr.Checkpoint = r.cfg.CheckpointPath
+	return phaseErr
+}
+
+// restore: 从检查点还原循环状态
+func (cp *Checkpoint) restore() (ReflectionState, ReflectionResult, *issueTracker) {
+	state := ReflectionState{
+		CurrentCode:    cp.Code,
+		MessageHistory: cp.History,
+		Iteration:      cp.Iteration,
+		Scores:         cp.Scores,
+		Unchanged:      cp.Unchanged,
+		OpenIssues:     cp.OpenIssues,
+	}
+	tracker := newIssueTracker()
+	for i := range cp.Issues {
+		record := cp.Issues[i]
+		tracker.records = append(tracker.records, &record)
+		if record.ResolvedIn == 0 {
+			tracker.open[record.key()] = &record
+		}
+	}
+	result := cp.Result
+	result.Status = StatusMaxIterations
+	return state, result, tracker
+}
+
+// countRetries: 所有轮次的重试次数（尝试次数减去第一次）
+func countRetries(iterations []Iteration) int {
+	retries := 0
+	for _, it := range iterations {
+		retries += max(it.GenerateAttempts-1, 0) + max(it.CritiqueAttempts-1, 0)
+	}
+	return retries
+}
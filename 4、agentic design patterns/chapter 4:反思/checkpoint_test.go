@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ch4/fakemodel"
+	"shared/retry"
+)
+
+// noWait: 重试时不真正等待
+func noWait(ctx context.Context, d time.Duration) error { return nil }
+
+// checkpointConfig: 失败两次才放弃、检查点写到临时目录的配置
+func checkpointConfig(t *testing.T) ReflectionConfig {
+	return ReflectionConfig{
+		TaskPrompt:     "写一个函数",
+		MaxIterations:  3,
+		ShouldStop:     func(critique string) bool { return strings.Contains(critique, "通过") },
+		Retry:          retry.Policy{MaxAttempts: 2, Sleep: noWait},
+		CheckpointPath: filepath.Join(t.TempDir(), "checkpoint.json"),
+	}
+}
+
+// TestCheckpointCritiqueFailureResume: 反思阶段重试用尽后保存检查点，继续时沿用已生成的代码
+func TestCheckpointCritiqueFailureResume(t *testing.T) {
+	cfg := checkpointConfig(t)
+	llm := fakemodel.New("v1", "还需要处理空输入", "v2").
+		PushError(errors.New("503 service unavailable")).
+		PushError(errors.New("503 service unavailable"))
+	_, err := newTestRunner(t, llm, cfg).Run(context.Background())
+	var phaseErr *PhaseError
+	if !errors.As(err, &phaseErr) {
+		t.Fatalf("错误 = %v，want *PhaseError", err)
+	}
+	if phaseErr.Phase != phaseCritique || phaseErr.Iteration != 2 || phaseErr.Attempts != 2 || phaseErr.Checkpoint != cfg.CheckpointPath {
+		t.Errorf("PhaseError = %+v", phaseErr)
+	}
+	if !strings.Contains(err.Error(), "-resume "+cfg.CheckpointPath) {
+		t.Errorf("错误信息没有提示如何继续: %v", err)
+	}
+
+	cp, err := LoadCheckpoint(cfg.CheckpointPath)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+	if cp.Iteration != 2 || cp.Phase != phaseCritique || cp.Pending == nil || cp.Pending.Code != "v2" || len(cp.Result.Iterations) != 1 {
+		t.Fatalf("检查点 = %+v", cp)
+	}
+
+	llm = fakemodel.New("通过")
+	result, err := newTestRunner(t, llm, cfg).Resume(context.Background(), cp)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	// 只调用了一次审查者，没有重新生成
+	if len(llm.Calls()) != 1 {
+		t.Errorf("继续后调用模型 %d 次，want 1", len(llm.Calls()))
+	}
+	if result.Status != StatusPassed || result.FinalCode != "v2" || len(result.Iterations) != 2 {
+		t.Errorf("结果 = %+v", result)
+	}
+	if got := result.Iterations[0].Critique; got != "还需要处理空输入" {
+		t.Errorf("第 1 轮批评 = %q", got)
+	}
+}
+
+// TestCheckpointGenerateFailureResume: 生成阶段失败时从该轮重新生成
+func TestCheckpointGenerateFailureResume(t *testing.T) {
+	cfg := checkpointConfig(t)
+	llm := fakemodel.New().PushError(errors.New("429 too many requests")).PushError(errors.New("429 too many requests"))
+	_, err := newTestRunner(t, llm, cfg).Run(context.Background())
+	var phaseErr *PhaseError
+	if !errors.As(err, &phaseErr) || phaseErr.Phase != phaseGenerate || phaseErr.Iteration != 1 {
+		t.Fatalf("错误 = %v", err)
+	}
+	cp, err := LoadCheckpoint(cfg.CheckpointPath)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+	llm = fakemodel.New("v1", "通过")
+	result, err := newTestRunner(t, llm, cfg).Resume(context.Background(), cp)
+	if err != nil || result.FinalCode != "v1" || result.Status != StatusPassed {
+		t.Errorf("Resume = %+v, %v", result, err)
+	}
+}
+
+func TestRetryCountsAttempts(t *testing.T) {
+	cfg := checkpointConfig(t)
+	cfg.CheckpointPath = ""
+	llm := fakemodel.New().PushError(errors.New("502 bad gateway")).Push("v1", "通过")
+	result, err := newTestRunner(t, llm, cfg).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if it := result.Iterations[0]; it.GenerateAttempts != 2 || it.CritiqueAttempts != 1 || countRetries(result.Iterations) != 1 {
+		t.Errorf("尝试次数 = %d/%d", it.GenerateAttempts, it.CritiqueAttempts)
+	}
+}
+
+// TestPhaseErrorWithoutCheckpoint: 未配置检查点路径时只返回错误，不写文件
+func TestPhaseErrorWithoutCheckpoint(t *testing.T) {
+	cfg := checkpointConfig(t)
+	cfg.CheckpointPath = ""
+	llm := fakemodel.New().PushError(errors.New("invalid api key"))
+	_, err := newTestRunner(t, llm, cfg).Run(context.Background())
+	var phaseErr *PhaseError
+	if !errors.As(err, &phaseErr) || phaseErr.Attempts != 1 || phaseErr.Checkpoint != "" {
+		t.Fatalf("不可重试的错误应只尝试一次: %v", err)
+	}
+	if strings.Contains(err.Error(), "-resume") {
+		t.Errorf("没有检查点时不应提示 -resume: %v", err)
+	}
+}
+
+func TestLoadCheckpointValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cp      Checkpoint
+		wantErr string
+	}{
+		{"版本不符", Checkpoint{Version: checkpointVersion + 1, Phase: phaseGenerate}, "不支持的检查点版本"},
+		{"阶段无效", Checkpoint{Version: checkpointVersion, Phase: "execute"}, "检查点的阶段无效"},
+		{"反思阶段缺少代码", Checkpoint{Version: checkpointVersion, Phase: phaseCritique}, "缺少反思阶段失败时已生成的代码"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cp.json")
+			data, _ := json.Marshal(tt.cp)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadCheckpoint(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("文件不存在时应返回错误")
+	}
+}
+
+func TestResumeRejectsMismatch(t *testing.T) {
+	cfg := checkpointConfig(t)
+	runner := newTestRunner(t, fakemodel.New(), cfg)
+	tests := []struct {
+		name    string
+		cp      Checkpoint
+		wantErr string
+	}{
+		{"任务不同", Checkpoint{TaskType: pythonCodeProfile.Name(), Task: "别的任务", Iteration: 1}, "不一致"},
+		{"任务类型不同", Checkpoint{TaskType: "sql", Task: cfg.TaskPrompt, Iteration: 1}, "不一致"},
+		{"轮次超过上限", Checkpoint{TaskType: pythonCodeProfile.Name(), Task: cfg.TaskPrompt, Iteration: 4}, "超过了最大迭代次数"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := runner.Resume(context.Background(), &tt.cp); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// CriticSpec: 一个审查者的定义
+type CriticSpec struct {
+	Key          string // Key: 审查者名称，同时是并行图节点名和输出键
+	Label        string // Label: 批评小节的中文标题
+	SystemPrompt string // SystemPrompt: 审查者的系统提示词（FString 模板，字面花括号需写成 {{ }}）
+	Sentinel     string // Sentinel: 审查者认为没有问题时输出的标记
+}
+
+// defaultCritics: 多审查者模式的默认审查者：正确性、风格、安全
+var defaultCritics = []CriticSpec{
+	{
+		Key:   "correctness",
+		Label: "正确性与边缘情况",
+		SystemPrompt: `你是一名严谨的 Python 测试工程师，只关注正确性。
+根据原始任务检查代码的逻辑错误、遗漏的边缘情况和与要求不符之处，不要评论风格或安全。
+如果没有任何正确性问题，只输出 'CORRECTNESS_PERFECT'，否则输出问题的项目符号列表。`,
+		Sentinel: "CORRECTNESS_PERFECT",
+	},
+	{
+		Key:   "style",
+		Label: "风格与可读性",
+		SystemPrompt: `你是一名注重代码质量的 Python 评审，只关注风格与可读性。
+检查命名、文档字符串、PEP 8、结构和注释，不要评论逻辑正确性或安全。
+如果没有任何风格问题，只输出 'STYLE_PERFECT'，否则输出问题的项目符号列表。`,
+		Sentinel: "STYLE_PERFECT",
+	},
+	{
+		Key:   "security",
+		Label: "安全与输入校验",
+		SystemPrompt: `你是一名安全工程师，只关注安全与输入校验。
+检查类型和取值校验、异常处理、资源耗尽（如超大输入、深递归）和不安全的调用，不要评论风格。
+如果没有任何安全问题，只输出 'SECURITY_PERFECT'，否则输出问题的项目符号列表。`,
+		Sentinel: "SECURITY_PERFECT",
+	},
+}
+
+// CriticReview: 一个审查者对一版代码的意见
+type CriticReview struct {
+	Key    string  // Key: 审查者名称
+	Label  string  // Label: 审查者中文标题
+	Output string  // Output: 审查者的原始输出
+	Passed bool    // Passed: 审查者认为没有问题
+	Score  float64 // Score: 审查者的质量评分（1-10），未评分时为 0
+}
+
+// critique: 一轮审查的结果
+type critique struct {
+	Text       string         // Text: 交给生成者的批评（多审查者时按审查者分节）
+	Passed     bool           // Passed: 满足停止条件（多审查者时要求全体通过）
+	Reviews    []CriticReview // Reviews: 各审查者的意见，单审查者模式为空
+	Score      float64        // Score: 质量评分（多审查者时取最低分），未评分时为 0
+	Issues     []Issue        // Issues: 结构化的问题列表（启用时）
+	Structured bool           // Structured: 所有审查者的输出都解析出了问题数组
+}
+
+// criticFormat: 对审查者输出格式的额外要求
+type criticFormat struct {
+	Scored bool // Scored: 末尾给出 1-10 的质量评分
+	Issues bool // Issues: 以 JSON 数组列出问题，并把上一轮未解决的问题交给审查者核对
+}
+
+// critiqueFunc: 对当前状态执行一轮审查
+type critiqueFunc func(ctx context.Context, state ReflectionState) (critique, error)
+
+// buildCriticChain: 构建审查链：Lambda（准备输入）-> Template -> ChatModel -> Lambda（提取文本）
+// criticKey 是审查者名称（单审查者为空），用于从未解决的问题中挑出该审查者报告过的
+func buildCriticChain(ctx context.Context, llm model.BaseChatModel, taskPrompt, systemPrompt, criticKey string, format criticFormat) (compose.Runnable[ReflectionState, string], error) {
+	if format.Issues {
+		systemPrompt += issueInstruction
+	}
+	if format.Scored {
+		systemPrompt += scoreInstruction
+	}
+	reflectorPrompt := prompt.FromMessages(
+		schema.FString,
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage("原始任务：\n{task_prompt}\n\n要审查的代码：\n{current_code}{execution}{open_issues}"),
+	)
+	// Lambda 函数：准备反思输入
+	prepareReflection := compose.InvokableLambda(func(ctx context.Context, state ReflectionState) (map[string]any, error) {
+		execution := ""
+		if state.Execution != "" {
+			execution = "\n\n代码的实际运行结果：\n" + state.Execution
+		}
+		openIssues := ""
+		var previous []Issue
+		for _, issue := range state.OpenIssues {
+			if issue.Critic == criticKey {
+				previous = append(previous, issue)
+			}
+		}
+		if len(previous) > 0 {
+			openIssues = "\n\n上一轮报告的未解决问题（仍然存在的沿用原 id，已修复的不要再列出）：\n" + formatIssues(previous)
+		}
+		return map[string]any{
+			"task_prompt":  taskPrompt,
+			"current_code": state.CurrentCode,
+			"execution":    execution,
+			"open_issues":  openIssues,
+		}, nil
+	})
+	// Lambda 函数：从 Message 中提取 Content
+	extractContent := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+		return msg.Content, nil
+	})
+	return compose.NewChain[ReflectionState, string]().
+		AppendLambda(prepareReflection).
+		AppendChatTemplate(reflectorPrompt).
+		AppendChatModel(llm).
+		AppendLambda(extractContent).
+		Compile(ctx)
+}
+
+// buildSingleCritic: 单审查者：批评原样交给生成者，shouldStop 判断是否停止
+func buildSingleCritic(ctx context.Context, llm model.BaseChatModel, taskPrompt, systemPrompt string,
+	shouldStop func(critique string) bool, format criticFormat) (critiqueFunc, error) {
+	chain, err := buildCriticChain(ctx, llm, taskPrompt, systemPrompt, "", format)
+	if err != nil {
+		return nil, fmt.Errorf("编译反思链失败: %w", err)
+	}
+	return func(ctx context.Context, state ReflectionState) (critique, error) {
+		text, err := chain.Invoke(ctx, state)
+		if err != nil {
+			return critique{}, err
+		}
+		score, _ := parseScore(text)
+		result := critique{Text: text, Passed: shouldStop(text), Score: score}
+		if format.Issues {
+			// 空的问题数组等同于停止标记
+			result.Issues, result.Structured = parseIssues(text)
+			result.Passed = result.Passed || (result.Structured && len(result.Issues) == 0)
+		}
+		return result, nil
+	}, nil
+}
+
+// buildMultiCritic: 多审查者：每个审查者是并行图中的一个节点（与第 3 章的并行分支相同），
+// 所有审查者同时审查同一版代码，意见按审查者分节合并；只有全体通过才停止
+func buildMultiCritic(ctx context.Context, llm model.BaseChatModel, taskPrompt string, critics []CriticSpec, format criticFormat) (critiqueFunc, error) {
+	seen := map[string]bool{}
+	graph := compose.NewGraph[ReflectionState, map[string]any]()
+	for _, spec := range critics {
+		if spec.Key == "" || seen[spec.Key] {
+			return nil, fmt.Errorf("审查者名称为空或重复: %q", spec.Key)
+		}
+		seen[spec.Key] = true
+		chain, err := buildCriticChain(ctx, llm, taskPrompt, spec.SystemPrompt, spec.Key, format)
+		if err != nil {
+			return nil, fmt.Errorf("编译%s审查链失败: %w", spec.Label, err)
+		}
+		node := compose.InvokableLambda(func(ctx context.Context, state ReflectionState) (string, error) {
+			return chain.Invoke(ctx, state)
+		})
+		if err := graph.AddLambdaNode(spec.Key, node, compose.WithOutputKey(spec.Key)); err != nil {
+			return nil, fmt.Errorf("添加 %s 节点失败: %w", spec.Key, err)
+		}
+		if err := graph.AddEdge(compose.START, spec.Key); err != nil {
+			return nil, fmt.Errorf("添加边 START -> %s 失败: %w", spec.Key, err)
+		}
+		if err := graph.AddEdge(spec.Key, compose.END); err != nil {
+			return nil, fmt.Errorf("添加边 %s -> END 失败: %w", spec.Key, err)
+		}
+	}
+	runnable, err := graph.Compile(ctx, compose.WithNodeTriggerMode(compose.AllPredecessor))
+	if err != nil {
+		return nil, fmt.Errorf("编译审查图失败: %w", err)
+	}
+
+	return func(ctx context.Context, state ReflectionState) (critique, error) {
+		outputs, err := runnable.Invoke(ctx, state)
+		if err != nil {
+			return critique{}, err
+		}
+		result := critique{Passed: true, Structured: format.Issues}
+		for _, spec := range critics {
+			output, _ := outputs[spec.Key].(string)
+			review := CriticReview{Key: spec.Key, Label: spec.Label, Output: output, Passed: criticPassed(spec, output)}
+			review.Score, _ = parseScore(output)
+			if format.Issues {
+				issues, ok := parseIssues(output)
+				for _, issue := range issues {
+					issue.Critic = spec.Key
+					result.Issues = append(result.Issues, issue)
+				}
+				result.Structured = result.Structured && ok
+			}
+			result.Reviews = append(result.Reviews, review)
+			result.Passed = result.Passed && review.Passed
+			if review.Score > 0 && (result.Score == 0 || review.Score < result.Score) {
+				result.Score = review.Score
+			}
+		}
+		result.Text = mergeReviews(result.Reviews)
+		return result, nil
+	}, nil
+}
+
+// criticPassed: 审查者是否认为没有问题：输出包含自己的标记，或是空的问题列表（如 "[]"，可带评分行）
+func criticPassed(spec CriticSpec, output string) bool {
+	if spec.Sentinel != "" && strings.Contains(output, spec.Sentinel) {
+		return true
+	}
+	trimmed := strings.TrimSpace(labeledScore.ReplaceAllString(cleanCodeBlock(output), ""))
+	return trimmed == "[]"
+}
+
+// mergeReviews: 把各审查者的意见合并为分节的批评，通过的审查者标记为"未发现问题"
+func mergeReviews(reviews []CriticReview) string {
+	var sb strings.Builder
+	for i, review := range reviews {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "## %s\n", review.Label)
+		if review.Passed {
+			sb.WriteString("未发现问题。")
+		} else {
+			sb.WriteString(strings.TrimSpace(review.Output))
+		}
+	}
+	return sb.String()
+}
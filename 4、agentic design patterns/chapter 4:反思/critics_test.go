@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"ch4/fakemodel"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// routedModel: 按系统提示词中的关键字把调用分给不同的脚本（并行审查者的调用顺序不确定），
+// 没有系统提示词或没有匹配的关键字时交给 fallback（生成者）
+type routedModel struct {
+	routes   map[string]*fakemodel.Model
+	fallback *fakemodel.Model
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (m *routedModel) pick(input []*schema.Message) *fakemodel.Model {
+	if len(input) > 0 && input[0].Role == schema.System {
+		for key, script := range m.routes {
+			if strings.Contains(input[0].Content, key) {
+				m.mu.Lock()
+				if m.counts == nil {
+					m.counts = map[string]int{}
+				}
+				m.counts[key]++
+				m.mu.Unlock()
+				return script
+			}
+		}
+	}
+	return m.fallback
+}
+
+func (m *routedModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.pick(input).Generate(ctx, input, opts...)
+}
+
+func (m *routedModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return m.pick(input).Stream(ctx, input, opts...)
+}
+
+// count: 路由到 key 的调用次数
+func (m *routedModel) count(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[key]
+}
+
+// criticRoutes: 按默认审查者的停止标记路由，每个审查者依次返回给定的输出
+func criticRoutes(correctness, style, security []string) map[string]*fakemodel.Model {
+	return map[string]*fakemodel.Model{
+		"CORRECTNESS_PERFECT": fakemodel.New(correctness...),
+		"STYLE_PERFECT":       fakemodel.New(style...),
+		"SECURITY_PERFECT":    fakemodel.New(security...),
+	}
+}
+
+func TestCriticPassed(t *testing.T) {
+	spec := CriticSpec{Key: "style", Sentinel: "STYLE_PERFECT"}
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"STYLE_PERFECT", true},
+		{"代码风格良好。STYLE_PERFECT", true},
+		{"[]", true},
+		{"```json\n[]\n```", true},
+		{"[]\nScore: 9", true},
+		{"- 缺少文档字符串", false},
+		{`[{"id": "S1"}]`, false},
+		{"CORRECTNESS_PERFECT", false},
+	}
+	for _, tt := range tests {
+		if got := criticPassed(spec, tt.output); got != tt.want {
+			t.Errorf("criticPassed(%q) = %v，want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestMergeReviews(t *testing.T) {
+	got := mergeReviews([]CriticReview{
+		{Label: "正确性", Output: "  - 没有处理负数\n", Passed: false},
+		{Label: "风格", Output: "STYLE_PERFECT", Passed: true},
+	})
+	want := "## 正确性\n- 没有处理负数\n\n## 风格\n未发现问题。"
+	if got != want {
+		t.Errorf("合并结果 = %q\nwant %q", got, want)
+	}
+}
+
+// TestMultiCriticUnanimousStop: 多审查者并行审查，意见按审查者分节，全体通过才停止
+func TestMultiCriticUnanimousStop(t *testing.T) {
+	llm := &routedModel{
+		fallback: fakemodel.New("v1", "v2"),
+		routes: criticRoutes(
+			[]string{"CORRECTNESS_PERFECT", "CORRECTNESS_PERFECT"},
+			[]string{"- 变量名不清楚", "STYLE_PERFECT"},
+			[]string{"[]", "SECURITY_PERFECT"},
+		),
+	}
+	runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{TaskPrompt: "任务", Critics: defaultCritics})
+	if err != nil {
+		t.Fatalf("NewReflectionRunner: %v", err)
+	}
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != StatusPassed || len(result.Iterations) != 2 || result.FinalCode != "v2" {
+		t.Fatalf("结果 = %+v，want 第 2 轮全体通过", result)
+	}
+
+	first := result.Iterations[0]
+	if len(first.Reviews) != len(defaultCritics) {
+		t.Fatalf("第 1 轮意见 = %+v", first.Reviews)
+	}
+	var passed []string
+	for i, review := range first.Reviews {
+		if review.Key != defaultCritics[i].Key {
+			t.Errorf("意见顺序 = %s，want 按审查者定义顺序", review.Key)
+		}
+		passed = append(passed, fmt.Sprintf("%s=%v", review.Key, review.Passed))
+	}
+	if got := strings.Join(passed, ","); got != "correctness=true,style=false,security=true" {
+		t.Errorf("第 1 轮审查结果 = %s", got)
+	}
+	if !strings.Contains(first.Critique, "## 风格与可读性\n- 变量名不清楚") || !strings.Contains(first.Critique, "## 安全与输入校验\n未发现问题。") {
+		t.Errorf("第 1 轮批评 = %q", first.Critique)
+	}
+
+	// 每个审查者每轮各调用一次；分节的批评交给生成者完善
+	for _, spec := range defaultCritics {
+		if got := llm.count(spec.Sentinel); got != 2 {
+			t.Errorf("%s 调用 %d 次，want 2", spec.Key, got)
+		}
+	}
+	generatorCalls := llm.fallback.Calls()
+	if last := generatorCalls[1][len(generatorCalls[1])-2].Content; !strings.Contains(last, "## 风格与可读性") {
+		t.Errorf("第 2 轮生成者收到的批评 = %q", last)
+	}
+}
+
+// TestMultiCriticLowestScore: 多审查者时评分取最低分
+func TestMultiCriticLowestScore(t *testing.T) {
+	llm := &routedModel{
+		fallback: fakemodel.New("v1"),
+		routes: criticRoutes(
+			[]string{"- 问题\nScore: 6"},
+			[]string{"STYLE_PERFECT\nScore: 9"},
+			[]string{"- 问题\nScore: 8"},
+		),
+	}
+	runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{
+		TaskPrompt: "任务", Critics: defaultCritics, ScoreThreshold: 9, MaxIterations: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewReflectionRunner: %v", err)
+	}
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := result.Iterations[0].Score; got != 6 {
+		t.Errorf("评分 = %v，want 最低分 6", got)
+	}
+	if result.Status != StatusMaxIterations {
+		t.Errorf("状态 = %s", result.Status)
+	}
+}
+
+// TestIterationUsage: 每轮记录生成和审查的 token 用量，并累计到结果
+func TestIterationUsage(t *testing.T) {
+	tokens := func(p, c int) *schema.TokenUsage {
+		return &schema.TokenUsage{PromptTokens: p, CompletionTokens: c, TotalTokens: p + c}
+	}
+	llm := fakemodel.New()
+	llm.PushResponse(fakemodel.Response{Content: "v1", Usage: tokens(10, 5)})
+	llm.PushResponse(fakemodel.Response{Content: "- 问题", Usage: tokens(20, 3)})
+	llm.PushResponse(fakemodel.Response{Content: "v2", Usage: tokens(30, 5)})
+	llm.PushResponse(fakemodel.Response{Content: "CODE_IS_PERFECT", Usage: tokens(40, 2)})
+
+	result, err := newTestRunner(t, llm, ReflectionConfig{TaskPrompt: "任务"}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(result.Iterations) != 2 {
+		t.Fatalf("迭代 %d 轮", len(result.Iterations))
+	}
+	if got := result.Iterations[0].Usage.Total(); got != 38 {
+		t.Errorf("第 1 轮用量 = %d，want 38", got)
+	}
+	if got := result.Iterations[1].Total.Total(); got != 115 {
+		t.Errorf("累计用量 = %d，want 115", got)
+	}
+	if result.Usage != result.Iterations[1].Total {
+		t.Errorf("结果用量 %v 与最后一轮累计 %v 不一致", result.Usage, result.Iterations[1].Total)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// diffContext: 统一 diff 中每处改动前后保留的上下文行数
+const diffContext = 3
+
+// ANSI 颜色
+const (
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+	ansiReset = "\x1b[0m"
+)
+
+// diffOp: 一行的变化类型
+type diffOp byte
+
+const (
+	opEqual  diffOp = ' '
+	opDelete diffOp = '-'
+	opInsert diffOp = '+'
+)
+
+// diffLine: diff 中的一行
+type diffLine struct {
+	Op   diffOp
+	Text string
+	A, B int // A, B: 该行在旧、新文本中的行号（从 1 开始，不存在时为 0）
+}
+
+// splitLines: 按行拆分，忽略末尾的换行
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// lineDiff: 基于最长公共子序列的逐行 diff（代码只有几十到几百行，O(n*m) 足够）
+func lineDiff(oldText, newText string) []diffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+	// lcs[i][j]: a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{Op: opEqual, Text: a[i], A: i + 1, B: j + 1})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			// 删除行排在新增行之前，与常见 diff 工具一致
+			lines = append(lines, diffLine{Op: opDelete, Text: a[i], A: i + 1})
+			i++
+		default:
+			lines = append(lines, diffLine{Op: opInsert, Text: b[j], B: j + 1})
+			j++
+		}
+	}
+	return lines
+}
+
+// unifiedDiff: 统一 diff 格式（@@ -a,n +b,m @@ 分块，每块前后保留 diffContext 行上下文），两版相同时返回空字符串
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	lines := lineDiff(oldText, newText)
+	changed := false
+	for _, line := range lines {
+		if line.Op != opEqual {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(lines); {
+		// 找下一处改动，连同上下文组成一个分块；相距不超过 2*diffContext 的改动合并到同一块
+		first := start
+		for first < len(lines) && lines[first].Op == opEqual {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		from := max(first-diffContext, start)
+		to := first
+		for k := first; k < len(lines); k++ {
+			if lines[k].Op != opEqual {
+				to = k
+			} else if k-to > 2*diffContext {
+				break
+			}
+		}
+		to = min(to+diffContext, len(lines)-1)
+		writeHunk(&sb, lines[from:to+1])
+		start = to + 1
+	}
+	return sb.String()
+}
+
+// writeHunk: 写出一个分块（含 @@ 头）
+func writeHunk(sb *strings.Builder, hunk []diffLine) {
+	aStart, bStart, aCount, bCount := 0, 0, 0, 0
+	for _, line := range hunk {
+		if line.Op != opInsert {
+			if aStart == 0 {
+				aStart = line.A
+			}
+			aCount++
+		}
+		if line.Op != opDelete {
+			if bStart == 0 {
+				bStart = line.B
+			}
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, line := range hunk {
+		fmt.Fprintf(sb, "%c%s\n", line.Op, line.Text)
+	}
+}
+
+// colorizeDiff: 给统一 diff 上色：删除行红色、新增行绿色、分块头青色
+func colorizeDiff(diff string) string {
+	var sb strings.Builder
+	for _, line := range splitLines(diff) {
+		color := ""
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "@@"):
+			color = ansiCyan
+		case strings.HasPrefix(line, "+"):
+			color = ansiGreen
+		case strings.HasPrefix(line, "-"):
+			color = ansiRed
+		}
+		if color != "" {
+			line = color + line + ansiReset
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// supportsColor: w 是终端且没有设置 NO_COLOR、TERM 不是 dumb 时输出颜色
+func supportsColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
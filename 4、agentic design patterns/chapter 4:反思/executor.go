@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultExecTimeout = 10 * time.Second // defaultExecTimeout: 执行生成代码的默认超时
+	defaultOutputLimit = 4096             // defaultOutputLimit: stdout / stderr 各自保留的最大字节数
+)
+
+// codeFence: Markdown 代码块（可带语言标记）
+var codeFence = regexp.MustCompile("(?s)```[a-zA-Z0-9_+-]*\\s*\\n(.*?)```")
+
+// cleanCodeBlock: 模型常把代码包在 ```python 代码块里，取出第一个代码块的内容；没有代码块时原样返回
+func cleanCodeBlock(text string) string {
+	if m := codeFence.FindStringSubmatch(text); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return strings.TrimSpace(text)
+}
+
+// ExecuteFunc: 执行一版代码并返回运行结果，供反思阶段参考
+type ExecuteFunc func(ctx context.Context, code string) ExecResult
+
+// ExecResult: 一次代码执行的结果
+type ExecResult struct {
+	Skipped    bool          // Skipped: 没有执行（如找不到解释器），原因见 SkipReason
+	SkipReason string        // SkipReason: 跳过执行的原因
+	ExitCode   int           // ExitCode: 进程退出码（超时被杀死时为 -1）
+	Stdout     string        // Stdout: 标准输出（超过上限时被截断）
+	Stderr     string        // Stderr: 标准错误（超过上限时被截断）
+	TimedOut   bool          // TimedOut: 超时被杀死
+	Truncated  bool          // Truncated: 输出超过上限被截断
+	Elapsed    time.Duration // Elapsed: 执行耗时
+}
+
+// SyntaxError: 代码没能通过解释器的语法检查
+func (r ExecResult) SyntaxError() bool {
+	return !r.Skipped && (strings.Contains(r.Stderr, "SyntaxError") || strings.Contains(r.Stderr, "IndentationError"))
+}
+
+// Report: 追加到审查提示词中的运行结果
+func (r ExecResult) Report() string {
+	if r.Skipped {
+		return "（未执行：" + r.SkipReason + "）"
+	}
+	var sb strings.Builder
+	switch {
+	case r.TimedOut:
+		fmt.Fprintf(&sb, "退出状态：超时（%s）被终止\n", r.Elapsed.Round(time.Millisecond))
+	default:
+		fmt.Fprintf(&sb, "退出状态：%d\n", r.ExitCode)
+	}
+	fmt.Fprintf(&sb, "stdout：\n%s\n", orNone(r.Stdout))
+	fmt.Fprintf(&sb, "stderr：\n%s\n", orNone(r.Stderr))
+	if r.Truncated {
+		sb.WriteString("（输出过长，已截断）\n")
+	}
+	if r.SyntaxError() {
+		sb.WriteString("⚠ 代码存在语法错误，根本无法运行：这是最严重的问题，必须在批评中首先指出，且不能认为代码已经完美。\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// orNone: 空输出显示为"（无）"，去掉末尾换行
+func orNone(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "（无）"
+	}
+	return strings.TrimRight(s, "\n")
+}
+
+// PythonExecutor: 用本机 python3 在临时目录中执行生成的代码（没有沙箱，只应执行可信任务的代码）
+type PythonExecutor struct {
+	Python      string        // Python: 解释器名称或路径，为空时取 "python3"
+	Timeout     time.Duration // Timeout: 超时，<=0 时取 defaultExecTimeout，超时后杀死进程
+	OutputLimit int           // OutputLimit: stdout / stderr 各自保留的最大字节数，<=0 时取 defaultOutputLimit
+}
+
+// Run: 把代码写入临时文件并执行，找不到解释器时返回 Skipped 结果
+func (e PythonExecutor) Run(ctx context.Context, code string) ExecResult {
+	python, timeout, limit := e.Python, e.Timeout, e.OutputLimit
+	if python == "" {
+		python = "python3"
+	}
+	if timeout <= 0 {
+		timeout = defaultExecTimeout
+	}
+	if limit <= 0 {
+		limit = defaultOutputLimit
+	}
+	path, err := exec.LookPath(python)
+	if err != nil {
+		return ExecResult{Skipped: true, SkipReason: fmt.Sprintf("未找到 %s", python)}
+	}
+
+	dir, err := os.MkdirTemp("", "reflection-exec-*")
+	if err != nil {
+		return ExecResult{Skipped: true, SkipReason: fmt.Sprintf("创建临时目录失败: %v", err)}
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "main.py")
+	if err := os.WriteFile(script, []byte(cleanCodeBlock(code)), 0o600); err != nil {
+		return ExecResult{Skipped: true, SkipReason: fmt.Sprintf("写入临时文件失败: %v", err)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	stdout, stderr := &cappedBuffer{limit: limit}, &cappedBuffer{limit: limit}
+	cmd := exec.CommandContext(ctx, path, script)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// 被杀死的脚本可能留下仍占用输出管道的子进程，WaitDelay 保证 Wait 按时返回
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+	result := ExecResult{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+		Elapsed:   time.Since(start),
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.TimedOut, result.ExitCode = true, -1
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return ExecResult{Skipped: true, SkipReason: fmt.Sprintf("启动 %s 失败: %v", python, err)}
+	}
+	return result
+}
+
+// cappedBuffer: 只保留前 limit 个字节的输出缓冲，超出部分丢弃并记录截断
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil // 报告全部写入，避免子进程因管道写失败而提前退出
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"ch4/fakemodel"
+)
+
+// requirePython: 本机没有 python3 时跳过
+func requirePython(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("未找到 python3")
+	}
+}
+
+func TestPythonExecutorRun(t *testing.T) {
+	requirePython(t)
+	tests := []struct {
+		name       string
+		code       string
+		wantExit   int
+		wantStdout string
+		wantStderr string
+		syntaxErr  bool
+	}{
+		{"正常退出", "print('hello')", 0, "hello\n", "", false},
+		{"代码块中的代码", "```python\nprint(1 + 1)\n```", 0, "2\n", "", false},
+		{"运行时异常", "raise ValueError('bad')", 1, "", "ValueError: bad", false},
+		{"语法错误", "def f(:\n  pass", 1, "", "SyntaxError", true},
+		{"指定退出码", "import sys\nsys.exit(3)", 3, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := PythonExecutor{}.Run(context.Background(), tt.code)
+			if res.Skipped || res.TimedOut {
+				t.Fatalf("执行结果 = %+v", res)
+			}
+			if res.ExitCode != tt.wantExit || res.Stdout != tt.wantStdout || !strings.Contains(res.Stderr, tt.wantStderr) {
+				t.Errorf("退出码 %d，stdout %q，stderr %q", res.ExitCode, res.Stdout, res.Stderr)
+			}
+			if res.SyntaxError() != tt.syntaxErr {
+				t.Errorf("SyntaxError() = %v，want %v", res.SyntaxError(), tt.syntaxErr)
+			}
+		})
+	}
+}
+
+func TestPythonExecutorTimeout(t *testing.T) {
+	requirePython(t)
+	start := time.Now()
+	res := PythonExecutor{Timeout: 200 * time.Millisecond}.Run(context.Background(), "import time\ntime.sleep(30)")
+	if !res.TimedOut || res.ExitCode != -1 {
+		t.Errorf("执行结果 = %+v，want 超时", res)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("超时后 %s 才返回", elapsed)
+	}
+}
+
+func TestPythonExecutorOutputLimit(t *testing.T) {
+	requirePython(t)
+	res := PythonExecutor{OutputLimit: 10}.Run(context.Background(), "print('x' * 100)")
+	if !res.Truncated || len(res.Stdout) != 10 || res.ExitCode != 0 {
+		t.Errorf("执行结果 = %+v，want 截断为 10 字节且正常退出", res)
+	}
+}
+
+func TestPythonExecutorMissingInterpreter(t *testing.T) {
+	res := PythonExecutor{Python: "no-such-python-interpreter"}.Run(context.Background(), "print(1)")
+	if !res.Skipped || !strings.Contains(res.SkipReason, "未找到 no-such-python-interpreter") {
+		t.Errorf("执行结果 = %+v，want 跳过", res)
+	}
+}
+
+func TestExecResultReport(t *testing.T) {
+	tests := []struct {
+		name string
+		res  ExecResult
+		want []string
+	}{
+		{"跳过", ExecResult{Skipped: true, SkipReason: "未找到 python3"}, []string{"（未执行：未找到 python3）"}},
+		{"正常", ExecResult{Stdout: "120\n"}, []string{"退出状态：0", "stdout：\n120\n", "stderr：\n（无）"}},
+		{"超时", ExecResult{TimedOut: true, ExitCode: -1, Elapsed: 1500 * time.Millisecond}, []string{"退出状态：超时（1.5s）被终止"}},
+		{"截断", ExecResult{Stdout: "x", Truncated: true}, []string{"（输出过长，已截断）"}},
+		{"语法错误", ExecResult{ExitCode: 1, Stderr: "SyntaxError: invalid syntax"}, []string{"退出状态：1", "⚠ 代码存在语法错误"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := tt.res.Report()
+			for _, want := range tt.want {
+				if !strings.Contains(report, want) {
+					t.Errorf("报告缺少 %q:\n%s", want, report)
+				}
+			}
+		})
+	}
+}
+
+// TestExecutionFeedsCritique: 执行结果附加到审查提示词；有语法错误时忽略停止标记继续迭代
+func TestExecutionFeedsCritique(t *testing.T) {
+	llm := fakemodel.New("def f(:", "CODE_IS_PERFECT", "def f(): pass", "CODE_IS_PERFECT")
+	runs := 0
+	runner := newTestRunner(t, llm, ReflectionConfig{
+		TaskPrompt: "任务",
+		Execute: func(ctx context.Context, code string) ExecResult {
+			runs++
+			if runs == 1 {
+				return ExecResult{ExitCode: 1, Stderr: "SyntaxError: invalid syntax"}
+			}
+			return ExecResult{Stdout: "ok"}
+		},
+	})
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != StatusPassed || len(result.Iterations) != 2 {
+		t.Fatalf("结果 = %+v，want 第 2 轮通过", result)
+	}
+	if it := result.Iterations[0]; it.Exec == nil || !it.Exec.SyntaxError() || it.Stopped {
+		t.Errorf("第 1 轮 = %+v，语法错误时不应停止", it)
+	}
+
+	calls := llm.Calls()
+	if critic := calls[1][len(calls[1])-1].Content; !strings.Contains(critic, "代码的实际运行结果：\n退出状态：1") {
+		t.Errorf("审查提示词缺少运行结果:\n%s", critic)
+	}
+	if critic := calls[3][len(calls[3])-1].Content; !strings.Contains(critic, "stdout：\nok") {
+		t.Errorf("第 2 轮审查提示词缺少运行结果:\n%s", critic)
+	}
+}
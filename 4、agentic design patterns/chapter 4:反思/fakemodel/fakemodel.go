@@ -0,0 +1,128 @@
+// Package fakemodel: 按脚本返回的假 ChatModel，用于不调用真实模型地验证 Agent 的循环逻辑
+//
+// Model 按顺序返回预先排好的响应（文本或错误），并记录每次调用收到的消息；
+// Generate 和 Stream 共用同一个队列，Stream 把文本按 ChunkSize 拆成多个分片，便于验证流式输出。
+// 包只依赖 eino，其他章节可以原样复制使用。
+package fakemodel
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ErrExhausted: 响应队列已空时返回的错误
+var ErrExhausted = errors.New("fakemodel: 没有更多预设的响应")
+
+// Response: 一次调用的预设结果
+type Response struct {
+	Content string             // Content: 返回的文本
+	Err     error              // Err: 不为空时本次调用返回该错误（不返回文本）
+	Usage   *schema.TokenUsage // Usage: 写入 ResponseMeta 的 token 用量，为空时不带用量
+}
+
+// Model: 按脚本返回的假 ChatModel，并发安全
+type Model struct {
+	ChunkSize int // ChunkSize: Stream 每个分片的字符数（按 rune 计），<=0 时整段作为一个分片
+
+	mu        sync.Mutex
+	responses []Response
+	calls     [][]*schema.Message
+}
+
+var _ model.BaseChatModel = (*Model)(nil)
+
+// New: 依次返回给定文本的假模型
+func New(contents ...string) *Model {
+	m := &Model{}
+	m.Push(contents...)
+	return m
+}
+
+// Push: 在队列末尾追加文本响应
+func (m *Model) Push(contents ...string) *Model {
+	for _, content := range contents {
+		m.PushResponse(Response{Content: content})
+	}
+	return m
+}
+
+// PushError: 在队列末尾追加一次失败的调用
+func (m *Model) PushError(err error) *Model {
+	return m.PushResponse(Response{Err: err})
+}
+
+// PushResponse: 在队列末尾追加任意预设结果
+func (m *Model) PushResponse(resp Response) *Model {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses = append(m.responses, resp)
+	return m
+}
+
+// Calls: 每次调用收到的消息（按调用顺序，消息切片是调用时的副本）
+func (m *Model) Calls() [][]*schema.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	calls := make([][]*schema.Message, len(m.calls))
+	copy(calls, m.calls)
+	return calls
+}
+
+// Remaining: 队列中尚未使用的响应个数
+func (m *Model) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.responses)
+}
+
+// next: 记录本次调用并取出队首的响应
+func (m *Model) next(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, append([]*schema.Message(nil), input...))
+	if len(m.responses) == 0 {
+		return nil, ErrExhausted
+	}
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	msg := schema.AssistantMessage(resp.Content, nil)
+	if resp.Usage != nil {
+		msg.ResponseMeta = &schema.ResponseMeta{Usage: resp.Usage}
+	}
+	return msg, nil
+}
+
+// Generate: 返回队首的响应
+func (m *Model) Generate(ctx context.Context, input []*schema.Message, _ ...model.Option) (*schema.Message, error) {
+	return m.next(ctx, input)
+}
+
+// Stream: 把队首的响应按 ChunkSize 拆成分片返回，用量放在最后一个分片上
+func (m *Model) Stream(ctx context.Context, input []*schema.Message, _ ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.next(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(msg.Content)
+	size := m.ChunkSize
+	if size <= 0 || size > len(runes) {
+		size = max(len(runes), 1)
+	}
+	var chunks []*schema.Message
+	for start := 0; start < len(runes) || len(chunks) == 0; start += size {
+		end := min(start+size, len(runes))
+		chunks = append(chunks, schema.AssistantMessage(string(runes[start:end]), nil))
+	}
+	chunks[len(chunks)-1].ResponseMeta = msg.ResponseMeta
+	return schema.StreamReaderFromArray(chunks), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// issueInstruction: 启用结构化问题列表时追加到审查者系统提示词末尾的要求（FString 模板，字面花括号写成 {{ }}）
+const issueInstruction = `
+
+输出格式要求（优先于上面的格式说明）：只输出一个 JSON 数组列出发现的问题，每个元素形如
+{{"id": "1", "severity": "high", "description": "问题描述", "line_hint": "第 3 行"}}。
+severity 取 critical、high、medium、low 之一；上一轮已报告且仍然存在的问题必须沿用原来的 id，新问题使用新的 id；
+没有任何问题时只输出 []。`
+
+// severities: 合法的严重程度，其他取值按 medium 处理
+var severities = map[string]bool{"critical": true, "high": true, "medium": true, "low": true}
+
+// Issue: 审查者报告的一个问题
+type Issue struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	LineHint    string `json:"line_hint,omitempty"`
+	Critic      string `json:"critic,omitempty"` // Critic: 报告该问题的审查者（多审查者模式），与 ID 一起唯一标识问题
+}
+
+// key: 跨轮次识别同一问题的键
+func (i Issue) key() string {
+	return i.Critic + "/" + i.ID
+}
+
+// String: 单行描述，如 "#2 [high] 未处理负数（第 3 行）"
+func (i Issue) String() string {
+	id := "#" + i.ID
+	if i.Critic != "" {
+		id = "#" + i.Critic + "/" + i.ID
+	}
+	s := fmt.Sprintf("%s [%s] %s", id, i.Severity, i.Description)
+	if i.LineHint != "" {
+		s += "（" + i.LineHint + "）"
+	}
+	return s
+}
+
+// parseIssues: 宽松地解析审查者输出的问题数组：可以包在代码块里，前后可以有说明文字，字段缺失时补默认值
+// 找不到 JSON 数组时返回 false（空数组 [] 是合法的"没有问题"）
+func parseIssues(text string) ([]Issue, bool) {
+	var candidates []string
+	for _, m := range codeFence.FindAllStringSubmatch(text, -1) {
+		candidates = append(candidates, m[1])
+	}
+	candidates = append(candidates, text)
+	for _, candidate := range candidates {
+		if items, ok := firstJSONArray(candidate); ok {
+			return toIssues(items), true
+		}
+	}
+	return nil, false
+}
+
+// firstJSONArray: 从第一个能解码为对象数组的 '[' 开始解码，忽略其后的文字
+func firstJSONArray(text string) ([]map[string]any, bool) {
+	for i := strings.IndexByte(text, '['); i >= 0; {
+		var items []map[string]any
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&items); err == nil {
+			return items, true
+		}
+		next := strings.IndexByte(text[i+1:], '[')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return nil, false
+}
+
+// toIssues: 把解码后的对象转换为问题，容忍字段名和类型的常见变体，没有描述的条目被丢弃
+func toIssues(items []map[string]any) []Issue {
+	issues := make([]Issue, 0, len(items))
+	for n, item := range items {
+		issue := Issue{
+			ID:          field(item, "id", "issue_id", "number"),
+			Severity:    strings.ToLower(field(item, "severity", "level", "priority")),
+			Description: field(item, "description", "desc", "issue", "message", "problem"),
+			LineHint:    field(item, "line_hint", "line", "lines", "location"),
+		}
+		if issue.Description == "" {
+			continue
+		}
+		if issue.ID == "" {
+			issue.ID = strconv.Itoa(n + 1)
+		}
+		if !severities[issue.Severity] {
+			issue.Severity = "medium"
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// field: 取第一个存在的字段，数字转为字符串（整数不带小数点）
+func field(item map[string]any, names ...string) string {
+	for _, name := range names {
+		switch v := item[name].(type) {
+		case string:
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// formatIssues: 项目符号形式的问题列表
+func formatIssues(issues []Issue) string {
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = "- " + issue.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// IssueRecord: 一个问题的生命周期
+type IssueRecord struct {
+	Issue
+	OpenedIn   int `json:"opened_in"`             // OpenedIn: 首次报告的轮次
+	ResolvedIn int `json:"resolved_in,omitempty"` // ResolvedIn: 审查者不再报告它的轮次，0 表示仍未解决
+}
+
+// issueTracker: 跨轮次跟踪问题：新报告的问题打开，下一轮不再报告的问题标记为已解决
+type issueTracker struct {
+	records []*IssueRecord          // records: 按首次报告顺序排列
+	open    map[string]*IssueRecord // open: 未解决的问题，按 Issue.key 索引
+}
+
+func newIssueTracker() *issueTracker {
+	return &issueTracker{open: map[string]*IssueRecord{}}
+}
+
+// update: 用第 round 轮的问题列表更新状态，返回新增和已解决的问题
+func (t *issueTracker) update(round int, issues []Issue) (opened, resolved []Issue) {
+	reported := make(map[string]bool, len(issues))
+	for _, issue := range issues {
+		key := issue.key()
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+		if record, ok := t.open[key]; ok {
+			record.Issue = issue // 沿用 id，描述以最新一轮为准
+			continue
+		}
+		record := &IssueRecord{Issue: issue, OpenedIn: round}
+		t.records = append(t.records, record)
+		t.open[key] = record
+		opened = append(opened, issue)
+	}
+	for _, record := range t.records {
+		if record.ResolvedIn == 0 && !reported[record.key()] && t.open[record.key()] == record {
+			record.ResolvedIn = round
+			delete(t.open, record.key())
+			resolved = append(resolved, record.Issue)
+		}
+	}
+	return opened, resolved
+}
+
+// openIssues: 未解决的问题（按首次报告顺序）
+func (t *issueTracker) openIssues() []Issue {
+	var issues []Issue
+	for _, record := range t.records {
+		if record.ResolvedIn == 0 {
+			issues = append(issues, record.Issue)
+		}
+	}
+	return issues
+}
+
+// lifecycle: 所有问题的生命周期
+func (t *issueTracker) lifecycle() []IssueRecord {
+	records := make([]IssueRecord, len(t.records))
+	for i, record := range t.records {
+		records[i] = *record
+	}
+	return records
+}
+
+// formatLifecycle: 最终报告中的问题生命周期
+func formatLifecycle(records []IssueRecord) string {
+	var sb strings.Builder
+	for _, record := range records {
+		status := "仍未解决"
+		if record.ResolvedIn > 0 {
+			status = fmt.Sprintf("第 %d 轮解决", record.ResolvedIn)
+		}
+		fmt.Fprintf(&sb, "  %s：第 %d 轮提出，%s\n", record.Issue, record.OpenedIn, status)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIssues(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Issue
+	}{
+		{
+			name: "代码块中的数组",
+			text: "发现以下问题：\n```json\n[{\"id\": \"1\", \"severity\": \"high\", \"description\": \"未处理负数\", \"line_hint\": \"第 3 行\"}]\n```\n请修改。",
+			want: []Issue{{ID: "1", Severity: "high", Description: "未处理负数", LineHint: "第 3 行"}},
+		},
+		{
+			name: "说明文字中有多余的方括号",
+			text: "参考 [PEP 8] 和 [1, 2] 的写法，问题如下：[{\"id\": \"2\", \"severity\": \"low\", \"description\": \"命名不规范\"}] 以上。",
+			want: []Issue{{ID: "2", Severity: "low", Description: "命名不规范"}},
+		},
+		{
+			name: "字段名的变体",
+			text: `[{"number": 3, "level": "CRITICAL", "desc": "除零", "line": "第 7 行"}, {"issue_id": "a", "priority": "Low", "message": "缺少注释"}]`,
+			want: []Issue{
+				{ID: "3", Severity: "critical", Description: "除零", LineHint: "第 7 行"},
+				{ID: "a", Severity: "low", Description: "缺少注释"},
+			},
+		},
+		{
+			name: "没有描述的条目被丢弃，缺少 id 时按位置编号",
+			text: `[{"id": "1", "severity": "high"}, {"severity": "high", "problem": "  死循环  "}, {"description": "   "}]`,
+			want: []Issue{{ID: "2", Severity: "high", Description: "死循环"}},
+		},
+		{
+			name: "未知的严重程度按 medium 处理",
+			text: `[{"id": 1.5, "severity": "blocker", "description": "内存泄漏"}, {"id": "2", "description": "缺少测试"}]`,
+			want: []Issue{
+				{ID: "1.5", Severity: "medium", Description: "内存泄漏"},
+				{ID: "2", Severity: "medium", Description: "缺少测试"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseIssues(tt.text)
+			if !ok || len(got) != len(tt.want) {
+				t.Fatalf("parseIssues = %+v, %v，want %+v", got, ok, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("第 %d 个问题 = %+v，want %+v", i+1, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseIssuesEmpty(t *testing.T) {
+	// 空数组表示没有问题，与找不到数组不同
+	for _, text := range []string{"[]", "代码没有问题。\n```json\n[]\n```"} {
+		if issues, ok := parseIssues(text); !ok || len(issues) != 0 {
+			t.Errorf("parseIssues(%q) = %+v, %v，应为没有问题", text, issues, ok)
+		}
+	}
+	for _, text := range []string{"", "代码写得很好，没有发现问题。", "见 [1] 和 [2]", `["未处理负数"]`} {
+		if issues, ok := parseIssues(text); ok {
+			t.Errorf("parseIssues(%q) 应找不到问题数组，得到 %+v", text, issues)
+		}
+	}
+}
+
+func TestIssueTracker(t *testing.T) {
+	tracker := newIssueTracker()
+	negative := Issue{ID: "1", Severity: "high", Description: "未处理负数"}
+	naming := Issue{ID: "2", Severity: "low", Description: "命名不规范"}
+
+	opened, resolved := tracker.update(1, []Issue{negative, naming, negative})
+	if len(opened) != 2 || len(resolved) != 0 {
+		t.Fatalf("第 1 轮：新增 %+v，解决 %+v", opened, resolved)
+	}
+
+	// 第 2 轮：#1 仍然存在（描述更新），#2 已解决，#3 是新问题
+	negative.Description = "未处理负数和零"
+	zero := Issue{ID: "3", Severity: "critical", Description: "除零"}
+	opened, resolved = tracker.update(2, []Issue{negative, zero})
+	if len(opened) != 1 || opened[0].ID != "3" || len(resolved) != 1 || resolved[0].ID != "2" {
+		t.Fatalf("第 2 轮：新增 %+v，解决 %+v", opened, resolved)
+	}
+	if open := tracker.openIssues(); len(open) != 2 || open[0].Description != "未处理负数和零" || open[1].ID != "3" {
+		t.Errorf("未解决的问题 = %+v", open)
+	}
+
+	// 第 3 轮：其他审查者的同 id 问题是不同的问题；已解决的 #2 再次出现时重新打开
+	style := Issue{ID: "1", Severity: "low", Description: "缩进不一致", Critic: "style"}
+	opened, resolved = tracker.update(3, []Issue{negative, style, naming})
+	if len(opened) != 2 || len(resolved) != 1 || resolved[0].ID != "3" {
+		t.Fatalf("第 3 轮：新增 %+v，解决 %+v", opened, resolved)
+	}
+
+	records := tracker.lifecycle()
+	var got []string
+	for _, r := range records {
+		got = append(got, r.key())
+	}
+	if strings.Join(got, ",") != "/1,/2,/3,style/1,/2" {
+		t.Errorf("问题记录 = %s", strings.Join(got, ","))
+	}
+	if records[0].OpenedIn != 1 || records[0].ResolvedIn != 0 || records[1].ResolvedIn != 2 || records[2].OpenedIn != 2 || records[2].ResolvedIn != 3 || records[4].OpenedIn != 3 {
+		t.Errorf("生命周期 = %+v", records)
+	}
+	want := "  #1 [high] 未处理负数和零：第 1 轮提出，仍未解决\n  #2 [low] 命名不规范：第 1 轮提出，第 2 轮解决"
+	if got := formatLifecycle(records[:2]); got != want {
+		t.Errorf("formatLifecycle =\n%s", got)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Limiter: 模型调用的共享限流器，获取成功后调用 release 归还（与第 3 章 ratelimit.Limiter 的 Acquire 签名一致）
+type Limiter interface {
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+// concurrencyLimiter: 最简单的限流器：最多 n 个模型调用同时进行
+type concurrencyLimiter chan struct{}
+
+// newConcurrencyLimiter: 创建最多 n 个并发调用的限流器，n<=0 时返回 nil（不限流）
+func newConcurrencyLimiter(n int) Limiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(concurrencyLimiter, n)
+}
+
+// Acquire: 等待空闲名额，ctx 取消时返回错误
+func (l concurrencyLimiter) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case l <- struct{}{}:
+		return func() { <-l }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// limitedModel: 包装 ChatModel，每次调用前先从限流器获取名额，所有角色和候选共享同一个限流器
+type limitedModel struct {
+	inner   model.BaseChatModel
+	limiter Limiter
+}
+
+// Generate: 获取名额后调用内部模型
+func (m *limitedModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	release, err := m.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return m.inner.Generate(ctx, input, opts...)
+}
+
+// Stream: 获取名额后调用内部模型的流式接口，流读完或出错时才归还名额
+func (m *limitedModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	release, err := m.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sr, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	out, w := schema.Pipe[*schema.Message](0)
+	go func() {
+		defer release()
+		defer w.Close()
+		defer sr.Close()
+		for {
+			msg, err := sr.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if closed := w.Send(msg, err); closed || err != nil {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
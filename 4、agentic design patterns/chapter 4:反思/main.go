@@ -7,6 +7,9 @@
 	对比反思	   多方案选择	 全面评估		             计算成本高			     方案选型、设计决策
 	协作反思	   团队场景	   集思广益		             协调复杂			        代码审查、团队讨论
 
+	反思循环由 ReflectionRunner 实现，任务类型由 TaskProfile 描述；main 只是一个示例：按 -task-type 生成代码或文本并反复审查完善，
+	可叠加代码执行、多审查者、评分、结构化问题列表、候选生成、重试与检查点；命令行参数见 go run . -h。
+
 	此代码根据 MIT 许可证授权。
 	请参阅仓库中的 LICENSE 文件以获取完整许可文本。
 */
@@ -15,14 +18,17 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"shared/retry"
 
 	"github.com/cloudwego/eino-ext/components/model/openai"
-	"github.com/cloudwego/eino/components/prompt"
-	"github.com/cloudwego/eino/compose"
-	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/eino/components/model"
 )
 
 // float32Ptr: 辅助函数，将 float32 值转换为 *float32 指针
@@ -30,143 +36,49 @@ func float32Ptr(f float32) *float32 {
 	return &f
 }
 
-// ReflectionState: 反思循环的状态
-type ReflectionState struct {
-	CurrentCode    string
-	MessageHistory []*schema.Message
-	Iteration      int
-}
-
-func runReflectionLoop(ctx context.Context, llm *openai.ChatModel) error {
-	// --- 核心任务 ---
-	taskPrompt := `
-你的任务是创建一个名为 calculate_factorial 的 Python 函数。
-
-此函数应执行以下操作：
-1. 接受单个整数 n 作为输入。
-2. 计算其阶乘 (n!)。
-3. 包含清楚解释函数功能的文档字符串。
-4. 处理边缘情况：0 的阶乘是 1。
-5. 处理无效输入：如果输入是负数，则引发 ValueError。
-`
-
-	// --- 构建生成链 ---
-	// Lambda 函数：从 Message 中提取 Content
-	extractContent := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
-		return msg.Content, nil
-	})
+func main() {
+	// taskType: 任务类型决定任务、审查者提示词、输出后处理和停止标记
+	taskType := flag.String("task-type", pythonCodeProfile.Name(), "任务类型: "+strings.Join(profileNames(), ", "))
+	// execute: 在生成和反思之间用本机 python3 运行生成的代码，运行结果交给审查者
+	execute := flag.Bool("execute", false, "在反思前用 python3 运行生成的代码（无沙箱，只用于可信任务）")
+	execTimeout := flag.Duration("exec-timeout", defaultExecTimeout, "运行生成代码的超时，超时后终止进程")
+	// multiCritic: 每轮由正确性、风格、安全三个审查者并行审查，全部通过才停止
+	multiCritic := flag.Bool("multi-critic", false, "每轮并行运行正确性、风格、安全三个审查者（token 用量约为单审查者的三倍）")
+	// scoreThreshold / minImprovement: 审查者给出 1-10 的评分，达到阈值或评分停滞时停止
+	scoreThreshold := flag.Float64("score-threshold", 9, "评分达到该值即停止（<=0 关闭评分）")
+	minImprovement := flag.Float64("min-improvement", defaultMinImprovement, "评分两轮内提升不足该值时停止")
+	// issues: 审查者以 JSON 数组列出问题，跨轮次跟踪每个问题是否解决
+	issues := flag.Bool("issues", false, "要求审查者输出结构化问题列表并跟踪问题的生命周期")
+	// candidates / maxConcurrency: 首轮并发生成多个候选并择优，所有模型调用共享并发上限
+	candidates := flag.Int("candidates", 1, "首轮并发生成的候选个数，>1 时由排序链打分并选出最好的一个")
+	maxConcurrency := flag.Int("max-concurrency", 4, "同时进行的模型调用上限（<=0 不限制）")
+	// maxAttempts / deadline / resume: 模型调用的瞬时错误（429、5xx）按指数退避重试，用尽后保存检查点
+	maxAttempts := flag.Int("max-attempts", retry.DefaultMaxAttempts, "生成和反思阶段调用模型的最多尝试次数（含第一次）")
+	deadline := flag.Duration("deadline", 0, "整个运行（包括重试等待）的截止时间（<=0 不限制）")
+	resume := flag.String("resume", "", "从检查点文件继续上次失败的运行")
+	// quiet: 关闭生成过程的实时预览（CI 等非交互环境）
+	quiet := flag.Bool("quiet", false, "不实时显示生成过程，生成完成后再打印代码")
+	// tokenBudget / historyWindow: 控制越来越长的消息历史带来的成本
+	tokenBudget := flag.Int("token-budget", 0, "累计 token 用量达到该值即停止（<=0 不限制）")
+	historyWindow := flag.Int("history-window", 0, "消息历史超过该条数时只保留任务和最近的代码与批评（<=0 不裁剪，最小 4）")
+	// outDir / keepLast: 每次运行的产物写入 <out-dir>/reflection-<时间戳>/，只保留最近 keep-last 次
+	outDir := flag.String("out-dir", defaultArtifactRoot, "每轮代码、批评、diff 和 run.json 的输出根目录（为空时不保存）")
+	keepLast := flag.Int("keep-last", 0, "只保留最近 N 次运行的产物目录（<=0 不清理）")
+	flag.Parse()
 
-	// 生成链：直接使用消息历史调用 LLM
-	generateChain, err := compose.NewChain[[]*schema.Message, string]().
-		AppendChatModel(llm).
-		AppendLambda(extractContent).
-		Compile(ctx)
-	if err != nil {
-		return fmt.Errorf("编译生成链失败: %w", err)
-	}
-
-	// --- 构建反思链 ---
-	reflectorPrompt := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage(`你是一名高级软件工程师和 Python 专家。
-你的角色是执行细致的代码审查。
-根据原始任务要求批判性地评估提供的 Python 代码。
-查找错误、风格问题、缺失的边缘情况和改进领域。
-如果代码完美并满足所有要求，用单一短语 'CODE_IS_PERFECT' 响应。
-否则，提供批评的项目符号列表。`),
-		schema.UserMessage("原始任务：\n{task_prompt}\n\n要审查的代码：\n{current_code}"),
-	)
-
-	// Lambda 函数：准备反思输入
-	prepareReflection := compose.InvokableLambda(func(ctx context.Context, state ReflectionState) (map[string]any, error) {
-		return map[string]any{
-			"task_prompt":  taskPrompt,
-			"current_code": state.CurrentCode,
-		}, nil
-	})
+	ctx := context.Background()
 
-	// 反思链：Lambda -> Template -> ChatModel -> Lambda
-	reflectionChain, err := compose.NewChain[ReflectionState, string]().
-		AppendLambda(prepareReflection).
-		AppendChatTemplate(reflectorPrompt).
-		AppendChatModel(llm).
-		AppendLambda(extractContent).
-		Compile(ctx)
+	profile, err := profileByName(*taskType)
 	if err != nil {
-		return fmt.Errorf("编译反思链失败: %w", err)
-	}
-
-	// --- 反思循环 ---
-	maxIterations := 3
-	state := ReflectionState{
-		CurrentCode:    "",
-		MessageHistory: []*schema.Message{schema.UserMessage(taskPrompt)},
-		Iteration:      0,
+		fmt.Printf("参数错误: %v\n", err)
+		os.Exit(1)
 	}
-
-	for i := 0; i < maxIterations; i++ {
-		state.Iteration = i + 1
-		fmt.Printf("\n%s 反思循环：迭代 %d %s\n", strings.Repeat("=", 25), state.Iteration, strings.Repeat("=", 25))
-
-		// --- 1. 生成/完善阶段 ---
-		if i == 0 {
-			fmt.Println("\n>>> 阶段 1：生成初始代码...")
-			// 第一次迭代：直接使用消息历史生成代码
-			response, err := generateChain.Invoke(ctx, state.MessageHistory)
-			if err != nil {
-				return fmt.Errorf("生成代码失败: %w", err)
-			}
-			state.CurrentCode = response
-		} else {
-			fmt.Println("\n>>> 阶段 1：基于先前批评完善代码...")
-			// 后续迭代：添加完善指令
-			improveMessage := schema.UserMessage("请使用提供的批评完善代码。")
-			improveHistory := append(state.MessageHistory, improveMessage)
-			response, err := generateChain.Invoke(ctx, improveHistory)
-			if err != nil {
-				return fmt.Errorf("完善代码失败: %w", err)
-			}
-			state.CurrentCode = response
-		}
-
-		fmt.Printf("\n--- 生成的代码 (v%d) ---\n%s\n", state.Iteration, state.CurrentCode)
-
-		// 将生成的代码添加到历史记录
-		state.MessageHistory = append(state.MessageHistory, &schema.Message{
-			Role:    schema.Assistant,
-			Content: state.CurrentCode,
-		})
-
-		// --- 2. 反思阶段 ---
-		fmt.Println("\n>>> 阶段 2：对生成的代码进行反思...")
-		critique, err := reflectionChain.Invoke(ctx, state)
-		if err != nil {
-			return fmt.Errorf("反思失败: %w", err)
-		}
-
-		// --- 3. 停止条件 ---
-		if strings.Contains(critique, "CODE_IS_PERFECT") {
-			fmt.Println("\n--- 批评 ---\n未发现进一步批评。代码令人满意。")
-			break
-		}
-
-		fmt.Printf("\n--- 批评 ---\n%s\n", critique)
-
-		// 将批评添加到历史记录以用于下一个完善循环
-		critiqueMessage := schema.UserMessage(fmt.Sprintf("对先前代码的批评：\n%s", critique))
-		state.MessageHistory = append(state.MessageHistory, critiqueMessage)
+	// 执行阶段和默认的多审查者都是针对 Python 代码的
+	if profile.Name() != pythonCodeProfile.Name() && (*execute || *multiCritic) {
+		fmt.Printf("参数错误: -execute 和 -multi-critic 只支持 %s 任务类型\n", pythonCodeProfile.Name())
+		os.Exit(1)
 	}
 
-	fmt.Printf("\n%s 最终结果 %s\n", strings.Repeat("=", 30), strings.Repeat("=", 30))
-	fmt.Println("\n反思过程后的最终精炼代码：\n")
-	fmt.Println(state.CurrentCode)
-
-	return nil
-}
-
-func main() {
-	ctx := context.Background()
-
 	// --- 配置 ---
 	// 从环境变量读取 API 密钥
 	apiKey := os.Getenv("OPENAI_API_KEY")
@@ -178,30 +90,121 @@ func main() {
 	// 从环境变量读取自定义 BaseURL（可选）
 	baseURL := os.Getenv("OPENAI_BASE_URL")
 
-	// 创建 OpenAI ChatModel 配置
-	config := &openai.ChatModelConfig{
-		Model:       "deepseek-ai/DeepSeek-V3.1", // 需要支持工具调用的模型
-		APIKey:      apiKey,
-		Temperature: float32Ptr(0.1), // 使用较低的温度以获得更确定性的输出
+	// 生成者和审查者可以使用不同的模型（GENERATOR_MODEL / CRITIC_MODEL），同名时共用一个实例
+	generatorName, criticName := roleModelNames(os.Getenv)
+	pool := newModelPool(func(ctx context.Context, name string) (model.BaseChatModel, error) {
+		// 创建 OpenAI ChatModel 配置
+		config := &openai.ChatModelConfig{
+			Model:       name,
+			APIKey:      apiKey,
+			Temperature: float32Ptr(0.1), // 使用较低的温度以获得更确定性的输出
+		}
+		// 如果设置了自定义 BaseURL，则使用它（支持代理或兼容 API）
+		if baseURL != "" {
+			config.BaseURL = baseURL
+		}
+		return openai.NewChatModel(ctx, config)
+	})
+	generatorLLM, criticLLM, err := roleModels(ctx, pool, generatorName, criticName)
+	if err != nil {
+		fmt.Printf("初始化语言模型时出错: %v\n", err)
+		os.Exit(1)
 	}
 
-	// 如果设置了自定义 BaseURL，则使用它（支持代理或兼容 API）
-	if baseURL != "" {
-		config.BaseURL = baseURL
+	fmt.Printf("语言模型已初始化: 生成者 %s，审查者 %s（%d 个实例）\n", generatorName, criticName, pool.size())
+
+	// 构建并运行反思循环
+	reflectionConfig := ReflectionConfig{
+		Profile:            profile,
+		MaxIterations:      defaultMaxIterations,
+		ScoreThreshold:     *scoreThreshold,
+		MinImprovement:     *minImprovement,
+		TokenBudget:        *tokenBudget,
+		HistoryWindow:      *historyWindow,
+		CriticModel:        criticLLM,
+		StructuredIssues:   *issues,
+		Candidates:         *candidates,
+		Limiter:            newConcurrencyLimiter(*maxConcurrency),
+		Deadline:           *deadline,
+		StreamPreview:      !*quiet,
+		GeneratorModelName: generatorName,
+		CriticModelName:    criticName,
+		Log:                os.Stdout,
 	}
-
-	// 初始化 LLM
-	llm, err := openai.NewChatModel(ctx, config)
+	reflectionConfig.Retry = retry.DefaultPolicy()
+	reflectionConfig.Retry.MaxAttempts = *maxAttempts
+	if *multiCritic {
+		reflectionConfig.Critics = defaultCritics
+	}
+	if *execute {
+		reflectionConfig.Execute = PythonExecutor{Timeout: *execTimeout}.Run
+	}
+	started := time.Now()
+	// 产物目录在循环开始前创建；创建或写入失败只打印警告，不影响反思循环
+	if *outDir != "" {
+		artifacts, err := NewArtifactWriter(*outDir, started)
+		if err != nil {
+			fmt.Printf("⚠ 无法保存运行产物: %v\n", err)
+		} else {
+			reflectionConfig.Artifacts = artifacts
+			fmt.Printf("运行产物目录: %s\n", artifacts.Dir)
+			removed, err := pruneRuns(*outDir, *keepLast)
+			if err != nil {
+				fmt.Printf("⚠ 清理旧的运行产物失败: %v\n", err)
+			}
+			if len(removed) > 0 {
+				fmt.Printf("已删除 %d 个旧的运行产物目录（-keep-last %d）\n", len(removed), *keepLast)
+			}
+		}
+	}
+	// 检查点与本次运行的产物放在一起，不保存产物时写到当前目录
+	reflectionConfig.CheckpointPath = "reflection-checkpoint.json"
+	if reflectionConfig.Artifacts != nil {
+		reflectionConfig.CheckpointPath = filepath.Join(reflectionConfig.Artifacts.Dir, "checkpoint.json")
+	}
+	runner, err := NewReflectionRunner(ctx, generatorLLM, reflectionConfig)
 	if err != nil {
-		fmt.Printf("初始化语言模型时出错: %v\n", err)
+		fmt.Printf("构建反思循环失败: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Printf("语言模型已初始化: %s\n", config.Model)
-
-	// 运行反思循环
-	if err := runReflectionLoop(ctx, llm); err != nil {
-		fmt.Printf("反思循环执行失败: %v\n", err)
+	var result ReflectionResult
+	var runErr error
+	if *resume != "" {
+		checkpoint, err := LoadCheckpoint(*resume)
+		if err != nil {
+			fmt.Printf("参数错误: %v\n", err)
+			os.Exit(1)
+		}
+		result, runErr = runner.Resume(ctx, checkpoint)
+	} else {
+		result, runErr = runner.Run(ctx)
+	}
+	if artifacts := reflectionConfig.Artifacts; artifacts != nil {
+		if err := artifacts.WriteRun(newRunRecord(runner.Config(), result, started, runErr)); err != nil {
+			fmt.Printf("⚠ %v\n", err)
+		}
+	}
+	if runErr != nil {
+		fmt.Printf("反思循环执行失败: %v\n", runErr)
 		os.Exit(1)
 	}
+
+	fmt.Printf("\n%s 最终结果 %s\n", strings.Repeat("=", 30), strings.Repeat("=", 30))
+	fmt.Printf("\n反思过程后的最终结果（%s，共 %d 轮，%s）：\n\n", profile.Name(), len(result.Iterations), result.Status)
+	fmt.Println(result.FinalCode)
+	if result.Outstanding != "" {
+		fmt.Printf("\n未被采纳的批评（代码已连续 %d 轮没有实质变化）：\n%s\n", convergedRounds, result.Outstanding)
+	}
+	if len(result.Scores) > 0 {
+		fmt.Printf("\n评分轨迹：%s\n", formatScores(result.Scores))
+	}
+	if len(result.Issues) > 0 {
+		fmt.Printf("\n问题生命周期：\n%s\n", formatLifecycle(result.Issues))
+	}
+	if retries := countRetries(result.Iterations); retries > 0 {
+		fmt.Printf("\n重试：共 %d 次（各轮尝试次数见 run.json）\n", retries)
+	}
+	fmt.Printf("\ntoken 用量：%s\n", result.Usage)
+	fmt.Printf("  生成者（%s）：%s\n", generatorName, result.Roles.Generator)
+	fmt.Printf("  审查者（%s）：%s\n", criticName, result.Roles.Critic)
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+)
+
+// defaultModel: 生成者和审查者的默认模型
+const defaultModel = "deepseek-ai/DeepSeek-V3.1"
+
+// modelFactory: 按模型名称创建 ChatModel
+type modelFactory func(ctx context.Context, name string) (model.BaseChatModel, error)
+
+// modelPool: 按名称懒加载模型：第一次用到某个名称时才创建，同名的角色共享同一个实例
+type modelPool struct {
+	mu      sync.Mutex
+	factory modelFactory
+	models  map[string]model.BaseChatModel
+}
+
+func newModelPool(factory modelFactory) *modelPool {
+	return &modelPool{factory: factory, models: map[string]model.BaseChatModel{}}
+}
+
+// get: 返回名称对应的模型，尚未创建时调用 factory 创建并缓存
+func (p *modelPool) get(ctx context.Context, name string) (model.BaseChatModel, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if llm, ok := p.models[name]; ok {
+		return llm, nil
+	}
+	llm, err := p.factory(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("初始化模型 %s 失败: %w", name, err)
+	}
+	p.models[name] = llm
+	return llm, nil
+}
+
+// size: 已创建的模型实例数
+func (p *modelPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.models)
+}
+
+// roleModelNames: 从环境变量 GENERATOR_MODEL / CRITIC_MODEL 读取两个角色的模型，未设置时取 defaultModel
+func roleModelNames(getenv func(string) string) (generator, critic string) {
+	generator, critic = strings.TrimSpace(getenv("GENERATOR_MODEL")), strings.TrimSpace(getenv("CRITIC_MODEL"))
+	if generator == "" {
+		generator = defaultModel
+	}
+	if critic == "" {
+		critic = defaultModel
+	}
+	return generator, critic
+}
+
+// roleModels: 两个角色的模型实例（名称相同时是同一个实例）
+func roleModels(ctx context.Context, pool *modelPool, generatorName, criticName string) (generator, critic model.BaseChatModel, err error) {
+	if generator, err = pool.get(ctx, generatorName); err != nil {
+		return nil, nil, err
+	}
+	if critic, err = pool.get(ctx, criticName); err != nil {
+		return nil, nil, err
+	}
+	return generator, critic, nil
+}
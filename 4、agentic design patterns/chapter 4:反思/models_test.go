@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ch4/fakemodel"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestRoleModelNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		wantGenerator string
+		wantCritic    string
+	}{
+		{"都未设置", nil, defaultModel, defaultModel},
+		{"只设置审查者", map[string]string{"CRITIC_MODEL": " critic "}, defaultModel, "critic"},
+		{"分别设置", map[string]string{"GENERATOR_MODEL": "gen", "CRITIC_MODEL": "critic"}, "gen", "critic"},
+		{"空白视为未设置", map[string]string{"GENERATOR_MODEL": "  "}, defaultModel, defaultModel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, critic := roleModelNames(func(key string) string { return tt.env[key] })
+			if generator != tt.wantGenerator || critic != tt.wantCritic {
+				t.Errorf("模型 = %q / %q，want %q / %q", generator, critic, tt.wantGenerator, tt.wantCritic)
+			}
+		})
+	}
+}
+
+func TestModelPool(t *testing.T) {
+	var created []string
+	pool := newModelPool(func(ctx context.Context, name string) (model.BaseChatModel, error) {
+		created = append(created, name)
+		if name == "broken" {
+			return nil, errors.New("缺少 API Key")
+		}
+		return fakemodel.New(), nil
+	})
+	ctx := context.Background()
+
+	// 同名的角色共享同一个实例
+	generator, critic, err := roleModels(ctx, pool, "a", "a")
+	if err != nil {
+		t.Fatalf("roleModels: %v", err)
+	}
+	if generator != critic || pool.size() != 1 {
+		t.Errorf("同名模型应共用一个实例（已创建 %d 个）", pool.size())
+	}
+	generator, critic, err = roleModels(ctx, pool, "a", "b")
+	if err != nil {
+		t.Fatalf("roleModels: %v", err)
+	}
+	if generator == critic || pool.size() != 2 {
+		t.Errorf("不同名称应是不同实例（已创建 %d 个）", pool.size())
+	}
+	if strings.Join(created, ",") != "a,b" {
+		t.Errorf("创建顺序 = %v，每个名称只应创建一次", created)
+	}
+
+	if _, _, err := roleModels(ctx, pool, "a", "broken"); err == nil || !strings.Contains(err.Error(), "初始化模型 broken 失败") {
+		t.Errorf("错误 = %v", err)
+	}
+	if pool.size() != 2 {
+		t.Errorf("创建失败的模型不应缓存")
+	}
+}
+
+// TestSeparateCriticModel: 审查者使用单独的模型，token 用量按角色分别统计
+func TestSeparateCriticModel(t *testing.T) {
+	tokens := func(total int) *schema.TokenUsage {
+		return &schema.TokenUsage{PromptTokens: total, TotalTokens: total}
+	}
+	generator := fakemodel.New()
+	generator.PushResponse(fakemodel.Response{Content: "v1", Usage: tokens(10)})
+	generator.PushResponse(fakemodel.Response{Content: "v2", Usage: tokens(20)})
+	critic := fakemodel.New()
+	critic.PushResponse(fakemodel.Response{Content: "- 问题", Usage: tokens(100)})
+	critic.PushResponse(fakemodel.Response{Content: "CODE_IS_PERFECT", Usage: tokens(200)})
+
+	var log strings.Builder
+	runner := newTestRunner(t, generator, ReflectionConfig{
+		TaskPrompt: "任务", CriticModel: critic, GeneratorModelName: "gen-model", CriticModelName: "critic-model", Log: &log,
+	})
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(generator.Calls()) != 2 || len(critic.Calls()) != 2 {
+		t.Errorf("调用次数：生成者 %d，审查者 %d", len(generator.Calls()), len(critic.Calls()))
+	}
+	if result.Roles.Generator.Total() != 30 || result.Roles.Critic.Total() != 300 {
+		t.Errorf("按角色用量 = %+v", result.Roles)
+	}
+	if it := result.Iterations[1].Roles; it.Generator.Total() != 20 || it.Critic.Total() != 200 {
+		t.Errorf("第 2 轮按角色用量 = %+v", it)
+	}
+	for _, want := range []string{"生成初始代码...［gen-model］", "进行反思...［critic-model］"} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("日志缺少 %q", want)
+		}
+	}
+}
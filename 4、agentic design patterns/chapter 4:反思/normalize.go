@@ -0,0 +1,97 @@
+package main
+
+import "strings"
+
+// convergedRounds: 规范化后的代码连续这么多轮没有变化时视为收敛
+const convergedRounds = 2
+
+// commentSyntax: 规范化时识别的注释和字符串字面量语法
+type commentSyntax struct {
+	line       string // line: 行注释标记，如 "#"、"--"，为空表示没有行注释
+	blockStart string // blockStart, blockEnd: 块注释的起止标记，为空表示没有块注释
+	blockEnd   string
+	quotes     string // quotes: 字符串字面量的引号字符，字符串内的内容原样保留
+	escape     bool   // escape: 字符串内的反斜杠转义下一个字符
+	lineBreaks bool   // lineBreaks: 换行是语法的一部分（如 Python），否则换行和其他空白一样合并为一个空格
+}
+
+var (
+	// pythonSyntax: # 行注释，单双引号和三引号字符串
+	pythonSyntax = commentSyntax{line: "#", quotes: `'"`, escape: true, lineBreaks: true}
+	// sqlSyntax: -- 行注释和 /* */ 块注释，单引号字符串和双引号标识符（'' 转义按两个相邻字符串处理，结果相同）
+	sqlSyntax = commentSyntax{line: "--", blockStart: "/*", blockEnd: "*/", quotes: `'"`}
+	// proseSyntax: 文章没有注释，引号是正文的一部分，只规范化空白
+	proseSyntax = commentSyntax{}
+)
+
+// normalizeCode: 去掉注释和无意义的空白，用于判断两版代码是否有实质变化
+// 字符串字面量外：注释删除，行首行尾空白和空行删除，行内连续空白合并为一个空格（换行无意义时整体合并）；字符串字面量内原样保留
+// 缩进同样被忽略：只调整缩进的修改视为没有实质变化，收敛检测要连续多轮才生效，误判的代价很小
+func normalizeCode(code string, syntax commentSyntax) string {
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+	var sb strings.Builder
+	// space / newline: 字符串外待输出的空白，遇到下一个有效字符时才决定是否输出
+	space, newline := false, false
+	emit := func(s string) {
+		if sb.Len() > 0 {
+			if newline {
+				sb.WriteByte('\n')
+			} else if space {
+				sb.WriteByte(' ')
+			}
+		}
+		space, newline = false, false
+		sb.WriteString(s)
+	}
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == '\n' && syntax.lineBreaks:
+			newline = true
+			i++
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			space = true
+			i++
+		case syntax.line != "" && strings.HasPrefix(code[i:], syntax.line):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				end = len(code) - i
+			}
+			i += end
+		case syntax.blockStart != "" && strings.HasPrefix(code[i:], syntax.blockStart):
+			end := strings.Index(code[i+len(syntax.blockStart):], syntax.blockEnd)
+			if end < 0 {
+				i = len(code)
+				break
+			}
+			i += len(syntax.blockStart) + end + len(syntax.blockEnd)
+			space = true // 块注释相当于分隔符：a/*x*/b 与 a b 相同
+		case strings.IndexByte(syntax.quotes, c) >= 0:
+			end := stringLiteralEnd(code, i, syntax.escape)
+			emit(code[i:end])
+			i = end
+		default:
+			emit(code[i : i+1])
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// stringLiteralEnd: 从 code[start] 的引号开始的字符串字面量的结束位置（不含），支持三引号；没有闭合时到文本末尾
+func stringLiteralEnd(code string, start int, escape bool) int {
+	delim := code[start : start+1]
+	if triple := strings.Repeat(delim, 3); strings.HasPrefix(code[start:], triple) {
+		delim = triple
+	}
+	for i := start + len(delim); i < len(code); i++ {
+		if escape && code[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(code[i:], delim) {
+			return i + len(delim)
+		}
+	}
+	return len(code)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"ch4/fakemodel"
+)
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		syntax commentSyntax
+		want   string
+	}{
+		{"Python 行注释和行尾空白", "x = 1  # 赋值\n\n\ny  =  2   \n", pythonSyntax, "x = 1\ny = 2"},
+		{"Python 缩进被忽略", "def f():\n    return 1\n", pythonSyntax, "def f():\nreturn 1"},
+		{"Python CRLF", "a\r\nb\r\n", pythonSyntax, "a\nb"},
+		{"字符串中的 # 不是注释", `s = "a # b"  # 注释`, pythonSyntax, `s = "a # b"`},
+		{"单引号字符串中的 #", `s = 'x#y'`, pythonSyntax, `s = 'x#y'`},
+		{"字符串内空白原样保留", `s = "a   b"`, pythonSyntax, `s = "a   b"`},
+		{"转义引号不结束字符串", `s = "a\" # b"  # 注释`, pythonSyntax, `s = "a\" # b"`},
+		{"三引号跨行", "s = \"\"\"\n# 不是注释\n  缩进\n\"\"\"\n# 注释", pythonSyntax, "s = \"\"\"\n# 不是注释\n  缩进\n\"\"\""},
+		{"未闭合的字符串到末尾", `s = "abc # d`, pythonSyntax, `s = "abc # d`},
+		{"SQL 换行合并为空格", "SELECT *\n  FROM t -- 注释\nWHERE id = 1", sqlSyntax, "SELECT * FROM t WHERE id = 1"},
+		{"SQL 块注释相当于分隔符", "SELECT/* x */a", sqlSyntax, "SELECT a"},
+		{"SQL 未闭合的块注释", "SELECT 1 /* 注释", sqlSyntax, "SELECT 1"},
+		{"SQL 字符串中的注释标记", "SELECT '-- x /* y */'", sqlSyntax, "SELECT '-- x /* y */'"},
+		{"文章只规范化空白", "第一段 # 不是注释\n\n  \"引号\"   内", proseSyntax, "第一段 # 不是注释 \"引号\" 内"},
+		{"空文本", "  \n\t ", pythonSyntax, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeCode(tt.code, tt.syntax); got != tt.want {
+				t.Errorf("normalizeCode = %q，want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalizeCodeStringChangeIsSubstantive: 字符串内容的变化不会被规范化掉
+func TestNormalizeCodeStringChangeIsSubstantive(t *testing.T) {
+	a := normalizeCode(`print("a # b")`, pythonSyntax)
+	b := normalizeCode(`print("a # c")`, pythonSyntax)
+	if a == b {
+		t.Errorf("字符串内 # 之后的内容被当作注释删除: %q", a)
+	}
+}
+
+// TestConvergedStops: 代码连续两轮只改了空白和注释时停止，并保留最后一轮未被采纳的批评
+func TestConvergedStops(t *testing.T) {
+	llm := fakemodel.New(
+		"x = 1", "请改进命名",
+		"x = 1  # 计数", "请改进命名",
+		"x  =  1\n", "还是请改进命名",
+	)
+	result, err := newTestRunner(t, llm, ReflectionConfig{TaskPrompt: "任务", MaxIterations: 5}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != StatusConverged || len(result.Iterations) != 3 {
+		t.Fatalf("状态 = %s，轮数 = %d，want converged / 3", result.Status, len(result.Iterations))
+	}
+	if result.Outstanding != "还是请改进命名" {
+		t.Errorf("Outstanding = %q", result.Outstanding)
+	}
+	for i, want := range []bool{false, true, true} {
+		if got := result.Iterations[i].Unchanged; got != want {
+			t.Errorf("第 %d 轮 Unchanged = %v，want %v", i+1, got, want)
+		}
+	}
+	if llm.Remaining() != 0 {
+		t.Errorf("还剩 %d 条回复未使用", llm.Remaining())
+	}
+}
+
+// TestSubstantiveChangeResetsConvergence: 中间出现实质变化时重新计数
+func TestSubstantiveChangeResetsConvergence(t *testing.T) {
+	llm := fakemodel.New(
+		"x = 1", "改",
+		"x = 1 # a", "改",
+		"x = 2", "改",
+		"x = 2 # b", "改",
+	)
+	result, err := newTestRunner(t, llm, ReflectionConfig{TaskPrompt: "任务", MaxIterations: 4}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != StatusMaxIterations {
+		t.Errorf("状态 = %s，want max_iterations", result.Status)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TaskProfile: 一类任务的定义：任务提示词、审查者提示词、生成结果的后处理和审查者的停止标记
+type TaskProfile interface {
+	Name() string               // Name: 任务类型名称（--task-type 的取值）
+	TaskPrompt() string         // TaskPrompt: 默认任务，作为第一条用户消息
+	CriticSystemPrompt() string // CriticSystemPrompt: 审查者的系统提示词（FString 模板，字面花括号需写成 {{ }}）
+	PostProcess(output string) string
+	Normalize(code string) string // Normalize: 去掉注释和空白后的形式，用于收敛检测
+	StopSentinel() string         // StopSentinel: 审查者认为无需修改时输出的标记
+	Extension() string            // Extension: 每轮产物文件的扩展名（含点）
+}
+
+// staticProfile: 内置任务类型的实现
+type staticProfile struct {
+	name     string
+	task     string
+	critic   string
+	sentinel string
+	ext      string
+	post     func(string) string // post: 生成结果的后处理，如去掉 Markdown 代码块
+	syntax   commentSyntax       // syntax: 规范化时识别的注释和字符串语法
+}
+
+func (p staticProfile) Name() string                     { return p.name }
+func (p staticProfile) TaskPrompt() string               { return p.task }
+func (p staticProfile) CriticSystemPrompt() string       { return p.critic }
+func (p staticProfile) PostProcess(output string) string { return p.post(output) }
+func (p staticProfile) Normalize(code string) string     { return normalizeCode(code, p.syntax) }
+func (p staticProfile) StopSentinel() string             { return p.sentinel }
+func (p staticProfile) Extension() string                { return p.ext }
+
+// pythonCodeProfile: 生成 Python 函数（默认任务类型）
+var pythonCodeProfile TaskProfile = staticProfile{
+	name: "python-code",
+	task: `
+你的任务是创建一个名为 calculate_factorial 的 Python 函数。
+
+此函数应执行以下操作：
+1. 接受单个整数 n 作为输入。
+2. 计算其阶乘 (n!)。
+3. 包含清楚解释函数功能的文档字符串。
+4. 处理边缘情况：0 的阶乘是 1。
+5. 处理无效输入：如果输入是负数，则引发 ValueError。
+`,
+	critic: `你是一名高级软件工程师和 Python 专家。
+你的角色是执行细致的代码审查。
+根据原始任务要求批判性地评估提供的 Python 代码。
+查找错误、风格问题、缺失的边缘情况和改进领域。
+如果代码完美并满足所有要求，用单一短语 'CODE_IS_PERFECT' 响应。
+否则，提供批评的项目符号列表。`,
+	sentinel: "CODE_IS_PERFECT",
+	ext:      ".py",
+	post:     cleanCodeBlock,
+	syntax:   pythonSyntax,
+}
+
+// sqlQueryProfile: 根据表结构编写 SQL 查询
+var sqlQueryProfile TaskProfile = staticProfile{
+	name: "sql-query",
+	task: `
+你的任务是编写一条 SQL 查询（PostgreSQL 方言），只输出 SQL。
+
+表结构：
+- customers(id, name, country, created_at)
+- orders(id, customer_id, amount, status, created_at)
+
+要求：
+1. 找出 2024 年内已完成（status = 'completed'）订单总金额最高的 10 位客户。
+2. 输出客户姓名、国家、订单数和订单总金额，按总金额降序排列。
+3. 没有已完成订单的客户不出现在结果中。
+4. 使用清楚的别名，避免 SELECT *。
+`,
+	critic: `你是一名资深数据库工程师。
+根据原始任务和表结构审查提供的 SQL 查询。
+检查语法、连接条件、过滤条件（日期范围、状态）、聚合与分组、排序和 LIMIT 是否正确，以及可读性和性能问题。
+如果查询完全正确并满足所有要求，用单一短语 'QUERY_IS_PERFECT' 响应。
+否则，提供批评的项目符号列表。`,
+	sentinel: "QUERY_IS_PERFECT",
+	ext:      ".sql",
+	post:     cleanCodeBlock,
+	syntax:   sqlSyntax,
+}
+
+// shortEssayProfile: 撰写短文
+var shortEssayProfile TaskProfile = staticProfile{
+	name: "short-essay",
+	task: `
+你的任务是写一篇 300 字左右的中文短文，题目是《为什么软件需要代码审查》。
+
+要求：
+1. 有明确的论点，并用两到三个具体理由支撑。
+2. 结构完整：开头点题、主体论证、结尾总结。
+3. 语言简洁，避免空话和重复。
+4. 只输出正文（可带标题），不要额外说明。
+`,
+	critic: `你是一名严格的中文写作编辑。
+根据原始任务审查提供的短文。
+检查论点是否明确、论证是否充分、结构是否完整、字数是否符合要求，以及用词、语病和冗余。
+如果短文已经达到发表水平并满足所有要求，用单一短语 'ESSAY_IS_PERFECT' 响应。
+否则，提供批评的项目符号列表。`,
+	sentinel: "ESSAY_IS_PERFECT",
+	ext:      ".md",
+	post:     strings.TrimSpace,
+	syntax:   proseSyntax,
+}
+
+// taskProfiles: 内置任务类型
+var taskProfiles = []TaskProfile{pythonCodeProfile, sqlQueryProfile, shortEssayProfile}
+
+// profileNames: 内置任务类型的名称
+func profileNames() []string {
+	names := make([]string, len(taskProfiles))
+	for i, profile := range taskProfiles {
+		names[i] = profile.Name()
+	}
+	return names
+}
+
+// profileByName: 按名称查找内置任务类型
+func profileByName(name string) (TaskProfile, error) {
+	for _, profile := range taskProfiles {
+		if profile.Name() == name {
+			return profile, nil
+		}
+	}
+	return nil, fmt.Errorf("未知的任务类型: %s（可用的任务类型: %s）", name, strings.Join(profileNames(), ", "))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ch4/fakemodel"
+)
+
+func TestProfileByName(t *testing.T) {
+	for _, name := range []string{"python-code", "sql-query", "short-essay"} {
+		profile, err := profileByName(name)
+		if err != nil || profile.Name() != name {
+			t.Errorf("profileByName(%q) = %v, %v", name, profile, err)
+		}
+	}
+	_, err := profileByName("haiku")
+	if err == nil || !strings.Contains(err.Error(), "可用的任务类型: python-code, sql-query, short-essay") {
+		t.Errorf("未知任务类型: 错误 = %v", err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		profile  TaskProfile
+		output   string
+		wantPost string
+		wantExt  string
+	}{
+		{pythonCodeProfile, "说明\n```python\ndef f():\n    pass\n```\n", "def f():\n    pass", ".py"},
+		{sqlQueryProfile, "```sql\nSELECT 1;\n```", "SELECT 1;", ".sql"},
+		{shortEssayProfile, "\n  # 标题\n\n正文 ```不是代码块```\n", "# 标题\n\n正文 ```不是代码块```", ".md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile.Name(), func(t *testing.T) {
+			if got := tt.profile.PostProcess(tt.output); got != tt.wantPost {
+				t.Errorf("PostProcess = %q，want %q", got, tt.wantPost)
+			}
+			if tt.profile.Extension() != tt.wantExt {
+				t.Errorf("Extension = %q", tt.profile.Extension())
+			}
+			// 审查者提示词要求输出自己的停止标记；提示词是 FString 模板，不能有未转义的花括号
+			if !strings.Contains(tt.profile.CriticSystemPrompt(), tt.profile.StopSentinel()) {
+				t.Errorf("审查者提示词没有提到停止标记 %s", tt.profile.StopSentinel())
+			}
+			if strings.ContainsAny(tt.profile.CriticSystemPrompt(), "{}") {
+				t.Errorf("审查者提示词含有花括号")
+			}
+			if strings.TrimSpace(tt.profile.TaskPrompt()) == "" {
+				t.Errorf("任务提示词为空")
+			}
+		})
+	}
+}
+
+// TestProfileDrivesRun: 任务类型决定任务、审查者提示词和停止标记
+func TestProfileDrivesRun(t *testing.T) {
+	// CODE_IS_PERFECT 不是 SQL 任务的停止标记，不应让循环停止
+	llm := fakemodel.New("SELECT 1;", "CODE_IS_PERFECT", "SELECT 2;", "QUERY_IS_PERFECT")
+	result, err := newTestRunner(t, llm, ReflectionConfig{Profile: sqlQueryProfile}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != StatusPassed || len(result.Iterations) != 2 || result.FinalCode != "SELECT 2;" {
+		t.Errorf("结果 = %+v", result)
+	}
+	calls := llm.Calls()
+	if task := calls[0][0].Content; task != sqlQueryProfile.TaskPrompt() {
+		t.Errorf("第一条用户消息 = %q", task)
+	}
+	if system := calls[1][0].Content; system != sqlQueryProfile.CriticSystemPrompt() {
+		t.Errorf("审查者系统提示词 = %q", system)
+	}
+}
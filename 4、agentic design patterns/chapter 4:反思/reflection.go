@@ -0,0 +1,502 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"ch4/usage"
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// defaultMaxIterations: 默认的最大迭代次数
+const defaultMaxIterations = 3
+
+// improveInstruction: 第二轮起追加在历史末尾的完善指令
+const improveInstruction = "请使用提供的批评完善上一版内容。"
+
+// ReflectionState: 反思循环的状态
+type ReflectionState struct {
+	CurrentCode    string
+	MessageHistory []*schema.Message
+	Iteration      int
+	Execution      string    // Execution: 本轮代码的运行结果（ExecResult.Report），未启用执行阶段时为空
+	Scores         []float64 // Scores: 各轮的质量评分（未评分的轮次不计入）
+	Unchanged      int       // Unchanged: 代码连续没有实质变化（规范化后相同）的轮数，供收敛检测使用
+	OpenIssues     []Issue   // OpenIssues: 尚未解决的问题（启用结构化问题列表时）
+}
+
+// ReflectionConfig: 反思循环的配置
+type ReflectionConfig struct {
+	Profile               TaskProfile                // Profile: 任务类型，提供默认的任务和审查者提示词、输出后处理和停止标记，为空时取 python-code
+	TaskPrompt            string                     // TaskPrompt: 原始任务，作为第一条用户消息，也随代码一起交给审查者，为空时取 Profile 的任务
+	GeneratorSystemPrompt string                     // GeneratorSystemPrompt: 生成者的系统提示词，为空时不加系统消息
+	CriticSystemPrompt    string                     // CriticSystemPrompt: 审查者的系统提示词（FString 模板，字面花括号需写成 {{ }}），为空时取 Profile 的提示词
+	MaxIterations         int                        // MaxIterations: 最大迭代次数，<=0 时取 defaultMaxIterations
+	ShouldStop            func(critique string) bool // ShouldStop: 根据批评判断是否停止，为空时以包含 Profile 的停止标记为准（仅单审查者）
+	StructuredIssues      bool                       // StructuredIssues: 要求审查者以 JSON 数组列出问题，跨轮次跟踪每个问题是否解决
+	Critics               []CriticSpec               // Critics: 非空时启用多审查者并行审查，全部通过才停止，忽略 CriticSystemPrompt 和 ShouldStop
+	ScoreThreshold        float64                    // ScoreThreshold: >0 时要求审查者给出 1-10 的评分，评分达到该值即停止
+	MinImprovement        float64                    // MinImprovement: 评分两轮内提升不足该值时停止（评分停滞），<=0 时取 defaultMinImprovement
+	Execute               ExecuteFunc                // Execute: 可选的执行阶段，在生成和反思之间运行代码，结果附加到审查提示词
+	TokenBudget           int                        // TokenBudget: >0 时累计 token 用量（提示+生成）达到该值即停止
+	HistoryWindow         int                        // HistoryWindow: >0 时消息历史超过该条数就裁剪为滑动窗口（始终保留任务和最新的代码与批评）
+	Artifacts             *ArtifactWriter            // Artifacts: 非空时每轮结束后写入代码、批评和 diff，写入失败只打印警告
+	CriticModel           model.BaseChatModel        // CriticModel: 审查者使用的模型，为空时与生成者共用同一个模型
+	GeneratorModelName    string                     // GeneratorModelName: 生成者模型名称，只用于日志和报告
+	CriticModelName       string                     // CriticModelName: 审查者模型名称，为空时取 GeneratorModelName
+	Candidates            int                        // Candidates: >1 时首轮并发生成多个候选，由排序链打分后只保留最好的一个，之后的轮次仍只生成一个
+	Limiter               Limiter                    // Limiter: 非空时生成者和审查者的所有模型调用（包括并发的候选）共享该限流器
+	Retry                 retry.Policy               // Retry: 生成和反思阶段调用模型的重试策略，零值表示不重试
+	Deadline              time.Duration              // Deadline: >0 时整个运行（包括重试等待）的截止时间
+	CheckpointPath        string                     // CheckpointPath: 非空时某一阶段重试用尽后把进度保存到该文件，可用 Resume 继续
+	StreamPreview         bool                       // StreamPreview: 流式调用生成者并在 Log 中实时显示生成的内容，模型不支持流式时退回一次性生成
+	Log                   io.Writer                  // Log: 过程输出（各阶段、代码和批评），为空时不输出
+}
+
+// Iteration: 一轮生成-反思的记录
+type Iteration struct {
+	Number           int            // Number: 迭代序号（从 1 开始）
+	Code             string         // Code: 本轮生成的代码
+	Critique         string         // Critique: 审查者对本轮代码的批评
+	Exec             *ExecResult    // Exec: 本轮代码的运行结果，未启用执行阶段时为空
+	Reviews          []CriticReview // Reviews: 多审查者模式下各审查者的意见
+	Diff             string         // Diff: 相对上一版代码的统一 diff，首轮和无变化时为空
+	Unchanged        bool           // Unchanged: 本轮代码与上一版规范化后相同（只改了空白或注释）
+	Elapsed          time.Duration  // Elapsed: 本轮耗时
+	Usage            usage.Usage    // Usage: 本轮生成和审查的 token 用量
+	Roles            RoleUsage      // Roles: 本轮按角色拆分的 token 用量
+	Total            usage.Usage    // Total: 截至本轮的累计 token 用量
+	Score            float64        // Score: 本轮质量评分，未评分时为 0
+	Candidates       []Candidate    // Candidates: 首轮的所有候选及评分（启用多候选时）
+	GenerateAttempts int            // GenerateAttempts: 生成阶段的尝试次数（含重试）
+	CritiqueAttempts int            // CritiqueAttempts: 反思阶段的尝试次数（含重试）
+	Issues           []Issue        // Issues: 本轮报告的问题（启用结构化问题列表且解析成功时）
+	Stopped          bool           // Stopped: 本轮批评满足停止条件
+}
+
+// RoleUsage: 按角色拆分的 token 用量
+type RoleUsage struct {
+	Generator usage.Usage // Generator: 生成者（生成和完善代码）
+	Critic    usage.Usage // Critic: 审查者（多审查者时为全体之和）
+}
+
+// Add: 两个按角色用量之和
+func (u RoleUsage) Add(other RoleUsage) RoleUsage {
+	return RoleUsage{Generator: u.Generator.Add(other.Generator), Critic: u.Critic.Add(other.Critic)}
+}
+
+// Total: 两个角色的用量之和
+func (u RoleUsage) Total() usage.Usage {
+	return u.Generator.Add(u.Critic)
+}
+
+// ReflectionResult: 反思循环的结果
+type ReflectionResult struct {
+	FinalCode   string        // FinalCode: 最后一轮生成的代码
+	Iterations  []Iteration   // Iterations: 每一轮的完整记录
+	Status      StopStatus    // Status: 循环结束的原因
+	Scores      []float64     // Scores: 评分轨迹（启用评分时）
+	Usage       usage.Usage   // Usage: 所有轮次的 token 用量
+	Roles       RoleUsage     // Roles: 所有轮次按角色拆分的 token 用量
+	Issues      []IssueRecord // Issues: 问题的生命周期（启用结构化问题列表时）
+	Outstanding string        // Outstanding: 代码收敛时最后一轮未被采纳的批评
+}
+
+// ReflectionRunner: 可复用的生成-反思-改进循环
+type ReflectionRunner struct {
+	cfg      ReflectionConfig
+	log      io.Writer
+	color    bool // color: 日志输出是终端时给 diff 上色
+	generate compose.Runnable[[]*schema.Message, string]
+	reflect  critiqueFunc
+	rank     compose.Runnable[[]string, string] // rank: 候选排序链，未启用多候选时为空
+}
+
+// NewReflectionRunner: 编译生成链和反思链，创建反思循环；llm 是生成者模型，审查者模型见 ReflectionConfig.CriticModel
+func NewReflectionRunner(ctx context.Context, llm model.BaseChatModel, cfg ReflectionConfig) (*ReflectionRunner, error) {
+	if cfg.Profile == nil {
+		cfg.Profile = pythonCodeProfile
+	}
+	if cfg.TaskPrompt == "" {
+		cfg.TaskPrompt = cfg.Profile.TaskPrompt()
+	}
+	if cfg.CriticSystemPrompt == "" {
+		cfg.CriticSystemPrompt = cfg.Profile.CriticSystemPrompt()
+	}
+	if strings.TrimSpace(cfg.TaskPrompt) == "" {
+		return nil, fmt.Errorf("任务提示词不能为空")
+	}
+	if strings.TrimSpace(cfg.CriticSystemPrompt) == "" && len(cfg.Critics) == 0 {
+		return nil, fmt.Errorf("审查者系统提示词不能为空")
+	}
+	if cfg.MaxIterations <= 0 {
+		cfg.MaxIterations = defaultMaxIterations
+	}
+	if cfg.HistoryWindow > 0 && cfg.HistoryWindow < minHistoryWindow {
+		cfg.HistoryWindow = minHistoryWindow
+	}
+	if cfg.MinImprovement <= 0 {
+		cfg.MinImprovement = defaultMinImprovement
+	}
+	format := criticFormat{Scored: cfg.ScoreThreshold > 0, Issues: cfg.StructuredIssues}
+	if cfg.ShouldStop == nil {
+		sentinel := cfg.Profile.StopSentinel()
+		cfg.ShouldStop = func(critique string) bool {
+			return strings.Contains(critique, sentinel)
+		}
+	}
+	log := cfg.Log
+	if log == nil {
+		log = io.Discard
+	}
+	if cfg.CriticModelName == "" {
+		cfg.CriticModelName = cfg.GeneratorModelName
+	}
+	criticLLM := cfg.CriticModel
+	if criticLLM == nil {
+		criticLLM = llm
+	}
+	// 包装模型以统计每轮的 token 用量（生成和审查在不同的上下文中调用，用量分别统计）
+	if cfg.Limiter != nil {
+		llm = &limitedModel{inner: llm, limiter: cfg.Limiter}
+		criticLLM = &limitedModel{inner: criticLLM, limiter: cfg.Limiter}
+	}
+	llm = usage.NewCountingModel(llm)
+	criticLLM = usage.NewCountingModel(criticLLM)
+
+	// 生成链：直接使用消息历史调用 LLM；最后一个 Lambda 是流式转换，既支持 Invoke 得到完整文本，也支持 Stream 逐块输出
+	generateChain, err := compose.NewChain[[]*schema.Message, string]().
+		AppendChatModel(llm).
+		AppendLambda(compose.TransformableLambda(func(ctx context.Context, msgs *schema.StreamReader[*schema.Message]) (*schema.StreamReader[string], error) {
+			return schema.StreamReaderWithConvert(msgs, func(msg *schema.Message) (string, error) {
+				return msg.Content, nil
+			}), nil
+		})).
+		Compile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("编译生成链失败: %w", err)
+	}
+
+	// 反思：单审查者链，或多个审查者组成的并行图
+	var reflect critiqueFunc
+	if len(cfg.Critics) > 0 {
+		reflect, err = buildMultiCritic(ctx, criticLLM, cfg.TaskPrompt, cfg.Critics, format)
+	} else {
+		reflect, err = buildSingleCritic(ctx, criticLLM, cfg.TaskPrompt, cfg.CriticSystemPrompt, cfg.ShouldStop, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 排序链：由审查者模型给各候选打分
+	var rank compose.Runnable[[]string, string]
+	if cfg.Candidates > 1 {
+		if rank, err = buildRankingChain(ctx, criticLLM, cfg.TaskPrompt); err != nil {
+			return nil, fmt.Errorf("编译排序链失败: %w", err)
+		}
+	}
+
+	return &ReflectionRunner{cfg: cfg, log: log, color: supportsColor(log), generate: generateChain, reflect: reflect, rank: rank}, nil
+}
+
+// Config: 补全默认值后的配置
+func (r *ReflectionRunner) Config() ReflectionConfig {
+	return r.cfg
+}
+
+// modelTag: 日志中标注某个角色使用的模型，未提供模型名称时为空
+func modelTag(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf("［%s］", name)
+}
+
+// initialHistory: 初始消息历史：可选的生成者系统提示词 + 任务
+func (r *ReflectionRunner) initialHistory() []*schema.Message {
+	var history []*schema.Message
+	if r.cfg.GeneratorSystemPrompt != "" {
+		history = append(history, schema.SystemMessage(r.cfg.GeneratorSystemPrompt))
+	}
+	return append(history, schema.UserMessage(r.cfg.TaskPrompt))
+}
+
+// minHistoryWindow: 滑动窗口的最小条数：系统提示词 + 任务 + 最新代码 + 最新批评
+const minHistoryWindow = 4
+
+// trimHistory: 历史超过 limit 条时裁剪为滑动窗口：保留开头的 head 条（系统提示词和任务），
+// 其余只保留最近的若干组"代码 + 批评"，至少保留最新的一组
+func trimHistory(history []*schema.Message, head, limit int) []*schema.Message {
+	if limit <= 0 || len(history) <= limit {
+		return history
+	}
+	tail := (limit - head) / 2 * 2
+	tail = max(tail, 2)
+	if head+tail >= len(history) {
+		return history
+	}
+	trimmed := make([]*schema.Message, 0, head+tail)
+	trimmed = append(trimmed, history[:head]...)
+	return append(trimmed, history[len(history)-tail:]...)
+}
+
+// Run: 执行反思循环，直到批评满足停止条件或达到最大迭代次数
+// 每轮：生成（首轮）或基于批评完善代码 -> 审查 -> 判断是否停止，批评追加到历史供下一轮使用
+// 某一阶段重试用尽时返回 *PhaseError（配置了 CheckpointPath 时先保存检查点）
+func (r *ReflectionRunner) Run(ctx context.Context) (ReflectionResult, error) {
+	return r.run(ctx, nil)
+}
+
+// Resume: 从检查点继续：从失败的轮次开始，反思阶段失败时沿用已生成的代码
+func (r *ReflectionRunner) Resume(ctx context.Context, cp *Checkpoint) (ReflectionResult, error) {
+	if cp.TaskType != r.cfg.Profile.Name() || cp.Task != r.cfg.TaskPrompt {
+		return ReflectionResult{}, fmt.Errorf("检查点的任务（%s）与当前任务（%s）不一致", cp.TaskType, r.cfg.Profile.Name())
+	}
+	if cp.Iteration > r.cfg.MaxIterations {
+		return ReflectionResult{}, fmt.Errorf("检查点停在第 %d 轮，超过了最大迭代次数 %d", cp.Iteration, r.cfg.MaxIterations)
+	}
+	return r.run(ctx, cp)
+}
+
+// run: 反思循环的实现，cp 不为空时从检查点继续
+func (r *ReflectionRunner) run(ctx context.Context, cp *Checkpoint) (ReflectionResult, error) {
+	if r.cfg.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Deadline)
+		defer cancel()
+	}
+	result := ReflectionResult{Status: StatusMaxIterations}
+	state := ReflectionState{MessageHistory: r.initialHistory()}
+	tracker := newIssueTracker()
+	// head: 历史开头始终保留的消息（系统提示词和任务）
+	head := len(state.MessageHistory)
+	first := 0
+	var resumed *Iteration // resumed: 反思阶段失败的那一轮已生成的部分
+	if cp != nil {
+		state, result, tracker = cp.restore()
+		first = cp.Iteration - 1
+		if cp.Phase == phaseCritique {
+			resumed = cp.Pending
+		}
+		fmt.Fprintf(r.log, "从检查点继续：第 %d 轮%s阶段（已完成 %d 轮）\n", cp.Iteration, cp.Phase, len(result.Iterations))
+	}
+
+	for i := first; i < r.cfg.MaxIterations; i++ {
+		state.Iteration = i + 1
+		start := time.Now()
+		// generateCtx / criticCtx: 本轮生成者和审查者的模型调用分别记到各自的累加器上
+		generateCtx, generateCounter := usage.With(ctx)
+		criticCtx, criticCounter := usage.With(ctx)
+		fmt.Fprintf(r.log, "\n%s 反思循环：迭代 %d %s\n", strings.Repeat("=", 25), state.Iteration, strings.Repeat("=", 25))
+
+		// --- 1. 生成/完善阶段 ---
+		var iteration Iteration
+		if resumed != nil && i == first {
+			iteration = *resumed
+			fmt.Fprintf(r.log, "\n沿用检查点中已生成的代码 (v%d)，直接进入反思阶段\n%s\n", state.Iteration, state.CurrentCode)
+		} else {
+			history := state.MessageHistory
+			if i == 0 {
+				fmt.Fprintf(r.log, "\n>>> 阶段 1：生成初始代码...%s\n", modelTag(r.cfg.GeneratorModelName))
+			} else {
+				fmt.Fprintf(r.log, "\n>>> 阶段 1：基于先前批评完善代码...%s\n", modelTag(r.cfg.GeneratorModelName))
+				// 后续迭代：添加完善指令（只用于本次调用，不写入历史）
+				instruction := improveInstruction
+				if len(state.OpenIssues) > 0 {
+					instruction += "\n\n以下问题尚未解决，请逐一修复：\n" + formatIssues(state.OpenIssues)
+				}
+				history = append(history[:len(history):len(history)], schema.UserMessage(instruction))
+			}
+			var candidates []Candidate
+			output, attempts, err := withRetry(generateCtx, r, phaseGenerate, func(ctx context.Context) (string, error) {
+				if i == 0 && r.rank != nil {
+					// 首轮多候选：排序的用量记到审查者
+					output, cands, err := r.generateCandidates(ctx, criticCtx, history)
+					if err == nil {
+						candidates = cands
+						fmt.Fprintf(r.log, "\n--- 生成的代码 (v%d) ---\n%s\n", state.Iteration, output)
+					}
+					return output, err
+				}
+				return r.generateCode(ctx, state.Iteration, history)
+			})
+			if err != nil {
+				return result, r.fail(phaseGenerate, attempts, err, state, result, tracker, nil)
+			}
+			// 按任务类型后处理（如去掉代码块标记），审查、diff 和产物都基于处理后的结果
+			code := r.cfg.Profile.PostProcess(output)
+			previous := state.CurrentCode
+			state.CurrentCode = code
+			var diff string
+			if i > 0 {
+				diff = r.printDiff(&state, previous, code)
+			}
+			// 将生成的代码添加到历史记录
+			state.MessageHistory = append(state.MessageHistory, schema.AssistantMessage(state.CurrentCode, nil))
+			iteration = Iteration{Number: state.Iteration, Code: code, Diff: diff, Unchanged: i > 0 && state.Unchanged > 0,
+				Candidates: candidates, GenerateAttempts: attempts}
+		}
+		code := iteration.Code
+		result.FinalCode = code
+
+		// --- 1.5 执行阶段（可选）---
+		var execResult *ExecResult
+		state.Execution = ""
+		if r.cfg.Execute != nil {
+			fmt.Fprintln(r.log, "\n>>> 阶段 1.5：运行生成的代码...")
+			res := r.cfg.Execute(ctx, code)
+			execResult = &res
+			state.Execution = res.Report()
+			if res.Skipped {
+				fmt.Fprintf(r.log, "⚠ 跳过执行：%s\n", res.SkipReason)
+			} else {
+				fmt.Fprintf(r.log, "\n--- 运行结果 ---\n%s\n", state.Execution)
+			}
+		}
+
+		// --- 2. 反思阶段 ---
+		fmt.Fprintf(r.log, "\n>>> 阶段 2：对生成的代码进行反思...%s\n", modelTag(r.cfg.CriticModelName))
+		review, attempts, err := withRetry(criticCtx, r, phaseCritique, func(ctx context.Context) (critique, error) {
+			return r.reflect(ctx, state)
+		})
+		if err != nil {
+			// 检查点保留本轮已生成的代码和生成者用量，继续时不必重新生成
+			pending := iteration
+			pending.Roles.Generator = pending.Roles.Generator.Add(generateCounter.Usage())
+			return result, r.fail(phaseCritique, attempts, err, state, result, tracker, &pending)
+		}
+		iteration.Critique, iteration.Exec, iteration.Reviews, iteration.CritiqueAttempts = review.Text, execResult, review.Reviews, attempts
+		for _, critic := range review.Reviews {
+			mark := "❌"
+			if critic.Passed {
+				mark = "✅"
+			}
+			fmt.Fprintf(r.log, "%s %s\n", mark, critic.Label)
+		}
+		if r.cfg.StructuredIssues {
+			r.trackIssues(tracker, &state, &iteration, review)
+			result.Issues = tracker.lifecycle()
+		}
+
+		// --- 3. 停止条件 ---
+		// 停止标记（或全体审查者通过）> 评分达到阈值 > 评分停滞；代码有语法错误时一律继续迭代，除非 token 预算耗尽
+		var status StopStatus
+		if r.cfg.ScoreThreshold > 0 && review.Score > 0 {
+			iteration.Score = review.Score
+			state.Scores = append(state.Scores, review.Score)
+			fmt.Fprintf(r.log, "评分：%s（阈值 %s）\n", formatScore(review.Score), formatScore(r.cfg.ScoreThreshold))
+		}
+		switch {
+		case review.Passed:
+			status = StatusPassed
+		case iteration.Score > 0 && iteration.Score >= r.cfg.ScoreThreshold:
+			status = StatusThreshold
+		case iteration.Score > 0 && plateaued(state.Scores, r.cfg.MinImprovement):
+			status = StatusPlateau
+		}
+		if status != "" && execResult != nil && execResult.SyntaxError() {
+			fmt.Fprintln(r.log, "⚠ 代码存在语法错误，忽略停止条件，继续完善")
+			status = ""
+		}
+		// 生成者不再实质修改代码时继续迭代没有意义（即使有语法错误），把剩下的批评留给最终报告
+		if status == "" && state.Unchanged >= convergedRounds {
+			status = StatusConverged
+			result.Outstanding = review.Text
+		}
+		iteration.Roles = iteration.Roles.Add(RoleUsage{Generator: generateCounter.Usage(), Critic: criticCounter.Usage()})
+		iteration.Usage = iteration.Roles.Total()
+		iteration.Elapsed = time.Since(start)
+		result.Usage = result.Usage.Add(iteration.Usage)
+		result.Roles = result.Roles.Add(iteration.Roles)
+		iteration.Total = result.Usage
+		// token 预算是硬上限：即使代码有语法错误也不再继续
+		if status == "" && r.cfg.TokenBudget > 0 && result.Usage.Total() >= r.cfg.TokenBudget {
+			status = StatusBudget
+		}
+		stop := status != ""
+		iteration.Stopped = stop
+		result.Iterations = append(result.Iterations, iteration)
+		result.Scores = state.Scores
+		if status == StatusPassed {
+			fmt.Fprintln(r.log, "\n--- 批评 ---\n未发现进一步批评。代码令人满意。")
+		} else {
+			fmt.Fprintf(r.log, "\n--- 批评 ---\n%s\n", review.Text)
+		}
+		fmt.Fprintf(r.log, "\n本轮 token 用量：%s（生成者 %d / 审查者 %d，累计 %s）\n",
+			iteration.Usage, iteration.Roles.Generator.Total(), iteration.Roles.Critic.Total(), result.Usage)
+		switch status {
+		case StatusBudget:
+			fmt.Fprintf(r.log, "累计用量达到 token 预算 %d\n", r.cfg.TokenBudget)
+		case StatusConverged:
+			fmt.Fprintf(r.log, "代码连续 %d 轮没有实质变化（忽略空白和注释），不再迭代\n", state.Unchanged)
+		}
+		if r.cfg.Artifacts != nil {
+			if err := r.cfg.Artifacts.WriteIteration(iteration, r.cfg.Profile.Extension()); err != nil {
+				fmt.Fprintf(r.log, "⚠ 保存第 %d 轮产物失败: %v\n", iteration.Number, err)
+			}
+		}
+		if stop {
+			if status != StatusPassed {
+				fmt.Fprintf(r.log, "\n停止：%s\n", status)
+			}
+			result.Status = status
+			break
+		}
+
+		// 将批评添加到历史记录以用于下一个完善循环
+		state.MessageHistory = append(state.MessageHistory, schema.UserMessage(fmt.Sprintf("对先前代码的批评：\n%s", review.Text)))
+		if trimmed := trimHistory(state.MessageHistory, head, r.cfg.HistoryWindow); len(trimmed) < len(state.MessageHistory) {
+			fmt.Fprintf(r.log, "消息历史 %d 条超过窗口 %d，裁剪为 %d 条（保留任务和最近的代码与批评）\n",
+				len(state.MessageHistory), r.cfg.HistoryWindow, len(trimmed))
+			state.MessageHistory = trimmed
+		}
+	}
+	return result, nil
+}
+
+// printDiff: 打印本轮代码相对上一版的 diff 并更新连续没有实质变化的轮数，返回 diff（完全相同时为空）
+func (r *ReflectionRunner) printDiff(state *ReflectionState, previous, current string) string {
+	fmt.Fprintf(r.log, "\n--- 代码变化 (v%d -> v%d) ---\n", state.Iteration-1, state.Iteration)
+	diff := unifiedDiff(fmt.Sprintf("v%d", state.Iteration-1), fmt.Sprintf("v%d", state.Iteration),
+		previous, current)
+	if r.cfg.Profile.Normalize(previous) != r.cfg.Profile.Normalize(current) {
+		state.Unchanged = 0
+	} else {
+		state.Unchanged++
+	}
+	if diff == "" {
+		fmt.Fprintf(r.log, "无变化（连续 %d 轮）\n", state.Unchanged)
+		return ""
+	}
+	if state.Unchanged > 0 {
+		fmt.Fprintf(r.log, "只有空白或注释变化（连续 %d 轮没有实质变化）\n", state.Unchanged)
+	}
+	if r.color {
+		fmt.Fprint(r.log, colorizeDiff(diff))
+	} else {
+		fmt.Fprint(r.log, diff)
+	}
+	return diff
+}
+
+// trackIssues: 用本轮的问题列表更新问题状态；审查者输出无法解析时问题状态保持不变
+func (r *ReflectionRunner) trackIssues(tracker *issueTracker, state *ReflectionState, iteration *Iteration, review critique) {
+	if !review.Structured {
+		fmt.Fprintln(r.log, "⚠ 批评中没有可解析的问题数组，问题状态保持不变")
+		return
+	}
+	iteration.Issues = review.Issues
+	opened, resolved := tracker.update(state.Iteration, review.Issues)
+	state.OpenIssues = tracker.openIssues()
+	fmt.Fprintf(r.log, "问题：新增 %d，已解决 %d，未解决 %d\n", len(opened), len(resolved), len(state.OpenIssues))
+	for _, issue := range resolved {
+		fmt.Fprintf(r.log, "  ✅ %s\n", issue)
+	}
+}
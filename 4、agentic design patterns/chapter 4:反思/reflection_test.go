@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ch4/fakemodel"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// TestReflectionStopsOnSentinel: 首轮审查者给出停止标记时只跑一轮
+func TestReflectionStopsOnSentinel(t *testing.T) {
+	llm := fakemodel.New("```python\ndef f():\n    return 1\n```", "CODE_IS_PERFECT")
+	result, err := newTestRunner(t, llm, ReflectionConfig{MaxIterations: 3}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != StatusPassed || len(result.Iterations) != 1 || !result.Iterations[0].Stopped {
+		t.Fatalf("状态 = %s，轮数 = %d", result.Status, len(result.Iterations))
+	}
+	if want := "def f():\n    return 1"; result.FinalCode != want {
+		t.Errorf("FinalCode = %q，want 去掉代码块标记后的 %q", result.FinalCode, want)
+	}
+	if len(llm.Calls()) != 2 || llm.Remaining() != 0 {
+		t.Errorf("调用模型 %d 次，want 2", len(llm.Calls()))
+	}
+}
+
+// TestReflectionRunsToMaxIterations: 审查者一直有意见时跑满最大迭代次数
+func TestReflectionRunsToMaxIterations(t *testing.T) {
+	llm := fakemodel.New("x = 1", "批评一", "x = 2", "批评二", "x = 3", "批评三")
+	result, err := newTestRunner(t, llm, ReflectionConfig{MaxIterations: 3}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != StatusMaxIterations || len(result.Iterations) != 3 || result.FinalCode != "x = 3" {
+		t.Fatalf("结果 = %+v", result)
+	}
+	for i, it := range result.Iterations {
+		if it.Number != i+1 || it.Stopped {
+			t.Errorf("第 %d 轮记录 = %+v", i+1, it)
+		}
+	}
+	if got := result.Iterations[1].Critique; got != "批评二" {
+		t.Errorf("第 2 轮批评 = %q", got)
+	}
+	if result.Iterations[1].Diff == "" {
+		t.Errorf("第 2 轮应有 diff")
+	}
+}
+
+// TestReflectionHistoryOrder: 每轮依次追加代码和批评，完善指令只出现在本次调用中
+func TestReflectionHistoryOrder(t *testing.T) {
+	llm := fakemodel.New("v1", "批评一", "v2", "批评二", "v3", "CODE_IS_PERFECT")
+	if _, err := newTestRunner(t, llm, ReflectionConfig{GeneratorSystemPrompt: "你是程序员", TaskPrompt: "任务", MaxIterations: 3}).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	calls := llm.Calls()
+	if len(calls) != 6 {
+		t.Fatalf("调用模型 %d 次，want 6", len(calls))
+	}
+	type msg struct {
+		role    schema.RoleType
+		content string
+	}
+	want := []msg{
+		{schema.System, "你是程序员"},
+		{schema.User, "任务"},
+		{schema.Assistant, "v1"},
+		{schema.User, "对先前代码的批评：\n批评一"},
+		{schema.Assistant, "v2"},
+		{schema.User, "对先前代码的批评：\n批评二"},
+		{schema.User, improveInstruction},
+	}
+	// 第 3 轮生成者的输入
+	got := calls[4]
+	if len(got) != len(want) {
+		t.Fatalf("第 3 轮生成者收到 %d 条消息，want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Role != w.role || got[i].Content != w.content {
+			t.Errorf("消息 %d = %s %q，want %s %q", i, got[i].Role, got[i].Content, w.role, w.content)
+		}
+	}
+	// 完善指令不写入历史：第 2 轮的指令没有留在第 3 轮的输入里
+	for _, m := range got[:len(got)-1] {
+		if m.Content == improveInstruction {
+			t.Errorf("完善指令被写入了历史")
+		}
+	}
+	// 审查者看到的是当前这一版代码
+	critic := calls[5]
+	if last := critic[len(critic)-1].Content; !strings.Contains(last, "v3") {
+		t.Errorf("第 3 轮审查者输入没有包含最新代码: %q", last)
+	}
+}
+
+func TestCleanCodeBlock(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"带语言标记", "```python\nprint(1)\n```", "print(1)"},
+		{"不带语言标记", "```\nprint(1)\n```", "print(1)"},
+		{"前后有说明文字", "这是代码：\n```py\na = 1\nb = 2\n```\n希望有帮助", "a = 1\nb = 2"},
+		{"多个代码块取第一个", "```python\nfirst\n```\n```python\nsecond\n```", "first"},
+		{"没有代码块原样返回", "  print(1)  \n", "print(1)"},
+		{"未闭合的代码块原样返回", "```python\nprint(1)", "```python\nprint(1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanCodeBlock(tt.text); got != tt.want {
+				t.Errorf("cleanCodeBlock = %q，want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReflectionGenerateError: 生成失败时返回 *PhaseError，已完成的轮次保留在结果中
+func TestReflectionGenerateError(t *testing.T) {
+	boom := errors.New("模型不可用")
+	llm := fakemodel.New("v1", "批评一").PushError(boom)
+	result, err := newTestRunner(t, llm, ReflectionConfig{MaxIterations: 3}).Run(context.Background())
+	var phaseErr *PhaseError
+	if !errors.As(err, &phaseErr) || !errors.Is(err, boom) {
+		t.Fatalf("错误 = %v，want 包装 %v 的 *PhaseError", err, boom)
+	}
+	if phaseErr.Phase != phaseGenerate || phaseErr.Iteration != 2 || phaseErr.Attempts != 1 {
+		t.Errorf("PhaseError = %+v", phaseErr)
+	}
+	if len(result.Iterations) != 1 || result.Iterations[0].Code != "v1" {
+		t.Errorf("已完成的轮次 = %+v", result.Iterations)
+	}
+}
+
+// TestReflectionModelExhausted: 脚本用完时的错误同样经由 PhaseError 返回
+func TestReflectionModelExhausted(t *testing.T) {
+	_, err := newTestRunner(t, fakemodel.New(), ReflectionConfig{}).Run(context.Background())
+	if !errors.Is(err, fakemodel.ErrExhausted) {
+		t.Errorf("错误 = %v", err)
+	}
+}
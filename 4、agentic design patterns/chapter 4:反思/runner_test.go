@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ch4/fakemodel"
+)
+
+// newTestRunner: 用假模型创建反思循环，测试失败时直接终止
+func newTestRunner(t *testing.T, llm *fakemodel.Model, cfg ReflectionConfig) *ReflectionRunner {
+	t.Helper()
+	runner, err := NewReflectionRunner(context.Background(), llm, cfg)
+	if err != nil {
+		t.Fatalf("NewReflectionRunner: %v", err)
+	}
+	return runner
+}
+
+func TestNewReflectionRunnerDefaults(t *testing.T) {
+	cfg := newTestRunner(t, fakemodel.New(), ReflectionConfig{GeneratorModelName: "gen", HistoryWindow: 1}).Config()
+	if cfg.Profile.Name() != pythonCodeProfile.Name() {
+		t.Errorf("默认任务类型 = %s", cfg.Profile.Name())
+	}
+	if cfg.TaskPrompt != pythonCodeProfile.TaskPrompt() || cfg.CriticSystemPrompt != pythonCodeProfile.CriticSystemPrompt() {
+		t.Errorf("任务或审查者提示词没有取任务类型的默认值")
+	}
+	if cfg.MaxIterations != defaultMaxIterations {
+		t.Errorf("最大迭代次数 = %d，want %d", cfg.MaxIterations, defaultMaxIterations)
+	}
+	if cfg.MinImprovement != defaultMinImprovement {
+		t.Errorf("最小提升 = %v，want %v", cfg.MinImprovement, defaultMinImprovement)
+	}
+	if cfg.HistoryWindow != minHistoryWindow {
+		t.Errorf("历史窗口 = %d，want 至少 %d", cfg.HistoryWindow, minHistoryWindow)
+	}
+	if cfg.CriticModelName != "gen" {
+		t.Errorf("审查者模型名称 = %q，want 与生成者相同", cfg.CriticModelName)
+	}
+	if !cfg.ShouldStop("好的 CODE_IS_PERFECT") || cfg.ShouldStop("- 缺少文档字符串") {
+		t.Errorf("默认停止条件应以任务类型的停止标记为准")
+	}
+}
+
+func TestNewReflectionRunnerKeepsExplicitConfig(t *testing.T) {
+	cfg := newTestRunner(t, fakemodel.New(), ReflectionConfig{
+		TaskPrompt:         "写一个函数",
+		CriticSystemPrompt: "审查它",
+		MaxIterations:      7,
+		Profile:            sqlQueryProfile,
+		CriticModelName:    "critic",
+	}).Config()
+	if cfg.TaskPrompt != "写一个函数" || cfg.CriticSystemPrompt != "审查它" || cfg.MaxIterations != 7 {
+		t.Errorf("显式配置被覆盖: %+v", cfg)
+	}
+	if !cfg.ShouldStop("QUERY_IS_PERFECT") {
+		t.Errorf("停止标记应取显式指定的任务类型")
+	}
+	if cfg.CriticModelName != "critic" {
+		t.Errorf("审查者模型名称 = %q", cfg.CriticModelName)
+	}
+}
+
+func TestNewReflectionRunnerValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ReflectionConfig
+		wantErr string
+	}{
+		{"任务只有空白", ReflectionConfig{TaskPrompt: " \n"}, "任务提示词不能为空"},
+		{"审查者提示词只有空白", ReflectionConfig{CriticSystemPrompt: "\t"}, "审查者系统提示词不能为空"},
+		{"审查者名称重复", ReflectionConfig{Critics: []CriticSpec{{Key: "a", SystemPrompt: "x"}, {Key: "a", SystemPrompt: "y"}}}, "审查者名称为空或重复"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReflectionRunner(context.Background(), fakemodel.New(), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 多审查者模式不需要单审查者的提示词
+	if _, err := NewReflectionRunner(context.Background(), fakemodel.New(), ReflectionConfig{CriticSystemPrompt: " ", Critics: defaultCritics}); err != nil {
+		t.Errorf("多审查者模式: %v", err)
+	}
+}
+
+func TestInitialHistory(t *testing.T) {
+	runner := newTestRunner(t, fakemodel.New(), ReflectionConfig{TaskPrompt: "任务"})
+	if history := runner.initialHistory(); len(history) != 1 || history[0].Content != "任务" {
+		t.Errorf("没有生成者系统提示词时历史 = %v", history)
+	}
+
+	runner = newTestRunner(t, fakemodel.New(), ReflectionConfig{TaskPrompt: "任务", GeneratorSystemPrompt: "你是程序员"})
+	history := runner.initialHistory()
+	if len(history) != 2 || history[0].Role != "system" || history[0].Content != "你是程序员" || history[1].Content != "任务" {
+		t.Errorf("历史 = %v", history)
+	}
+}
+
+// TestCustomShouldStop: 自定义停止条件替代停止标记
+func TestCustomShouldStop(t *testing.T) {
+	llm := fakemodel.New("v1", "LGTM")
+	runner := newTestRunner(t, llm, ReflectionConfig{
+		TaskPrompt: "任务",
+		ShouldStop: func(critique string) bool { return critique == "LGTM" },
+	})
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != StatusPassed || len(result.Iterations) != 1 || result.FinalCode != "v1" {
+		t.Errorf("结果 = %+v", result)
+	}
+}
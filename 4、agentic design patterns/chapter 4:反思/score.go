@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	minScore              = 1   // minScore: 质量评分下限
+	maxScore              = 10  // maxScore: 质量评分上限
+	defaultMinImprovement = 1.0 // defaultMinImprovement: 两轮内评分至少提升多少，否则视为停滞
+)
+
+// scoreInstruction: 启用评分时追加到审查者系统提示词末尾的要求
+const scoreInstruction = `
+
+无论是否发现问题，最后一行都必须以 "Score: N" 的格式给出 1-10 的整数质量评分（10 表示完全满足要求、无可挑剔）。`
+
+var (
+	// jsonScore: JSON 中的评分字段，如 {"score": 8}
+	jsonScore = regexp.MustCompile(`(?i)"score"\s*:\s*"?(\d+(?:\.\d+)?)`)
+	// labeledScore: 文字中的评分，如 "Score: 8"、"**评分**：7/10"
+	labeledScore = regexp.MustCompile(`(?i)(?:score|评分|得分)\**\s*[:：=]\s*\**\s*(\d+(?:\.\d+)?)\s*(?:/\s*10)?`)
+)
+
+// parseScore: 从审查者输出中解析质量评分，优先 JSON 字段，其次取最后一个 "Score: N"；不在 1-10 之间视为没有评分
+func parseScore(text string) (float64, bool) {
+	for _, re := range []*regexp.Regexp{jsonScore, labeledScore} {
+		matches := re.FindAllStringSubmatch(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		score, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+		if err != nil || score < minScore || score > maxScore {
+			return 0, false
+		}
+		return score, true
+	}
+	return 0, false
+}
+
+// plateaued: 最新评分比两轮前提升不足 delta（至少需要三个评分）
+func plateaued(scores []float64, delta float64) bool {
+	if len(scores) < 3 {
+		return false
+	}
+	return scores[len(scores)-1]-scores[len(scores)-3] < delta
+}
+
+// formatScore: 评分的文字形式，整数不带小数点
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// formatScores: 评分轨迹，如 "6 → 7.5 → 9"
+func formatScores(scores []float64) string {
+	parts := make([]string, len(scores))
+	for i, score := range scores {
+		parts[i] = formatScore(score)
+	}
+	return strings.Join(parts, " → ")
+}
+
+// StopStatus: 反思循环结束的原因
+type StopStatus string
+
+const (
+	StatusPassed        StopStatus = "passed"          // StatusPassed: 审查者给出停止标记（多审查者时全体通过）
+	StatusThreshold     StopStatus = "score_threshold" // StatusThreshold: 评分达到阈值
+	StatusPlateau       StopStatus = "plateau"         // StatusPlateau: 评分停滞，继续迭代收益不大
+	StatusMaxIterations StopStatus = "max_iterations"  // StatusMaxIterations: 达到最大迭代次数
+	StatusBudget        StopStatus = "token_budget"    // StatusBudget: 累计 token 用量达到预算
+	StatusConverged     StopStatus = "converged"       // StatusConverged: 代码连续多轮没有实质变化（忽略空白和注释）
+)
+
+// String: 结束原因的中文说明
+func (s StopStatus) String() string {
+	switch s {
+	case StatusPassed:
+		return "审查通过"
+	case StatusThreshold:
+		return "评分达到阈值"
+	case StatusPlateau:
+		return "评分停滞"
+	case StatusMaxIterations:
+		return "达到最大迭代次数"
+	case StatusBudget:
+		return "token 预算耗尽"
+	case StatusConverged:
+		return "代码已收敛"
+	default:
+		return fmt.Sprintf("未知状态（%s）", string(s))
+	}
+}
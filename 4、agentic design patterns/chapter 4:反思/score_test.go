@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ch4/fakemodel"
+)
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   float64
+		wantOK bool
+	}{
+		{"末尾评分行", "- 缺少文档字符串\nScore: 7", 7, true},
+		{"小写和全角冒号", "score：8", 8, true},
+		{"中文加粗带满分", "**评分**：6/10", 6, true},
+		{"小数", "得分 = 7.5", 7.5, true},
+		{"取最后一个", "上一版 Score: 5\n本版 Score: 9", 9, true},
+		{"JSON 优先", `{"score": 8} 以及 Score: 3`, 8, true},
+		{"JSON 字符串形式", `{"score": "10"}`, 10, true},
+		{"超出范围", "Score: 11", 0, false},
+		{"零分", "Score: 0", 0, false},
+		{"没有评分", "CODE_IS_PERFECT", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseScore(tt.text)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseScore(%q) = %v, %v，want %v, %v", tt.text, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPlateaued(t *testing.T) {
+	tests := []struct {
+		scores []float64
+		want   bool
+	}{
+		{[]float64{5, 6}, false},
+		{[]float64{5, 6, 7}, false},
+		{[]float64{5, 5.5, 5.5}, true},
+		{[]float64{3, 7, 7.5, 7.8}, true},
+	}
+	for _, tt := range tests {
+		if got := plateaued(tt.scores, 1); got != tt.want {
+			t.Errorf("plateaued(%v) = %v，want %v", tt.scores, got, tt.want)
+		}
+	}
+}
+
+func TestFormatScores(t *testing.T) {
+	if got := formatScores([]float64{6, 7.5, 9}); got != "6 → 7.5 → 9" {
+		t.Errorf("formatScores = %q", got)
+	}
+}
+
+func TestStopStatusString(t *testing.T) {
+	if got := StatusThreshold.String(); got != "评分达到阈值" {
+		t.Errorf("StatusThreshold = %q", got)
+	}
+	if got := StopStatus("other").String(); got != "未知状态（other）" {
+		t.Errorf("未知状态 = %q", got)
+	}
+}
+
+func TestScoreStops(t *testing.T) {
+	tests := []struct {
+		name       string
+		replies    []string
+		threshold  float64
+		wantStatus StopStatus
+		wantScores []float64
+	}{
+		{"达到阈值", []string{"v1", "- 问题\nScore: 6", "v2", "- 小问题\nScore: 8"}, 8, StatusThreshold, []float64{6, 8}},
+		{"评分停滞", []string{"v1", "- a\nScore: 5", "v2", "- b\nScore: 5.5", "v3", "- c\nScore: 5.8"}, 9, StatusPlateau, []float64{5, 5.5, 5.8}},
+		{"停止标记优先于评分", []string{"v1", "CODE_IS_PERFECT\nScore: 3"}, 9, StatusPassed, []float64{3}},
+		{"没有评分时不触发评分停止", []string{"v1", "- a", "v2", "- b", "v3", "- c"}, 9, StatusMaxIterations, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := fakemodel.New(tt.replies...)
+			result, err := newTestRunner(t, llm, ReflectionConfig{TaskPrompt: "任务", ScoreThreshold: tt.threshold}).Run(context.Background())
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("状态 = %s，want %s", result.Status, tt.wantStatus)
+			}
+			if formatScores(result.Scores) != formatScores(tt.wantScores) {
+				t.Errorf("评分轨迹 = %v，want %v", result.Scores, tt.wantScores)
+			}
+			if llm.Remaining() != 0 {
+				t.Errorf("还有 %d 个响应没有用到", llm.Remaining())
+			}
+		})
+	}
+}
+
+// TestScoreInstruction: 启用评分时审查者系统提示词末尾要求给出评分
+func TestScoreInstruction(t *testing.T) {
+	llm := fakemodel.New("v1", "CODE_IS_PERFECT")
+	if _, err := newTestRunner(t, llm, ReflectionConfig{TaskPrompt: "任务", ScoreThreshold: 8}).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	system := llm.Calls()[1][0].Content
+	if !strings.HasSuffix(system, strings.TrimLeft(scoreInstruction, "\n")) {
+		t.Errorf("审查者系统提示词没有评分要求:\n%s", system)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// defaultPreviewInterval: 实时预览两次刷新之间的最短间隔，避免每个分块都写一次终端
+const defaultPreviewInterval = 100 * time.Millisecond
+
+// previewWriter: 节流的实时预览：分块先攒在 pending 中，距上次刷新超过 interval 才写出
+type previewWriter struct {
+	w        io.Writer
+	interval time.Duration
+	now      func() time.Time // now: 当前时间，测试时可替换
+	last     time.Time
+	pending  strings.Builder
+}
+
+func newPreviewWriter(w io.Writer, interval time.Duration) *previewWriter {
+	return &previewWriter{w: w, interval: interval, now: time.Now}
+}
+
+// write: 追加一个分块，到了刷新时间就写出累积的内容
+func (p *previewWriter) write(chunk string) {
+	p.pending.WriteString(chunk)
+	if now := p.now(); now.Sub(p.last) >= p.interval {
+		p.flush()
+		p.last = now
+	}
+}
+
+// flush: 写出尚未写出的内容
+func (p *previewWriter) flush() {
+	if p.pending.Len() == 0 {
+		return
+	}
+	fmt.Fprint(p.w, p.pending.String())
+	p.pending.Reset()
+}
+
+// streamGenerate: 流式调用生成链，分块实时写入 preview 并拼接为完整内容
+// 模型不支持流式（Stream 直接返回错误或第一个分块就出错）时退回 Invoke，fellBack 为 true
+func (r *ReflectionRunner) streamGenerate(ctx context.Context, history []*schema.Message, preview *previewWriter) (code string, fellBack bool, err error) {
+	chunks, err := r.generate.Stream(ctx, history)
+	if err != nil {
+		code, err = r.generate.Invoke(ctx, history)
+		return code, true, err
+	}
+	defer chunks.Close()
+	var sb strings.Builder
+	received := false
+	for {
+		chunk, err := chunks.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if !received {
+				code, err = r.generate.Invoke(ctx, history)
+				return code, true, err
+			}
+			preview.flush()
+			return sb.String(), false, fmt.Errorf("流式生成中断: %w", err)
+		}
+		received = true
+		sb.WriteString(chunk)
+		preview.write(chunk)
+	}
+	preview.flush()
+	return sb.String(), false, nil
+}
+
+// generateCode: 调用生成链并打印这一版代码：启用实时预览时边生成边输出，否则生成完成后一次性打印
+func (r *ReflectionRunner) generateCode(ctx context.Context, iteration int, history []*schema.Message) (string, error) {
+	header := fmt.Sprintf("\n--- 生成的代码 (v%d) ---\n", iteration)
+	if !r.cfg.StreamPreview {
+		code, err := r.generate.Invoke(ctx, history)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(r.log, "%s%s\n", header, code)
+		return code, nil
+	}
+	fmt.Fprint(r.log, header)
+	code, fellBack, err := r.streamGenerate(ctx, history, newPreviewWriter(r.log, defaultPreviewInterval))
+	if err != nil {
+		fmt.Fprintln(r.log)
+		return "", err
+	}
+	if fellBack {
+		fmt.Fprintf(r.log, "（模型不支持流式输出，已改为一次性生成）\n%s\n", code)
+	} else {
+		fmt.Fprintln(r.log)
+	}
+	return code, nil
+}
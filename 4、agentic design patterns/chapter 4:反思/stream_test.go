@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ch4/fakemodel"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const streamedCode = "def add(a, b):\n    return a + b\n"
+
+// streamCountingModel: 记录 Stream 调用次数的假模型，用来确认是否走了流式路径
+type streamCountingModel struct {
+	*fakemodel.Model
+	streams atomic.Int32
+}
+
+func (m *streamCountingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.streams.Add(1)
+	return m.Model.Stream(ctx, input, opts...)
+}
+
+func TestStreamGenerate(t *testing.T) {
+	history := []*schema.Message{schema.UserMessage("写一个加法函数")}
+	llm := &fakemodel.Model{ChunkSize: 4}
+	llm.Push(streamedCode, streamedCode)
+	runner := newTestRunner(t, llm, ReflectionConfig{})
+
+	var preview bytes.Buffer
+	code, fellBack, err := runner.streamGenerate(context.Background(), history, newPreviewWriter(&preview, 0))
+	if err != nil || fellBack {
+		t.Fatalf("streamGenerate = %v, fellBack = %v", err, fellBack)
+	}
+	invoked, err := runner.generate.Invoke(context.Background(), history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != streamedCode || code != invoked {
+		t.Errorf("流式拼接的代码 = %q，一次性生成 = %q", code, invoked)
+	}
+	if preview.String() != streamedCode {
+		t.Errorf("预览 = %q，应逐块写出全部内容", preview.String())
+	}
+}
+
+func TestStreamGenerateFallback(t *testing.T) {
+	history := []*schema.Message{schema.UserMessage("写一个加法函数")}
+	llm := fakemodel.New().PushError(errors.New("stream not supported")).Push(streamedCode)
+	runner := newTestRunner(t, llm, ReflectionConfig{})
+
+	var preview bytes.Buffer
+	code, fellBack, err := runner.streamGenerate(context.Background(), history, newPreviewWriter(&preview, 0))
+	if err != nil || !fellBack || code != streamedCode {
+		t.Fatalf("streamGenerate = %q, fellBack = %v, %v", code, fellBack, err)
+	}
+	if preview.Len() != 0 || len(llm.Calls()) != 2 {
+		t.Errorf("退回一次性生成时不应预览，预览 = %q，调用 %d 次", preview.String(), len(llm.Calls()))
+	}
+
+	// 退回后一次性生成也失败时返回错误
+	llm = fakemodel.New().PushError(errors.New("stream not supported")).PushError(errors.New("401 unauthorized"))
+	if _, fellBack, err := newTestRunner(t, llm, ReflectionConfig{}).streamGenerate(context.Background(), history, newPreviewWriter(&preview, 0)); err == nil || !fellBack {
+		t.Errorf("一次性生成失败时 = %v, fellBack = %v", err, fellBack)
+	}
+}
+
+func TestGenerateCodePreview(t *testing.T) {
+	history := []*schema.Message{schema.UserMessage("写一个加法函数")}
+	for _, quiet := range []bool{false, true} {
+		llm := &streamCountingModel{Model: &fakemodel.Model{ChunkSize: 3}}
+		llm.Push(streamedCode)
+		var log bytes.Buffer
+		runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{StreamPreview: !quiet, Log: &log})
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := runner.generateCode(context.Background(), 1, history)
+		if err != nil || code != streamedCode {
+			t.Fatalf("quiet=%v: generateCode = %q, %v", quiet, code, err)
+		}
+		if want := "\n--- 生成的代码 (v1) ---\n" + streamedCode + "\n"; log.String() != want {
+			t.Errorf("quiet=%v: 输出 = %q，want %q", quiet, log.String(), want)
+		}
+		if streams := llm.streams.Load(); (streams == 0) != quiet {
+			t.Errorf("quiet=%v: 调用 Stream %d 次", quiet, streams)
+		}
+	}
+
+	// 模型不支持流式时提示已退回
+	llm := fakemodel.New().PushError(errors.New("stream not supported")).Push(streamedCode)
+	var log bytes.Buffer
+	runner, err := NewReflectionRunner(context.Background(), llm, ReflectionConfig{StreamPreview: true, Log: &log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.generateCode(context.Background(), 2, history); err != nil || !strings.Contains(log.String(), "（模型不支持流式输出，已改为一次性生成）\n"+streamedCode) {
+		t.Errorf("退回时的输出 = %q, %v", log.String(), err)
+	}
+}
+
+func TestPreviewWriterThrottles(t *testing.T) {
+	var out bytes.Buffer
+	now := time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC)
+	p := newPreviewWriter(&out, 100*time.Millisecond)
+	p.now = func() time.Time { return now }
+
+	p.write("a")
+	now = now.Add(30 * time.Millisecond)
+	p.write("b")
+	if out.String() != "a" {
+		t.Errorf("间隔内不应刷新，输出 = %q", out.String())
+	}
+	now = now.Add(80 * time.Millisecond)
+	p.write("c")
+	p.write("d")
+	if out.String() != "abc" {
+		t.Errorf("超过间隔后应写出累积的内容，输出 = %q", out.String())
+	}
+	p.flush()
+	if out.String() != "abcd" {
+		t.Errorf("flush 后输出 = %q", out.String())
+	}
+}
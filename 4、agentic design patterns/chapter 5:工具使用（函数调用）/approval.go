@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// defaultDangerousTools: 默认需要人工确认的工具：写文件有副作用，搜索可能调用付费接口
+var defaultDangerousTools = []string{"write_file", "web_search"}
+
+// Prompter: 向用户确认一次工具调用，返回是否批准
+type Prompter interface {
+	Confirm(ctx context.Context, toolName, argumentsInJSON string) (bool, error)
+}
+
+// StdinPrompter: 在终端打印待执行的工具调用，从标准输入读取 y/n
+type StdinPrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewStdinPrompter: 从 os.Stdin 读取、向 os.Stdout 提示的 Prompter
+func NewStdinPrompter() *StdinPrompter {
+	return &StdinPrompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+}
+
+func (p *StdinPrompter) Confirm(ctx context.Context, toolName, argumentsInJSON string) (bool, error) {
+	fmt.Fprintf(p.out, "\n--- ✋ 待确认的工具调用：%s，参数：%s ---\n是否执行？[y/N] ", toolName, argumentsInJSON)
+	line, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return false, fmt.Errorf("读取确认输入失败（非交互运行请使用 -auto-approve）: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes", "是":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// ApprovalRecord: 一次确认的记录
+type ApprovalRecord struct {
+	Time      time.Time
+	Tool      string
+	Arguments string
+	Approved  bool
+	Auto      bool // Auto: 由 -auto-approve 自动批准
+}
+
+// ApprovalGate: 危险工具调用的审批入口，记录本次运行的所有确认结果；并发的调用逐个确认
+type ApprovalGate struct {
+	mu          sync.Mutex
+	prompter    Prompter
+	autoApprove bool
+	records     []ApprovalRecord
+}
+
+// NewApprovalGate: autoApprove 为 true 时不询问、全部批准（用于非交互运行），但仍然记录
+func NewApprovalGate(prompter Prompter, autoApprove bool) *ApprovalGate {
+	return &ApprovalGate{prompter: prompter, autoApprove: autoApprove}
+}
+
+// approve: 询问并记录一次工具调用是否批准；读取输入失败视为拒绝
+func (g *ApprovalGate) approve(ctx context.Context, toolName, argumentsInJSON string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	record := ApprovalRecord{Time: time.Now(), Tool: toolName, Arguments: argumentsInJSON, Approved: true, Auto: g.autoApprove}
+	if !g.autoApprove {
+		approved, err := g.prompter.Confirm(ctx, toolName, argumentsInJSON)
+		if err != nil {
+			fmt.Printf("⚠ %v，按拒绝处理\n", err)
+		}
+		record.Approved = approved && err == nil
+	}
+	g.records = append(g.records, record)
+	return record.Approved
+}
+
+// Records: 本次运行的确认记录（按时间顺序）
+func (g *ApprovalGate) Records() []ApprovalRecord {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]ApprovalRecord(nil), g.records...)
+}
+
+// PrintLog: 打印本次运行的确认记录
+func (g *ApprovalGate) PrintLog(w io.Writer) {
+	records := g.Records()
+	if len(records) == 0 {
+		fmt.Fprintln(w, "本次运行没有需要确认的工具调用")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "时间\t工具\t结果\t参数")
+	for _, r := range records {
+		decision := "拒绝"
+		switch {
+		case r.Auto:
+			decision = "自动批准"
+		case r.Approved:
+			decision = "批准"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Time.Format("15:04:05"), r.Tool, decision, r.Arguments)
+	}
+	tw.Flush()
+}
+
+// ApprovalTool: 执行前需要人工确认的工具装饰器；被拒绝时把"用户拒绝"的说明（而不是 error）交给模型，模型可以据此调整方案
+type ApprovalTool struct {
+	inner tool.InvokableTool
+	name  string
+	gate  *ApprovalGate
+}
+
+var _ tool.InvokableTool = (*ApprovalTool)(nil)
+
+func (t *ApprovalTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.inner.Info(ctx)
+}
+
+func (t *ApprovalTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if !t.gate.approve(ctx, t.name, argumentsInJSON) {
+		msg := fmt.Sprintf("用户拒绝了本次工具调用（%s），未执行；请调整方案，或询问用户希望如何继续", t.name)
+		fmt.Printf("--- 🚫 %s ---\n", msg)
+		return msg, nil
+	}
+	return t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+// applyApproval: 给 dangerous 列出的可调用工具加上人工确认，其他工具原样保留
+// 应最后调用（最外层）：每次工具调用只确认一次，重试不会再次询问
+func applyApproval(ctx context.Context, tools []tool.BaseTool, gate *ApprovalGate, dangerous []string) ([]tool.BaseTool, error) {
+	needsApproval := make(map[string]bool, len(dangerous))
+	for _, name := range dangerous {
+		needsApproval[strings.TrimSpace(name)] = true
+	}
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = t
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		if needsApproval[info.Name] {
+			wrapped[i] = &ApprovalTool{inner: invokable, name: info.Name, gate: gate}
+		}
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// scriptedPrompter: 依次返回预设的确认结果，记录被询问的工具
+type scriptedPrompter struct {
+	answers []bool
+	err     error
+	asked   []string
+}
+
+func (p *scriptedPrompter) Confirm(ctx context.Context, toolName, argumentsInJSON string) (bool, error) {
+	p.asked = append(p.asked, toolName)
+	if p.err != nil {
+		return false, p.err
+	}
+	answer := p.answers[0]
+	p.answers = p.answers[1:]
+	return answer, nil
+}
+
+func TestStdinPrompter(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		{"y\n", true, false},
+		{" YES \n", true, false},
+		{"是\n", true, false},
+		{"n\n", false, false},
+		{"\n", false, false},
+		{"随便\n", false, false},
+		{"y", true, false}, // 最后一行没有换行
+		{"", false, true},  // 标准输入已关闭
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		p := &StdinPrompter{in: bufio.NewReader(strings.NewReader(tt.input)), out: &out}
+		got, err := p.Confirm(context.Background(), "write_file", `{"path":"a.txt"}`)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("输入 %q: Confirm = %v, %v", tt.input, got, err)
+		}
+		if !strings.Contains(out.String(), `write_file，参数：{"path":"a.txt"}`) {
+			t.Errorf("提示 = %q", out.String())
+		}
+	}
+}
+
+func TestApprovalTool(t *testing.T) {
+	prompter := &scriptedPrompter{answers: []bool{false, true}}
+	gate := NewApprovalGate(prompter, false)
+	inner := &funcTool{name: "write_file", run: func(ctx context.Context, args string) (string, error) { return "已写入", nil }}
+	wrapped := &ApprovalTool{inner: inner, name: "write_file", gate: gate}
+
+	got, err := wrapped.InvokableRun(context.Background(), `{"path":"a"}`)
+	if err != nil || !strings.HasPrefix(got, "用户拒绝了本次工具调用（write_file）") || inner.calls.Load() != 0 {
+		t.Errorf("拒绝时 = %q, %v，调用 %d 次", got, err, inner.calls.Load())
+	}
+	if got, err := wrapped.InvokableRun(context.Background(), `{"path":"b"}`); err != nil || got != "已写入" {
+		t.Errorf("批准时 = %q, %v", got, err)
+	}
+	records := gate.Records()
+	if len(records) != 2 || records[0].Approved || !records[1].Approved || records[1].Arguments != `{"path":"b"}` {
+		t.Errorf("确认记录 = %+v", records)
+	}
+}
+
+// TestApprovalGateInputError: 读取输入失败按拒绝处理
+func TestApprovalGateInputError(t *testing.T) {
+	gate := NewApprovalGate(&scriptedPrompter{err: errors.New("EOF")}, false)
+	if gate.approve(context.Background(), "web_search", "{}") {
+		t.Errorf("读取失败时应拒绝")
+	}
+	if r := gate.Records(); len(r) != 1 || r[0].Approved {
+		t.Errorf("确认记录 = %+v", r)
+	}
+}
+
+func TestApprovalGateAutoApprove(t *testing.T) {
+	prompter := &scriptedPrompter{}
+	gate := NewApprovalGate(prompter, true)
+	if !gate.approve(context.Background(), "write_file", "{}") || len(prompter.asked) != 0 {
+		t.Errorf("自动批准时不应询问")
+	}
+	var out bytes.Buffer
+	gate.PrintLog(&out)
+	if !strings.Contains(out.String(), "write_file  自动批准") {
+		t.Errorf("确认记录 =\n%s", out.String())
+	}
+}
+
+func TestApprovalGatePrintLogEmpty(t *testing.T) {
+	var out bytes.Buffer
+	NewApprovalGate(&scriptedPrompter{}, false).PrintLog(&out)
+	if !strings.Contains(out.String(), "没有需要确认的工具调用") {
+		t.Errorf("PrintLog = %q", out.String())
+	}
+}
+
+func TestApplyApproval(t *testing.T) {
+	prompter := &scriptedPrompter{answers: []bool{true}}
+	tools := []tool.BaseTool{
+		&funcTool{name: "calculator", run: func(ctx context.Context, args string) (string, error) { return "1", nil }},
+		&funcTool{name: "write_file", run: func(ctx context.Context, args string) (string, error) { return "ok", nil }},
+	}
+	wrapped, err := applyApproval(context.Background(), tools, NewApprovalGate(prompter, false), []string{" write_file", "web_search"})
+	if err != nil {
+		t.Fatalf("applyApproval: %v", err)
+	}
+	if wrapped[0] != tools[0] {
+		t.Errorf("calculator 不需要确认")
+	}
+	if _, ok := wrapped[1].(*ApprovalTool); !ok {
+		t.Fatalf("write_file 应需要确认")
+	}
+	for _, w := range wrapped {
+		if _, err := w.(tool.InvokableTool).InvokableRun(context.Background(), "{}"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(prompter.asked, ",") != "write_file" {
+		t.Errorf("询问了 %v", prompter.asked)
+	}
+}
@@ -0,0 +1,316 @@
+// Package audit: 可复用的工具调用审计组件
+//
+// Logger.Handler 返回一个 eino 回调处理器，注册到 Agent（compose.WithCallbacks）后记录每次工具调用的
+// 工具名、参数（敏感字段脱敏）、结果大小、耗时、重试次数和错误：每条记录追加到 JSONL 文件，同时累计按工具的统计；
+// 同一个处理器还从模型回调中累计本次运行的 token 用量。运行结束后用 PrintSummary 打印汇总表，
+// 或用 Report 取得可序列化为 JSON 的汇总。包只依赖 eino，其他章节可以原样复制使用。
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	template "github.com/cloudwego/eino/utils/callbacks"
+)
+
+// redacted: 敏感字段脱敏后的取值
+const redacted = "***"
+
+// Entry: 一次工具调用的审计记录（JSONL 的一行）
+type Entry struct {
+	Time        time.Time       `json:"time"`  // Time: 调用结束的时间
+	Start       time.Time       `json:"start"` // Start: 开始执行的时间（见 MarkStarted），并行的调用时间区间互相重叠
+	Tool        string          `json:"tool"`
+	Arguments   json.RawMessage `json:"arguments"` // Arguments: 脱敏后的参数；不是合法 JSON 时记录为字符串
+	ResultBytes int             `json:"result_bytes"`
+	LatencyMS   int64           `json:"latency_ms"`
+	Cached      bool            `json:"cached,omitempty"`  // Cached: 结果来自缓存，没有真正调用工具（见 MarkCached）
+	Retries     int             `json:"retries,omitempty"` // Retries: 第一次之后的重试次数（见 MarkRetry）
+	Error       string          `json:"error,omitempty"`
+}
+
+// ToolStats: 一个工具在本次运行中的累计统计
+type ToolStats struct {
+	Calls       int
+	Errors      int
+	Latency     time.Duration // Latency: 累计耗时
+	ResultBytes int           // ResultBytes: 累计结果字节数
+	CacheHits   int           // CacheHits: 命中缓存的调用次数
+	Retries     int           // Retries: 累计重试次数
+}
+
+// callState: OnStart 存进上下文、OnEnd / OnError 取出的调用信息
+type callState struct {
+	start   time.Time
+	now     func() time.Time
+	running atomic.Int64 // running: MarkStarted 记录的实际开始执行的时间（UnixNano），0 表示未标记
+	args    string
+	cached  atomic.Bool  // cached: 工具包装器通过 MarkCached 标记结果来自缓存
+	retries atomic.Int32 // retries: 工具包装器通过 MarkRetry 累计的重试次数
+	failure atomic.Value // failure: 工具包装器通过 MarkError 记录的、已转为工具结果的错误（string）
+}
+
+type callStateKey struct{}
+
+// MarkCached: 在工具实现（或缓存包装器）中调用，把本次调用的审计记录标记为缓存命中；ctx 不来自审计回调时什么也不做
+func MarkCached(ctx context.Context) {
+	if state, ok := ctx.Value(callStateKey{}).(*callState); ok {
+		state.cached.Store(true)
+	}
+}
+
+// MarkRetry: 在重试包装器中每次重试前调用，本次调用的审计记录的重试次数加一；ctx 不来自审计回调时什么也不做
+func MarkRetry(ctx context.Context) {
+	if state, ok := ctx.Value(callStateKey{}).(*callState); ok {
+		state.retries.Add(1)
+	}
+}
+
+// MarkError: 工具包装器把 err 转为结果交给模型时调用，本次调用仍记为失败；ctx 不来自审计回调时什么也不做
+func MarkError(ctx context.Context, err error) {
+	if state, ok := ctx.Value(callStateKey{}).(*callState); ok && err != nil {
+		state.failure.Store(err.Error())
+	}
+}
+
+// MarkStarted: 在并发限制包装器拿到执行名额后调用，本次调用的开始时间和耗时从此刻算起（不含排队等待）；
+// ctx 不来自审计回调时什么也不做
+func MarkStarted(ctx context.Context) {
+	if state, ok := ctx.Value(callStateKey{}).(*callState); ok {
+		state.running.Store(state.now().UnixNano())
+	}
+}
+
+// Logger: 工具调用审计器，并发安全（并行的工具调用各自记录）
+type Logger struct {
+	mu     sync.Mutex
+	out    io.WriteCloser // out: JSONL 输出，为空时只统计不落盘
+	redact map[string]bool
+	stats  map[string]*ToolStats
+	order  []string // order: 工具首次被调用的顺序，汇总表按此排列
+	now    func() time.Time
+
+	modelCalls int            // modelCalls: 模型调用次数
+	tokens     TokenUsage     // tokens: 累计 token 用量
+	pending    sync.WaitGroup // pending: 尚未读完的流式模型输出（用量在流结束时才知道）
+	paths      map[string]int // paths: 每种回答路径服务的查询数，由 RecordQuery 记录
+}
+
+// New: 创建审计器，path 为空时不写文件；redactKeys 中的参数名（不区分大小写，任意嵌套层级）记录为 ***
+func New(path string, redactKeys []string) (*Logger, error) {
+	l := &Logger{redact: map[string]bool{}, stats: map[string]*ToolStats{}, now: time.Now}
+	for _, key := range redactKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			l.redact[strings.ToLower(key)] = true
+		}
+	}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("打开审计日志失败: %w", err)
+		}
+		l.out = f
+	}
+	return l, nil
+}
+
+// Close: 关闭审计日志文件
+func (l *Logger) Close() error {
+	if l.out == nil {
+		return nil
+	}
+	return l.out.Close()
+}
+
+// Handler: 记录工具调用和模型 token 用量的回调处理器（只关心工具和模型组件）
+func (l *Logger) Handler() callbacks.Handler {
+	return template.NewHandlerHelper().ChatModel(&template.ModelCallbackHandler{
+		OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *model.CallbackOutput) context.Context {
+			l.recordModel(tokenUsage(output))
+			return ctx
+		},
+		OnEndWithStreamOutput: func(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[*model.CallbackOutput]) context.Context {
+			l.pending.Add(1)
+			go func() {
+				defer l.pending.Done()
+				defer output.Close()
+				// 用量通常只在最后一个分块中；按累计值处理，取最后出现的一次
+				var last *TokenUsage
+				for {
+					chunk, err := output.Recv()
+					if err != nil {
+						break
+					}
+					if usage := tokenUsage(chunk); usage != nil {
+						last = usage
+					}
+				}
+				l.recordModel(last)
+			}()
+			return ctx
+		},
+	}).Tool(&template.ToolCallbackHandler{
+		OnStart: func(ctx context.Context, info *callbacks.RunInfo, input *tool.CallbackInput) context.Context {
+			state := &callState{start: l.now(), now: l.now}
+			if input != nil {
+				state.args = input.ArgumentsInJSON
+			}
+			return context.WithValue(ctx, callStateKey{}, state)
+		},
+		OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *tool.CallbackOutput) context.Context {
+			var result string
+			if output != nil {
+				result = output.Response
+			}
+			l.record(ctx, info, result, nil)
+			return ctx
+		},
+		OnError: func(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+			l.record(ctx, info, "", err)
+			return ctx
+		},
+	}).Handler()
+}
+
+// record: 追加一条审计记录并更新统计
+func (l *Logger) record(ctx context.Context, info *callbacks.RunInfo, result string, err error) {
+	entry := Entry{Time: l.now(), ResultBytes: len(result)}
+	var latency time.Duration
+	if info != nil {
+		entry.Tool = info.Name
+	}
+	if state, ok := ctx.Value(callStateKey{}).(*callState); ok {
+		entry.Start = state.start
+		if running := state.running.Load(); running != 0 {
+			entry.Start = time.Unix(0, running)
+		}
+		entry.Arguments = l.redactArguments(state.args)
+		latency = entry.Time.Sub(entry.Start)
+		entry.LatencyMS = latency.Milliseconds()
+		entry.Cached = state.cached.Load()
+		entry.Retries = int(state.retries.Load())
+		if failure, ok := state.failure.Load().(string); ok {
+			entry.Error = failure
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats, ok := l.stats[entry.Tool]
+	if !ok {
+		stats = &ToolStats{}
+		l.stats[entry.Tool] = stats
+		l.order = append(l.order, entry.Tool)
+	}
+	stats.Calls++
+	if entry.Error != "" {
+		stats.Errors++
+	}
+	stats.Latency += latency
+	stats.ResultBytes += entry.ResultBytes
+	if entry.Cached {
+		stats.CacheHits++
+	}
+	stats.Retries += entry.Retries
+
+	if l.out == nil {
+		return
+	}
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		fmt.Fprintf(os.Stderr, "⚠ 序列化审计记录失败: %v\n", marshalErr)
+		return
+	}
+	if _, writeErr := l.out.Write(append(line, '\n')); writeErr != nil {
+		fmt.Fprintf(os.Stderr, "⚠ 写入审计日志失败: %v\n", writeErr)
+	}
+}
+
+// redactArguments: 把参数中的敏感字段替换为 ***；参数不是合法 JSON 时原样记录为字符串
+func (l *Logger) redactArguments(args string) json.RawMessage {
+	var value any
+	if err := json.Unmarshal([]byte(args), &value); err != nil {
+		raw, _ := json.Marshal(args)
+		return raw
+	}
+	raw, err := json.Marshal(l.redactValue(value))
+	if err != nil {
+		raw, _ = json.Marshal(args)
+	}
+	return raw
+}
+
+// redactValue: 递归脱敏对象和数组中的敏感字段
+func (l *Logger) redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if l.redact[strings.ToLower(key)] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = l.redactValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = l.redactValue(item)
+		}
+	}
+	return value
+}
+
+// Stats: 按工具的统计快照（工具按首次调用的顺序）
+func (l *Logger) Stats() ([]string, map[string]ToolStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	snapshot := make(map[string]ToolStats, len(l.stats))
+	for name, stats := range l.stats {
+		snapshot[name] = *stats
+	}
+	return append([]string(nil), l.order...), snapshot
+}
+
+// PrintSummary: 打印按工具的调用次数、成功率、失败次数、缓存命中次数、重试次数、平均耗时和结果字节数，以及模型的 token 用量
+func (l *Logger) PrintSummary(w io.Writer) {
+	report := l.Report()
+	if len(report.Tools) == 0 {
+		fmt.Fprintln(w, "本次运行没有调用任何工具")
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "工具\t调用\t成功率\t失败\t缓存命中\t重试\t平均耗时\t结果字节")
+		for _, r := range append(report.Tools, report.Total) {
+			fmt.Fprintf(tw, "%s\t%d\t%.0f%%\t%d\t%d\t%d\t%s\t%d\n", r.Tool, r.Calls, r.SuccessRate*100, r.Errors, r.CacheHits, r.Retries,
+				time.Duration(r.AvgLatencyMS*float64(time.Millisecond)).Round(time.Millisecond), r.ResultBytes)
+		}
+		tw.Flush()
+	}
+	fmt.Fprintf(w, "模型调用 %d 次，%s\n", report.ModelCalls, report.Tokens)
+	if len(report.Paths) > 0 {
+		names := make([]string, 0, len(report.Paths))
+		for name := range report.Paths {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = fmt.Sprintf("%s %d 次", name, report.Paths[name])
+		}
+		fmt.Fprintf(w, "查询路径：%s\n", strings.Join(parts, "，"))
+	}
+}
@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+)
+
+// TokenUsage: 模型的 token 用量
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// String: 单行用量摘要
+func (u TokenUsage) String() string {
+	return fmt.Sprintf("token：提示 %d + 生成 %d = %d", u.PromptTokens, u.CompletionTokens, u.TotalTokens)
+}
+
+// Pricing: 每百万 token 的价格（单位由调用方约定，如美元）
+type Pricing struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+// Cost: 按价格估算的费用
+func (u TokenUsage) Cost(p Pricing) float64 {
+	return (float64(u.PromptTokens)*p.PromptPerMillion + float64(u.CompletionTokens)*p.CompletionPerMillion) / 1e6
+}
+
+// tokenUsage: 从模型回调输出中读取用量：优先使用回调中的 TokenUsage，没有时读消息的 ResponseMeta；都没有时返回 nil
+func tokenUsage(output *model.CallbackOutput) *TokenUsage {
+	if output == nil {
+		return nil
+	}
+	if u := output.TokenUsage; u != nil {
+		return &TokenUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+	}
+	if msg := output.Message; msg != nil && msg.ResponseMeta != nil && msg.ResponseMeta.Usage != nil {
+		u := msg.ResponseMeta.Usage
+		return &TokenUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+	}
+	return nil
+}
+
+// recordModel: 累计一次模型调用，usage 为空表示模型没有返回用量
+func (l *Logger) recordModel(usage *TokenUsage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.modelCalls++
+	if usage == nil {
+		return
+	}
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	l.tokens.PromptTokens += usage.PromptTokens
+	l.tokens.CompletionTokens += usage.CompletionTokens
+	l.tokens.TotalTokens += total
+}
+
+// ToolReport: 一个工具（或全部工具合计）的汇总
+type ToolReport struct {
+	Tool         string  `json:"tool"`
+	Calls        int     `json:"calls"`
+	Errors       int     `json:"errors"`
+	SuccessRate  float64 `json:"success_rate"` // SuccessRate: 没有出错的调用占比（0~1，转为结果交给模型的错误也算出错），没有调用时为 0
+	AvgLatencyMS float64 `json:"avg_latency_ms"`
+	CacheHits    int     `json:"cache_hits"`
+	Retries      int     `json:"retries"`
+	ResultBytes  int     `json:"result_bytes"`
+}
+
+// Report: 本次运行的汇总：按工具的统计（按首次调用的顺序）、合计和模型 token 用量
+type Report struct {
+	Tools         []ToolReport   `json:"tools"`
+	Total         ToolReport     `json:"total"`
+	ModelCalls    int            `json:"model_calls"`
+	Tokens        TokenUsage     `json:"tokens"`
+	Paths         map[string]int `json:"paths,omitempty"`          // Paths: 每种回答路径服务的查询数
+	EstimatedCost float64        `json:"estimated_cost,omitempty"` // EstimatedCost: 由调用方按 Pricing 填写
+}
+
+// RecordQuery: 记录一个查询由哪条路径回答（如 agent 或 direct），模型调用和 token 用量不区分路径，均计入合计
+func (l *Logger) RecordQuery(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.paths == nil {
+		l.paths = map[string]int{}
+	}
+	l.paths[path]++
+}
+
+// Report: 汇总当前的统计；会等待尚未读完的流式模型输出，以免漏掉最后的用量
+func (l *Logger) Report() Report {
+	l.pending.Wait()
+	order, stats := l.Stats()
+	report := Report{Tools: make([]ToolReport, 0, len(order))}
+	var total ToolStats
+	for _, name := range order {
+		s := stats[name]
+		report.Tools = append(report.Tools, toolReport(name, s))
+		total.Calls += s.Calls
+		total.Errors += s.Errors
+		total.CacheHits += s.CacheHits
+		total.Retries += s.Retries
+		total.Latency += s.Latency
+		total.ResultBytes += s.ResultBytes
+	}
+	report.Total = toolReport("总计", total)
+	l.mu.Lock()
+	report.ModelCalls, report.Tokens = l.modelCalls, l.tokens
+	if len(l.paths) > 0 {
+		report.Paths = maps.Clone(l.paths)
+	}
+	l.mu.Unlock()
+	return report
+}
+
+// toolReport: 由累计统计计算成功率和平均耗时
+func toolReport(name string, s ToolStats) ToolReport {
+	r := ToolReport{Tool: name, Calls: s.Calls, Errors: s.Errors, CacheHits: s.CacheHits, Retries: s.Retries, ResultBytes: s.ResultBytes}
+	if s.Calls > 0 {
+		r.SuccessRate = float64(s.Calls-s.Errors) / float64(s.Calls)
+		r.AvgLatencyMS = float64(s.Latency) / float64(s.Calls) / float64(time.Millisecond)
+	}
+	return r
+}
+
+// WriteJSON: 以缩进的 JSON 写出汇总
+func (r Report) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化运行汇总失败: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
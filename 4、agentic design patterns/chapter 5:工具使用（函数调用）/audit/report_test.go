@@ -0,0 +1,224 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeClock: 每次读取前进 step 的时钟
+type fakeClock struct {
+	t    time.Time
+	step time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	c.t = c.t.Add(c.step)
+	return c.t
+}
+
+// newTestLogger: 写入临时文件、使用假时钟的审计器
+func newTestLogger(t *testing.T, redact ...string) (*Logger, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := New(path, redact)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	clock := &fakeClock{t: time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC), step: 10 * time.Millisecond}
+	l.now = clock.now
+	return l, path
+}
+
+// callTool: 通过回调处理器模拟一次工具调用，during 在 OnStart 之后、结束之前执行（可调用 Mark*）
+func callTool(l *Logger, name, args, result string, err error, during func(ctx context.Context)) {
+	h := l.Handler()
+	info := &callbacks.RunInfo{Name: name, Component: components.ComponentOfTool}
+	ctx := h.OnStart(context.Background(), info, &tool.CallbackInput{ArgumentsInJSON: args})
+	if during != nil {
+		during(ctx)
+	}
+	if err != nil {
+		h.OnError(ctx, info, err)
+		return
+	}
+	h.OnEnd(ctx, info, &tool.CallbackOutput{Response: result})
+}
+
+// readEntries: 读出审计日志中的全部记录
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("解析审计记录 %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLoggerRecordsToolCalls(t *testing.T) {
+	l, path := newTestLogger(t, "api_key", " Password ")
+	callTool(l, "web_search", `{"query":"x","API_KEY":"k","nested":[{"password":"p"}]}`, "结果", nil, nil)
+	callTool(l, "calculator", `{"a":1}`, "", errors.New("除以零错误"), nil)
+	callTool(l, "get_weather", `not json`, "转交的失败说明", nil, func(ctx context.Context) {
+		MarkRetry(ctx)
+		MarkRetry(ctx)
+		MarkError(ctx, errors.New("HTTP 503"))
+	})
+	callTool(l, "web_search", `{"query":"x"}`, "缓存的结果", nil, func(ctx context.Context) { MarkCached(ctx) })
+
+	entries := readEntries(t, path)
+	if len(entries) != 4 {
+		t.Fatalf("记录 %d 条，want 4", len(entries))
+	}
+	if got := string(entries[0].Arguments); got != `{"API_KEY":"***","nested":[{"password":"***"}],"query":"x"}` {
+		t.Errorf("脱敏后的参数 = %s", got)
+	}
+	if entries[0].ResultBytes != len("结果") || entries[0].LatencyMS != 10 {
+		t.Errorf("第 1 条 = %+v", entries[0])
+	}
+	if entries[1].Error != "除以零错误" {
+		t.Errorf("第 2 条的错误 = %q", entries[1].Error)
+	}
+	if string(entries[2].Arguments) != `"not json"` || entries[2].Retries != 2 || entries[2].Error != "HTTP 503" {
+		t.Errorf("第 3 条 = %+v", entries[2])
+	}
+	if !entries[3].Cached {
+		t.Errorf("第 4 条应标记为缓存命中")
+	}
+
+	report := l.Report()
+	if len(report.Tools) != 3 || report.Tools[0].Tool != "web_search" || report.Tools[1].Tool != "calculator" {
+		t.Fatalf("工具顺序 = %+v", report.Tools)
+	}
+	search := report.Tools[0]
+	if search.Calls != 2 || search.SuccessRate != 1 || search.CacheHits != 1 || search.AvgLatencyMS != 10 {
+		t.Errorf("web_search = %+v", search)
+	}
+	if weather := report.Tools[2]; weather.Errors != 1 || weather.SuccessRate != 0 || weather.Retries != 2 {
+		t.Errorf("get_weather = %+v", weather)
+	}
+	if total := report.Total; total.Tool != "总计" || total.Calls != 4 || total.Errors != 2 || total.SuccessRate != 0.5 {
+		t.Errorf("合计 = %+v", total)
+	}
+}
+
+// TestMarkStartedExcludesQueueing: MarkStarted 之后的时间才计入耗时
+func TestMarkStartedExcludesQueueing(t *testing.T) {
+	l, path := newTestLogger(t)
+	callTool(l, "read_file", `{}`, "", nil, func(ctx context.Context) {
+		l.now() // 排队等待
+		l.now()
+		MarkStarted(ctx)
+	})
+	if e := readEntries(t, path)[0]; e.LatencyMS != 10 || !e.Start.Equal(time.Date(2024, 3, 9, 12, 0, 0, 40_000_000, time.UTC)) {
+		t.Errorf("记录 = %+v", e)
+	}
+}
+
+func TestMarkWithoutAuditContext(t *testing.T) {
+	ctx := context.Background()
+	MarkCached(ctx)
+	MarkRetry(ctx)
+	MarkError(ctx, errors.New("x"))
+	MarkStarted(ctx)
+}
+
+func TestLoggerTokenUsage(t *testing.T) {
+	l, _ := newTestLogger(t)
+	h := l.Handler()
+	info := &callbacks.RunInfo{Name: "model", Component: components.ComponentOfChatModel}
+	h.OnEnd(context.Background(), info, &model.CallbackOutput{TokenUsage: &model.TokenUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}})
+	// 没有 TokenUsage 时读消息的 ResponseMeta，TotalTokens 为 0 时按两者之和计
+	msg := schema.AssistantMessage("hi", nil)
+	msg.ResponseMeta = &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 5}}
+	h.OnEnd(context.Background(), info, &model.CallbackOutput{Message: msg})
+	// 流式输出：取最后出现的用量
+	stream := schema.StreamReaderFromArray([]callbacks.CallbackOutput{
+		&model.CallbackOutput{TokenUsage: &model.TokenUsage{PromptTokens: 1, TotalTokens: 1}},
+		&model.CallbackOutput{Message: schema.AssistantMessage("分块", nil)},
+		&model.CallbackOutput{TokenUsage: &model.TokenUsage{PromptTokens: 30, CompletionTokens: 7, TotalTokens: 37}},
+	})
+	h.OnEndWithStreamOutput(context.Background(), info, stream)
+	// 没有用量的调用只计次数
+	h.OnEnd(context.Background(), info, &model.CallbackOutput{Message: schema.AssistantMessage("无用量", nil)})
+
+	report := l.Report()
+	want := TokenUsage{PromptTokens: 140, CompletionTokens: 32, TotalTokens: 172}
+	if report.ModelCalls != 4 || report.Tokens != want {
+		t.Errorf("模型调用 %d 次，用量 %+v，want 4 次 %+v", report.ModelCalls, report.Tokens, want)
+	}
+	if got := want.Cost(Pricing{PromptPerMillion: 2, CompletionPerMillion: 8}); got != (140*2+32*8)/1e6 {
+		t.Errorf("Cost = %v", got)
+	}
+}
+
+func TestReportOutput(t *testing.T) {
+	l, _ := newTestLogger(t)
+	var empty bytes.Buffer
+	l.PrintSummary(&empty)
+	if !strings.Contains(empty.String(), "本次运行没有调用任何工具") {
+		t.Errorf("空汇总 = %q", empty.String())
+	}
+
+	callTool(l, "calculator", `{}`, "11", nil, nil)
+	l.RecordQuery("agent")
+	l.RecordQuery("direct")
+	l.RecordQuery("agent")
+	var out bytes.Buffer
+	l.PrintSummary(&out)
+	for _, want := range []string{"calculator  1", "100%", "总计", "模型调用 0 次", "查询路径：agent 2 次，direct 1 次"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("汇总缺少 %q:\n%s", want, out.String())
+		}
+	}
+
+	var js bytes.Buffer
+	if err := l.Report().WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("解析汇总 JSON: %v", err)
+	}
+	if decoded.Total.Calls != 1 || decoded.Paths["agent"] != 2 || decoded.Tools[0].SuccessRate != 1 {
+		t.Errorf("汇总 JSON = %s", js.String())
+	}
+}
+
+func TestNewWithoutFile(t *testing.T) {
+	l, err := New("", nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	callTool(l, "calculator", `{}`, "1", nil, nil)
+	if l.Report().Total.Calls != 1 || l.Close() != nil {
+		t.Errorf("不写文件时仍应统计")
+	}
+	if _, err := New(filepath.Join(t.TempDir(), "missing", "audit.jsonl"), nil); err == nil {
+		t.Errorf("目录不存在时应返回错误")
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"ch5/audit"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	defaultCacheEntries = 256              // defaultCacheEntries: LRU 缓存的最大条目数
+	defaultCacheTTL     = 10 * time.Minute // defaultCacheTTL: 未单独配置的工具的缓存有效期
+)
+
+// defaultCacheExclude: 默认不缓存的工具：结果依赖工作区的当前状态或当前时间、每次调用应得到不同的结果，或调用本身有副作用
+var defaultCacheExclude = []string{"read_file", "write_file", "list_dir", "datetime", "random"}
+
+// resultCacheable: 工具可以实现此接口，拒绝缓存某些结果（如以字符串返回的失败说明）
+type resultCacheable interface {
+	Cacheable(result string) bool
+}
+
+// cachedResult: 一条缓存的工具结果
+type cachedResult struct {
+	Key     string    `json:"key"`
+	Result  string    `json:"result"`
+	Expires time.Time `json:"expires,omitempty"` // Expires: 过期时间，零值表示不过期
+}
+
+// toolCache: 并发安全的 LRU 缓存，可选地持久化到 JSON 文件
+type toolCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List               // order: 最近使用的在前
+	items    map[string]*list.Element // items: Key -> order 中的元素（值为 *cachedResult）
+	now      func() time.Time
+}
+
+// newToolCache: 最多 capacity 条的 LRU 缓存
+func newToolCache(capacity int) *toolCache {
+	return &toolCache{capacity: max(capacity, 1), order: list.New(), items: map[string]*list.Element{}, now: time.Now}
+}
+
+// get: 读取未过期的缓存结果，命中时移到最前；过期的条目顺便删除
+func (c *toolCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*cachedResult)
+	if !entry.Expires.IsZero() && !c.now().Before(entry.Expires) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return entry.Result, true
+}
+
+// put: 写入缓存，ttl<=0 表示不过期；超出容量时淘汰最久未使用的条目
+func (c *toolCache) put(key, result string, ttl time.Duration) {
+	entry := &cachedResult{Key: key, Result: result}
+	if ttl > 0 {
+		entry.Expires = c.now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.insert(entry)
+}
+
+// insert: put 和 load 共用的插入逻辑，调用方持有锁
+func (c *toolCache) insert(entry *cachedResult) {
+	if elem, ok := c.items[entry.Key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[entry.Key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cachedResult).Key)
+	}
+}
+
+// load: 从 JSON 文件恢复缓存（文件不存在时什么也不做），跳过已过期的条目
+func (c *toolCache) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取工具缓存失败: %w", err)
+	}
+	var entries []*cachedResult
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("解析工具缓存失败: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	// 文件中最近使用的在前，倒序插入以保持 LRU 顺序
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entry := entries[i]; entry.Expires.IsZero() || now.Before(entry.Expires) {
+			c.insert(entry)
+		}
+	}
+	return nil
+}
+
+// save: 把缓存写入 JSON 文件（最近使用的在前）
+func (c *toolCache) save(path string) error {
+	c.mu.Lock()
+	entries := make([]*cachedResult, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		entries = append(entries, elem.Value.(*cachedResult))
+	}
+	c.mu.Unlock()
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化工具缓存失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("写入工具缓存失败: %w", err)
+	}
+	return nil
+}
+
+// cacheKey: 工具名 + 规范化的参数 JSON（对象的键排序、去掉空白），参数不是合法 JSON 时使用去掉首尾空白的原文
+func cacheKey(name, argumentsInJSON string) string {
+	decoder := json.NewDecoder(strings.NewReader(argumentsInJSON))
+	decoder.UseNumber() // 保留数字原文，避免大整数经 float64 丢失精度
+	var value any
+	if err := decoder.Decode(&value); err == nil {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err == nil {
+			return name + "\x00" + strings.TrimSpace(buf.String())
+		}
+	}
+	return name + "\x00" + strings.TrimSpace(argumentsInJSON)
+}
+
+// CachedTool: 缓存幂等工具结果的装饰器：相同工具名和参数（键顺序、空白无关）在有效期内直接返回缓存的结果
+// 只缓存成功的调用；工具实现了 Cacheable 时还要它同意。命中时通过 audit.MarkCached 在审计日志中标记
+type CachedTool struct {
+	inner tool.InvokableTool
+	name  string
+	ttl   time.Duration
+	cache *toolCache
+}
+
+var _ tool.InvokableTool = (*CachedTool)(nil)
+
+func (t *CachedTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.inner.Info(ctx)
+}
+
+func (t *CachedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	key := cacheKey(t.name, argumentsInJSON)
+	if result, ok := t.cache.get(key); ok {
+		audit.MarkCached(ctx)
+		fmt.Printf("\n--- 💾 缓存命中：%s，参数：%s ---\n", t.name, argumentsInJSON)
+		return result, nil
+	}
+	result, err := t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err != nil {
+		return "", err
+	}
+	if checker, ok := t.inner.(resultCacheable); !ok || checker.Cacheable(result) {
+		t.cache.put(key, result, t.ttl)
+	}
+	return result, nil
+}
+
+// applyCache: 给不在 exclude 中的可调用工具加上缓存，有效期按工具名取 ttls，没有配置时用 fallback（0 表示不过期）
+// 应在 applyTimeouts 之前调用：超时说明不会被缓存，工具的 Cacheable 也仍然可见
+func applyCache(ctx context.Context, tools []tool.BaseTool, cache *toolCache, ttls map[string]time.Duration,
+	fallback time.Duration, exclude []string) ([]tool.BaseTool, error) {
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		excluded[strings.TrimSpace(name)] = true
+	}
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = t
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		if excluded[info.Name] {
+			continue
+		}
+		ttl, ok := ttls[info.Name]
+		if !ok {
+			ttl = fallback
+		}
+		wrapped[i] = &CachedTool{inner: invokable, name: info.Name, ttl: ttl, cache: cache}
+	}
+	return wrapped, nil
+}
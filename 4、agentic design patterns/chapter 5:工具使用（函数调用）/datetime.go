@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // 内嵌时区数据库，没有系统时区数据的环境（如 Windows、精简容器）也能解析 IANA 时区名
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// dateTimeFailure: 需要模型向用户澄清或更正输入时的说明前缀
+const dateTimeFailure = "无法完成时间计算："
+
+// dateTimeArgs: datetime 工具的参数
+type dateTimeArgs struct {
+	Operation string `json:"operation" desc:"now：查询当前时间；add_duration：在 base 上加（减）一段时长；diff：计算 start 到 end 的间隔" required:"true" enum:"now,add_duration,diff"`
+	Timezone  string `json:"timezone" desc:"IANA 时区名，如 Asia/Tokyo、America/New_York；不带时区的时间按此时区理解，结果也用此时区显示，默认本地时区"`
+	Base      string `json:"base" desc:"add_duration 的起始时间，如 2024-03-09 12:00 或 2024-03-09T12:00:00+08:00，默认当前时间"`
+	Duration  string `json:"duration" desc:"add_duration 的时长，如 90d、2h30m、-1w、1y2mo，也可以写 90天、3个月；单位：y 年、mo 月、w 周、d 天、h 小时、m 分钟、s 秒"`
+	Start     string `json:"start" desc:"diff 的起始时间，默认当前时间"`
+	End       string `json:"end" desc:"diff 的结束时间，默认当前时间"`
+}
+
+// dateTimeTool: 当前时间、日期加减和时间间隔计算
+type dateTimeTool struct {
+	now   func() time.Time // now: 当前时间，测试时可替换
+	local *time.Location   // local: 未指定 timezone 时使用的时区
+}
+
+// NewDateTimeTool: 支持时区和日期运算的时间工具
+func NewDateTimeTool() tool.InvokableTool {
+	t := &dateTimeTool{now: time.Now, local: time.Local}
+	return NewStructTool("datetime", "查询任意时区的当前时间、计算若干天/月/小时之后（之前）的时间、计算两个时间的间隔", t.run)
+}
+
+// run: 参数有歧义或无效时返回以 dateTimeFailure 开头的说明（而不是 error），模型可以据此向用户确认
+func (t *dateTimeTool) run(ctx context.Context, args dateTimeArgs) (string, error) {
+	fmt.Printf("\n--- 🛠️ 工具调用：datetime，操作：'%s'，时区：'%s' ---\n", args.Operation, args.Timezone)
+	result, err := t.evaluate(args)
+	if err != nil {
+		result = dateTimeFailure + err.Error()
+	}
+	fmt.Printf("--- 工具结果：%s ---\n", result)
+	return result, nil
+}
+
+// evaluate: 按操作计算结果
+func (t *dateTimeTool) evaluate(args dateTimeArgs) (string, error) {
+	loc, err := t.location(args.Timezone)
+	if err != nil {
+		return "", err
+	}
+	switch args.Operation {
+	case "now":
+		return "当前时间：" + formatDateTime(t.now().In(loc)), nil
+	case "add_duration":
+		base, err := t.parseTime(args.Base, loc)
+		if err != nil {
+			return "", fmt.Errorf("base %w", err)
+		}
+		span, err := parseCalendarDuration(args.Duration)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s 是 %s", formatDateTime(base), strings.TrimSpace(args.Duration), formatDateTime(span.addTo(base))), nil
+	case "diff":
+		start, err := t.parseTime(args.Start, loc)
+		if err != nil {
+			return "", fmt.Errorf("start %w", err)
+		}
+		end, err := t.parseTime(args.End, loc)
+		if err != nil {
+			return "", fmt.Errorf("end %w", err)
+		}
+		return fmt.Sprintf("从 %s 到 %s：%s", formatDateTime(start), formatDateTime(end), humanizeDuration(end.Sub(start))), nil
+	default:
+		return "", fmt.Errorf("未知操作 %q（可用：now、add_duration、diff）", args.Operation)
+	}
+}
+
+// location: 校验并加载 IANA 时区，为空时使用本地时区
+func (t *dateTimeTool) location(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return t.local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("未知的时区 %q，请使用 IANA 时区名，如 Asia/Shanghai、Asia/Tokyo、America/New_York", name)
+	}
+	return loc, nil
+}
+
+// absoluteLayouts: 自带时区偏移的时间格式
+var absoluteLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05Z07:00", "2006-01-02 15:04Z07:00"}
+
+// wallClockLayouts: 不带时区的时间格式，按 timezone 参数理解
+var wallClockLayouts = []string{
+	"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02",
+	"2006/01/02 15:04:05", "2006/01/02 15:04", "2006/01/02",
+	"2006年1月2日 15:04:05", "2006年1月2日 15:04", "2006年1月2日",
+}
+
+// slashDate: 日/月顺序不确定的 "01/02/2024" 形式
+var slashDate = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/(\d{4})(?:\s+(\d{1,2}:\d{2}(?::\d{2})?))?$`)
+
+// parseTime: 解析常见的时间格式；空值、now、现在表示当前时间；Unix 时间戳（秒）也可以
+func (t *dateTimeTool) parseTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "", "now", "现在":
+		return t.now().In(loc), nil
+	}
+	for _, layout := range absoluteLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.In(loc), nil
+		}
+	}
+	if m := slashDate.FindStringSubmatch(value); m != nil {
+		first, _ := strconv.Atoi(m[1])
+		second, _ := strconv.Atoi(m[2])
+		if first <= 12 && second <= 12 && first != second {
+			return time.Time{}, fmt.Errorf("%q 有歧义：可能是 %s年%d月%d日，也可能是 %s年%d月%d日，请改用 YYYY-MM-DD 格式",
+				value, m[3], first, second, m[3], second, first)
+		}
+		month, day := first, second // 默认按 月/日/年
+		if first > 12 {
+			month, day = second, first
+		}
+		value = fmt.Sprintf("%s-%02d-%02d", m[3], month, day)
+		if m[4] != "" {
+			value += " " + m[4]
+		}
+	}
+	for _, layout := range wallClockLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return wallClockIn(parsed, loc)
+		}
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && len(value) >= 9 {
+		return time.Unix(seconds, 0).In(loc), nil
+	}
+	return time.Time{}, fmt.Errorf("%q 不是可识别的时间，请使用 2024-03-09 12:00 或 2024-03-09T12:00:00+08:00 这样的格式", value)
+}
+
+// wallClockIn: 把不带时区的时间（解析结果为 UTC）理解为 loc 中的本地时间
+// 夏令时切换时，有的本地时间不存在（拨快的那一小时），有的出现两次（拨慢的那一小时），这两种情况都要求澄清
+func wallClockIn(wall time.Time, loc *time.Location) (time.Time, error) {
+	guess := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+	// 用前后各 3 小时的 UTC 偏移推算候选时刻，再检查它们在 loc 中的本地时间是否就是输入
+	var matches []time.Time
+	for _, probe := range []time.Time{guess.Add(-3 * time.Hour), guess.Add(3 * time.Hour)} {
+		_, offset := probe.Zone()
+		candidate := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if candidate.Format(time.DateTime) == wall.Format(time.DateTime) &&
+			(len(matches) == 0 || !matches[0].Equal(candidate)) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return time.Time{}, fmt.Errorf("%s 在 %s 不存在（夏令时拨快跳过了这段时间），请确认具体时间", wall.Format(time.DateTime), loc)
+	case 1:
+		return matches[0], nil
+	default:
+		return time.Time{}, fmt.Errorf("%s 在 %s 出现两次（夏令时拨慢），可能是 %s 或 %s，请带上 UTC 偏移",
+			wall.Format(time.DateTime), loc, matches[0].Format("15:04 -07:00"), matches[1].Format("15:04 -07:00"))
+	}
+}
+
+// calendarDuration: 日历时长：年、月、天按日历（本地时间）加，时、分、秒按实际经过的时间加
+type calendarDuration struct {
+	years, months, days int
+	clock               time.Duration
+}
+
+// addTo: 先按日历加年月日（跨夏令时切换时保持本地时刻不变），再加时分秒
+func (d calendarDuration) addTo(t time.Time) time.Time {
+	return t.AddDate(d.years, d.months, d.days).Add(d.clock)
+}
+
+// durationUnits: 时长单位及其写法
+var durationUnits = []struct {
+	names []string
+	apply func(d *calendarDuration, n float64) bool // apply: 返回 false 表示该单位不接受小数
+}{
+	{[]string{"years", "year", "y", "年"}, func(d *calendarDuration, n float64) bool { d.years += int(n); return n == float64(int(n)) }},
+	{[]string{"months", "month", "mo", "个月", "月"}, func(d *calendarDuration, n float64) bool { d.months += int(n); return n == float64(int(n)) }},
+	{[]string{"weeks", "week", "w", "周", "星期"}, func(d *calendarDuration, n float64) bool { d.days += 7 * int(n); return n == float64(int(n)) }},
+	{[]string{"days", "day", "d", "天", "日"}, func(d *calendarDuration, n float64) bool { d.days += int(n); return n == float64(int(n)) }},
+	{[]string{"hours", "hour", "h", "小时"}, func(d *calendarDuration, n float64) bool {
+		d.clock += time.Duration(n * float64(time.Hour))
+		return true
+	}},
+	{[]string{"minutes", "minute", "min", "m", "分钟"}, func(d *calendarDuration, n float64) bool {
+		d.clock += time.Duration(n * float64(time.Minute))
+		return true
+	}},
+	{[]string{"seconds", "second", "sec", "s", "秒"}, func(d *calendarDuration, n float64) bool {
+		d.clock += time.Duration(n * float64(time.Second))
+		return true
+	}},
+}
+
+// durationPart: 时长中的一段 "数字 + 单位"
+var durationPart = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([^\d\s.]+)\s*`)
+
+// parseCalendarDuration: 解析 "90d"、"-1y2mo"、"2h30m"、"3个月" 这样的时长，开头的 - 表示向前推
+func parseCalendarDuration(spec string) (calendarDuration, error) {
+	var d calendarDuration
+	rest := strings.TrimSpace(spec)
+	if rest == "" {
+		return d, fmt.Errorf("缺少 duration，如 90d、2h30m、-1w")
+	}
+	negative := strings.HasPrefix(rest, "-")
+	rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(rest, "-"), "+"))
+	// "90天后"、"3天前"
+	if before, ok := strings.CutSuffix(rest, "前"); ok {
+		rest, negative = before, !negative
+	} else {
+		rest = strings.TrimSuffix(rest, "后")
+	}
+	for rest != "" {
+		m := durationPart.FindStringSubmatch(rest)
+		if m == nil {
+			return d, fmt.Errorf("无法解析时长 %q，请使用 90d、2h30m、-1w、1y2mo 这样的写法", spec)
+		}
+		n, _ := strconv.ParseFloat(m[1], 64)
+		unit, ok := matchUnit(strings.ToLower(m[2]))
+		if !ok {
+			return d, fmt.Errorf("时长 %q 中的单位 %q 无法识别（可用：y、mo、w、d、h、m、s）", spec, m[2])
+		}
+		if !unit(&d, n) {
+			return d, fmt.Errorf("时长 %q 中的年、月、周、天必须是整数", spec)
+		}
+		rest = rest[len(m[0]):]
+	}
+	if negative {
+		d.years, d.months, d.days, d.clock = -d.years, -d.months, -d.days, -d.clock
+	}
+	return d, nil
+}
+
+// matchUnit: 按写法查找时长单位
+func matchUnit(name string) (func(d *calendarDuration, n float64) bool, bool) {
+	for _, unit := range durationUnits {
+		if slices.Contains(unit.names, name) {
+			return unit.apply, true
+		}
+	}
+	return nil, false
+}
+
+// formatDateTime: 带时区、UTC 偏移和星期的时间
+func formatDateTime(t time.Time) string {
+	weekdays := [...]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+	name, _ := t.Zone()
+	return fmt.Sprintf("%s %s（%s，UTC%s，%s）", t.Format(time.DateTime), name, t.Location(), t.Format("-07:00"), weekdays[t.Weekday()])
+}
+
+// humanizeDuration: "90 天 3 小时 20 分钟" 形式的间隔，结束早于开始时注明
+func humanizeDuration(d time.Duration) string {
+	prefix := ""
+	if d < 0 {
+		prefix = "结束时间早于开始时间，相差 "
+		d = -d
+	}
+	d = d.Round(time.Second)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	var parts []string
+	for _, unit := range []struct {
+		n    time.Duration
+		name string
+	}{{days, "天"}, {d / time.Hour, "小时"}, {d % time.Hour / time.Minute, "分钟"}, {d % time.Minute / time.Second, "秒"}} {
+		if unit.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", unit.n, unit.name))
+		}
+	}
+	if len(parts) == 0 {
+		return "两个时间相同"
+	}
+	return prefix + strings.Join(parts, " ")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedDateTimeTool: 当前时间固定为 2024-03-09 12:00 UTC（星期六）、本地时区为 UTC 的时间工具
+func fixedDateTimeTool() *dateTimeTool {
+	now := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	return &dateTimeTool{now: func() time.Time { return now }, local: time.UTC}
+}
+
+func TestDateTimeNow(t *testing.T) {
+	tool := fixedDateTimeTool()
+	tests := []struct {
+		timezone string
+		want     string
+	}{
+		{"", "当前时间：2024-03-09 12:00:00 UTC（UTC，UTC+00:00，星期六）"},
+		{"Asia/Tokyo", "当前时间：2024-03-09 21:00:00 JST（Asia/Tokyo，UTC+09:00，星期六）"},
+		{" America/New_York ", "当前时间：2024-03-09 07:00:00 EST（America/New_York，UTC-05:00，星期六）"},
+	}
+	for _, tt := range tests {
+		got, err := tool.evaluate(dateTimeArgs{Operation: "now", Timezone: tt.timezone})
+		if err != nil || got != tt.want {
+			t.Errorf("时区 %q: %q, %v，want %q", tt.timezone, got, err, tt.want)
+		}
+	}
+}
+
+func TestDateTimeAddDuration(t *testing.T) {
+	tool := fixedDateTimeTool()
+	tests := []struct {
+		name     string
+		timezone string
+		base     string
+		duration string
+		want     string // want: 结果中 "是" 之后的时间
+	}{
+		{"天", "", "", "90d", "2024-06-07 12:00:00"},
+		{"中文写法", "", "", "90天后", "2024-06-07 12:00:00"},
+		{"向前推", "", "", "3天前", "2024-03-06 12:00:00"},
+		{"负号", "", "", "-1w", "2024-03-02 12:00:00"},
+		{"组合", "", "", "2h30m", "2024-03-09 14:30:00"},
+		{"带空格和全称", "", "", "1 day 2 hours", "2024-03-10 14:00:00"},
+		{"小时可以是小数", "", "", "1.5h", "2024-03-09 13:30:00"},
+		{"月末加一个月", "", "2024-01-31", "1mo", "2024-03-02 00:00:00"},
+		{"闰年加一年", "", "2024-02-29", "1y", "2025-03-01 00:00:00"},
+		{"年月组合", "", "2024-01-15", "1y2mo", "2025-03-15 00:00:00"},
+		{"中文日期", "", "2024年3月9日 08:00", "3个月", "2024-06-09 08:00:00"},
+		{"自带偏移的时间换算到时区", "", "2024-03-09T12:00:00+08:00", "0s", "2024-03-09 04:00:00"},
+		{"Unix 时间戳", "", "1710000000", "1h", "2024-03-09 17:00:00"},
+		// 2024-03-10 纽约夏令时开始：按天加保持本地时刻，按小时加是实际经过的时间
+		{"跨夏令时按天加", "America/New_York", "2024-03-09 12:00", "1d", "2024-03-10 12:00:00 EDT"},
+		{"跨夏令时按小时加", "America/New_York", "2024-03-09 12:00", "24h", "2024-03-10 13:00:00 EDT"},
+		{"日大于 12 的斜杠日期", "", "13/04/2024", "1d", "2024-04-14 00:00:00"},
+		{"月/日/年", "", "04/13/2024 10:30", "1d", "2024-04-14 10:30:00"},
+		{"日月相同不算歧义", "", "04/04/2024", "0d", "2024-04-04 00:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tool.evaluate(dateTimeArgs{Operation: "add_duration", Timezone: tt.timezone, Base: tt.base, Duration: tt.duration})
+			if err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if _, after, _ := strings.Cut(got, " 是 "); !strings.HasPrefix(after, tt.want) {
+				t.Errorf("结果 = %q，want 是 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateTimeDiff(t *testing.T) {
+	tool := fixedDateTimeTool()
+	tests := []struct {
+		start, end string
+		want       string
+	}{
+		{"2024-03-09", "2024-06-07 03:20", "90 天 3 小时 20 分钟"},
+		{"", "2024-03-09 12:00:05", "5 秒"},
+		{"2024-03-10", "2024-03-09", "结束时间早于开始时间，相差 1 天"},
+		{"now", "现在", "两个时间相同"},
+	}
+	for _, tt := range tests {
+		got, err := tool.evaluate(dateTimeArgs{Operation: "diff", Start: tt.start, End: tt.end})
+		if err != nil || !strings.HasSuffix(got, "："+tt.want) {
+			t.Errorf("diff(%q, %q) = %q, %v，want %q", tt.start, tt.end, got, err, tt.want)
+		}
+	}
+}
+
+func TestDateTimeClarifications(t *testing.T) {
+	tool := fixedDateTimeTool()
+	tests := []struct {
+		name    string
+		args    dateTimeArgs
+		wantErr string
+	}{
+		{"未知时区", dateTimeArgs{Operation: "now", Timezone: "Mars/Olympus"}, `未知的时区 "Mars/Olympus"`},
+		{"日月顺序有歧义", dateTimeArgs{Operation: "diff", Start: "03/04/2024"}, "start \"03/04/2024\" 有歧义：可能是 2024年3月4日，也可能是 2024年4月3日"},
+		{"无法识别的时间", dateTimeArgs{Operation: "add_duration", Base: "下周三", Duration: "1d"}, `base "下周三" 不是可识别的时间`},
+		{"短数字不当作时间戳", dateTimeArgs{Operation: "diff", End: "12345"}, "end \"12345\" 不是可识别的时间"},
+		{"夏令时跳过的时间", dateTimeArgs{Operation: "add_duration", Timezone: "America/New_York", Base: "2024-03-10 02:30", Duration: "1h"},
+			"2024-03-10 02:30:00 在 America/New_York 不存在"},
+		{"夏令时重复的时间", dateTimeArgs{Operation: "add_duration", Timezone: "America/New_York", Base: "2024-11-03 01:30", Duration: "1h"},
+			"出现两次（夏令时拨慢），可能是 01:30 -04:00 或 01:30 -05:00"},
+		{"缺少时长", dateTimeArgs{Operation: "add_duration"}, "缺少 duration"},
+		{"无法解析的时长", dateTimeArgs{Operation: "add_duration", Duration: "很久"}, `无法解析时长 "很久"`},
+		{"未知单位", dateTimeArgs{Operation: "add_duration", Duration: "3x"}, `单位 "x" 无法识别`},
+		{"天不能是小数", dateTimeArgs{Operation: "add_duration", Duration: "1.5d"}, "年、月、周、天必须是整数"},
+		{"未知操作", dateTimeArgs{Operation: "sleep"}, `未知操作 "sleep"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tool.evaluate(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestDateTimeToolRun: 需要澄清时返回以 dateTimeFailure 开头的结果而不是 error
+func TestDateTimeToolRun(t *testing.T) {
+	tool := fixedDateTimeTool()
+	got, err := tool.run(context.Background(), dateTimeArgs{Operation: "now", Timezone: "Nowhere"})
+	if err != nil || !strings.HasPrefix(got, dateTimeFailure+"未知的时区") {
+		t.Errorf("run = %q, %v", got, err)
+	}
+	if _, err := NewDateTimeTool().InvokableRun(context.Background(), `{"operation": "yesterday"}`); err == nil {
+		t.Errorf("不在可选值中的操作应在解析参数时被拒绝")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	pathAgent  = "agent"  // pathAgent: 由带工具的 ReAct Agent 回答
+	pathDirect = "direct" // pathDirect: 判断为不需要工具，由模型直接回答
+)
+
+// toolKeywords: 提示查询可能需要工具的关键词（计算、天气、搜索、文件、时间、随机数），英文按小写匹配
+var toolKeywords = []string{
+	"计算", "等于", "多少", "算", "加", "减", "乘", "除", "平方", "开方",
+	"天气", "气温", "温度", "下雨", "下雪",
+	"搜索", "查", "最新", "新闻",
+	"文件", "写入", "读取", "读出", "目录", "保存",
+	"时间", "几点", "日期", "哪天", "星期", "今天", "明天", "昨天", "天后", "天前", "时区",
+	"随机", "骰子", "抽签",
+	"calculate", "compute", "weather", "search", "latest", "news", "file", "read", "write", "time", "date", "today", "tomorrow", "random", "dice", "uuid",
+}
+
+// arithmeticSymbols: 出现在算式中的符号
+const arithmeticSymbols = "+-*/=%^×÷"
+
+// classifyQuery: 轻量的预分类：查询中有数字、算式符号或工具关键词时需要工具，否则（如寒暄、开放性问题）直接回答
+// 宁可误判为需要工具（多走一次 Agent），也不要把需要工具的查询交给不能调用工具的模型；reason 说明判断依据
+func classifyQuery(query string) (needsTools bool, reason string) {
+	for _, r := range query {
+		if unicode.IsDigit(r) {
+			return true, fmt.Sprintf("包含数字 %q", r)
+		}
+		if strings.ContainsRune(arithmeticSymbols, r) {
+			return true, fmt.Sprintf("包含算式符号 %q", r)
+		}
+	}
+	lower := strings.ToLower(query)
+	for _, keyword := range toolKeywords {
+		if strings.Contains(lower, keyword) {
+			return true, fmt.Sprintf("包含关键词 %q", keyword)
+		}
+	}
+	return false, "没有数字、算式符号或工具关键词"
+}
+
+// chainRunner: 用编译好的链回答查询，opts 在每次调用时传给链（如审计回调）
+type chainRunner struct {
+	runnable compose.Runnable[[]*schema.Message, *schema.Message]
+	opts     []compose.Option
+}
+
+// newDirectRunner: 只包含模型、不带工具的直接回答链
+func newDirectRunner(ctx context.Context, llm model.BaseChatModel, opts ...compose.Option) (chainRunner, error) {
+	runnable, err := compose.NewChain[[]*schema.Message, *schema.Message]().
+		AppendChatModel(llm).
+		Compile(ctx)
+	if err != nil {
+		return chainRunner{}, fmt.Errorf("编译直接回答链失败: %w", err)
+	}
+	return chainRunner{runnable: runnable, opts: opts}, nil
+}
+
+func (r chainRunner) Generate(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+	return r.runnable.Invoke(ctx, input, r.opts...)
+}
+
+func (r chainRunner) Stream(ctx context.Context, input []*schema.Message) (*schema.StreamReader[*schema.Message], error) {
+	return r.runnable.Stream(ctx, input, r.opts...)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+type forcedToolKey struct{}
+
+// withForcedTool: 要求本次查询的第一步必须调用名为 name 的工具（name 为空时不强制）
+func withForcedTool(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, forcedToolKey{}, name)
+}
+
+// forcedToolModel: 支持按查询强制调用指定工具的模型包装
+// 上下文中有 withForcedTool 设置的工具名、且对话中还没有工具结果（ReAct 的第一步）时，只把这一个工具交给模型并设置
+// ToolChoiceForced，模型必须调用它；之后的步骤恢复正常，否则模型每一步都被迫调用工具，循环无法结束
+type forcedToolModel struct {
+	inner model.ToolCallingChatModel
+	tools map[string]*schema.ToolInfo // tools: 可以强制的工具，按名称索引
+}
+
+var _ model.ToolCallingChatModel = (*forcedToolModel)(nil)
+
+// newForcedToolModel: 包装模型，tools 为 Agent 的全部工具
+func newForcedToolModel(ctx context.Context, inner model.ToolCallingChatModel, tools []tool.BaseTool) (*forcedToolModel, error) {
+	infos := make(map[string]*schema.ToolInfo, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		infos[info.Name] = info
+	}
+	return &forcedToolModel{inner: inner, tools: infos}, nil
+}
+
+// has: 是否有名为 name 的工具
+func (m *forcedToolModel) has(name string) bool {
+	_, ok := m.tools[name]
+	return ok
+}
+
+func (m *forcedToolModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.inner.Generate(ctx, input, m.options(ctx, input, opts)...)
+}
+
+func (m *forcedToolModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return m.inner.Stream(ctx, input, m.options(ctx, input, opts)...)
+}
+
+func (m *forcedToolModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &forcedToolModel{inner: inner, tools: m.tools}, nil
+}
+
+// options: 第一步需要强制时追加工具列表和 ToolChoiceForced
+func (m *forcedToolModel) options(ctx context.Context, input []*schema.Message, opts []model.Option) []model.Option {
+	name, _ := ctx.Value(forcedToolKey{}).(string)
+	info, ok := m.tools[name]
+	if !ok {
+		return opts
+	}
+	for _, msg := range input {
+		if msg.Role == schema.Tool {
+			return opts // 已经调用过工具，不再强制
+		}
+	}
+	fmt.Printf("--- 🎯 强制调用工具：%s ---\n", name)
+	return append(slices.Clone(opts), model.WithTools([]*schema.ToolInfo{info}), model.WithToolChoice(schema.ToolChoiceForced))
+}
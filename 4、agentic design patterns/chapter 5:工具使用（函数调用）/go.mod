@@ -5,6 +5,7 @@ go 1.23.2
 require (
 	github.com/cloudwego/eino v0.7.0
 	github.com/cloudwego/eino-ext/components/model/openai v0.1.5
+	shared v0.0.0
 )
 
 require (
@@ -39,3 +40,5 @@ require (
 	golang.org/x/sys v0.28.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace shared => ../shared
@@ -7,6 +7,9 @@
 	搜索工具	   信息检索		 获取最新信息		         可能返回不相关信息			     网络搜索、文档检索
 	系统工具	   系统操作		 直接操作系统		         安全风险高			     文件操作、命令执行（需谨慎）
 
+	本示例提供计算、天气、搜索、时间、随机数与文件读写等工具，并可叠加超时、重试、缓存、人工审批和审计日志；
+	可用的命令行参数见 go run . -h。
+
 	此代码根据 MIT 许可证授权。
 	请参阅仓库中的 LICENSE 文件以获取完整许可文本。
 */
@@ -15,14 +18,20 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
+	"time"
+
+	"ch5/audit"
+	"shared/retry"
+	"shared/toolkit"
 
 	"github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
+	einoagent "github.com/cloudwego/eino/flow/agent"
 	"github.com/cloudwego/eino/flow/agent/react"
 	"github.com/cloudwego/eino/schema"
 )
@@ -34,48 +43,24 @@ func float32Ptr(f float32) *float32 {
 
 // --- 定义工具 ---
 
-// CalculatorTool: 自定义计算器工具
-type CalculatorTool struct{}
-
-func (c *CalculatorTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
-	return &schema.ToolInfo{
-		Name: "calculator",
-		Desc: "执行基本算术运算（加、减、乘、除）",
-		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
-			"operation": {
-				Type:     schema.String,
-				Desc:     "要执行的操作：add（加）、subtract（减）、multiply（乘）、divide（除）",
-				Required: true,
-			},
-			"a": {
-				Type:     schema.Number,
-				Desc:     "第一个数字",
-				Required: true,
-			},
-			"b": {
-				Type:     schema.Number,
-				Desc:     "第二个数字",
-				Required: true,
-			},
-		}),
-	}, nil
+// calculatorArgs: calculator 工具的参数，ToolInfo 由 NewStructTool 从字段标签推导
+type calculatorArgs struct {
+	Operation string  `json:"operation" desc:"要执行的操作：add（加）、subtract（减）、multiply（乘）、divide（除）" required:"true" enum:"add,subtract,multiply,divide"`
+	A         float64 `json:"a" desc:"第一个数字" required:"true"`
+	B         float64 `json:"b" desc:"第二个数字" required:"true"`
 }
 
-func (c *CalculatorTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
-	var args struct {
-		Operation string  `json:"operation"`
-		A         float64 `json:"a"`
-		B         float64 `json:"b"`
-	}
-
-	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
-		return "", fmt.Errorf("无效的参数: %w", err)
-	}
+// NewCalculatorTool: 自定义计算器工具，参数的定义、解析和校验都由 NewStructTool 完成
+func NewCalculatorTool() tool.InvokableTool {
+	return NewStructTool("calculator", "执行基本算术运算（加、减、乘、除）", calculate)
+}
 
+// calculate: calculator 工具的实现
+func calculate(ctx context.Context, args calculatorArgs) (string, error) {
 	fmt.Printf("\n--- 🛠️ 工具调用：calculator，操作：'%s'，参数：a=%.2f, b=%.2f ---\n", args.Operation, args.A, args.B)
 
 	var result float64
-	switch strings.ToLower(args.Operation) {
+	switch args.Operation {
 	case "add":
 		result = args.A + args.B
 	case "subtract":
@@ -97,6 +82,51 @@ func (c *CalculatorTool) InvokableRun(ctx context.Context, argumentsInJSON strin
 }
 
 func main() {
+	workspaceDir := flag.String("workspace", "workspace", "文件工具的工作区目录（不存在时创建），工具无法访问此目录之外的文件")
+	toolTimeout := flag.Duration("tool-timeout", defaultToolTimeout, "单次工具调用的默认超时（0 表示不限制）")
+	toolTimeoutSpec := flag.String("tool-timeouts", "", "按工具名单独设置超时，如 get_weather=15s,web_search=20s")
+	toolParallelism := flag.Int("tool-parallelism", defaultToolParallelism, "同一步中最多同时执行的工具调用数（1 表示逐个执行）")
+	sequentialTools := flag.String("sequential-tools", strings.Join(defaultSequentialTools, ","), "共享状态、彼此之间只能串行执行的工具（逗号分隔）")
+	toolRetry := flag.Int("tool-retry", retry.DefaultMaxAttempts, "工具瞬时失败时的最多尝试次数（含第一次，1 表示不重试）")
+	toolRetrySpec := flag.String("tool-retries", "", "按工具名单独设置最多尝试次数，如 get_weather=5,web_search=2")
+	retryExclude := flag.String("retry-exclude", strings.Join(defaultRetryExclude, ","), "默认不重试的非幂等工具（逗号分隔），-tool-retries 中单独设置的次数仍然生效")
+	toolRetryDelay := flag.Duration("tool-retry-delay", retry.DefaultBaseDelay, "第一次重试前的等待时间（之后按指数退避）")
+	useCache := flag.Bool("cache", true, "缓存幂等工具的结果")
+	cacheTTL := flag.Duration("cache-ttl", defaultCacheTTL, "工具结果的默认缓存有效期（0 表示不过期）")
+	cacheTTLSpec := flag.String("cache-ttls", "", "按工具名单独设置缓存有效期，如 get_weather=5m,evaluate_expression=0")
+	cacheExclude := flag.String("cache-exclude", strings.Join(defaultCacheExclude, ","), "不缓存的工具（逗号分隔），如有副作用或结果依赖外部状态的工具")
+	cacheFile := flag.String("cache-file", "", "工具缓存的持久化文件（启动时加载、结束时保存；为空时只在内存中缓存）")
+	passToolErrors := flag.Bool("pass-tool-errors", true, "把工具错误作为结果交给模型自行纠正（false 时工具出错即中止查询）")
+	maxToolFailures := flag.Int("max-tool-failures", defaultMaxToolFailures, "每个查询中最多交给模型的工具错误数，超过后中止查询")
+	dangerous := flag.String("dangerous", strings.Join(defaultDangerousTools, ","), "执行前需要人工确认的工具（逗号分隔，为空时都不需要确认）")
+	autoApprove := flag.Bool("auto-approve", false, "自动批准所有需要确认的工具调用（非交互运行时使用，仍会记录）")
+	noStream := flag.Bool("no-stream", false, "等待完整回答后一次性打印（默认流式输出最终回答）")
+	randomSeed := flag.Uint64("random-seed", 0, "random 工具的种子（0 表示基于当前时间，每次运行结果不同）")
+	forceTool := flag.String("force-tool", "", "每个查询的第一步强制调用此工具（为空时由模型自行选择）")
+	fastPath := flag.Bool("fast-path", true, "不需要工具的查询（没有数字和工具关键词）跳过 Agent，由模型直接回答")
+	auditPath := flag.String("audit-log", "tool-audit.jsonl", "工具调用审计日志（JSONL，追加写入；为空时只统计不落盘）")
+	statsJSON := flag.String("stats-json", "", "把运行汇总（按工具的统计和 token 用量）另存为 JSON 文件")
+	pricePrompt := flag.Float64("price-prompt", 0, "每百万提示 token 的价格，用于估算费用（0 表示不估算）")
+	priceCompletion := flag.Float64("price-completion", 0, "每百万生成 token 的价格，用于估算费用")
+	auditRedact := flag.String("audit-redact", "api_key,password,token,secret", "审计日志中需要脱敏的参数名（逗号分隔，不区分大小写）")
+	flag.Parse()
+
+	toolTimeouts, err := parseToolDurations(*toolTimeoutSpec)
+	if err != nil {
+		fmt.Printf("参数错误: %v\n", err)
+		os.Exit(1)
+	}
+	cacheTTLs, err := parseToolDurations(*cacheTTLSpec)
+	if err != nil {
+		fmt.Printf("参数错误: %v\n", err)
+		os.Exit(1)
+	}
+	toolRetries, err := parseToolAttempts(*toolRetrySpec)
+	if err != nil {
+		fmt.Printf("参数错误: %v\n", err)
+		os.Exit(1)
+	}
+
 	ctx := context.Background()
 
 	// --- 配置 ---
@@ -132,15 +162,99 @@ func main() {
 	fmt.Printf("✅ 语言模型已初始化: %s\n", config.Model)
 
 	// --- 创建工具 ---
-	calculator := &CalculatorTool{}
+	calculator := NewCalculatorTool()
+	expressionCalculator := &toolkit.ExpressionCalculatorTool{}
+	weather := NewWeatherTool()
+	search := NewSearchTool()
+	datetime := NewDateTimeTool()
+	seed := *randomSeed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	random := NewRandomTool(seed)
+	workspace, err := toolkit.NewWorkspace(*workspaceDir)
+	if err != nil {
+		fmt.Printf("初始化工作区失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("📁 文件工具工作区: %s\n", workspace.Root)
 
-	tools := []tool.BaseTool{calculator}
+	tools := []tool.BaseTool{
+		calculator,
+		expressionCalculator,
+		weather,
+		search,
+		datetime,
+		random,
+		&toolkit.ReadFileTool{Workspace: workspace},
+		&toolkit.WriteFileTool{Workspace: workspace},
+		&toolkit.ListDirTool{Workspace: workspace},
+	}
+	// 由内到外：缓存、超时、并发限制、重试、错误转交、人工确认。超时以可重试的错误返回，每次尝试各自受超时约束，
+	// 重试用尽的失败说明不会被缓存；等待并发名额的时间不计入超时，重试前的退避等待也不占用名额
+	var cache *toolCache
+	if *useCache {
+		cache = newToolCache(defaultCacheEntries)
+		if *cacheFile != "" {
+			if err := cache.load(*cacheFile); err != nil {
+				fmt.Printf("⚠ %v\n", err)
+			}
+		}
+		tools, err = applyCache(ctx, tools, cache, cacheTTLs, *cacheTTL, strings.Split(*cacheExclude, ","))
+		if err != nil {
+			fmt.Printf("配置工具缓存失败: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	tools, err = applyTimeouts(ctx, tools, toolTimeouts, *toolTimeout)
+	if err != nil {
+		fmt.Printf("配置工具超时失败: %v\n", err)
+		os.Exit(1)
+	}
+	tools, err = applyConcurrency(ctx, tools, NewConcurrencyLimiter(*toolParallelism), strings.Split(*sequentialTools, ","))
+	if err != nil {
+		fmt.Printf("配置工具并发失败: %v\n", err)
+		os.Exit(1)
+	}
+	retryPolicy := retry.DefaultPolicy()
+	retryPolicy.MaxAttempts = *toolRetry
+	retryPolicy.BaseDelay = *toolRetryDelay
+	tools, err = applyRetries(ctx, tools, toolRetries, retryPolicy, strings.Split(*retryExclude, ","))
+	if err != nil {
+		fmt.Printf("配置工具重试失败: %v\n", err)
+		os.Exit(1)
+	}
+	if *passToolErrors {
+		tools, err = applyErrorPassthrough(ctx, tools, *maxToolFailures)
+		if err != nil {
+			fmt.Printf("配置工具错误转交失败: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// 人工确认在最外层：每次调用只问一次，被拒绝的调用不会进入重试
+	approvals := NewApprovalGate(NewStdinPrompter(), *autoApprove)
+	tools, err = applyApproval(ctx, tools, approvals, strings.Split(*dangerous, ","))
+	if err != nil {
+		fmt.Printf("配置工具确认失败: %v\n", err)
+		os.Exit(1)
+	}
 
 	// --- 创建 ReAct Agent ---
+	forcedModel, err := newForcedToolModel(ctx, llm, tools)
+	if err != nil {
+		fmt.Printf("配置强制工具调用失败: %v\n", err)
+		os.Exit(1)
+	}
+	if *forceTool != "" && !forcedModel.has(*forceTool) {
+		fmt.Printf("参数错误: -force-tool 指定的工具 %q 不存在\n", *forceTool)
+		os.Exit(1)
+	}
 	agentConfig := &react.AgentConfig{
-		ToolCallingModel: llm,
+		ToolCallingModel: forcedModel,
 		ToolsConfig: compose.ToolsNodeConfig{
 			Tools: tools,
+			// 同一步中的多个工具调用默认并行执行，-tool-parallelism=1 时按模型给出的顺序逐个执行
+			ExecuteSequentially: *toolParallelism <= 1,
 		},
 		MaxStep: 10,
 	}
@@ -151,14 +265,39 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --- 工具调用审计 ---
+	auditor, err := audit.New(*auditPath, strings.Split(*auditRedact, ","))
+	if err != nil {
+		fmt.Printf("初始化审计日志失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer auditor.Close()
+	agentPath := agentRunner{agent: agent, opts: []einoagent.AgentOption{einoagent.WithComposeOptions(compose.WithCallbacks(auditor.Handler()))}}
+	directPath, err := newDirectRunner(ctx, llm, compose.WithCallbacks(auditor.Handler()))
+	if err != nil {
+		fmt.Printf("创建直接回答链失败: %v\n", err)
+		os.Exit(1)
+	}
+
 	// --- 运行 Agent 查询 ---
 	queries := []string{
 		"5+6等于多少？",
 		"5-6等于多少？",
 		"5*6等于多少？",
 		"5/6等于多少？",
+		"计算 5/0，如果不行请解释原因",
+		"分别计算 12+34、56*78 和 90/3",
+		"计算 (5+3)*2-4/2",
+		"北京现在的天气怎么样？",
+		"搜索一下 Eino 框架是什么，简要介绍",
+		"现在东京时间几点？",
+		"90 天后是哪天？",
+		"掷一个 20 面骰子三次",
+		"把 5*6 的结果写入 result.txt（已存在则覆盖）再读出来",
+		"你好，请介绍一下你自己",
 	}
 
+	var outcomes []queryOutcome
 	for _, query := range queries {
 		fmt.Printf("\n--- 🏃 使用查询运行 Agent：'%s' ---\n", query)
 
@@ -166,14 +305,67 @@ func main() {
 			schema.UserMessage(query),
 		}
 
-		response, err := agent.Generate(ctx, messages)
+		path, runner := pathAgent, queryRunner(agentPath)
+		if *fastPath && *forceTool == "" {
+			if needsTools, reason := classifyQuery(query); !needsTools {
+				path, runner = pathDirect, directPath
+				fmt.Printf("--- ⚡ 无需工具（%s），直接回答 ---\n", reason)
+			}
+		}
+		auditor.RecordQuery(path)
+
+		// 每个查询单独计算工具错误数
+		queryCtx := withForcedTool(withToolFailures(ctx), *forceTool)
+		response, err := runQuery(queryCtx, runner, messages, !*noStream, os.Stdout)
+		outcomes = append(outcomes, queryOutcome{Query: query, Path: path, Answer: response, Err: err})
 		if err != nil {
 			fmt.Printf("🛑 Agent 执行期间发生错误：%v\n", err)
 			continue
 		}
-
-		fmt.Println("\n--- ✅ 最终 Agent 响应 ---")
-		fmt.Println(response.Content)
 		fmt.Println(strings.Repeat("-", 60))
 	}
+
+	if cache != nil && *cacheFile != "" {
+		if err := cache.save(*cacheFile); err != nil {
+			fmt.Printf("⚠ %v\n", err)
+		}
+	}
+
+	fmt.Println("\n--- 📝 查询汇总 ---")
+	printOutcomes(os.Stdout, outcomes)
+
+	fmt.Println("\n--- ✋ 工具调用确认记录 ---")
+	approvals.PrintLog(os.Stdout)
+
+	fmt.Println("\n--- 📊 工具调用汇总 ---")
+	auditor.PrintSummary(os.Stdout)
+	report := auditor.Report()
+	pricing := audit.Pricing{PromptPerMillion: *pricePrompt, CompletionPerMillion: *priceCompletion}
+	if pricing != (audit.Pricing{}) {
+		report.EstimatedCost = report.Tokens.Cost(pricing)
+		fmt.Printf("估算费用：%.4f\n", report.EstimatedCost)
+	}
+	if *auditPath != "" {
+		fmt.Printf("每次调用的详细记录见 %s\n", *auditPath)
+	}
+	if *statsJSON != "" {
+		if err := writeReport(*statsJSON, report); err != nil {
+			fmt.Printf("⚠ %v\n", err)
+		} else {
+			fmt.Printf("运行汇总已写入 %s\n", *statsJSON)
+		}
+	}
+}
+
+// writeReport: 把运行汇总写入 JSON 文件
+func writeReport(path string, report audit.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建运行汇总文件失败: %w", err)
+	}
+	if err := report.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"ch5/audit"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const defaultToolParallelism = 4 // defaultToolParallelism: 同一步中最多同时执行的工具调用数
+
+// defaultSequentialTools: 默认只能串行执行的工具：它们读写同一个工作区，并发执行时结果取决于先后顺序
+var defaultSequentialTools = []string{"read_file", "write_file", "list_dir"}
+
+// ConcurrencyLimiter: 模型在一步中发出多个工具调用时，ToolsNode 并行执行它们；
+// ConcurrencyLimiter 限制同时执行的调用数，并让标记为串行的工具（共享状态）彼此之间不并发
+type ConcurrencyLimiter struct {
+	slots      chan struct{}
+	sequential sync.Mutex // sequential: 串行工具共用的锁
+}
+
+// NewConcurrencyLimiter: 最多 parallelism 个工具调用同时执行（<1 时按 1 处理）
+func NewConcurrencyLimiter(parallelism int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{slots: make(chan struct{}, max(parallelism, 1))}
+}
+
+// LimitedTool: 执行前先占用 ConcurrencyLimiter 的名额的工具装饰器
+type LimitedTool struct {
+	inner      tool.InvokableTool
+	limiter    *ConcurrencyLimiter
+	sequential bool // sequential: 与其他串行工具互斥
+}
+
+var _ tool.InvokableTool = (*LimitedTool)(nil)
+
+func (t *LimitedTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.inner.Info(ctx)
+}
+
+func (t *LimitedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	select {
+	case t.limiter.slots <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-t.limiter.slots }()
+	if t.sequential {
+		t.limiter.sequential.Lock()
+		defer t.limiter.sequential.Unlock()
+	}
+	audit.MarkStarted(ctx) // 审计日志的开始时间和耗时不含排队等待
+	return t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+// applyConcurrency: 给每个可调用的工具加上并发限制，sequential 中的工具彼此串行
+// 应在 applyTimeouts 之后调用：等待名额的时间不计入工具超时
+func applyConcurrency(ctx context.Context, tools []tool.BaseTool, limiter *ConcurrencyLimiter, sequential []string) ([]tool.BaseTool, error) {
+	serial := make(map[string]bool, len(sequential))
+	for _, name := range sequential {
+		serial[strings.TrimSpace(name)] = true
+	}
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = t
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		wrapped[i] = &LimitedTool{inner: invokable, limiter: limiter, sequential: serial[info.Name]}
+	}
+	return wrapped, nil
+}
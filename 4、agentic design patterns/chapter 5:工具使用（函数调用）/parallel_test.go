@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// gauge: 记录同时执行的调用数的最大值
+type gauge struct {
+	active, peak atomic.Int32
+}
+
+// track: 执行期间计入一个活跃调用，停留 d
+func (g *gauge) track(d time.Duration) {
+	n := g.active.Add(1)
+	defer g.active.Add(-1)
+	for {
+		old := g.peak.Load()
+		if n <= old || g.peak.CompareAndSwap(old, n) {
+			break
+		}
+	}
+	time.Sleep(d)
+}
+
+// sleepTool: 执行时计入 g 并停留 d 的工具
+func sleepTool(name string, g *gauge, d time.Duration) *funcTool {
+	return &funcTool{name: name, run: func(ctx context.Context, args string) (string, error) {
+		g.track(d)
+		return name, nil
+	}}
+}
+
+// runAll: 并发调用所有工具各 n 次并等待结束
+func runAll(t *testing.T, tools []tool.BaseTool, n int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for _, tl := range tools {
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := tl.(tool.InvokableTool).InvokableRun(context.Background(), "{}"); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestConcurrencyLimiterBoundsParallelism(t *testing.T) {
+	var g gauge
+	tools := []tool.BaseTool{sleepTool("a", &g, 10*time.Millisecond), sleepTool("b", &g, 10*time.Millisecond)}
+	wrapped, err := applyConcurrency(context.Background(), tools, NewConcurrencyLimiter(3), nil)
+	if err != nil {
+		t.Fatalf("applyConcurrency: %v", err)
+	}
+	runAll(t, wrapped, 4)
+	if got := g.peak.Load(); got != 3 {
+		t.Errorf("最大并发 = %d，want 3", got)
+	}
+}
+
+// TestConcurrencyLimiterSequentialTools: 串行工具彼此不并发，但可以与其他工具并发
+func TestConcurrencyLimiterSequentialTools(t *testing.T) {
+	var files, all gauge
+	fileTool := func(name string) *funcTool {
+		return &funcTool{name: name, run: func(ctx context.Context, args string) (string, error) {
+			done := make(chan struct{})
+			go func() { all.track(10 * time.Millisecond); close(done) }()
+			files.track(10 * time.Millisecond)
+			<-done
+			return name, nil
+		}}
+	}
+	tools := []tool.BaseTool{fileTool("read_file"), fileTool("write_file"), sleepTool("calculator", &all, 10*time.Millisecond)}
+	wrapped, err := applyConcurrency(context.Background(), tools, NewConcurrencyLimiter(8), []string{" read_file", "write_file "})
+	if err != nil {
+		t.Fatalf("applyConcurrency: %v", err)
+	}
+	runAll(t, wrapped, 3)
+	if got := files.peak.Load(); got != 1 {
+		t.Errorf("文件工具最大并发 = %d，want 1", got)
+	}
+	if got := all.peak.Load(); got < 2 {
+		t.Errorf("其他工具应能与文件工具并发，最大并发 = %d", got)
+	}
+}
+
+func TestNewConcurrencyLimiterMinimum(t *testing.T) {
+	if got := cap(NewConcurrencyLimiter(0).slots); got != 1 {
+		t.Errorf("parallelism=0 时名额 = %d，want 1", got)
+	}
+}
+
+// TestLimitedToolCancelWhileQueued: 排队等待名额时取消，立即返回且不执行工具
+func TestLimitedToolCancelWhileQueued(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1)
+	release := make(chan struct{})
+	busy := &funcTool{name: "busy", run: func(ctx context.Context, args string) (string, error) {
+		<-release
+		return "", nil
+	}}
+	queued := &funcTool{name: "queued", run: func(ctx context.Context, args string) (string, error) { return "", nil }}
+	go (&LimitedTool{inner: busy, limiter: limiter}).InvokableRun(context.Background(), "{}")
+	for busy.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := (&LimitedTool{inner: queued, limiter: limiter}).InvokableRun(ctx, "{}")
+	close(release)
+	if !errors.Is(err, context.DeadlineExceeded) || queued.calls.Load() != 0 {
+		t.Errorf("错误 = %v，工具执行了 %d 次", err, queued.calls.Load())
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"ch5/audit"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const defaultMaxToolFailures = 3 // defaultMaxToolFailures: 每个查询中最多转交给模型的工具错误数
+
+// toolFailures: 一个查询中已转交给模型的工具错误数，由 withToolFailures 挂在查询的上下文上
+type toolFailures struct {
+	n atomic.Int32
+}
+
+type toolFailuresKey struct{}
+
+// withToolFailures: 为一个查询创建新的工具错误计数器，同一查询中的所有工具调用共用
+func withToolFailures(ctx context.Context) context.Context {
+	return context.WithValue(ctx, toolFailuresKey{}, &toolFailures{})
+}
+
+// ErrorPassthroughTool: 把工具返回的 error 转为工具结果的装饰器：ReAct Agent 遇到工具 error 会中止整个查询，
+// 转为结果后模型能看到错误说明，换一组参数重试或向用户解释原因
+// 同一查询中超过 maxFailures 次后原样返回 error 结束查询，避免模型反复用错误的参数调用；上下文取消不转换
+type ErrorPassthroughTool struct {
+	inner       tool.InvokableTool
+	name        string
+	maxFailures int
+}
+
+var _ tool.InvokableTool = (*ErrorPassthroughTool)(nil)
+
+func (t *ErrorPassthroughTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.inner.Info(ctx)
+}
+
+func (t *ErrorPassthroughTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	result, err := t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	failures := 1
+	if counter, ok := ctx.Value(toolFailuresKey{}).(*toolFailures); ok {
+		failures = int(counter.n.Add(1))
+	}
+	if failures > t.maxFailures {
+		return "", fmt.Errorf("本次查询中工具已失败 %d 次（上限 %d），停止执行: %w", failures, t.maxFailures, err)
+	}
+	audit.MarkError(ctx, err) // 审计日志中仍记为失败
+	msg := fmt.Sprintf("工具 %s 执行出错：%v（本次查询第 %d 次工具错误，最多 %d 次）；请检查参数后重试，或向用户解释原因",
+		t.name, err, failures, t.maxFailures)
+	fmt.Printf("--- ⚠️ %s ---\n", msg)
+	return msg, nil
+}
+
+// applyErrorPassthrough: 给每个可调用的工具加上错误转交
+// 应在 applyRetries 之后调用：可重试的错误先重试，重试用尽或不可重试的错误才转交给模型
+func applyErrorPassthrough(ctx context.Context, tools []tool.BaseTool, maxFailures int) ([]tool.BaseTool, error) {
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = t
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		wrapped[i] = &ErrorPassthroughTool{inner: invokable, name: info.Name, maxFailures: maxFailures}
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestErrorPassthroughConvertsErrors(t *testing.T) {
+	boom := errors.New("参数无效")
+	inner := &funcTool{name: "calculator", run: func(ctx context.Context, args string) (string, error) {
+		if args == "ok" {
+			return "42", nil
+		}
+		return "", boom
+	}}
+	wrapped := &ErrorPassthroughTool{inner: inner, name: "calculator", maxFailures: 2}
+	ctx := withToolFailures(context.Background())
+
+	if got, err := wrapped.InvokableRun(ctx, "ok"); err != nil || got != "42" {
+		t.Fatalf("成功的调用 = %q, %v", got, err)
+	}
+	for i, want := range []string{"第 1 次工具错误", "第 2 次工具错误"} {
+		got, err := wrapped.InvokableRun(ctx, "bad")
+		if err != nil {
+			t.Fatalf("第 %d 次失败应转为结果: %v", i+1, err)
+		}
+		if !strings.Contains(got, "calculator") || !strings.Contains(got, "参数无效") || !strings.Contains(got, want) {
+			t.Errorf("第 %d 次失败的结果 = %q", i+1, got)
+		}
+	}
+	_, err := wrapped.InvokableRun(ctx, "bad")
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "上限 2") {
+		t.Errorf("超过上限后应返回 error: %v", err)
+	}
+
+	// 新查询重新计数
+	if _, err := wrapped.InvokableRun(withToolFailures(context.Background()), "bad"); err != nil {
+		t.Errorf("新查询的第一次失败应转为结果: %v", err)
+	}
+}
+
+// TestErrorPassthroughSharedCounter: 同一查询中的不同工具共用计数
+func TestErrorPassthroughSharedCounter(t *testing.T) {
+	fail := func(ctx context.Context, args string) (string, error) { return "", errors.New("失败") }
+	tools, err := applyErrorPassthrough(context.Background(), []tool.BaseTool{
+		&funcTool{name: "a", run: fail},
+		&funcTool{name: "b", run: fail},
+	}, 1)
+	if err != nil {
+		t.Fatalf("applyErrorPassthrough: %v", err)
+	}
+	ctx := withToolFailures(context.Background())
+	if _, err := tools[0].(tool.InvokableTool).InvokableRun(ctx, "{}"); err != nil {
+		t.Fatalf("第一次失败应转为结果: %v", err)
+	}
+	if _, err := tools[1].(tool.InvokableTool).InvokableRun(ctx, "{}"); err == nil {
+		t.Errorf("另一个工具的失败也应计入同一查询的上限")
+	}
+}
+
+func TestErrorPassthroughWithoutCounter(t *testing.T) {
+	inner := &funcTool{name: "x", run: func(ctx context.Context, args string) (string, error) { return "", errors.New("失败") }}
+	wrapped := &ErrorPassthroughTool{inner: inner, name: "x", maxFailures: 1}
+	for i := 0; i < 3; i++ {
+		if _, err := wrapped.InvokableRun(context.Background(), "{}"); err != nil {
+			t.Fatalf("没有计数器时每次都按第一次处理: %v", err)
+		}
+	}
+}
+
+func TestErrorPassthroughKeepsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(withToolFailures(context.Background()))
+	cancel()
+	wrapped := &ErrorPassthroughTool{inner: hangingTool("slow", nil, false), name: "slow", maxFailures: 3}
+	if _, err := wrapped.InvokableRun(ctx, "{}"); !errors.Is(err, context.Canceled) {
+		t.Errorf("上下文取消应原样返回: %v", err)
+	}
+}
+
+// infoOnlyTool: 只实现 BaseTool 的工具
+type infoOnlyTool struct{}
+
+func (t infoOnlyTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "info_only"}, nil
+}
+
+func TestApplyErrorPassthrough(t *testing.T) {
+	base := infoOnlyTool{}
+	inner := &funcTool{name: "echo", run: func(ctx context.Context, args string) (string, error) { return args, nil }}
+	tools, err := applyErrorPassthrough(context.Background(), []tool.BaseTool{base, inner}, 3)
+	if err != nil {
+		t.Fatalf("applyErrorPassthrough: %v", err)
+	}
+	if tools[0] != tool.BaseTool(base) {
+		t.Errorf("不可调用的工具应原样保留")
+	}
+	wrapped, ok := tools[1].(*ErrorPassthroughTool)
+	if !ok || wrapped.name != "echo" || wrapped.maxFailures != 3 {
+		t.Fatalf("可调用的工具应被包装: %#v", tools[1])
+	}
+	if info, err := wrapped.Info(context.Background()); err != nil || info.Name != "echo" {
+		t.Errorf("Info = %+v, %v", info, err)
+	}
+
+	broken := &brokenInfoTool{funcTool: funcTool{name: "broken"}}
+	if _, err := applyErrorPassthrough(context.Background(), []tool.BaseTool{broken}, 3); err == nil {
+		t.Errorf("获取工具信息失败时应返回错误")
+	}
+}
+
+// brokenInfoTool: Info 返回错误的可调用工具
+type brokenInfoTool struct{ funcTool }
+
+func (b *brokenInfoTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return nil, errors.New("信息不可用")
+}
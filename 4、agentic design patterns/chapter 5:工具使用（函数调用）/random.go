@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+const (
+	maxDiceCount = 100                   // maxDiceCount: 一次最多掷的骰子数
+	maxDiceSides = 1000                  // maxDiceSides: 骰子最多的面数
+	maxRandomAbs = 1_000_000_000_000_000 // maxRandomAbs: int 操作的 min/max 绝对值上限，保证区间长度不溢出
+)
+
+// diceNotation: NdM 记法，N 省略时为 1，如 3d20、d6
+var diceNotation = regexp.MustCompile(`^(\d*)[dD](\d+)$`)
+
+// randomArgs: random 工具的参数
+type randomArgs struct {
+	Operation string `json:"operation" desc:"int：在 [min, max] 中取一个随机整数；dice：按 NdM 记法掷骰子；uuid：生成一个随机 UUID（v4）" required:"true" enum:"int,dice,uuid"`
+	Min       int64  `json:"min" desc:"int 操作的最小值（含）"`
+	Max       int64  `json:"max" desc:"int 操作的最大值（含），必须大于 min"`
+	Dice      string `json:"dice" desc:"dice 操作的骰子，NdM 表示掷 N 个 M 面骰子，如 3d20、2d6、d100"`
+}
+
+// randomTool: 随机整数、掷骰子和 UUID；rng 由种子确定，同一种子的调用序列结果相同
+type randomTool struct {
+	mu  sync.Mutex // mu: 同一步中的多个调用可能并行执行，rng 不是并发安全的
+	rng *rand.Rand
+}
+
+// NewRandomTool: 随机数工具，seed 决定全部结果：测试时传固定值以得到确定的结果，演示时传基于时间的种子
+func NewRandomTool(seed uint64) tool.InvokableTool {
+	t := &randomTool{rng: rand.New(rand.NewPCG(seed, seed))}
+	return NewStructTool("random", "生成随机整数、掷骰子（如 3d20，列出每次点数和总和）或生成 UUID", t.run)
+}
+
+// run: random 工具的实现
+func (t *randomTool) run(ctx context.Context, args randomArgs) (string, error) {
+	fmt.Printf("\n--- 🛠️ 工具调用：random，操作：'%s' ---\n", args.Operation)
+	result, err := t.evaluate(args)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("--- 工具结果：%s ---\n", result)
+	return result, nil
+}
+
+// evaluate: 按操作生成结果
+func (t *randomTool) evaluate(args randomArgs) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch args.Operation {
+	case "int":
+		if args.Min >= args.Max {
+			return "", fmt.Errorf("min（%d）必须小于 max（%d）", args.Min, args.Max)
+		}
+		if args.Min < -maxRandomAbs || args.Max > maxRandomAbs {
+			return "", fmt.Errorf("min 和 max 的绝对值不能超过 %d", int64(maxRandomAbs))
+		}
+		n := args.Min + t.rng.Int64N(args.Max-args.Min+1)
+		return fmt.Sprintf("[%d, %d] 中的随机整数：%d", args.Min, args.Max, n), nil
+	case "dice":
+		count, sides, err := parseDice(args.Dice)
+		if err != nil {
+			return "", err
+		}
+		return t.roll(count, sides), nil
+	case "uuid":
+		return "UUID：" + t.uuid(), nil
+	default:
+		return "", fmt.Errorf("未知操作: %s", args.Operation)
+	}
+}
+
+// parseDice: 解析 NdM 记法并检查骰子数和面数的上限
+func parseDice(notation string) (count, sides int, err error) {
+	m := diceNotation.FindStringSubmatch(strings.TrimSpace(notation))
+	if m == nil {
+		return 0, 0, fmt.Errorf("无效的骰子 %q（格式：NdM，如 3d20）", notation)
+	}
+	count = 1
+	if m[1] != "" {
+		if count, err = strconv.Atoi(m[1]); err != nil {
+			count = maxDiceCount + 1 // 数字过长，按超出上限处理
+		}
+	}
+	if sides, err = strconv.Atoi(m[2]); err != nil {
+		sides = maxDiceSides + 1
+	}
+	if count < 1 || count > maxDiceCount {
+		return 0, 0, fmt.Errorf("骰子数应在 1~%d 之间，实际为 %s", maxDiceCount, m[1])
+	}
+	if sides < 2 || sides > maxDiceSides {
+		return 0, 0, fmt.Errorf("骰子面数应在 2~%d 之间，实际为 %s", maxDiceSides, m[2])
+	}
+	return count, sides, nil
+}
+
+// roll: 掷 count 个 sides 面骰子，列出每次的点数和总和，如 "3d20：7 + 15 + 2 = 24"
+func (t *randomTool) roll(count, sides int) string {
+	rolls := make([]string, count)
+	sum := 0
+	for i := range rolls {
+		n := 1 + t.rng.IntN(sides)
+		sum += n
+		rolls[i] = strconv.Itoa(n)
+	}
+	if count == 1 {
+		return fmt.Sprintf("1d%d：%d", sides, sum)
+	}
+	return fmt.Sprintf("%dd%d：%s = %d", count, sides, strings.Join(rolls, " + "), sum)
+}
+
+// uuid: 由 rng 生成的 UUID v4（RFC 4122 变体），同一种子下可复现
+func (t *randomTool) uuid() string {
+	var b [16]byte
+	for i := 0; i < len(b); i += 8 {
+		v := t.rng.Uint64()
+		for j := 0; j < 8; j++ {
+			b[i+j] = byte(v >> (8 * j))
+		}
+	}
+	b[6] = b[6]&0x0f | 0x40 // 版本 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 变体
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseDice(t *testing.T) {
+	tests := []struct {
+		name      string
+		notation  string
+		wantCount int
+		wantSides int
+		wantErr   string
+	}{
+		{"标准记法", "3d20", 3, 20, ""},
+		{"省略个数", "d6", 1, 6, ""},
+		{"大写并带空白", " 2D100 ", 2, 100, ""},
+		{"上限", "100d1000", 100, 1000, ""},
+		{"格式错误", "3x20", 0, 0, "无效的骰子"},
+		{"空字符串", "", 0, 0, "无效的骰子"},
+		{"负数", "-1d6", 0, 0, "无效的骰子"},
+		{"零个骰子", "0d6", 0, 0, "骰子数应在 1~100 之间"},
+		{"骰子过多", "101d6", 0, 0, "骰子数应在 1~100 之间"},
+		{"个数溢出", "99999999999999999999d6", 0, 0, "骰子数应在 1~100 之间"},
+		{"一面骰子", "2d1", 0, 0, "骰子面数应在 2~1000 之间"},
+		{"面数过多", "2d1001", 0, 0, "骰子面数应在 2~1000 之间"},
+		{"面数溢出", "d99999999999999999999", 0, 0, "骰子面数应在 2~1000 之间"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, sides, err := parseDice(tt.notation)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseDice(%q) 错误 = %v，want 包含 %q", tt.notation, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || count != tt.wantCount || sides != tt.wantSides {
+				t.Errorf("parseDice(%q) = %d, %d, %v，want %d, %d", tt.notation, count, sides, err, tt.wantCount, tt.wantSides)
+			}
+		})
+	}
+}
+
+// newSeededRandom: 固定种子的随机数工具
+func newSeededRandom(seed uint64) *randomTool {
+	return &randomTool{rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+func TestRandomToolInt(t *testing.T) {
+	rt := newSeededRandom(1)
+	pattern := regexp.MustCompile(`^\[-3, 3\] 中的随机整数：(-?\d+)$`)
+	seen := map[int]bool{}
+	for i := 0; i < 500; i++ {
+		got, err := rt.evaluate(randomArgs{Operation: "int", Min: -3, Max: 3})
+		if err != nil {
+			t.Fatalf("evaluate: %v", err)
+		}
+		m := pattern.FindStringSubmatch(got)
+		if m == nil {
+			t.Fatalf("结果格式 = %q", got)
+		}
+		n, _ := strconv.Atoi(m[1])
+		if n < -3 || n > 3 {
+			t.Fatalf("%d 超出区间", n)
+		}
+		seen[n] = true
+	}
+	if len(seen) != 7 {
+		t.Errorf("500 次应覆盖区间内全部 7 个值，实际 %v", seen)
+	}
+
+	// 区间长度接近 int64 上限时不应溢出
+	if _, err := rt.evaluate(randomArgs{Operation: "int", Min: -maxRandomAbs, Max: maxRandomAbs}); err != nil {
+		t.Errorf("最大区间: %v", err)
+	}
+}
+
+func TestRandomToolErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    randomArgs
+		wantErr string
+	}{
+		{"min 等于 max", randomArgs{Operation: "int", Min: 5, Max: 5}, "min（5）必须小于 max（5）"},
+		{"min 大于 max", randomArgs{Operation: "int", Min: 6, Max: 1}, "必须小于"},
+		{"超出下限", randomArgs{Operation: "int", Min: -maxRandomAbs - 1, Max: 0}, "绝对值不能超过"},
+		{"超出上限", randomArgs{Operation: "int", Min: 0, Max: maxRandomAbs + 1}, "绝对值不能超过"},
+		{"无效骰子", randomArgs{Operation: "dice", Dice: "abc"}, "无效的骰子"},
+		{"未知操作", randomArgs{Operation: "coin"}, "未知操作: coin"},
+	}
+	rt := newSeededRandom(1)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rt.evaluate(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want 包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRandomToolDice(t *testing.T) {
+	rt := newSeededRandom(7)
+	got, err := rt.evaluate(randomArgs{Operation: "dice", Dice: "5d6"})
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	m := regexp.MustCompile(`^5d6：(.+) = (\d+)$`).FindStringSubmatch(got)
+	if m == nil {
+		t.Fatalf("结果格式 = %q", got)
+	}
+	rolls := strings.Split(m[1], " + ")
+	if len(rolls) != 5 {
+		t.Fatalf("应列出 5 次点数: %q", got)
+	}
+	sum := 0
+	for _, r := range rolls {
+		n, _ := strconv.Atoi(r)
+		if n < 1 || n > 6 {
+			t.Errorf("点数 %d 超出 1~6", n)
+		}
+		sum += n
+	}
+	if strconv.Itoa(sum) != m[2] {
+		t.Errorf("总和 %s，各次点数之和 %d", m[2], sum)
+	}
+
+	single, err := rt.evaluate(randomArgs{Operation: "dice", Dice: "d20"})
+	if err != nil || !regexp.MustCompile(`^1d20：\d+$`).MatchString(single) {
+		t.Errorf("单个骰子 = %q, %v", single, err)
+	}
+}
+
+func TestRandomToolUUID(t *testing.T) {
+	rt := newSeededRandom(3)
+	v4 := regexp.MustCompile(`^UUID：[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, _ := rt.evaluate(randomArgs{Operation: "uuid"})
+	second, _ := rt.evaluate(randomArgs{Operation: "uuid"})
+	if !v4.MatchString(first) || !v4.MatchString(second) {
+		t.Errorf("不是 UUID v4: %q %q", first, second)
+	}
+	if first == second {
+		t.Errorf("连续两次生成了相同的 UUID")
+	}
+}
+
+// TestRandomToolSeeded: 同一种子的调用序列结果相同，不同种子结果不同
+func TestRandomToolSeeded(t *testing.T) {
+	calls := []string{
+		`{"operation":"int","min":1,"max":1000000}`,
+		`{"operation":"dice","dice":"3d20"}`,
+		`{"operation":"uuid"}`,
+	}
+	run := func(seed uint64) string {
+		rt := NewRandomTool(seed)
+		var out []string
+		for _, args := range calls {
+			got, err := rt.InvokableRun(context.Background(), args)
+			if err != nil {
+				t.Fatalf("InvokableRun(%s): %v", args, err)
+			}
+			out = append(out, got)
+		}
+		return strings.Join(out, "\n")
+	}
+	if a, b := run(42), run(42); a != b {
+		t.Errorf("同一种子的结果不同:\n%s\n---\n%s", a, b)
+	}
+	if run(42) == run(43) {
+		t.Errorf("不同种子的结果相同")
+	}
+}
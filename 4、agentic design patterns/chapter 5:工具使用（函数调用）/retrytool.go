@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ch5/audit"
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// defaultRetryExclude: 默认不自动重试的工具：调用有副作用，失败的那次可能已经部分生效，重复执行不安全
+var defaultRetryExclude = []string{"write_file"}
+
+// RetryTool: 按 retry.Policy 重试瞬时失败（网络错误、5xx、限流、工具超时等）的工具装饰器
+// 重试次数用尽后把最后一次的错误作为工具结果（而不是 error）交给模型，模型可以道歉、换一种方式或如实告知用户；
+// 不可重试的错误原样返回。每次重试都通过 audit.MarkRetry 计入审计日志
+type RetryTool struct {
+	inner  tool.InvokableTool
+	name   string
+	policy retry.Policy
+}
+
+var _ tool.InvokableTool = (*RetryTool)(nil)
+
+// NewRetryTool: 包装一个可调用的工具，name 用于日志和失败说明
+func NewRetryTool(inner tool.InvokableTool, name string, policy retry.Policy) *RetryTool {
+	return &RetryTool{inner: inner, name: name, policy: policy}
+}
+
+func (t *RetryTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.inner.Info(ctx)
+}
+
+func (t *RetryTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	result, res, err := retry.Do(ctx, t.policy, func(ctx context.Context, attempt int) (string, error) {
+		if attempt > 1 {
+			audit.MarkRetry(ctx)
+			fmt.Printf("--- 🔁 重试工具 %s（第 %d 次尝试）---\n", t.name, attempt)
+		}
+		return t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+	})
+	if err == nil {
+		return result, nil
+	}
+	last := res.Errors[len(res.Errors)-1]
+	if ctx.Err() != nil || !t.retryable(last) {
+		return "", err
+	}
+	audit.MarkError(ctx, last)
+	msg := fmt.Sprintf("工具 %s 调用失败（已尝试 %d 次）：%v；可以稍后重试、换一种方式完成任务，或如实告知用户", t.name, res.Attempts, last)
+	fmt.Printf("--- ⚠️ %s ---\n", msg)
+	return msg, nil
+}
+
+// retryable: 使用策略中的判断，没有配置时使用 retry.IsRetryable
+func (t *RetryTool) retryable(err error) bool {
+	if t.policy.Retryable != nil {
+		return t.policy.Retryable(err)
+	}
+	return retry.IsRetryable(err)
+}
+
+// applyRetries: 按工具名给每个可调用的工具加上重试，attempts 中没有的工具使用 policy.MaxAttempts，
+// exclude 中的非幂等工具默认只尝试一次（attempts 中单独设置的次数仍然生效）
+// 尝试次数为 1 的工具也会包装：不重试，但瞬时失败同样作为结果交给模型
+func applyRetries(ctx context.Context, tools []tool.BaseTool, attempts map[string]int, policy retry.Policy, exclude []string) ([]tool.BaseTool, error) {
+	excluded := map[string]bool{}
+	for _, name := range exclude {
+		if name = strings.TrimSpace(name); name != "" {
+			excluded[name] = true
+		}
+	}
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = t
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		toolPolicy := policy
+		if excluded[info.Name] {
+			toolPolicy.MaxAttempts = 1
+		}
+		if n, ok := attempts[info.Name]; ok {
+			toolPolicy.MaxAttempts = n
+		}
+		wrapped[i] = NewRetryTool(invokable, info.Name, toolPolicy)
+	}
+	return wrapped, nil
+}
+
+// parseToolAttempts: 解析 "get_weather=5,write_file=1" 形式的按工具尝试次数配置
+func parseToolAttempts(spec string) (map[string]int, error) {
+	attempts := map[string]int{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("无效的配置 %q（格式：工具名=次数）", item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("无效的尝试次数 %q: 应为不小于 1 的整数", item)
+		}
+		attempts[name] = n
+	}
+	return attempts, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// testRetryPolicy: 最多尝试 n 次、重试时不等待的策略
+func testRetryPolicy(n int) retry.Policy {
+	return retry.Policy{MaxAttempts: n, Sleep: func(ctx context.Context, d time.Duration) error { return nil }}
+}
+
+// flakyTool: 前 failures 次返回 err，之后返回 "ok"
+func flakyTool(name string, failures int, err error) *funcTool {
+	f := &funcTool{name: name}
+	f.run = func(ctx context.Context, args string) (string, error) {
+		if int(f.calls.Load()) <= failures {
+			return "", err
+		}
+		return "ok", nil
+	}
+	return f
+}
+
+func TestRetryToolRecoversFromTransientErrors(t *testing.T) {
+	inner := flakyTool("get_weather", 2, errors.New("HTTP 503 Service Unavailable"))
+	got, err := NewRetryTool(inner, "get_weather", testRetryPolicy(3)).InvokableRun(context.Background(), "{}")
+	if err != nil || got != "ok" || inner.calls.Load() != 3 {
+		t.Errorf("InvokableRun = %q, %v，调用 %d 次", got, err, inner.calls.Load())
+	}
+}
+
+// TestRetryToolExhausted: 重试用尽后把最后一次的错误作为结果交给模型
+func TestRetryToolExhausted(t *testing.T) {
+	inner := flakyTool("get_weather", 5, errors.New("connection refused"))
+	got, err := NewRetryTool(inner, "get_weather", testRetryPolicy(3)).InvokableRun(context.Background(), "{}")
+	if err != nil {
+		t.Fatalf("重试用尽时不应返回 error: %v", err)
+	}
+	if want := "工具 get_weather 调用失败（已尝试 3 次）：connection refused"; !strings.HasPrefix(got, want) {
+		t.Errorf("结果 = %q，want 以 %q 开头", got, want)
+	}
+}
+
+func TestRetryToolNonRetryable(t *testing.T) {
+	boom := errors.New("参数无效")
+	inner := flakyTool("calculator", 5, boom)
+	_, err := NewRetryTool(inner, "calculator", testRetryPolicy(3)).InvokableRun(context.Background(), "{}")
+	if !errors.Is(err, boom) || inner.calls.Load() != 1 {
+		t.Errorf("不可重试的错误应原样返回且只尝试一次: %v，调用 %d 次", err, inner.calls.Load())
+	}
+}
+
+// TestRetryToolRetriesTimeouts: 超时包装器在重试包装器之内，每次尝试各自受超时约束，超时会被重试
+func TestRetryToolRetriesTimeouts(t *testing.T) {
+	var attempts atomic.Int32
+	inner := &funcTool{name: "slow"}
+	inner.run = func(ctx context.Context, args string) (string, error) {
+		if attempts.Add(1) < 3 {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "ok", nil
+	}
+	wrapped := NewRetryTool(NewToolWithTimeout(inner, 10*time.Millisecond), "slow", testRetryPolicy(3))
+	if got, err := wrapped.InvokableRun(context.Background(), "{}"); err != nil || got != "ok" || attempts.Load() != 3 {
+		t.Errorf("InvokableRun = %q, %v，尝试 %d 次", got, err, attempts.Load())
+	}
+
+	// 每次都超时：重试用尽后说明交给模型
+	hang := hangingTool("slow", nil, false)
+	got, err := NewRetryTool(NewToolWithTimeout(hang, 5*time.Millisecond), "slow", testRetryPolicy(2)).InvokableRun(context.Background(), "{}")
+	if err != nil || !strings.Contains(got, "已尝试 2 次）：工具 slow 执行超时（5ms）") || hang.calls.Load() != 2 {
+		t.Errorf("InvokableRun = %q, %v，调用 %d 次", got, err, hang.calls.Load())
+	}
+}
+
+// TestRetryToolParentCancel: 上层取消时不再重试，原样返回错误
+func TestRetryToolParentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	inner := &funcTool{name: "get_weather", run: func(ctx context.Context, args string) (string, error) {
+		cancel()
+		return "", errors.New("HTTP 503")
+	}}
+	_, err := NewRetryTool(inner, "get_weather", testRetryPolicy(3)).InvokableRun(ctx, "{}")
+	if err == nil || inner.calls.Load() != 1 {
+		t.Errorf("取消后错误 = %v，调用 %d 次", err, inner.calls.Load())
+	}
+}
+
+func TestApplyRetries(t *testing.T) {
+	transient := errors.New("HTTP 502 Bad Gateway")
+	tools := []tool.BaseTool{
+		flakyTool("get_weather", 10, transient),
+		flakyTool("write_file", 10, transient),
+		flakyTool("web_search", 10, transient),
+		flakyTool("read_file", 10, transient),
+	}
+	wrapped, err := applyRetries(context.Background(), tools, map[string]int{"web_search": 2, "read_file": 4},
+		testRetryPolicy(3), []string{" write_file ", "read_file", ""})
+	if err != nil {
+		t.Fatalf("applyRetries: %v", err)
+	}
+	for _, w := range wrapped {
+		if _, err := w.(tool.InvokableTool).InvokableRun(context.Background(), "{}"); err != nil {
+			t.Fatalf("InvokableRun: %v", err)
+		}
+	}
+	// 默认 3 次；write_file 非幂等只尝试 1 次；单独设置的次数优先于排除列表
+	for i, want := range []int32{3, 1, 2, 4} {
+		if got := tools[i].(*funcTool).calls.Load(); got != want {
+			t.Errorf("%s 尝试 %d 次，want %d", tools[i].(*funcTool).name, got, want)
+		}
+	}
+}
+
+func TestParseToolAttempts(t *testing.T) {
+	got, err := parseToolAttempts(" get_weather=5, write_file = 1 ,")
+	if err != nil || got["get_weather"] != 5 || got["write_file"] != 1 || len(got) != 2 {
+		t.Errorf("parseToolAttempts = %v, %v", got, err)
+	}
+	for _, spec := range []string{"get_weather", "=2", "a=x", "a=0"} {
+		if _, err := parseToolAttempts(spec); err == nil {
+			t.Errorf("parseToolAttempts(%q) 应返回错误", spec)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	defaultSearchResults    = 5                // defaultSearchResults: 未指定 max_results 时返回的结果数
+	maxSearchResults        = 10               // maxSearchResults: max_results 的上限
+	defaultSearchByteBudget = 2000             // defaultSearchByteBudget: 返回给模型的结果总字节数上限
+	defaultSearchTimeout    = 10 * time.Second // defaultSearchTimeout: 一次搜索请求的超时
+	searchFailure           = "搜索失败："          // searchFailure: 失败说明的前缀
+)
+
+// searchProvider: 搜索接口的风格
+type searchProvider string
+
+const (
+	providerSearxNG searchProvider = "searxng" // providerSearxNG: GET <url>?q=...&format=json
+	providerTavily  searchProvider = "tavily"  // providerTavily: POST <url>，JSON 请求体带 api_key、query、max_results
+)
+
+// searchResult: 一条搜索结果
+type searchResult struct {
+	Title   string `json:"title"`
+	Snippet string `json:"content"` // Snippet: SearxNG 和 Tavily 都把摘要放在 content 字段
+	URL     string `json:"url"`
+}
+
+// offlineSearchResults: SEARCH_OFFLINE=1 时对任何查询返回的固定结果
+var offlineSearchResults = []searchResult{
+	{Title: "Eino：字节跳动开源的 Go 大模型应用开发框架", Snippet: "Eino 提供组件抽象、编排（Chain、Graph）和流式处理能力，内置 ReAct Agent 等常用流程。", URL: "https://github.com/cloudwego/eino"},
+	{Title: "ReAct: Synergizing Reasoning and Acting in Language Models", Snippet: "ReAct 让模型交替产生推理轨迹和动作（如调用工具），根据观察结果决定下一步。", URL: "https://arxiv.org/abs/2210.03629"},
+	{Title: "Function calling - OpenAI API", Snippet: "函数调用让模型根据 JSON Schema 生成结构化的参数，由应用执行函数并把结果交还给模型。", URL: "https://platform.openai.com/docs/guides/function-calling"},
+}
+
+// SearchTool: 网络搜索工具，返回编号的标题、链接和摘要，总长度受 ByteBudget 限制
+// 接口风格由 Provider 决定（SearxNG 或 Tavily）；失败时返回可读的说明而不是 error，模型可以换个查询词或直接作答；
+// 瞬时失败（5xx、连接被拒绝等，见 retry.IsRetryable）返回 error，交给 RetryTool 重试
+type SearchTool struct {
+	Client     *http.Client
+	APIURL     string // APIURL: 搜索接口地址，为空且非离线模式时工具返回"未配置"
+	APIKey     string // APIKey: Tavily 风格接口的密钥
+	Provider   searchProvider
+	Timeout    time.Duration // Timeout: 一次搜索请求的超时
+	ByteBudget int           // ByteBudget: 返回给模型的结果总字节数上限，<=0 时不限制
+	Offline    bool          // Offline: 使用固定结果，不访问网络
+}
+
+// NewSearchTool: 从环境变量配置搜索工具
+// SEARCH_API_URL / SEARCH_API_KEY 指定接口；SEARCH_PROVIDER 取 searxng 或 tavily，未设置时有密钥用 tavily、否则用 searxng；
+// SEARCH_OFFLINE=1 时使用固定结果
+func NewSearchTool() *SearchTool {
+	t := &SearchTool{
+		Client:     http.DefaultClient,
+		APIURL:     os.Getenv("SEARCH_API_URL"),
+		APIKey:     os.Getenv("SEARCH_API_KEY"),
+		Provider:   searchProvider(os.Getenv("SEARCH_PROVIDER")),
+		Timeout:    defaultSearchTimeout,
+		ByteBudget: defaultSearchByteBudget,
+		Offline:    os.Getenv("SEARCH_OFFLINE") == "1",
+	}
+	if t.Provider == "" {
+		t.Provider = providerSearxNG
+		if t.APIKey != "" {
+			t.Provider = providerTavily
+		}
+	}
+	return t
+}
+
+func (t *SearchTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "web_search",
+		Desc: "搜索网络获取最新信息，返回编号的标题、链接和摘要",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"query": {
+				Type:     schema.String,
+				Desc:     "搜索关键词",
+				Required: true,
+			},
+			"max_results": {
+				Type: schema.Integer,
+				Desc: fmt.Sprintf("最多返回的结果数，默认 %d，最大 %d", defaultSearchResults, maxSearchResults),
+			},
+		}),
+	}, nil
+}
+
+func (t *SearchTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Query      string `json:"query"`
+		MaxResults int    `json:"max_results"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return fmt.Sprintf(searchFailure+"参数无效（%v），需要形如 {\"query\": \"关键词\"} 的参数", err), nil
+	}
+	query := strings.TrimSpace(args.Query)
+	if query == "" {
+		return searchFailure + "缺少搜索关键词", nil
+	}
+	limit := args.MaxResults
+	if limit <= 0 {
+		limit = defaultSearchResults
+	}
+	limit = min(limit, maxSearchResults)
+
+	fmt.Printf("\n--- 🛠️ 工具调用：web_search，查询：'%s'，最多 %d 条 ---\n", query, limit)
+
+	results, err := t.search(ctx, query, limit)
+	var output string
+	switch {
+	case err != nil && retry.IsRetryable(err):
+		fmt.Printf("--- 工具错误：%v ---\n", err)
+		return "", fmt.Errorf("搜索失败: %w", err)
+	case err != nil:
+		output = searchFailure + err.Error()
+	case len(results) == 0:
+		output = fmt.Sprintf("没有找到与 %q 相关的结果，可以换个关键词再试", query)
+	default:
+		output = formatSearchResults(dedupeResults(results, limit), t.ByteBudget)
+	}
+	fmt.Printf("--- 工具结果：%d 字节 ---\n", len(output))
+	return output, nil
+}
+
+// Cacheable: 失败说明不缓存（网络错误可能是暂时的）
+func (t *SearchTool) Cacheable(result string) bool {
+	return !strings.HasPrefix(result, searchFailure)
+}
+
+// search: 按 Provider 调用搜索接口
+func (t *SearchTool) search(ctx context.Context, query string, limit int) ([]searchResult, error) {
+	if t.Offline {
+		return offlineSearchResults, nil
+	}
+	if t.APIURL == "" {
+		return nil, fmt.Errorf("搜索接口未配置（设置 SEARCH_API_URL，或设置 SEARCH_OFFLINE=1 使用固定结果）")
+	}
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	var req *http.Request
+	var err error
+	switch t.Provider {
+	case providerTavily:
+		body, _ := json.Marshal(map[string]any{"api_key": t.APIKey, "query": query, "max_results": limit})
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, t.APIURL, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+t.APIKey)
+		}
+	case providerSearxNG:
+		params := url.Values{"q": {query}, "format": {"json"}}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, t.APIURL+"?"+params.Encode(), nil)
+	default:
+		return nil, fmt.Errorf("未知的搜索接口类型: %s（可用: searxng, tavily）", t.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("HTTP %s", resp.Status)
+	}
+	var payload struct {
+		Results []searchResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	return payload.Results, nil
+}
+
+// dedupeResults: 按 URL 去重（忽略片段和末尾的 /），去掉没有 URL 的结果，最多保留 limit 条
+func dedupeResults(results []searchResult, limit int) []searchResult {
+	seen := make(map[string]bool, len(results))
+	var unique []searchResult
+	for _, result := range results {
+		key := strings.TrimSpace(result.URL)
+		if i := strings.IndexByte(key, '#'); i >= 0 {
+			key = key[:i]
+		}
+		key = strings.ToLower(strings.TrimSuffix(key, "/"))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, result)
+		if len(unique) == limit {
+			break
+		}
+	}
+	return unique
+}
+
+// formatSearchResults: 编号的 "标题 / 链接 / 摘要" 文本；超出 budget 时截短最后一条的摘要，放不下的结果整条省略
+// （末尾一行简短的省略说明不计入 budget）
+func formatSearchResults(results []searchResult, budget int) string {
+	var sb strings.Builder
+	for i, result := range results {
+		head := fmt.Sprintf("%d. %s\n   %s\n", i+1, strings.TrimSpace(result.Title), strings.TrimSpace(result.URL))
+		snippet := strings.Join(strings.Fields(result.Snippet), " ")
+		entry := head + "   " + snippet + "\n"
+		if budget > 0 && sb.Len()+len(entry) > budget {
+			// 至少保留标题和链接，摘要按剩余字节截短
+			remaining := budget - sb.Len() - len(head) - len("   …\n")
+			if remaining < 0 {
+				fmt.Fprintf(&sb, "（另有 %d 条结果因长度限制省略）", len(results)-i)
+				break
+			}
+			entry = head + "   " + truncateUTF8(snippet, remaining) + "…\n"
+			sb.WriteString(entry)
+			if i+1 < len(results) {
+				fmt.Fprintf(&sb, "（另有 %d 条结果因长度限制省略）", len(results)-i-1)
+			}
+			break
+		}
+		sb.WriteString(entry)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// truncateUTF8: 截取不超过 n 字节的前缀，不切断多字节字符
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
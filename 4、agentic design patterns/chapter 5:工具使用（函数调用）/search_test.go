@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// searchServer: 记录收到的请求并返回 body 的搜索接口
+func searchServer(t *testing.T, status int, body string, requests *[]*http.Request, bodies *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		data, _ := io.ReadAll(r.Body)
+		*bodies = append(*bodies, string(data))
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const twoResults = `{"results":[
+	{"title":"Eino","url":"https://github.com/cloudwego/eino","content":"Go 大模型\n应用框架"},
+	{"title":"Eino 重复","url":"https://github.com/cloudwego/eino/#readme","content":"重复"},
+	{"title":"ReAct","url":"https://arxiv.org/abs/2210.03629","content":"推理与行动"}
+]}`
+
+func TestSearchToolSearxNG(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	srv := searchServer(t, http.StatusOK, twoResults, &requests, &bodies)
+	s := &SearchTool{Client: srv.Client(), APIURL: srv.URL, Provider: providerSearxNG, Timeout: time.Second}
+	got, err := s.InvokableRun(context.Background(), `{"query": " eino 框架 "}`)
+	if err != nil {
+		t.Fatalf("InvokableRun: %v", err)
+	}
+	want := "1. Eino\n   https://github.com/cloudwego/eino\n   Go 大模型 应用框架\n2. ReAct\n   https://arxiv.org/abs/2210.03629\n   推理与行动"
+	if got != want {
+		t.Errorf("结果 =\n%s\nwant\n%s", got, want)
+	}
+	if len(requests) != 1 || requests[0].Method != http.MethodGet || requests[0].URL.Query().Get("q") != "eino 框架" ||
+		requests[0].URL.Query().Get("format") != "json" {
+		t.Errorf("SearxNG 请求 = %+v", requests)
+	}
+}
+
+func TestSearchToolTavily(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	srv := searchServer(t, http.StatusOK, twoResults, &requests, &bodies)
+	s := &SearchTool{Client: srv.Client(), APIURL: srv.URL, APIKey: "k", Provider: providerTavily}
+	if _, err := s.InvokableRun(context.Background(), `{"query": "eino", "max_results": 50}`); err != nil {
+		t.Fatalf("InvokableRun: %v", err)
+	}
+	if requests[0].Method != http.MethodPost || requests[0].Header.Get("Authorization") != "Bearer k" {
+		t.Errorf("Tavily 请求 = %s %v", requests[0].Method, requests[0].Header)
+	}
+	var body struct {
+		APIKey     string `json:"api_key"`
+		Query      string `json:"query"`
+		MaxResults int    `json:"max_results"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &body); err != nil {
+		t.Fatalf("请求体 %q: %v", bodies[0], err)
+	}
+	// max_results 超过上限时按上限请求
+	if body.APIKey != "k" || body.Query != "eino" || body.MaxResults != maxSearchResults {
+		t.Errorf("请求体 = %+v", body)
+	}
+}
+
+func TestSearchToolFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		tool    SearchTool
+		args    string
+		want    string
+		wantErr bool // wantErr: 瞬时失败，返回 error 交给 RetryTool
+	}{
+		{name: "参数无效", args: `{"query": 1}`, want: searchFailure + "参数无效"},
+		{name: "缺少关键词", args: `{"query": " "}`, want: searchFailure + "缺少搜索关键词"},
+		{name: "未配置接口", tool: SearchTool{Provider: providerSearxNG}, args: `{"query": "x"}`, want: "搜索接口未配置"},
+		{name: "未知接口类型", tool: SearchTool{APIURL: "http://127.0.0.1:1", Provider: "bing"}, args: `{"query": "x"}`, want: "未知的搜索接口类型: bing"},
+		{name: "没有结果", status: http.StatusOK, body: `{"results":[]}`, args: `{"query": "x"}`, want: `没有找到与 "x" 相关的结果`},
+		{name: "401", status: http.StatusUnauthorized, args: `{"query": "x"}`, want: searchFailure + "HTTP 401"},
+		{name: "响应无法解析", status: http.StatusOK, body: `[]`, args: `{"query": "x"}`, want: "解析响应失败"},
+		{name: "502", status: http.StatusBadGateway, args: `{"query": "x"}`, want: "HTTP 502", wantErr: true},
+		{name: "429", status: http.StatusTooManyRequests, args: `{"query": "x"}`, want: "HTTP 429", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.tool
+			if tt.status != 0 {
+				var requests []*http.Request
+				var bodies []string
+				srv := searchServer(t, tt.status, tt.body, &requests, &bodies)
+				s = SearchTool{Client: srv.Client(), APIURL: srv.URL, Provider: providerSearxNG}
+			}
+			got, err := s.InvokableRun(context.Background(), tt.args)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("错误 = %v，want 包含 %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil || !strings.Contains(got, tt.want) {
+				t.Errorf("结果 = %q, %v，want 包含 %q", got, err, tt.want)
+			}
+			if strings.HasPrefix(got, searchFailure) && s.Cacheable(got) {
+				t.Errorf("失败说明不应缓存")
+			}
+		})
+	}
+}
+
+func TestSearchToolOffline(t *testing.T) {
+	s := &SearchTool{Offline: true, ByteBudget: defaultSearchByteBudget}
+	got, err := s.InvokableRun(context.Background(), `{"query": "eino", "max_results": 2}`)
+	if err != nil || !strings.HasPrefix(got, "1. Eino") || !strings.Contains(got, "2. ReAct") || strings.Contains(got, "3.") {
+		t.Errorf("离线结果 = %q, %v", got, err)
+	}
+	if !s.Cacheable(got) {
+		t.Errorf("成功的结果应可缓存")
+	}
+}
+
+func TestDedupeResults(t *testing.T) {
+	results := []searchResult{
+		{Title: "a", URL: "https://A.com/x/"},
+		{Title: "a 重复", URL: "https://a.com/x#top"},
+		{Title: "没有链接", URL: " "},
+		{Title: "b", URL: "https://b.com"},
+		{Title: "c", URL: "https://c.com"},
+	}
+	var titles []string
+	for _, r := range dedupeResults(results, 2) {
+		titles = append(titles, r.Title)
+	}
+	if got := strings.Join(titles, ","); got != "a,b" {
+		t.Errorf("dedupeResults = %s，want a,b", got)
+	}
+}
+
+func TestFormatSearchResultsBudget(t *testing.T) {
+	results := []searchResult{
+		{Title: "一", URL: "u1", Snippet: strings.Repeat("摘要", 10)},
+		{Title: "二", URL: "u2", Snippet: strings.Repeat("很长的摘要", 40)},
+		{Title: "三", URL: "u3", Snippet: "x"},
+	}
+	full := formatSearchResults(results, 0)
+	if strings.Contains(full, "…") || !strings.Contains(full, "3. 三") {
+		t.Errorf("不限制长度时应完整输出: %q", full)
+	}
+
+	budget := 200
+	got := formatSearchResults(results, budget)
+	body, note, _ := strings.Cut(got, "（另有")
+	if len(body) > budget {
+		t.Errorf("结果 %d 字节，超出上限 %d", len(body), budget)
+	}
+	if !strings.Contains(got, "2. 二") || !strings.Contains(got, "…") || note != " 1 条结果因长度限制省略）" {
+		t.Errorf("截短后的结果 = %q", got)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("截短切断了多字节字符")
+	}
+
+	// 剩余空间连标题和链接都放不下时整条省略
+	got = formatSearchResults(results, 60)
+	if strings.Contains(got, "2. 二") || !strings.HasSuffix(got, "（另有 2 条结果因长度限制省略）") {
+		t.Errorf("放不下的结果应整条省略: %q", got)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 2, "ab"},
+		{"你好", 4, "你"},
+		{"你好", 2, ""},
+		{"a你", 3, "a"},
+	}
+	for _, tt := range tests {
+		if got := truncateUTF8(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncateUTF8(%q, %d) = %q，want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
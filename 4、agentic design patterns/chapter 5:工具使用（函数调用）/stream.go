@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	einoagent "github.com/cloudwego/eino/flow/agent"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+)
+
+// answerHeader: 最终回答前打印的标题
+const answerHeader = "\n--- ✅ 最终 Agent 响应 ---"
+
+// queryOutcome: 一次查询的结果，用于运行结束后的汇总
+type queryOutcome struct {
+	Query  string
+	Path   string          // Path: 回答查询的路径（pathAgent 或 pathDirect）
+	Answer *schema.Message // Answer: 最终回答（流式时由分块拼接而成，中途出错时只含已收到的部分）
+	Err    error
+}
+
+// queryRunner: 回答查询的方式：ReAct Agent（agentRunner）或不带工具的直接回答链（chainRunner）
+type queryRunner interface {
+	Generate(ctx context.Context, input []*schema.Message) (*schema.Message, error)
+	Stream(ctx context.Context, input []*schema.Message) (*schema.StreamReader[*schema.Message], error)
+}
+
+// agentRunner: 用 ReAct Agent 回答，opts 在每次调用时传给 Agent（如审计回调）
+type agentRunner struct {
+	agent *react.Agent
+	opts  []einoagent.AgentOption
+}
+
+func (r agentRunner) Generate(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+	return r.agent.Generate(ctx, input, r.opts...)
+}
+
+func (r agentRunner) Stream(ctx context.Context, input []*schema.Message) (*schema.StreamReader[*schema.Message], error) {
+	return r.agent.Stream(ctx, input, r.opts...)
+}
+
+// runQuery: 运行一次查询并打印最终回答
+// stream 为 true 时用 Stream 边收边打印，工具调用仍由审计回调记录；否则用 Generate 等待完整回答
+func runQuery(ctx context.Context, runner queryRunner, messages []*schema.Message, stream bool, w io.Writer) (*schema.Message, error) {
+	if !stream {
+		response, err := runner.Generate(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(w, answerHeader)
+		fmt.Fprintln(w, response.Content)
+		return response, nil
+	}
+
+	reader, err := runner.Stream(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return printStream(reader, w)
+}
+
+// printStream: 逐块打印流式回答并拼接为完整消息；中途出错时返回已收到的部分和错误
+func printStream(reader *schema.StreamReader[*schema.Message], w io.Writer) (*schema.Message, error) {
+	defer reader.Close()
+	var chunks []*schema.Message
+	for {
+		chunk, err := reader.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			partial, _ := concatChunks(chunks)
+			if len(chunks) > 0 {
+				fmt.Fprintln(w)
+			}
+			return partial, fmt.Errorf("流式输出中断（已收到 %d 个分块）: %w", len(chunks), err)
+		}
+		if len(chunks) == 0 {
+			fmt.Fprintln(w, answerHeader) // 工具调用的日志都在第一个分块之前打印完
+		}
+		chunks = append(chunks, chunk)
+		fmt.Fprint(w, chunk.Content)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("模型没有返回任何内容")
+	}
+	fmt.Fprintln(w)
+	return concatChunks(chunks)
+}
+
+// concatChunks: 把流式分块拼接为一条完整消息
+func concatChunks(chunks []*schema.Message) (*schema.Message, error) {
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	message, err := schema.ConcatMessages(chunks)
+	if err != nil {
+		return nil, fmt.Errorf("拼接流式分块失败: %w", err)
+	}
+	return message, nil
+}
+
+// printOutcomes: 打印每个查询的路径和结果：成功、失败或中途中断（附已收到的回答字数）
+func printOutcomes(w io.Writer, outcomes []queryOutcome) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "查询\t路径\t结果\t回答字数")
+	for _, o := range outcomes {
+		status := "✅ 完成"
+		switch {
+		case o.Err != nil && o.Answer != nil:
+			status = "⚠️ 中断"
+		case o.Err != nil:
+			status = "🛑 失败"
+		}
+		length := 0
+		if o.Answer != nil {
+			length = utf8.RuneCountInString(strings.TrimSpace(o.Answer.Content))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", o.Query, o.Path, status, length)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// scriptedRunner: 按预设的分块和错误回答查询的 queryRunner
+type scriptedRunner struct {
+	chunks    []string
+	streamErr error // streamErr: 发完 chunks 后在流中返回的错误
+	callErr   error // callErr: Generate / Stream 直接返回的错误
+}
+
+func (r scriptedRunner) Generate(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+	if r.callErr != nil {
+		return nil, r.callErr
+	}
+	return schema.AssistantMessage(strings.Join(r.chunks, ""), nil), nil
+}
+
+func (r scriptedRunner) Stream(ctx context.Context, input []*schema.Message) (*schema.StreamReader[*schema.Message], error) {
+	if r.callErr != nil {
+		return nil, r.callErr
+	}
+	reader, writer := schema.Pipe[*schema.Message](len(r.chunks) + 1)
+	go func() {
+		defer writer.Close()
+		for _, c := range r.chunks {
+			writer.Send(schema.AssistantMessage(c, nil), nil)
+		}
+		if r.streamErr != nil {
+			writer.Send(nil, r.streamErr)
+		}
+	}()
+	return reader, nil
+}
+
+func TestRunQuery(t *testing.T) {
+	boom := errors.New("连接断开")
+	tests := []struct {
+		name       string
+		runner     scriptedRunner
+		stream     bool
+		wantAnswer string // wantAnswer: 返回的回答，"-" 表示应为 nil
+		wantErr    string
+		wantOutput string
+	}{
+		{name: "流式完整回答", runner: scriptedRunner{chunks: []string{"你", "好", "！"}}, stream: true,
+			wantAnswer: "你好！", wantOutput: answerHeader + "\n你好！\n"},
+		{name: "一次性回答", runner: scriptedRunner{chunks: []string{"你", "好"}}, stream: false,
+			wantAnswer: "你好", wantOutput: answerHeader + "\n你好\n"},
+		{name: "中途中断保留已收到的部分", runner: scriptedRunner{chunks: []string{"一", "二"}, streamErr: boom}, stream: true,
+			wantAnswer: "一二", wantErr: "流式输出中断（已收到 2 个分块）: 连接断开", wantOutput: answerHeader + "\n一二\n"},
+		{name: "第一个分块前出错", runner: scriptedRunner{streamErr: boom}, stream: true,
+			wantAnswer: "-", wantErr: "已收到 0 个分块", wantOutput: ""},
+		{name: "没有内容", runner: scriptedRunner{}, stream: true,
+			wantAnswer: "-", wantErr: "模型没有返回任何内容"},
+		{name: "流式调用失败", runner: scriptedRunner{callErr: boom}, stream: true, wantAnswer: "-", wantErr: "连接断开"},
+		{name: "一次性调用失败", runner: scriptedRunner{callErr: boom}, stream: false, wantAnswer: "-", wantErr: "连接断开"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			answer, err := runQuery(context.Background(), tt.runner, []*schema.Message{schema.UserMessage("hi")}, tt.stream, &out)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+			if tt.wantAnswer == "-" {
+				if answer != nil {
+					t.Errorf("回答 = %q，want nil", answer.Content)
+				}
+			} else if answer == nil || answer.Content != tt.wantAnswer {
+				t.Errorf("回答 = %v，want %q", answer, tt.wantAnswer)
+			}
+			if out.String() != tt.wantOutput {
+				t.Errorf("输出 = %q，want %q", out.String(), tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestPrintOutcomes(t *testing.T) {
+	var out bytes.Buffer
+	printOutcomes(&out, []queryOutcome{
+		{Query: "q1", Path: pathAgent, Answer: schema.AssistantMessage(" 你好 ", nil)},
+		{Query: "q2", Path: pathAgent, Answer: schema.AssistantMessage("一半", nil), Err: errors.New("中断")},
+		{Query: "q3", Path: pathDirect, Err: errors.New("失败")},
+	})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("输出 =\n%s", out.String())
+	}
+	for i, want := range []string{"✅ 完成", "⚠️ 中断", "🛑 失败"} {
+		if !strings.Contains(lines[i+1], want) {
+			t.Errorf("第 %d 行 = %q，want 包含 %q", i+2, lines[i+1], want)
+		}
+	}
+	if !strings.HasSuffix(lines[1], "2") || !strings.Contains(lines[3], pathDirect) {
+		t.Errorf("输出 =\n%s", out.String())
+	}
+}
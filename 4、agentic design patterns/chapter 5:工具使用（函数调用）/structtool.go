@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// StructTool: 由参数结构体 T 生成的工具：ToolInfo 从 T 的字段和标签推导，参数先校验再解码为 T 交给 fn
+//
+// 支持的字段标签：
+//
+//	json:"name"          参数名（与 encoding/json 相同，"-" 表示忽略该字段）
+//	desc:"说明"          参数说明
+//	required:"true"      必填参数
+//	enum:"add,subtract"  字符串参数的可选值
+//
+// 支持的字段类型：string、bool、各种整数和浮点数、切片和数组、嵌套结构体、键为 string 的 map，以及它们的指针；
+// 实现 encoding.TextUnmarshaler 的类型（如 time.Time）视为字符串；匿名嵌入的结构体字段展开到外层（与 encoding/json 一致）。
+// 直接或间接引用自身的结构体无法展开为有限的参数定义，返回错误
+type StructTool[T any] struct {
+	name   string
+	desc   string
+	params map[string]*schema.ParameterInfo
+	fn     func(ctx context.Context, args T) (string, error)
+}
+
+var _ tool.InvokableTool = (*StructTool[struct{}])(nil)
+
+// NewStructTool: 用参数结构体 T 和处理函数创建工具；T 不是结构体或含有不支持的字段类型时 panic（属于编程错误，启动时即可发现）
+func NewStructTool[T any](name, desc string, fn func(ctx context.Context, args T) (string, error)) tool.InvokableTool {
+	params, err := structParams(reflect.TypeFor[T](), map[reflect.Type]bool{})
+	if err != nil {
+		panic(fmt.Sprintf("工具 %s 的参数类型无效: %v", name, err))
+	}
+	return &StructTool[T]{name: name, desc: desc, params: params, fn: fn}
+}
+
+func (t *StructTool[T]) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name:        t.name,
+		Desc:        t.desc,
+		ParamsOneOf: schema.NewParamsOneOfByParams(t.params),
+	}, nil
+}
+
+func (t *StructTool[T]) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	args, err := t.parse(argumentsInJSON)
+	if err != nil {
+		return "", fmt.Errorf("无效的参数: %w", err)
+	}
+	return t.fn(ctx, args)
+}
+
+// parse: 先按推导出的参数定义校验必填字段和枚举值，再解码为 T
+func (t *StructTool[T]) parse(argumentsInJSON string) (T, error) {
+	var args T
+	if strings.TrimSpace(argumentsInJSON) == "" {
+		argumentsInJSON = "{}" // 没有参数的调用，部分模型会给空字符串
+	}
+	decoder := json.NewDecoder(strings.NewReader(argumentsInJSON))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return args, fmt.Errorf("不是合法的 JSON: %w", err)
+	}
+	if err := validateParams(t.params, value, ""); err != nil {
+		return args, err
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return args, fmt.Errorf("字段 %s 的类型应为 %s，实际为 %s", typeErr.Field, jsonType(typeErr.Type), typeErr.Value)
+		}
+		return args, err
+	}
+	return args, nil
+}
+
+// textUnmarshalerType: encoding.TextUnmarshaler 接口类型
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// structParams: 由结构体类型推导参数定义；visiting 记录正在展开的结构体，用于发现自我引用
+func structParams(typ reflect.Type, visiting map[reflect.Type]bool) (map[string]*schema.ParameterInfo, error) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("参数类型必须是结构体，实际为 %s", typ)
+	}
+	if visiting[typ] {
+		return nil, fmt.Errorf("类型 %s 引用了自身，无法展开为参数定义", typ)
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ) // 只拦截引用链上的重复，同一类型出现在多个字段中是允许的
+	params := map[string]*schema.ParameterInfo{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		// 没有 json 名的匿名结构体字段：其字段展开到外层
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				inner, err := structParams(embedded, visiting)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", field.Name, err)
+				}
+				for key, param := range inner {
+					if _, ok := params[key]; !ok { // 外层字段优先
+						params[key] = param
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		param, err := fieldParam(field.Type, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name, err)
+		}
+		param.Desc = field.Tag.Get("desc")
+		param.Required = field.Tag.Get("required") == "true"
+		if enum := field.Tag.Get("enum"); enum != "" {
+			if param.Type != schema.String {
+				return nil, fmt.Errorf("%s: enum 只能用于字符串字段", field.Name)
+			}
+			for _, item := range strings.Split(enum, ",") {
+				param.Enum = append(param.Enum, strings.TrimSpace(item))
+			}
+		}
+		params[name] = param
+	}
+	return params, nil
+}
+
+// fieldParam: 一个字段类型对应的参数定义（不含说明、必填和枚举）
+func fieldParam(typ reflect.Type, visiting map[reflect.Type]bool) (*schema.ParameterInfo, error) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	// 自行从文本解码的类型（如 time.Time、net.IP）在 JSON 中是字符串
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return &schema.ParameterInfo{Type: schema.String}, nil
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return &schema.ParameterInfo{Type: schema.String}, nil
+	case reflect.Bool:
+		return &schema.ParameterInfo{Type: schema.Boolean}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema.ParameterInfo{Type: schema.Integer}, nil
+	case reflect.Float32, reflect.Float64:
+		return &schema.ParameterInfo{Type: schema.Number}, nil
+	case reflect.Slice, reflect.Array:
+		elem, err := fieldParam(typ.Elem(), visiting)
+		if err != nil {
+			return nil, fmt.Errorf("元素: %w", err)
+		}
+		return &schema.ParameterInfo{Type: schema.Array, ElemInfo: elem}, nil
+	case reflect.Struct:
+		sub, err := structParams(typ, visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &schema.ParameterInfo{Type: schema.Object, SubParams: sub}, nil
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map 的键必须是 string，实际为 %s", typ.Key())
+		}
+		if _, err := fieldParam(typ.Elem(), visiting); err != nil {
+			return nil, fmt.Errorf("map 的值: %w", err)
+		}
+		return &schema.ParameterInfo{Type: schema.Object}, nil // 键不固定，不列出子参数
+	default:
+		return nil, fmt.Errorf("不支持的字段类型 %s", typ)
+	}
+}
+
+// validateParams: 检查对象中缺少的必填字段（一次列出全部）和不在可选值中的字符串，递归检查嵌套对象和数组元素
+func validateParams(params map[string]*schema.ParameterInfo, value any, path string) error {
+	if value == nil {
+		value = map[string]any{}
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		if path == "" {
+			return fmt.Errorf("参数应为 JSON 对象")
+		}
+		return nil // 类型不符由解码时报告
+	}
+	var missing []string
+	for name, param := range params {
+		if item, ok := object[name]; !ok || item == nil {
+			if param.Required {
+				missing = append(missing, path+name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("缺少必填字段: %s", strings.Join(missing, ", "))
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names) // 错误信息稳定
+	for _, name := range names {
+		if param, ok := params[name]; ok && object[name] != nil {
+			if err := validateValue(param, object[name], path+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateValue: 校验单个参数值的枚举，并递归进入对象和数组
+func validateValue(param *schema.ParameterInfo, value any, path string) error {
+	switch param.Type {
+	case schema.String:
+		if s, ok := value.(string); ok && len(param.Enum) > 0 && !slices.Contains(param.Enum, s) {
+			return fmt.Errorf("字段 %s 的取值 %q 无效，可选值: %s", path, s, strings.Join(param.Enum, ", "))
+		}
+	case schema.Object:
+		if param.SubParams != nil {
+			return validateParams(param.SubParams, value, path+".")
+		}
+	case schema.Array:
+		if items, ok := value.([]any); ok && param.ElemInfo != nil {
+			for i, item := range items {
+				if item == nil {
+					continue
+				}
+				if err := validateValue(param.ElemInfo, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonType: Go 类型对应的 JSON 类型名，用于类型错误的提示
+func jsonType(typ reflect.Type) string {
+	param, err := fieldParam(typ, map[reflect.Type]bool{})
+	if err != nil {
+		return typ.String()
+	}
+	return string(param.Type)
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// derive: 推导 T 的参数定义
+func derive[T any](t *testing.T) map[string]*schema.ParameterInfo {
+	t.Helper()
+	params, err := structParams(reflect.TypeFor[T](), map[reflect.Type]bool{})
+	if err != nil {
+		t.Fatalf("structParams(%s): %v", reflect.TypeFor[T](), err)
+	}
+	return params
+}
+
+type point struct {
+	X float64 `json:"x" required:"true"`
+	Y float64 `json:"y"`
+}
+
+type Embedded struct {
+	Shared string `json:"shared" desc:"内层"`
+	Inner  int    `json:"inner"`
+}
+
+type allFields struct {
+	Embedded
+	Shared   string            `json:"shared" desc:"外层"`
+	Name     string            `json:"name" desc:"名称" required:"true"`
+	Mode     string            `json:"mode" enum:"fast, slow"`
+	Flag     bool              `json:"flag"`
+	Count    int64             `json:"count"`
+	Small    uint8             `json:"small"`
+	Ratio    float32           `json:"ratio"`
+	Optional *int              `json:"optional"`
+	Tags     []string          `json:"tags"`
+	Fixed    [2]int            `json:"fixed"`
+	Points   []point           `json:"points"`
+	Origin   point             `json:"origin"`
+	Labels   map[string]string `json:"labels"`
+	When     time.Time         `json:"when"`
+	Deadline *time.Time        `json:"deadline"`
+	Addr     net.IP            `json:"addr"`
+	Timeout  time.Duration     `json:"timeout"`
+	NoTag    string
+	Skipped  string `json:"-"`
+	private  string
+}
+
+func TestStructParamsFieldTypes(t *testing.T) {
+	params := derive[allFields](t)
+	tests := []struct {
+		name string
+		want schema.DataType
+	}{
+		{"name", schema.String},
+		{"mode", schema.String},
+		{"flag", schema.Boolean},
+		{"count", schema.Integer},
+		{"small", schema.Integer},
+		{"ratio", schema.Number},
+		{"optional", schema.Integer},
+		{"tags", schema.Array},
+		{"fixed", schema.Array},
+		{"points", schema.Array},
+		{"origin", schema.Object},
+		{"labels", schema.Object},
+		{"when", schema.String},
+		{"deadline", schema.String},
+		{"addr", schema.String},
+		{"timeout", schema.Integer}, // time.Duration 在 JSON 中是纳秒整数
+		{"NoTag", schema.String},
+		{"shared", schema.String},
+		{"inner", schema.Integer},
+	}
+	for _, tt := range tests {
+		param, ok := params[tt.name]
+		if !ok {
+			t.Errorf("缺少参数 %s", tt.name)
+			continue
+		}
+		if param.Type != tt.want {
+			t.Errorf("参数 %s 的类型 = %s，want %s", tt.name, param.Type, tt.want)
+		}
+	}
+	if len(params) != len(tests) {
+		t.Errorf("参数个数 = %d，want %d（忽略 json:\"-\" 和未导出字段）", len(params), len(tests))
+	}
+
+	if p := params["name"]; p.Desc != "名称" || !p.Required {
+		t.Errorf("name = %+v", p)
+	}
+	if got := params["mode"].Enum; strings.Join(got, ",") != "fast,slow" {
+		t.Errorf("mode 的可选值 = %v", got)
+	}
+	if params["shared"].Desc != "外层" {
+		t.Errorf("与嵌入字段同名时应以外层字段为准")
+	}
+	if elem := params["tags"].ElemInfo; elem == nil || elem.Type != schema.String {
+		t.Errorf("tags 的元素 = %+v", elem)
+	}
+	points := params["points"].ElemInfo
+	if points == nil || points.Type != schema.Object || points.SubParams["x"] == nil || !points.SubParams["x"].Required {
+		t.Errorf("points 的元素 = %+v", points)
+	}
+	if params["origin"].SubParams["y"] == nil {
+		t.Errorf("origin 的子参数 = %+v", params["origin"].SubParams)
+	}
+	if params["labels"].SubParams != nil {
+		t.Errorf("map 不应列出子参数")
+	}
+}
+
+type selfRef struct {
+	Name     string    `json:"name"`
+	Children []selfRef `json:"children"`
+}
+
+type loopA struct {
+	B *loopB `json:"b"`
+}
+
+type loopB struct {
+	A loopA `json:"a"`
+}
+
+type mapLoop struct {
+	Next map[string]mapLoop `json:"next"`
+}
+
+type ptrLoop struct {
+	Next *ptrLoop `json:"next"`
+}
+
+type embedLoop struct {
+	*embedLoop
+}
+
+// twoPoints: 同一类型出现在多个字段中不是自我引用
+type twoPoints struct {
+	From point `json:"from"`
+	To   point `json:"to"`
+}
+
+func TestStructParamsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     reflect.Type
+		wantErr string
+	}{
+		{"不是结构体", reflect.TypeFor[string](), "参数类型必须是结构体"},
+		{"切片引用自身", reflect.TypeFor[selfRef](), "引用了自身"},
+		{"间接引用自身", reflect.TypeFor[loopA](), "引用了自身"},
+		{"map 的值引用自身", reflect.TypeFor[mapLoop](), "引用了自身"},
+		{"指针引用自身", reflect.TypeFor[ptrLoop](), "引用了自身"},
+		{"嵌入自身", reflect.TypeFor[embedLoop](), "引用了自身"},
+		{"不支持的类型", reflect.TypeFor[struct {
+			C chan int `json:"c"`
+		}](), "不支持的字段类型 chan int"},
+		{"map 的键不是 string", reflect.TypeFor[struct {
+			M map[int]string `json:"m"`
+		}](), "map 的键必须是 string"},
+		{"非字符串字段的 enum", reflect.TypeFor[struct {
+			N int `json:"n" enum:"1,2"`
+		}](), "enum 只能用于字符串字段"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := structParams(tt.typ, map[reflect.Type]bool{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if params := derive[twoPoints](t); params["from"] == nil || params["to"] == nil {
+		t.Errorf("twoPoints = %v", params)
+	}
+}
+
+func TestNewStructToolPanicsOnInvalidType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "工具 tree 的参数类型无效") {
+			t.Errorf("recover = %v", r)
+		}
+	}()
+	NewStructTool("tree", "", func(ctx context.Context, args selfRef) (string, error) { return "", nil })
+}
+
+type scheduleArgs struct {
+	Title  string    `json:"title" required:"true"`
+	At     time.Time `json:"at" required:"true"`
+	Kind   string    `json:"kind" enum:"meeting,call"`
+	Guests []struct {
+		Name string `json:"name" required:"true"`
+		Role string `json:"role" enum:"host,guest"`
+	} `json:"guests"`
+	Repeat *int `json:"repeat"`
+}
+
+func TestStructToolParse(t *testing.T) {
+	var got scheduleArgs
+	st := NewStructTool("schedule", "安排日程", func(ctx context.Context, args scheduleArgs) (string, error) {
+		got = args
+		return "ok", nil
+	})
+	result, err := st.InvokableRun(context.Background(), `{"title": "周会", "at": "2026-10-19T09:30:00+08:00", "kind": "meeting",
+		"guests": [{"name": "甲", "role": "host"}], "repeat": 3}`)
+	if err != nil || result != "ok" {
+		t.Fatalf("InvokableRun = %q, %v", result, err)
+	}
+	if got.At.Hour() != 9 || got.At.Minute() != 30 || got.Guests[0].Role != "host" || got.Repeat == nil || *got.Repeat != 3 {
+		t.Errorf("解码结果 = %+v", got)
+	}
+
+	tests := []struct {
+		name    string
+		args    string
+		wantErr string
+	}{
+		{"不是 JSON", `{`, "不是合法的 JSON"},
+		{"不是对象", `[1]`, "参数应为 JSON 对象"},
+		{"空参数", ``, "缺少必填字段: at, title"},
+		{"null", `null`, "缺少必填字段: at, title"},
+		{"必填字段为 null", `{"title": null, "at": "2026-10-19T09:30:00Z"}`, "缺少必填字段: title"},
+		{"枚举值无效", `{"title": "a", "at": "2026-10-19T09:30:00Z", "kind": "party"}`, `字段 kind 的取值 "party" 无效，可选值: meeting, call`},
+		{"嵌套的必填字段", `{"title": "a", "at": "2026-10-19T09:30:00Z", "guests": [{"role": "host"}]}`, "缺少必填字段: guests[0].name"},
+		{"嵌套的枚举值", `{"title": "a", "at": "2026-10-19T09:30:00Z", "guests": [{"name": "甲"}, {"name": "乙", "role": "x"}]}`, `字段 guests[1].role 的取值 "x" 无效`},
+		{"字段类型不符", `{"title": "a", "at": "2026-10-19T09:30:00Z", "repeat": "三"}`, "字段 repeat 的类型应为 integer，实际为 string"},
+		{"时间格式无效", `{"title": "a", "at": "明天"}`, "无效的参数"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.InvokableRun(context.Background(), tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestStructToolInfo: 推导出的 ToolInfo 可以转换为 JSON Schema，time.Time 字段是字符串
+func TestStructToolInfo(t *testing.T) {
+	info, err := NewStructTool("schedule", "安排日程", func(ctx context.Context, args scheduleArgs) (string, error) { return "", nil }).Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	js, err := info.ParamsOneOf.ToJSONSchema()
+	if err != nil {
+		t.Fatalf("ToJSONSchema: %v", err)
+	}
+	data, _ := json.Marshal(js)
+	var decoded struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("解析 JSON Schema: %v", err)
+	}
+	slices.Sort(decoded.Required)
+	if decoded.Properties["at"].Type != "string" || strings.Join(decoded.Required, ",") != "at,title" {
+		t.Errorf("JSON Schema = %s", data)
+	}
+}
+
+func TestCalculatorTool(t *testing.T) {
+	calc := NewCalculatorTool()
+	tests := []struct {
+		args    string
+		want    string
+		wantErr string
+	}{
+		{args: `{"operation": "add", "a": 5, "b": 6}`, want: "11.00"},
+		{args: `{"operation": "divide", "a": 5, "b": 6}`, want: "0.83"},
+		{args: `{"operation": "divide", "a": 5, "b": 0}`, wantErr: "除以零错误"},
+		{args: `{"operation": "power", "a": 5, "b": 2}`, wantErr: `字段 operation 的取值 "power" 无效`},
+		{args: `{"operation": "add", "a": 5}`, wantErr: "缺少必填字段: b"},
+	}
+	for _, tt := range tests {
+		got, err := calc.InvokableRun(context.Background(), tt.args)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: 错误 = %v，want %q", tt.args, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %q, %v，want %q", tt.args, got, err, tt.want)
+		}
+	}
+}
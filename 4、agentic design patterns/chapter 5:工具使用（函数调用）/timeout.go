@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// defaultToolTimeout: 未单独配置的工具的默认超时
+const defaultToolTimeout = 30 * time.Second
+
+// ToolTimeoutError: 一次工具调用超时；实现 net.Error 的 Timeout()，retry.IsRetryable 视其为可重试的瞬时错误
+type ToolTimeoutError struct {
+	Tool  string
+	Limit time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("工具 %s 执行超时（%s）", e.Tool, e.Limit)
+}
+
+func (e *ToolTimeoutError) Timeout() bool   { return true }
+func (e *ToolTimeoutError) Temporary() bool { return true }
+
+// ToolWithTimeout: 给工具加上单次调用超时的装饰器
+// 超时后取消传给工具的 ctx 并立即返回 *ToolTimeoutError，ReAct 循环不会被挂起的工具卡住；
+// 外层的 RetryTool 会重试超时的调用，重试用尽后把说明交给模型，由模型换一种方式完成任务。
+// 忽略 ctx 的工具会在后台自行结束，其结果被丢弃
+type ToolWithTimeout struct {
+	inner   tool.InvokableTool
+	timeout time.Duration
+}
+
+var _ tool.InvokableTool = (*ToolWithTimeout)(nil)
+
+// NewToolWithTimeout: 包装一个可调用的工具，timeout<=0 表示不限制
+func NewToolWithTimeout(inner tool.InvokableTool, timeout time.Duration) *ToolWithTimeout {
+	return &ToolWithTimeout{inner: inner, timeout: timeout}
+}
+
+func (t *ToolWithTimeout) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.inner.Info(ctx)
+}
+
+func (t *ToolWithTimeout) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if t.timeout <= 0 {
+		return t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1) // 带缓冲：超时返回后，工具结束时也不会阻塞
+	go func() {
+		result, err := t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		// 工具自己感知到 ctx 到期而返回的错误同样视为超时
+		if out.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", t.timedOut(ctx)
+		}
+		return out.result, out.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", t.timedOut(ctx)
+		}
+		// 上层取消（如整个运行被中止）：原样返回错误，不当作工具结果交给模型
+		return "", ctx.Err()
+	}
+}
+
+// timedOut: 超时时返回的错误
+func (t *ToolWithTimeout) timedOut(ctx context.Context) error {
+	name := "unknown"
+	if info, err := t.inner.Info(ctx); err == nil {
+		name = info.Name
+	}
+	err := &ToolTimeoutError{Tool: name, Limit: t.timeout}
+	fmt.Printf("--- ⏱️ %v，已放弃本次尝试 ---\n", err)
+	return err
+}
+
+// applyTimeouts: 按工具名给每个工具加上超时，timeouts 中没有的工具使用 fallback；超时为 0 的工具不包装
+// 只包装可调用（InvokableTool）的工具，其他工具原样保留
+func applyTimeouts(ctx context.Context, tools []tool.BaseTool, timeouts map[string]time.Duration, fallback time.Duration) ([]tool.BaseTool, error) {
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = t
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		timeout, ok := timeouts[info.Name]
+		if !ok {
+			timeout = fallback
+		}
+		if timeout > 0 {
+			wrapped[i] = NewToolWithTimeout(invokable, timeout)
+		}
+	}
+	return wrapped, nil
+}
+
+// parseToolDurations: 解析 "get_weather=15s,web_search=20s" 形式的按工具时长配置（超时、缓存有效期），0 表示不限制
+func parseToolDurations(spec string) (map[string]time.Duration, error) {
+	durations := map[string]time.Duration{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("无效的配置 %q（格式：工具名=时长）", item)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("无效的时长 %q: 时长格式如 10s、1m", item)
+		}
+		durations[name] = duration
+	}
+	return durations, nil
+}
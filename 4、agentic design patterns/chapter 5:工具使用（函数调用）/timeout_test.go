@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// funcTool: 由函数实现的测试工具，记录调用次数
+type funcTool struct {
+	name  string
+	run   func(ctx context.Context, args string) (string, error)
+	calls atomic.Int32
+}
+
+func (f *funcTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: f.name, Desc: "测试工具"}, nil
+}
+
+func (f *funcTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	f.calls.Add(1)
+	return f.run(ctx, argumentsInJSON)
+}
+
+// hangingTool: 一直等到 ctx 结束的工具，ignoreCtx 时忽略 ctx 直到 release 关闭
+func hangingTool(name string, release <-chan struct{}, ignoreCtx bool) *funcTool {
+	return &funcTool{name: name, run: func(ctx context.Context, args string) (string, error) {
+		if ignoreCtx {
+			<-release
+			return "迟到的结果", nil
+		}
+		<-ctx.Done()
+		return "", ctx.Err()
+	}}
+}
+
+func TestToolWithTimeoutPassesThrough(t *testing.T) {
+	inner := &funcTool{name: "echo", run: func(ctx context.Context, args string) (string, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return "", errors.New("没有截止时间")
+		}
+		return "结果:" + args, nil
+	}}
+	got, err := NewToolWithTimeout(inner, time.Second).InvokableRun(context.Background(), "x")
+	if err != nil || got != "结果:x" {
+		t.Errorf("InvokableRun = %q, %v", got, err)
+	}
+	boom := errors.New("参数无效")
+	inner.run = func(ctx context.Context, args string) (string, error) { return "", boom }
+	if _, err := NewToolWithTimeout(inner, time.Second).InvokableRun(context.Background(), "x"); !errors.Is(err, boom) {
+		t.Errorf("工具自身的错误应原样返回: %v", err)
+	}
+}
+
+func TestToolWithTimeoutExpires(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	for _, ignoreCtx := range []bool{false, true} {
+		start := time.Now()
+		_, err := NewToolWithTimeout(hangingTool("slow", release, ignoreCtx), 20*time.Millisecond).InvokableRun(context.Background(), "{}")
+		var timeoutErr *ToolTimeoutError
+		if !errors.As(err, &timeoutErr) || timeoutErr.Tool != "slow" || err.Error() != "工具 slow 执行超时（20ms）" {
+			t.Errorf("忽略 ctx=%v: 错误 = %v，want *ToolTimeoutError", ignoreCtx, err)
+		}
+		// 超时可以重试：外层的 RetryTool 会重新尝试
+		if !retry.IsRetryable(err) {
+			t.Errorf("超时错误应可重试")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("忽略 ctx=%v: 超时后没有立即返回（%s）", ignoreCtx, elapsed)
+		}
+	}
+}
+
+// TestToolWithTimeoutParentCancel: 上层取消不当作超时，原样返回错误
+func TestToolWithTimeoutParentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := NewToolWithTimeout(hangingTool("slow", nil, false), time.Minute).InvokableRun(ctx, "{}")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("错误 = %v，want context.Canceled", err)
+	}
+}
+
+func TestToolWithTimeoutDisabled(t *testing.T) {
+	inner := &funcTool{name: "echo", run: func(ctx context.Context, args string) (string, error) {
+		if _, ok := ctx.Deadline(); ok {
+			return "", errors.New("不应设置截止时间")
+		}
+		return "ok", nil
+	}}
+	if got, err := NewToolWithTimeout(inner, 0).InvokableRun(context.Background(), "{}"); err != nil || got != "ok" {
+		t.Errorf("timeout=0 时 = %q, %v", got, err)
+	}
+}
+
+func TestApplyTimeouts(t *testing.T) {
+	tools := []tool.BaseTool{
+		&funcTool{name: "a"},
+		&funcTool{name: "b"},
+		&funcTool{name: "c"},
+	}
+	wrapped, err := applyTimeouts(context.Background(), tools, map[string]time.Duration{"b": 5 * time.Second, "c": 0}, time.Second)
+	if err != nil {
+		t.Fatalf("applyTimeouts: %v", err)
+	}
+	if w, ok := wrapped[0].(*ToolWithTimeout); !ok || w.timeout != time.Second {
+		t.Errorf("a 应使用默认超时: %#v", wrapped[0])
+	}
+	if w, ok := wrapped[1].(*ToolWithTimeout); !ok || w.timeout != 5*time.Second {
+		t.Errorf("b 应使用单独配置的超时: %#v", wrapped[1])
+	}
+	if wrapped[2] != tools[2] {
+		t.Errorf("超时为 0 的工具不应包装")
+	}
+}
+
+func TestParseToolDurations(t *testing.T) {
+	got, err := parseToolDurations(" get_weather=15s, web_search = 1m ,,")
+	if err != nil || got["get_weather"] != 15*time.Second || got["web_search"] != time.Minute || len(got) != 2 {
+		t.Errorf("parseToolDurations = %v, %v", got, err)
+	}
+	for _, spec := range []string{"get_weather", "=1s", "a=abc", "a=-1s"} {
+		if _, err := parseToolDurations(spec); err == nil {
+			t.Errorf("parseToolDurations(%q) 应返回错误", spec)
+		}
+	}
+}
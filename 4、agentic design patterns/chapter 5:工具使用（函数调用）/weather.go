@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	defaultGeocodeURL     = "https://geocoding-api.open-meteo.com/v1/search" // defaultGeocodeURL: Open-Meteo 地理编码接口（无需密钥）
+	defaultForecastURL    = "https://api.open-meteo.com/v1/forecast"         // defaultForecastURL: Open-Meteo 天气接口（无需密钥）
+	defaultWeatherTimeout = 10 * time.Second                                 // defaultWeatherTimeout: 一次工具调用（地理编码 + 天气）的总超时
+	weatherFailure        = "查询天气失败："                                        // weatherFailure: 失败说明的前缀
+)
+
+// weatherReport: 一个城市的当前天气
+type weatherReport struct {
+	City        string
+	Country     string
+	Temperature float64 // Temperature: 气温（°C）
+	Humidity    float64 // Humidity: 相对湿度（%）
+	WindSpeed   float64 // WindSpeed: 10 米风速（km/h）
+	Code        int     // Code: WMO 天气代码
+}
+
+// String: 返回给模型的简短摘要
+func (w weatherReport) String() string {
+	place := w.City
+	if w.Country != "" {
+		place += "，" + w.Country
+	}
+	return fmt.Sprintf("%s：%s，气温 %.1f°C，相对湿度 %.0f%%，风速 %.1f km/h",
+		place, weatherDescription(w.Code), w.Temperature, w.Humidity, w.WindSpeed)
+}
+
+// offlineWeather: WEATHER_OFFLINE=1 时使用的固定数据，按城市名索引
+var offlineWeather = map[string]weatherReport{
+	"北京": {City: "北京", Country: "中国", Temperature: 18.5, Humidity: 35, WindSpeed: 12.2, Code: 0},
+	"上海": {City: "上海", Country: "中国", Temperature: 22.1, Humidity: 78, WindSpeed: 9.4, Code: 61},
+	"深圳": {City: "深圳", Country: "中国", Temperature: 27.3, Humidity: 82, WindSpeed: 7.6, Code: 2},
+	"伦敦": {City: "伦敦", Country: "英国", Temperature: 11.0, Humidity: 88, WindSpeed: 18.0, Code: 3},
+	"东京": {City: "东京", Country: "日本", Temperature: 20.4, Humidity: 60, WindSpeed: 10.8, Code: 1},
+}
+
+// weatherDescription: WMO 天气代码的中文说明
+func weatherDescription(code int) string {
+	switch {
+	case code == 0:
+		return "晴"
+	case code == 1 || code == 2:
+		return "多云"
+	case code == 3:
+		return "阴"
+	case code == 45 || code == 48:
+		return "雾"
+	case code >= 51 && code <= 57:
+		return "毛毛雨"
+	case code >= 61 && code <= 67, code >= 80 && code <= 82:
+		return "雨"
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return "雪"
+	case code >= 95:
+		return "雷暴"
+	default:
+		return fmt.Sprintf("天气代码 %d", code)
+	}
+}
+
+// WeatherTool: 查询城市当前天气的 API 工具，基于 Open-Meteo（无需 API 密钥）
+// 失败（城市不存在、超时等）时返回可读的说明而不是 error，模型可以据此换一个城市名重试或如实告知用户；
+// 瞬时失败（5xx、连接被拒绝等，见 retry.IsRetryable）返回 error，交给 RetryTool 重试
+type WeatherTool struct {
+	Client      *http.Client
+	GeocodeURL  string        // GeocodeURL: 地理编码接口地址（测试时指向 httptest 服务）
+	ForecastURL string        // ForecastURL: 天气接口地址
+	Timeout     time.Duration // Timeout: 一次调用的总超时
+	Offline     bool          // Offline: 使用固定数据，不访问网络
+}
+
+// NewWeatherTool: 使用 Open-Meteo 的天气工具，环境变量 WEATHER_OFFLINE=1 时改用固定数据
+func NewWeatherTool() *WeatherTool {
+	return &WeatherTool{
+		Client:      http.DefaultClient,
+		GeocodeURL:  defaultGeocodeURL,
+		ForecastURL: defaultForecastURL,
+		Timeout:     defaultWeatherTimeout,
+		Offline:     os.Getenv("WEATHER_OFFLINE") == "1",
+	}
+}
+
+func (w *WeatherTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "get_weather",
+		Desc: "查询指定城市的当前天气（天气状况、气温、湿度、风速）",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"city": {
+				Type:     schema.String,
+				Desc:     "城市名称，如 北京、London",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+func (w *WeatherTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		City string `json:"city"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return fmt.Sprintf(weatherFailure+"参数无效（%v），需要形如 {\"city\": \"北京\"} 的参数", err), nil
+	}
+	city := strings.TrimSpace(args.City)
+	if city == "" {
+		return weatherFailure + "缺少城市名称", nil
+	}
+
+	fmt.Printf("\n--- 🛠️ 工具调用：get_weather，城市：'%s' ---\n", city)
+
+	var result string
+	if report, err := w.lookup(ctx, city); err != nil {
+		if retry.IsRetryable(err) {
+			fmt.Printf("--- 工具错误：%v ---\n", err)
+			return "", fmt.Errorf("查询天气失败: %w", err)
+		}
+		result = weatherFailure + err.Error()
+	} else {
+		result = report.String()
+	}
+	fmt.Printf("--- 工具结果：%s ---\n", result)
+	return result, nil
+}
+
+// Cacheable: 失败说明不缓存（网络错误可能是暂时的）
+func (w *WeatherTool) Cacheable(result string) bool {
+	return !strings.HasPrefix(result, weatherFailure)
+}
+
+// lookup: 先地理编码得到经纬度，再查询当前天气
+func (w *WeatherTool) lookup(ctx context.Context, city string) (weatherReport, error) {
+	if w.Offline {
+		report, ok := offlineWeather[city]
+		if !ok {
+			return weatherReport{}, fmt.Errorf("离线模式下没有 %s 的数据（可用城市：北京、上海、深圳、伦敦、东京）", city)
+		}
+		return report, nil
+	}
+
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
+
+	var geo struct {
+		Results []struct {
+			Name      string  `json:"name"`
+			Country   string  `json:"country"`
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"results"`
+	}
+	query := url.Values{"name": {city}, "count": {"1"}, "language": {"zh"}, "format": {"json"}}
+	if err := w.getJSON(ctx, w.GeocodeURL, query, &geo); err != nil {
+		return weatherReport{}, fmt.Errorf("地理编码失败: %w", err)
+	}
+	if len(geo.Results) == 0 {
+		return weatherReport{}, fmt.Errorf("未找到城市 %s，请检查名称或换用英文名", city)
+	}
+	place := geo.Results[0]
+
+	var forecast struct {
+		Current struct {
+			Temperature float64 `json:"temperature_2m"`
+			Humidity    float64 `json:"relative_humidity_2m"`
+			WindSpeed   float64 `json:"wind_speed_10m"`
+			WeatherCode int     `json:"weather_code"`
+		} `json:"current"`
+	}
+	query = url.Values{
+		"latitude":  {fmt.Sprintf("%.4f", place.Latitude)},
+		"longitude": {fmt.Sprintf("%.4f", place.Longitude)},
+		"current":   {"temperature_2m,relative_humidity_2m,wind_speed_10m,weather_code"},
+	}
+	if err := w.getJSON(ctx, w.ForecastURL, query, &forecast); err != nil {
+		return weatherReport{}, fmt.Errorf("获取天气失败: %w", err)
+	}
+	return weatherReport{
+		City:        place.Name,
+		Country:     place.Country,
+		Temperature: forecast.Current.Temperature,
+		Humidity:    forecast.Current.Humidity,
+		WindSpeed:   forecast.Current.WindSpeed,
+		Code:        forecast.Current.WeatherCode,
+	}, nil
+}
+
+// getJSON: 发送 GET 请求并解码 JSON 响应，非 2xx 状态码视为错误
+func (w *WeatherTool) getJSON(ctx context.Context, endpoint string, query url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
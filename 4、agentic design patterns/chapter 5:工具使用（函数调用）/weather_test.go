@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// weatherServer: 模拟 Open-Meteo 的地理编码和天气接口，geocode / forecast 为空时返回对应的默认数据
+type weatherServer struct {
+	geocode  http.HandlerFunc
+	forecast http.HandlerFunc
+}
+
+func (s weatherServer) start(t *testing.T) *WeatherTool {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/geocode", func(w http.ResponseWriter, r *http.Request) {
+		if s.geocode != nil {
+			s.geocode(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"results":[{"name":%q,"country":"中国","latitude":39.9,"longitude":116.4}]}`, r.URL.Query().Get("name"))
+	})
+	mux.HandleFunc("/forecast", func(w http.ResponseWriter, r *http.Request) {
+		if s.forecast != nil {
+			s.forecast(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"current":{"temperature_2m":21.5,"relative_humidity_2m":40,"wind_speed_10m":8.2,"weather_code":61}}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return &WeatherTool{Client: srv.Client(), GeocodeURL: srv.URL + "/geocode", ForecastURL: srv.URL + "/forecast", Timeout: time.Second}
+}
+
+func TestWeatherToolLookup(t *testing.T) {
+	var forecastQuery string
+	w := weatherServer{forecast: func(w http.ResponseWriter, r *http.Request) {
+		forecastQuery = r.URL.RawQuery
+		fmt.Fprint(w, `{"current":{"temperature_2m":21.5,"relative_humidity_2m":40,"wind_speed_10m":8.2,"weather_code":61}}`)
+	}}.start(t)
+	got, err := w.InvokableRun(context.Background(), `{"city": " 北京 "}`)
+	if err != nil {
+		t.Fatalf("InvokableRun: %v", err)
+	}
+	if want := "北京，中国：雨，气温 21.5°C，相对湿度 40%，风速 8.2 km/h"; got != want {
+		t.Errorf("结果 = %q，want %q", got, want)
+	}
+	if !strings.Contains(forecastQuery, "latitude=39.9000") || !strings.Contains(forecastQuery, "longitude=116.4000") {
+		t.Errorf("天气接口没有收到地理编码的经纬度: %s", forecastQuery)
+	}
+}
+
+func TestWeatherToolFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  weatherServer
+		args    string
+		want    string // want: 返回给模型的失败说明中应包含的内容
+		wantErr bool   // wantErr: 瞬时失败，返回 error 交给 RetryTool
+	}{
+		{name: "参数无效", args: `{"city": 1}`, want: "参数无效"},
+		{name: "缺少城市", args: `{"city": "  "}`, want: "缺少城市名称"},
+		{name: "城市不存在", server: weatherServer{geocode: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"results":[]}`)
+		}}, args: `{"city": "不存在"}`, want: "未找到城市 不存在"},
+		{name: "地理编码 404", server: weatherServer{geocode: func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		}}, args: `{"city": "北京"}`, want: "地理编码失败: HTTP 404"},
+		{name: "响应无法解析", server: weatherServer{forecast: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"current": "晴"}`)
+		}}, args: `{"city": "北京"}`, want: "解析响应失败"},
+		{name: "响应被截断", server: weatherServer{forecast: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"current":`)
+		}}, args: `{"city": "北京"}`, want: "unexpected EOF", wantErr: true},
+		{name: "天气接口 503", server: weatherServer{forecast: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}}, args: `{"city": "北京"}`, want: "HTTP 503", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tt.server.start(t)
+			got, err := w.InvokableRun(context.Background(), tt.args)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("错误 = %v，want 包含 %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InvokableRun: %v", err)
+			}
+			if !strings.HasPrefix(got, weatherFailure) || !strings.Contains(got, tt.want) {
+				t.Errorf("结果 = %q，want 包含 %q 的失败说明", got, tt.want)
+			}
+			if w.Cacheable(got) {
+				t.Errorf("失败说明不应缓存")
+			}
+		})
+	}
+}
+
+func TestWeatherToolOffline(t *testing.T) {
+	w := &WeatherTool{Offline: true}
+	got, err := w.InvokableRun(context.Background(), `{"city": "伦敦"}`)
+	if err != nil || got != "伦敦，英国：阴，气温 11.0°C，相对湿度 88%，风速 18.0 km/h" {
+		t.Errorf("离线结果 = %q, %v", got, err)
+	}
+	if !w.Cacheable(got) {
+		t.Errorf("成功的结果应可缓存")
+	}
+	got, _ = w.InvokableRun(context.Background(), `{"city": "巴黎"}`)
+	if !strings.Contains(got, "离线模式下没有 巴黎 的数据") {
+		t.Errorf("未知城市 = %q", got)
+	}
+}
+
+func TestWeatherDescription(t *testing.T) {
+	tests := map[int]string{0: "晴", 2: "多云", 3: "阴", 45: "雾", 53: "毛毛雨", 81: "雨", 86: "雪", 99: "雷暴", 20: "天气代码 20"}
+	for code, want := range tests {
+		if got := weatherDescription(code); got != want {
+			t.Errorf("weatherDescription(%d) = %q，want %q", code, got, want)
+		}
+	}
+}
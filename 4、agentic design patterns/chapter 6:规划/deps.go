@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// --- 任务依赖 ---
+
+// index: 按 ID 查找任务的下标，找不到时返回 -1
+func (l *TodoList) index(id string) int {
+	return slices.IndexFunc(l.Items, func(item TodoItem) bool { return item.ID == id })
+}
+
+// effectiveDeps: 任务自己的依赖加上所有祖先任务的依赖（父任务在等待的任务，子任务同样要等），去重
+func (l *TodoList) effectiveDeps(item TodoItem) []string {
+	deps := slices.Clone(item.DependsOn)
+	for _, ancestor := range l.ancestors(item) {
+		for _, dep := range ancestor.DependsOn {
+			if !slices.Contains(deps, dep) {
+				deps = append(deps, dep)
+			}
+		}
+	}
+	return deps
+}
+
+// settled: 已完成或已取消的任务不再参与执行，也不再阻塞依赖它的任务
+func settled(item TodoItem) bool {
+	return item.Status == "completed" || item.Status == "cancelled"
+}
+
+// blockers: item（及其祖先）依赖的任务中尚未完成的，按 depends_on 的顺序；清单中已不存在或已取消的依赖视为已解除
+func (l *TodoList) blockers(item TodoItem) []TodoItem {
+	var blocked []TodoItem
+	for _, dep := range l.effectiveDeps(item) {
+		if i := l.index(dep); i >= 0 && !settled(l.Items[i]) {
+			blocked = append(blocked, l.Items[i])
+		}
+	}
+	return blocked
+}
+
+// checkDependsOn: 校验任务 id 的新依赖并去重：依赖的任务必须存在，不能依赖自己、自己的祖先或后代，也不能与已有的依赖形成循环
+// parentID 为任务的父任务（新建子任务时任务还不在清单中）
+func (l *TodoList) checkDependsOn(id, parentID string, deps []string) ([]string, error) {
+	related := l.descendants(id)
+	for _, ancestor := range l.ancestors(TodoItem{ParentID: parentID}) {
+		related = append(related, ancestor.ID)
+	}
+	var unique []string
+	for _, dep := range deps {
+		dep = strings.TrimSpace(dep)
+		switch {
+		case dep == "" || slices.Contains(unique, dep):
+			continue
+		case dep == id:
+			return nil, fmt.Errorf("任务 %s 不能依赖自己", id)
+		case l.index(dep) < 0:
+			return nil, fmt.Errorf("依赖的任务不存在: %s", dep)
+		case slices.Contains(related, dep):
+			return nil, fmt.Errorf("任务 %s 不能依赖自己的父任务或子任务 %s（父任务在全部子任务完成后才完成）", id, dep)
+		}
+		unique = append(unique, dep)
+	}
+
+	// 在副本上应用新依赖，检查是否形成循环
+	trial := TodoList{Items: slices.Clone(l.Items)}
+	if i := trial.index(id); i >= 0 {
+		trial.Items[i].DependsOn = unique
+		if _, cycle := trial.executionOrder(); cycle != nil {
+			return nil, fmt.Errorf("设置依赖后会形成循环：%s", strings.Join(cycle, " → "))
+		}
+	}
+	return unique, nil
+}
+
+// executionOrder: 未完成（含失败）任务的执行顺序（拓扑排序）：依赖的任务排在前面，父任务排在子任务之后，没有先后约束的任务保持清单中的顺序
+// 存在循环依赖时返回 nil 和循环上的任务 ID（首尾相同，如 todo-1 → todo-2 → todo-1）
+func (l *TodoList) executionOrder() ([]TodoItem, []string) {
+	// waiting: 每个未完成任务还在等待的未完成依赖
+	waiting := map[string]map[string]bool{}
+	var remaining []TodoItem
+	for _, item := range l.Items {
+		if settled(item) {
+			continue
+		}
+		waiting[item.ID] = map[string]bool{}
+		remaining = append(remaining, item)
+	}
+	for _, item := range remaining {
+		for _, dep := range l.effectiveDeps(item) {
+			if _, ok := waiting[dep]; ok {
+				waiting[item.ID][dep] = true
+			}
+		}
+		if _, ok := waiting[item.ParentID]; ok {
+			waiting[item.ParentID][item.ID] = true // 父任务在子任务之后
+		}
+	}
+
+	order := make([]TodoItem, 0, len(remaining))
+	for len(remaining) > 0 {
+		next := slices.IndexFunc(remaining, func(item TodoItem) bool { return len(waiting[item.ID]) == 0 })
+		if next < 0 {
+			return nil, findCycle(remaining, waiting)
+		}
+		done := remaining[next]
+		order = append(order, done)
+		remaining = slices.Delete(remaining, next, next+1)
+		for _, item := range remaining {
+			delete(waiting[item.ID], done.ID)
+		}
+	}
+	return order, nil
+}
+
+// findCycle: 剩下的任务都还有未满足的依赖，沿着依赖一直走下去必然回到走过的任务，从第一次经过它的位置截取出循环
+func findCycle(remaining []TodoItem, waiting map[string]map[string]bool) []string {
+	var path []string
+	id := remaining[0].ID
+	for !slices.Contains(path, id) {
+		path = append(path, id)
+		for _, item := range remaining { // 按清单顺序选择等待的任务，结果稳定
+			if waiting[id][item.ID] {
+				id = item.ID
+				break
+			}
+		}
+	}
+	start := slices.Index(path, id)
+	return append(path[start:], id)
+}
+
+// formatTasks: 把任务列为 "todo-1（需求分析，pending）、todo-2（UI设计，in_progress）"
+func formatTasks(items []TodoItem) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprintf("%s（%s，%s）", item.ID, item.Title, item.Status)
+	}
+	return strings.Join(parts, "、")
+}
+
+// ready: 任务是否可以立即开始：待处理、依赖都已完成；有子任务的任务由子任务推进，不单独开始
+func (l *TodoList) ready(item TodoItem) bool {
+	return item.Status == "pending" && !l.hasChildren(item.ID) && len(l.blockers(item)) == 0
+}
+
+// renderNext: 可以立即开始的待处理任务（依赖都已完成），以及其余未完成任务的执行顺序
+func (t *TodoManagerTool) renderNext() string {
+	order, cycle := t.todos.executionOrder()
+	if cycle != nil {
+		return fmt.Sprintf("任务依赖存在循环：%s，请用 update 修改其中一个任务的 depends_on 解除循环", strings.Join(cycle, " → "))
+	}
+	if len(order) == 0 {
+		return "🎉 所有任务都已完成"
+	}
+
+	var sb strings.Builder
+	var later []string
+	sb.WriteString("▶️ 可以开始的任务（按依赖顺序）：\n")
+	ready := 0
+	for _, item := range order {
+		if t.todos.ready(item) {
+			ready++
+			sb.WriteString(fmt.Sprintf("%d. [%s] %s\n", ready, item.ID, item.Title))
+			continue
+		}
+		later = append(later, item.ID)
+	}
+	if ready == 0 {
+		sb.WriteString("（暂无：剩下的任务正在进行中、已失败或在等待依赖完成）\n")
+	}
+	if len(later) > 0 {
+		sb.WriteString(fmt.Sprintf("其余未完成任务的执行顺序：%s\n", strings.Join(later, " → ")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// listOf: 由 ID、状态和依赖组成的清单，deps 的键为任务 ID
+func listOf(statuses map[string]string, order []string, deps map[string][]string) *TodoList {
+	l := &TodoList{}
+	for _, id := range order {
+		l.Items = append(l.Items, TodoItem{ID: id, Title: id, Status: statuses[id], DependsOn: deps[id]})
+	}
+	return l
+}
+
+// ids: 任务的 ID 列表
+func ids(items []TodoItem) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.ID
+	}
+	return out
+}
+
+func TestCheckDependsOn(t *testing.T) {
+	l := listOf(
+		map[string]string{"a": "pending", "b": "pending", "c": "pending"},
+		[]string{"a", "b", "c"},
+		map[string][]string{"b": {"a"}, "c": {"b"}},
+	)
+	tests := []struct {
+		name    string
+		id      string
+		deps    []string
+		want    []string
+		wantErr string
+	}{
+		{"去重并去掉空白", "c", []string{" a ", "a", "", "b"}, []string{"a", "b"}, ""},
+		{"清除依赖", "b", []string{}, nil, ""},
+		{"依赖自己", "a", []string{"a"}, nil, "不能依赖自己"},
+		{"依赖不存在的任务", "a", []string{"x"}, nil, "依赖的任务不存在: x"},
+		{"形成循环", "a", []string{"c"}, nil, "会形成循环：a → c → b → a"},
+		{"新任务", "d", []string{"c"}, []string{"c"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.checkDependsOn(tt.id, "", tt.deps)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v，want 包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("checkDependsOn = %v, %v，want %v", got, err, tt.want)
+			}
+		})
+	}
+	if l.Items[0].DependsOn != nil {
+		t.Errorf("校验不应修改清单: %v", l.Items[0].DependsOn)
+	}
+}
+
+func TestExecutionOrder(t *testing.T) {
+	l := listOf(
+		map[string]string{"测试": "pending", "后端": "pending", "需求": "completed", "UI": "in_progress", "文档": "pending"},
+		[]string{"测试", "后端", "需求", "UI", "文档"},
+		map[string][]string{"测试": {"后端", "UI"}, "后端": {"需求"}, "UI": {"需求"}},
+	)
+	order, cycle := l.executionOrder()
+	if cycle != nil {
+		t.Fatalf("不应有循环: %v", cycle)
+	}
+	if want := []string{"后端", "UI", "测试", "文档"}; !slices.Equal(ids(order), want) {
+		t.Errorf("执行顺序 = %v，want %v", ids(order), want)
+	}
+
+	l.Items[2].Status = "pending"
+	l.Items[2].DependsOn = []string{"测试"}
+	if _, cycle := l.executionOrder(); !slices.Equal(cycle, []string{"测试", "后端", "需求", "测试"}) {
+		t.Errorf("循环 = %v", cycle)
+	}
+}
+
+func TestBlockers(t *testing.T) {
+	l := listOf(
+		map[string]string{"a": "completed", "b": "in_progress", "c": "cancelled", "d": "pending"},
+		[]string{"a", "b", "c", "d"},
+		map[string][]string{"d": {"a", "b", "c", "已删除"}},
+	)
+	if got := ids(l.blockers(l.Items[3])); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("blockers = %v，want [b]：已完成、已取消和已删除的依赖不阻塞", got)
+	}
+	if l.ready(l.Items[3]) {
+		t.Errorf("有未完成依赖的任务不能开始")
+	}
+	l.Items[1].Status = "completed"
+	if !l.ready(l.Items[3]) {
+		t.Errorf("依赖都完成后应可以开始")
+	}
+}
+
+func TestTodoManagerDependencies(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "需求分析")
+	mustTodo(t, m, map[string]any{"action": "add", "title": "后端开发", "depends_on": []string{"todo-1"}})
+	if _, err := todoRun(m, map[string]any{"action": "update", "id": "todo-1", "depends_on": []string{"todo-2"}}); err == nil {
+		t.Fatalf("形成循环的依赖应被拒绝")
+	}
+
+	// 依赖未完成时拒绝完成，作为结果告诉 Agent 被哪些任务阻塞
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "status": "in_progress"})
+	out := mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-2"})
+	if !strings.Contains(out, "无法完成任务 todo-2") || !strings.Contains(out, "todo-1（需求分析，pending）") {
+		t.Errorf("被阻塞时的结果 = %q", out)
+	}
+	if item(t, m, "todo-2").Status != "in_progress" {
+		t.Errorf("被阻塞的任务不应完成")
+	}
+	if next := mustTodo(t, m, map[string]any{"action": "next"}); !strings.Contains(next, "1. [todo-1] 需求分析") || !strings.Contains(next, "执行顺序：todo-2") {
+		t.Errorf("next = %q", next)
+	}
+
+	// update 时清除依赖后可以完成
+	out = mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "status": "completed", "depends_on": []string{}})
+	if !strings.Contains(out, "状态=completed") {
+		t.Errorf("清除依赖并完成 = %q", out)
+	}
+}
+
+func TestRenderNext(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	if got := mustTodo(t, m, map[string]any{"action": "next"}); !strings.Contains(got, "所有任务都已完成") {
+		t.Errorf("空清单的 next = %q", got)
+	}
+	m.todos = listOf(
+		map[string]string{"a": "pending", "b": "pending"},
+		[]string{"a", "b"},
+		map[string][]string{"a": {"b"}, "b": {"a"}},
+	)
+	if got := mustTodo(t, m, map[string]any{"action": "next"}); !strings.Contains(got, "任务依赖存在循环：a → b → a") {
+		t.Errorf("循环依赖的 next = %q", got)
+	}
+	m.todos = listOf(map[string]string{"a": "in_progress"}, []string{"a"}, nil)
+	if got := mustTodo(t, m, map[string]any{"action": "next"}); !strings.Contains(got, "（暂无") {
+		t.Errorf("没有可以开始的任务时 = %q", got)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// --- 工作量估计和进度预测 ---
+
+// sizeHours: T 恤尺码对应的工时：S 约 1 小时，M 约半天，L 约一天
+var sizeHours = map[string]float64{"S": 1, "M": 4, "L": 8}
+
+// hourUnits: 工时的单位后缀（先匹配较长的后缀）及其对应的小时数
+var hourUnits = []struct {
+	suffix string
+	hours  float64
+}{
+	{"小时", 1}, {"分钟", 1.0 / 60}, {"天", 8},
+	{"h", 1}, {"m", 1.0 / 60}, {"d", 8},
+}
+
+// parseEstimate: 解析工作量估计：S/M/L，或带单位的时长如 "3h"、"90m"、"2天"、"1.5小时"，只有数字时按小时；返回小时数
+func parseEstimate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if hours, ok := sizeHours[strings.ToUpper(s)]; ok {
+		return hours, nil
+	}
+	value, unit := strings.ToLower(s), 1.0
+	for _, u := range hourUnits {
+		if strings.HasSuffix(value, u.suffix) {
+			value, unit = strings.TrimSpace(strings.TrimSuffix(value, u.suffix)), u.hours
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("无效的工作量估计 %q（格式：S、M、L，或 3h、90m、2天 这样的时长）", s)
+	}
+	return n * unit, nil
+}
+
+// formatHours: 工时的显示，如 "4h"、"1.5h"
+func formatHours(hours float64) string {
+	return strconv.FormatFloat(math.Round(hours*10)/10, 'f', -1, 64) + "h"
+}
+
+// effortStats: 按工作量统计的进度；只统计没有子任务的任务（父任务的工作量就是子任务之和），已取消的任务不计入
+type effortStats struct {
+	tasks       int     // tasks: 参与统计的任务数
+	done        int     // done: 其中已完成的任务数
+	estimated   int     // estimated: 有估计的任务数
+	totalHours  float64 // totalHours: 有估计的任务的工时之和
+	doneHours   float64 // doneHours: 其中已完成任务的工时之和
+	unestimated int     // unestimated: 未完成且没有估计的任务数，剩余工时不包括它们
+}
+
+// computeEffort: 汇总清单的工作量
+func computeEffort(l *TodoList) effortStats {
+	var st effortStats
+	for _, item := range l.Items {
+		if item.Status == "cancelled" || l.hasChildren(item.ID) {
+			continue
+		}
+		st.tasks++
+		completed := item.Status == "completed"
+		if completed {
+			st.done++
+		}
+		if item.EstimateHours <= 0 {
+			if !completed {
+				st.unestimated++
+			}
+			continue
+		}
+		st.estimated++
+		st.totalHours += item.EstimateHours
+		if completed {
+			st.doneHours += item.EstimateHours
+		}
+	}
+	return st
+}
+
+// remainingHours: 未完成任务的估计工时之和，即朴素的完成预测
+func (st effortStats) remainingHours() float64 {
+	return st.totalHours - st.doneHours
+}
+
+// percent: 按工作量计算的完成百分比；没有任何估计时按任务数计算，byEffort 为 false
+func (st effortStats) percent() (pct int, byEffort bool) {
+	switch {
+	case st.totalHours > 0:
+		return int(math.Round(st.doneHours / st.totalHours * 100)), true
+	case st.tasks > 0:
+		return int(math.Round(float64(st.done) / float64(st.tasks) * 100)), false
+	}
+	return 0, false
+}
+
+// progressLine: 统计中的进度行：有估计时显示已完成和剩余的工时（剩余工时即完成预测），否则按任务数显示
+func (st effortStats) progressLine() string {
+	pct, byEffort := st.percent()
+	if !byEffort {
+		return fmt.Sprintf("📈 进度: 已完成 %d/%d 个任务（%d%%）", st.done, st.tasks, pct)
+	}
+	line := fmt.Sprintf("📈 进度: 已完成 %s / %s（%d%%），预计还需约 %s",
+		formatHours(st.doneHours), formatHours(st.totalHours), pct, formatHours(st.remainingHours()))
+	if st.unestimated > 0 {
+		line += fmt.Sprintf("（另有 %d 个未完成的任务没有估计）", st.unestimated)
+	}
+	return line
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEstimate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"S", 1},
+		{" m ", 4},
+		{"L", 8},
+		{"3h", 3},
+		{"90m", 1.5},
+		{"2天", 16},
+		{"1.5小时", 1.5},
+		{"30 分钟", 0.5},
+		{"1d", 8},
+		{"2", 2},
+	}
+	for _, tt := range tests {
+		got, err := parseEstimate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseEstimate(%q) = %v, %v，期望 %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "XL", "0h", "-2h", "很快", "h", "Infh", "NaN"} {
+		if _, err := parseEstimate(in); err == nil {
+			t.Errorf("parseEstimate(%q) 应返回错误", in)
+		}
+	}
+}
+
+func TestFormatHours(t *testing.T) {
+	tests := []struct {
+		hours float64
+		want  string
+	}{
+		{4, "4h"},
+		{1.5, "1.5h"},
+		{1.0 / 3, "0.3h"},
+		{12.96, "13h"},
+	}
+	for _, tt := range tests {
+		if got := formatHours(tt.hours); got != tt.want {
+			t.Errorf("formatHours(%v) = %q，期望 %q", tt.hours, got, tt.want)
+		}
+	}
+}
+
+func TestComputeEffort(t *testing.T) {
+	l := &TodoList{Items: []TodoItem{
+		{ID: "todo-1", Status: "completed", EstimateHours: 2},
+		{ID: "todo-2", Status: "in_progress", EstimateHours: 6},
+		{ID: "todo-3", Status: "pending"},
+		{ID: "todo-4", Status: "cancelled", EstimateHours: 8},
+		// 父任务的工作量由子任务计算，自己的估计不计入
+		{ID: "todo-5", Status: "in_progress", EstimateHours: 100},
+		{ID: "todo-6", Status: "completed", ParentID: "todo-5", EstimateHours: 1},
+		{ID: "todo-7", Status: "pending", ParentID: "todo-5", EstimateHours: 1},
+		{ID: "todo-8", Status: "completed"},
+	}}
+	got := computeEffort(l)
+	want := effortStats{tasks: 6, done: 3, estimated: 4, totalHours: 10, doneHours: 3, unestimated: 1}
+	if got != want {
+		t.Fatalf("computeEffort = %+v，期望 %+v", got, want)
+	}
+	if got.remainingHours() != 7 {
+		t.Errorf("剩余工时 = %v，期望 7", got.remainingHours())
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		name string
+		st   effortStats
+		want string
+	}{
+		{"没有任务", effortStats{}, "📈 进度: 已完成 0/0 个任务（0%）"},
+		{"没有估计", effortStats{tasks: 3, done: 1}, "📈 进度: 已完成 1/3 个任务（33%）"},
+		{"按工作量", effortStats{tasks: 2, done: 1, estimated: 2, totalHours: 8, doneHours: 2},
+			"📈 进度: 已完成 2h / 8h（25%），预计还需约 6h"},
+		{"部分没有估计", effortStats{tasks: 3, done: 1, estimated: 2, totalHours: 5, doneHours: 1, unestimated: 1},
+			"📈 进度: 已完成 1h / 5h（20%），预计还需约 4h（另有 1 个未完成的任务没有估计）"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.st.progressLine(); got != tt.want {
+				t.Errorf("progressLine = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTodoManagerEstimate: 通过工具设置估计，列表中显示估计和按工作量的进度
+func TestTodoManagerEstimate(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	mustTodo(t, m, map[string]any{"action": "add", "title": "设计接口", "estimate": "M"})
+	mustTodo(t, m, map[string]any{"action": "add", "title": "编写文档"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "estimate": "90m"})
+	if got := item(t, m, "todo-2").EstimateHours; got != 1.5 {
+		t.Fatalf("update 后的估计 = %v，期望 1.5", got)
+	}
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1"})
+
+	out := mustTodo(t, m, map[string]any{"action": "list"})
+	for _, want := range []string{"⌛ 4h", "⌛ 1.5h", "已完成 4h / 5.5h（73%），预计还需约 1.5h"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("列表中缺少 %q：\n%s", want, out)
+		}
+	}
+	if _, err := todoRun(m, map[string]any{"action": "add", "title": "部署", "estimate": "XL"}); err == nil {
+		t.Error("无效的估计应返回错误")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// --- 进度事件 ---
+
+// TodoEventType: 清单变化的类型
+type TodoEventType string
+
+const (
+	EventAdded     TodoEventType = "added"     // EventAdded: 新增任务（包括规划和子任务）
+	EventUpdated   TodoEventType = "updated"   // EventUpdated: 任务的状态、依赖等发生变化（包括失败、父任务状态推导）
+	EventCompleted TodoEventType = "completed" // EventCompleted: 任务完成
+	EventDeleted   TodoEventType = "deleted"   // EventDeleted: 任务被删除，Item 为删除前的内容
+	EventReplanned TodoEventType = "replanned" // EventReplanned: 计划被修订，Revision 为新的修订记录
+)
+
+// TodoEvent: 清单的一次变化；一次操作可能产生多个事件，如完成最后一个子任务时父任务也随之完成
+type TodoEvent struct {
+	Type      TodoEventType `json:"type"`
+	Item      TodoItem      `json:"item"`               // Item: 变化后的任务，replanned 事件为零值
+	Revision  *PlanRevision `json:"revision,omitempty"` // Revision: 只用于 replanned 事件
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// Subscription: 事件订阅；事件按发生的顺序送到有缓冲的通道，通道满时丢弃新事件，慢的订阅者不会拖住 Agent
+type Subscription struct {
+	ch      chan TodoEvent
+	dropped atomic.Int64
+}
+
+// Events: 事件通道，Unsubscribe 后关闭
+func (s *Subscription) Events() <-chan TodoEvent {
+	return s.ch
+}
+
+// Dropped: 因通道已满而丢弃的事件数
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Subscribe: 订阅清单的变化，buffer 为通道的缓冲大小
+func (t *TodoManagerTool) Subscribe(buffer int) *Subscription {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &Subscription{ch: make(chan TodoEvent, max(buffer, 0))}
+	t.subscribers = append(t.subscribers, s)
+	return s
+}
+
+// Unsubscribe: 取消订阅并关闭事件通道
+func (t *TodoManagerTool) Unsubscribe(s *Subscription) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i := slices.Index(t.subscribers, s); i >= 0 {
+		t.subscribers = slices.Delete(t.subscribers, i, i+1)
+		close(s.ch)
+	}
+}
+
+// record: 记录一个事件，保存成功后才发布；调用方须持有 t.mu
+func (t *TodoManagerTool) record(typ TodoEventType, item TodoItem) {
+	t.pending = append(t.pending, TodoEvent{Type: typ, Item: item, Timestamp: t.now()})
+}
+
+// recordDiff: 整体替换清单（规划、重新规划）时，按新旧清单的差异记录事件：新增的任务、有变化的任务
+func (t *TodoManagerTool) recordDiff(before, after *TodoList) {
+	for _, item := range after.Items {
+		i := before.index(item.ID)
+		switch {
+		case i < 0:
+			t.record(EventAdded, item)
+		case item.Status == "completed" && before.Items[i].Status != "completed":
+			t.record(EventCompleted, item)
+		case !reflect.DeepEqual(item, before.Items[i]):
+			t.record(EventUpdated, item)
+		}
+	}
+}
+
+// rollUp: 重新推导父任务的状态，状态有变化的父任务记录为 updated（变为完成时为 completed）事件；调用方须持有 t.mu
+func (t *TodoManagerTool) rollUp(id string) {
+	for _, parent := range t.todos.rollUp(id, t.now()) {
+		if parent.Status == "completed" {
+			t.record(EventCompleted, parent)
+		} else {
+			t.record(EventUpdated, parent)
+		}
+	}
+}
+
+// publish: 把记录的事件发给所有订阅者，不阻塞；调用方须持有 t.mu
+func (t *TodoManagerTool) publish() {
+	for _, event := range t.pending {
+		for _, s := range t.subscribers {
+			select {
+			case s.ch <- event:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+	}
+	t.pending = nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// drain: 取出通道中已有的事件
+func drain(sub *Subscription) []TodoEvent {
+	var events []TodoEvent
+	for {
+		select {
+		case event := <-sub.Events():
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// eventTypes: 事件的类型和任务 ID，如 "added todo-1"
+func eventTypes(events []TodoEvent) []string {
+	out := make([]string, len(events))
+	for i, event := range events {
+		out[i] = string(event.Type) + " " + event.Item.ID
+	}
+	return out
+}
+
+func TestSubscribeReceivesEvents(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	sub := m.Subscribe(16)
+	addTasks(t, m, "发布版本")
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-1", "title": "打标签"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-2"})
+
+	got := eventTypes(drain(sub))
+	// 完成唯一的子任务时父任务也随之完成
+	want := []string{
+		"added todo-1",
+		"added todo-2",
+		"updated todo-2", "updated todo-1",
+		"completed todo-2", "completed todo-1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("事件 = %v，期望 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("第 %d 个事件 = %q，期望 %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+// TestEventsPublishedAfterSave: 保存失败的修改不发布事件，也不会在下一次修改时补发
+func TestEventsPublishedAfterSave(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	sub := m.Subscribe(16)
+	m.storage = failingStorage{errors.New("磁盘已满")}
+	if _, err := todoRun(m, map[string]any{"action": "add", "title": "写文档"}); err == nil {
+		t.Fatal("保存失败应返回错误")
+	}
+	if events := drain(sub); len(events) != 0 {
+		t.Fatalf("保存失败时不应发布事件，得到 %v", eventTypes(events))
+	}
+
+	m.storage = &MemoryStorage{}
+	addTasks(t, m, "写测试")
+	if got := eventTypes(drain(sub)); len(got) != 1 || got[0] != "added todo-2" {
+		t.Errorf("事件 = %v，只应有这次修改的事件", got)
+	}
+}
+
+func TestSubscriptionDropsWhenFull(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	slow := m.Subscribe(1)
+	fast := m.Subscribe(8)
+	addTasks(t, m, "一", "二", "三")
+
+	if n := slow.Dropped(); n != 2 {
+		t.Errorf("缓冲为 1 的订阅丢弃 %d 个事件，期望 2", n)
+	}
+	if got := eventTypes(drain(slow)); len(got) != 1 || got[0] != "added todo-1" {
+		t.Errorf("慢订阅者应收到最早的事件，得到 %v", got)
+	}
+	if n := len(drain(fast)); n != 3 || fast.Dropped() != 0 {
+		t.Errorf("缓冲足够的订阅收到 %d 个事件、丢弃 %d 个", n, fast.Dropped())
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	sub := m.Subscribe(4)
+	m.Unsubscribe(sub)
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("取消订阅后通道应已关闭")
+	}
+	// 重复取消订阅不会再次关闭通道，之后的修改也不再发给它
+	m.Unsubscribe(sub)
+	addTasks(t, m, "写文档")
+	if sub.Dropped() != 0 {
+		t.Errorf("取消订阅后不应再计算丢弃的事件")
+	}
+}
+
+func TestRecordDiff(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	before := listOf(
+		map[string]string{"todo-1": "pending", "todo-2": "in_progress", "todo-3": "pending"},
+		[]string{"todo-1", "todo-2", "todo-3"}, nil)
+	after := before.clone()
+	after.Items[0].Title = "改过的标题"
+	after.Items[1].Status = "completed"
+	after.Items = append(after.Items, TodoItem{ID: "todo-4", Status: "pending"})
+
+	m.recordDiff(before, after)
+	got := eventTypes(m.pending)
+	want := []string{"updated todo-1", "completed todo-2", "added todo-4"}
+	if len(got) != len(want) {
+		t.Fatalf("事件 = %v，期望 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("第 %d 个事件 = %q，期望 %q", i+1, got[i], want[i])
+		}
+	}
+}
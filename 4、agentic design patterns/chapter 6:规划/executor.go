@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+	ucb "github.com/cloudwego/eino/utils/callbacks"
+)
+
+// --- 执行阶段 ---
+
+// workerPrompt: 执行单个任务的 worker 的系统提示词
+const workerPrompt = `你是任务执行助手，负责完成 Todo List 中的一个具体任务。
+
+1. 只完成当前这一个任务，不要去做清单中的其他任务
+2. 需要计算时使用 evaluate_expression，需要产出文档、代码等内容时用 write_file 写入工作区，可以用 read_file / list_dir 查看前置任务留下的文件
+3. 完成后用简洁的中文给出执行结果（结论、产出的文件名等），它会被记录为任务结果，供后续任务参考`
+
+// TaskExecutor: 按依赖顺序取出可以开始的任务，交给带工具的 ReAct worker 真正执行，
+// 把 worker 的最终回答通过 complete 记为任务结果；worker 出错或超时时把任务标记为 failed，并触发重新规划
+type TaskExecutor struct {
+	todos   *TodoManagerTool
+	worker  *react.Agent
+	timeout time.Duration // timeout: 每个任务的执行时限
+
+	// Replan: 任务失败后调用的重新规划（如 PlannerTool.Replan），为空时不重新规划
+	Replan func(ctx context.Context, reason string) (string, error)
+	// MaxReplans: 一次 RunAll 中最多重新规划的次数；修订可能让失败的任务重试，限制次数避免反复失败、反复修订
+	MaxReplans int
+	replans    int
+}
+
+// NewTaskExecutor: 创建执行任务的 worker，tools 为 worker 可用的工具，maxStep 限制每个任务的推理步数
+func NewTaskExecutor(ctx context.Context, llm model.ToolCallingChatModel, tools []tool.BaseTool, todos *TodoManagerTool, maxStep int, timeout time.Duration) (*TaskExecutor, error) {
+	worker, err := react.NewAgent(ctx, &react.AgentConfig{
+		ToolCallingModel: llm,
+		ToolsConfig: compose.ToolsNodeConfig{
+			Tools: tools,
+		},
+		MaxStep: maxStep,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建执行 Agent 失败: %w", err)
+	}
+	return &TaskExecutor{todos: todos, worker: worker, timeout: timeout}, nil
+}
+
+// taskContext: 交给 worker 的任务及其上下文
+type taskContext struct {
+	task      TodoItem
+	ancestors []TodoItem // ancestors: 父任务链，子任务借此了解自己属于哪个大任务
+	deps      []TodoItem // deps: 已完成的依赖任务及其子任务，带执行结果
+}
+
+// nextTask: 执行顺序中第一个可以立即开始的任务；没有时 ok 为 false（全部完成、都在等待失败的任务，或存在循环依赖）
+func (t *TodoManagerTool) nextTask() (tc taskContext, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	order, _ := t.todos.executionOrder()
+	for _, item := range order {
+		if !t.todos.ready(item) {
+			continue
+		}
+		tc = taskContext{task: item, ancestors: t.todos.ancestors(item)}
+		for _, dep := range t.todos.effectiveDeps(item) {
+			if i := t.todos.index(dep); i >= 0 {
+				tc.deps = append(tc.deps, t.todos.Items[i])
+			}
+			// 父任务由子任务推导完成，结果记录在子任务上
+			for _, id := range t.todos.descendants(dep) {
+				tc.deps = append(tc.deps, t.todos.Items[t.todos.index(id)])
+			}
+		}
+		return tc, true
+	}
+	return taskContext{}, false
+}
+
+// prompt: worker 的用户消息：整体目标、当前任务、所属的父任务和前置任务的结果
+func (tc taskContext) prompt(goal string) string {
+	var sb strings.Builder
+	if goal != "" {
+		sb.WriteString(fmt.Sprintf("整体目标：%s\n", goal))
+	}
+	sb.WriteString(fmt.Sprintf("当前任务：[%s] %s\n", tc.task.ID, tc.task.Title))
+	if tc.task.Description != "" {
+		sb.WriteString(fmt.Sprintf("任务描述：%s\n", tc.task.Description))
+	}
+	for _, ancestor := range tc.ancestors {
+		sb.WriteString(fmt.Sprintf("所属任务：[%s] %s\n", ancestor.ID, ancestor.Title))
+	}
+	header := false
+	for _, dep := range tc.deps {
+		if dep.Result == "" {
+			continue
+		}
+		if !header {
+			sb.WriteString("前置任务的结果：\n")
+			header = true
+		}
+		sb.WriteString(fmt.Sprintf("- [%s] %s：%s\n", dep.ID, dep.Title, dep.Result))
+	}
+	return sb.String()
+}
+
+// todo: 通过 todo_manager 的操作更新任务，与 Agent 调用工具走同一条路径（校验、状态推导、保存）
+func (e *TaskExecutor) todo(ctx context.Context, args map[string]any) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("序列化参数失败: %w", err)
+	}
+	return e.todos.InvokableRun(ctx, string(data))
+}
+
+// ExecuteNext: 执行下一个可以开始的任务；没有可执行的任务时返回 false
+// worker 的失败只把任务标记为 failed，返回的 error 表示清单本身无法更新（如保存失败）
+func (e *TaskExecutor) ExecuteNext(ctx context.Context, goal string) (bool, error) {
+	tc, ok := e.todos.nextTask()
+	if !ok {
+		return false, nil
+	}
+	id := tc.task.ID
+	fmt.Printf("\n--- 👷 执行任务：[%s] %s ---\n", id, tc.task.Title)
+	if _, err := e.todo(ctx, map[string]any{"action": "update", "id": id, "status": "in_progress"}); err != nil {
+		return true, err
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	usage := &workerUsage{}
+	response, err := e.worker.Generate(taskCtx, []*schema.Message{
+		schema.SystemMessage(workerPrompt),
+		schema.UserMessage(tc.prompt(goal)),
+	}, agent.WithComposeOptions(compose.WithCallbacks(usage.handler())))
+	e.todos.addUsage(id, int(usage.toolCalls.Load()), int(usage.tokens.Load()))
+
+	var reason string
+	switch {
+	case errors.Is(taskCtx.Err(), context.DeadlineExceeded):
+		reason = fmt.Sprintf("执行超时（%s）", e.timeout)
+	case err != nil:
+		reason, _, _ = strings.Cut(err.Error(), "\n") // 只保留第一行，去掉图的节点路径等细节
+	case strings.TrimSpace(response.Content) == "":
+		reason = "执行 Agent 没有给出结果"
+	}
+	if reason != "" {
+		if _, err := e.todo(ctx, map[string]any{"action": "fail", "id": id, "error": reason}); err != nil {
+			return true, err
+		}
+		e.replanAfterFailure(ctx, fmt.Sprintf("任务 %s（%s）失败：%s", id, tc.task.Title, reason))
+		return true, nil
+	}
+	_, err = e.todo(ctx, map[string]any{"action": "complete", "id": id, "result": strings.TrimSpace(response.Content)})
+	return true, err
+}
+
+// workerUsage: 通过回调统计 worker 执行一个任务的工具调用次数和 Token 数；工具可能并行执行，所以用原子计数
+type workerUsage struct {
+	toolCalls atomic.Int64
+	tokens    atomic.Int64
+}
+
+// handler: 模型每次返回时累加 Token（模型没有返回用量时不计），每次调用工具时计数
+func (u *workerUsage) handler() callbacks.Handler {
+	return ucb.NewHandlerHelper().
+		ChatModel(&ucb.ModelCallbackHandler{
+			OnEnd: func(ctx context.Context, _ *callbacks.RunInfo, output *model.CallbackOutput) context.Context {
+				switch {
+				case output.TokenUsage != nil:
+					u.tokens.Add(int64(output.TokenUsage.TotalTokens))
+				case output.Message != nil && output.Message.ResponseMeta != nil && output.Message.ResponseMeta.Usage != nil:
+					u.tokens.Add(int64(output.Message.ResponseMeta.Usage.TotalTokens))
+				}
+				return ctx
+			},
+		}).
+		Tool(&ucb.ToolCallbackHandler{
+			OnStart: func(ctx context.Context, _ *callbacks.RunInfo, _ *tool.CallbackInput) context.Context {
+				u.toolCalls.Add(1)
+				return ctx
+			},
+		}).
+		Handler()
+}
+
+// replanAfterFailure: 任务失败后重新规划；重新规划失败不影响继续执行其余任务
+func (e *TaskExecutor) replanAfterFailure(ctx context.Context, trigger string) {
+	if e.Replan == nil || e.replans >= e.MaxReplans {
+		return
+	}
+	e.replans++
+	if _, err := e.Replan(ctx, trigger); err != nil {
+		fmt.Printf("⚠ 重新规划失败，继续执行剩下的任务: %v\n", err)
+	}
+}
+
+// RunAll: 逐个执行任务，直到没有可以开始的任务；返回执行的任务数
+// 每个任务执行后都变为 completed 或 failed，只有重新规划（最多 MaxReplans 次）会让任务重新待处理，因此循环一定结束
+func (e *TaskExecutor) RunAll(ctx context.Context, goal string) (int, error) {
+	e.replans = 0
+	executed := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return executed, err
+		}
+		ok, err := e.ExecuteNext(ctx, goal)
+		if err != nil {
+			return executed, err
+		}
+		if !ok {
+			return executed, nil
+		}
+		executed++
+	}
+}
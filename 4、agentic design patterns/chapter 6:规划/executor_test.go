@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shared/toolkit"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// scriptedModel: 由函数给出回答的假模型，用于 worker 和重新规划链
+type scriptedModel struct {
+	reply func(ctx context.Context, input []*schema.Message) (*schema.Message, error)
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.reply(ctx, input)
+}
+
+func (m *scriptedModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.reply(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (m *scriptedModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// currentTask: worker 的用户消息中当前任务的标题
+func currentTask(input []*schema.Message) string {
+	for _, msg := range input {
+		if msg.Role != schema.User {
+			continue
+		}
+		for _, line := range strings.Split(msg.Content, "\n") {
+			if rest, ok := strings.CutPrefix(line, "当前任务："); ok {
+				_, title, _ := strings.Cut(rest, "] ")
+				return title
+			}
+		}
+	}
+	return ""
+}
+
+// newTestExecutor: 使用 reply 作为 worker 模型的执行器，worker 只有计算器
+func newTestExecutor(t *testing.T, m *TodoManagerTool, timeout time.Duration, reply func(ctx context.Context, input []*schema.Message) (*schema.Message, error)) *TaskExecutor {
+	t.Helper()
+	e, err := NewTaskExecutor(context.Background(), &scriptedModel{reply: reply}, []tool.BaseTool{&toolkit.ExpressionCalculatorTool{}}, m, 4, timeout)
+	if err != nil {
+		t.Fatalf("NewTaskExecutor: %v", err)
+	}
+	return e
+}
+
+func TestTaskExecutorRunsInDependencyOrder(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	mustTodo(t, m, map[string]any{"action": "add", "title": "测试", "description": "覆盖主要流程"})
+	addTasks(t, m, "需求分析")
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "depends_on": []string{"todo-2"}})
+
+	var prompts []string
+	e := newTestExecutor(t, m, time.Second, func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+		prompts = append(prompts, input[len(input)-1].Content)
+		return schema.AssistantMessage(currentTask(input)+"已完成", nil), nil
+	})
+	executed, err := e.RunAll(context.Background(), "开发待办应用")
+	if err != nil || executed != 2 {
+		t.Fatalf("RunAll = %d, %v，want 2", executed, err)
+	}
+	if got := item(t, m, "todo-1"); got.Status != "completed" || got.Result != "测试已完成" {
+		t.Errorf("todo-1 = %+v", got)
+	}
+	if len(prompts) != 2 || !strings.Contains(prompts[0], "当前任务：[todo-2] 需求分析") {
+		t.Fatalf("执行顺序: %q", prompts)
+	}
+	for _, want := range []string{"整体目标：开发待办应用", "任务描述：覆盖主要流程", "前置任务的结果：\n- [todo-2] 需求分析：需求分析已完成"} {
+		if !strings.Contains(prompts[1], want) {
+			t.Errorf("第二个任务的提示缺少 %q:\n%s", want, prompts[1])
+		}
+	}
+}
+
+// TestTaskExecutorFailures: worker 出错、超时或没有结果时任务标记为失败，依赖它的任务不再执行
+func TestTaskExecutorFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      func(ctx context.Context, input []*schema.Message) (*schema.Message, error)
+		wantReason string
+	}{
+		{"模型出错", func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+			return nil, errors.New("服务不可用\n详细的节点路径")
+		}, "服务不可用"},
+		{"超时", func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}, "执行超时（50ms）"},
+		{"没有结果", func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+			return schema.AssistantMessage("  ", nil), nil
+		}, "执行 Agent 没有给出结果"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestTodoManager(t, nil)
+			addTasks(t, m, "需求分析")
+			mustTodo(t, m, map[string]any{"action": "add", "title": "后端开发", "depends_on": []string{"todo-1"}})
+			e := newTestExecutor(t, m, 50*time.Millisecond, tt.reply)
+			var triggers []string
+			e.Replan = func(ctx context.Context, reason string) (string, error) {
+				triggers = append(triggers, reason)
+				return "", errors.New("规划链不可用")
+			}
+			e.MaxReplans = 1
+
+			executed, err := e.RunAll(context.Background(), "")
+			if err != nil || executed != 1 {
+				t.Fatalf("RunAll = %d, %v，want 1", executed, err)
+			}
+			got := item(t, m, "todo-1")
+			if got.Status != "failed" || !strings.Contains(got.Error, tt.wantReason) || strings.Contains(got.Error, "\n") {
+				t.Errorf("todo-1 = %s，原因 %q，want 包含 %q", got.Status, got.Error, tt.wantReason)
+			}
+			if item(t, m, "todo-2").Status != "pending" {
+				t.Errorf("依赖失败任务的任务不应执行")
+			}
+			if len(triggers) != 1 || !strings.HasPrefix(triggers[0], "任务 todo-1（需求分析）失败：") {
+				t.Errorf("重新规划的原因 = %q", triggers)
+			}
+		})
+	}
+}
+
+// TestTaskExecutorReplanLimit: 重新规划让失败的任务重试，最多 MaxReplans 次
+func TestTaskExecutorReplanLimit(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "总是失败")
+	e := newTestExecutor(t, m, time.Second, func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+		return nil, errors.New("失败")
+	})
+	replans := 0
+	e.Replan = func(ctx context.Context, reason string) (string, error) {
+		replans++
+		desc := "换一种做法"
+		_, err := m.applyPatch(ctx, PlanPatch{Modify: []PatchModify{{ID: "todo-1", Description: &desc}}}, reason)
+		return "", err
+	}
+	e.MaxReplans = 2
+	executed, err := e.RunAll(context.Background(), "")
+	if err != nil || executed != 3 || replans != 2 {
+		t.Errorf("RunAll = %d, %v，重新规划 %d 次，want 3 次执行、2 次重新规划", executed, err, replans)
+	}
+}
+
+// TestTaskExecutorCountsUsage: 通过回调统计 worker 的工具调用和 Token
+func TestTaskExecutorCountsUsage(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "计算预算")
+	e := newTestExecutor(t, m, time.Second, func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+		var msg *schema.Message
+		if input[len(input)-1].Role == schema.Tool {
+			msg = schema.AssistantMessage("预算为 "+input[len(input)-1].Content, nil)
+		} else {
+			msg = schema.AssistantMessage("", []schema.ToolCall{{
+				ID:       "call-1",
+				Function: schema.FunctionCall{Name: "evaluate_expression", Arguments: `{"expression":"3*(4+6)"}`},
+			}})
+		}
+		msg.ResponseMeta = &schema.ResponseMeta{Usage: &schema.TokenUsage{TotalTokens: 100}}
+		return msg, nil
+	})
+	if _, err := e.ExecuteNext(context.Background(), ""); err != nil {
+		t.Fatalf("ExecuteNext: %v", err)
+	}
+	got := item(t, m, "todo-1")
+	if got.Status != "completed" || got.Result != "预算为 30" || got.ToolCalls != 1 || got.Tokens != 200 {
+		t.Errorf("todo-1 = %+v", got)
+	}
+	if ok, err := e.ExecuteNext(context.Background(), ""); ok || err != nil {
+		t.Errorf("没有可执行的任务时 ExecuteNext = %v, %v", ok, err)
+	}
+}
+
+func TestNextTaskContext(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "设计", "开发")
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-1", "title": "接口设计"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "depends_on": []string{"todo-1"}})
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-2", "title": "后端"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-3", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-3", "result": "REST 接口"})
+
+	tc, ok := m.nextTask()
+	if !ok || tc.task.ID != "todo-4" {
+		t.Fatalf("nextTask = %+v, %v，want todo-4", tc.task, ok)
+	}
+	if got := ids(tc.ancestors); !slices.Equal(got, []string{"todo-2"}) {
+		t.Errorf("ancestors = %v", got)
+	}
+	// 依赖的父任务由子任务完成，结果来自子任务
+	if got := ids(tc.deps); !slices.Equal(got, []string{"todo-1", "todo-3"}) {
+		t.Errorf("deps = %v", got)
+	}
+	prompt := tc.prompt("")
+	if strings.Contains(prompt, "整体目标") || !strings.Contains(prompt, "所属任务：[todo-2] 开发") || !strings.Contains(prompt, "- [todo-3] 接口设计：REST 接口") {
+		t.Errorf("prompt:\n%s", prompt)
+	}
+}
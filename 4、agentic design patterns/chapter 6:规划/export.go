@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- 导出 ---
+
+// exportExtensions: 导出格式对应的文件扩展名
+var exportExtensions = map[string]string{"markdown": ".md", "json": ".json"}
+
+// exportTodoList: 按格式导出清单；配置了 exportDir 时同时写入带时间戳的文件，返回内容前加上文件路径
+func (t *TodoManagerTool) exportTodoList(format string) (string, error) {
+	if format == "" {
+		format = "markdown"
+	}
+	ext, ok := exportExtensions[format]
+	if !ok {
+		return "", fmt.Errorf("不支持的导出格式 %q（可选：markdown、json）", format)
+	}
+
+	var content string
+	switch format {
+	case "markdown":
+		content = renderMarkdown(t.todos, t.now())
+	case "json":
+		data, err := json.MarshalIndent(t.todos, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("序列化 Todo List 失败: %w", err)
+		}
+		content = string(data) + "\n"
+	}
+	if t.exportDir == "" {
+		return content, nil
+	}
+
+	if err := os.MkdirAll(t.exportDir, 0o755); err != nil {
+		return "", fmt.Errorf("创建导出目录失败: %w", err)
+	}
+	path := filepath.Join(t.exportDir, "todo-"+t.now().Format("20060102-150405.000")+ext)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("写入导出文件失败: %w", err)
+	}
+	fmt.Printf("📤 Todo List 已导出到 %s\n", path)
+	return fmt.Sprintf("已导出到 %s\n\n%s", path, content), nil
+}
+
+// renderMarkdown: 把清单渲染为 Markdown 复选框列表，可以直接粘贴到 issue 中：子任务缩进在父任务下，
+// 按清单中的顺序排列，附带描述、执行结果、失败原因和用时；执行过任务时最后附上执行摘要
+func renderMarkdown(todos *TodoList, now time.Time) string {
+	var sb strings.Builder
+	title := todos.Goal
+	if title == "" {
+		title = "Todo List"
+	}
+	sb.WriteString("# " + title + "\n\n")
+	if n := len(todos.Revisions); n > 0 {
+		latest := todos.Revisions[n-1]
+		reason := latest.Reason
+		if reason == "" {
+			reason = latest.Trigger
+		}
+		sb.WriteString(fmt.Sprintf("> 计划第 %d 版（%s）：%s\n\n", latest.Number, latest.At.Format("2006-01-02 15:04"), reason))
+	}
+	if len(todos.Items) == 0 {
+		sb.WriteString("（没有任务）\n")
+		return sb.String()
+	}
+
+	var walk func(items []TodoItem, depth int)
+	walk = func(items []TodoItem, depth int) {
+		indent := strings.Repeat("  ", depth)
+		for _, item := range items {
+			sb.WriteString(indent + markdownTaskLine(item, now) + "\n")
+			detail := func(label, text string) {
+				for i, line := range strings.Split(strings.TrimSpace(text), "\n") {
+					if i == 0 && label != "" {
+						line = label + line
+					}
+					sb.WriteString(indent + "  " + line + "\n")
+				}
+			}
+			if item.Description != "" {
+				detail("", item.Description)
+			}
+			if item.Status == "completed" && item.Result != "" {
+				detail("结果：", item.Result)
+			}
+			if item.Status == "failed" && item.Error != "" {
+				detail("失败原因：", item.Error)
+			}
+			walk(todos.children(item.ID), depth+1)
+		}
+	}
+	walk(todos.roots(), 0)
+
+	counts := map[string]int{}
+	for _, item := range todos.Items {
+		counts[item.Status]++
+	}
+	stats := []string{fmt.Sprintf("总计 %d", len(todos.Items))}
+	for _, status := range statusOrder {
+		if counts[status] > 0 {
+			stats = append(stats, fmt.Sprintf("%s %d", statusLabels[status], counts[status]))
+		}
+	}
+	sb.WriteString("\n---\n" + strings.Join(stats, " · ") + "\n\n")
+	sb.WriteString(computeEffort(todos).progressLine() + "\n")
+	if summary := summarizeRuns(todos, now); len(summary.runs) > 0 {
+		sb.WriteString("\n## 执行摘要\n\n" + summary.render())
+	}
+	return sb.String()
+}
+
+// markdownTaskLine: 任务行：完成的任务打勾，已取消的加删除线；后面依次是 ID、状态、优先级、截止时间和用时
+func markdownTaskLine(item TodoItem, now time.Time) string {
+	box := "- [ ] "
+	if item.Status == "completed" {
+		box = "- [x] "
+	}
+	title := item.Title
+	if item.Status == "cancelled" {
+		title = "~~" + title + "~~"
+	}
+	parts := []string{fmt.Sprintf("%s%s `%s`", box, title, item.ID)}
+	switch item.Status {
+	case "in_progress":
+		parts = append(parts, "🔄 进行中")
+	case "failed":
+		parts = append(parts, "❌ 失败")
+	case "cancelled":
+		parts = append(parts, "⛔ 已取消")
+	}
+	if icon, ok := priorityIcons[item.Priority]; ok {
+		parts = append(parts, icon)
+	}
+	if item.EstimateHours > 0 {
+		parts = append(parts, "⌛ "+formatHours(item.EstimateHours))
+	}
+	if !item.Due.IsZero() {
+		due := "📅 " + formatDue(item.Due)
+		if overdue(item, now) {
+			due += "（已逾期）"
+		}
+		parts = append(parts, due)
+	}
+	if d, ok := taskDuration(item, now); ok {
+		if item.Status != "in_progress" {
+			parts = append(parts, "⏱️ 用时 "+formatDuration(d))
+		} else {
+			parts = append(parts, "⏱️ 已进行 "+formatDuration(d))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
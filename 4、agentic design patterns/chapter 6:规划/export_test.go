@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newExportFixture: 包含所有状态和两层子任务的清单，经过一次重新规划
+func newExportFixture(t *testing.T) *TodoManagerTool {
+	t.Helper()
+	m, clock := newTestTodoManager(t, nil)
+	m.todos = &TodoList{
+		Goal: "发布 v2",
+		Items: []TodoItem{
+			{ID: "todo-1", Title: "需求分析", Status: "completed", Result: "需求文档\n已评审", Priority: "high"},
+			{ID: "todo-2", Title: "开发", Status: "in_progress", Description: "前后端一起"},
+			{ID: "todo-3", Title: "后端接口", Status: "failed", ParentID: "todo-2", Error: "数据库连接超时"},
+			{ID: "todo-4", Title: "鉴权", Status: "pending", ParentID: "todo-3", EstimateHours: 2},
+			{ID: "todo-5", Title: "旧版迁移", Status: "cancelled", Priority: "low"},
+		},
+		NextID: 6,
+		Revisions: []PlanRevision{
+			{Number: 1, At: clock.t.Add(-time.Hour), Trigger: "todo-3 失败", Summary: "新增 todo-4"},
+			{Number: 2, At: clock.t, Trigger: "todo-3 失败", Reason: "拆分鉴权", Summary: "取消 todo-5"},
+		},
+	}
+	return m
+}
+
+func TestExportMarkdown(t *testing.T) {
+	m := newExportFixture(t)
+	got, err := m.exportTodoList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# 发布 v2\n\n" +
+		"> 计划第 2 版（2024-03-09 09:00）：拆分鉴权\n\n" +
+		"- [x] 需求分析 `todo-1` · 🔴 高\n" +
+		"  结果：需求文档\n" +
+		"  已评审\n" +
+		"- [ ] 开发 `todo-2` · 🔄 进行中\n" +
+		"  前后端一起\n" +
+		"  - [ ] 后端接口 `todo-3` · ❌ 失败\n" +
+		"    失败原因：数据库连接超时\n" +
+		"    - [ ] 鉴权 `todo-4` · ⌛ 2h\n" +
+		"- [ ] ~~旧版迁移~~ `todo-5` · ⛔ 已取消 · 🟢 低\n" +
+		"\n---\n"
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("导出的 Markdown =\n%s\n期望以下内容开头\n%s", got, want)
+	}
+	if !strings.Contains(got, "总计 5 · ✅ 已完成 1 · 🔄 进行中 1 · ⏳ 待处理 1 · ❌ 失败 1 · ⛔ 已取消 1") {
+		t.Errorf("统计行不对：\n%s", got)
+	}
+
+	m.todos = &TodoList{}
+	if got, _ := m.exportTodoList("markdown"); got != "# Todo List\n\n（没有任务）\n" {
+		t.Errorf("空清单 = %q", got)
+	}
+}
+
+func TestExportJSONKeepsRevisions(t *testing.T) {
+	m := newExportFixture(t)
+	got, err := m.exportTodoList("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list TodoList
+	if err := json.Unmarshal([]byte(got), &list); err != nil {
+		t.Fatalf("导出的 JSON 无法解析: %v", err)
+	}
+	if len(list.Items) != 5 || list.Items[3].ParentID != "todo-3" || list.Goal != "发布 v2" {
+		t.Errorf("任务 = %+v", list.Items)
+	}
+	if len(list.Revisions) != 2 || list.Revisions[1].Reason != "拆分鉴权" || list.Revisions[1].Summary != "取消 todo-5" {
+		t.Errorf("修订历史 = %+v", list.Revisions)
+	}
+}
+
+func TestExportWritesFile(t *testing.T) {
+	m := newExportFixture(t)
+	m.exportDir = filepath.Join(t.TempDir(), "exports")
+	for format, name := range map[string]string{"markdown": "todo-20240309-090000.000.md", "json": "todo-20240309-090000.000.json"} {
+		got, err := m.exportTodoList(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(m.exportDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s 应写入 %s: %v", format, name, err)
+		}
+		if got != "已导出到 "+path+"\n\n"+string(data) {
+			t.Errorf("%s 的返回值应为文件路径加内容，得到 %q", format, got)
+		}
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	m := newExportFixture(t)
+	m.exportDir = t.TempDir()
+	if _, err := m.exportTodoList("csv"); err == nil || !strings.Contains(err.Error(), `不支持的导出格式 "csv"`) {
+		t.Errorf("未知格式 = %v", err)
+	}
+	if entries, _ := os.ReadDir(m.exportDir); len(entries) != 0 {
+		t.Errorf("未知格式不应写入文件，得到 %d 个", len(entries))
+	}
+	if _, err := todoRun(m, map[string]any{"action": "export", "format": "yaml"}); err == nil {
+		t.Error("通过 todo_manager 导出未知格式应返回错误")
+	}
+}
@@ -0,0 +1,114 @@
+//go:build integration
+
+// 需要 Redis 和 Elasticsearch 的集成测试：go test -tags integration .
+// 连接取自 REDIS_ADDR / REDIS_PASSWORD 和 ES_ADDR / ES_USER / ES_PASSWORD，服务不可用时跳过
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// getenv: 读取环境变量，为空时使用默认值
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// newTestRedisStorage: 连接 Redis，使用本次测试独有的键，测试结束时删除键及其备份
+func newTestRedisStorage(t *testing.T) *RedisStorage {
+	t.Helper()
+	key := sessionTodosKey(fmt.Sprintf("ch6-test-%d", time.Now().UnixNano()))
+	s, err := NewRedisStorage(context.Background(), getenv("REDIS_ADDR", "localhost:6379"), os.Getenv("REDIS_PASSWORD"), 0, key)
+	if err != nil {
+		t.Skipf("Redis 不可用: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		backups, _ := s.client.Keys(ctx, key+"*").Result()
+		s.client.Del(ctx, backups...)
+		s.Close()
+	})
+	return s
+}
+
+func TestRedisStorageIntegration(t *testing.T) {
+	ctx := context.Background()
+	s := newTestRedisStorage(t)
+
+	m, _ := newTestTodoManager(t, s)
+	addTasks(t, m, "设计", "开发")
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+
+	// 同一个键上的第二次会话继续上一次的清单
+	next, _ := newTestTodoManager(t, s)
+	if len(next.todos.Items) != 2 || item(t, next, "todo-1").Status != "in_progress" || next.todos.NextID != 3 {
+		t.Fatalf("从 Redis 加载的清单 = %+v", next.todos)
+	}
+
+	if err := s.client.Set(ctx, s.key, "{不是 JSON", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	todos, err := s.Load(ctx)
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) || corrupt.Backup == "" || len(todos.Items) != 0 {
+		t.Fatalf("内容损坏时 = %+v, %v", todos, err)
+	}
+	backup, err := s.client.Get(ctx, corrupt.Backup[len("redis:"):]).Result()
+	if err != nil || backup != "{不是 JSON" {
+		t.Errorf("备份内容 = %q, %v", backup, err)
+	}
+}
+
+// fixedEmbedder: 返回固定向量的 Embedding，集成测试不调用模型
+type fixedEmbedder struct{}
+
+func (fixedEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i := range texts {
+		vectors[i] = []float64{0.1, 0.2, 0.3}
+	}
+	return vectors, nil
+}
+
+func TestLongTermMemoryIntegration(t *testing.T) {
+	ctx := context.Background()
+	addr := getenv("ES_ADDR", "http://localhost:9200")
+	index := fmt.Sprintf("ch6_test_%d", time.Now().UnixNano())
+	ltm, err := NewLongTermMemory(ctx, addr, os.Getenv("ES_USER"), os.Getenv("ES_PASSWORD"), index, fixedEmbedder{})
+	if err != nil {
+		t.Skipf("Elasticsearch 不可用: %v", err)
+	}
+	t.Cleanup(func() {
+		client, err := elasticsearch.NewClient(elasticsearch.Config{
+			Addresses: []string{addr}, Username: os.Getenv("ES_USER"), Password: os.Getenv("ES_PASSWORD"),
+		})
+		if err == nil {
+			if res, err := client.Indices.Delete([]string{index}); err == nil {
+				res.Body.Close()
+			}
+		}
+	})
+
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "调研")
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1", "result": "选 Redis"})
+	id, err := m.RecordOutcome(ctx, ltm, "ch6-test")
+	if err != nil || id == "" {
+		t.Fatalf("RecordOutcome = %q, %v", id, err)
+	}
+	if id, _ := m.RecordOutcome(ctx, ltm, "ch6-test"); id != "" {
+		t.Errorf("已写入的结果不应重复写入，得到 %q", id)
+	}
+}
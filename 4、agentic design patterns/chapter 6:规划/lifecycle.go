@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- 任务生命周期 ---
+
+// transitions: 允许的状态转换：pending → in_progress → completed；进行中的任务可以失败或退回待处理，
+// 失败的任务可以重试，未完成的任务都可以取消；completed 和 cancelled 是终态
+var transitions = map[string][]string{
+	"pending":     {"in_progress", "cancelled"},
+	"in_progress": {"completed", "failed", "pending", "cancelled"},
+	"failed":      {"pending", "in_progress", "cancelled"},
+	"completed":   {},
+	"cancelled":   {},
+}
+
+// statusOrder: 统计和错误信息中状态的顺序
+var statusOrder = []string{"completed", "in_progress", "pending", "failed", "cancelled"}
+
+// statusLabels: 统计中状态的显示
+var statusLabels = map[string]string{
+	"pending":     "⏳ 待处理",
+	"in_progress": "🔄 进行中",
+	"completed":   "✅ 已完成",
+	"failed":      "❌ 失败",
+	"cancelled":   "⛔ 已取消",
+}
+
+// parseStatus: 校验状态名称
+func parseStatus(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if _, ok := transitions[s]; !ok {
+		return "", fmt.Errorf("无效的状态 %q（可选：%s）", s, strings.Join(statusOrder, "、"))
+	}
+	return s, nil
+}
+
+// checkTransition: 不允许的状态转换返回说明（包含当前状态和可以转换到的状态），作为工具结果交给 Agent；允许时返回空字符串
+func checkTransition(item TodoItem, to string) string {
+	allowed := transitions[item.Status]
+	if slices.Contains(allowed, to) {
+		return ""
+	}
+	if len(allowed) == 0 {
+		return fmt.Sprintf("任务 %s 当前状态为 %s，已经结束，不能再变为 %s", item.ID, item.Status, to)
+	}
+	return fmt.Sprintf("任务 %s 当前状态为 %s，不能变为 %s（只能变为：%s）", item.ID, item.Status, to, strings.Join(allowed, "、"))
+}
+
+// enterStatus: 切换状态并记录时间：进入 in_progress 时记录开始时间，完成或失败时记录结束时间；不是失败时清除失败原因
+func enterStatus(item *TodoItem, status string, now time.Time) {
+	item.Status = status
+	switch status {
+	case "in_progress":
+		item.StartedAt = now
+		item.CompletedAt = time.Time{}
+	case "completed", "failed":
+		item.CompletedAt = now
+	}
+	if status != "failed" {
+		item.Error = ""
+	}
+}
+
+// taskDuration: 已完成（或失败）任务的用时、进行中任务已经进行的时间；没有开始时间时 ok 为 false
+func taskDuration(item TodoItem, now time.Time) (d time.Duration, ok bool) {
+	if item.StartedAt.IsZero() {
+		return 0, false
+	}
+	switch item.Status {
+	case "completed", "failed":
+		if item.CompletedAt.IsZero() {
+			return 0, false
+		}
+		return item.CompletedAt.Sub(item.StartedAt), true
+	case "in_progress":
+		return now.Sub(item.StartedAt), true
+	}
+	return 0, false
+}
+
+// formatDuration: 用时的显示，精确到秒
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return "不到 1 秒"
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStatus(t *testing.T) {
+	for _, s := range []string{"pending", "in_progress", "completed", "failed", "cancelled", " failed "} {
+		if _, err := parseStatus(s); err != nil {
+			t.Errorf("parseStatus(%q) 出错: %v", s, err)
+		}
+	}
+	_, err := parseStatus("done")
+	if err == nil || !strings.Contains(err.Error(), "completed、in_progress、pending、failed、cancelled") {
+		t.Errorf("无效状态的错误应列出可选状态，得到 %v", err)
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string // want: 为空表示允许，否则是说明中应包含的内容
+	}{
+		{"开始任务", "pending", "in_progress", ""},
+		{"完成任务", "in_progress", "completed", ""},
+		{"退回待处理", "in_progress", "pending", ""},
+		{"重试失败的任务", "failed", "in_progress", ""},
+		{"取消待处理的任务", "pending", "cancelled", ""},
+		{"跳过进行中", "pending", "completed", "只能变为：in_progress、cancelled"},
+		{"待处理的任务不能失败", "pending", "failed", "不能变为 failed"},
+		{"已完成是终态", "completed", "pending", "已经结束"},
+		{"已取消是终态", "cancelled", "in_progress", "已经结束"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := checkTransition(TodoItem{ID: "todo-1", Status: tt.from}, tt.to)
+			if tt.want == "" {
+				if msg != "" {
+					t.Errorf("%s → %s 应被允许，得到 %q", tt.from, tt.to, msg)
+				}
+				return
+			}
+			if !strings.Contains(msg, tt.want) || !strings.Contains(msg, "当前状态为 "+tt.from) {
+				t.Errorf("%s → %s 的说明 = %q，应包含 %q 和当前状态", tt.from, tt.to, msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnterStatus(t *testing.T) {
+	start := time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Second)
+
+	item := TodoItem{Status: "pending"}
+	enterStatus(&item, "in_progress", start)
+	if !item.StartedAt.Equal(start) || !item.CompletedAt.IsZero() {
+		t.Fatalf("开始后 StartedAt=%v, CompletedAt=%v", item.StartedAt, item.CompletedAt)
+	}
+
+	item.Error = "超时"
+	enterStatus(&item, "failed", end)
+	if !item.CompletedAt.Equal(end) || item.Error != "超时" {
+		t.Fatalf("失败后 CompletedAt=%v, Error=%q，应记录结束时间并保留失败原因", item.CompletedAt, item.Error)
+	}
+
+	// 重试时重新记录开始时间，清除结束时间和失败原因
+	retry := end.Add(time.Minute)
+	enterStatus(&item, "in_progress", retry)
+	if !item.StartedAt.Equal(retry) || !item.CompletedAt.IsZero() || item.Error != "" {
+		t.Errorf("重试后 StartedAt=%v, CompletedAt=%v, Error=%q", item.StartedAt, item.CompletedAt, item.Error)
+	}
+}
+
+func TestTaskDuration(t *testing.T) {
+	start := time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC)
+	now := start.Add(10 * time.Minute)
+	tests := []struct {
+		name   string
+		item   TodoItem
+		want   time.Duration
+		wantOK bool
+	}{
+		{"未开始", TodoItem{Status: "pending"}, 0, false},
+		{"进行中", TodoItem{Status: "in_progress", StartedAt: start}, 10 * time.Minute, true},
+		{"已完成", TodoItem{Status: "completed", StartedAt: start, CompletedAt: start.Add(3 * time.Minute)}, 3 * time.Minute, true},
+		{"失败", TodoItem{Status: "failed", StartedAt: start, CompletedAt: start.Add(time.Minute)}, time.Minute, true},
+		{"完成但没有结束时间", TodoItem{Status: "completed", StartedAt: start}, 0, false},
+		{"退回待处理", TodoItem{Status: "pending", StartedAt: start}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := taskDuration(tt.item, now)
+			if d != tt.want || ok != tt.wantOK {
+				t.Errorf("taskDuration = %v, %v，期望 %v, %v", d, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "不到 1 秒"},
+		{400 * time.Millisecond, "不到 1 秒"},
+		{1500 * time.Millisecond, "2s"},
+		{90 * time.Second, "1m30s"},
+		{2*time.Hour + 3*time.Second, "2h0m3s"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q，期望 %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+// TestTodoManagerLifecycle: 通过工具推进任务状态，不允许的转换作为说明返回而不是错误
+func TestTodoManagerLifecycle(t *testing.T) {
+	m, clock := newTestTodoManager(t, nil)
+	addTasks(t, m, "写接口文档")
+
+	out := mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1"})
+	if !strings.Contains(out, "不能变为 completed") {
+		t.Fatalf("跳过 in_progress 直接完成应被拒绝，得到 %q", out)
+	}
+
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	clock.advance(2 * time.Minute)
+	mustTodo(t, m, map[string]any{"action": "fail", "id": "todo-1", "error": "缺少接口设计"})
+	if got := item(t, m, "todo-1"); got.Status != "failed" || got.Error != "缺少接口设计" {
+		t.Fatalf("fail 后 状态=%s, 原因=%q", got.Status, got.Error)
+	}
+
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	clock.advance(3 * time.Minute)
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1", "result": "文档已提交"})
+	got := item(t, m, "todo-1")
+	if got.Status != "completed" || got.Error != "" || got.Result != "文档已提交" {
+		t.Fatalf("完成后 状态=%s, 原因=%q, 结果=%q", got.Status, got.Error, got.Result)
+	}
+	if d, _ := taskDuration(got, clock.now()); d != 3*time.Minute {
+		t.Errorf("用时 = %v，应从重试时开始计算", d)
+	}
+
+	out = mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "pending"})
+	if !strings.Contains(out, "已经结束") {
+		t.Errorf("已完成的任务不应再修改状态，得到 %q", out)
+	}
+	if _, err := todoRun(m, map[string]any{"action": "update", "id": "todo-1", "status": "done"}); err == nil {
+		t.Error("无效的状态应返回错误")
+	}
+}
@@ -1,17 +1,29 @@
 /*
 规划 (Planning) 是 Agent 的“实时导航系统”：它将模糊的最终目标转化为可执行的“动态待办清单 (Dynamic To-Do List)”，并具备在执行过程中根据反馈随时“重新规划 (Re-planning)”的能力。
+
+规划 Agent 用 planner 拆分任务、用 todo_manager 维护清单（依赖、子任务、优先级、状态）；执行阶段由 worker 按依赖顺序完成任务，
+任务失败时重新规划。清单可以保存到文件或 Redis，跨会话继续。可用的命令行参数见 go run . -h。
 */
 package main
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"shared/toolkit"
+
+	openaiEmbedding "github.com/cloudwego/eino-ext/components/embedding/openai"
 	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/flow/agent/react"
@@ -28,59 +40,154 @@ type TodoItem struct {
 	ID          string    `json:"id"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
-	Status      string    `json:"status"` // "pending", "in_progress", "completed"
+	Status      string    `json:"status"` // "pending", "in_progress", "completed", "failed", "cancelled"
 	CreatedAt   time.Time `json:"created_at"`
-	CompletedAt time.Time `json:"completed_at,omitempty"`
+	StartedAt   time.Time `json:"started_at,omitempty"`   // StartedAt: 最近一次进入 in_progress 的时间，用于显示用时
+	CompletedAt time.Time `json:"completed_at,omitempty"` // CompletedAt: 完成或失败的时间
 	Result      string    `json:"result,omitempty"`
+	DependsOn   []string  `json:"depends_on,omitempty"` // DependsOn: 必须先完成的任务 ID
+	Priority    string    `json:"priority,omitempty"`   // Priority: "high"、"medium"、"low"，为空时按 medium 处理
+	Due         time.Time `json:"due,omitempty"`        // Due: 截止时间，零值表示没有截止时间
+	ParentID    string    `json:"parent_id,omitempty"`  // ParentID: 父任务 ID，为空表示顶层任务
+	Error       string    `json:"error,omitempty"`      // Error: 任务失败（status 为 failed）的原因
+
+	EstimateHours float64 `json:"estimate_hours,omitempty"` // EstimateHours: 估计工时（小时），0 表示没有估计
+	ToolCalls     int     `json:"tool_calls,omitempty"`     // ToolCalls: 执行阶段 worker 调用工具的次数
+	Tokens        int     `json:"tokens,omitempty"`         // Tokens: 执行阶段 worker 消耗的 Token 数（模型返回用量时）
 }
 
 type TodoList struct {
-	Items []TodoItem `json:"items"`
+	Items     []TodoItem     `json:"items"`
+	NextID    int            `json:"next_id"`             // NextID: 下一个任务 ID 的序号，只增不减，删除任务后也不会重复使用 ID
+	Goal      string         `json:"goal,omitempty"`      // Goal: 规划时的整体目标，重新规划时交给规划链
+	Revisions []PlanRevision `json:"revisions,omitempty"` // Revisions: 计划的修订历史，按版本号递增
+
+	OutcomeStoredAt time.Time `json:"outcome_stored_at,omitempty"` // OutcomeStoredAt: 计划结果最近一次写入长期记忆的时间
+}
+
+// ensureNextID: 旧版本保存的清单没有 next_id，从已有 ID 的最大序号之后开始
+// 加载时就要补上：否则先删除序号最大的任务再添加，新任务会重新用到它的 ID
+func (l *TodoList) ensureNextID() {
+	if l.NextID > 0 {
+		return
+	}
+	l.NextID = 1
+	for _, item := range l.Items {
+		var n int
+		if _, err := fmt.Sscanf(item.ID, "todo-%d", &n); err == nil && n >= l.NextID {
+			l.NextID = n + 1
+		}
+	}
+}
+
+// newID: 分配新的任务 ID，万一生成的 ID 已被占用（如手工编辑过文件）则继续递增
+func (l *TodoList) newID() string {
+	l.ensureNextID()
+	for {
+		id := fmt.Sprintf("todo-%d", l.NextID)
+		l.NextID++
+		if l.index(id) < 0 {
+			return id
+		}
+	}
 }
 
 // --- Todo 管理工具 ---
 
 // TodoManagerTool: Todo List 管理工具
 type TodoManagerTool struct {
-	todos *TodoList
+	mu      sync.Mutex // mu: 同一步中的多个工具调用可能并行执行，修改清单和保存都在锁内完成
+	todos   *TodoList
+	storage Storage          // storage: 为空时只保存在内存中
+	now     func() time.Time // now: 当前时间，用于创建/完成时间和逾期判断，测试时可替换
+
+	exportDir string // exportDir: export 操作同时写入文件的目录，为空时只返回导出的内容
+
+	subscribers []*Subscription // subscribers: 进度事件的订阅者
+	pending     []TodoEvent     // pending: 本次修改产生、等保存成功后发布的事件
 }
 
-func NewTodoManagerTool() *TodoManagerTool {
-	return &TodoManagerTool{
+// NewTodoManagerTool: 从 storage 加载已有任务（storage 为空时从空清单开始，不持久化）
+// 已保存的内容损坏时打印警告并从空清单开始，原内容已由 storage 备份
+func NewTodoManagerTool(ctx context.Context, storage Storage) (*TodoManagerTool, error) {
+	t := &TodoManagerTool{
 		todos: &TodoList{
 			Items: make([]TodoItem, 0),
 		},
+		storage: storage,
+		now:     time.Now,
 	}
+	if storage == nil {
+		return t, nil
+	}
+	todos, err := storage.Load(ctx)
+	var corrupt *CorruptError
+	switch {
+	case errors.As(err, &corrupt):
+		fmt.Printf("⚠ %v\n", err)
+	case err != nil:
+		return nil, fmt.Errorf("加载 Todo List 失败: %w", err)
+	}
+	t.todos = todos
+	return t, nil
+}
+
+// save: 每次修改后整体保存清单，保存成功后发布这次修改的事件；调用方须持有 t.mu
+func (t *TodoManagerTool) save(ctx context.Context) error {
+	if t.storage != nil {
+		if err := t.storage.Save(ctx, t.todos); err != nil {
+			t.pending = nil
+			return fmt.Errorf("保存 Todo List 失败: %w", err)
+		}
+	}
+	t.publish()
+	return nil
 }
 
 func (t *TodoManagerTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 	return &schema.ToolInfo{
 		Name: "todo_manager",
-		Desc: "管理 Todo List：添加任务、更新状态、查看列表",
+		Desc: "管理 Todo List：添加任务、更新状态、依赖、优先级和截止时间、查看（筛选）列表、查看下一步可以执行的任务",
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"action": {
 				Type:     schema.String,
-				Desc:     "操作类型：'add'（添加任务）、'update'（更新状态）、'list'（查看列表）、'complete'（完成任务）",
+				Desc:     "add / add_subtask 添加任务，update / complete / fail 修改状态，delete / move 调整清单，list / search / get 查看任务，next 按依赖给出可以开始的任务，summary 执行摘要，export 导出",
+				Enum:     []string{"add", "add_subtask", "update", "complete", "fail", "delete", "move", "list", "search", "get", "next", "summary", "export"},
 				Required: true,
 			},
 			"id": {
 				Type:     schema.String,
-				Desc:     "任务 ID（用于 update 和 complete 操作）",
+				Desc:     "任务 ID（用于 update、complete、delete 和 move 操作）",
+				Required: false,
+			},
+			"parent_id": {
+				Type:     schema.String,
+				Desc:     "父任务 ID（用于 add_subtask 操作）",
+				Required: false,
+			},
+			"force": {
+				Type:     schema.Boolean,
+				Desc:     "确认删除有子任务的任务（用于 delete 操作），子任务会一并删除",
+				Required: false,
+			},
+			"position": {
+				Type:     schema.Integer,
+				Desc:     "任务在清单中的新位置，从 1 开始（用于 move 操作）；同状态、同优先级、同截止时间的任务按此顺序列出，next 也按此顺序给出没有依赖约束的任务",
 				Required: false,
 			},
 			"title": {
 				Type:     schema.String,
-				Desc:     "任务标题（用于 add 操作）",
+				Desc:     "任务标题（用于 add 和 add_subtask 操作）",
 				Required: false,
 			},
 			"description": {
 				Type:     schema.String,
-				Desc:     "任务描述（用于 add 操作）",
+				Desc:     "任务描述（用于 add 和 add_subtask 操作）",
 				Required: false,
 			},
 			"status": {
 				Type:     schema.String,
-				Desc:     "任务状态：'pending'、'in_progress'、'completed'（用于 update 操作）",
+				Desc:     "任务状态：'pending'、'in_progress'、'completed'、'failed'（用于 update 操作，search 时按状态筛选，还可以是 'cancelled'）。状态按 pending → in_progress → completed 推进，进行中的任务可以失败或退回待处理，失败的任务可以重试，已完成的任务不能再修改状态",
 				Required: false,
 			},
 			"result": {
@@ -88,161 +195,651 @@ func (t *TodoManagerTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 				Desc:     "任务执行结果（用于 complete 操作）",
 				Required: false,
 			},
+			"error": {
+				Type:     schema.String,
+				Desc:     "失败原因（用于 fail 操作）",
+				Required: false,
+			},
+			"depends_on": {
+				Type:     schema.Array,
+				ElemInfo: &schema.ParameterInfo{Type: schema.String},
+				Desc:     "必须先完成的任务 ID 列表（用于 add 和 update 操作，update 时传空列表表示清除依赖）",
+				Required: false,
+			},
+			"priority": {
+				Type:     schema.String,
+				Desc:     "任务优先级：'high'、'medium'、'low'（用于 add 和 update 操作，默认 medium；search 时按优先级筛选）",
+				Enum:     []string{"high", "medium", "low"},
+				Required: false,
+			},
+			"due": {
+				Type:     schema.String,
+				Desc:     "截止时间，如 '2024-12-31'、'2024-12-31 18:00' 或 RFC3339（用于 add 和 update 操作，update 时传空字符串表示清除）",
+				Required: false,
+			},
+			"estimate": {
+				Type:     schema.String,
+				Desc:     "工作量估计（用于 add 和 update 操作）：'S'（约 1 小时）、'M'（约半天）、'L'（约一天），或时长如 '3h'、'90m'、'2天'",
+				Required: false,
+			},
+			"filter": {
+				Type:     schema.String,
+				Desc:     "list 操作的筛选条件，逗号分隔，如 'status=pending'、'overdue=true'、'priority=high,status=in_progress'",
+				Required: false,
+			},
+			"query": {
+				Type:     schema.String,
+				Desc:     "search 操作的关键词，在任务标题和描述中查找",
+				Required: false,
+			},
+			"limit": {
+				Type:     schema.Integer,
+				Desc:     "list 操作最多列出的任务数，其余的只给出数量；清单很长时用它节省上下文",
+				Required: false,
+			},
+			"format": {
+				Type:     schema.String,
+				Desc:     "导出格式（用于 export 操作）：'markdown'（默认，复选框列表，可以直接粘贴到 issue 中）或 'json'（完整清单，包括修订历史）",
+				Enum:     []string{"markdown", "json"},
+				Required: false,
+			},
 		}),
 	}, nil
 }
 
-func (t *TodoManagerTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
-	var args struct {
-		Action      string `json:"action"`
-		ID          string `json:"id,omitempty"`
-		Title       string `json:"title,omitempty"`
-		Description string `json:"description,omitempty"`
-		Status      string `json:"status,omitempty"`
-		Result      string `json:"result,omitempty"`
+// todoArgs: todo_manager 的参数；priority、due、estimate 由 InvokableRun 统一校验后交给各操作
+type todoArgs struct {
+	Action      string    `json:"action"`
+	ID          string    `json:"id,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Result      string    `json:"result,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DependsOn   *[]string `json:"depends_on,omitempty"` // DependsOn: 为空指针表示未传，update 时保留原依赖
+	Priority    string    `json:"priority,omitempty"`
+	Due         *string   `json:"due,omitempty"` // Due: 为空指针表示未传，update 时空字符串表示清除
+	Filter      string    `json:"filter,omitempty"`
+	Format      string    `json:"format,omitempty"`
+	Position    int       `json:"position,omitempty"`
+	Query       string    `json:"query,omitempty"`
+	Limit       int       `json:"limit,omitempty"`
+	ParentID    string    `json:"parent_id,omitempty"`
+	Force       bool      `json:"force,omitempty"`
+	Estimate    string    `json:"estimate,omitempty"`
+
+	priority string    // priority: 校验后的优先级
+	due      time.Time // due: 解析后的截止时间，未传或传空字符串时为零值
+	estimate float64   // estimate: 解析后的工时，未传时为 0
+}
+
+// parse: 校验优先级、截止时间和工作量估计
+func (a *todoArgs) parse(loc *time.Location) error {
+	var err error
+	if a.priority, err = parsePriority(a.Priority); err != nil {
+		return err
+	}
+	if a.Due != nil && strings.TrimSpace(*a.Due) != "" {
+		if a.due, err = parseDue(*a.Due, loc); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(a.Estimate) != "" {
+		if a.estimate, err = parseEstimate(a.Estimate); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
+func (t *TodoManagerTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args todoArgs
 	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
 		return "", fmt.Errorf("无效的参数: %w", err)
 	}
 
 	fmt.Printf("\n--- 🛠️ 工具调用：todo_manager，操作：'%s' ---\n", args.Action)
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := args.parse(t.now().Location()); err != nil {
+		return "", err
+	}
+
 	switch args.Action {
 	case "add":
-		id := fmt.Sprintf("todo-%d", len(t.todos.Items)+1)
-		todo := TodoItem{
-			ID:          id,
-			Title:       args.Title,
-			Description: args.Description,
-			Status:      "pending",
-			CreatedAt:   time.Now(),
-		}
-		t.todos.Items = append(t.todos.Items, todo)
-		fmt.Printf("✅ 已添加任务: %s - %s\n", id, args.Title)
-		return fmt.Sprintf("任务已添加: ID=%s, 标题=%s", id, args.Title), nil
-
+		return t.addTask(ctx, &args, "")
+	case "add_subtask":
+		if t.todos.index(args.ParentID) < 0 {
+			return "", fmt.Errorf("未找到父任务: %s", args.ParentID)
+		}
+		return t.addTask(ctx, &args, args.ParentID)
 	case "update":
-		for i := range t.todos.Items {
-			if t.todos.Items[i].ID == args.ID {
-				if args.Status != "" {
-					t.todos.Items[i].Status = args.Status
-				}
-				fmt.Printf("✅ 已更新任务: %s, 状态=%s\n", args.ID, args.Status)
-				return fmt.Sprintf("任务已更新: ID=%s, 状态=%s", args.ID, args.Status), nil
-			}
+		return t.updateTask(ctx, &args)
+	case "complete":
+		return t.completeTask(ctx, &args)
+	case "fail":
+		return t.failTask(ctx, &args)
+	case "list":
+		filter, err := parseTodoFilter(args.Filter)
+		if err != nil {
+			return "", err
 		}
-		return "", fmt.Errorf("未找到任务: %s", args.ID)
+		return t.renderTodoList(filter, args.Limit), nil
+	case "search":
+		return t.searchTodos(args.Query, args.Status, args.priority)
+	case "get":
+		return t.renderTask(args.ID)
+	case "export":
+		return t.exportTodoList(args.Format)
+	case "summary":
+		return t.renderSummary(), nil
+	case "next":
+		return t.renderNext(), nil
+	case "delete":
+		return t.deleteTask(ctx, &args)
+	case "move":
+		return t.moveTask(ctx, &args)
+	default:
+		return "", fmt.Errorf("未知操作: %s", args.Action)
+	}
+}
 
-	case "complete":
-		for i := range t.todos.Items {
-			if t.todos.Items[i].ID == args.ID {
-				t.todos.Items[i].Status = "completed"
-				t.todos.Items[i].CompletedAt = time.Now()
-				if args.Result != "" {
-					t.todos.Items[i].Result = args.Result
-				}
-				fmt.Printf("✅ 已完成任务: %s\n", args.ID)
-				return fmt.Sprintf("任务已完成: ID=%s, 结果=%s", args.ID, args.Result), nil
-			}
+// addTask: add 和 add_subtask 操作，parentID 为空时添加顶层任务；调用方须持有 t.mu
+func (t *TodoManagerTool) addTask(ctx context.Context, args *todoArgs, parentID string) (string, error) {
+	id := t.todos.newID()
+	var dependsOn []string
+	if args.DependsOn != nil {
+		deps, err := t.todos.checkDependsOn(id, parentID, *args.DependsOn)
+		if err != nil {
+			return "", err
 		}
+		dependsOn = deps
+	}
+	todo := TodoItem{
+		ID:          id,
+		Title:       args.Title,
+		Description: args.Description,
+		Status:      "pending",
+		CreatedAt:   t.now(),
+		DependsOn:   dependsOn,
+		Priority:    args.priority,
+		Due:         args.due,
+		ParentID:    parentID,
+
+		EstimateHours: args.estimate,
+	}
+	t.todos.Items = append(t.todos.Items, todo)
+	t.record(EventAdded, todo)
+	t.rollUp(parentID)
+	if err := t.save(ctx); err != nil {
+		return "", err
+	}
+	if parentID != "" {
+		fmt.Printf("✅ 已添加子任务: %s - %s（父任务 %s）\n", id, args.Title, parentID)
+		return fmt.Sprintf("子任务已添加: ID=%s, 标题=%s, 父任务=%s", id, args.Title, parentID), nil
+	}
+	fmt.Printf("✅ 已添加任务: %s - %s\n", id, args.Title)
+	return fmt.Sprintf("任务已添加: ID=%s, 标题=%s", id, args.Title), nil
+}
+
+// updateTask: update 操作，修改状态、依赖、优先级、截止时间和估计；调用方须持有 t.mu
+func (t *TodoManagerTool) updateTask(ctx context.Context, args *todoArgs) (string, error) {
+	i := t.todos.index(args.ID)
+	if i < 0 {
 		return "", fmt.Errorf("未找到任务: %s", args.ID)
+	}
+	if args.Status != "" && t.todos.hasChildren(args.ID) {
+		return "", fmt.Errorf("任务 %s 有子任务，状态由子任务推导，请更新子任务的状态", args.ID)
+	}
+	status := ""
+	if args.Status != "" {
+		var err error
+		if status, err = parseStatus(args.Status); err != nil {
+			return "", err
+		}
+		if msg := checkTransition(t.todos.Items[i], status); msg != "" {
+			return msg, nil
+		}
+	}
+	var dependsOn []string
+	if args.DependsOn != nil {
+		deps, err := t.todos.checkDependsOn(args.ID, t.todos.Items[i].ParentID, *args.DependsOn)
+		if err != nil {
+			return "", err
+		}
+		dependsOn = deps
+	}
+	if status == "completed" {
+		item := t.todos.Items[i]
+		if args.DependsOn != nil {
+			item.DependsOn = dependsOn
+		}
+		if blocked := t.todos.blockers(item); len(blocked) > 0 {
+			return blockedMessage(args.ID, blocked), nil
+		}
+	}
 
-	case "list":
-		return t.renderTodoList(), nil
+	item := &t.todos.Items[i]
+	if args.DependsOn != nil {
+		item.DependsOn = dependsOn
+	}
+	if status != "" {
+		enterStatus(item, status, t.now())
+	}
+	if args.priority != "" {
+		item.Priority = args.priority
+	}
+	if args.Due != nil {
+		item.Due = args.due
+	}
+	if args.estimate > 0 {
+		item.EstimateHours = args.estimate
+	}
+	if status == "completed" {
+		t.record(EventCompleted, *item)
+	} else {
+		t.record(EventUpdated, *item)
+	}
+	t.rollUp(item.ParentID)
+	if err := t.save(ctx); err != nil {
+		return "", err
+	}
+	updated := t.todos.Items[i]
+	fmt.Printf("✅ 已更新任务: %s, 状态=%s, 依赖=%v\n", args.ID, updated.Status, updated.DependsOn)
+	return fmt.Sprintf("任务已更新: ID=%s, 状态=%s, 依赖=%v", args.ID, updated.Status, updated.DependsOn), nil
+}
 
-	default:
-		return "", fmt.Errorf("未知操作: %s", args.Action)
+// completeTask: complete 操作，依赖或子任务未完成时拒绝并说明原因；调用方须持有 t.mu
+func (t *TodoManagerTool) completeTask(ctx context.Context, args *todoArgs) (string, error) {
+	i := t.todos.index(args.ID)
+	if i < 0 {
+		return "", fmt.Errorf("未找到任务: %s", args.ID)
+	}
+	if children := t.todos.children(args.ID); len(children) > 0 && derivedStatus(children) != "completed" {
+		return unfinishedChildrenMessage(args.ID, children), nil
+	}
+	if msg := checkTransition(t.todos.Items[i], "completed"); msg != "" {
+		return msg, nil
 	}
+	if blocked := t.todos.blockers(t.todos.Items[i]); len(blocked) > 0 {
+		return blockedMessage(args.ID, blocked), nil
+	}
+	enterStatus(&t.todos.Items[i], "completed", t.now())
+	if args.Result != "" {
+		t.todos.Items[i].Result = args.Result
+	}
+	t.record(EventCompleted, t.todos.Items[i])
+	t.rollUp(t.todos.Items[i].ParentID)
+	if err := t.save(ctx); err != nil {
+		return "", err
+	}
+	fmt.Printf("✅ 已完成任务: %s\n", args.ID)
+	return fmt.Sprintf("任务已完成: ID=%s, 结果=%s", args.ID, args.Result), nil
+}
+
+// failTask: fail 操作，把进行中的任务标记为失败并记录原因；调用方须持有 t.mu
+func (t *TodoManagerTool) failTask(ctx context.Context, args *todoArgs) (string, error) {
+	i := t.todos.index(args.ID)
+	if i < 0 {
+		return "", fmt.Errorf("未找到任务: %s", args.ID)
+	}
+	if t.todos.hasChildren(args.ID) {
+		return "", fmt.Errorf("任务 %s 有子任务，状态由子任务推导，请把失败的子任务标记为 failed", args.ID)
+	}
+	if msg := checkTransition(t.todos.Items[i], "failed"); msg != "" {
+		return msg, nil
+	}
+	enterStatus(&t.todos.Items[i], "failed", t.now())
+	t.todos.Items[i].Error = args.Error
+	t.record(EventUpdated, t.todos.Items[i])
+	t.rollUp(t.todos.Items[i].ParentID)
+	if err := t.save(ctx); err != nil {
+		return "", err
+	}
+	fmt.Printf("❌ 任务失败: %s, 原因=%s\n", args.ID, args.Error)
+	return fmt.Sprintf("任务已标记为失败: ID=%s, 原因=%s", args.ID, args.Error), nil
+}
+
+// deleteTask: delete 操作，有子任务时需要 force，并从其他任务的依赖中移除被删除的任务；调用方须持有 t.mu
+func (t *TodoManagerTool) deleteTask(ctx context.Context, args *todoArgs) (string, error) {
+	i := t.todos.index(args.ID)
+	if i < 0 {
+		return "", fmt.Errorf("未找到任务: %s", args.ID)
+	}
+	deleted := t.todos.Items[i]
+	descendants := t.todos.descendants(deleted.ID)
+	if len(descendants) > 0 && !args.Force {
+		return "", fmt.Errorf("任务 %s 有 %d 个子任务（%s），确认一并删除请传 force=true",
+			deleted.ID, len(descendants), strings.Join(descendants, "、"))
+	}
+	removed := append([]string{deleted.ID}, descendants...)
+	for _, id := range removed {
+		t.record(EventDeleted, t.todos.Items[t.todos.index(id)])
+	}
+	t.todos.Items = slices.DeleteFunc(t.todos.Items, func(item TodoItem) bool { return slices.Contains(removed, item.ID) })
+	// 其他任务不再等待被删除的任务
+	var dependents []string
+	for j := range t.todos.Items {
+		item := &t.todos.Items[j]
+		n := len(item.DependsOn)
+		item.DependsOn = slices.DeleteFunc(item.DependsOn, func(dep string) bool { return slices.Contains(removed, dep) })
+		if len(item.DependsOn) < n {
+			dependents = append(dependents, item.ID)
+			t.record(EventUpdated, *item)
+		}
+	}
+	t.rollUp(deleted.ParentID)
+	if err := t.save(ctx); err != nil {
+		return "", err
+	}
+	fmt.Printf("🗑️ 已删除任务: %s - %s\n", deleted.ID, deleted.Title)
+	msg := fmt.Sprintf("任务已删除: ID=%s, 标题=%s", deleted.ID, deleted.Title)
+	if len(descendants) > 0 {
+		msg += fmt.Sprintf("；子任务 %s 已一并删除", strings.Join(descendants, "、"))
+	}
+	if len(dependents) > 0 {
+		msg += fmt.Sprintf("；已从 %s 的依赖中移除", strings.Join(dependents, "、"))
+	}
+	return msg, nil
+}
+
+// moveTask: move 操作，把任务移到清单中的第 position 位；调用方须持有 t.mu
+func (t *TodoManagerTool) moveTask(ctx context.Context, args *todoArgs) (string, error) {
+	i := t.todos.index(args.ID)
+	if i < 0 {
+		return "", fmt.Errorf("未找到任务: %s", args.ID)
+	}
+	if args.Position < 1 || args.Position > len(t.todos.Items) {
+		return "", fmt.Errorf("位置超出范围: %d（应在 1~%d 之间）", args.Position, len(t.todos.Items))
+	}
+	item := t.todos.Items[i]
+	t.todos.Items = slices.Insert(slices.Delete(t.todos.Items, i, i+1), args.Position-1, item)
+	t.record(EventUpdated, item)
+	if err := t.save(ctx); err != nil {
+		return "", err
+	}
+	order := make([]string, len(t.todos.Items))
+	for j, item := range t.todos.Items {
+		order[j] = item.ID
+	}
+	fmt.Printf("↕️ 已移动任务: %s -> 第 %d 位\n", args.ID, args.Position)
+	return fmt.Sprintf("任务已移动: ID=%s, 位置=%d, 当前顺序=%s", args.ID, args.Position, strings.Join(order, ", ")), nil
 }
 
-// renderTodoList: 渲染 Todo List（类似 Cursor 的展示格式）
-func (t *TodoManagerTool) renderTodoList() string {
+// blockedMessage: 依赖未完成时拒绝完成任务的说明，作为工具结果交给 Agent，由它先去完成阻塞的任务
+func blockedMessage(id string, blocked []TodoItem) string {
+	fmt.Printf("🚫 任务 %s 被阻塞: %s\n", id, formatTasks(blocked))
+	return fmt.Sprintf("无法完成任务 %s：依赖的任务尚未完成：%s。请先完成这些任务，或用 update 修改 depends_on", id, formatTasks(blocked))
+}
+
+// renderTodoList: 渲染 Todo List（类似 Cursor 的展示格式），子任务缩进显示在父任务下；同一层级内按状态、优先级、截止时间排序
+// 只列出满足 filter 的任务（以及为了显示层级所需的祖先任务），统计始终针对整个清单
+// limit 大于 0 时最多列出 limit 个任务，其余的只给出数量，避免大清单占满 Agent 的上下文
+func (t *TodoManagerTool) renderTodoList(filter todoFilter, limit int) string {
 	if len(t.todos.Items) == 0 {
 		return "📋 Todo List 为空"
 	}
 
+	now := t.now()
+	// visible: 满足筛选条件的任务及其祖先
+	visible := map[string]bool{}
+	for _, item := range t.todos.Items {
+		if filter.match(item, now) {
+			visible[item.ID] = true
+			for _, ancestor := range t.todos.ancestors(item) {
+				visible[ancestor.ID] = true
+			}
+		}
+	}
+
 	var sb strings.Builder
 	sb.WriteString("\n")
 	sb.WriteString("╔════════════════════════════════════════════════════════════╗\n")
 	sb.WriteString("║                    📋 TODO LIST                            ║\n")
+	if n := len(t.todos.Revisions); n > 0 {
+		latest := t.todos.Revisions[n-1]
+		reason := latest.Reason
+		if reason == "" {
+			reason = latest.Trigger
+		}
+		sb.WriteString(fmt.Sprintf("║ 🔁 计划第 %d 版（%s）：%s\n", latest.Number, latest.At.Format("2006-01-02 15:04"), reason))
+	}
 	sb.WriteString("╠════════════════════════════════════════════════════════════╣\n")
+	if len(visible) == 0 {
+		sb.WriteString("║ （没有符合筛选条件的任务）\n")
+	}
+	st := &renderState{visible: visible, now: now, limit: limit}
+	t.renderItems(&sb, t.todos.roots(), "", st)
+	if hidden := len(visible) - st.shown; hidden > 0 {
+		sb.WriteString(fmt.Sprintf("║ …以及其余 %d 个任务（用 search 查找，或调大 limit）\n", hidden))
+	}
+
+	sb.WriteString("╚════════════════════════════════════════════════════════════╝\n")
+
+	// 统计信息
+	counts := map[string]int{}
+	blocked := 0
+	overdueCount := 0
+	for _, item := range t.todos.Items {
+		if (item.Status == "pending" || item.Status == "in_progress") && len(t.todos.blockers(item)) > 0 {
+			blocked++
+		}
+		if overdue(item, now) {
+			overdueCount++
+		}
+		counts[item.Status]++
+	}
+
+	stats := []string{fmt.Sprintf("总计 %d", len(t.todos.Items))}
+	for _, status := range statusOrder {
+		stats = append(stats, fmt.Sprintf("%s %d", statusLabels[status], counts[status]))
+	}
+	stats = append(stats, fmt.Sprintf("🚫 受阻 %d", blocked), fmt.Sprintf("⏰ 逾期 %d", overdueCount))
+	sb.WriteString("\n📊 统计: " + strings.Join(stats, " | ") + "\n")
+	sb.WriteString(computeEffort(t.todos).progressLine() + "\n")
+	if !filter.empty() {
+		shown := 0
+		for _, item := range t.todos.Items {
+			if filter.match(item, now) {
+				shown++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("🔍 符合筛选条件的任务 %d 个\n", shown))
+	}
+
+	return sb.String()
+}
 
-	for i, item := range t.todos.Items {
-		// 状态图标
+// renderState: 一次列表渲染的状态
+type renderState struct {
+	visible map[string]bool // visible: 要显示的任务（满足筛选条件的任务及其祖先）
+	now     time.Time
+	limit   int // limit: 最多显示的任务数，0 表示不限
+	shown   int // shown: 已显示的任务数
+}
+
+// full: 是否已经显示到上限
+func (st *renderState) full() bool {
+	return st.limit > 0 && st.shown >= st.limit
+}
+
+// renderItems: 渲染同一层级的任务并递归渲染其子任务；number 为父任务的编号前缀（如 "2."），子任务编号为 2.1、2.2
+func (t *TodoManagerTool) renderItems(sb *strings.Builder, siblings []TodoItem, number string, st *renderState) {
+	var items []TodoItem
+	for _, item := range sortTodos(siblings) {
+		if st.visible[item.ID] {
+			items = append(items, item)
+		}
+	}
+	depth := strings.Count(number, ".")
+	indent := strings.Repeat("   ", depth)
+	now := st.now
+	for i, item := range items {
+		if st.full() {
+			return
+		}
+		st.shown++
+		// 状态图标：未完成且有未完成依赖的任务显示为受阻
 		var statusIcon string
-		switch item.Status {
-		case "completed":
+		blocked := t.todos.blockers(item)
+		switch {
+		case item.Status == "completed":
 			statusIcon = "✅"
-		case "in_progress":
+			blocked = nil
+		case item.Status == "failed":
+			statusIcon = "❌"
+			blocked = nil
+		case item.Status == "cancelled":
+			statusIcon = "⛔"
+			blocked = nil
+		case len(blocked) > 0:
+			statusIcon = "🚫"
+		case item.Status == "in_progress":
 			statusIcon = "🔄"
 		default:
 			statusIcon = "⏳"
 		}
 
-		// 任务行
-		sb.WriteString(fmt.Sprintf("║ %d. %s [%s] %s\n", i+1, statusIcon, item.ID, item.Title))
+		// 任务行：子任务进度、优先级、截止时间，逾期时加 ⏰
+		itemNumber := fmt.Sprintf("%s%d.", number, i+1)
+		line := fmt.Sprintf("║ %s%s %s [%s] %s", indent, itemNumber, statusIcon, item.ID, item.Title)
+		if done, total := t.todos.progress(item.ID); total > 0 {
+			line += fmt.Sprintf(" [%d/%d]", done, total)
+		}
+		if icon, ok := priorityIcons[item.Priority]; ok {
+			line += " " + icon
+		}
+		if item.EstimateHours > 0 {
+			line += " ⌛ " + formatHours(item.EstimateHours)
+		}
+		if !item.Due.IsZero() {
+			line += " 📅 " + formatDue(item.Due)
+		}
+		if overdue(item, now) {
+			line += " ⏰ 已逾期"
+		}
+		if d, ok := taskDuration(item, now); ok {
+			if item.Status != "in_progress" {
+				line += " ⏱️ 用时 " + formatDuration(d)
+			} else {
+				line += " ⏱️ 已进行 " + formatDuration(d)
+			}
+		}
+		sb.WriteString(line + "\n")
 		if item.Description != "" {
-			sb.WriteString(fmt.Sprintf("║    └─ %s\n", item.Description))
+			sb.WriteString(fmt.Sprintf("║ %s   └─ %s\n", indent, item.Description))
+		}
+		if len(blocked) > 0 {
+			sb.WriteString(fmt.Sprintf("║ %s   └─ 等待: %s\n", indent, formatTasks(blocked)))
 		}
 		if item.Status == "completed" && item.Result != "" {
-			sb.WriteString(fmt.Sprintf("║    └─ 结果: %s\n", item.Result))
+			sb.WriteString(fmt.Sprintf("║ %s   └─ 结果: %s\n", indent, item.Result))
 		}
-		if i < len(t.todos.Items)-1 {
-			sb.WriteString("║\n")
+		if item.Status == "failed" && item.Error != "" {
+			sb.WriteString(fmt.Sprintf("║ %s   └─ 失败: %s\n", indent, item.Error))
 		}
-	}
-
-	sb.WriteString("╚════════════════════════════════════════════════════════════╝\n")
-
-	// 统计信息
-	completed := 0
-	inProgress := 0
-	pending := 0
-	for _, item := range t.todos.Items {
-		switch item.Status {
-		case "completed":
-			completed++
-		case "in_progress":
-			inProgress++
-		default:
-			pending++
+		t.renderItems(sb, t.todos.children(item.ID), itemNumber, st)
+		if depth == 0 && i < len(items)-1 && !st.full() {
+			sb.WriteString("║\n")
 		}
 	}
-
-	sb.WriteString(fmt.Sprintf("\n📊 统计: 总计 %d | ✅ 已完成 %d | 🔄 进行中 %d | ⏳ 待处理 %d\n",
-		len(t.todos.Items), completed, inProgress, pending))
-
-	return sb.String()
 }
 
 // --- 规划工具 ---
 
-// PlannerTool: 规划工具，根据目标生成 Todo List
+// PlannerTool: 规划工具，根据目标生成 Todo List，执行中可以重新规划（修订清单）
 type PlannerTool struct {
 	todoManager *TodoManagerTool
+	replan      compose.Runnable[map[string]any, string] // replan: 重新规划链，为空时不支持 replan
+	templates   *TemplateRegistry                        // templates: 计划模板，为空时不支持 plan_from_template
 }
 
-func NewPlannerTool(todoManager *TodoManagerTool) *PlannerTool {
-	return &PlannerTool{
+// NewPlannerTool: llm 用于重新规划，为空时只能生成初始计划
+func NewPlannerTool(ctx context.Context, todoManager *TodoManagerTool, llm model.BaseChatModel) (*PlannerTool, error) {
+	p := &PlannerTool{
 		todoManager: todoManager,
 	}
+	if llm == nil {
+		return p, nil
+	}
+	replan, err := buildReplanChain(ctx, llm)
+	if err != nil {
+		return nil, fmt.Errorf("构建重新规划链失败: %w", err)
+	}
+	p.replan = replan
+	return p, nil
 }
 
 func (p *PlannerTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	desc := "根据用户目标规划并生成 Todo List：输入目标描述，自动分解为可执行的任务列表。" +
+		"发现计划有问题（如任务失败、遗漏步骤）时用 replan 修订清单：新增、修改或取消任务"
+	if p.templates != nil {
+		desc += "。常见的工作可以用 plan_from_template 从模板生成清单，可用的模板：" + p.templates.describe()
+	}
 	return &schema.ToolInfo{
 		Name: "planner",
-		Desc: "根据用户目标规划并生成 Todo List。输入目标描述，自动分解为可执行的任务列表",
+		Desc: desc,
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"action": {
+				Type:     schema.String,
+				Desc:     "操作类型：'plan'（默认，生成初始计划）、'plan_from_template'（从模板生成计划）或 'replan'（根据当前进度修订计划）",
+				Enum:     []string{"plan", "plan_from_template", "replan"},
+				Required: false,
+			},
+			"template": {
+				Type:     schema.String,
+				Desc:     "模板名称（用于 plan_from_template 操作）",
+				Required: false,
+			},
+			"variables": {
+				Type:     schema.Object,
+				Desc:     "模板变量，键为占位符名称、值为替换的文字，例如 {\"feature_name\": \"用户登录\"}（用于 plan_from_template 操作，必须提供模板用到的所有变量）",
+				Required: false,
+			},
 			"goal": {
 				Type:     schema.String,
-				Desc:     "用户的目标描述，例如：'开发一个待办事项应用'、'分析公司财报'",
-				Required: true,
+				Desc:     "用户的目标描述，例如：'开发一个待办事项应用'、'分析公司财报'（用于 plan 操作）",
+				Required: false,
 			},
 			"tasks": {
+				Type: schema.Array,
+				Desc: "任务列表（用于 plan 操作），按执行的先后排列",
+				ElemInfo: &schema.ParameterInfo{
+					Type: schema.Object,
+					SubParams: map[string]*schema.ParameterInfo{
+						"title": {
+							Type:     schema.String,
+							Desc:     "任务标题",
+							Required: true,
+						},
+						"description": {
+							Type: schema.String,
+							Desc: "任务描述",
+						},
+						"priority": {
+							Type: schema.String,
+							Desc: "优先级",
+							Enum: []string{"high", "medium", "low"},
+						},
+						"estimate": {
+							Type: schema.String,
+							Desc: "工作量估计：'S'（约 1 小时）、'M'（约半天）、'L'（约一天），或时长如 '3h'、'2天'",
+						},
+						"depends_on": {
+							Type:     schema.Array,
+							Desc:     "必须先完成的任务：已有任务的 ID（如 todo-1），或用 new-N 引用本次列表中的第 N 个任务",
+							ElemInfo: &schema.ParameterInfo{Type: schema.String},
+						},
+					},
+				},
+				Required: false,
+			},
+			"reason": {
 				Type:     schema.String,
-				Desc:     "JSON 格式的任务列表，例如：'[{\"title\":\"任务1\",\"description\":\"描述1\"},{\"title\":\"任务2\",\"description\":\"描述2\"}]'",
-				Required: true,
+				Desc:     "需要重新规划的原因，例如：'todo-3 失败：缺少接口文档'（用于 replan 操作）",
+				Required: false,
 			},
 		}),
 	}, nil
@@ -250,39 +847,141 @@ func (p *PlannerTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 
 func (p *PlannerTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	var args struct {
-		Goal  string `json:"goal"`
-		Tasks string `json:"tasks"`
+		Action string          `json:"action,omitempty"`
+		Goal   string          `json:"goal"`
+		Tasks  json.RawMessage `json:"tasks"`
+		Reason string          `json:"reason,omitempty"`
+
+		Template  string            `json:"template,omitempty"`
+		Variables map[string]string `json:"variables,omitempty"`
 	}
 
 	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
 		return "", fmt.Errorf("无效的参数: %w", err)
 	}
 
+	switch args.Action {
+	case "replan":
+		return p.Replan(ctx, args.Reason)
+	case "plan_from_template":
+		return p.PlanFromTemplate(ctx, args.Template, args.Variables)
+	}
+
 	fmt.Printf("\n--- 🧠 规划工具：目标='%s' ---\n", args.Goal)
 
-	// 解析任务列表
-	var tasks []struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
+	tasks, err := parsePlannedTasks(args.Tasks)
+	if err != nil {
+		return "", err
 	}
-	if err := json.Unmarshal([]byte(args.Tasks), &tasks); err != nil {
-		return "", fmt.Errorf("无效的任务列表格式: %w", err)
+	return p.addPlan(ctx, args.Goal, tasks)
+}
+
+// PlanFromTemplate: 用变量实例化模板，把得到的任务加入清单；缺少变量时不修改清单
+func (p *PlannerTool) PlanFromTemplate(ctx context.Context, name string, vars map[string]string) (string, error) {
+	if p.templates == nil {
+		return "", errors.New("未配置计划模板")
 	}
+	tpl, err := p.templates.Get(name)
+	if err != nil {
+		return "", err
+	}
+	goal, tasks, err := tpl.Instantiate(vars)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("\n--- 🧩 规划工具：模板=%s，目标='%s' ---\n", tpl.Name, goal)
+	return p.addPlan(ctx, goal, tasks)
+}
 
-	// 添加任务到 Todo List
-	for _, task := range tasks {
-		_, err := p.todoManager.InvokableRun(ctx, fmt.Sprintf(`{"action":"add","title":"%s","description":"%s"}`,
-			task.Title, task.Description))
-		if err != nil {
-			return "", fmt.Errorf("添加任务失败: %w", err)
+// addPlan: 把规划好的任务加入清单并汇报
+func (p *PlannerTool) addPlan(ctx context.Context, goal string, tasks []TaskSpec) (string, error) {
+	added, err := p.todoManager.addPlannedTasks(ctx, goal, tasks)
+	if err != nil {
+		return "", fmt.Errorf("添加任务失败: %w", err)
+	}
+
+	summary := make([]string, len(added))
+	for i, item := range added {
+		fmt.Printf("✅ 已添加任务: %s - %s\n", item.ID, item.Title)
+		summary[i] = fmt.Sprintf("%s %s", item.ID, item.Title)
+	}
+	fmt.Printf("✅ 已规划 %d 个任务\n", len(added))
+	return fmt.Sprintf("规划完成：已生成 %d 个任务：%s", len(added), strings.Join(summary, "、")), nil
+}
+
+// trailingComma: JSON 数组或对象末尾多余的逗号
+var trailingComma = regexp.MustCompile(`,\s*([\]}])`)
+
+// parsePlannedTasks: 解析 plan 的 tasks 参数：正常是对象数组；兼容旧的写法——把数组序列化成字符串传入，
+// 这种字符串常被模型写坏，所以宽松解析：去掉代码块标记和末尾多余的逗号，只有一个任务对象时也接受
+func parsePlannedTasks(raw json.RawMessage) ([]TaskSpec, error) {
+	var tasks []TaskSpec
+	if err := json.Unmarshal(raw, &tasks); err == nil {
+		if len(tasks) == 0 {
+			return nil, errors.New("任务列表为空")
 		}
+		return tasks, nil
 	}
 
-	fmt.Printf("✅ 已规划 %d 个任务\n", len(tasks))
-	return fmt.Sprintf("规划完成：已生成 %d 个任务", len(tasks)), nil
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return nil, fmt.Errorf("无效的任务列表格式: %w", err)
+	}
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	text = trailingComma.ReplaceAllString(strings.TrimSpace(text), "$1")
+	if strings.HasPrefix(text, "{") {
+		text = "[" + text + "]"
+	}
+	if err := json.Unmarshal([]byte(text), &tasks); err != nil {
+		return nil, fmt.Errorf("无效的任务列表格式: %w", err)
+	}
+	if len(tasks) == 0 {
+		return nil, errors.New("任务列表为空")
+	}
+	return tasks, nil
+}
+
+// Replan: 把整体目标、当前清单和原因交给重新规划链，得到的补丁整体应用到清单并记录为新的修订
+func (p *PlannerTool) Replan(ctx context.Context, reason string) (string, error) {
+	if p.replan == nil {
+		return "", errors.New("未配置重新规划链")
+	}
+	fmt.Printf("\n--- 🔁 重新规划：%s ---\n", reason)
+	goal, list := p.todoManager.snapshot()
+	output, err := p.replan.Invoke(ctx, map[string]any{"goal": goal, "todos": list, "reason": reason})
+	if err != nil {
+		return "", fmt.Errorf("重新规划失败: %w", err)
+	}
+	patch, err := parsePatch(output)
+	if err != nil {
+		return "", err
+	}
+	revision, err := p.todoManager.applyPatch(ctx, patch, reason)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("✅ 计划已修订为第 %d 版：%s\n", revision.Number, revision.Summary)
+	return fmt.Sprintf("计划已修订为第 %d 版：%s。说明：%s", revision.Number, revision.Summary, revision.Reason), nil
 }
 
 func main() {
+	todoFile := flag.String("todo-file", "", "把 Todo List 保存到此 JSON 文件，下次运行时继续（为空时只保存在内存中）")
+	todoRedisKey := flag.String("todo-redis", "", "把 Todo List 保存到此 Redis 键（优先于 -todo-file）")
+	sessionID := flag.String("session", "", "会话 ID：Todo List 保存在 Redis 键 session:{id}:todos（与第 8 章的短期记忆相同的命名），用同一个 ID 再次运行时继续上次的计划")
+	useMemory := flag.Bool("memory", false, "计划全部完成时把结果摘要写入第 8 章的长期记忆（Elasticsearch，连接取自 ES_ADDR / ES_USER / ES_PASSWORD / ES_INDEX）")
+	execute := flag.Bool("execute", true, "规划完成后由 worker Agent 按依赖顺序逐个执行任务")
+	taskTimeout := flag.Duration("task-timeout", 2*time.Minute, "执行单个任务的时限，超时的任务标记为失败")
+	workerSteps := flag.Int("worker-steps", 8, "执行单个任务时 worker Agent 的最大步数")
+	outDir := flag.String("out-dir", "", "export 操作同时把导出内容写入此目录（文件名带时间戳），运行结束时也在此导出 Markdown 和 JSON")
+	eventBuffer := flag.Int("event-buffer", 64, "进度事件订阅的缓冲大小，缓冲满时丢弃新事件，不会拖慢 Agent")
+	maxReplans := flag.Int("max-replans", 3, "执行阶段任务失败后最多重新规划的次数，0 表示失败时不重新规划")
+	templatesFile := flag.String("templates", "", "计划模板文件（JSON 或 YAML），其中的模板与内置模板一起提供给 planner，同名时覆盖内置模板")
+	workspaceDir := flag.String("workspace", "workspace", "worker 文件工具的工作区目录（不存在时创建），工具无法访问此目录之外的文件")
+	flag.Parse()
+
 	ctx := context.Background()
 
 	// --- 配置 ---
@@ -314,15 +1013,116 @@ func main() {
 
 	fmt.Printf("✅ 语言模型已初始化: %s\n\n", config.Model)
 
+	// --- Todo List 存储 ---
+	var storage Storage
+	redisKey := *todoRedisKey
+	if redisKey == "" && *sessionID != "" {
+		redisKey = sessionTodosKey(*sessionID)
+	}
+	switch {
+	case redisKey != "":
+		redisAddr := os.Getenv("REDIS_ADDR")
+		if redisAddr == "" {
+			redisAddr = "localhost:6379"
+		}
+		redisStorage, err := NewRedisStorage(ctx, redisAddr, os.Getenv("REDIS_PASSWORD"), 0, redisKey)
+		if err != nil {
+			fmt.Printf("初始化 Redis 存储失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer redisStorage.Close()
+		storage = redisStorage
+		fmt.Printf("💾 Todo List 保存在 Redis 键 %s\n", redisKey)
+	case *todoFile != "":
+		storage = NewFileStorage(*todoFile)
+		fmt.Printf("💾 Todo List 保存在 %s\n", *todoFile)
+	}
+
 	// --- 创建工具 ---
-	todoManager := NewTodoManagerTool()
-	planner := NewPlannerTool(todoManager)
+	todoManager, err := NewTodoManagerTool(ctx, storage)
+	if err != nil {
+		fmt.Printf("初始化 Todo List 失败: %v\n", err)
+		os.Exit(1)
+	}
+	if n := len(todoManager.todos.Items); n > 0 {
+		fmt.Printf("📋 已加载 %d 个任务\n", n)
+	}
+	todoManager.exportDir = *outDir
+
+	// --- 长期记忆：计划全部完成后写入结果摘要，以后的会话（如第 8 章的 Agent）可以检索到 ---
+	var outcomes OutcomeMemory
+	if *useMemory {
+		esAddr := os.Getenv("ES_ADDR")
+		if esAddr == "" {
+			esAddr = "http://localhost:9200"
+		}
+		indexName := os.Getenv("ES_INDEX")
+		if indexName == "" {
+			indexName = "eino_memory" // 与第 8 章相同的索引
+		}
+		embedder, err := openaiEmbedding.NewEmbedder(ctx, &openaiEmbedding.EmbeddingConfig{
+			APIKey:  apiKey,
+			Model:   "Qwen/Qwen3-Embedding-8B",
+			Timeout: 30 * time.Second,
+			BaseURL: baseURL,
+		})
+		if err != nil {
+			fmt.Printf("初始化 Embedding 模型失败: %v\n", err)
+			os.Exit(1)
+		}
+		longTermMemory, err := NewLongTermMemory(ctx, esAddr, os.Getenv("ES_USER"), os.Getenv("ES_PASSWORD"), indexName, embedder)
+		if err != nil {
+			fmt.Printf("初始化长期记忆失败: %v\n", err)
+			os.Exit(1)
+		}
+		outcomes = longTermMemory
+		fmt.Printf("🧠 计划结果将写入长期记忆索引 %s\n", indexName)
+	}
+
+	// --- 进度订阅：演示如何在不轮询 list 的情况下跟踪任务变化（如推送给 Web UI） ---
+	progress := todoManager.Subscribe(*eventBuffer)
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		printProgress(progress, slices.Clone(todoManager.todos.Items))
+	}()
+	planner, err := NewPlannerTool(ctx, todoManager, llm)
+	if err != nil {
+		fmt.Printf("创建规划工具失败: %v\n", err)
+		os.Exit(1)
+	}
+	templates, err := NewTemplateRegistry(*templatesFile)
+	if err != nil {
+		fmt.Printf("加载计划模板失败: %v\n", err)
+		os.Exit(1)
+	}
+	planner.templates = templates
 
 	tools := []tool.BaseTool{
 		todoManager,
 		planner,
 	}
 
+	// --- 执行阶段的 worker：计算器和工作区内的文件工具 ---
+	workspace, err := toolkit.NewWorkspace(*workspaceDir)
+	if err != nil {
+		fmt.Printf("初始化工作区失败: %v\n", err)
+		os.Exit(1)
+	}
+	workerTools := []tool.BaseTool{
+		&toolkit.ExpressionCalculatorTool{},
+		&toolkit.ReadFileTool{Workspace: workspace},
+		&toolkit.WriteFileTool{Workspace: workspace},
+		&toolkit.ListDirTool{Workspace: workspace},
+	}
+	executor, err := NewTaskExecutor(ctx, llm, workerTools, todoManager, *workerSteps, *taskTimeout)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	executor.Replan = planner.Replan
+	executor.MaxReplans = *maxReplans
+
 	// --- 创建 ReAct Agent ---
 	agentConfig := &react.AgentConfig{
 		ToolCallingModel: llm,
@@ -341,20 +1141,37 @@ func main() {
 	// --- 系统提示词：指导 Agent 使用规划模式 ---
 	systemPrompt := `你是一个智能任务规划助手。当用户提出目标时，你需要：
 
-1. 首先使用 planner 工具将目标分解为具体的任务列表
-2. 使用 todo_manager 的 list 操作查看当前任务列表
-3. 逐个执行任务，使用 todo_manager 更新任务状态（in_progress -> completed）
-4. 每完成一个任务，记录执行结果
-5. 定期使用 todo_manager 的 list 操作展示当前进度
+1. 首先使用 planner 工具将目标分解为具体的任务列表（目标属于某个计划模板覆盖的常见工作时，用 plan_from_template 从模板生成），每个任务用 estimate 给出工作量估计（S/M/L 或小时数，如 3h），
+   较大的任务可以用 todo_manager 的 add_subtask 拆成子任务
+2. 任务之间的依赖（如后端开发依赖需求分析）可以在 planner 的 tasks 中直接用 depends_on 设置（new-N 表示同一列表中的第 N 个任务），
+   也可以用 todo_manager 的 list 操作查看当前任务列表后，用 update 的 depends_on 设置
+3. 用 todo_manager 的 next 操作确认执行顺序合理、没有循环依赖；发现计划有问题时用 planner 的 replan 修订
+4. 最后用 list 操作展示规划好的任务列表
 
-请按照这个流程帮助用户完成任务。`
+你只负责规划，不要把任务标记为进行中或完成：规划结束后，每个任务会按依赖顺序交给执行 Agent 真正完成并记录结果。`
 
 	// --- 示例：用户目标 ---
 	userGoals := []string{
 		"帮我规划一个简单的待办事项应用开发任务，包括：需求分析、UI设计、后端开发、测试",
+		"用 web_feature 模板为待办事项应用规划“任务提醒”功能",
+	}
+
+	// 跨会话继续：加载的计划还有没结束的任务时（如第二次用同一个 -session 运行），先让 Agent 汇报这些任务，再继续执行
+	resumedGoal := ""
+	if left := todoManager.outstanding(); len(left) > 0 {
+		resumedGoal = todoManager.todos.Goal
+		fmt.Printf("🔁 继续上次的计划“%s”，还有 %d 个任务没有完成\n", resumedGoal, len(left))
+		userGoals = []string{
+			fmt.Sprintf("这是上一次会话留下的计划（目标：%s）。请用 todo_manager 查看还没有完成的任务（list 的 filter 可以按状态筛选），"+
+				"告诉我每个任务的状态、失败的原因，以及接下来可以先做哪些；不要重新规划已有的任务", resumedGoal),
+		}
 	}
 
 	for _, goal := range userGoals {
+		planGoal := goal
+		if resumedGoal != "" {
+			planGoal = resumedGoal
+		}
 		fmt.Println(strings.Repeat("=", 70))
 		fmt.Printf("🎯 用户目标: %s\n", goal)
 		fmt.Println(strings.Repeat("=", 70))
@@ -377,11 +1194,79 @@ func main() {
 		fmt.Println(strings.Repeat("-", 70))
 		fmt.Println(response.Content)
 
+		// 执行阶段
+		if *execute {
+			fmt.Println("\n" + strings.Repeat("-", 70))
+			fmt.Println("👷 执行阶段:")
+			fmt.Println(strings.Repeat("-", 70))
+			executed, err := executor.RunAll(ctx, planGoal)
+			if err != nil {
+				fmt.Printf("🛑 执行阶段发生错误：%v\n", err)
+			}
+			fmt.Printf("\n👷 共执行 %d 个任务\n", executed)
+			summary, _ := todoManager.InvokableRun(ctx, `{"action":"summary"}`)
+			fmt.Println("\n" + summary)
+		}
+
 		// 显示最终的 Todo List
 		fmt.Println("\n" + strings.Repeat("=", 70))
 		fmt.Println("📋 最终 Todo List 状态:")
 		fmt.Println(strings.Repeat("=", 70))
 		finalList, _ := todoManager.InvokableRun(ctx, `{"action":"list"}`)
 		fmt.Println(finalList)
+
+		// 导出计划，便于粘贴到 issue 或归档
+		if *outDir != "" {
+			for _, format := range []string{"markdown", "json"} {
+				if _, err := todoManager.InvokableRun(ctx, fmt.Sprintf(`{"action":"export","format":%q}`, format)); err != nil {
+					fmt.Printf("⚠ 导出 %s 失败: %v\n", format, err)
+				}
+			}
+		}
+
+		// 计划全部完成时写入长期记忆
+		if outcomes != nil {
+			id, err := todoManager.RecordOutcome(ctx, outcomes, *sessionID)
+			switch {
+			case err != nil:
+				fmt.Printf("⚠ %v\n", err)
+			case id != "":
+				fmt.Printf("🧠 计划结果已写入长期记忆，ID: %s\n", id)
+			}
+		}
+	}
+	todoManager.Unsubscribe(progress)
+	<-progressDone
+	if dropped := progress.Dropped(); dropped > 0 {
+		fmt.Printf("⚠ 进度订阅丢弃了 %d 个事件（-event-buffer 太小）\n", dropped)
+	}
+}
+
+// printProgress: 演示订阅者：按事件维护各任务的状态，每个事件打印一行进度
+func printProgress(sub *Subscription, initial []TodoItem) {
+	statuses := map[string]string{}
+	for _, item := range initial {
+		statuses[item.ID] = item.Status
+	}
+	for event := range sub.Events() {
+		switch {
+		case event.Type == EventDeleted:
+			delete(statuses, event.Item.ID)
+		case event.Item.ID != "":
+			statuses[event.Item.ID] = event.Item.Status
+		}
+		finished := 0
+		for _, status := range statuses {
+			if status == "completed" || status == "cancelled" {
+				finished++
+			}
+		}
+		line := fmt.Sprintf("📈 [%s] 进度 %d/%d | %s", event.Timestamp.Format("15:04:05"), finished, len(statuses), event.Type)
+		if event.Revision != nil {
+			line += fmt.Sprintf(" 计划第 %d 版：%s", event.Revision.Number, event.Revision.Summary)
+		} else {
+			line += fmt.Sprintf(" %s %s（%s）", event.Item.ID, event.Item.Title, event.Item.Status)
+		}
+		fmt.Println(line)
 	}
 }
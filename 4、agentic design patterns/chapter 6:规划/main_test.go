@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClock: 测试用的时钟，只在调用 advance 时前进
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// newTestTodoManager: 使用 storage（可以为空）和固定时钟的 TodoManagerTool
+func newTestTodoManager(t *testing.T, storage Storage) (*TodoManagerTool, *testClock) {
+	t.Helper()
+	m, err := NewTodoManagerTool(context.Background(), storage)
+	if err != nil {
+		t.Fatalf("NewTodoManagerTool: %v", err)
+	}
+	clock := &testClock{t: time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC)}
+	m.now = clock.now
+	return m, clock
+}
+
+// todoRun: 以 JSON 参数调用 todo_manager
+func todoRun(m *TodoManagerTool, args map[string]any) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	return m.InvokableRun(context.Background(), string(data))
+}
+
+// mustTodo: 调用 todo_manager，出错时终止测试
+func mustTodo(t *testing.T, m *TodoManagerTool, args map[string]any) string {
+	t.Helper()
+	out, err := todoRun(m, args)
+	if err != nil {
+		t.Fatalf("todo_manager %v: %v", args, err)
+	}
+	return out
+}
+
+// addTasks: 依次添加标题为 titles 的任务
+func addTasks(t *testing.T, m *TodoManagerTool, titles ...string) {
+	t.Helper()
+	for _, title := range titles {
+		mustTodo(t, m, map[string]any{"action": "add", "title": title})
+	}
+}
+
+// item: 按 ID 取出任务，不存在时终止测试
+func item(t *testing.T, m *TodoManagerTool, id string) TodoItem {
+	t.Helper()
+	i := m.todos.index(id)
+	if i < 0 {
+		t.Fatalf("任务 %s 不存在", id)
+	}
+	return m.todos.Items[i]
+}
+
+// TestTodoManagerResumesFromStorage: 第二个 TodoManagerTool 从同一个存储继续上一次的清单
+func TestTodoManagerResumesFromStorage(t *testing.T) {
+	for name, storage := range map[string]Storage{
+		"文件": NewFileStorage(t.TempDir() + "/todos.json"),
+		"内存": &MemoryStorage{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			first, _ := newTestTodoManager(t, storage)
+			addTasks(t, first, "需求分析", "UI设计")
+			mustTodo(t, first, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+			mustTodo(t, first, map[string]any{"action": "complete", "id": "todo-1", "result": "需求文档"})
+
+			second, _ := newTestTodoManager(t, storage)
+			if len(second.todos.Items) != 2 {
+				t.Fatalf("加载了 %d 个任务，want 2", len(second.todos.Items))
+			}
+			if got := item(t, second, "todo-1"); got.Status != "completed" || got.Result != "需求文档" {
+				t.Errorf("todo-1 = %+v", got)
+			}
+			addTasks(t, second, "后端开发")
+			if second.todos.Items[2].ID != "todo-3" {
+				t.Errorf("新任务的 ID = %s，want todo-3", second.todos.Items[2].ID)
+			}
+		})
+	}
+}
+
+func TestTodoManagerUnknownAction(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	if _, err := todoRun(m, map[string]any{"action": "archive"}); err == nil || !strings.Contains(err.Error(), "未知操作") {
+		t.Errorf("未知操作的错误 = %v", err)
+	}
+	if _, err := m.InvokableRun(context.Background(), "{"); err == nil {
+		t.Errorf("无效的 JSON 应返回错误")
+	}
+}
+
+// TestTodoIDsNotReused: 删除任务后计数器不回退，新任务不会用到已删除任务的 ID
+func TestTodoIDsNotReused(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "A", "B", "C")
+	mustTodo(t, m, map[string]any{"action": "delete", "id": "todo-3"})
+	addTasks(t, m, "D")
+	if got := m.todos.Items[2].ID; got != "todo-4" {
+		t.Errorf("删除 todo-3 后新任务的 ID = %s，want todo-4", got)
+	}
+
+	// 手工编辑过的清单中 ID 已被占用时继续递增
+	l := &TodoList{Items: []TodoItem{{ID: "todo-5"}}, NextID: 5}
+	if got := l.newID(); got != "todo-6" || l.NextID != 7 {
+		t.Errorf("newID = %s, next_id=%d，want todo-6, 7", got, l.NextID)
+	}
+	old := &TodoList{Items: []TodoItem{{ID: "todo-3"}, {ID: "todo-9"}}}
+	if got := old.newID(); got != "todo-10" {
+		t.Errorf("没有 next_id 时 newID = %s，want todo-10", got)
+	}
+}
+
+func TestTodoManagerDelete(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "需求分析")
+	mustTodo(t, m, map[string]any{"action": "add", "title": "后端开发", "depends_on": []string{"todo-1"}})
+	mustTodo(t, m, map[string]any{"action": "add", "title": "测试", "depends_on": []string{"todo-1", "todo-2"}})
+
+	out := mustTodo(t, m, map[string]any{"action": "delete", "id": "todo-1"})
+	if want := "任务已删除: ID=todo-1, 标题=需求分析；已从 todo-2、todo-3 的依赖中移除"; out != want {
+		t.Errorf("delete = %q，want %q", out, want)
+	}
+	if deps := item(t, m, "todo-3").DependsOn; len(deps) != 1 || deps[0] != "todo-2" {
+		t.Errorf("todo-3 的依赖 = %v", deps)
+	}
+	if _, err := todoRun(m, map[string]any{"action": "delete", "id": "todo-1"}); err == nil {
+		t.Errorf("删除不存在的任务应返回错误")
+	}
+}
+
+func TestTodoManagerMove(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "A", "B", "C")
+	out := mustTodo(t, m, map[string]any{"action": "move", "id": "todo-3", "position": 1})
+	if !strings.HasSuffix(out, "当前顺序=todo-3, todo-1, todo-2") {
+		t.Errorf("move = %q", out)
+	}
+	mustTodo(t, m, map[string]any{"action": "move", "id": "todo-3", "position": 3})
+	if got := ids(m.todos.Items); strings.Join(got, ",") != "todo-1,todo-2,todo-3" {
+		t.Errorf("移回末尾后的顺序 = %v", got)
+	}
+	// 没有依赖约束时 next 按清单顺序给出
+	mustTodo(t, m, map[string]any{"action": "move", "id": "todo-2", "position": 1})
+	if next := mustTodo(t, m, map[string]any{"action": "next"}); !strings.Contains(next, "1. [todo-2] B") {
+		t.Errorf("next = %q", next)
+	}
+
+	for _, args := range []map[string]any{
+		{"action": "move", "id": "todo-1", "position": 0},
+		{"action": "move", "id": "todo-1", "position": 4},
+		{"action": "move", "id": "todo-9", "position": 1},
+	} {
+		if _, err := todoRun(m, args); err == nil {
+			t.Errorf("%v 应返回错误", args)
+		}
+	}
+}
+
+func TestParsePlannedTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr string
+	}{
+		{"对象数组", `[{"title": "A"}, {"title": "B", "depends_on": ["new-1"]}]`, []string{"A", "B"}, ""},
+		{"序列化成字符串", `"[{\"title\": \"A\"}]"`, []string{"A"}, ""},
+		{"代码块和末尾逗号", "\"```json\\n[{\\\"title\\\": \\\"A\\\",}, {\\\"title\\\": \\\"B\\\"},]\\n```\"", []string{"A", "B"}, ""},
+		{"只有一个对象", `"{\"title\": \"A\"}"`, []string{"A"}, ""},
+		{"空数组", `[]`, nil, "任务列表为空"},
+		{"空字符串数组", `"[]"`, nil, "任务列表为空"},
+		{"不是数组", `42`, nil, "无效的任务列表格式"},
+		{"字符串无法解析", `"需求分析、设计"`, nil, "无效的任务列表格式"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := parsePlannedTasks(json.RawMessage(tt.raw))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v，want 包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePlannedTasks: %v", err)
+			}
+			titles := make([]string, len(tasks))
+			for i, task := range tasks {
+				titles[i] = task.Title
+			}
+			if !slices.Equal(titles, tt.want) {
+				t.Errorf("标题 = %v，want %v", titles, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlannerAddsStructuredTasks(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "已有任务")
+	planner, err := NewPlannerTool(context.Background(), m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := planner.InvokableRun(context.Background(), `{"goal": "开发待办应用", "tasks": [
+		{"title": "需求分析", "priority": "high", "estimate": "S"},
+		{"title": "后端开发", "depends_on": ["new-1", "todo-1"], "estimate": "2天"}
+	]}`)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if out != "规划完成：已生成 2 个任务：todo-2 需求分析、todo-3 后端开发" {
+		t.Errorf("plan = %q", out)
+	}
+	if m.todos.Goal != "开发待办应用" {
+		t.Errorf("目标 = %q", m.todos.Goal)
+	}
+	backend := item(t, m, "todo-3")
+	if !slices.Equal(backend.DependsOn, []string{"todo-2", "todo-1"}) || backend.EstimateHours != 16 {
+		t.Errorf("todo-3 = %+v", backend)
+	}
+	if item(t, m, "todo-2").Priority != "high" {
+		t.Errorf("优先级未设置")
+	}
+}
+
+// TestPlannerRejectsWholePlan: 任一任务无效时整个计划都不加入清单
+func TestPlannerRejectsWholePlan(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	planner, _ := NewPlannerTool(context.Background(), m, nil)
+	for _, args := range []string{
+		`{"goal": "g", "tasks": [{"title": "A"}, {"title": "B", "depends_on": ["new-3"]}]}`,
+		`{"goal": "g", "tasks": [{"title": "A", "depends_on": ["new-2"]}, {"title": "B", "depends_on": ["new-1"]}]}`,
+		`{"goal": "g", "tasks": [{"title": "A"}, {"title": ""}]}`,
+		`{"goal": "g", "tasks": [{"title": "A", "due": "某天"}]}`,
+	} {
+		if _, err := planner.InvokableRun(context.Background(), args); err == nil {
+			t.Errorf("%s 应返回错误", args)
+		}
+	}
+	if len(m.todos.Items) != 0 || m.todos.Goal != "" || m.todos.NextID > 1 {
+		t.Errorf("无效的计划不应修改清单: %+v", m.todos)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	es8Indexer "github.com/cloudwego/eino-ext/components/indexer/es8"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/schema"
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// --- 与第 8 章记忆管理的集成 ---
+
+// sessionTodosKey: 会话的 Todo List 在 Redis 中的键，与第 8 章短期记忆的 session:{id}:messages 等键放在同一命名空间下
+func sessionTodosKey(sessionID string) string {
+	return fmt.Sprintf("session:%s:todos", sessionID)
+}
+
+// OutcomeMemory: 保存计划结果的长期记忆，方法与第 8 章 LongTermMemory.Store 相同
+type OutcomeMemory interface {
+	Store(ctx context.Context, content string, metadata map[string]interface{}) (string, error)
+}
+
+// ========== 长期记忆：Elasticsearch 8 向量数据库（改编自第 8 章，只需要写入） ==========
+
+// LongTermMemory: 长期记忆管理器（使用 Elasticsearch 8），计划结果向量化后写入，第 8 章的 Agent 可以检索到
+type LongTermMemory struct {
+	indexer *es8Indexer.Indexer
+}
+
+var _ OutcomeMemory = (*LongTermMemory)(nil)
+
+// NewLongTermMemory: 创建长期记忆管理器，字段映射与第 8 章相同，两章可以共用同一个索引
+func NewLongTermMemory(ctx context.Context, esAddr, esUser, esPassword, indexName string, embedder embedding.Embedder) (*LongTermMemory, error) {
+	cfg := elasticsearch.Config{
+		Addresses: []string{esAddr},
+	}
+	if esUser != "" && esPassword != "" {
+		cfg.Username = esUser
+		cfg.Password = esPassword
+	}
+	esClient, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Elasticsearch 客户端失败: %w", err)
+	}
+	res, err := esClient.Info()
+	if err != nil {
+		return nil, fmt.Errorf("连接 Elasticsearch 失败: %w", err)
+	}
+	defer res.Body.Close()
+
+	const (
+		fieldContent       = "content"
+		fieldContentVector = "content_vector"
+		fieldMetadata      = "metadata"
+		fieldDocID         = "doc_id"
+	)
+	indexer, err := es8Indexer.NewIndexer(ctx, &es8Indexer.IndexerConfig{
+		Client:    esClient,
+		Index:     indexName,
+		BatchSize: 5,
+		DocumentToFields: func(ctx context.Context, doc *schema.Document) (map[string]es8Indexer.FieldValue, error) {
+			fields := map[string]es8Indexer.FieldValue{
+				fieldDocID:   {Value: doc.ID},
+				fieldContent: {Value: doc.Content, EmbedKey: fieldContentVector},
+			}
+			if len(doc.MetaData) > 0 {
+				fields[fieldMetadata] = es8Indexer.FieldValue{Value: doc.MetaData}
+			}
+			return fields, nil
+		},
+		Embedding: embedder,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建索引器失败: %w", err)
+	}
+	return &LongTermMemory{indexer: indexer}, nil
+}
+
+// Store: 存储长期记忆
+func (ltm *LongTermMemory) Store(ctx context.Context, content string, metadata map[string]interface{}) (string, error) {
+	doc := &schema.Document{
+		ID:       generateDocID(),
+		Content:  content,
+		MetaData: metadata,
+	}
+	ids, err := ltm.indexer.Store(ctx, []*schema.Document{doc})
+	if err != nil {
+		return "", fmt.Errorf("存储长期记忆失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("存储失败：未返回 ID")
+	}
+	return ids[0], nil
+}
+
+// generateDocID: 生成文档ID（时间戳+随机数），与第 8 章相同
+func generateDocID() string {
+	timestamp := time.Now().UnixNano()
+	randomBytes := make([]byte, 8)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return fmt.Sprintf("doc_%d", timestamp)
+	}
+	return fmt.Sprintf("doc_%d_%s", timestamp, hex.EncodeToString(randomBytes))
+}
+
+// MemoryOutcomes: 保存在内存中的长期记忆，没有 Elasticsearch 时（如单元测试）代替 LongTermMemory
+type MemoryOutcomes struct {
+	mu   sync.Mutex
+	docs []*schema.Document
+}
+
+var _ OutcomeMemory = (*MemoryOutcomes)(nil)
+
+func (m *MemoryOutcomes) Store(ctx context.Context, content string, metadata map[string]interface{}) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := fmt.Sprintf("outcome-%d", len(m.docs)+1)
+	m.docs = append(m.docs, &schema.Document{ID: id, Content: content, MetaData: metadata})
+	return id, nil
+}
+
+// Documents: 已保存的记忆，按保存的顺序
+func (m *MemoryOutcomes) Documents() []*schema.Document {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*schema.Document(nil), m.docs...)
+}
+
+// --- 计划结果 ---
+
+// planFinished: 整个计划是否已经完成：至少有一个任务，所有任务都已完成或取消，并且不是全部取消
+func planFinished(l *TodoList) bool {
+	completed := false
+	for _, item := range l.Items {
+		if !settled(item) {
+			return false
+		}
+		completed = completed || item.Status == "completed"
+	}
+	return completed
+}
+
+// outcomePending: 计划已完成，且上次写入长期记忆后又有任务新增或完成（如追加了新的规划）
+func outcomePending(l *TodoList) bool {
+	if !planFinished(l) {
+		return false
+	}
+	if l.OutcomeStoredAt.IsZero() {
+		return true
+	}
+	for _, item := range l.Items {
+		if item.CreatedAt.After(l.OutcomeStoredAt) || item.CompletedAt.After(l.OutcomeStoredAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// outcomeResultLimit: 结果摘要中每个任务的结果最多保留的字数，避免记忆过长、稀释检索
+const outcomeResultLimit = 200
+
+// outcomeSummary: 计划结果的自然语言摘要：目标、任务数、用时，以及每个完成的任务的结果
+func outcomeSummary(l *TodoList, now time.Time) string {
+	var completed []TodoItem
+	var cancelled []string
+	for _, item := range l.Items {
+		if l.hasChildren(item.ID) {
+			continue
+		}
+		if item.Status == "completed" {
+			completed = append(completed, item)
+		} else {
+			cancelled = append(cancelled, item.Title)
+		}
+	}
+
+	var sb strings.Builder
+	goal := l.Goal
+	if goal == "" {
+		goal = "（未记录目标）"
+	}
+	sb.WriteString(fmt.Sprintf("计划“%s”已于 %s 全部完成", goal, now.Format("2006-01-02 15:04")))
+	if n := len(l.Revisions); n > 0 {
+		sb.WriteString(fmt.Sprintf("，期间修订到第 %d 版", l.Revisions[n-1].Number))
+	}
+	sb.WriteString(fmt.Sprintf("。共完成 %d 个任务", len(completed)))
+	if len(cancelled) > 0 {
+		sb.WriteString(fmt.Sprintf("，取消 %d 个（%s）", len(cancelled), strings.Join(cancelled, "、")))
+	}
+	if runs := summarizeRuns(l, now); len(runs.runs) > 0 {
+		sb.WriteString(fmt.Sprintf("，执行用时合计 %s", formatDuration(runs.total)))
+	}
+	sb.WriteString("。\n各任务的结果：\n")
+	for _, item := range completed {
+		result := strings.Join(strings.Fields(item.Result), " ")
+		if result == "" {
+			result = "（没有记录结果）"
+		}
+		if runes := []rune(result); len(runes) > outcomeResultLimit {
+			result = string(runes[:outcomeResultLimit]) + "…"
+		}
+		sb.WriteString(fmt.Sprintf("- %s：%s\n", item.Title, result))
+	}
+	return sb.String()
+}
+
+// RecordOutcome: 计划全部完成时把结果摘要写入长期记忆，元数据为 {type: "plan_outcome", goal, session_id}；
+// 计划还没完成或结果已经写入过时什么也不做，返回空 ID
+func (t *TodoManagerTool) RecordOutcome(ctx context.Context, memory OutcomeMemory, sessionID string) (string, error) {
+	t.mu.Lock()
+	if !outcomePending(t.todos) {
+		t.mu.Unlock()
+		return "", nil
+	}
+	now := t.now()
+	goal, content := t.todos.Goal, outcomeSummary(t.todos, now)
+	t.mu.Unlock()
+
+	// 写入长期记忆可能要调用 Embedding 模型，不持有锁
+	id, err := memory.Store(ctx, content, map[string]interface{}{
+		"type":       "plan_outcome",
+		"goal":       goal,
+		"session_id": sessionID,
+		"timestamp":  now.Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("写入计划结果失败: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.todos.OutcomeStoredAt = now
+	if err := t.save(ctx); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// outstanding: 还没有结束的任务（待处理、进行中、失败），新会话据此报告上次留下的工作
+func (t *TodoManagerTool) outstanding() []TodoItem {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var items []TodoItem
+	for _, item := range t.todos.Items {
+		if !settled(item) && !t.todos.hasChildren(item.ID) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionTodosKey(t *testing.T) {
+	if got := sessionTodosKey("user-42"); got != "session:user-42:todos" {
+		t.Errorf("sessionTodosKey = %q", got)
+	}
+}
+
+func TestPlanFinished(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     bool
+	}{
+		{"空清单", nil, false},
+		{"全部完成", []string{"completed", "completed"}, true},
+		{"完成和取消", []string{"completed", "cancelled"}, true},
+		{"全部取消", []string{"cancelled", "cancelled"}, false},
+		{"还有待处理的任务", []string{"completed", "pending"}, false},
+		{"还有失败的任务", []string{"completed", "failed"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &TodoList{}
+			for _, status := range tt.statuses {
+				l.Items = append(l.Items, TodoItem{ID: l.newID(), Status: status})
+			}
+			if got := planFinished(l); got != tt.want {
+				t.Errorf("planFinished = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutcomePending(t *testing.T) {
+	stored := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	before, after := stored.Add(-time.Hour), stored.Add(time.Hour)
+	tests := []struct {
+		name string
+		l    *TodoList
+		want bool
+	}{
+		{"未完成", &TodoList{Items: []TodoItem{{Status: "pending"}}}, false},
+		{"完成后还没写入", &TodoList{Items: []TodoItem{{Status: "completed", CompletedAt: before}}}, true},
+		{"已经写入", &TodoList{OutcomeStoredAt: stored, Items: []TodoItem{{Status: "completed", CreatedAt: before, CompletedAt: before}}}, false},
+		{"写入后又完成了新的任务", &TodoList{OutcomeStoredAt: stored, Items: []TodoItem{
+			{Status: "completed", CreatedAt: before, CompletedAt: before},
+			{Status: "completed", CreatedAt: after, CompletedAt: after},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outcomePending(tt.l); got != tt.want {
+				t.Errorf("outcomePending = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutcomeSummary(t *testing.T) {
+	start := time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC)
+	l := &TodoList{
+		Goal:      "发布 v2.0",
+		Revisions: []PlanRevision{{Number: 1}, {Number: 2}},
+		Items: []TodoItem{
+			{ID: "todo-1", Title: "写发布说明", Status: "completed", StartedAt: start, CompletedAt: start.Add(2 * time.Minute),
+				Result: "  已写好\n发布说明  "},
+			{ID: "todo-2", Title: "发布", Status: "completed"},
+			{ID: "todo-3", Title: "打标签", Status: "completed", ParentID: "todo-2", Result: strings.Repeat("长", outcomeResultLimit+10)},
+			{ID: "todo-4", Title: "发邮件", Status: "cancelled"},
+		},
+	}
+	got := outcomeSummary(l, start.Add(time.Hour))
+	want := "计划“发布 v2.0”已于 2024-03-09 10:00 全部完成，期间修订到第 2 版。共完成 2 个任务，取消 1 个（发邮件），执行用时合计 2m0s。\n" +
+		"各任务的结果：\n" +
+		"- 写发布说明：已写好 发布说明\n" +
+		"- 打标签：" + strings.Repeat("长", outcomeResultLimit) + "…\n"
+	if got != want {
+		t.Errorf("outcomeSummary =\n%s\n期望\n%s", got, want)
+	}
+
+	got = outcomeSummary(&TodoList{Items: []TodoItem{{Title: "调研", Status: "completed"}}}, start)
+	if !strings.HasPrefix(got, "计划“（未记录目标）”") || !strings.Contains(got, "- 调研：（没有记录结果）") {
+		t.Errorf("没有目标和结果时 =\n%s", got)
+	}
+}
+
+// failingOutcomes: 写入总是失败的长期记忆
+type failingOutcomes struct{ err error }
+
+func (f failingOutcomes) Store(ctx context.Context, content string, metadata map[string]interface{}) (string, error) {
+	return "", f.err
+}
+
+func TestRecordOutcome(t *testing.T) {
+	ctx := context.Background()
+	storage := &MemoryStorage{}
+	m, clock := newTestTodoManager(t, storage)
+	outcomes := &MemoryOutcomes{}
+	addTasks(t, m, "调研")
+	m.todos.Goal = "选型"
+
+	if id, err := m.RecordOutcome(ctx, outcomes, "s1"); id != "" || err != nil {
+		t.Fatalf("计划未完成时 = %q, %v，不应写入", id, err)
+	}
+
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1", "result": "选 Redis"})
+	if _, err := m.RecordOutcome(ctx, failingOutcomes{errors.New("ES 不可用")}, "s1"); err == nil {
+		t.Fatal("写入失败应返回错误")
+	}
+	if !m.todos.OutcomeStoredAt.IsZero() {
+		t.Fatal("写入失败时不应记录写入时间")
+	}
+
+	clock.advance(time.Minute)
+	id, err := m.RecordOutcome(ctx, outcomes, "s1")
+	if err != nil || id != "outcome-1" {
+		t.Fatalf("RecordOutcome = %q, %v", id, err)
+	}
+	docs := outcomes.Documents()
+	if len(docs) != 1 || !strings.Contains(docs[0].Content, "- 调研：选 Redis") {
+		t.Fatalf("写入的记忆 = %+v", docs)
+	}
+	meta := docs[0].MetaData
+	if meta["type"] != "plan_outcome" || meta["goal"] != "选型" || meta["session_id"] != "s1" || meta["timestamp"] != clock.now().Unix() {
+		t.Errorf("元数据 = %v", meta)
+	}
+
+	// 写入时间随清单保存，下一次会话不会重复写入
+	next, _ := newTestTodoManager(t, storage)
+	if id, err := next.RecordOutcome(ctx, outcomes, "s1"); id != "" || err != nil || len(outcomes.Documents()) != 1 {
+		t.Errorf("已写入的结果不应重复写入，得到 %q, %v", id, err)
+	}
+}
+
+func TestOutstanding(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "设计", "开发", "测试")
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-2", "title": "后端"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-3", "status": "cancelled"})
+
+	if got := strings.Join(ids(m.outstanding()), ","); got != "todo-4" {
+		t.Errorf("outstanding = %s，期望只有没有子任务、还没结束的 todo-4", got)
+	}
+}
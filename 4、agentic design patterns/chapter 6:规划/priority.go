@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 优先级和截止时间 ---
+
+// priorityRank: 优先级的排序位置，数字越小越靠前；未设置的按 medium 处理
+var priorityRank = map[string]int{"high": 0, "medium": 1, "": 1, "low": 2}
+
+// priorityIcons: 列表中优先级的显示
+var priorityIcons = map[string]string{"high": "🔴 高", "medium": "🟡 中", "low": "🟢 低"}
+
+// statusRank: 列表中状态的排序位置：进行中、待处理、失败、已完成、已取消
+var statusRank = map[string]int{"in_progress": 0, "pending": 1, "failed": 2, "completed": 3, "cancelled": 4}
+
+// parsePriority: 校验优先级，空字符串表示未设置
+func parsePriority(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, ok := priorityRank[s]; !ok {
+		return "", fmt.Errorf("无效的优先级 %q（可选：high、medium、low）", s)
+	}
+	return s, nil
+}
+
+// parseDue: 解析截止时间：RFC3339（如 2024-12-31T18:00:00+08:00）、"2024-12-31 18:00" 或只有日期的 "2024-12-31"
+// 不带时区的时间按 loc 理解；只有日期时截止到当天结束（23:59:59）
+func parseDue(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if due, err := time.Parse(time.RFC3339, s); err == nil {
+		return due, nil
+	}
+	if due, err := time.ParseInLocation("2006-01-02 15:04", s, loc); err == nil {
+		return due, nil
+	}
+	if day, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return day.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("无效的截止时间 %q（格式：2024-12-31、2024-12-31 18:00 或 RFC3339）", s)
+}
+
+// overdue: 未完成（也未取消）且已过截止时间
+func overdue(item TodoItem, now time.Time) bool {
+	return !settled(item) && !item.Due.IsZero() && now.After(item.Due)
+}
+
+// sortTodos: 按状态、优先级、截止时间（没有截止时间的排在最后）排序，其余保持清单中的顺序
+func sortTodos(items []TodoItem) []TodoItem {
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b TodoItem) int {
+		if c := cmp.Compare(statusRank[a.Status], statusRank[b.Status]); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(priorityRank[a.Priority], priorityRank[b.Priority]); c != 0 {
+			return c
+		}
+		switch {
+		case a.Due.IsZero() && b.Due.IsZero():
+			return 0
+		case a.Due.IsZero():
+			return 1
+		case b.Due.IsZero():
+			return -1
+		}
+		return a.Due.Compare(b.Due)
+	})
+	return sorted
+}
+
+// formatDue: 截止时间的显示，只有日期的截止时间（23:59:59）只显示日期
+func formatDue(due time.Time) string {
+	if due.Hour() == 23 && due.Minute() == 59 && due.Second() == 59 {
+		return due.Format("2006-01-02")
+	}
+	return due.Format("2006-01-02 15:04")
+}
+
+// todoFilter: list 和 search 操作的筛选条件，零值表示不筛选
+type todoFilter struct {
+	status   string
+	priority string
+	overdue  *bool
+	query    string // query: 标题或描述中包含的关键词
+}
+
+// parseTodoFilter: 解析 "status=pending,overdue=true,priority=high,query=后端" 形式的筛选条件，各条件同时满足
+func parseTodoFilter(spec string) (todoFilter, error) {
+	var f todoFilter
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok {
+			return todoFilter{}, fmt.Errorf("无效的筛选条件 %q（格式：键=值）", item)
+		}
+		switch key {
+		case "status":
+			status, err := parseStatus(value)
+			if err != nil {
+				return todoFilter{}, err
+			}
+			f.status = status
+		case "priority":
+			p, err := parsePriority(value)
+			if err != nil {
+				return todoFilter{}, err
+			}
+			f.priority = p
+		case "overdue":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return todoFilter{}, fmt.Errorf("无效的筛选条件 %q: overdue 应为 true 或 false", item)
+			}
+			f.overdue = &b
+		case "query":
+			f.query = value
+		default:
+			return todoFilter{}, fmt.Errorf("未知的筛选字段 %q（可选：status、priority、overdue、query）", key)
+		}
+	}
+	return f, nil
+}
+
+// match: 任务是否满足所有筛选条件；按 medium 筛选时包含未设置优先级的任务
+func (f todoFilter) match(item TodoItem, now time.Time) bool {
+	if f.status != "" && item.Status != f.status {
+		return false
+	}
+	if f.priority != "" && priorityRank[item.Priority] != priorityRank[f.priority] {
+		return false
+	}
+	if f.overdue != nil && overdue(item, now) != *f.overdue {
+		return false
+	}
+	return matchQuery(item, f.query)
+}
+
+// empty: 是否没有任何筛选条件
+func (f todoFilter) empty() bool {
+	return f.status == "" && f.priority == "" && f.overdue == nil && strings.TrimSpace(f.query) == ""
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	for in, want := range map[string]string{"": "", "high": "high", " Medium ": "medium", "LOW": "low"} {
+		if got, err := parsePriority(in); err != nil || got != want {
+			t.Errorf("parsePriority(%q) = %q, %v，want %q", in, got, err, want)
+		}
+	}
+	if _, err := parsePriority("urgent"); err == nil || !strings.Contains(err.Error(), "无效的优先级") {
+		t.Errorf("无效的优先级应返回错误: %v", err)
+	}
+}
+
+func TestParseDue(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-12-31", time.Date(2024, 12, 31, 23, 59, 59, 0, shanghai), false},
+		{" 2024-12-31 18:00 ", time.Date(2024, 12, 31, 18, 0, 0, 0, shanghai), false},
+		{"2024-12-31T18:00:00Z", time.Date(2024, 12, 31, 18, 0, 0, 0, time.UTC), false},
+		{"明天", time.Time{}, true},
+		{"2024-13-01", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDue(tt.in, shanghai)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDue(%q) 应返回错误", tt.in)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseDue(%q) = %v, %v，want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatDue(t *testing.T) {
+	if got := formatDue(time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)); got != "2024-12-31" {
+		t.Errorf("只有日期的截止时间 = %q", got)
+	}
+	if got := formatDue(time.Date(2024, 12, 31, 18, 0, 0, 0, time.UTC)); got != "2024-12-31 18:00" {
+		t.Errorf("带时间的截止时间 = %q", got)
+	}
+}
+
+func TestOverdue(t *testing.T) {
+	now := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name string
+		item TodoItem
+		want bool
+	}{
+		{"已过截止时间", TodoItem{Status: "pending", Due: past}, true},
+		{"失败的任务仍算逾期", TodoItem{Status: "failed", Due: past}, true},
+		{"未到截止时间", TodoItem{Status: "pending", Due: future}, false},
+		{"没有截止时间", TodoItem{Status: "pending"}, false},
+		{"已完成", TodoItem{Status: "completed", Due: past}, false},
+		{"已取消", TodoItem{Status: "cancelled", Due: past}, false},
+	}
+	for _, tt := range tests {
+		if got := overdue(tt.item, now); got != tt.want {
+			t.Errorf("%s: overdue = %v", tt.name, got)
+		}
+	}
+}
+
+func TestSortTodos(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	items := []TodoItem{
+		{ID: "完成", Status: "completed", Priority: "high"},
+		{ID: "低", Status: "pending", Priority: "low"},
+		{ID: "中-无截止", Status: "pending"},
+		{ID: "中-10日", Status: "pending", Priority: "medium", Due: day(10)},
+		{ID: "高", Status: "pending", Priority: "high"},
+		{ID: "中-5日", Status: "pending", Due: day(5)},
+		{ID: "进行中", Status: "in_progress", Priority: "low"},
+		{ID: "取消", Status: "cancelled"},
+		{ID: "失败", Status: "failed"},
+	}
+	want := []string{"进行中", "高", "中-5日", "中-10日", "中-无截止", "低", "失败", "完成", "取消"}
+	if got := ids(sortTodos(items)); !slices.Equal(got, want) {
+		t.Errorf("sortTodos = %v，want %v", got, want)
+	}
+	if items[0].ID != "完成" {
+		t.Errorf("sortTodos 不应修改原切片")
+	}
+}
+
+func TestParseTodoFilter(t *testing.T) {
+	f, err := parseTodoFilter(" status=pending , priority=HIGH,overdue=true,query=后端 ")
+	if err != nil {
+		t.Fatalf("parseTodoFilter: %v", err)
+	}
+	if f.status != "pending" || f.priority != "high" || f.overdue == nil || !*f.overdue || f.query != "后端" || f.empty() {
+		t.Errorf("parseTodoFilter = %+v", f)
+	}
+	if f, err := parseTodoFilter(""); err != nil || !f.empty() {
+		t.Errorf("空筛选条件 = %+v, %v", f, err)
+	}
+	for spec, wantErr := range map[string]string{
+		"status":          "格式：键=值",
+		"status=done":     "无效的状态",
+		"priority=top":    "无效的优先级",
+		"overdue=maybe":   "overdue 应为 true 或 false",
+		"owner=张三":        "未知的筛选字段",
+		"status=pending,": "",
+	} {
+		_, err := parseTodoFilter(spec)
+		if wantErr == "" {
+			if err != nil {
+				t.Errorf("parseTodoFilter(%q): %v", spec, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("parseTodoFilter(%q) 错误 = %v，want 包含 %q", spec, err, wantErr)
+		}
+	}
+}
+
+func TestTodoFilterMatch(t *testing.T) {
+	now := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	item := TodoItem{Title: "后端开发", Status: "pending", Due: now.Add(-time.Hour)}
+	yes, no := true, false
+	tests := []struct {
+		name   string
+		filter todoFilter
+		want   bool
+	}{
+		{"不筛选", todoFilter{}, true},
+		{"状态", todoFilter{status: "pending"}, true},
+		{"状态不符", todoFilter{status: "completed"}, false},
+		{"未设置优先级按 medium", todoFilter{priority: "medium"}, true},
+		{"优先级不符", todoFilter{priority: "high"}, false},
+		{"逾期", todoFilter{overdue: &yes}, true},
+		{"未逾期", todoFilter{overdue: &no}, false},
+		{"关键词", todoFilter{query: "后端"}, true},
+		{"关键词不符", todoFilter{query: "前端"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.match(item, now); got != tt.want {
+			t.Errorf("%s: match = %v", tt.name, got)
+		}
+	}
+}
+
+func TestTodoManagerPriorityAndDue(t *testing.T) {
+	m, clock := newTestTodoManager(t, nil)
+	mustTodo(t, m, map[string]any{"action": "add", "title": "写文档", "priority": "low"})
+	mustTodo(t, m, map[string]any{"action": "add", "title": "修复线上问题", "priority": "high", "due": "2024-03-09 18:00"})
+	if _, err := todoRun(m, map[string]any{"action": "add", "title": "X", "due": "下周"}); err == nil {
+		t.Errorf("无效的截止时间应返回错误")
+	}
+	if _, err := todoRun(m, map[string]any{"action": "add", "title": "X", "priority": "urgent"}); err == nil {
+		t.Errorf("无效的优先级应返回错误")
+	}
+
+	list := mustTodo(t, m, map[string]any{"action": "list"})
+	if strings.Index(list, "修复线上问题") > strings.Index(list, "写文档") {
+		t.Errorf("高优先级的任务应排在前面:\n%s", list)
+	}
+	if !strings.Contains(list, "📅 2024-03-09 18:00") || strings.Contains(list, "⏰ 已逾期") {
+		t.Errorf("截止时间的显示:\n%s", list)
+	}
+
+	clock.advance(10 * time.Hour)
+	list = mustTodo(t, m, map[string]any{"action": "list", "filter": "overdue=true"})
+	if !strings.Contains(list, "修复线上问题 🔴 高 📅 2024-03-09 18:00 ⏰ 已逾期") || strings.Contains(list, "写文档") {
+		t.Errorf("逾期筛选:\n%s", list)
+	}
+	if !strings.Contains(list, "⏰ 逾期 1") || !strings.Contains(list, "符合筛选条件的任务 1 个") {
+		t.Errorf("逾期统计:\n%s", list)
+	}
+
+	// update 时传空字符串清除截止时间
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "due": ""})
+	if !item(t, m, "todo-2").Due.IsZero() {
+		t.Errorf("截止时间应被清除")
+	}
+	if list := mustTodo(t, m, map[string]any{"action": "list", "filter": "status=completed"}); !strings.Contains(list, "没有符合筛选条件的任务") {
+		t.Errorf("没有匹配时:\n%s", list)
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// --- 重新规划 ---
+
+// replanSystemPrompt: 重新规划链的系统提示词，要求只输出补丁 JSON
+const replanSystemPrompt = `你是任务规划助手，负责在执行过程中修订 Todo List。
+根据整体目标、当前清单（状态、执行结果和失败原因）和这次重新规划的原因，给出对清单的修改。
+只输出一个 JSON 对象，例如：
+{{"reason": "后端开发失败：缺少接口设计，先补充接口设计再重试",
+  "add": [{{"title": "接口设计", "description": "定义待办事项的增删改查接口", "depends_on": ["todo-1"]}}],
+  "modify": [{{"id": "todo-3", "description": "按接口设计实现后端", "depends_on": ["new-1"]}}],
+  "cancel": ["todo-5"]}}
+规则：
+- add 中的任务按顺序编号为 new-1、new-2……，可以在 depends_on 和 parent_id 中引用；还可以设置 priority（high/medium/low）、due 和 estimate（S/M/L 或时长如 3h）
+- modify 只写需要修改的字段（title、description、depends_on、priority、due、estimate）；修改失败的任务会把它重置为待处理，按新内容重试
+- cancel 取消不再需要的任务（连同其未完成的子任务），不能取消或修改已完成的任务
+- 能通过修改失败任务的做法解决时，不要重复添加相同的任务；不需要修改的部分不要出现`
+
+// PlanPatch: 重新规划给出的修改，整体应用：任一项校验失败时清单保持不变
+type PlanPatch struct {
+	Reason string        `json:"reason"` // Reason: 规划链对这次修订的说明
+	Add    []TaskSpec    `json:"add,omitempty"`
+	Modify []PatchModify `json:"modify,omitempty"`
+	Cancel []string      `json:"cancel,omitempty"` // Cancel: 要取消的任务 ID
+}
+
+// TaskSpec: 要新增的任务（规划或重新规划）；depends_on 和 parent_id 可以用 new-N 引用同一批中的第 N 个新任务
+type TaskSpec struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+	ParentID    string   `json:"parent_id,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Due         string   `json:"due,omitempty"`
+	Estimate    string   `json:"estimate,omitempty"` // Estimate: 工作量估计，S/M/L 或时长如 3h
+}
+
+// PatchModify: 修改已有任务，为空指针的字段保持不变
+type PatchModify struct {
+	ID          string    `json:"id"`
+	Title       *string   `json:"title,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	DependsOn   *[]string `json:"depends_on,omitempty"`
+	Priority    *string   `json:"priority,omitempty"`
+	Due         *string   `json:"due,omitempty"` // Due: 空字符串表示清除截止时间
+	Estimate    *string   `json:"estimate,omitempty"`
+}
+
+// empty: 补丁是否没有任何修改
+func (p PlanPatch) empty() bool {
+	return len(p.Add) == 0 && len(p.Modify) == 0 && len(p.Cancel) == 0
+}
+
+// PlanRevision: 一次计划修订的记录
+type PlanRevision struct {
+	Number  int       `json:"number"`           // Number: 修订版本号，从 1 开始
+	At      time.Time `json:"at"`               // At: 修订时间
+	Trigger string    `json:"trigger"`          // Trigger: 触发重新规划的原因，如某个任务失败
+	Reason  string    `json:"reason,omitempty"` // Reason: 规划链对修订的说明
+	Summary string    `json:"summary"`          // Summary: 修改摘要，如 "新增 todo-7；修改 todo-3；取消 todo-5"
+}
+
+// clone: 深拷贝清单，补丁先应用在副本上，全部校验通过后才替换原清单
+func (l *TodoList) clone() *TodoList {
+	c := &TodoList{
+		Items:     slices.Clone(l.Items),
+		NextID:    l.NextID,
+		Goal:      l.Goal,
+		Revisions: slices.Clone(l.Revisions),
+
+		OutcomeStoredAt: l.OutcomeStoredAt,
+	}
+	for i := range c.Items {
+		c.Items[i].DependsOn = slices.Clone(c.Items[i].DependsOn)
+	}
+	return c
+}
+
+// applyPatch: 在清单上应用补丁并记录修订；返回错误时清单可能已被部分修改，调用方应在副本上调用
+// 已完成的任务不能取消或修改；已取消的任务不能修改，也不能作为新任务的父任务或依赖
+func (l *TodoList) applyPatch(p PlanPatch, trigger string, now time.Time) (PlanRevision, error) {
+	if p.empty() {
+		return PlanRevision{}, errors.New("补丁没有任何修改")
+	}
+	var summary []string
+
+	// 取消：连同未完成的子任务，已完成的子任务保留
+	var cancelled []string
+	for _, id := range p.Cancel {
+		i := l.index(id)
+		switch {
+		case i < 0:
+			return PlanRevision{}, fmt.Errorf("要取消的任务不存在: %s", id)
+		case l.Items[i].Status == "completed":
+			return PlanRevision{}, fmt.Errorf("不能取消已完成的任务: %s", id)
+		}
+		for _, target := range append([]string{id}, l.descendants(id)...) {
+			j := l.index(target)
+			if settled(l.Items[j]) {
+				continue
+			}
+			l.Items[j].Status = "cancelled"
+			cancelled = append(cancelled, target)
+		}
+	}
+
+	// 新增：依赖在所有修改完成后统一设置，修改中的依赖也可以引用新任务
+	added, resolve, err := l.insertTasks(p.Add, now)
+	if err != nil {
+		return PlanRevision{}, err
+	}
+
+	// 修改：修改失败的任务会把它重置为待处理，按新内容重试
+	var modified []string
+	for _, mod := range p.Modify {
+		i := l.index(mod.ID)
+		switch {
+		case i < 0:
+			return PlanRevision{}, fmt.Errorf("要修改的任务不存在: %s", mod.ID)
+		case settled(l.Items[i]):
+			return PlanRevision{}, fmt.Errorf("不能修改已%s的任务: %s", statusName(l.Items[i].Status), mod.ID)
+		}
+		item := &l.Items[i]
+		if mod.Title != nil {
+			item.Title = *mod.Title
+		}
+		if mod.Description != nil {
+			item.Description = *mod.Description
+		}
+		if mod.Priority != nil {
+			priority, err := parsePriority(*mod.Priority)
+			if err != nil {
+				return PlanRevision{}, err
+			}
+			item.Priority = priority
+		}
+		if mod.Due != nil {
+			item.Due = time.Time{}
+			if strings.TrimSpace(*mod.Due) != "" {
+				due, err := parseDue(*mod.Due, now.Location())
+				if err != nil {
+					return PlanRevision{}, err
+				}
+				item.Due = due
+			}
+		}
+		if mod.Estimate != nil {
+			estimate, err := parseEstimate(*mod.Estimate)
+			if err != nil {
+				return PlanRevision{}, err
+			}
+			item.EstimateHours = estimate
+		}
+		if item.Status == "failed" && !l.hasChildren(item.ID) {
+			item.Status = "pending"
+			item.Error = ""
+		}
+		modified = append(modified, mod.ID)
+	}
+
+	if err := l.setTaskDeps(p.Add, added, resolve); err != nil {
+		return PlanRevision{}, err
+	}
+	for _, mod := range p.Modify {
+		if mod.DependsOn != nil {
+			if err := l.setDeps(mod.ID, *mod.DependsOn, resolve); err != nil {
+				return PlanRevision{}, err
+			}
+		}
+	}
+	if _, cycle := l.executionOrder(); cycle != nil {
+		return PlanRevision{}, fmt.Errorf("修改后任务依赖存在循环：%s", strings.Join(cycle, " → "))
+	}
+
+	// 子任务变化后重新推导所有父任务的状态
+	for _, item := range l.Items {
+		l.rollUp(item.ParentID, now)
+	}
+
+	if len(added) > 0 {
+		summary = append(summary, "新增 "+strings.Join(added, "、"))
+	}
+	if len(modified) > 0 {
+		summary = append(summary, "修改 "+strings.Join(modified, "、"))
+	}
+	if len(cancelled) > 0 {
+		summary = append(summary, "取消 "+strings.Join(cancelled, "、"))
+	}
+	revision := PlanRevision{
+		Number:  len(l.Revisions) + 1,
+		At:      now,
+		Trigger: trigger,
+		Reason:  p.Reason,
+		Summary: strings.Join(summary, "；"),
+	}
+	l.Revisions = append(l.Revisions, revision)
+	return revision, nil
+}
+
+// insertTasks: 把新任务（暂不设置依赖）加入清单，返回分配的 ID 和把 new-N 解析为实际 ID 的函数
+func (l *TodoList) insertTasks(tasks []TaskSpec, now time.Time) ([]string, func(string) string, error) {
+	refs := map[string]string{}
+	resolve := func(id string) string {
+		id = strings.TrimSpace(id)
+		if real, ok := refs[id]; ok {
+			return real
+		}
+		return id
+	}
+	var added []string
+	for n, task := range tasks {
+		if strings.TrimSpace(task.Title) == "" {
+			return nil, nil, fmt.Errorf("第 %d 个新任务缺少标题", n+1)
+		}
+		parentID := resolve(task.ParentID)
+		if parentID != "" {
+			i := l.index(parentID)
+			if i < 0 {
+				return nil, nil, fmt.Errorf("新任务 %q 的父任务不存在: %s", task.Title, task.ParentID)
+			}
+			if settled(l.Items[i]) {
+				return nil, nil, fmt.Errorf("不能给已%s的任务 %s 添加子任务", statusName(l.Items[i].Status), parentID)
+			}
+		}
+		priority, err := parsePriority(task.Priority)
+		if err != nil {
+			return nil, nil, err
+		}
+		var due time.Time
+		if strings.TrimSpace(task.Due) != "" {
+			if due, err = parseDue(task.Due, now.Location()); err != nil {
+				return nil, nil, err
+			}
+		}
+		var estimate float64
+		if strings.TrimSpace(task.Estimate) != "" {
+			if estimate, err = parseEstimate(task.Estimate); err != nil {
+				return nil, nil, err
+			}
+		}
+		id := l.newID()
+		refs[fmt.Sprintf("new-%d", n+1)] = id
+		l.Items = append(l.Items, TodoItem{
+			ID:          id,
+			Title:       task.Title,
+			Description: task.Description,
+			Status:      "pending",
+			CreatedAt:   now,
+			Priority:    priority,
+			Due:         due,
+			ParentID:    parentID,
+
+			EstimateHours: estimate,
+		})
+		added = append(added, id)
+	}
+	return added, resolve, nil
+}
+
+// setTaskDeps: 所有新任务都已加入清单后再设置它们的依赖，新任务之间可以互相依赖
+func (l *TodoList) setTaskDeps(tasks []TaskSpec, ids []string, resolve func(string) string) error {
+	for n, task := range tasks {
+		if err := l.setDeps(ids[n], task.DependsOn, resolve); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setDeps: 解析 new-N 引用后校验并设置任务的依赖；不能依赖已取消的任务
+func (l *TodoList) setDeps(id string, deps []string, resolve func(string) string) error {
+	resolved := make([]string, len(deps))
+	for k, dep := range deps {
+		resolved[k] = resolve(dep)
+		if j := l.index(resolved[k]); j >= 0 && l.Items[j].Status == "cancelled" {
+			return fmt.Errorf("任务 %s 不能依赖已取消的任务 %s", id, resolved[k])
+		}
+	}
+	i := l.index(id)
+	checked, err := l.checkDependsOn(id, l.Items[i].ParentID, resolved)
+	if err != nil {
+		return err
+	}
+	l.Items[i].DependsOn = checked
+	return nil
+}
+
+// statusName: 错误信息中的状态名称
+func statusName(status string) string {
+	switch status {
+	case "completed":
+		return "完成"
+	case "cancelled":
+		return "取消"
+	}
+	return status
+}
+
+// applyPatch: 在副本上应用补丁，校验全部通过且保存成功后才替换当前清单
+func (t *TodoManagerTool) applyPatch(ctx context.Context, p PlanPatch, trigger string) (PlanRevision, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trial := t.todos.clone()
+	revision, err := trial.applyPatch(p, trigger, t.now())
+	if err != nil {
+		return PlanRevision{}, fmt.Errorf("无效的补丁: %w", err)
+	}
+	previous := t.todos
+	t.todos = trial
+	t.recordDiff(previous, trial)
+	t.pending = append(t.pending, TodoEvent{Type: EventReplanned, Revision: &revision, Timestamp: t.now()})
+	if err := t.save(ctx); err != nil {
+		t.todos = previous
+		return PlanRevision{}, err
+	}
+	return revision, nil
+}
+
+// addPlannedTasks: 规划工具生成初始计划：记录整体目标，新任务整体校验通过且保存成功后才加入清单
+func (t *TodoManagerTool) addPlannedTasks(ctx context.Context, goal string, tasks []TaskSpec) ([]TodoItem, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trial := t.todos.clone()
+	trial.Goal = goal
+	ids, resolve, err := trial.insertTasks(tasks, t.now())
+	if err != nil {
+		return nil, err
+	}
+	if err := trial.setTaskDeps(tasks, ids, resolve); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		trial.rollUp(trial.Items[trial.index(id)].ParentID, t.now())
+	}
+	previous := t.todos
+	t.todos = trial
+	t.recordDiff(previous, trial)
+	if err := t.save(ctx); err != nil {
+		t.todos = previous
+		return nil, err
+	}
+	added := make([]TodoItem, len(ids))
+	for i, id := range ids {
+		added[i] = trial.Items[trial.index(id)]
+	}
+	return added, nil
+}
+
+// snapshot: 重新规划需要的整体目标和当前清单
+func (t *TodoManagerTool) snapshot() (goal, list string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.todos.Goal, t.renderTodoList(todoFilter{}, 0)
+}
+
+// buildReplanChain: 构建重新规划链：Template -> ChatModel -> Lambda（提取文本）
+func buildReplanChain(ctx context.Context, llm model.BaseChatModel) (compose.Runnable[map[string]any, string], error) {
+	replanPrompt := prompt.FromMessages(
+		schema.FString,
+		schema.SystemMessage(replanSystemPrompt),
+		schema.UserMessage("整体目标：{goal}\n\n当前清单：\n{todos}\n\n重新规划的原因：{reason}"),
+	)
+	extractContent := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (string, error) {
+		return msg.Content, nil
+	})
+	return compose.NewChain[map[string]any, string]().
+		AppendChatTemplate(replanPrompt).
+		AppendChatModel(llm).
+		AppendLambda(extractContent).
+		Compile(ctx)
+}
+
+// parsePatch: 从规划链的输出中找出第一个能解析为非空补丁的 JSON 对象（可能包在代码块或说明文字中）
+func parsePatch(text string) (PlanPatch, error) {
+	for i := strings.IndexByte(text, '{'); i >= 0; {
+		var patch PlanPatch
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&patch); err == nil && !patch.empty() {
+			return patch, nil
+		}
+		next := strings.IndexByte(text[i+1:], '{')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return PlanPatch{}, errors.New("无法从重新规划的输出中解析出补丁")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// replanFixture: 需求分析已完成、后端开发失败、测试依赖后端开发的清单
+func replanFixture(t *testing.T) *TodoManagerTool {
+	t.Helper()
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "需求分析", "后端开发")
+	mustTodo(t, m, map[string]any{"action": "add", "title": "测试", "depends_on": []string{"todo-2"}})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "fail", "id": "todo-2", "error": "缺少接口设计"})
+	return m
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestApplyPatch(t *testing.T) {
+	m := replanFixture(t)
+	now := time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC)
+	patch := PlanPatch{
+		Reason: "先补充接口设计",
+		Add:    []TaskSpec{{Title: "接口设计", DependsOn: []string{"todo-1"}, Estimate: "M"}},
+		Modify: []PatchModify{{ID: "todo-2", Description: strPtr("按接口设计实现"), DependsOn: &[]string{"new-1"}, Priority: strPtr("high")}},
+		Cancel: []string{"todo-3"},
+	}
+	revision, err := m.todos.applyPatch(patch, "todo-2 失败", now)
+	if err != nil {
+		t.Fatalf("applyPatch: %v", err)
+	}
+	if revision.Number != 1 || revision.Summary != "新增 todo-4；修改 todo-2；取消 todo-3" || revision.Trigger != "todo-2 失败" || revision.Reason != "先补充接口设计" {
+		t.Errorf("revision = %+v", revision)
+	}
+	backend := item(t, m, "todo-2")
+	if backend.Status != "pending" || backend.Error != "" || backend.Priority != "high" || !slices.Equal(backend.DependsOn, []string{"todo-4"}) {
+		t.Errorf("修改后的失败任务应重置为待处理: %+v", backend)
+	}
+	if added := item(t, m, "todo-4"); added.EstimateHours != 4 || !slices.Equal(added.DependsOn, []string{"todo-1"}) {
+		t.Errorf("新任务 = %+v", added)
+	}
+	if item(t, m, "todo-3").Status != "cancelled" {
+		t.Errorf("todo-3 应被取消")
+	}
+}
+
+func TestApplyPatchRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   PlanPatch
+		wantErr string
+	}{
+		{"空补丁", PlanPatch{Reason: "无"}, "补丁没有任何修改"},
+		{"取消已完成的任务", PlanPatch{Cancel: []string{"todo-1"}}, "不能取消已完成的任务: todo-1"},
+		{"取消不存在的任务", PlanPatch{Cancel: []string{"todo-9"}}, "要取消的任务不存在"},
+		{"修改已完成的任务", PlanPatch{Modify: []PatchModify{{ID: "todo-1", Title: strPtr("x")}}}, "不能修改已完成的任务"},
+		{"修改不存在的任务", PlanPatch{Modify: []PatchModify{{ID: "todo-9"}}}, "要修改的任务不存在"},
+		{"新任务缺少标题", PlanPatch{Add: []TaskSpec{{Title: " "}}}, "第 1 个新任务缺少标题"},
+		{"依赖不存在", PlanPatch{Add: []TaskSpec{{Title: "x", DependsOn: []string{"new-2"}}}}, "依赖的任务不存在: new-2"},
+		{"形成循环", PlanPatch{Modify: []PatchModify{{ID: "todo-2", DependsOn: &[]string{"todo-3"}}}}, "循环"},
+		{"依赖已取消的任务", PlanPatch{Cancel: []string{"todo-3"}, Add: []TaskSpec{{Title: "x", DependsOn: []string{"todo-3"}}}}, "不能依赖已取消的任务"},
+		{"无效的优先级", PlanPatch{Modify: []PatchModify{{ID: "todo-2", Priority: strPtr("urgent")}}}, "无效的优先级"},
+		{"无效的估计", PlanPatch{Add: []TaskSpec{{Title: "x", Estimate: "很久"}}}, "无效的工作量估计"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := replanFixture(t)
+			before := m.todos.clone()
+			_, err := m.applyPatch(context.Background(), tt.patch, "测试")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("错误 = %v，want 包含 %q", err, tt.wantErr)
+			}
+			if len(m.todos.Items) != len(before.Items) || len(m.todos.Revisions) != 0 || item(t, m, "todo-2").Status != "failed" {
+				t.Errorf("校验失败时清单应保持不变")
+			}
+		})
+	}
+}
+
+// TestApplyPatchSaveFailure: 保存失败时恢复原清单
+func TestApplyPatchSaveFailure(t *testing.T) {
+	m := replanFixture(t)
+	m.storage = failingStorage{errors.New("连接断开")}
+	if _, err := m.applyPatch(context.Background(), PlanPatch{Cancel: []string{"todo-3"}}, "测试"); err == nil {
+		t.Fatalf("保存失败应返回错误")
+	}
+	if item(t, m, "todo-3").Status != "pending" || len(m.todos.Revisions) != 0 {
+		t.Errorf("保存失败后应恢复原清单")
+	}
+}
+
+func TestCancelWithSubtasks(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "开发")
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-1", "title": "后端"})
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-1", "title": "前端"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "status": "in_progress"})
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-2"})
+
+	revision, err := m.applyPatch(context.Background(), PlanPatch{Cancel: []string{"todo-1"}}, "需求变更")
+	if err != nil {
+		t.Fatalf("applyPatch: %v", err)
+	}
+	if revision.Summary != "取消 todo-1、todo-3" || item(t, m, "todo-2").Status != "completed" {
+		t.Errorf("已完成的子任务应保留，只取消未完成的: %q", revision.Summary)
+	}
+	// 父任务的状态按剩下的子任务重新推导
+	if got := item(t, m, "todo-1").Status; got != "completed" {
+		t.Errorf("父任务 = %s，want completed", got)
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{"纯 JSON", `{"reason":"r","cancel":["todo-5"]}`, []string{"todo-5"}, false},
+		{"代码块", "```json\n{\"cancel\": [\"todo-1\"]}\n```", []string{"todo-1"}, false},
+		{"说明文字中的 JSON", "修订如下：{\"cancel\":[\"todo-2\"]} 以上。", []string{"todo-2"}, false},
+		{"跳过空的对象", `示例 {"reason":"没有修改"} 实际：{"cancel":["todo-3"]}`, []string{"todo-3"}, false},
+		{"没有 JSON", "无法修订", nil, true},
+		{"只有空补丁", `{"reason":"x"}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := parsePatch(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("应返回错误，得到 %+v", patch)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(patch.Cancel, tt.want) {
+				t.Errorf("parsePatch = %+v, %v", patch, err)
+			}
+		})
+	}
+}
+
+func TestTodoListClone(t *testing.T) {
+	l := &TodoList{Items: []TodoItem{{ID: "a", DependsOn: []string{"b"}}}, Revisions: []PlanRevision{{Number: 1}}}
+	c := l.clone()
+	c.Items[0].DependsOn[0] = "x"
+	c.Items[0].Title = "改"
+	c.Revisions[0].Number = 9
+	if l.Items[0].DependsOn[0] != "b" || l.Items[0].Title != "" || l.Revisions[0].Number != 1 {
+		t.Errorf("clone 应是深拷贝: %+v", l)
+	}
+}
+
+// TestPlannerReplan: 重新规划链拿到目标、清单和原因，补丁应用后列表标题显示修订版本
+func TestPlannerReplan(t *testing.T) {
+	m := replanFixture(t)
+	m.todos.Goal = "开发待办应用"
+	var prompt string
+	llm := &scriptedModel{reply: func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+		prompt = input[len(input)-1].Content
+		return schema.AssistantMessage("```json\n{\"reason\": \"补充接口设计\", \"add\": [{\"title\": \"接口设计\"}], \"modify\": [{\"id\": \"todo-2\", \"depends_on\": [\"new-1\"]}]}\n```", nil), nil
+	}}
+	planner, err := NewPlannerTool(context.Background(), m, llm)
+	if err != nil {
+		t.Fatalf("NewPlannerTool: %v", err)
+	}
+	out, err := planner.InvokableRun(context.Background(), `{"action":"replan","reason":"todo-2 失败"}`)
+	if err != nil {
+		t.Fatalf("replan: %v", err)
+	}
+	if out != "计划已修订为第 1 版：新增 todo-4；修改 todo-2。说明：补充接口设计" {
+		t.Errorf("replan = %q", out)
+	}
+	for _, want := range []string{"整体目标：开发待办应用", "缺少接口设计", "重新规划的原因：todo-2 失败"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("规划链的输入缺少 %q:\n%s", want, prompt)
+		}
+	}
+	if list := mustTodo(t, m, map[string]any{"action": "list"}); !strings.Contains(list, "🔁 计划第 1 版（2024-03-09 09:00）：补充接口设计") {
+		t.Errorf("列表标题:\n%s", list)
+	}
+
+	llm.reply = func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+		return schema.AssistantMessage("没有需要修改的", nil), nil
+	}
+	if _, err := planner.Replan(context.Background(), "再看看"); err == nil {
+		t.Errorf("无法解析补丁时应返回错误")
+	}
+	noLLM, _ := NewPlannerTool(context.Background(), m, nil)
+	if _, err := noLLM.Replan(context.Background(), "x"); err == nil {
+		t.Errorf("没有规划链时应返回错误")
+	}
+}
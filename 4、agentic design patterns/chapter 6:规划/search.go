@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- 搜索和详情 ---
+
+// matchQuery: 标题或描述中包含 query（英文不区分大小写），query 为空时都匹配
+func matchQuery(item TodoItem, query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(item.Title), query) || strings.Contains(strings.ToLower(item.Description), query)
+}
+
+// searchTodos: 按关键词、状态、优先级查找任务，条件同时满足；返回紧凑的编号列表，比完整列表省上下文
+func (t *TodoManagerTool) searchTodos(query, status, priority string) (string, error) {
+	filter := todoFilter{query: query, priority: priority}
+	if status != "" {
+		var err error
+		if filter.status, err = parseStatus(status); err != nil {
+			return "", err
+		}
+	}
+
+	var conditions []string
+	if strings.TrimSpace(query) != "" {
+		conditions = append(conditions, fmt.Sprintf("关键词=%q", strings.TrimSpace(query)))
+	}
+	if filter.status != "" {
+		conditions = append(conditions, "status="+filter.status)
+	}
+	if priority != "" {
+		conditions = append(conditions, "priority="+priority)
+	}
+
+	now := t.now()
+	var sb strings.Builder
+	n := 0
+	for _, item := range t.todos.Items {
+		if !filter.match(item, now) {
+			continue
+		}
+		n++
+		line := fmt.Sprintf("%d. [%s] %s · %s", n, item.ID, item.Title, item.Status)
+		if icon, ok := priorityIcons[item.Priority]; ok {
+			line += " · " + icon
+		}
+		if item.ParentID != "" {
+			line += " · 父任务 " + item.ParentID
+		}
+		sb.WriteString(line + "\n")
+	}
+	if n == 0 {
+		return fmt.Sprintf("🔍 没有找到符合条件的任务（%s）", strings.Join(conditions, ", ")), nil
+	}
+	return fmt.Sprintf("🔍 找到 %d 个任务（%s）：\n%s", n, strings.Join(conditions, ", "), sb.String()), nil
+}
+
+// renderTask: 单个任务的完整信息：状态、时间、层级、依赖、结果或失败原因
+func (t *TodoManagerTool) renderTask(id string) (string, error) {
+	i := t.todos.index(id)
+	if i < 0 {
+		return "", fmt.Errorf("未找到任务: %s", id)
+	}
+	item := t.todos.Items[i]
+	now := t.now()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📌 [%s] %s\n", item.ID, item.Title))
+	field := func(label, value string) {
+		if value != "" {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", label, value))
+		}
+	}
+	field("描述", item.Description)
+	field("状态", item.Status)
+	if icon, ok := priorityIcons[item.Priority]; ok {
+		field("优先级", icon)
+	}
+	if item.EstimateHours > 0 {
+		field("估计工时", formatHours(item.EstimateHours))
+	}
+	if !item.Due.IsZero() {
+		due := formatDue(item.Due)
+		if overdue(item, now) {
+			due += "（已逾期）"
+		}
+		field("截止时间", due)
+	}
+	field("创建时间", item.CreatedAt.Format("2006-01-02 15:04:05"))
+	if !item.StartedAt.IsZero() {
+		field("开始时间", item.StartedAt.Format("2006-01-02 15:04:05"))
+	}
+	if !item.CompletedAt.IsZero() {
+		label := "完成时间"
+		if item.Status == "failed" {
+			label = "结束时间"
+		}
+		field(label, item.CompletedAt.Format("2006-01-02 15:04:05"))
+	}
+	if d, ok := taskDuration(item, now); ok {
+		field("用时", formatDuration(d))
+	}
+	if item.ToolCalls > 0 || item.Tokens > 0 {
+		field("执行用量", fmt.Sprintf("工具调用 %d 次，Token %d", item.ToolCalls, item.Tokens))
+	}
+	if parents := t.todos.ancestors(item); len(parents) > 0 {
+		field("父任务", formatTasks(parents[:1]))
+	}
+	if children := t.todos.children(item.ID); len(children) > 0 {
+		done, total := t.todos.progress(item.ID)
+		field("子任务", fmt.Sprintf("%s（完成 %d/%d）", formatTasks(children), done, total))
+	}
+	var deps []TodoItem
+	for _, dep := range item.DependsOn {
+		if j := t.todos.index(dep); j >= 0 {
+			deps = append(deps, t.todos.Items[j])
+		}
+	}
+	field("依赖", formatTasks(deps))
+	if !settled(item) {
+		field("等待", formatTasks(t.todos.blockers(item)))
+	}
+	field("结果", item.Result)
+	if item.Status == "failed" {
+		field("失败原因", item.Error)
+	}
+	return sb.String(), nil
+}
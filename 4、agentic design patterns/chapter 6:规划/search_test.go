@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchQuery(t *testing.T) {
+	item := TodoItem{Title: "Write API docs", Description: "覆盖所有接口的参数说明"}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"  ", true},
+		{"api", true},
+		{" DOCS ", true},
+		{"参数", true},
+		{"部署", false},
+	}
+	for _, tt := range tests {
+		if got := matchQuery(item, tt.query); got != tt.want {
+			t.Errorf("matchQuery(%q) = %v，期望 %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestTodoManagerSearch(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	mustTodo(t, m, map[string]any{"action": "add", "title": "设计接口", "priority": "high"})
+	mustTodo(t, m, map[string]any{"action": "add", "title": "编写接口文档", "description": "补充示例"})
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-2", "title": "补充错误码"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want []string // want: 结果中应出现的任务行，按顺序
+	}{
+		{"关键词", map[string]any{"query": "接口"}, []string{"1. [todo-1] 设计接口 · in_progress · 🔴 高", "2. [todo-2] 编写接口文档"}},
+		{"描述中的关键词", map[string]any{"query": "示例"}, []string{"1. [todo-2]"}},
+		{"状态", map[string]any{"status": "pending"}, []string{"1. [todo-2]", "2. [todo-3] 补充错误码 · pending · 父任务 todo-2"}},
+		{"关键词和优先级", map[string]any{"query": "接口", "priority": "high"}, []string{"1. [todo-1]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]any{"action": "search"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			out := mustTodo(t, m, args)
+			if !strings.HasPrefix(out, fmt.Sprintf("🔍 找到 %d 个任务", len(tt.want))) {
+				t.Fatalf("结果 = %q，期望找到 %d 个任务", out, len(tt.want))
+			}
+			rest := out
+			for _, line := range tt.want {
+				i := strings.Index(rest, line)
+				if i < 0 {
+					t.Fatalf("结果中缺少（或顺序不对）%q：\n%s", line, out)
+				}
+				rest = rest[i+len(line):]
+			}
+		})
+	}
+
+	out := mustTodo(t, m, map[string]any{"action": "search", "query": "部署", "status": "completed"})
+	if out != `🔍 没有找到符合条件的任务（关键词="部署", status=completed）` {
+		t.Errorf("没有结果时 = %q", out)
+	}
+	if _, err := todoRun(m, map[string]any{"action": "search", "status": "done"}); err == nil {
+		t.Error("无效的状态应返回错误")
+	}
+}
+
+func TestTodoManagerGet(t *testing.T) {
+	m, clock := newTestTodoManager(t, nil)
+	addTasks(t, m, "设计接口")
+	mustTodo(t, m, map[string]any{"action": "add", "title": "编写接口文档", "depends_on": []string{"todo-1"}, "estimate": "M"})
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-2", "title": "补充错误码"})
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	clock.advance(90 * time.Second)
+	mustTodo(t, m, map[string]any{"action": "fail", "id": "todo-1", "error": "需求未定"})
+
+	out := mustTodo(t, m, map[string]any{"action": "get", "id": "todo-1"})
+	for _, want := range []string{"📌 [todo-1] 设计接口", "状态: failed", "结束时间: 2024-03-09 09:01:30", "用时: 1m30s", "失败原因: 需求未定"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("todo-1 的详情缺少 %q：\n%s", want, out)
+		}
+	}
+
+	out = mustTodo(t, m, map[string]any{"action": "get", "id": "todo-2"})
+	for _, want := range []string{"子任务: todo-3（补充错误码，pending）（完成 0/1）", "依赖: todo-1（设计接口，failed）", "等待: todo-1", "估计工时: "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("todo-2 的详情缺少 %q：\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "结果:") || strings.Contains(out, "开始时间") {
+		t.Errorf("空字段不应出现在详情中：\n%s", out)
+	}
+
+	if out := mustTodo(t, m, map[string]any{"action": "get", "id": "todo-3"}); !strings.Contains(out, "父任务: todo-2（编写接口文档") {
+		t.Errorf("子任务的详情应给出父任务：\n%s", out)
+	}
+	if _, err := todoRun(m, map[string]any{"action": "get", "id": "todo-9"}); err == nil {
+		t.Error("不存在的任务应返回错误")
+	}
+}
+
+func TestTodoManagerListLimit(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "一", "二", "三", "四", "五")
+
+	out := mustTodo(t, m, map[string]any{"action": "list", "limit": 2})
+	if !strings.Contains(out, "[todo-2]") || strings.Contains(out, "[todo-3]") {
+		t.Errorf("limit=2 应只列出前两个任务：\n%s", out)
+	}
+	if !strings.Contains(out, "…以及其余 3 个任务") {
+		t.Errorf("应给出其余任务的数量：\n%s", out)
+	}
+	if out := mustTodo(t, m, map[string]any{"action": "list"}); !strings.Contains(out, "[todo-5]") || strings.Contains(out, "以及其余") {
+		t.Errorf("不传 limit 时应列出全部任务：\n%s", out)
+	}
+}
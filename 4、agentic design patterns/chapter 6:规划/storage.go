@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Storage: TodoList 的持久化存储，让待办清单跨进程、跨会话保留
+type Storage interface {
+	// Load: 读取已保存的清单，还没有保存过时返回空清单
+	// 内容损坏时备份原内容并返回空清单和 *CorruptError，调用方可以报告后继续使用空清单
+	Load(ctx context.Context) (*TodoList, error)
+	// Save: 整体保存清单
+	Save(ctx context.Context, todos *TodoList) error
+}
+
+// CorruptError: 已保存的内容无法解析，原内容已备份到 Backup
+type CorruptError struct {
+	Source string // Source: 损坏内容的位置（文件路径或 Redis 键）
+	Backup string // Backup: 备份位置，备份失败时为空
+	Err    error
+}
+
+func (e *CorruptError) Error() string {
+	if e.Backup == "" {
+		return fmt.Sprintf("Todo List %s 已损坏（备份失败）: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("Todo List %s 已损坏，原内容已备份到 %s: %v", e.Source, e.Backup, e.Err)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// decodeTodoList: 解析保存的 JSON，items 为 null 时视为空清单，没有 next_id 时按已有 ID 补上
+func decodeTodoList(data []byte) (*TodoList, error) {
+	var todos TodoList
+	if err := json.Unmarshal(data, &todos); err != nil {
+		return nil, err
+	}
+	if todos.Items == nil {
+		todos.Items = make([]TodoItem, 0)
+	}
+	todos.ensureNextID()
+	return &todos, nil
+}
+
+// backupSuffix: 损坏内容的备份后缀，带时间戳以免覆盖之前的备份
+func backupSuffix() string {
+	return ".corrupt-" + time.Now().Format("20060102-150405.000")
+}
+
+// --- JSON 文件存储 ---
+
+// FileStorage: 把清单保存为 JSON 文件；先写同目录下的临时文件再重命名，进程中途退出也不会留下写了一半的文件
+type FileStorage struct {
+	path string
+	mu   sync.Mutex // mu: 串行化同一存储上的读写，后一次保存不会与前一次交错
+}
+
+var _ Storage = (*FileStorage)(nil)
+
+// NewFileStorage: 使用 path 指定的 JSON 文件，文件可以不存在
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{path: path}
+}
+
+func (s *FileStorage) Load(ctx context.Context) (*TodoList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &TodoList{Items: make([]TodoItem, 0)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 Todo List 文件失败: %w", err)
+	}
+	todos, err := decodeTodoList(data)
+	if err == nil {
+		return todos, nil
+	}
+	corrupt := &CorruptError{Source: s.path, Err: err}
+	backup := s.path + backupSuffix()
+	if renameErr := os.Rename(s.path, backup); renameErr == nil {
+		corrupt.Backup = backup
+	}
+	return &TodoList{Items: make([]TodoItem, 0)}, corrupt
+}
+
+func (s *FileStorage) Save(ctx context.Context, todos *TodoList) error {
+	data, err := json.MarshalIndent(todos, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 Todo List 失败: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("创建 Todo List 目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后临时文件已不存在，删除失败可以忽略
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("保存 Todo List 文件失败: %w", err)
+	}
+	return nil
+}
+
+// --- Redis 存储 ---
+
+// RedisStorage: 把清单以 JSON 字符串保存在 Redis 的一个键中，连接方式与第 8 章的短期记忆相同
+type RedisStorage struct {
+	client *redis.Client
+	key    string
+	mu     sync.Mutex
+}
+
+var _ Storage = (*RedisStorage)(nil)
+
+// NewRedisStorage: 连接 Redis 并检查连接，清单保存在 key 中
+func NewRedisStorage(ctx context.Context, redisAddr, redisPassword string, db int, key string) (*RedisStorage, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: redisPassword,
+		DB:       db,
+	})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("无法连接到 Redis: %w", err)
+	}
+	return &RedisStorage{client: rdb, key: key}, nil
+}
+
+func (s *RedisStorage) Load(ctx context.Context) (*TodoList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.client.Get(ctx, s.key).Bytes()
+	if err == redis.Nil {
+		return &TodoList{Items: make([]TodoItem, 0)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("从 Redis 读取 Todo List 失败: %w", err)
+	}
+	todos, err := decodeTodoList(data)
+	if err == nil {
+		return todos, nil
+	}
+	corrupt := &CorruptError{Source: "redis:" + s.key, Err: err}
+	backup := s.key + backupSuffix()
+	if renameErr := s.client.Rename(ctx, s.key, backup).Err(); renameErr == nil {
+		corrupt.Backup = "redis:" + backup
+	}
+	return &TodoList{Items: make([]TodoItem, 0)}, corrupt
+}
+
+func (s *RedisStorage) Save(ctx context.Context, todos *TodoList) error {
+	data, err := json.Marshal(todos)
+	if err != nil {
+		return fmt.Errorf("序列化 Todo List 失败: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.client.Set(ctx, s.key, data, 0).Err(); err != nil {
+		return fmt.Errorf("保存 Todo List 到 Redis 失败: %w", err)
+	}
+	return nil
+}
+
+// Close: 关闭 Redis 连接
+func (s *RedisStorage) Close() error {
+	return s.client.Close()
+}
+
+// --- 内存存储 ---
+
+// MemoryStorage: 把清单序列化后保存在内存中，不依赖文件或 Redis；同一个实例可以交给先后两个 TodoManagerTool，
+// 模拟“下一次会话加载上一次的计划”，便于在单元测试中代替 RedisStorage
+type MemoryStorage struct {
+	data []byte
+	mu   sync.Mutex
+}
+
+var _ Storage = (*MemoryStorage)(nil)
+
+func (s *MemoryStorage) Load(ctx context.Context) (*TodoList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		return &TodoList{Items: make([]TodoItem, 0)}, nil
+	}
+	return decodeTodoList(s.data)
+}
+
+func (s *MemoryStorage) Save(ctx context.Context, todos *TodoList) error {
+	data, err := json.Marshal(todos)
+	if err != nil {
+		return fmt.Errorf("序列化 Todo List 失败: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	return nil
+}
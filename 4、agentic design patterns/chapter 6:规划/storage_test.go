@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStorageRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "todos.json")
+	s := NewFileStorage(path)
+	ctx := context.Background()
+
+	empty, err := s.Load(ctx)
+	if err != nil || len(empty.Items) != 0 || empty.Items == nil {
+		t.Fatalf("文件不存在时应返回空清单: %+v, %v", empty, err)
+	}
+
+	want := &TodoList{Items: []TodoItem{{ID: "todo-1", Title: "需求分析", Status: "pending"}}, NextID: 5, Goal: "开发应用"}
+	if err := s.Save(ctx, want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := s.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got.Items) != 1 || got.Items[0].Title != "需求分析" || got.NextID != 5 || got.Goal != "开发应用" {
+		t.Errorf("Load = %+v", got)
+	}
+
+	// 保存后目录中只有目标文件，没有残留的临时文件
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "todos.json" {
+		t.Errorf("目录内容 = %v", entries)
+	}
+}
+
+func TestFileStorageCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todos.json")
+	if err := os.WriteFile(path, []byte(`{"items": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	todos, err := NewFileStorage(path).Load(context.Background())
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) {
+		t.Fatalf("损坏的文件应返回 *CorruptError: %v", err)
+	}
+	if todos == nil || len(todos.Items) != 0 {
+		t.Errorf("损坏时应返回空清单: %+v", todos)
+	}
+	if !strings.HasPrefix(corrupt.Backup, path+".corrupt-") {
+		t.Errorf("备份位置 = %q", corrupt.Backup)
+	}
+	if data, err := os.ReadFile(corrupt.Backup); err != nil || string(data) != `{"items": [` {
+		t.Errorf("备份内容 = %q, %v", data, err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("损坏的文件应被移走: %v", err)
+	}
+
+	// TodoManagerTool 报告后从空清单开始
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewTodoManagerTool(context.Background(), NewFileStorage(path))
+	if err != nil || len(m.todos.Items) != 0 {
+		t.Errorf("内容损坏时应从空清单开始: %v", err)
+	}
+}
+
+func TestDecodeTodoList(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantItems  int
+		wantNextID int
+	}{
+		{"items 为 null", `{"items": null}`, 0, 1},
+		{"旧版本没有 next_id", `{"items": [{"id": "todo-2"}, {"id": "todo-7"}, {"id": "手工"}]}`, 3, 8},
+		{"保留 next_id", `{"items": [{"id": "todo-2"}], "next_id": 10}`, 1, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todos, err := decodeTodoList([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("decodeTodoList: %v", err)
+			}
+			if todos.Items == nil || len(todos.Items) != tt.wantItems || todos.NextID != tt.wantNextID {
+				t.Errorf("decodeTodoList = %d 个任务, next_id=%d，want %d, %d", len(todos.Items), todos.NextID, tt.wantItems, tt.wantNextID)
+			}
+		})
+	}
+	if _, err := decodeTodoList([]byte("[")); err == nil {
+		t.Errorf("无效的 JSON 应返回错误")
+	}
+}
+
+func TestMemoryStorageRoundTrip(t *testing.T) {
+	s := &MemoryStorage{}
+	ctx := context.Background()
+	if todos, err := s.Load(ctx); err != nil || len(todos.Items) != 0 {
+		t.Fatalf("空存储: %+v, %v", todos, err)
+	}
+	saved := &TodoList{Items: []TodoItem{{ID: "todo-1", Title: "A"}}, NextID: 2}
+	if err := s.Save(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+	saved.Items[0].Title = "保存后修改"
+	got, err := s.Load(ctx)
+	if err != nil || got.Items[0].Title != "A" {
+		t.Errorf("应保存序列化后的副本: %+v, %v", got, err)
+	}
+}
+
+// failingStorage: 读写都失败的存储
+type failingStorage struct{ err error }
+
+func (s failingStorage) Load(ctx context.Context) (*TodoList, error)     { return nil, s.err }
+func (s failingStorage) Save(ctx context.Context, todos *TodoList) error { return s.err }
+
+func TestTodoManagerStorageErrors(t *testing.T) {
+	boom := errors.New("磁盘已满")
+	if _, err := NewTodoManagerTool(context.Background(), failingStorage{boom}); !errors.Is(err, boom) {
+		t.Errorf("加载失败应返回错误: %v", err)
+	}
+
+	m, _ := newTestTodoManager(t, nil)
+	m.storage = failingStorage{boom}
+	if _, err := todoRun(m, map[string]any{"action": "add", "title": "A"}); !errors.Is(err, boom) || !strings.Contains(err.Error(), "保存 Todo List 失败") {
+		t.Errorf("保存失败应返回错误: %v", err)
+	}
+}
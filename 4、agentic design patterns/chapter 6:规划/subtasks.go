@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// --- 子任务 ---
+
+// children: 直接子任务，按清单中的顺序
+func (l *TodoList) children(id string) []TodoItem {
+	var children []TodoItem
+	for _, item := range l.Items {
+		if item.ParentID == id && item.ID != id {
+			children = append(children, item)
+		}
+	}
+	return children
+}
+
+// hasChildren: 任务是否有子任务；有子任务的任务状态由子任务推导
+func (l *TodoList) hasChildren(id string) bool {
+	return len(l.children(id)) > 0
+}
+
+// ancestors: 从父任务开始逐级向上的祖先任务；遇到不存在的父任务或（手工编辑造成的）环时停止
+func (l *TodoList) ancestors(item TodoItem) []TodoItem {
+	var chain []TodoItem
+	for item.ParentID != "" {
+		i := l.index(item.ParentID)
+		if i < 0 || slices.ContainsFunc(chain, func(a TodoItem) bool { return a.ID == item.ParentID }) {
+			break
+		}
+		item = l.Items[i]
+		chain = append(chain, item)
+	}
+	return chain
+}
+
+// descendants: 所有后代任务的 ID（深度优先）
+func (l *TodoList) descendants(id string) []string {
+	var ids []string
+	var walk func(id string)
+	walk = func(id string) {
+		for _, child := range l.children(id) {
+			if slices.Contains(ids, child.ID) {
+				continue // 手工编辑造成的环
+			}
+			ids = append(ids, child.ID)
+			walk(child.ID)
+		}
+	}
+	walk(id)
+	return ids
+}
+
+// roots: 顶层任务（没有父任务，或父任务已不在清单中）
+func (l *TodoList) roots() []TodoItem {
+	var roots []TodoItem
+	for _, item := range l.Items {
+		if item.ParentID == "" || l.index(item.ParentID) < 0 {
+			roots = append(roots, item)
+		}
+	}
+	return roots
+}
+
+// progress: 直接子任务的完成数和总数，已取消的子任务不计入
+func (l *TodoList) progress(id string) (done, total int) {
+	for _, child := range l.children(id) {
+		if child.Status == "cancelled" {
+			continue
+		}
+		total++
+		if child.Status == "completed" {
+			done++
+		}
+	}
+	return done, total
+}
+
+// derivedStatus: 由子任务推导父任务的状态：全部完成时为 completed，有子任务进行中时为 in_progress，
+// 否则有子任务失败时为 failed，已部分完成时为 in_progress，都未开始时为 pending；
+// 已取消的子任务不参与推导，全部取消时父任务也为 cancelled
+func derivedStatus(children []TodoItem) string {
+	children = slices.DeleteFunc(slices.Clone(children), func(child TodoItem) bool { return child.Status == "cancelled" })
+	if len(children) == 0 {
+		return "cancelled"
+	}
+	completed, failed := 0, 0
+	for _, child := range children {
+		switch child.Status {
+		case "completed":
+			completed++
+		case "failed":
+			failed++
+		case "in_progress":
+			return "in_progress"
+		}
+	}
+	if failed > 0 {
+		return "failed"
+	}
+	switch completed {
+	case len(children):
+		return "completed"
+	case 0:
+		return "pending"
+	default:
+		return "in_progress"
+	}
+}
+
+// rollUp: 子任务变化后，从 id 开始逐级向上重新推导父任务的状态；父任务开始时记录开始时间，
+// 变为完成时记录完成时间，不再完成时清除；返回状态有变化的父任务
+func (l *TodoList) rollUp(id string, now time.Time) []TodoItem {
+	var changed []TodoItem
+	for seen := map[string]bool{}; id != "" && !seen[id]; {
+		seen[id] = true
+		i := l.index(id)
+		if i < 0 {
+			break
+		}
+		children := l.children(id)
+		if len(children) == 0 {
+			break
+		}
+		parent := &l.Items[i]
+		status := derivedStatus(children)
+		if (status == "in_progress" || status == "completed") && parent.StartedAt.IsZero() {
+			parent.StartedAt = now
+		}
+		switch {
+		case status == "completed" && parent.Status != "completed":
+			parent.CompletedAt = now
+		case status != "completed":
+			parent.CompletedAt = time.Time{}
+		}
+		if parent.Status != status {
+			parent.Status = status
+			changed = append(changed, *parent)
+		}
+		id = parent.ParentID
+	}
+	return changed
+}
+
+// unfinishedChildrenMessage: 直接完成还有未完成子任务的父任务时的说明，作为工具结果交给 Agent
+func unfinishedChildrenMessage(id string, children []TodoItem) string {
+	var unfinished []TodoItem
+	for _, child := range children {
+		if !settled(child) {
+			unfinished = append(unfinished, child)
+		}
+	}
+	return fmt.Sprintf("无法完成任务 %s：它的状态由子任务决定，还有未完成的子任务：%s。请逐个完成这些子任务", id, formatTasks(unfinished))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDerivedStatus(t *testing.T) {
+	children := func(statuses ...string) []TodoItem {
+		items := make([]TodoItem, len(statuses))
+		for i, s := range statuses {
+			items[i] = TodoItem{Status: s}
+		}
+		return items
+	}
+	tests := []struct {
+		name     string
+		children []TodoItem
+		want     string
+	}{
+		{"都未开始", children("pending", "pending"), "pending"},
+		{"部分完成", children("completed", "pending"), "in_progress"},
+		{"有进行中", children("in_progress", "failed"), "in_progress"},
+		{"有失败", children("completed", "failed"), "failed"},
+		{"全部完成", children("completed", "completed"), "completed"},
+		{"已取消的不参与", children("completed", "cancelled"), "completed"},
+		{"全部取消", children("cancelled", "cancelled"), "cancelled"},
+	}
+	for _, tt := range tests {
+		if got := derivedStatus(tt.children); got != tt.want {
+			t.Errorf("%s: derivedStatus = %s，want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTodoListHierarchy(t *testing.T) {
+	l := &TodoList{Items: []TodoItem{
+		{ID: "1", Status: "pending"},
+		{ID: "1.1", ParentID: "1", Status: "completed"},
+		{ID: "1.2", ParentID: "1", Status: "cancelled"},
+		{ID: "1.1.1", ParentID: "1.1", Status: "completed"},
+		{ID: "孤儿", ParentID: "已删除"},
+		{ID: "环-a", ParentID: "环-b"},
+		{ID: "环-b", ParentID: "环-a"},
+	}}
+	if got := ids(l.children("1")); !slices.Equal(got, []string{"1.1", "1.2"}) {
+		t.Errorf("children = %v", got)
+	}
+	if got := l.descendants("1"); !slices.Equal(got, []string{"1.1", "1.1.1", "1.2"}) {
+		t.Errorf("descendants = %v", got)
+	}
+	if got := ids(l.ancestors(l.Items[3])); !slices.Equal(got, []string{"1.1", "1"}) {
+		t.Errorf("ancestors = %v", got)
+	}
+	if got := ids(l.roots()); !slices.Equal(got, []string{"1", "孤儿"}) {
+		t.Errorf("roots = %v", got)
+	}
+	if done, total := l.progress("1"); done != 1 || total != 1 {
+		t.Errorf("progress = %d/%d，want 1/1（已取消的不计入）", done, total)
+	}
+	// 手工编辑造成的环不会死循环
+	if got := ids(l.ancestors(l.Items[5])); len(got) != 2 {
+		t.Errorf("环上的 ancestors = %v", got)
+	}
+	if got := l.descendants("环-a"); len(got) != 2 {
+		t.Errorf("环上的 descendants = %v", got)
+	}
+}
+
+func TestRollUp(t *testing.T) {
+	start := time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC)
+	l := &TodoList{Items: []TodoItem{
+		{ID: "root", Status: "pending"},
+		{ID: "mid", ParentID: "root", Status: "pending"},
+		{ID: "leaf", ParentID: "mid", Status: "in_progress"},
+	}}
+	changed := l.rollUp("mid", start)
+	if got := ids(changed); !slices.Equal(got, []string{"mid", "root"}) {
+		t.Fatalf("状态变化的父任务 = %v", got)
+	}
+	if l.Items[0].Status != "in_progress" || !l.Items[0].StartedAt.Equal(start) {
+		t.Errorf("root = %+v", l.Items[0])
+	}
+
+	end := start.Add(time.Hour)
+	l.Items[2].Status = "completed"
+	l.rollUp("mid", end)
+	if l.Items[0].Status != "completed" || !l.Items[0].CompletedAt.Equal(end) || !l.Items[0].StartedAt.Equal(start) {
+		t.Errorf("全部完成后 root = %+v", l.Items[0])
+	}
+	if changed := l.rollUp("mid", end.Add(time.Hour)); len(changed) != 0 {
+		t.Errorf("状态没有变化时不应报告: %v", ids(changed))
+	}
+
+	l.Items[2].Status = "failed"
+	l.rollUp("mid", end)
+	if l.Items[0].Status != "failed" || !l.Items[0].CompletedAt.IsZero() {
+		t.Errorf("子任务失败后 root = %+v", l.Items[0])
+	}
+}
+
+func TestTodoManagerSubtasks(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "后端开发")
+	out := mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-1", "title": "设计表结构"})
+	if out != "子任务已添加: ID=todo-2, 标题=设计表结构, 父任务=todo-1" {
+		t.Errorf("add_subtask = %q", out)
+	}
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-1", "title": "实现接口"})
+	if _, err := todoRun(m, map[string]any{"action": "add_subtask", "parent_id": "todo-9", "title": "X"}); err == nil {
+		t.Errorf("父任务不存在时应返回错误")
+	}
+	if _, err := todoRun(m, map[string]any{"action": "add_subtask", "parent_id": "todo-1", "title": "X", "depends_on": []string{"todo-1"}}); err == nil {
+		t.Errorf("子任务不能依赖自己的父任务")
+	}
+
+	// 父任务的状态由子任务推导
+	if _, err := todoRun(m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"}); err == nil {
+		t.Errorf("有子任务的任务不能直接更新状态")
+	}
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-2", "status": "in_progress"})
+	if item(t, m, "todo-1").Status != "in_progress" {
+		t.Errorf("子任务开始后父任务应为进行中")
+	}
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-2"})
+	if out := mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1"}); !strings.Contains(out, "还有未完成的子任务：todo-3（实现接口，pending）") {
+		t.Errorf("完成有未完成子任务的父任务 = %q", out)
+	}
+
+	list := mustTodo(t, m, map[string]any{"action": "list"})
+	for _, want := range []string{"║ 1. 🔄 [todo-1] 后端开发 [1/2]", "║    1.1. ⏳ [todo-3] 实现接口", "║    1.2. ✅ [todo-2] 设计表结构"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("列表缺少 %q:\n%s", want, list)
+		}
+	}
+
+	if _, err := todoRun(m, map[string]any{"action": "delete", "id": "todo-1"}); err == nil || !strings.Contains(err.Error(), "force=true") {
+		t.Errorf("删除有子任务的任务应要求 force: %v", err)
+	}
+	out = mustTodo(t, m, map[string]any{"action": "delete", "id": "todo-1", "force": true})
+	if !strings.Contains(out, "子任务 todo-2、todo-3 已一并删除") || len(m.todos.Items) != 0 {
+		t.Errorf("force 删除 = %q，剩余 %d 个任务", out, len(m.todos.Items))
+	}
+}
+
+// TestSubtaskInheritsDependencies: 父任务等待的任务，子任务同样要等
+func TestSubtaskInheritsDependencies(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	addTasks(t, m, "需求分析")
+	mustTodo(t, m, map[string]any{"action": "add", "title": "开发", "depends_on": []string{"todo-1"}})
+	mustTodo(t, m, map[string]any{"action": "add_subtask", "parent_id": "todo-2", "title": "后端"})
+	if blocked := ids(m.todos.blockers(item(t, m, "todo-3"))); !slices.Equal(blocked, []string{"todo-1"}) {
+		t.Errorf("子任务的 blockers = %v", blocked)
+	}
+	order, _ := m.todos.executionOrder()
+	if got := ids(order); !slices.Equal(got, []string{"todo-1", "todo-3", "todo-2"}) {
+		t.Errorf("执行顺序 = %v，父任务应排在子任务之后", got)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- 执行摘要 ---
+
+// taskRun: 执行过的一个任务及其用时
+type taskRun struct {
+	item     TodoItem
+	duration time.Duration
+}
+
+// runSummary: 执行阶段的时间和资源汇总
+type runSummary struct {
+	runs      []taskRun     // runs: 按用时从长到短排列
+	total     time.Duration // total: 各任务用时之和
+	wall      time.Duration // wall: 从最早开始到最晚结束（或到现在）的时间，任务并行时小于 total
+	toolCalls int
+	tokens    int
+}
+
+// summarizeRuns: 汇总执行过（有开始时间）的任务；父任务的用时和用量由子任务体现，不重复统计
+func summarizeRuns(l *TodoList, now time.Time) runSummary {
+	var s runSummary
+	var first, last time.Time
+	for _, item := range l.Items {
+		if l.hasChildren(item.ID) {
+			continue
+		}
+		d, ok := taskDuration(item, now)
+		if !ok {
+			continue
+		}
+		s.runs = append(s.runs, taskRun{item: item, duration: d})
+		s.total += d
+		s.toolCalls += item.ToolCalls
+		s.tokens += item.Tokens
+
+		end := now
+		if item.Status != "in_progress" {
+			end = item.CompletedAt
+		}
+		if first.IsZero() || item.StartedAt.Before(first) {
+			first = item.StartedAt
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+	if !first.IsZero() {
+		s.wall = last.Sub(first)
+	}
+	slices.SortStableFunc(s.runs, func(a, b taskRun) int {
+		return cmp.Compare(b.duration, a.duration)
+	})
+	return s
+}
+
+// table: Markdown 表格，每个任务一行，最后是合计；没有用量数据的任务显示 -
+func (s runSummary) table() string {
+	count := func(n int) string {
+		if n == 0 {
+			return "-"
+		}
+		return fmt.Sprint(n)
+	}
+	var sb strings.Builder
+	sb.WriteString("| 任务 | 状态 | 用时 | 占比 | 工具调用 | Token |\n")
+	sb.WriteString("| --- | --- | ---: | ---: | ---: | ---: |\n")
+	for _, run := range s.runs {
+		share := 0.0
+		if s.total > 0 {
+			share = float64(run.duration) / float64(s.total) * 100
+		}
+		sb.WriteString(fmt.Sprintf("| [%s] %s | %s | %s | %.0f%% | %s | %s |\n",
+			run.item.ID, run.item.Title, statusLabels[run.item.Status], formatDuration(run.duration), share,
+			count(run.item.ToolCalls), count(run.item.Tokens)))
+	}
+	sb.WriteString(fmt.Sprintf("| **合计（%d 个任务）** | | %s | 100%% | %s | %s |\n",
+		len(s.runs), formatDuration(s.total), count(s.toolCalls), count(s.tokens)))
+	return sb.String()
+}
+
+// render: 执行摘要：表格和总耗时；还没有执行过任务时给出说明
+func (s runSummary) render() string {
+	if len(s.runs) == 0 {
+		return "（还没有执行过任务）\n"
+	}
+	return s.table() + fmt.Sprintf("\n总耗时 %s（从第一个任务开始到最后一个任务结束）\n", formatDuration(s.wall))
+}
+
+// renderSummary: summary 操作：清单中已执行任务的用时、工具调用和 Token 汇总
+func (t *TodoManagerTool) renderSummary() string {
+	return "⏱️ 执行摘要（按用时从长到短）\n\n" + summarizeRuns(t.todos, t.now()).render()
+}
+
+// addUsage: 累加 worker 执行任务的工具调用次数和 Token 数（重试时累计），与随后的 complete / fail 一起保存
+func (t *TodoManagerTool) addUsage(id string, toolCalls, tokens int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i := t.todos.index(id); i >= 0 {
+		t.todos.Items[i].ToolCalls += toolCalls
+		t.todos.Items[i].Tokens += tokens
+	}
+}
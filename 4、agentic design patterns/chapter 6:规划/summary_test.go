@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeRuns(t *testing.T) {
+	start := time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	l := &TodoList{Items: []TodoItem{
+		{ID: "todo-1", Status: "completed", StartedAt: at(0), CompletedAt: at(2), ToolCalls: 3, Tokens: 1200},
+		{ID: "todo-2", Status: "failed", StartedAt: at(1), CompletedAt: at(6), Tokens: 800},
+		{ID: "todo-3", Status: "pending"},
+		// 父任务的用时由子任务体现
+		{ID: "todo-4", Status: "in_progress", StartedAt: at(5), ToolCalls: 99},
+		{ID: "todo-5", Status: "in_progress", ParentID: "todo-4", StartedAt: at(7), ToolCalls: 1},
+	}}
+
+	s := summarizeRuns(l, at(10))
+	var order []string
+	for _, run := range s.runs {
+		order = append(order, run.item.ID)
+	}
+	if strings.Join(order, ",") != "todo-2,todo-5,todo-1" {
+		t.Errorf("应按用时从长到短排列，得到 %v", order)
+	}
+	if s.total != 10*time.Minute {
+		t.Errorf("total = %v，期望 10m", s.total)
+	}
+	// 从 todo-1 开始到现在（todo-5 仍在进行）
+	if s.wall != 10*time.Minute {
+		t.Errorf("wall = %v，期望 10m", s.wall)
+	}
+	if s.toolCalls != 4 || s.tokens != 2000 {
+		t.Errorf("工具调用 = %d，Token = %d，期望 4 和 2000", s.toolCalls, s.tokens)
+	}
+}
+
+func TestRunSummaryRender(t *testing.T) {
+	if got := (runSummary{}).render(); got != "（还没有执行过任务）\n" {
+		t.Errorf("没有任务时 = %q", got)
+	}
+
+	s := runSummary{
+		runs: []taskRun{
+			{item: TodoItem{ID: "todo-2", Title: "编写文档", Status: "completed", ToolCalls: 2, Tokens: 500}, duration: 3 * time.Minute},
+			{item: TodoItem{ID: "todo-1", Title: "设计接口", Status: "failed"}, duration: time.Minute},
+		},
+		total:     4 * time.Minute,
+		wall:      3 * time.Minute,
+		toolCalls: 2,
+		tokens:    500,
+	}
+	want := "| 任务 | 状态 | 用时 | 占比 | 工具调用 | Token |\n" +
+		"| --- | --- | ---: | ---: | ---: | ---: |\n" +
+		"| [todo-2] 编写文档 | ✅ 已完成 | 3m0s | 75% | 2 | 500 |\n" +
+		"| [todo-1] 设计接口 | ❌ 失败 | 1m0s | 25% | - | - |\n" +
+		"| **合计（2 个任务）** | | 4m0s | 100% | 2 | 500 |\n" +
+		"\n总耗时 3m0s（从第一个任务开始到最后一个任务结束）\n"
+	if got := s.render(); got != want {
+		t.Errorf("render =\n%s\n期望\n%s", got, want)
+	}
+}
+
+func TestTodoManagerSummary(t *testing.T) {
+	m, clock := newTestTodoManager(t, nil)
+	addTasks(t, m, "设计接口", "编写文档")
+	if out := mustTodo(t, m, map[string]any{"action": "summary"}); !strings.Contains(out, "还没有执行过任务") {
+		t.Errorf("还没有执行时 = %q", out)
+	}
+
+	mustTodo(t, m, map[string]any{"action": "update", "id": "todo-1", "status": "in_progress"})
+	clock.advance(2 * time.Minute)
+	m.addUsage("todo-1", 2, 300)
+	m.addUsage("todo-1", 1, 200) // 重试时累计
+	m.addUsage("todo-9", 1, 1)   // 不存在的任务忽略
+	mustTodo(t, m, map[string]any{"action": "complete", "id": "todo-1"})
+
+	out := mustTodo(t, m, map[string]any{"action": "summary"})
+	for _, want := range []string{"⏱️ 执行摘要（按用时从长到短）", "| [todo-1] 设计接口 | ✅ 已完成 | 2m0s | 100% | 3 | 500 |", "总耗时 2m0s"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("摘要中缺少 %q：\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "编写文档") {
+		t.Errorf("没有开始的任务不应出现在摘要中：\n%s", out)
+	}
+}
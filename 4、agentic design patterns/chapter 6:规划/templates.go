@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// --- 计划模板 ---
+
+// PlanTemplate: 可复用的计划：经常重复的同类工作（如“Web 功能开发”、“数据分析”）不必每次让 LLM 重新拆分；
+// goal 和任务的标题、描述、截止时间中可以使用占位符如 {feature_name}，实例化时替换为提供的变量
+type PlanTemplate struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Goal        string     `json:"goal"`
+	Tasks       []TaskSpec `json:"tasks"`
+}
+
+// placeholderPattern: 占位符 {name}，name 由字母、数字和下划线组成
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// builtinTemplates: 内置的模板，模板文件中的同名模板会覆盖它们
+var builtinTemplates = []PlanTemplate{
+	{
+		Name:        "web_feature",
+		Description: "开发一个 Web 功能：需求、接口设计、前后端实现、测试和上线",
+		Goal:        "为 {product} 开发“{feature_name}”功能",
+		Tasks: []TaskSpec{
+			{Title: "梳理“{feature_name}”的需求", Description: "明确 {product} 中“{feature_name}”的用户场景和验收标准", Priority: "high", Estimate: "M"},
+			{Title: "设计“{feature_name}”的接口和数据模型", DependsOn: []string{"new-1"}, Priority: "high", Estimate: "M"},
+			{Title: "实现“{feature_name}”的后端", DependsOn: []string{"new-2"}, Estimate: "L"},
+			{Title: "实现“{feature_name}”的前端页面", DependsOn: []string{"new-2"}, Estimate: "L"},
+			{Title: "测试“{feature_name}”", Description: "按验收标准覆盖主要流程和异常情况", DependsOn: []string{"new-3", "new-4"}, Estimate: "M"},
+			{Title: "上线“{feature_name}”", DependsOn: []string{"new-5"}, Priority: "low", Estimate: "S"},
+		},
+	},
+	{
+		Name:        "data_analysis",
+		Description: "数据分析：明确问题、获取和清洗数据、分析、写报告",
+		Goal:        "分析 {dataset}，回答：{question}",
+		Tasks: []TaskSpec{
+			{Title: "明确分析问题", Description: "把“{question}”拆成可以用数据回答的具体指标", Priority: "high", Estimate: "S"},
+			{Title: "获取 {dataset}", DependsOn: []string{"new-1"}, Estimate: "M"},
+			{Title: "清洗 {dataset}", Description: "处理缺失值、重复和异常数据", DependsOn: []string{"new-2"}, Estimate: "M"},
+			{Title: "分析数据", Description: "计算指标，回答“{question}”", DependsOn: []string{"new-3"}, Priority: "high", Estimate: "L"},
+			{Title: "撰写分析报告", DependsOn: []string{"new-4"}, Estimate: "M"},
+		},
+	},
+}
+
+// TemplateRegistry: 按名称查找计划模板
+type TemplateRegistry struct {
+	templates map[string]PlanTemplate
+}
+
+// NewTemplateRegistry: 创建包含内置模板的注册表；path 不为空时再加载其中的模板（JSON 或 YAML，按扩展名区分）
+func NewTemplateRegistry(path string) (*TemplateRegistry, error) {
+	r := &TemplateRegistry{templates: map[string]PlanTemplate{}}
+	for _, tpl := range builtinTemplates {
+		r.templates[tpl.Name] = tpl
+	}
+	if path == "" {
+		return r, nil
+	}
+	templates, err := loadTemplates(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, tpl := range templates {
+		r.templates[tpl.Name] = tpl
+	}
+	return r, nil
+}
+
+// loadTemplates: 读取模板文件，内容是模板的数组；YAML 先转为 JSON，两种格式使用相同的字段名
+func loadTemplates(path string) ([]PlanTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取模板文件失败: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("解析模板文件 %s 失败: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("解析模板文件 %s 失败: %w", path, err)
+		}
+	case ".json":
+	default:
+		return nil, fmt.Errorf("不支持的模板文件格式 %q（可选：.json、.yaml、.yml）", filepath.Ext(path))
+	}
+
+	var templates []PlanTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("解析模板文件 %s 失败: %w", path, err)
+	}
+	for i, tpl := range templates {
+		if strings.TrimSpace(tpl.Name) == "" {
+			return nil, fmt.Errorf("模板文件 %s 中第 %d 个模板没有名称", path, i+1)
+		}
+		if len(tpl.Tasks) == 0 {
+			return nil, fmt.Errorf("模板 %s 没有任务", tpl.Name)
+		}
+	}
+	return templates, nil
+}
+
+// Get: 按名称查找模板
+func (r *TemplateRegistry) Get(name string) (PlanTemplate, error) {
+	tpl, ok := r.templates[strings.TrimSpace(name)]
+	if !ok {
+		return PlanTemplate{}, fmt.Errorf("未找到模板 %q（可选：%s）", name, strings.Join(r.Names(), "、"))
+	}
+	return tpl, nil
+}
+
+// Names: 所有模板的名称，按字母顺序
+func (r *TemplateRegistry) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describe: 模板清单，放进 planner 的工具描述，让 Agent 知道有哪些模板、各需要哪些变量
+func (r *TemplateRegistry) describe() string {
+	lines := make([]string, 0, len(r.templates))
+	for _, name := range r.Names() {
+		tpl := r.templates[name]
+		lines = append(lines, fmt.Sprintf("%s（%s；变量：%s）", name, tpl.Description, strings.Join(tpl.placeholders(), "、")))
+	}
+	return strings.Join(lines, "；")
+}
+
+// placeholders: 模板中用到的所有占位符名称，按首次出现的顺序
+func (tpl PlanTemplate) placeholders() []string {
+	var names []string
+	collect := func(s string) {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+			if !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+			}
+		}
+	}
+	collect(tpl.Goal)
+	for _, task := range tpl.Tasks {
+		collect(task.Title)
+		collect(task.Description)
+		collect(task.Due)
+	}
+	return names
+}
+
+// Instantiate: 用变量替换模板中的占位符，得到目标和任务列表；缺少任何变量时返回错误并列出所有缺少的变量，不做部分替换
+func (tpl PlanTemplate) Instantiate(vars map[string]string) (goal string, tasks []TaskSpec, err error) {
+	var missing []string
+	for _, name := range tpl.placeholders() {
+		if strings.TrimSpace(vars[name]) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", nil, fmt.Errorf("模板 %s 缺少变量：%s", tpl.Name, strings.Join(missing, "、"))
+	}
+
+	fill := func(s string) string {
+		return placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+			return strings.TrimSpace(vars[m[1:len(m)-1]])
+		})
+	}
+	tasks = make([]TaskSpec, len(tpl.Tasks))
+	for i, task := range tpl.Tasks {
+		task.Title = fill(task.Title)
+		task.Description = fill(task.Description)
+		task.Due = fill(task.Due)
+		task.DependsOn = slices.Clone(task.DependsOn)
+		tasks[i] = task
+	}
+	return fill(tpl.Goal), tasks, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTemplateFile: 在临时目录中写一个模板文件
+func writeTemplateFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTemplateRegistryBuiltin(t *testing.T) {
+	r, err := NewTemplateRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := r.Names(); !slices.Equal(names, []string{"data_analysis", "web_feature"}) {
+		t.Errorf("内置模板 = %v", names)
+	}
+	tpl, err := r.Get(" web_feature ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tpl.placeholders(); !slices.Equal(got, []string{"product", "feature_name"}) {
+		t.Errorf("web_feature 的变量 = %v", got)
+	}
+	if _, err := r.Get("mobile_app"); err == nil || !strings.Contains(err.Error(), "data_analysis、web_feature") {
+		t.Errorf("未知模板的错误应列出可选模板，得到 %v", err)
+	}
+	if desc := r.describe(); !strings.Contains(desc, "data_analysis（数据分析：明确问题、获取和清洗数据、分析、写报告；变量：dataset、question）") {
+		t.Errorf("describe = %q", desc)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	const yamlTemplates = `
+- name: web_feature
+  description: 覆盖内置模板
+  goal: "修复 {bug}"
+  tasks:
+    - title: "复现 {bug}"
+      estimate: S
+    - title: 修复并回归
+      depends_on: [new-1]
+- name: release
+  goal: "发布 {version}"
+  tasks:
+    - title: "打标签 {version}"
+`
+	const jsonTemplates = `[{"name": "release", "goal": "发布 {version}", "tasks": [{"title": "打标签 {version}", "due": "{date}"}]}]`
+
+	for name, path := range map[string]string{
+		"YAML": writeTemplateFile(t, "templates.yaml", yamlTemplates),
+		"JSON": writeTemplateFile(t, "templates.json", jsonTemplates),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewTemplateRegistry(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Contains(r.Names(), "release") || !slices.Contains(r.Names(), "data_analysis") {
+				t.Errorf("应包含文件中的模板和内置模板，得到 %v", r.Names())
+			}
+		})
+	}
+
+	r, _ := NewTemplateRegistry(writeTemplateFile(t, "templates.yml", yamlTemplates))
+	tpl, _ := r.Get("web_feature")
+	if tpl.Description != "覆盖内置模板" || len(tpl.Tasks) != 2 || !slices.Equal(tpl.Tasks[1].DependsOn, []string{"new-1"}) {
+		t.Errorf("同名模板应覆盖内置模板，得到 %+v", tpl)
+	}
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{"格式不支持", "templates.toml", "", "不支持的模板文件格式"},
+		{"JSON 无效", "templates.json", `[{"name": `, "解析模板文件"},
+		{"YAML 无效", "templates.yaml", "- name: [", "解析模板文件"},
+		{"没有名称", "templates.json", `[{"goal": "g", "tasks": [{"title": "A"}]}]`, "第 1 个模板没有名称"},
+		{"没有任务", "templates.json", `[{"name": "empty", "goal": "g"}]`, "模板 empty 没有任务"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTemplateRegistry(writeTemplateFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误 = %v，应包含 %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := NewTemplateRegistry(filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "读取模板文件失败") {
+		t.Errorf("文件不存在时 = %v", err)
+	}
+}
+
+func TestTemplateInstantiate(t *testing.T) {
+	tpl := builtinTemplates[0]
+	goal, tasks, err := tpl.Instantiate(map[string]string{"product": "商城", "feature_name": " 用户登录 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if goal != "为 商城 开发“用户登录”功能" {
+		t.Errorf("目标 = %q", goal)
+	}
+	if tasks[0].Title != "梳理“用户登录”的需求" || tasks[0].Description != "明确 商城 中“用户登录”的用户场景和验收标准" {
+		t.Errorf("第一个任务 = %+v", tasks[0])
+	}
+	// 实例化得到的任务与模板互不影响
+	tasks[4].DependsOn[0] = "new-9"
+	if tpl.Tasks[4].DependsOn[0] != "new-3" || strings.Contains(tpl.Tasks[0].Title, "用户登录") {
+		t.Error("实例化不应修改模板")
+	}
+
+	_, _, err = tpl.Instantiate(map[string]string{"feature_name": "用户登录", "product": "  "})
+	if err == nil || !strings.Contains(err.Error(), "缺少变量：product") {
+		t.Errorf("空白变量应视为缺少，得到 %v", err)
+	}
+	_, _, err = tpl.Instantiate(nil)
+	if err == nil || !strings.Contains(err.Error(), "product、feature_name") {
+		t.Errorf("应列出所有缺少的变量，得到 %v", err)
+	}
+}
+
+func TestPlannerPlanFromTemplate(t *testing.T) {
+	m, _ := newTestTodoManager(t, nil)
+	planner, err := NewPlannerTool(context.Background(), m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := planner.InvokableRun(context.Background(), `{"action": "plan_from_template", "template": "data_analysis"}`); err == nil {
+		t.Error("未配置模板时应返回错误")
+	}
+
+	planner.templates, _ = NewTemplateRegistry("")
+	_, err = planner.InvokableRun(context.Background(), `{"action": "plan_from_template", "template": "data_analysis", "variables": {"dataset": "订单表"}}`)
+	if err == nil || !strings.Contains(err.Error(), "缺少变量：question") {
+		t.Fatalf("缺少变量时 = %v", err)
+	}
+	if len(m.todos.Items) != 0 {
+		t.Fatalf("缺少变量时不应修改清单，得到 %d 个任务", len(m.todos.Items))
+	}
+
+	out, err := planner.InvokableRun(context.Background(), `{"action": "plan_from_template", "template": "data_analysis",
+		"variables": {"dataset": "订单表", "question": "复购率为什么下降"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "规划完成：已生成 5 个任务：todo-1 明确分析问题、todo-2 获取 订单表") {
+		t.Errorf("plan_from_template = %q", out)
+	}
+	if m.todos.Goal != "分析 订单表，回答：复购率为什么下降" {
+		t.Errorf("目标 = %q", m.todos.Goal)
+	}
+	if got := item(t, m, "todo-5"); !slices.Equal(got.DependsOn, []string{"todo-4"}) || got.EstimateHours != 4 {
+		t.Errorf("todo-5 = %+v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"shared/stats"
+)
+
+// --- 按 Agent 统计 Token 用量和费用 ---
+
+// Price: 每百万 token 的价格（单位由调用方约定，如元或美元）
+type Price struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+// Cost: 按价格估算的费用
+func (p Price) Cost(u stats.Usage) float64 {
+	return (float64(u.PromptTokens)*p.PromptPerMillion + float64(u.CompletionTokens)*p.CompletionPerMillion) / 1e6
+}
+
+// PriceTable: 模型名称到价格的映射，没有列出的模型不估算费用
+type PriceTable map[string]Price
+
+// parsePrices: 解析 -prices 参数：逗号分隔的 model=提示价格:生成价格（每百万 token），空字符串表示不估算费用
+func parsePrices(s string) (PriceTable, error) {
+	prices := PriceTable{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		prompt, completion, ok2 := strings.Cut(value, ":")
+		if !ok || !ok2 || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("无效的价格 %q（格式：model=提示价格:生成价格）", item)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(prompt), 64)
+		if err != nil || p < 0 {
+			return nil, fmt.Errorf("无效的提示价格 %q", prompt)
+		}
+		c, err := strconv.ParseFloat(strings.TrimSpace(completion), 64)
+		if err != nil || c < 0 {
+			return nil, fmt.Errorf("无效的生成价格 %q", completion)
+		}
+		prices[strings.TrimSpace(name)] = Price{PromptPerMillion: p, CompletionPerMillion: c}
+	}
+	return prices, nil
+}
+
+// Budget: 单次运行的预算，0 表示不限制；超出后不再开始下一个阶段
+type Budget struct {
+	MaxTokens int     // MaxTokens: 所有 Agent 的 token 总数上限
+	MaxCost   float64 // MaxCost: 估算费用上限（只计算 PriceTable 中有价格的模型）
+}
+
+// AgentUsage: 一个 Agent 的累计用量
+type AgentUsage struct {
+	Agent  string
+	Model  string
+	Calls  int
+	Usage  stats.Usage
+	Cost   float64
+	Priced bool // Priced: 模型有价格，Cost 有效
+}
+
+// UsageLedger: 按 Agent 累计 token 用量和估算费用，并发安全；nil *UsageLedger 只统计单次调用、不累计
+type UsageLedger struct {
+	prices PriceTable
+	budget Budget
+
+	mu     sync.Mutex
+	order  []string // order: Agent 第一次调用的顺序，用于输出
+	agents map[string]*AgentUsage
+}
+
+// NewUsageLedger: 创建用量账本
+func NewUsageLedger(prices PriceTable, budget Budget) *UsageLedger {
+	return &UsageLedger{prices: prices, budget: budget, agents: map[string]*AgentUsage{}}
+}
+
+// track: 在上下文上挂一个用量累加器（模型需用 stats.CountingModel 包装），
+// 调用结束后执行返回的 done：把这次调用的用量记到 agent 名下，并返回该用量
+func (l *UsageLedger) track(ctx context.Context, agent, model string) (context.Context, func() stats.Usage) {
+	ctx, counter := stats.WithUsage(ctx)
+	return ctx, func() stats.Usage {
+		u := counter.Usage()
+		l.record(agent, model, u)
+		return u
+	}
+}
+
+// record: 累加一次调用的用量
+func (l *UsageLedger) record(agent, model string, u stats.Usage) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.agents[agent]
+	if !ok {
+		a = &AgentUsage{Agent: agent, Model: model}
+		l.agents[agent] = a
+		l.order = append(l.order, agent)
+	}
+	a.Calls++
+	a.Usage = a.Usage.Add(u)
+	if price, ok := l.prices[model]; ok {
+		a.Cost += price.Cost(u)
+		a.Priced = true
+	}
+}
+
+// Agents: 各 Agent 的累计用量，按第一次调用的顺序
+func (l *UsageLedger) Agents() []AgentUsage {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	agents := make([]AgentUsage, len(l.order))
+	for i, name := range l.order {
+		agents[i] = *l.agents[name]
+	}
+	return agents
+}
+
+// Total: 所有 Agent 的合计；priced 表示至少有一个 Agent 的模型有价格
+func (l *UsageLedger) Total() (usage stats.Usage, cost float64, priced bool) {
+	for _, a := range l.Agents() {
+		usage = usage.Add(a.Usage)
+		cost += a.Cost
+		priced = priced || a.Priced
+	}
+	return usage, cost, priced
+}
+
+// Exceeded: 累计用量是否已超出预算，超出时返回原因
+func (l *UsageLedger) Exceeded() (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	usage, cost, _ := l.Total()
+	if l.budget.MaxTokens > 0 && usage.Total() > l.budget.MaxTokens {
+		return fmt.Sprintf("token 用量 %d 超出预算 %d", usage.Total(), l.budget.MaxTokens), true
+	}
+	if l.budget.MaxCost > 0 && cost > l.budget.MaxCost {
+		return fmt.Sprintf("估算费用 %.4f 超出预算 %.4f", cost, l.budget.MaxCost), true
+	}
+	return "", false
+}
+
+// Print: 打印各 Agent 的用量和费用表及合计；没有价格的模型费用显示 -
+func (l *UsageLedger) Print(w io.Writer) {
+	cost := func(c float64, priced bool) string {
+		if !priced {
+			return "-"
+		}
+		return fmt.Sprintf("%.4f", c)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Agent\t模型\t调用\t提示 tokens\t生成 tokens\t合计 tokens\t估算费用")
+	for _, a := range l.Agents() {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", a.Agent, a.Model, a.Calls,
+			a.Usage.PromptTokens, a.Usage.CompletionTokens, a.Usage.Total(), cost(a.Cost, a.Priced))
+	}
+	usage, total, priced := l.Total()
+	fmt.Fprintf(tw, "总计\t\t\t%d\t%d\t%d\t%s\n", usage.PromptTokens, usage.CompletionTokens, usage.Total(), cost(total, priced))
+	tw.Flush()
+}
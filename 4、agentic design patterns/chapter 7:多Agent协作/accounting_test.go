@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"shared/stats"
+)
+
+func TestParsePrices(t *testing.T) {
+	prices, err := parsePrices(" gpt-4o = 2.5:10 , deepseek-chat=0.27:1.1,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PriceTable{"gpt-4o": {2.5, 10}, "deepseek-chat": {0.27, 1.1}}
+	if len(prices) != len(want) || prices["gpt-4o"] != want["gpt-4o"] || prices["deepseek-chat"] != want["deepseek-chat"] {
+		t.Errorf("parsePrices = %v", prices)
+	}
+	if prices, err := parsePrices(""); err != nil || len(prices) != 0 {
+		t.Errorf("空字符串 = %v, %v", prices, err)
+	}
+	for _, s := range []string{"gpt-4o", "gpt-4o=1", "=1:2", "gpt-4o=x:1", "gpt-4o=1:-2"} {
+		if _, err := parsePrices(s); err == nil {
+			t.Errorf("parsePrices(%q) 应返回错误", s)
+		}
+	}
+}
+
+func TestPriceCost(t *testing.T) {
+	got := Price{PromptPerMillion: 2, CompletionPerMillion: 8}.Cost(stats.Usage{PromptTokens: 500_000, CompletionTokens: 250_000})
+	if math.Abs(got-3) > 1e-9 {
+		t.Errorf("Cost = %v，期望 3", got)
+	}
+}
+
+func TestUsageLedger(t *testing.T) {
+	l := NewUsageLedger(PriceTable{"big": {PromptPerMillion: 1_000_000, CompletionPerMillion: 2_000_000}}, Budget{})
+	l.record("研究分析师", "small", stats.Usage{PromptTokens: 100, CompletionTokens: 50})
+	l.record("编辑", "big", stats.Usage{PromptTokens: 1, CompletionTokens: 1})
+	l.record("研究分析师", "small", stats.Usage{PromptTokens: 10, CompletionTokens: 5})
+
+	agents := l.Agents()
+	if len(agents) != 2 || agents[0].Agent != "研究分析师" || agents[0].Calls != 2 || agents[0].Usage.Total() != 165 || agents[0].Priced {
+		t.Fatalf("Agents = %+v", agents)
+	}
+	if agents[1].Cost != 3 || !agents[1].Priced {
+		t.Errorf("编辑的费用 = %+v", agents[1])
+	}
+	usage, cost, priced := l.Total()
+	if usage.Total() != 167 || cost != 3 || !priced {
+		t.Errorf("Total = %v, %v, %v", usage, cost, priced)
+	}
+
+	var out bytes.Buffer
+	l.Print(&out)
+	// tabwriter 按字符数对齐，与中文的显示宽度不一致，只比较各行的字段
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{"研究分析师 small 2 110 55 165 -", "编辑 big 1 1 1 2 3.0000", "总计 111 56 167 3.0000"}
+	if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("用量表 =\n%s", out.String())
+	}
+}
+
+func TestUsageLedgerExceeded(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget Budget
+		want   string
+	}{
+		{"不限制", Budget{}, ""},
+		{"token 未超出", Budget{MaxTokens: 2000}, ""},
+		{"token 超出", Budget{MaxTokens: 1000}, "token 用量 1500 超出预算 1000"},
+		{"费用超出", Budget{MaxCost: 0.001}, "估算费用 0.0015 超出预算 0.0010"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewUsageLedger(PriceTable{"m": {PromptPerMillion: 1, CompletionPerMillion: 1}}, tt.budget)
+			l.record("作家", "m", stats.Usage{PromptTokens: 1000, CompletionTokens: 500})
+			reason, exceeded := l.Exceeded()
+			if reason != tt.want || exceeded != (tt.want != "") {
+				t.Errorf("Exceeded = %q, %v，期望 %q", reason, exceeded, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilUsageLedger(t *testing.T) {
+	var l *UsageLedger
+	ctx, done := l.track(context.Background(), "作家", "m")
+	stats.AddUsage(ctx, stats.Usage{PromptTokens: 3, CompletionTokens: 4})
+	if u := done(); u.Total() != 7 {
+		t.Errorf("nil 账本仍应返回单次调用的用量，得到 %v", u)
+	}
+	if _, exceeded := l.Exceeded(); exceeded || len(l.Agents()) != 0 {
+		t.Error("nil 账本不累计、不超出预算")
+	}
+}
+
+// TestBlogTeamBudget: 预算用尽后不再开始之后的阶段，返回已有的结果
+func TestBlogTeamBudget(t *testing.T) {
+	llm := newTeamModel()
+	ledger := NewUsageLedger(nil, Budget{MaxTokens: 10})
+	state, out, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, Ledger: ledger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.StoppedReason != "token 用量 15 超出预算 10，未开始 research_subtopics" {
+		t.Errorf("StoppedReason = %q", state.StoppedReason)
+	}
+	if len(state.Subtopics) != 2 || state.ResearchBrief != "" || state.Draft != "" {
+		t.Errorf("应只保留拆分的结果，得到 %+v", state)
+	}
+	if n := len(llm.inputs(researchSystemPrompt)) + len(llm.inputs(writingSystemPrompt)); n != 0 {
+		t.Errorf("超出预算后仍调用了 %d 次模型", n)
+	}
+	if agents := ledger.Agents(); len(agents) != 1 || agents[0].Agent != "研究主管" || agents[0].Usage.Total() != 15 {
+		t.Errorf("账本 = %+v", agents)
+	}
+	if strings.Count(out, "⛔ 预算已用尽") != 1 {
+		t.Errorf("只应在第一个未开始的阶段说明一次：\n%s", out)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- 运行产物：研究报告、作家变体、每一版草稿、最终文章、协作记录和 run.json，与阶段检查点放在同一个运行目录 ---
+
+// WriteResearch: 写入研究简报 research.md，有结构化报告时还写入 research.json
+func (s *RunStore) WriteResearch(state CollaborationState) error {
+	if s == nil {
+		return nil
+	}
+	errs := []error{writeArtifact(filepath.Join(s.Dir, "research.md"), state.ResearchBrief)}
+	if state.Report != nil {
+		data, err := json.MarshalIndent(state.Report, "", "  ")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("序列化研究报告失败: %w", err))
+		} else {
+			errs = append(errs, writeArtifact(filepath.Join(s.Dir, "research.json"), string(data)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteDraft: 把作家的每一版草稿依次写入 draft-<n>.md（初稿为 1；继续运行时接在已有的草稿之后），返回版本号
+func (s *RunStore) WriteDraft(draft string) (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drafts++
+	return s.drafts, writeArtifact(filepath.Join(s.Dir, fmt.Sprintf("draft-%d.md", s.drafts)), draft)
+}
+
+// Drafts: 运行目录中的草稿版本数
+func (s *RunStore) Drafts() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.drafts
+}
+
+// countDrafts: 运行目录中已有的 draft-<n>.md 的最大版本号
+func countDrafts(dir string) int {
+	paths, _ := filepath.Glob(filepath.Join(dir, "draft-*.md"))
+	n := 0
+	for _, path := range paths {
+		var v int
+		if _, err := fmt.Sscanf(filepath.Base(path), "draft-%d.md", &v); err == nil {
+			n = max(n, v)
+		}
+	}
+	return n
+}
+
+// WriteVariants: 把作家变体写出的每篇初稿写入 variant-<index>.md（失败的变体没有文件），评分记录在 run.json
+func (s *RunStore) WriteVariants(variants []DraftVariant) error {
+	if s == nil {
+		return nil
+	}
+	var errs []error
+	for _, v := range variants {
+		if v.Error == "" {
+			errs = append(errs, writeArtifact(filepath.Join(s.Dir, fmt.Sprintf("variant-%d.md", v.Index)), v.Draft))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteFinal: 写入最终文章 final.md
+func (s *RunStore) WriteFinal(article string) error {
+	if s == nil {
+		return nil
+	}
+	return writeArtifact(filepath.Join(s.Dir, "final.md"), article)
+}
+
+// WriteRun: 写入 run.json
+func (s *RunStore) WriteRun(record runRecord) error {
+	if s == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 run.json 失败: %w", err)
+	}
+	return writeArtifact(filepath.Join(s.Dir, "run.json"), string(data))
+}
+
+// AppendMessage: 把一条总线消息追加到协作记录 transcript.jsonl
+func (s *RunStore) AppendMessage(msg AgentMessage) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(filepath.Join(s.Dir, "transcript.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开 transcript.jsonl 失败: %w", err)
+	}
+	if err := writeMessageJSONL(f, msg); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadMessages: 读取运行目录中的协作记录（继续运行时接着之前的消息），没有记录时返回空
+func (s *RunStore) LoadMessages() ([]AgentMessage, error) {
+	if s == nil {
+		return nil, nil
+	}
+	f, err := os.Open(filepath.Join(s.Dir, "transcript.jsonl"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开 transcript.jsonl 失败: %w", err)
+	}
+	defer f.Close()
+	messages, err := readMessagesJSONL(f)
+	if err != nil {
+		return nil, fmt.Errorf("读取 transcript.jsonl 失败: %w", err)
+	}
+	return messages, nil
+}
+
+// TruncateMessages: 只保留协作记录的前 n 条消息并重写 transcript.jsonl，返回保留的消息；
+// 继续运行时 n 为检查点保存时的消息数，丢弃失败的阶段已经发布的消息
+func (s *RunStore) TruncateMessages(n int) ([]AgentMessage, error) {
+	messages, err := s.LoadMessages()
+	if err != nil || len(messages) <= n {
+		return messages, err
+	}
+	messages = messages[:n]
+	var sb strings.Builder
+	for _, msg := range messages {
+		if err := writeMessageJSONL(&sb, msg); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.WriteFile(filepath.Join(s.Dir, "transcript.jsonl"), []byte(sb.String()), 0o644); err != nil {
+		return nil, fmt.Errorf("写入 transcript.jsonl 失败: %w", err)
+	}
+	return messages, nil
+}
+
+// recordStage: 记录一个阶段的耗时和错误，写入 run.json
+func (s *RunStore) recordStage(stage string, elapsed time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	record := stageRecord{Stage: stage, ElapsedMS: elapsed.Milliseconds()}
+	if err != nil {
+		record.Error = errorLine(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stages = append(s.stages, record)
+}
+
+// Stages: 本次进程中各阶段的执行记录，按执行顺序
+func (s *RunStore) Stages() []stageRecord {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]stageRecord(nil), s.stages...)
+}
+
+// writeArtifact: 写入一个产物文件，内容以换行结尾
+func writeArtifact(path, content string) error {
+	if err := os.WriteFile(path, []byte(strings.TrimRight(content, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// runRecord: run.json 的内容：研究问题、各角色的模型、各阶段耗时、各 Agent 的 token 用量和修订次数
+type runRecord struct {
+	Query         string            `json:"query"`
+	Mode          string            `json:"mode"` // Mode: pipeline（固定流程）或 supervisor（监督者模式）
+	ConfigHash    string            `json:"config_hash"`
+	ResumedFrom   string            `json:"resumed_from,omitempty"` // ResumedFrom: 用 -resume-from 继续运行时的起始阶段
+	Models        map[string]string `json:"models"`                 // Models: 角色到实际使用的模型名称
+	StartedAt     time.Time         `json:"started_at"`
+	ElapsedMS     int64             `json:"elapsed_ms"`
+	Stages        []stageRecord     `json:"stages"`
+	Agents        []agentRecord     `json:"agents"`
+	TotalTokens   int               `json:"total_tokens"`
+	Cost          float64           `json:"estimated_cost,omitempty"` // Cost: 按 -prices 估算的费用，没有价格时省略
+	Subtopics     int               `json:"subtopics"`
+	FactChecks    int               `json:"fact_checks"`
+	Review        *ReviewRecord     `json:"review,omitempty"`   // Review: 人工审核的决定和审核人的更正
+	Drafts        int               `json:"drafts"`             // Drafts: 运行目录中的草稿版本数（draft-<n>.md）
+	Messages      int               `json:"messages"`           // Messages: 总线上的消息数（transcript.jsonl）
+	Variants      []DraftVariant    `json:"variants,omitempty"` // Variants: 作家变体的评分和入选结果
+	Revisions     int               `json:"revisions"`
+	Approved      bool              `json:"approved"`
+	StoppedReason string            `json:"stopped_reason,omitempty"`
+	Error         string            `json:"error,omitempty"` // Error: 运行失败时的错误，已完成的阶段仍然记录
+}
+
+// stageRecord: 一个阶段的执行记录（监督者模式下同一阶段可能出现多次）
+type stageRecord struct {
+	Stage     string `json:"stage"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// agentRecord: 一个 Agent 的 token 用量
+type agentRecord struct {
+	Agent            string  `json:"agent"`
+	Model            string  `json:"model"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"estimated_cost,omitempty"`
+}
+
+// newRunRecord: 由配置、用量账本和（可能不完整的）结果生成 run.json 的内容
+func newRunRecord(opts TeamOptions, mode string, result CollaborationState, started time.Time, runErr error) runRecord {
+	record := runRecord{
+		Query:         result.Query,
+		Mode:          mode,
+		ResumedFrom:   opts.ResumeFrom,
+		Models:        roleModels(opts, mode),
+		StartedAt:     started,
+		ElapsedMS:     time.Since(started).Milliseconds(),
+		Stages:        opts.Store.Stages(),
+		Agents:        []agentRecord{},
+		Subtopics:     len(result.Subtopics),
+		FactChecks:    len(result.FactChecks),
+		Review:        result.Review,
+		Drafts:        opts.Store.Drafts(),
+		Messages:      len(opts.Bus.History()),
+		Variants:      result.Variants,
+		Revisions:     result.Revisions,
+		Approved:      result.Approved,
+		StoppedReason: result.StoppedReason,
+	}
+	if opts.Store != nil {
+		record.ConfigHash = opts.Store.ConfigHash
+	}
+	for _, a := range opts.Ledger.Agents() {
+		record.Agents = append(record.Agents, agentRecord{
+			Agent:            a.Agent,
+			Model:            a.Model,
+			Calls:            a.Calls,
+			PromptTokens:     a.Usage.PromptTokens,
+			CompletionTokens: a.Usage.CompletionTokens,
+			TotalTokens:      a.Usage.Total(),
+			Cost:             a.Cost,
+		})
+	}
+	usage, cost, _ := opts.Ledger.Total()
+	record.TotalTokens, record.Cost = usage.Total(), cost
+	if runErr != nil {
+		record.Error = runErr.Error()
+	}
+	return record
+}
+
+// roleModels: 本次运行用到的角色及其模型，未单独指定的角色使用默认模型
+func roleModels(opts TeamOptions, mode string) map[string]string {
+	model := func(name string) string {
+		if name == "" {
+			return opts.ModelName
+		}
+		return name
+	}
+	models := map[string]string{
+		"researcher": model(opts.Models.Researcher),
+		"writer":     model(opts.Models.Writer),
+		"editor":     model(opts.Models.Editor),
+	}
+	if opts.FactCheck > 0 {
+		models["fact_checker"] = model(opts.Models.FactChecker)
+	}
+	if mode == "supervisor" {
+		models["supervisor"] = model(opts.Models.Supervisor)
+	}
+	return models
+}
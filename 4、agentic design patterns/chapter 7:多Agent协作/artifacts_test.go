@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readArtifact: 读取运行目录中的产物文件
+func readArtifact(t *testing.T, s *RunStore, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteResearch(t *testing.T) {
+	s := newTestRunStore(t, "hash")
+	if err := s.WriteResearch(CollaborationState{ResearchBrief: "# 研究简报\n\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readArtifact(t, s, "research.md"); got != "# 研究简报\n" {
+		t.Errorf("research.md = %q，内容应以一个换行结尾", got)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, "research.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("没有结构化报告时不应写入 research.json: %v", err)
+	}
+
+	report := &ResearchReport{Trends: []Trend{{Name: "多模态", Summary: "概述", Evidence: stringList{"证据"}}}}
+	if err := s.WriteResearch(CollaborationState{ResearchBrief: "简报", Report: report}); err != nil {
+		t.Fatal(err)
+	}
+	var got ResearchReport
+	if err := json.Unmarshal([]byte(readArtifact(t, s, "research.json")), &got); err != nil || got.Trends[0].Evidence[0] != "证据" {
+		t.Errorf("research.json = %+v, %v", got, err)
+	}
+}
+
+func TestWriteDrafts(t *testing.T) {
+	s := newTestRunStore(t, "hash")
+	for i, draft := range []string{"初稿", "修订稿"} {
+		n, err := s.WriteDraft(draft)
+		if err != nil || n != i+1 {
+			t.Fatalf("WriteDraft = %d, %v", n, err)
+		}
+	}
+	if got := readArtifact(t, s, "draft-2.md"); got != "修订稿\n" {
+		t.Errorf("draft-2.md = %q", got)
+	}
+
+	// 继续运行时接在已有的草稿之后，不覆盖之前的版本
+	reopened, err := OpenRunStore(s.Dir, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Drafts() != 2 {
+		t.Errorf("重新打开后的草稿数 = %d", reopened.Drafts())
+	}
+	if n, _ := reopened.WriteDraft("第三版"); n != 3 || readArtifact(t, s, "draft-1.md") != "初稿\n" {
+		t.Errorf("继续运行的草稿版本 = %d", n)
+	}
+	os.WriteFile(filepath.Join(s.Dir, "draft-10.md"), nil, 0o644)
+	os.WriteFile(filepath.Join(s.Dir, "draft-x.md"), nil, 0o644)
+	if got := countDrafts(s.Dir); got != 10 {
+		t.Errorf("countDrafts = %d，应取最大的版本号", got)
+	}
+}
+
+func TestWriteVariantsAndFinal(t *testing.T) {
+	s := newTestRunStore(t, "hash")
+	variants := []DraftVariant{{Index: 1, Draft: "稳妥"}, {Index: 2, Error: "超时"}, {Index: 3, Draft: "大胆"}}
+	if err := s.WriteVariants(variants); err != nil {
+		t.Fatal(err)
+	}
+	if readArtifact(t, s, "variant-3.md") != "大胆\n" {
+		t.Error("variant-3.md 内容不对")
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, "variant-2.md")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("失败的变体不应有文件: %v", err)
+	}
+	if err := s.WriteFinal("最终文章"); err != nil || readArtifact(t, s, "final.md") != "最终文章\n" {
+		t.Errorf("final.md 写入失败: %v", err)
+	}
+}
+
+func TestTranscriptFile(t *testing.T) {
+	s := newTestRunStore(t, "hash")
+	if messages, err := s.LoadMessages(); err != nil || messages != nil {
+		t.Errorf("没有协作记录时 = %v, %v", messages, err)
+	}
+	at := time.Date(2024, 3, 9, 10, 30, 0, 0, time.UTC)
+	sent := []AgentMessage{
+		{Seq: 1, From: "研究主管", To: broadcast, Kind: KindBrief, Content: "研究问题：AI 趋势", Timestamp: at},
+		{Seq: 2, From: "编辑", To: "技术内容作家", Kind: KindNotes, Content: "补充例子\n多行", Tokens: 15, Timestamp: at},
+	}
+	for _, msg := range sent {
+		if err := s.AppendMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.LoadMessages()
+	if err != nil || len(got) != 2 || got[1] != sent[1] {
+		t.Errorf("LoadMessages = %+v, %v", got, err)
+	}
+
+	os.WriteFile(filepath.Join(s.Dir, "transcript.jsonl"), []byte("{\"seq\": 1}\nnot json\n"), 0o644)
+	if _, err := s.LoadMessages(); err == nil || !strings.Contains(err.Error(), "读取 transcript.jsonl 失败") {
+		t.Errorf("记录损坏时 = %v", err)
+	}
+}
+
+func TestNilRunStore(t *testing.T) {
+	var s *RunStore
+	if n, err := s.WriteDraft("草稿"); n != 0 || err != nil {
+		t.Errorf("WriteDraft = %d, %v", n, err)
+	}
+	if err := errors.Join(s.WriteResearch(CollaborationState{}), s.WriteVariants([]DraftVariant{{Index: 1}}), s.WriteFinal("文章"),
+		s.WriteRun(runRecord{}), s.AppendMessage(AgentMessage{})); err != nil {
+		t.Errorf("没有运行目录时不应写入，得到 %v", err)
+	}
+	s.recordStage("writer_agent", time.Second, nil)
+	if s.Drafts() != 0 || s.Stages() != nil {
+		t.Error("没有运行目录时不应记录")
+	}
+}
+
+// TestBlogTeamArtifacts: 运行团队后，运行目录中有研究简报、每一版草稿、检查点，run.json 记录配置、各阶段、用量和结果
+func TestBlogTeamArtifacts(t *testing.T) {
+	store := newTestRunStore(t, "hash")
+	llm := newTeamModel().on(editorSystemPrompt, "修改\n补充例子", "通过")
+	opts := TeamOptions{ModelName: "gpt-4o", Models: AgentModels{Writer: "gpt-4o"}, MaxRevisions: 2,
+		Store: store, Bus: NewMessageBus(), Ledger: NewUsageLedger(nil, Budget{})}
+	started := time.Now()
+	state, _, err := runTeam(t, llm, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readArtifact(t, store, "research.md"), "# 研究简报：AI 趋势") {
+		t.Error("research.md 应为研究简报")
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, "research.json")); err != nil {
+		t.Errorf("应保存结构化报告: %v", err)
+	}
+	if store.Drafts() != 2 {
+		t.Errorf("修订一次后应有 2 版草稿，得到 %d", store.Drafts())
+	}
+
+	opts.MaxSubtopics = 3
+	if err := store.WriteRun(newRunRecord(opts, "pipeline", state, started, nil)); err != nil {
+		t.Fatal(err)
+	}
+	var record runRecord
+	if err := json.Unmarshal([]byte(readArtifact(t, store, "run.json")), &record); err != nil {
+		t.Fatal(err)
+	}
+	var stages []string
+	for _, s := range record.Stages {
+		stages = append(stages, s.Stage)
+	}
+	if got := strings.Join(stages, ","); got != "decompose_query,research_subtopics,human_review,fact_check,writer_agent,editor_agent,writer_agent,editor_agent" {
+		t.Errorf("run.json 的阶段 = %s", got)
+	}
+	if record.Query != "AI 趋势" || record.Mode != "pipeline" || record.ConfigHash != "hash" || record.Models["writer"] != "gpt-4o" ||
+		record.Drafts != 2 || record.Revisions != 1 || !record.Approved || record.Messages != len(opts.Bus.History()) || record.TotalTokens != 7*15 || len(record.Agents) != 4 {
+		t.Errorf("run.json = %+v", record)
+	}
+	if _, ok := record.Models["fact_checker"]; ok {
+		t.Error("没有事实核查时不应记录核查员的模型")
+	}
+
+	failed := newRunRecord(opts, "pipeline", state, started, errors.New("阶段 writer_agent 失败"))
+	if failed.Error != "阶段 writer_agent 失败" {
+		t.Errorf("失败时应记录错误，得到 %q", failed.Error)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- 消息总线：Agent 之间的交接以类型化的消息发布和读取，整个协作过程可以按对话的形式回放 ---
+
+// MessageKind: 消息的类型
+type MessageKind string
+
+const (
+	KindBrief   MessageKind = "brief"   // KindBrief: 任务或研究简报（研究问题、子主题、合并后的研究简报）
+	KindDraft   MessageKind = "draft"   // KindDraft: 文章草稿
+	KindNotes   MessageKind = "notes"   // KindNotes: 修改意见或更正（编辑的修改意见、审核人的更正）
+	KindVerdict MessageKind = "verdict" // KindVerdict: 结论（核查结果、评审结果、编辑通过、审核决定、监督者的调度）
+)
+
+// broadcast: 发给团队所有成员的消息的收件人
+const broadcast = "全体"
+
+// AgentMessage: 一条 Agent 之间的消息
+type AgentMessage struct {
+	Seq       int         `json:"seq"` // Seq: 发布顺序（从 1 开始），继续运行时接在已有的消息之后
+	From      string      `json:"from"`
+	To        string      `json:"to"` // To: 收件人，broadcast 表示所有成员
+	Kind      MessageKind `json:"kind"`
+	Content   string      `json:"content"`
+	Tokens    int         `json:"tokens,omitempty"` // Tokens: 产生这条消息消耗的 token 数
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// MessageBus: 并发安全的消息总线：保存所有消息，并按发布顺序通知订阅者；nil *MessageBus 不保存消息
+type MessageBus struct {
+	mu          sync.Mutex
+	messages    []AgentMessage
+	subscribers map[int]func(AgentMessage)
+	nextID      int
+
+	deliver sync.Mutex // deliver: 保证订阅者按发布顺序收到消息，订阅者中可以再读取总线
+}
+
+// NewMessageBus: 创建消息总线，history 为之前的消息（继续运行时从 transcript.jsonl 读取）
+func NewMessageBus(history ...AgentMessage) *MessageBus {
+	return &MessageBus{messages: slices.Clone(history), subscribers: map[int]func(AgentMessage){}}
+}
+
+// Subscribe: 订阅之后发布的消息，返回取消订阅的函数；nil *MessageBus 不会发布消息，返回的函数什么也不做
+func (b *MessageBus) Subscribe(fn func(AgentMessage)) (cancel func()) {
+	if b == nil {
+		return func() {}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Publish: 发布一条消息（填写序号和时间戳）并通知订阅者，返回发布后的消息
+func (b *MessageBus) Publish(msg AgentMessage) AgentMessage {
+	if b == nil {
+		return msg
+	}
+	b.deliver.Lock()
+	defer b.deliver.Unlock()
+	b.mu.Lock()
+	msg.Seq = 1
+	if n := len(b.messages); n > 0 {
+		msg.Seq = b.messages[n-1].Seq + 1
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	b.messages = append(b.messages, msg)
+	ids := make([]int, 0, len(b.subscribers))
+	for id := range b.subscribers {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	subscribers := make([]func(AgentMessage), len(ids))
+	for i, id := range ids {
+		subscribers[i] = b.subscribers[id]
+	}
+	b.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(msg)
+	}
+	return msg
+}
+
+// History: 所有消息，按发布顺序
+func (b *MessageBus) History() []AgentMessage {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.messages)
+}
+
+// Len: 总线上的消息数
+func (b *MessageBus) Len() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.messages)
+}
+
+// Latest: 最近一条 from 发给 to（或发给所有成员）的消息；from 为空表示任意发件人，kinds 为空表示任意类型
+func (b *MessageBus) Latest(from, to string, kinds ...MessageKind) (AgentMessage, bool) {
+	if b == nil {
+		return AgentMessage{}, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.messages) - 1; i >= 0; i-- {
+		msg := b.messages[i]
+		if from != "" && msg.From != from {
+			continue
+		}
+		if msg.To != to && msg.To != broadcast {
+			continue
+		}
+		if len(kinds) > 0 && !slices.Contains(kinds, msg.Kind) {
+			continue
+		}
+		return msg, true
+	}
+	return AgentMessage{}, false
+}
+
+// Transcript: 对话形式的协作记录，每条消息一段，内容超过 maxRunes 时截断（<=0 表示不截断）
+func (b *MessageBus) Transcript(maxRunes int) string {
+	var sb strings.Builder
+	for _, msg := range b.History() {
+		sb.WriteString(formatMessageHeader(msg) + "\n")
+		content := strings.TrimSpace(msg.Content)
+		if maxRunes > 0 {
+			content = truncateRunes(content, maxRunes)
+		}
+		for _, line := range strings.Split(content, "\n") {
+			sb.WriteString("    " + line + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// formatMessageHeader: 消息的标题行：序号、时间、发件人 → 收件人、类型和 token 数
+func formatMessageHeader(msg AgentMessage) string {
+	header := fmt.Sprintf("[%02d %s] %s → %s（%s", msg.Seq, msg.Timestamp.Format("15:04:05"), msg.From, msg.To, msg.Kind)
+	if msg.Tokens > 0 {
+		header += fmt.Sprintf("，%d tokens", msg.Tokens)
+	}
+	return header + "）"
+}
+
+// truncateRunes: 保留换行地截断到 max 个字符
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "..."
+}
+
+// publish: 团队成员发布一条消息
+func (t *blogTeam) publish(from, to string, kind MessageKind, content string, tokens int) {
+	t.bus.Publish(AgentMessage{From: from, To: to, Kind: kind, Content: content, Tokens: tokens})
+}
+
+// receive: to 读取 from 发来的最近一条 kind 消息的内容；总线上没有时（如从没有消息记录的检查点继续）退回状态中的 fallback
+func (t *blogTeam) receive(from, to string, kind MessageKind, fallback string) string {
+	if msg, ok := t.bus.Latest(from, to, kind); ok {
+		return msg.Content
+	}
+	return fallback
+}
+
+// receiveNotes: to 读取 from 最近一条消息中的意见：最近一条是修改意见或更正时返回其内容，是结论（如通过、批准）时返回空；
+// 总线上没有时退回 fallback
+func (t *blogTeam) receiveNotes(from, to, fallback string) string {
+	msg, ok := t.bus.Latest(from, to)
+	if !ok {
+		return fallback
+	}
+	if msg.Kind != KindNotes {
+		return ""
+	}
+	return msg.Content
+}
+
+// writeMessageJSONL: 把一条消息写成 JSONL 的一行
+func writeMessageJSONL(w io.Writer, msg AgentMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入消息失败: %w", err)
+	}
+	return nil
+}
+
+// readMessagesJSONL: 读取 JSONL 格式的消息，跳过空行
+func readMessagesJSONL(r io.Reader) ([]AgentMessage, error) {
+	var messages []AgentMessage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var msg AgentMessage
+		if err := json.Unmarshal([]byte(text), &msg); err != nil {
+			return nil, fmt.Errorf("解析第 %d 条消息失败: %w", line, err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取消息失败: %w", err)
+	}
+	return messages, nil
+}
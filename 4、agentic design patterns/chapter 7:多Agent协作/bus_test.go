@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageBusPublish(t *testing.T) {
+	at := time.Date(2024, 3, 9, 10, 30, 0, 0, time.UTC)
+	bus := NewMessageBus(AgentMessage{Seq: 7, From: "用户", To: "研究主管", Kind: KindBrief, Content: "AI 趋势"})
+	first := bus.Publish(AgentMessage{From: "研究主管", To: "研究分析师 1", Kind: KindBrief, Content: "模型能力"})
+	second := bus.Publish(AgentMessage{From: "研究主管", To: "研究分析师 2", Kind: KindBrief, Content: "行业应用", Timestamp: at})
+	if first.Seq != 8 || second.Seq != 9 {
+		t.Errorf("序号应接在之前的消息之后，得到 %d、%d", first.Seq, second.Seq)
+	}
+	if first.Timestamp.IsZero() || !second.Timestamp.Equal(at) {
+		t.Errorf("应填写没有时间戳的消息，保留已有的时间戳，得到 %v、%v", first.Timestamp, second.Timestamp)
+	}
+	history := bus.History()
+	if len(history) != 3 || bus.Len() != 3 || history[2] != second {
+		t.Fatalf("History = %+v", history)
+	}
+	history[0].Content = "改动"
+	if bus.History()[0].Content != "AI 趋势" {
+		t.Error("History 应返回副本")
+	}
+	if got := NewMessageBus().Publish(AgentMessage{}); got.Seq != 1 {
+		t.Errorf("第一条消息的序号 = %d", got.Seq)
+	}
+}
+
+func TestMessageBusSubscribe(t *testing.T) {
+	bus := NewMessageBus()
+	var got []string
+	bus.Subscribe(func(msg AgentMessage) {
+		// 订阅者中可以读取总线，此时消息已经保存
+		got = append(got, fmt.Sprintf("a:%s:%d", msg.Content, bus.Len()))
+	})
+	cancel := bus.Subscribe(func(msg AgentMessage) { got = append(got, "b:"+msg.Content) })
+	bus.Publish(AgentMessage{Content: "1"})
+	cancel()
+	cancel()
+	bus.Publish(AgentMessage{Content: "2"})
+	if strings.Join(got, ",") != "a:1:1,b:1,a:2:2" {
+		t.Errorf("订阅者应按订阅顺序收到消息，取消后不再收到，得到 %v", got)
+	}
+}
+
+func TestNilMessageBus(t *testing.T) {
+	var bus *MessageBus
+	called := false
+	cancel := bus.Subscribe(func(AgentMessage) { called = true })
+	cancel()
+	msg := bus.Publish(AgentMessage{Content: "内容"})
+	if called || msg.Seq != 0 || msg.Content != "内容" {
+		t.Errorf("nil 总线不应发布消息，得到 %+v", msg)
+	}
+	if _, ok := bus.Latest("", "编辑"); ok || bus.History() != nil || bus.Len() != 0 || bus.Transcript(0) != "" {
+		t.Error("nil 总线没有消息")
+	}
+}
+
+func TestMessageBusLatest(t *testing.T) {
+	bus := NewMessageBus()
+	for _, msg := range []AgentMessage{
+		{From: "技术内容作家", To: "编辑", Kind: KindDraft, Content: "初稿"},
+		{From: "编辑", To: "技术内容作家", Kind: KindNotes, Content: "补充例子"},
+		{From: "审核人", To: broadcast, Kind: KindNotes, Content: "法案 2024 年生效"},
+		{From: "技术内容作家", To: "编辑", Kind: KindDraft, Content: "修订稿"},
+	} {
+		bus.Publish(msg)
+	}
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		kinds []MessageKind
+		want  string
+	}{
+		{"最近一条", "技术内容作家", "编辑", nil, "修订稿"},
+		{"包括发给所有成员的消息", "", "技术内容作家", nil, "法案 2024 年生效"},
+		{"按发件人", "编辑", "技术内容作家", nil, "补充例子"},
+		{"按类型", "", "编辑", []MessageKind{KindNotes}, "法案 2024 年生效"},
+		{"没有匹配的消息", "编辑", "技术内容作家", []MessageKind{KindVerdict}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := bus.Latest(tt.from, tt.to, tt.kinds...)
+			if msg.Content != tt.want || ok != (tt.want != "") {
+				t.Errorf("Latest = %q, %v，期望 %q", msg.Content, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	at := time.Date(2024, 3, 9, 10, 30, 5, 0, time.UTC)
+	bus := NewMessageBus()
+	bus.Publish(AgentMessage{From: "研究主管", To: "研究分析师 1", Kind: KindBrief, Content: "模型能力", Tokens: 15, Timestamp: at})
+	bus.Publish(AgentMessage{From: "编辑", To: "技术内容作家", Kind: KindNotes, Content: "\n补充一个具体的例子\n语气更轻松\n", Timestamp: at})
+	want := "[01 10:30:05] 研究主管 → 研究分析师 1（brief，15 tokens）\n    模型能力\n" +
+		"[02 10:30:05] 编辑 → 技术内容作家（notes）\n    补充一个具体的例子\n    语气更轻松"
+	if got := bus.Transcript(0); got != want {
+		t.Errorf("Transcript =\n%s\n期望\n%s", got, want)
+	}
+	if got := bus.Transcript(4); !strings.HasSuffix(got, "（notes）\n    补充一个...") {
+		t.Errorf("截断后的 Transcript =\n%s", got)
+	}
+}
+
+func TestMessageJSONL(t *testing.T) {
+	at := time.Date(2024, 3, 9, 10, 30, 0, 0, time.UTC)
+	sent := []AgentMessage{
+		{Seq: 1, From: "用户", To: "研究主管", Kind: KindBrief, Content: "AI 趋势", Timestamp: at},
+		{Seq: 2, From: "编辑", To: "技术内容作家", Kind: KindNotes, Content: "多行\n意见", Tokens: 15, Timestamp: at},
+	}
+	var buf bytes.Buffer
+	for _, msg := range sent {
+		if err := writeMessageJSONL(&buf, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("每条消息应占一行，得到 %d 行", n)
+	}
+	buf.WriteString("\n")
+	got, err := readMessagesJSONL(&buf)
+	if err != nil || len(got) != 2 || got[0] != sent[0] || got[1] != sent[1] {
+		t.Errorf("readMessagesJSONL = %+v, %v", got, err)
+	}
+	if _, err := readMessagesJSONL(strings.NewReader("{}\n{\n")); err == nil || !strings.Contains(err.Error(), "解析第 2 条消息失败") {
+		t.Errorf("消息损坏时 = %v", err)
+	}
+}
+
+func TestReceive(t *testing.T) {
+	team := &blogTeam{bus: NewMessageBus()}
+	if got := team.receive("研究团队", "技术内容作家", KindBrief, "状态中的简报"); got != "状态中的简报" {
+		t.Errorf("总线上没有消息时应退回状态，得到 %q", got)
+	}
+	if got := team.receiveNotes("编辑", "技术内容作家", "状态中的意见"); got != "状态中的意见" {
+		t.Errorf("总线上没有意见时应退回状态，得到 %q", got)
+	}
+	team.publish("研究团队", broadcast, KindBrief, "总线上的简报", 0)
+	team.publish("编辑", "技术内容作家", KindNotes, "补充例子", 15)
+	if got := team.receive("研究团队", "技术内容作家", KindBrief, "状态中的简报"); got != "总线上的简报" {
+		t.Errorf("receive = %q", got)
+	}
+	if got := team.receiveNotes("编辑", "技术内容作家", "状态中的意见"); got != "补充例子" {
+		t.Errorf("receiveNotes = %q", got)
+	}
+	// 最近一条是结论（通过）时没有意见
+	team.publish("编辑", "技术内容作家", KindVerdict, "通过", 15)
+	if got := team.receiveNotes("编辑", "技术内容作家", "状态中的意见"); got != "" {
+		t.Errorf("编辑通过后 receiveNotes = %q", got)
+	}
+}
+
+// messageFlow: 总线上各消息的 发件人→收件人（类型），用 | 分隔
+func messageFlow(messages []AgentMessage) string {
+	flow := make([]string, len(messages))
+	for i, msg := range messages {
+		flow[i] = msg.From + "→" + msg.To + "（" + string(msg.Kind) + "）"
+	}
+	return strings.Join(flow, "|")
+}
+
+// recordingBus: 与 main 相同，把每条消息追加到运行目录的 transcript.jsonl
+func recordingBus(t *testing.T, store *RunStore, history ...AgentMessage) *MessageBus {
+	t.Helper()
+	bus := NewMessageBus(history...)
+	bus.Subscribe(func(msg AgentMessage) {
+		if err := store.AppendMessage(msg); err != nil {
+			t.Error(err)
+		}
+	})
+	return bus
+}
+
+// TestBlogTeamMessages: 固定流程中 Agent 之间的交接都经过总线，协作记录可以按对话回放
+func TestBlogTeamMessages(t *testing.T) {
+	llm := newTeamModel().
+		on(decomposeSystemPrompt, `["模型能力"]`).
+		on(editorSystemPrompt, "修改\n补充例子", "通过")
+	store := newTestRunStore(t, "hash")
+	bus := recordingBus(t, store)
+	bus.Publish(AgentMessage{From: "用户", To: "研究主管", Kind: KindBrief, Content: "AI 趋势"})
+	state, _, err := runTeam(t, llm, TeamOptions{MaxRevisions: 2, Bus: bus, Store: store,
+		Review: NewReviewGate(&fakePrompter{decision: ReviewDecision{Action: ReviewApprove}}, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "用户→研究主管（brief）|研究主管→研究分析师 1（brief）|研究分析师 1→研究主管（brief）|研究团队→全体（brief）|" +
+		"审核人→全体（verdict）|技术内容作家→编辑（draft）|编辑→技术内容作家（notes）|技术内容作家→编辑（draft）|编辑→技术内容作家（verdict）"
+	history := bus.History()
+	if got := messageFlow(history); got != want {
+		t.Errorf("消息 =\n%s\n期望\n%s", got, want)
+	}
+	if history[2].Content != renderReport("模型能力", mustParseReport(t, testReport)) || history[3].Content != state.ResearchBrief {
+		t.Errorf("研究结果应作为消息交给研究主管和团队，得到 %q", history[2].Content)
+	}
+	if history[6].Content != "补充例子" || history[7].Content != state.Draft || history[8].Content != "通过" {
+		t.Errorf("修订过程的消息 = %+v", history[6:])
+	}
+	for i, msg := range history {
+		if msg.Seq != i+1 {
+			t.Errorf("第 %d 条消息的序号 = %d", i+1, msg.Seq)
+		}
+	}
+	if history[1].Tokens != 15 || history[6].Tokens != 15 || history[3].Tokens != 0 {
+		t.Errorf("消息应带上产生它的 token 数，得到 %d、%d、%d", history[1].Tokens, history[6].Tokens, history[3].Tokens)
+	}
+	if writes := llm.inputs(writingSystemPrompt); len(writes) != 2 || !strings.Contains(writes[1], "补充例子") {
+		t.Errorf("作家应从总线读到编辑的意见，得到 %q", writes)
+	}
+	saved, err := store.LoadMessages()
+	if err != nil || messageFlow(saved) != want {
+		t.Errorf("transcript.jsonl = %s, %v", messageFlow(saved), err)
+	}
+	checkpoints, err := loadCheckpoints(store.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counts []int
+	for _, cp := range checkpoints {
+		counts = append(counts, cp.Messages)
+	}
+	// decompose、research、review、fact_check、writer、editor、writer、editor 完成时总线上的消息数
+	if got, want := counts, []int{2, 4, 5, 5, 6, 7, 8, 9}; !slices.Equal(got, want) {
+		t.Errorf("检查点记录的消息数 = %v，期望 %v", got, want)
+	}
+}
+
+// TestBlogTeamResumeTranscript: 从较早的阶段继续时，协作记录截断到检查点保存时的消息，
+// 之后的消息（旧的草稿和编辑结论）不会被新的运行读到，序号接在保留的消息之后
+func TestBlogTeamResumeTranscript(t *testing.T) {
+	store := newTestRunStore(t, "hash")
+	bus := recordingBus(t, store)
+	bus.Publish(AgentMessage{From: "用户", To: "研究主管", Kind: KindBrief, Content: "AI 趋势"})
+	if _, _, err := runTeam(t, newTeamModel(), TeamOptions{MaxRevisions: 1, Bus: bus, Store: store}); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := OpenRunStore(store.Dir, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, messages, err := resumed.ResumeState("editor_agent", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := resumed.TruncateMessages(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != bus.Len()-1 || history[len(history)-1].Kind != KindDraft {
+		t.Fatalf("应截断到作家的草稿，保留 %s", messageFlow(history))
+	}
+
+	// 编辑这次提出修改意见：若保留了旧的“通过”，作家之后读到的意见会被它覆盖
+	llm := newTeamModel().on(editorSystemPrompt, "修改\n补充例子", "通过")
+	resumedBus := recordingBus(t, resumed, history...)
+	state, _, err := runTeamFrom(t, llm, TeamOptions{MaxRevisions: 1, Bus: resumedBus, Store: resumed, ResumeFrom: "editor_agent"}, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Approved || state.Revisions != 1 {
+		t.Errorf("继续运行的结果 = %+v", state)
+	}
+	saved, err := resumed.LoadMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := messageFlow(saved[len(history):]); got != "编辑→技术内容作家（notes）|技术内容作家→编辑（draft）|编辑→技术内容作家（verdict）" {
+		t.Errorf("继续运行后的消息 = %s", got)
+	}
+	for i, msg := range saved {
+		if msg.Seq != i+1 {
+			t.Fatalf("transcript.jsonl 中第 %d 条消息的序号 = %d", i+1, msg.Seq)
+		}
+	}
+
+	if kept, err := resumed.TruncateMessages(len(saved) + 5); err != nil || len(kept) != len(saved) {
+		t.Errorf("不需要截断时 = %d 条, %v", len(kept), err)
+	}
+	if kept, err := (*RunStore)(nil).TruncateMessages(0); err != nil || kept != nil {
+		t.Errorf("没有运行目录时 = %v, %v", kept, err)
+	}
+}
+
+// mustParseReport: 解析测试用的研究报告
+func mustParseReport(t *testing.T, content string) ResearchReport {
+	t.Helper()
+	report, err := parseResearchReport(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/retry"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// --- 阶段检查点：每个阶段完成后保存状态，失败后从失败的阶段继续 ---
+
+const (
+	checkpointVersion   = 2                 // checkpointVersion: 阶段检查点的格式版本，格式不兼容地变化时递增
+	defaultArtifactRoot = "outputs"         // defaultArtifactRoot: 运行目录的默认根目录
+	runDirPrefix        = "collab-"         // runDirPrefix: 每次运行的目录名前缀，后接时间戳
+	runDirLayout        = "20060102-150405" // runDirLayout: 目录名中的时间戳格式，按字典序即按时间排序
+	stagesDir           = "stages"          // stagesDir: 运行目录下保存阶段检查点的子目录
+)
+
+// teamStages: 固定流程中的阶段，按执行顺序
+var teamStages = []string{"decompose_query", "research_subtopics", "human_review", "fact_check", "writer_agent", "editor_agent"}
+
+// stagePredecessors: 从某个阶段继续时，可以提供输入状态的上一阶段（editor_agent 退回修订时也会进入 writer_agent）
+var stagePredecessors = map[string][]string{
+	"research_subtopics": {"decompose_query"},
+	"human_review":       {"research_subtopics"},
+	"fact_check":         {"human_review"},
+	"writer_agent":       {"fact_check", "editor_agent"},
+	"editor_agent":       {"writer_agent"},
+}
+
+// StageCheckpoint: 一个阶段完成后保存的状态，文件名为 stages/<序号>-<阶段>.json
+type StageCheckpoint struct {
+	Version    int                `json:"version"`
+	Seq        int                `json:"seq"` // Seq: 本次运行中的完成顺序，修订循环中同一阶段会保存多次
+	Stage      string             `json:"stage"`
+	ConfigHash string             `json:"config_hash"` // ConfigHash: 保存时团队配置（提示词、模型等）的哈希，见 teamConfigHash
+	SavedAt    time.Time          `json:"saved_at"`
+	Messages   int                `json:"messages"` // Messages: 保存时总线上的消息数，继续运行时协作记录截断到这里
+	State      CollaborationState `json:"state"`
+}
+
+// StageError: 某个阶段（重试用尽后）失败，带上运行目录；固定流程中第一个阶段之后的阶段提示如何从该阶段继续
+type StageError struct {
+	Stage  string
+	RunDir string // RunDir: 已保存检查点的运行目录，没有保存检查点时为空
+	Err    error
+}
+
+func (e *StageError) Error() string {
+	msg := fmt.Sprintf("阶段 %s 失败: %s", e.Stage, errorLine(e.Err))
+	if _, resumable := stagePredecessors[e.Stage]; resumable && e.RunDir != "" {
+		msg += fmt.Sprintf("；之前阶段的结果已保存到 %s，可用 -resume-from %s -resume-dir %s 从此处继续", e.RunDir, e.Stage, e.RunDir)
+	}
+	return msg
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// teamConfigHash: 影响各阶段输出的配置（提示词、各角色的模型、子主题数、核查条数、作家变体数）的哈希；
+// 从检查点继续时哈希不同说明之前阶段的结果来自不同的配置
+func teamConfigHash(opts TeamOptions) string {
+	config := struct {
+		Prompts      []string
+		ModelName    string
+		Models       AgentModels
+		MaxSubtopics int
+		FactCheck    int
+		Variants     int
+	}{
+		Prompts: []string{
+			decomposeSystemPrompt, researchSystemPrompt, researcherUserTemplate, reportRepairSystemPrompt, reportRepairUserTemplate,
+			claimExtractSystemPrompt, claimVerifySystemPrompt,
+			writingSystemPrompt, writingUserTemplate, editorSystemPrompt, editorUserTemplate,
+			judgeSystemPrompt, judgeUserTemplate,
+		},
+		ModelName:    opts.ModelName,
+		Models:       opts.Models,
+		MaxSubtopics: opts.MaxSubtopics,
+		FactCheck:    opts.FactCheck,
+		Variants:     opts.Variants,
+	}
+	data, _ := json.Marshal(config)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// RunStore: 一次运行的目录 <root>/collab-<时间戳>/，每个阶段完成后把状态写入 stages/，
+// 运行产物（研究报告、草稿、最终文章和 run.json）见 artifacts.go；nil *RunStore 不保存
+type RunStore struct {
+	Dir        string // Dir: 本次运行的目录
+	ConfigHash string
+
+	mu     sync.Mutex
+	seq    int
+	drafts int           // drafts: 已写入的草稿版本数
+	stages []stageRecord // stages: 本次进程中各阶段的执行记录
+}
+
+// NewRunStore: 在 root 下创建本次运行的目录（同一秒内重复创建时追加序号，不覆盖之前的运行）
+func NewRunStore(root string, now time.Time, configHash string) (*RunStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("创建运行根目录失败: %w", err)
+	}
+	base := filepath.Join(root, runDirPrefix+now.Format(runDirLayout))
+	dir := base
+	for n := 2; ; n++ {
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("创建运行目录失败: %w", err)
+		}
+		dir = fmt.Sprintf("%s-%d", base, n)
+	}
+	if err := os.Mkdir(filepath.Join(dir, stagesDir), 0o755); err != nil {
+		return nil, fmt.Errorf("创建检查点目录失败: %w", err)
+	}
+	return &RunStore{Dir: dir, ConfigHash: configHash}, nil
+}
+
+// OpenRunStore: 打开之前的运行目录继续运行，新的检查点序号和草稿版本号接在已有的之后
+func OpenRunStore(dir, configHash string) (*RunStore, error) {
+	checkpoints, err := loadCheckpoints(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &RunStore{Dir: dir, ConfigHash: configHash, drafts: countDrafts(dir)}
+	for _, cp := range checkpoints {
+		s.seq = max(s.seq, cp.Seq)
+	}
+	return s, nil
+}
+
+// latestRunDir: root 下最新的运行目录
+func latestRunDir(root string) (string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", fmt.Errorf("读取运行根目录失败: %w", err)
+	}
+	var runs []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), runDirPrefix) {
+			runs = append(runs, entry.Name())
+		}
+	}
+	if len(runs) == 0 {
+		return "", fmt.Errorf("%s 下没有之前的运行目录", root)
+	}
+	// 按时间戳排序，同一秒内的 -2、-3 后缀排在原目录之后
+	sort.Slice(runs, func(i, j int) bool {
+		si, ni := splitRunDir(runs[i])
+		sj, nj := splitRunDir(runs[j])
+		if si != sj {
+			return si < sj
+		}
+		return ni < nj
+	})
+	return filepath.Join(root, runs[len(runs)-1]), nil
+}
+
+// splitRunDir: 把运行目录名拆成时间戳和序号（无后缀时序号为 1）
+func splitRunDir(name string) (string, int) {
+	stamp := strings.TrimPrefix(name, runDirPrefix)
+	if len(stamp) <= len(runDirLayout) {
+		return stamp, 1
+	}
+	seq, err := strconv.Atoi(strings.TrimPrefix(stamp[len(runDirLayout):], "-"))
+	if err != nil {
+		return stamp, 1
+	}
+	return stamp[:len(runDirLayout)], seq
+}
+
+// Save: 写入阶段完成后的状态和此时总线上的消息数
+func (s *RunStore) Save(stage string, state CollaborationState, messages int) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	cp := StageCheckpoint{
+		Version:    checkpointVersion,
+		Seq:        s.seq,
+		Stage:      stage,
+		ConfigHash: s.ConfigHash,
+		SavedAt:    time.Now(),
+		Messages:   messages,
+		State:      state,
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化检查点失败: %w", err)
+	}
+	path := filepath.Join(s.Dir, stagesDir, fmt.Sprintf("%02d-%s.json", cp.Seq, stage))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("写入检查点 %s 失败: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// loadCheckpoints: 读取运行目录中的所有阶段检查点，按序号排序
+func loadCheckpoints(dir string) ([]StageCheckpoint, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, stagesDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("查找检查点失败: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, stagesDir)); err != nil {
+		return nil, fmt.Errorf("%s 不是运行目录: %w", dir, err)
+	}
+	var checkpoints []StageCheckpoint
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取检查点失败: %w", err)
+		}
+		var cp StageCheckpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			return nil, fmt.Errorf("解析检查点 %s 失败: %w", filepath.Base(path), err)
+		}
+		if cp.Version != checkpointVersion {
+			return nil, fmt.Errorf("不支持的检查点版本 %d（%s，当前版本 %d）", cp.Version, filepath.Base(path), checkpointVersion)
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Seq < checkpoints[j].Seq })
+	return checkpoints, nil
+}
+
+// ResumeState: 从 stage 继续时的输入状态：最近一次保存的上一阶段检查点，以及保存时总线上的消息数；
+// 检查点的配置哈希与当前配置不同时拒绝继续，除非 force
+func (s *RunStore) ResumeState(stage string, force bool) (CollaborationState, int, error) {
+	predecessors, ok := stagePredecessors[stage]
+	if !ok {
+		if stage == teamStages[0] {
+			return CollaborationState{}, 0, fmt.Errorf("%s 是第一个阶段，不需要继续，直接重新运行即可", stage)
+		}
+		return CollaborationState{}, 0, fmt.Errorf("未知的阶段 %q（可选：%s）", stage, strings.Join(teamStages[1:], "、"))
+	}
+	checkpoints, err := loadCheckpoints(s.Dir)
+	if err != nil {
+		return CollaborationState{}, 0, err
+	}
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		cp := checkpoints[i]
+		if !slices.Contains(predecessors, cp.Stage) {
+			continue
+		}
+		if cp.ConfigHash != s.ConfigHash && !force {
+			return CollaborationState{}, 0, fmt.Errorf("检查点 %02d-%s 来自不同的配置（提示词或模型已改变，哈希 %s ≠ 当前 %s），使用 -force 仍然继续",
+				cp.Seq, cp.Stage, cp.ConfigHash, s.ConfigHash)
+		}
+		return cp.State, cp.Messages, nil
+	}
+	return CollaborationState{}, 0, fmt.Errorf("%s 中没有 %s 的检查点，无法从 %s 继续", s.Dir, strings.Join(predecessors, " 或 "), stage)
+}
+
+// checkpointed: 包装一个阶段：成功后把状态和 bus 上的消息数写入运行目录，失败时返回带阶段名的 StageError
+func (s *RunStore) checkpointed(stage string, out *stageOutput, bus *MessageBus,
+	run func(context.Context, CollaborationState) (CollaborationState, error)) func(context.Context, CollaborationState) (CollaborationState, error) {
+	return func(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+		start := time.Now()
+		next, err := run(ctx, state)
+		s.recordStage(stage, time.Since(start), err)
+		if err != nil {
+			// Eino 遇到包含内部节点错误的错误时直接返回内部错误、丢弃外层的包装，
+			// 因此只保留错误的第一行，保证调用方能用 errors.As 取到 StageError
+			stageErr := &StageError{Stage: stage, Err: errors.New(errorLine(err))}
+			if s != nil {
+				stageErr.RunDir = s.Dir
+			}
+			return next, stageErr
+		}
+		if err := s.Save(stage, next, bus.Len()); err != nil {
+			out.printf("⚠️ %v\n", err)
+		}
+		return next, nil
+	}
+}
+
+// withRetry: 按 TeamOptions.Retry 重试一次 Agent 调用（如 429、5xx 等瞬时错误），重试过程写入输出
+func withRetry[T any](ctx context.Context, policy retry.Policy, out *stageOutput, agent string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var lastErr error
+	result, _, err := retry.Do(ctx, policy, func(ctx context.Context, attempt int) (T, error) {
+		if attempt > 1 {
+			out.printf("↻ %s 第 %d 次尝试（上次失败: %s）\n", agent, attempt, errorLine(lastErr))
+		}
+		result, err := fn(ctx)
+		lastErr = err
+		return result, err
+	})
+	return result, err
+}
+
+// runWithRetry: 带重试的 stageOutput.run
+func (t *blogTeam) runWithRetry(ctx context.Context, agent, banner string, chain compose.Runnable[map[string]any, *schema.Message],
+	input map[string]any) (*schema.Message, error) {
+	return withRetry(ctx, t.opts.Retry, t.out, agent, func(ctx context.Context) (*schema.Message, error) {
+		return t.out.run(ctx, banner, chain, input)
+	})
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shared/retry"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// newTestRunStore: 在临时目录中创建运行目录
+func newTestRunStore(t *testing.T, configHash string) *RunStore {
+	t.Helper()
+	s, err := NewRunStore(t.TempDir(), time.Date(2024, 3, 9, 10, 30, 0, 0, time.UTC), configHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewRunStore(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2024, 3, 9, 10, 30, 0, 0, time.UTC)
+	var dirs []string
+	for i := 0; i < 3; i++ {
+		s, err := NewRunStore(root, now, "hash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, filepath.Base(s.Dir))
+		if _, err := os.Stat(filepath.Join(s.Dir, stagesDir)); err != nil {
+			t.Errorf("应创建检查点目录: %v", err)
+		}
+	}
+	if got := strings.Join(dirs, ","); got != "collab-20240309-103000,collab-20240309-103000-2,collab-20240309-103000-3" {
+		t.Errorf("同一秒内的运行目录 = %s", got)
+	}
+}
+
+func TestLatestRunDir(t *testing.T) {
+	root := t.TempDir()
+	if _, err := latestRunDir(root); err == nil {
+		t.Error("没有运行目录时应返回错误")
+	}
+	for _, name := range []string{"collab-20240309-103000", "collab-20240309-103000-10", "collab-20240309-103000-2", "collab-20240308-235959", "other"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := latestRunDir(root)
+	if err != nil || filepath.Base(got) != "collab-20240309-103000-10" {
+		t.Errorf("latestRunDir = %s, %v，-10 应排在 -2 之后", got, err)
+	}
+}
+
+func TestSaveAndResumeState(t *testing.T) {
+	s := newTestRunStore(t, "hash-a")
+	for _, cp := range []struct {
+		stage string
+		draft string
+	}{
+		{"decompose_query", ""},
+		{"research_subtopics", ""},
+		{"human_review", ""},
+		{"fact_check", ""},
+		{"writer_agent", "初稿"},
+		{"editor_agent", "初稿（有修改意见）"},
+	} {
+		if err := s.Save(cp.stage, CollaborationState{Query: "AI 趋势", Draft: cp.draft}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, stagesDir, "06-editor_agent.json")); err != nil {
+		t.Errorf("检查点文件名应为 <序号>-<阶段>.json: %v", err)
+	}
+
+	// 退回修订时 writer_agent 的输入是最近的 editor_agent 检查点
+	state, _, err := s.ResumeState("writer_agent", false)
+	if err != nil || state.Draft != "初稿（有修改意见）" {
+		t.Errorf("ResumeState(writer_agent) = %q, %v", state.Draft, err)
+	}
+	if state, _, err := s.ResumeState("editor_agent", false); err != nil || state.Draft != "初稿" {
+		t.Errorf("ResumeState(editor_agent) = %q, %v", state.Draft, err)
+	}
+
+	tests := []struct {
+		stage string
+		want  string
+	}{
+		{"decompose_query", "是第一个阶段，不需要继续"},
+		{"publish", `未知的阶段 "publish"`},
+	}
+	for _, tt := range tests {
+		if _, _, err := s.ResumeState(tt.stage, false); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ResumeState(%s) = %v，应包含 %q", tt.stage, err, tt.want)
+		}
+	}
+
+	// 配置改变后拒绝继续，除非 force；继续运行的序号接在已有的之后
+	reopened, err := OpenRunStore(s.Dir, "hash-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := reopened.ResumeState("writer_agent", false); err == nil || !strings.Contains(err.Error(), "来自不同的配置") {
+		t.Errorf("配置不同时 = %v", err)
+	}
+	if _, _, err := reopened.ResumeState("writer_agent", true); err != nil {
+		t.Errorf("-force 时 = %v", err)
+	}
+	if err := reopened.Save("writer_agent", CollaborationState{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, stagesDir, "07-writer_agent.json")); err != nil {
+		t.Errorf("继续运行的检查点序号应接在已有的之后: %v", err)
+	}
+}
+
+func TestResumeStateMissingCheckpoint(t *testing.T) {
+	s := newTestRunStore(t, "hash")
+	if err := s.Save("decompose_query", CollaborationState{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.ResumeState("editor_agent", false); err == nil || !strings.Contains(err.Error(), "没有 writer_agent 的检查点") {
+		t.Errorf("没有上一阶段的检查点时 = %v", err)
+	}
+
+	data, _ := json.Marshal(StageCheckpoint{Version: checkpointVersion + 1, Seq: 2, Stage: "research_subtopics"})
+	if err := os.WriteFile(filepath.Join(s.Dir, stagesDir, "02-research_subtopics.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenRunStore(s.Dir, "hash"); err == nil || !strings.Contains(err.Error(), "不支持的检查点版本") {
+		t.Errorf("版本不兼容时 = %v", err)
+	}
+	if _, err := OpenRunStore(t.TempDir(), "hash"); err == nil || !strings.Contains(err.Error(), "不是运行目录") {
+		t.Errorf("不是运行目录时 = %v", err)
+	}
+}
+
+func TestStageError(t *testing.T) {
+	err := &StageError{Stage: "writer_agent", RunDir: "outputs/collab-1", Err: errors.New("429\n节点路径")}
+	want := "阶段 writer_agent 失败: 429；之前阶段的结果已保存到 outputs/collab-1，可用 -resume-from writer_agent -resume-dir outputs/collab-1 从此处继续"
+	if err.Error() != want {
+		t.Errorf("Error = %q", err.Error())
+	}
+	// 第一个阶段和没有保存检查点时不提示继续
+	for _, e := range []*StageError{{Stage: "decompose_query", RunDir: "d", Err: errors.New("x")}, {Stage: "writer_agent", Err: errors.New("x")}} {
+		if strings.Contains(e.Error(), "-resume-from") {
+			t.Errorf("不应提示继续: %q", e.Error())
+		}
+	}
+}
+
+func TestTeamConfigHash(t *testing.T) {
+	base := TeamOptions{ModelName: "gpt-4o", MaxSubtopics: 3}
+	if teamConfigHash(base) != teamConfigHash(TeamOptions{ModelName: "gpt-4o", MaxSubtopics: 3, MaxRevisions: 5}) {
+		t.Error("不影响阶段输出的配置（如修订次数）不应改变哈希")
+	}
+	for _, changed := range []TeamOptions{
+		{ModelName: "gpt-4o-mini", MaxSubtopics: 3},
+		{ModelName: "gpt-4o", MaxSubtopics: 4},
+		{ModelName: "gpt-4o", MaxSubtopics: 3, Models: AgentModels{Writer: "small"}},
+		{ModelName: "gpt-4o", MaxSubtopics: 3, Variants: 2},
+	} {
+		if teamConfigHash(changed) == teamConfigHash(base) {
+			t.Errorf("配置 %+v 应改变哈希", changed)
+		}
+	}
+}
+
+// flakyModel: 前 failures 次调用 prompt 角色时返回 503，之后交给 scriptedModel
+type flakyModel struct {
+	*scriptedModel
+	prompt   string
+	mu       sync.Mutex
+	failures int
+}
+
+func (m *flakyModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.mu.Lock()
+	fail := m.failures > 0 && promptKey(in[0].Content) == promptKey(m.prompt)
+	if fail {
+		m.failures--
+	}
+	m.mu.Unlock()
+	if fail {
+		return nil, errors.New("503 Service Unavailable")
+	}
+	return m.scriptedModel.Generate(ctx, in, opts...)
+}
+
+func TestBlogTeamRetry(t *testing.T) {
+	llm := &flakyModel{scriptedModel: newTeamModel(), prompt: writingSystemPrompt, failures: 2}
+	policy := retry.Policy{MaxAttempts: 3, Sleep: func(ctx context.Context, d time.Duration) error { return nil }}
+	state, out, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, Retry: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Draft != "初稿" || !strings.Contains(out, "↻ 技术内容作家 第 3 次尝试（上次失败: [NodeRunError] 503 Service Unavailable）") {
+		t.Errorf("草稿 = %q，输出：\n%s", state.Draft, out)
+	}
+}
+
+// TestBlogTeamResume: 作家失败后返回 StageError，之前的阶段已保存；从 writer_agent 继续时不再调用之前的阶段
+func TestBlogTeamResume(t *testing.T) {
+	store := newTestRunStore(t, "hash")
+	llm := newTeamModel().failOn(writingSystemPrompt, errors.New("401 Unauthorized"))
+	_, _, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, Store: store})
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "writer_agent" || stageErr.RunDir != store.Dir {
+		t.Fatalf("应返回 writer_agent 的 StageError，得到 %v", err)
+	}
+	checkpoints, err := loadCheckpoints(store.Dir)
+	if err != nil || len(checkpoints) != 4 || checkpoints[3].Stage != "fact_check" {
+		t.Fatalf("检查点 = %+v, %v", checkpoints, err)
+	}
+
+	resumed, err := OpenRunStore(store.Dir, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, _, err := resumed.ResumeState("writer_agent", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm = newTeamModel()
+	state, _, err := runTeamFrom(t, llm, TeamOptions{MaxRevisions: 1, Store: resumed, ResumeFrom: "writer_agent"}, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Approved || state.Draft != "初稿" || len(state.Subtopics) != 2 {
+		t.Errorf("继续运行的结果 = %+v", state)
+	}
+	if n := len(llm.inputs(decomposeSystemPrompt)) + len(llm.inputs(researchSystemPrompt)); n != 0 {
+		t.Errorf("继续运行时不应重新执行之前的阶段，调用了 %d 次", n)
+	}
+
+	if _, err := buildBlogTeam(context.Background(), llm, TeamOptions{ResumeFrom: "publish"}); err == nil || !strings.Contains(err.Error(), "未知的阶段") {
+		t.Errorf("未知的阶段 = %v", err)
+	}
+}
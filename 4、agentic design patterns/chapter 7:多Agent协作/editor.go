@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ========== 第三个 Agent：编辑 ==========
+// 角色：资深编辑
+// 目标：对照研究结果审阅草稿，决定通过或退回修改
+// 背景：严谨的编辑，关注结构、事实准确性和篇幅
+const editorSystemPrompt = `你是一位严谨的资深编辑，负责在博客文章发布前把关质量。
+你会从以下几个方面审阅草稿：
+1. 结构：有吸引人的开头、层次清晰的正文和有力的结尾
+2. 准确性：文中的事实和结论必须来自研究结果，引用的来源必须在来源列表中，不能夸大或编造
+3. 篇幅：约 500 字（450～600 字之间可以接受）
+
+输出格式：
+- 第一行只写“通过”或“修改”
+- 选择“修改”时，从第二行起逐条写出具体、可执行的修改意见；选择“通过”时不需要写其他内容`
+
+// editorUserTemplate: 编辑的用户消息，length 为程序统计的草稿字数，避免模型自己数错
+const editorUserTemplate = "研究结果：\n\n{research_results}\n\n来源：\n{sources}\n\n待审阅的草稿（约 {length} 字）：\n\n{draft}"
+
+// parseEditorVerdict: 解析编辑的输出：第一行为“通过”时 approved 为 true；
+// 否则 notes 为修改意见（第一行之后的内容，没有时为整个输出），意见为空时也视为通过
+func parseEditorVerdict(content string) (approved bool, notes string) {
+	content = strings.TrimSpace(content)
+	first, rest, _ := strings.Cut(content, "\n")
+	first = strings.Trim(strings.TrimSpace(first), "*#：:。 ")
+	switch {
+	case strings.HasPrefix(first, "通过"):
+		return true, ""
+	case strings.HasPrefix(first, "修改"):
+		notes = strings.TrimSpace(rest)
+	default:
+		notes = content
+	}
+	if notes == "" {
+		return true, ""
+	}
+	return false, notes
+}
+
+// articleLength: 文章字数：每个汉字计 1，连续的字母或数字（英文单词、数字）计 1
+func articleLength(s string) int {
+	n := 0
+	inWord := false
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			n++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				n++
+			}
+			inWord = true
+		default:
+			inWord = false
+		}
+	}
+	return n
+}
+
+// truncateString: 截断过长的字符串用于日志显示
+func truncateString(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "..."
+}
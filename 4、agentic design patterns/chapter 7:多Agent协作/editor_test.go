@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestParseEditorVerdict(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		approved  bool
+		wantNotes string
+	}{
+		{"通过", "通过", true, ""},
+		{"加粗的通过", "**通过**。\n文章很好", true, ""},
+		{"修改", "修改\n1. 开头太长\n2. 补充来源", false, "1. 开头太长\n2. 补充来源"},
+		{"修改后带冒号", "修改：\n删掉最后一段", false, "删掉最后一段"},
+		{"只写修改没有意见", "修改", true, ""},
+		{"没有按格式输出", "开头太长，建议压缩", false, "开头太长，建议压缩"},
+		{"空输出", "  ", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approved, notes := parseEditorVerdict(tt.content)
+			if approved != tt.approved || notes != tt.wantNotes {
+				t.Errorf("parseEditorVerdict = %v, %q，期望 %v, %q", approved, notes, tt.approved, tt.wantNotes)
+			}
+		})
+	}
+}
+
+func TestArticleLength(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"人工智能", 4},
+		{"GPT-4o 发布于 2024 年", 7},
+		{"AI agents are here.", 4},
+		{"## 标题\n\n- 第一点", 5},
+	}
+	for _, tt := range tests {
+		if got := articleLength(tt.in); got != tt.want {
+			t.Errorf("articleLength(%q) = %d，期望 %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	if got := truncateString("第一行\n  第二行", 10); got != "第一行 第二行" {
+		t.Errorf("应合并空白，得到 %q", got)
+	}
+	if got := truncateString("一二三四五", 3); got != "一二三..." {
+		t.Errorf("应按字符截断，得到 %q", got)
+	}
+}
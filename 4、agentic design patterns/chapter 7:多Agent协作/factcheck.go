@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+)
+
+// --- 事实核查：提取研究简报中的事实陈述，用搜索工具逐条核查 ---
+
+// claimExtractSystemPrompt: 提取事实陈述的提示词，要求只输出 JSON 字符串数组，按重要性排序
+const claimExtractSystemPrompt = `你是一位严谨的事实核查员。
+请从用户给出的研究简报中提取最多 {max_claims} 条可以通过公开资料核实的事实陈述（数据、日期、机构、产品、事件等），
+按对文章结论的重要性从高到低排序，每条陈述是一句完整、不依赖上下文的话。观点、预测和泛泛而谈的描述不要提取。
+只输出 JSON 字符串数组，例如 ["陈述一", "陈述二"]，不要输出其他内容。`
+
+// claimVerifySystemPrompt: 核查单条陈述的 ReAct Agent 提示词，要求先搜索再给出 JSON 结论
+const claimVerifySystemPrompt = `你是一位严谨的事实核查员，负责核实一条事实陈述。
+请先用 web_search 工具搜索相关资料（可以换关键词搜索多次），再根据搜索结果给出结论：
+- supported：搜索结果明确支持该陈述
+- unsupported：搜索结果与该陈述矛盾，或明确表明它不成立
+- unknown：搜索失败或搜索结果不足以判断
+不要凭记忆下结论。最后只输出一个 JSON 对象，不要输出其他内容：
+{"verdict": "supported|unsupported|unknown", "evidence": "一句话说明依据", "source": "最相关的来源链接，没有时为空"}`
+
+// Verdict: 一条陈述的核查结论
+type Verdict string
+
+const (
+	VerdictSupported   Verdict = "supported"   // VerdictSupported: 搜索结果支持
+	VerdictUnsupported Verdict = "unsupported" // VerdictUnsupported: 搜索结果矛盾或表明不成立
+	VerdictUnknown     Verdict = "unknown"     // VerdictUnknown: 无法确认（搜索失败、证据不足或核查员输出无法解析）
+)
+
+// Label: 带图标的中文结论，用于输出和标注
+func (v Verdict) Label() string {
+	switch v {
+	case VerdictSupported:
+		return "✅ 已证实"
+	case VerdictUnsupported:
+		return "❌ 未证实"
+	default:
+		return "❓ 无法确认"
+	}
+}
+
+// ClaimCheck: 一条陈述的核查结果
+type ClaimCheck struct {
+	Claim    string  `json:"claim"`
+	Verdict  Verdict `json:"verdict"`
+	Evidence string  `json:"evidence,omitempty"` // Evidence: 核查员给出的依据，或无法核查的原因
+	Source   string  `json:"source,omitempty"`   // Source: 最相关的来源链接
+}
+
+// parseClaimVerdict: 解析核查员输出的 JSON 结论（允许包在代码块中或前后有说明文字），
+// 无法解析或结论不在三种之内时判定为无法确认，不让核查失败
+func parseClaimVerdict(claim, content string) ClaimCheck {
+	check := ClaimCheck{Claim: claim, Verdict: VerdictUnknown}
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		check.Evidence = "核查员没有给出 JSON 结论"
+		return check
+	}
+	var verdict struct {
+		Verdict  string `json:"verdict"`
+		Evidence string `json:"evidence"`
+		Source   string `json:"source"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &verdict); err != nil {
+		check.Evidence = fmt.Sprintf("核查员的结论无法解析: %v", err)
+		return check
+	}
+	check.Evidence = strings.TrimSpace(verdict.Evidence)
+	check.Source = strings.TrimSpace(verdict.Source)
+	switch v := Verdict(strings.ToLower(strings.TrimSpace(verdict.Verdict))); v {
+	case VerdictSupported, VerdictUnsupported, VerdictUnknown:
+		check.Verdict = v
+	default:
+		check.Evidence = strings.TrimSpace(fmt.Sprintf("未知的结论 %q。%s", verdict.Verdict, check.Evidence))
+	}
+	return check
+}
+
+// newClaimVerifier: 创建核查单条陈述的 ReAct Agent，可以调用搜索工具
+func newClaimVerifier(ctx context.Context, llm model.ToolCallingChatModel, search tool.BaseTool) (*react.Agent, error) {
+	return react.NewAgent(ctx, &react.AgentConfig{
+		ToolCallingModel: llm,
+		ToolsConfig: compose.ToolsNodeConfig{
+			Tools: []tool.BaseTool{search},
+		},
+		MaxStep: 10,
+	})
+}
+
+// verifyClaim: 用核查员 Agent 核查一条陈述；Agent 执行失败时判定为无法确认并记录原因
+func verifyClaim(ctx context.Context, verifier *react.Agent, claim string) ClaimCheck {
+	result, err := verifier.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(claimVerifySystemPrompt),
+		schema.UserMessage("待核查的陈述：" + claim),
+	})
+	if err != nil {
+		return ClaimCheck{Claim: claim, Verdict: VerdictUnknown, Evidence: "核查失败: " + errorLine(err)}
+	}
+	return parseClaimVerdict(claim, result.Content)
+}
+
+// annotateBrief: 在研究简报后附上事实核查结果（formatClaimChecks 的输出），并要求作家删除未证实的陈述、对无法确认的陈述使用审慎的措辞；
+// 没有核查结果时返回原简报
+func annotateBrief(brief, checks string) string {
+	if strings.TrimSpace(checks) == "" {
+		return brief
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(brief, "\n"))
+	sb.WriteString("\n\n## 事实核查\n\n")
+	sb.WriteString(checks)
+	sb.WriteString("\n\n写作要求：标记为“❌ 未证实”的陈述不要写进文章；标记为“❓ 无法确认”的陈述如果保留，" +
+		"请使用“据报道”“有观点认为”等审慎的措辞，不要当作确定的事实；“✅ 已证实”的陈述可以直接使用。\n")
+	return sb.String()
+}
+
+// formatClaimChecks: 编号的核查结果列表，每条包含结论、陈述、依据和来源
+func formatClaimChecks(checks []ClaimCheck) string {
+	var sb strings.Builder
+	for i, check := range checks {
+		sb.WriteString(fmt.Sprintf("%d. [%s] %s\n", i+1, check.Verdict.Label(), check.Claim))
+		if check.Evidence != "" {
+			sb.WriteString(fmt.Sprintf("   依据：%s\n", check.Evidence))
+		}
+		if check.Source != "" {
+			sb.WriteString(fmt.Sprintf("   来源：%s\n", check.Source))
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// countVerdicts: 各结论的陈述数
+func countVerdicts(checks []ClaimCheck) map[Verdict]int {
+	counts := map[Verdict]int{}
+	for _, check := range checks {
+		counts[check.Verdict]++
+	}
+	return counts
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseClaimVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		verdict  Verdict
+		evidence string
+		source   string
+	}{
+		{"支持", `{"verdict": "supported", "evidence": "报告第 3 页", "source": " https://a.example "}`, VerdictSupported, "报告第 3 页", "https://a.example"},
+		{"代码块和大写", "结论如下：\n```json\n{\"verdict\": \" Unsupported \", \"evidence\": \"数据是 55%\"}\n```", VerdictUnsupported, "数据是 55%", ""},
+		{"无法确认", `{"verdict": "unknown", "evidence": "搜索失败"}`, VerdictUnknown, "搜索失败", ""},
+		{"未知的结论", `{"verdict": "partly", "evidence": "只对了一半"}`, VerdictUnknown, `未知的结论 "partly"。只对了一半`, ""},
+		{"没有 JSON", "我认为是对的", VerdictUnknown, "核查员没有给出 JSON 结论", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseClaimVerdict("陈述", tt.content)
+			if got.Claim != "陈述" || got.Verdict != tt.verdict || got.Evidence != tt.evidence || got.Source != tt.source {
+				t.Errorf("parseClaimVerdict = %+v", got)
+			}
+		})
+	}
+	if got := parseClaimVerdict("陈述", `{"verdict": }`); got.Verdict != VerdictUnknown || !strings.HasPrefix(got.Evidence, "核查员的结论无法解析") {
+		t.Errorf("JSON 无效时 = %+v", got)
+	}
+}
+
+func TestFormatClaimChecks(t *testing.T) {
+	checks := []ClaimCheck{
+		{Claim: "65% 的企业使用生成式 AI", Verdict: VerdictSupported, Evidence: "McKinsey 调查", Source: "https://mckinsey.example"},
+		{Claim: "欧盟 2020 年通过 AI 法案", Verdict: VerdictUnsupported, Evidence: "2024 年生效"},
+		{Claim: "训练成本下降 90%", Verdict: VerdictUnknown},
+	}
+	want := "1. [✅ 已证实] 65% 的企业使用生成式 AI\n   依据：McKinsey 调查\n   来源：https://mckinsey.example\n" +
+		"2. [❌ 未证实] 欧盟 2020 年通过 AI 法案\n   依据：2024 年生效\n" +
+		"3. [❓ 无法确认] 训练成本下降 90%"
+	if got := formatClaimChecks(checks); got != want {
+		t.Errorf("formatClaimChecks =\n%s\n期望\n%s", got, want)
+	}
+	counts := countVerdicts(append(checks, ClaimCheck{Verdict: VerdictSupported}))
+	if counts[VerdictSupported] != 2 || counts[VerdictUnsupported] != 1 || counts[VerdictUnknown] != 1 {
+		t.Errorf("countVerdicts = %v", counts)
+	}
+}
+
+func TestAnnotateBrief(t *testing.T) {
+	if got := annotateBrief("简报\n", " "); got != "简报\n" {
+		t.Errorf("没有核查结果时应返回原简报，得到 %q", got)
+	}
+	got := annotateBrief("简报\n\n", "1. [✅ 已证实] 陈述")
+	if !strings.HasPrefix(got, "简报\n\n## 事实核查\n\n1. [✅ 已证实] 陈述\n\n写作要求：") || !strings.Contains(got, "“❌ 未证实”的陈述不要写进文章") {
+		t.Errorf("annotateBrief =\n%s", got)
+	}
+}
+
+// TestBlogTeamFactCheck: 核查员提取陈述、逐条核查，结果附在作家的简报后；核查员的输出无法解析时判定为无法确认
+func TestBlogTeamFactCheck(t *testing.T) {
+	llm := newTeamModel().
+		on(claimExtractSystemPrompt, `["GPT-4o 在 2024 年发布", "GPT-4o 在 2024 年发布", "多模态模型能处理语音", "第三条不核查"]`).
+		on(claimVerifySystemPrompt, `{"verdict": "supported", "evidence": "OpenAI 公告", "source": "https://openai.example"}`, "不知道")
+	state, _, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, FactCheck: 2, Search: &SearchTool{Offline: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.FactChecks) != 2 || state.FactChecks[0].Verdict != VerdictSupported || state.FactChecks[1].Verdict != VerdictUnknown {
+		t.Fatalf("FactChecks = %+v", state.FactChecks)
+	}
+	verifies := llm.inputs(claimVerifySystemPrompt)
+	if len(verifies) != 2 || verifies[0] != "待核查的陈述：GPT-4o 在 2024 年发布" || verifies[1] != "待核查的陈述：多模态模型能处理语音" {
+		t.Errorf("核查员收到的陈述 = %q", verifies)
+	}
+	if extract := llm.inputs(claimExtractSystemPrompt); len(extract) != 1 || !strings.Contains(extract[0], "# 研究简报：AI 趋势") {
+		t.Errorf("提取陈述时应收到研究简报，得到 %q", extract)
+	}
+	writes := llm.inputs(writingSystemPrompt)
+	if len(writes) != 1 || !strings.Contains(writes[0], "## 事实核查\n\n1. [✅ 已证实] GPT-4o 在 2024 年发布") {
+		t.Errorf("作家的简报应附上核查结果：\n%s", writes)
+	}
+}
+
+func TestBlogTeamFactCheckSkipped(t *testing.T) {
+	// 没有搜索工具时不创建核查员，也不核查
+	llm := newTeamModel()
+	team := newTestTeam(t, llm, TeamOptions{FactCheck: 3}, nil)
+	if team.factChecking() || team.verifier != nil {
+		t.Error("没有搜索工具时不应核查")
+	}
+
+	// 提取的陈述无法解析时跳过核查，不中断运行
+	llm = newTeamModel().on(claimExtractSystemPrompt, "没有可以核查的陈述")
+	var out bytes.Buffer
+	team = newTestTeam(t, llm, TeamOptions{FactCheck: 3, Search: &SearchTool{Offline: true}}, &out)
+	state, err := team.factCheck(context.Background(), CollaborationState{ResearchBrief: "简报"})
+	if err != nil || len(state.FactChecks) != 0 {
+		t.Fatalf("factCheck = %+v, %v", state.FactChecks, err)
+	}
+	if !strings.Contains(out.String(), "跳过事实核查") || len(llm.inputs(claimVerifySystemPrompt)) != 0 {
+		t.Errorf("应跳过核查：\n%s", out.String())
+	}
+
+	// 核查员的模型需要支持工具调用
+	_, err = newBlogTeam(context.Background(), chatOnlyModel{newTeamModel()}, TeamOptions{FactCheck: 1, Search: &SearchTool{Offline: true}})
+	if err == nil || !strings.Contains(err.Error(), "事实核查员的模型不可用") {
+		t.Errorf("模型不支持工具调用时 = %v", err)
+	}
+}
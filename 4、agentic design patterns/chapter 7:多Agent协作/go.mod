@@ -5,6 +5,8 @@ go 1.23.2
 require (
 	github.com/cloudwego/eino v0.7.0
 	github.com/cloudwego/eino-ext/components/model/openai v0.1.5
+	gopkg.in/yaml.v3 v3.0.1
+	shared v0.0.0
 )
 
 require (
@@ -37,5 +39,6 @@ require (
 	golang.org/x/arch v0.11.0 // indirect
 	golang.org/x/exp v0.0.0-20230713183714-613f0c0eb8a1 // indirect
 	golang.org/x/sys v0.28.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace shared => ../shared
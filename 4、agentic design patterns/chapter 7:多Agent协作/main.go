@@ -2,6 +2,54 @@
 多 Agent 协作（Multi-Agent Collaboration）是 Agent 系统的"团队协作模式"，
 它让多个具有不同专长的 Agent 协同工作，通过分工合作完成复杂任务。
 
+本示例的博客创建团队：研究主管把研究问题拆分为最多 -max-subtopics 个子主题，每个子主题由一个研究分析师并行研究
+（与第 3 章相同的并行图模式，共享 -rps、-max-in-flight 限流器）。研究分析师按 ResearchReport 的结构输出 JSON
+（趋势的名称、概述、证据和影响，以及注意事项和来源），无法解析时请模型修复一次，仍然失败时把原文作为一个自由格式的小节；
+各子主题的报告按顺序合并，再渲染为分节固定的研究简报交给作家，个别子主题失败时记入注意事项而不中断运行；事实核查员（调用搜索工具的 ReAct Agent）从简报中提取事实陈述，
+核查其中最重要的 -fact-check 条并标注为已证实 / 未证实 / 无法确认，作家据此删除未证实的陈述、对无法确认的陈述使用审慎的措辞；各 Agent 之间传递类型化的 CollaborationState（研究问题、简报、来源、草稿、
+编辑意见和 Token 用量），每个 Agent 只读写自己负责的字段，各阶段之间打印状态摘要；技术内容作家基于简报撰写文章，
+编辑对照研究结果审阅草稿（结构、准确性、约 500 字的篇幅），通过或给出修改意见；有意见时退回作家修订，最多修订 -max-revisions 次。
+
+人工审核：-review 时研究完成后在终端打印研究简报，由审核人批准、修改（粘贴更正，作为权威说明附在简报后交给作家和编辑）
+或中止（不再花费写作阶段的调用，研究报告仍然保存）；-auto-approve 时自动批准，用于非交互运行。审核的决定和更正记录在 run.json 中。
+
+作家变体：-variants K（K>1）时 K 位作家按不同的风格提示并发撰写初稿（共享 -rps、-max-in-flight 限流器），
+评审（使用编辑的模型）按准确性、结构、可读性和篇幅为每篇打分，总分最高的一篇交给编辑；之后的修订仍由一位作家完成。
+
+消息总线：Agent 之间的交接（子主题、研究简报、核查结果、审核决定、草稿、评审结果、编辑意见和监督者的调度）
+以类型化的 AgentMessage（发件人、收件人、类型 brief / draft / notes / verdict、内容、token 数和时间）发布到 MessageBus，
+每个阶段从总线读取自己的输入；终端在消息发布时显示一行，运行结束时按对话的形式打印协作记录，同时保存为 transcript.jsonl。
+
+每个阶段先打印横幅，再用 Stream 实时输出 Agent 的内容，拼接后的完整内容交给下一阶段；无法流式输出时退回 Invoke。
+并行的研究分析师完成后整段输出，所有输出共用一把锁，不会交错；-quiet（如 CI）时只打印横幅和进度。
+
+搜索工具与第 5 章的 SearchTool 相同，用 SEARCH_API_URL / SEARCH_API_KEY / SEARCH_PROVIDER 配置；
+SEARCH_OFFLINE=1 时使用离线夹具（SEARCH_FIXTURE 指定的 JSON 结果文件，或内置的固定结果），不访问网络。
+
+各角色可以使用不同的模型：RESEARCHER_MODEL（研究主管和研究分析师）、WRITER_MODEL、EDITOR_MODEL、FACT_CHECKER_MODEL、
+SUPERVISOR_MODEL，未设置时使用默认模型；
+配置驱动的阵容用每个 Agent 的 model 字段指定。模型在第一次使用时创建，名称相同的 Agent 共享同一个实例，阶段横幅和用量表都标注所用的模型。
+
+运行结束时按 Agent 打印提示 / 生成 token 和估算费用（-prices 设置各模型每百万 token 的价格）。
+设置 -budget-tokens 或 -budget-cost 后，超出预算时不再开始下一个阶段，返回已经完成的研究简报或草稿。
+
+监督者模式：go run . -supervisor
+不再按固定顺序执行：监督者（层级模式）每一步根据 CollaborationState 从成员注册表（researcher、fact_checker、writer、editor）
+中选择下一位成员或 FINISH（JSON 输出，附理由），成员执行后回到监督者，最多 -max-steps 步；
+没有理由地连续两次选择同一位成员视为无效决定，需要重新选择。每一步的调度决定都会打印并记录在状态中。
+
+每次运行在 outputs/collab-<时间戳>/stages/ 下保存各阶段完成后的状态（附配置哈希），每次 Agent 调用的瞬时错误按 -max-attempts 重试；
+某个阶段重试用尽失败后，用 -resume-from <阶段> [-resume-dir <运行目录>] 读取上一阶段的检查点，从失败的阶段重新进入 Graph，
+不必重新运行之前（昂贵的）研究阶段；提示词或模型改变后配置哈希不同，需要 -force 才能继续。
+同一目录中还保存运行产物：研究简报 research.md 和结构化的 research.json、作家变体的初稿 variant-<n>.md、作家的每一版草稿 draft-<n>.md、
+最终文章 final.md、协作记录 transcript.jsonl（继续运行时接着之前的消息），以及记录研究问题、各角色模型、各阶段耗时、各 Agent 用量和修订次数的 run.json；
+-out-dir 指定根目录（默认 outputs），每次运行新建目录，不会覆盖之前的运行。
+
+配置驱动的阵容：go run . -pipeline default（或 -pipeline pipeline.yaml）
+阵容中的每个 Agent 由 {name, system_prompt, user_template, input_key, output_key, model} 描述，
+代码为每个 Agent 编译一个 Chain 并按顺序自动连接成线性 Graph，输入键缺失等配置错误在构建时报告。
+内置的 default 阵容用配置重建了研究分析师 -> 技术内容作家流水线，并追加了一个 fact_summarizer Agent。
+
 此代码根据 MIT 许可证授权。
 请参阅仓库中的 LICENSE 文件以获取完整许可文本。
 */
@@ -10,14 +58,18 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
+	"time"
+
+	"shared/ratelimit"
+	"shared/retry"
 
 	"github.com/cloudwego/eino-ext/components/model/openai"
-	"github.com/cloudwego/eino/components/prompt"
-	"github.com/cloudwego/eino/compose"
-	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/eino/components/model"
 )
 
 // float32Ptr: 辅助函数，将 float32 值转换为 *float32 指针
@@ -26,6 +78,61 @@ func float32Ptr(f float32) *float32 {
 }
 
 func main() {
+	maxRevisions := flag.Int("max-revisions", 2, "编辑退回作家修订的最多次数，0 表示只审阅不修订")
+	maxSubtopics := flag.Int("max-subtopics", 3, "研究问题最多拆分的子主题数，每个子主题由一个研究分析师并行研究")
+	rps := flag.Float64("rps", 2, "研究分析师共享的每秒请求数上限，<=0 表示不限速")
+	maxInFlight := flag.Int("max-in-flight", 3, "同时进行的研究请求数上限，<=0 表示不限制")
+	prices := flag.String("prices", "", "模型价格（每百万 token），逗号分隔的 model=提示价格:生成价格，用于估算各 Agent 的费用")
+	budgetTokens := flag.Int("budget-tokens", 0, "单次运行的 token 预算，超出后不再开始下一个阶段（0 表示不限制）")
+	budgetCost := flag.Float64("budget-cost", 0, "单次运行的估算费用预算，需要 -prices（0 表示不限制）")
+	quiet := flag.Bool("quiet", false, "安静模式（如 CI）：只打印阶段横幅和进度，不实时输出各 Agent 的内容")
+	factCheckClaims := flag.Int("fact-check", 5, "事实核查员最多核查的事实陈述数，0 表示不核查")
+	variants := flag.Int("variants", 1, "并发撰写初稿的作家变体数，>1 时由评审按评分标准选出最好的一篇交给编辑（共享 -rps、-max-in-flight 限流器）")
+	review := flag.Bool("review", false, "研究完成后在终端人工审核研究简报：批准、修改（粘贴更正）或中止，再开始写作")
+	autoApprove := flag.Bool("auto-approve", false, "自动批准人工审核（非交互运行时使用，仍会记录在 run.json）")
+	supervisor := flag.Bool("supervisor", false, "由监督者 Agent 动态决定下一步由哪位成员工作，代替固定的流程")
+	maxSteps := flag.Int("max-steps", 8, "监督者模式下最多的调度步数")
+	maxAttempts := flag.Int("max-attempts", retry.DefaultMaxAttempts, "每次 Agent 调用的最多尝试次数（含第一次），瞬时错误（429、5xx）按指数退避重试")
+	resumeFrom := flag.String("resume-from", "", "从这个阶段继续之前失败的运行："+strings.Join(teamStages[1:], "、"))
+	resumeDir := flag.String("resume-dir", "", "-resume-from 使用的运行目录，默认为 -out-dir 下最新的 collab- 目录")
+	force := flag.Bool("force", false, "配置（提示词或模型）与检查点不同时仍然继续")
+	outDir := flag.String("out-dir", defaultArtifactRoot, "运行目录的根目录：每次运行在其下新建 collab-<时间戳>/，保存检查点、研究报告、各版草稿、最终文章和 run.json（为空时不保存）")
+	pipelinePath := flag.String("pipeline", "", "运行配置驱动的线性阵容：default 为内置阵容，否则为 JSON/YAML 配置文件路径")
+	flag.Parse()
+	if *maxRevisions < 0 {
+		fmt.Println("错误: -max-revisions 不能为负数")
+		os.Exit(1)
+	}
+	if *maxSubtopics < 1 {
+		fmt.Println("错误: -max-subtopics 至少为 1")
+		os.Exit(1)
+	}
+	if *variants < 1 {
+		fmt.Println("错误: -variants 至少为 1")
+		os.Exit(1)
+	}
+	if *maxSteps < 1 {
+		fmt.Println("错误: -max-steps 至少为 1")
+		os.Exit(1)
+	}
+	if *resumeFrom != "" && *supervisor {
+		fmt.Println("错误: -resume-from 只支持固定流程，不能与 -supervisor 一起使用")
+		os.Exit(1)
+	}
+	if *resumeFrom != "" && *resumeDir == "" && *outDir == "" {
+		fmt.Println("错误: -resume-from 需要 -resume-dir 或 -out-dir 来查找之前的运行")
+		os.Exit(1)
+	}
+	if *factCheckClaims < 0 {
+		fmt.Println("错误: -fact-check 不能为负数")
+		os.Exit(1)
+	}
+	priceTable, err := parsePrices(*prices)
+	if err != nil {
+		fmt.Printf("解析 -prices 失败: %v\n", err)
+		os.Exit(1)
+	}
+
 	ctx := context.Background()
 
 	// --- 设置环境 ---
@@ -57,135 +164,116 @@ func main() {
 
 	fmt.Printf("✅ 语言模型已初始化: %s\n\n", config.Model)
 
-	// ========== 创建第一个 Agent：研究分析师 ==========
-	// 角色：高级研究分析师
-	// 目标：查找并总结 AI 的最新趋势
-	// 背景：经验丰富的研究分析师，擅长识别关键趋势和综合信息
-	researchSystemPrompt := `你是一位经验丰富的研究分析师，擅长识别关键趋势和综合信息。
-你的任务是查找并总结 AI 的最新趋势，重点关注实际应用和潜在影响。
-请提供详细、准确且有价值的研究结果。`
-
-	researchTemplate := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage(researchSystemPrompt),
-		schema.UserMessage("{query}"),
-	)
-
-	// 创建研究 Agent Chain：Template -> ChatModel
-	// 这个 Chain 代表一个独立的 Agent，具有自己的角色和职责
-	researcherChain, err := compose.NewChain[map[string]any, *schema.Message]().
-		AppendChatTemplate(researchTemplate).
-		AppendChatModel(llm).
-		Compile(ctx)
-	if err != nil {
-		fmt.Printf("创建研究 Agent 失败: %v\n", err)
-		os.Exit(1)
+	// 指定了其他模型的 Agent 使用相同的 API 配置创建模型（在第一次使用时创建，同名的 Agent 共享）
+	newModel := func(ctx context.Context, name string) (model.BaseChatModel, error) {
+		agentConfig := *config
+		agentConfig.Model = name
+		return openai.NewChatModel(ctx, &agentConfig)
 	}
-
-	fmt.Println("✅ 研究分析师 Agent 已创建")
-
-	// ========== 创建第二个 Agent：技术内容作家 ==========
-	// 角色：技术内容作家
-	// 目标：基于研究发现撰写清晰且引人入胜的博客文章
-	// 背景：熟练的作家，可以将复杂的技术主题转化为易于理解的内容
-	writingSystemPrompt := `你是一位熟练的作家，可以将复杂的技术主题转化为易于理解的内容。
-你的任务是基于研究发现撰写清晰且引人入胜的博客文章。
-文章应该引人入胜且易于普通读者理解。`
-
-	writingTemplate := prompt.FromMessages(
-		schema.FString,
-		schema.SystemMessage(writingSystemPrompt),
-		schema.UserMessage("基于以下研究发现，撰写一篇 500 字的博客文章：\n\n{research_results}\n\n请确保文章引人入胜且易于普通读者理解。"),
-	)
-
-	// 创建写作 Agent Chain：Template -> ChatModel
-	// 这个 Chain 代表另一个独立的 Agent，具有自己的角色和职责
-	writerChain, err := compose.NewChain[map[string]any, *schema.Message]().
-		AppendChatTemplate(writingTemplate).
-		AppendChatModel(llm).
-		Compile(ctx)
-	if err != nil {
-		fmt.Printf("创建写作 Agent 失败: %v\n", err)
-		os.Exit(1)
+	agentModels := AgentModels{
+		Researcher:  os.Getenv("RESEARCHER_MODEL"),
+		Writer:      os.Getenv("WRITER_MODEL"),
+		Editor:      os.Getenv("EDITOR_MODEL"),
+		FactChecker: os.Getenv("FACT_CHECKER_MODEL"),
+		Supervisor:  os.Getenv("SUPERVISOR_MODEL"),
 	}
 
-	fmt.Println("✅ 技术内容作家 Agent 已创建")
+	// 各阶段的横幅和 Agent 的实时输出
+	output := newStageOutput(os.Stdout, *quiet)
 
-	// ========== 创建多 Agent 协作 Graph ==========
-	// 使用 Graph 来协调多个 Agent 的顺序执行
-	// 输入：包含查询的 map
-	// 输出：最终的消息（博客文章）
-	graph := compose.NewGraph[map[string]any, *schema.Message]()
+	// 定义研究任务
+	researchQuery := "研究 2024-2025 年人工智能中出现的前 3 个趋势。重点关注实际应用和潜在影响。"
 
-	// Lambda 节点 1：执行研究 Agent
-	// 将研究 Agent Chain 包装为 Lambda，嵌入到 Graph 中
-	researcherLambda := compose.InvokableLambda(func(ctx context.Context, input map[string]any) (*schema.Message, error) {
-		fmt.Println("🔍 研究分析师 Agent 正在工作...")
-		result, err := researcherChain.Invoke(ctx, input)
-		if err != nil {
-			return nil, fmt.Errorf("研究 Agent 执行失败: %w", err)
+	if *pipelinePath != "" {
+		if err := runPipeline(ctx, *pipelinePath, llm, newModel, output, researchQuery); err != nil {
+			fmt.Printf("\n发生意外错误：%v\n", err)
+			os.Exit(1)
 		}
-		fmt.Println("✅ 研究分析师 Agent 完成工作")
-		return result, nil
-	})
-	if err := graph.AddLambdaNode("researcher_agent", researcherLambda); err != nil {
-		fmt.Printf("添加研究 Agent 节点失败: %v\n", err)
-		os.Exit(1)
+		return
 	}
 
-	// Lambda 节点 2：准备写作输入
-	// 将研究结果转换为写作 Agent 需要的输入格式
-	prepareWritingInput := compose.InvokableLambda(func(ctx context.Context, msg *schema.Message) (map[string]any, error) {
-		return map[string]any{
-			"research_results": msg.Content,
-		}, nil
-	})
-	if err := graph.AddLambdaNode("prepare_writing", prepareWritingInput); err != nil {
-		fmt.Printf("添加准备写作节点失败: %v\n", err)
+	// --- 创建团队：研究主管、研究分析师、事实核查员、技术内容作家和编辑 ---
+	search, err := NewSearchTool()
+	if err != nil {
+		fmt.Printf("初始化搜索工具失败: %v\n", err)
 		os.Exit(1)
 	}
+	limiter := ratelimit.New(ratelimit.Config{RPS: *rps, Burst: 1, MaxInFlight: *maxInFlight})
+	ledger := NewUsageLedger(priceTable, Budget{MaxTokens: *budgetTokens, MaxCost: *budgetCost})
+	retryPolicy := retry.DefaultPolicy()
+	retryPolicy.MaxAttempts = *maxAttempts
+	teamOptions := TeamOptions{
+		MaxRevisions: *maxRevisions,
+		MaxSubtopics: *maxSubtopics,
+		Limiter:      limiter,
+		Output:       output,
+		Ledger:       ledger,
+		ModelName:    config.Model,
+		Models:       agentModels,
+		NewModel:     newModel,
+		FactCheck:    *factCheckClaims,
+		Search:       search,
+		MaxSteps:     *maxSteps,
+		Variants:     *variants,
+		Retry:        retryPolicy,
+		ResumeFrom:   *resumeFrom,
+	}
+	if *review {
+		teamOptions.Review = NewReviewGate(NewStdinPrompter(os.Stdin, os.Stdout), *autoApprove)
+	}
 
-	// Lambda 节点 3：执行写作 Agent
-	// 将写作 Agent Chain 包装为 Lambda，嵌入到 Graph 中
-	writerLambda := compose.InvokableLambda(func(ctx context.Context, input map[string]any) (*schema.Message, error) {
-		fmt.Println("✍️  技术内容作家 Agent 正在工作...")
-		result, err := writerChain.Invoke(ctx, input)
+	// 运行目录：新运行创建 <out-dir>/collab-<时间戳>/；继续运行时读取上一阶段的检查点作为输入
+	started := time.Now()
+	input := CollaborationState{Query: researchQuery}
+	var history []AgentMessage
+	configHash := teamConfigHash(teamOptions)
+	if *resumeFrom != "" {
+		dir := *resumeDir
+		if dir == "" {
+			if dir, err = latestRunDir(*outDir); err != nil {
+				fmt.Printf("查找之前的运行失败: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		var messages int
+		if teamOptions.Store, err = OpenRunStore(dir, configHash); err == nil {
+			input, messages, err = teamOptions.Store.ResumeState(*resumeFrom, *force)
+		}
+		if err == nil {
+			history, err = teamOptions.Store.TruncateMessages(messages)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("写作 Agent 执行失败: %w", err)
+			fmt.Printf("无法继续之前的运行: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("↩️  从 %s 的 %s 阶段继续\n", dir, *resumeFrom)
+	} else if *outDir != "" {
+		if teamOptions.Store, err = NewRunStore(*outDir, started, configHash); err != nil {
+			fmt.Printf("创建运行目录失败: %v\n", err)
+			os.Exit(1)
 		}
-		fmt.Println("✅ 技术内容作家 Agent 完成工作")
-		return result, nil
-	})
-	if err := graph.AddLambdaNode("writer_agent", writerLambda); err != nil {
-		fmt.Printf("添加写作 Agent 节点失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	// ========== 定义边的连接（顺序执行）==========
-	// 执行流程：
-	// START -> researcher_agent -> prepare_writing -> writer_agent -> END
-	// 这实现了两个 Agent 的顺序协作：研究 Agent 先工作，然后写作 Agent 基于研究结果工作
-	if err := graph.AddEdge(compose.START, "researcher_agent"); err != nil {
-		fmt.Printf("添加 START->researcher_agent 边失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := graph.AddEdge("researcher_agent", "prepare_writing"); err != nil {
-		fmt.Printf("添加 researcher_agent->prepare_writing 边失败: %v\n", err)
-		os.Exit(1)
-	}
-	if err := graph.AddEdge("prepare_writing", "writer_agent"); err != nil {
-		fmt.Printf("添加 prepare_writing->writer_agent 边失败: %v\n", err)
-		os.Exit(1)
 	}
-	if err := graph.AddEdge("writer_agent", compose.END); err != nil {
-		fmt.Printf("添加 writer_agent->END 边失败: %v\n", err)
-		os.Exit(1)
+	if teamOptions.Store != nil {
+		fmt.Printf("📁 运行目录: %s\n", teamOptions.Store.Dir)
 	}
 
-	// 编译 Graph
-	compiledGraph, err := graph.Compile(ctx)
+	// 消息总线：继续运行时接着检查点保存时的协作记录；每条消息在终端显示一行，并追加到 transcript.jsonl
+	bus := NewMessageBus(history...)
+	bus.Subscribe(func(msg AgentMessage) {
+		output.printf("📨 %s\n", formatMessageHeader(msg))
+		if err := teamOptions.Store.AppendMessage(msg); err != nil {
+			output.printf("⚠️ %v\n", err)
+		}
+	})
+	teamOptions.Bus = bus
+
+	build, mode := buildBlogTeam, "pipeline"
+	if *supervisor {
+		build, mode = buildSupervisorTeam, "supervisor"
+	}
+	compiledGraph, err := build(ctx, llm, teamOptions)
 	if err != nil {
-		fmt.Printf("编译 Graph 失败: %v\n", err)
+		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
 
@@ -195,19 +283,24 @@ func main() {
 	fmt.Println(strings.Repeat("=", 70))
 
 	// --- 执行团队 ---
-	// 定义研究任务
-	researchQuery := "研究 2024-2025 年人工智能中出现的前 3 个趋势。重点关注实际应用和潜在影响。"
-
-	input := map[string]any{
-		"query": researchQuery,
+	fmt.Printf("\n📋 研究任务: %s\n\n", input.Query)
+	if *resumeFrom == "" {
+		bus.Publish(AgentMessage{From: "用户", To: "研究主管", Kind: KindBrief, Content: input.Query})
 	}
 
-	fmt.Printf("\n📋 研究任务: %s\n\n", researchQuery)
-
-	// 执行团队（顺序执行多个 Agent）
+	// 执行团队（研究 -> 核查 -> 写作 -> 编辑，必要时循环修订；监督者模式下由监督者决定顺序）
 	result, err := compiledGraph.Invoke(ctx, input)
 	if err != nil {
+		// 阶段失败时只打印失败的阶段和继续运行的方法，不打印 Graph 的节点路径
+		var stageErr *StageError
+		if errors.As(err, &stageErr) {
+			err = stageErr
+		}
 		fmt.Printf("\n发生意外错误：%v\n", err)
+		if writeErr := teamOptions.Store.WriteRun(newRunRecord(teamOptions, mode, input, started, err)); writeErr != nil {
+			fmt.Printf("⚠️ %v\n", writeErr)
+		}
+		ledger.Print(os.Stdout)
 		os.Exit(1)
 	}
 
@@ -215,6 +308,90 @@ func main() {
 	fmt.Println(strings.Repeat("-", 70))
 	fmt.Println("## 团队最终输出 ##")
 	fmt.Println(strings.Repeat("-", 70))
-	fmt.Println(result.Content)
+	switch {
+	case result.Draft != "":
+		fmt.Println(result.Draft)
+	case result.ResearchBrief != "":
+		fmt.Println("（还没有草稿，以下是研究简报）")
+		fmt.Println(result.ResearchBrief)
+	default:
+		fmt.Println("（还没有草稿和研究简报）")
+	}
+	fmt.Println(strings.Repeat("-", 70))
+	if len(result.Sources) > 0 {
+		fmt.Printf("📚 来源:\n%s\n", formatSources(result.Sources))
+	}
+	if len(result.Routing) > 0 {
+		fmt.Printf("🧭 调度记录:\n%s\n", formatRouting(result.Routing))
+	}
+	if len(result.FactChecks) > 0 {
+		fmt.Printf("🔎 事实核查:\n%s\n", formatClaimChecks(result.FactChecks))
+	}
+	if len(result.Variants) > 0 {
+		fmt.Printf("🏆 作家变体:\n%s\n", formatVariants(result.Variants))
+	}
+	switch {
+	case result.StoppedReason != "":
+		fmt.Printf("⛔ 运行提前结束: %s\n", result.StoppedReason)
+	case result.Approved:
+		fmt.Printf("📝 修订次数: %d（编辑已通过）\n", result.Revisions)
+	default:
+		fmt.Printf("📝 修订次数: %d（达到上限，编辑仍有意见：%s）\n", result.Revisions, truncateString(result.EditorNotes, 100))
+	}
+	fmt.Printf("📦 最终状态: %s\n", result.Summary())
+	fmt.Println(strings.Repeat("-", 70))
+	fmt.Println("## 协作记录 ##")
+	fmt.Println(strings.Repeat("-", 70))
+	fmt.Println(bus.Transcript(200))
+	fmt.Println(strings.Repeat("-", 70))
+	if store := teamOptions.Store; store != nil {
+		if result.Draft != "" {
+			if err := store.WriteFinal(result.Draft); err != nil {
+				fmt.Printf("⚠️ %v\n", err)
+			}
+		}
+		if err := store.WriteRun(newRunRecord(teamOptions, mode, result, started, nil)); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		}
+		fmt.Printf("💾 运行产物已保存: %s（research.md、draft-<n>.md、final.md、transcript.jsonl、run.json）\n", store.Dir)
+	}
+	fmt.Println(limiter.Stats())
+	fmt.Println(strings.Repeat("-", 70))
+	fmt.Println("## 各 Agent 的用量 ##")
+	ledger.Print(os.Stdout)
+	fmt.Println(strings.Repeat("=", 70))
+}
+
+// runPipeline: 运行配置驱动的线性阵容，按 Agent 顺序打印每个 Agent 的输出
+func runPipeline(ctx context.Context, path string, llm model.BaseChatModel, newModel ModelFactory, output *stageOutput, query string) error {
+	cfg := defaultPipelineConfig()
+	if path != "default" {
+		var err error
+		if cfg, err = loadPipelineConfig(path); err != nil {
+			return err
+		}
+	}
+	pipeline, err := buildPipeline(ctx, cfg, llm, newModel, output)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(cfg.Agents))
+	for i, agent := range cfg.Agents {
+		names[i] = agent.Name
+	}
+	fmt.Printf("✅ 流水线已创建: %s\n", strings.Join(names, " -> "))
+	fmt.Printf("\n📋 研究任务: %s\n\n", query)
+
+	outputs, err := pipeline.Invoke(ctx, map[string]any{"query": query})
+	if err != nil {
+		return err
+	}
+	for _, agent := range cfg.Agents {
+		fmt.Println(strings.Repeat("-", 70))
+		fmt.Printf("## %s（%s） ##\n", agent.Name, agent.OutputKey)
+		fmt.Println(strings.Repeat("-", 70))
+		fmt.Println(outputs[agent.OutputKey])
+	}
 	fmt.Println(strings.Repeat("=", 70))
+	return nil
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"shared/stats"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// --- 按 Agent 角色使用不同的模型 ---
+
+// AgentModels: 各角色使用的模型名称，为空时使用默认模型
+type AgentModels struct {
+	Researcher  string // Researcher: 研究主管和研究分析师（RESEARCHER_MODEL）
+	Writer      string // Writer: 技术内容作家（WRITER_MODEL）
+	Editor      string // Editor: 编辑（EDITOR_MODEL）
+	FactChecker string // FactChecker: 事实核查员，需要支持工具调用（FACT_CHECKER_MODEL）
+	Supervisor  string // Supervisor: 监督者模式下决定下一步的监督者（SUPERVISOR_MODEL）
+}
+
+// modelPool: 按名称在第一次使用时创建模型，名称相同的 Agent 共享同一个实例
+type modelPool struct {
+	defaultName  string
+	defaultModel model.BaseChatModel
+	newModel     ModelFactory
+	models       map[string]model.BaseChatModel
+}
+
+// newModelPool: 创建模型池，默认模型以 defaultName 登记；newModel 为 nil 时只能使用默认模型
+func newModelPool(defaultModel model.BaseChatModel, defaultName string, newModel ModelFactory) *modelPool {
+	return &modelPool{
+		defaultName:  defaultName,
+		defaultModel: defaultModel,
+		newModel:     newModel,
+		models:       map[string]model.BaseChatModel{},
+	}
+}
+
+// get: 返回名称对应的模型和实际使用的名称；名称为空或与默认模型相同时返回默认模型
+func (p *modelPool) get(ctx context.Context, name string) (model.BaseChatModel, string, error) {
+	if name == "" || name == p.defaultName {
+		return p.defaultModel, p.defaultName, nil
+	}
+	if m, ok := p.models[name]; ok {
+		return m, name, nil
+	}
+	if p.newModel == nil {
+		return nil, "", fmt.Errorf("指定了模型 %s，但没有提供模型工厂", name)
+	}
+	m, err := p.newModel(ctx, name)
+	if err != nil {
+		return nil, "", fmt.Errorf("创建模型 %s 失败: %w", name, err)
+	}
+	p.models[name] = m
+	return m, name, nil
+}
+
+// modelTag: 阶段横幅中标注的模型名称
+func modelTag(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", name)
+}
+
+// countingToolModel: 支持工具调用的 stats.CountingModel，供 ReAct Agent（事实核查员）使用；
+// WithTools 返回的新模型同样统计用量
+type countingToolModel struct {
+	*stats.CountingModel
+	inner model.ToolCallingChatModel
+}
+
+var _ model.ToolCallingChatModel = (*countingToolModel)(nil)
+
+// newCountingToolModel: 包装支持工具调用的模型；模型不支持工具调用时返回错误
+func newCountingToolModel(m model.BaseChatModel) (*countingToolModel, error) {
+	inner, ok := m.(model.ToolCallingChatModel)
+	if !ok {
+		return nil, fmt.Errorf("模型 %T 不支持工具调用", m)
+	}
+	return &countingToolModel{CountingModel: stats.NewCountingModel(inner), inner: inner}, nil
+}
+
+func (m *countingToolModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return newCountingToolModel(inner)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"shared/stats"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// chatOnlyModel: 只实现 BaseChatModel、不支持工具调用的模型
+type chatOnlyModel struct{ m *scriptedModel }
+
+func (c chatOnlyModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return c.m.Generate(ctx, in, opts...)
+}
+
+func (c chatOnlyModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return c.m.Stream(ctx, in, opts...)
+}
+
+func TestModelPool(t *testing.T) {
+	ctx := context.Background()
+	def := newScriptedModel()
+	var created []string
+	pool := newModelPool(def, "default-model", func(ctx context.Context, name string) (model.BaseChatModel, error) {
+		if name == "broken" {
+			return nil, errors.New("未知模型")
+		}
+		created = append(created, name)
+		return newScriptedModel(), nil
+	})
+
+	for _, name := range []string{"", "default-model"} {
+		m, resolved, err := pool.get(ctx, name)
+		if err != nil || m != def || resolved != "default-model" {
+			t.Errorf("get(%q) = %v, %q, %v，期望默认模型", name, m, resolved, err)
+		}
+	}
+	first, resolved, err := pool.get(ctx, "small")
+	if err != nil || resolved != "small" {
+		t.Fatalf("get(small) = %q, %v", resolved, err)
+	}
+	if again, _, _ := pool.get(ctx, "small"); again != first || len(created) != 1 {
+		t.Errorf("名称相同的角色应共享同一个实例，创建了 %v", created)
+	}
+	if _, _, err := pool.get(ctx, "broken"); err == nil || !strings.Contains(err.Error(), "创建模型 broken 失败") {
+		t.Errorf("工厂失败时 = %v", err)
+	}
+
+	noFactory := newModelPool(def, "", nil)
+	if _, _, err := noFactory.get(ctx, "small"); err == nil || !strings.Contains(err.Error(), "没有提供模型工厂") {
+		t.Errorf("没有工厂时 = %v", err)
+	}
+}
+
+func TestModelTag(t *testing.T) {
+	if modelTag("") != "" || modelTag("gpt-4o") != " [gpt-4o]" {
+		t.Errorf("modelTag = %q, %q", modelTag(""), modelTag("gpt-4o"))
+	}
+}
+
+func TestCountingToolModel(t *testing.T) {
+	if _, err := newCountingToolModel(chatOnlyModel{newScriptedModel()}); err == nil || !strings.Contains(err.Error(), "不支持工具调用") {
+		t.Errorf("不支持工具调用的模型 = %v", err)
+	}
+
+	m, err := newCountingToolModel(newScriptedModel().on("你是核查员", "结论"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTools, err := m.WithTools([]*schema.ToolInfo{{Name: "web_search"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, counter := stats.WithUsage(context.Background())
+	if _, err := withTools.Generate(ctx, []*schema.Message{schema.SystemMessage("你是核查员")}); err != nil {
+		t.Fatal(err)
+	}
+	if u := counter.Usage(); u.Total() != 15 {
+		t.Errorf("WithTools 返回的模型也应统计用量，得到 %v", u)
+	}
+}
+
+// TestBlogTeamRoleModels: 各角色使用配置的模型，横幅标注模型名称，用量按角色和模型记账
+func TestBlogTeamRoleModels(t *testing.T) {
+	def := newTeamModel()
+	writer := newScriptedModel().on(writingSystemPrompt, "小模型写的初稿")
+	newModel := func(ctx context.Context, name string) (model.BaseChatModel, error) {
+		if name != "writer-model" {
+			t.Errorf("不应创建模型 %s", name)
+		}
+		return writer, nil
+	}
+	ledger := NewUsageLedger(nil, Budget{})
+	var out bytes.Buffer
+	team := newTestTeam(t, def, TeamOptions{ModelName: "default-model", Models: AgentModels{Writer: "writer-model", Editor: "default-model"},
+		NewModel: newModel, Ledger: ledger}, &out)
+	if team.researcherModel != "default-model" || team.writerModel != "writer-model" || team.editorModel != "default-model" {
+		t.Errorf("各角色的模型 = %s、%s、%s", team.researcherModel, team.writerModel, team.editorModel)
+	}
+
+	state, err := team.write(context.Background(), CollaborationState{ResearchBrief: "简报"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Draft != "小模型写的初稿" || len(def.inputs(writingSystemPrompt)) != 0 {
+		t.Errorf("作家应使用 writer-model，草稿 = %q", state.Draft)
+	}
+	if agents := ledger.Agents(); len(agents) != 1 || agents[0].Model != "writer-model" || agents[0].Usage.Total() != 15 {
+		t.Errorf("账本 = %+v", agents)
+	}
+	if !strings.Contains(out.String(), "✍️  技术内容作家 Agent 正在工作... [writer-model]") {
+		t.Errorf("横幅应标注模型：\n%s", out.String())
+	}
+
+	if _, err := newBlogTeam(context.Background(), def, TeamOptions{Models: AgentModels{Editor: "big"}}); err == nil || !strings.Contains(err.Error(), "创建编辑的模型失败") {
+		t.Errorf("没有模型工厂时 = %v", err)
+	}
+}
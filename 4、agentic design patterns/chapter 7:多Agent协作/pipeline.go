@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"gopkg.in/yaml.v3"
+)
+
+// --- 配置驱动的 Agent 阵容 ---
+
+// AgentSpec: 阵容中的一个 Agent：系统提示词和用户消息模板（FString，可以引用输入和之前任何 Agent 的输出键），
+// 从 InputKey 读取主要输入，结果写入 OutputKey；Model 为空时使用默认模型
+type AgentSpec struct {
+	Name         string `json:"name" yaml:"name"`
+	SystemPrompt string `json:"system_prompt" yaml:"system_prompt"`
+	UserTemplate string `json:"user_template,omitempty" yaml:"user_template,omitempty"` // UserTemplate: 为空时为 "{<input_key>}"
+	InputKey     string `json:"input_key" yaml:"input_key"`
+	OutputKey    string `json:"output_key" yaml:"output_key"`
+	Model        string `json:"model,omitempty" yaml:"model,omitempty"`
+}
+
+// PipelineConfig: 线性协作流水线：Agents 按顺序执行，每个 Agent 的输出键供后面的 Agent 使用
+type PipelineConfig struct {
+	Inputs []string    `json:"inputs" yaml:"inputs"` // Inputs: 运行时提供的输入键，为空时为 ["query"]
+	Agents []AgentSpec `json:"agents" yaml:"agents"`
+}
+
+// ModelFactory: 按名称创建模型，用于 AgentSpec.Model
+type ModelFactory func(ctx context.Context, name string) (model.BaseChatModel, error)
+
+// templateVarPattern: FString 模板中的变量 {name}
+var templateVarPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// defaultPipelineConfig: 用配置重建原来的研究分析师 -> 技术内容作家流水线，再追加一个事实摘要 Agent
+func defaultPipelineConfig() PipelineConfig {
+	return PipelineConfig{
+		Inputs: []string{"query"},
+		Agents: []AgentSpec{
+			{
+				Name: "researcher",
+				SystemPrompt: `你是一位经验丰富的研究分析师，擅长识别关键趋势和综合信息。
+你的任务是查找并总结 AI 的最新趋势，重点关注实际应用和潜在影响。
+请提供详细、准确且有价值的研究结果。`,
+				InputKey:  "query",
+				OutputKey: "research_results",
+			},
+			{
+				Name:         "writer",
+				SystemPrompt: writingSystemPrompt,
+				UserTemplate: "基于以下研究发现，撰写一篇 500 字的博客文章：\n\n{research_results}\n\n请确保文章引人入胜且易于普通读者理解。",
+				InputKey:     "research_results",
+				OutputKey:    "article",
+			},
+			{
+				Name: "fact_summarizer",
+				SystemPrompt: `你是一位严谨的事实核查助理。
+你的任务是从博客文章中提取读者应该记住的关键事实，每条事实必须能在研究结果中找到依据。`,
+				UserTemplate: "博客文章：\n\n{article}\n\n研究结果：\n\n{research_results}\n\n请用 3～5 条要点列出文章中的关键事实。",
+				InputKey:     "article",
+				OutputKey:    "key_facts",
+			},
+		},
+	}
+}
+
+// loadPipelineConfig: 读取流水线配置文件（JSON 或 YAML，按扩展名区分）
+func loadPipelineConfig(path string) (PipelineConfig, error) {
+	var cfg PipelineConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("读取流水线配置失败: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	case ".json":
+		err = json.Unmarshal(data, &cfg)
+	default:
+		return cfg, fmt.Errorf("不支持的流水线配置格式 %q（可选：.json、.yaml、.yml）", filepath.Ext(path))
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("解析流水线配置 %s 失败: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// userTemplate: Agent 的用户消息模板
+func (a AgentSpec) userTemplate() string {
+	if a.UserTemplate == "" {
+		return "{" + a.InputKey + "}"
+	}
+	return a.UserTemplate
+}
+
+// Validate: 在构建 Graph 前检查配置：名称和输出键不能为空或重复，
+// 每个 Agent 的输入键和模板中引用的键必须由运行时输入或之前的 Agent 提供
+func (c PipelineConfig) Validate() error {
+	if len(c.Agents) == 0 {
+		return fmt.Errorf("流水线没有 Agent")
+	}
+	available := c.inputs()
+	names := map[string]bool{}
+	for i, agent := range c.Agents {
+		if strings.TrimSpace(agent.Name) == "" {
+			return fmt.Errorf("第 %d 个 Agent 没有名称", i+1)
+		}
+		if names[agent.Name] {
+			return fmt.Errorf("Agent 名称 %s 重复", agent.Name)
+		}
+		names[agent.Name] = true
+		if agent.InputKey == "" || agent.OutputKey == "" {
+			return fmt.Errorf("Agent %s 缺少 input_key 或 output_key", agent.Name)
+		}
+		if !slices.Contains(available, agent.InputKey) {
+			return fmt.Errorf("Agent %s 的输入键 %q 没有由输入或之前的 Agent 提供（可用：%s）",
+				agent.Name, agent.InputKey, strings.Join(available, "、"))
+		}
+		for _, m := range templateVarPattern.FindAllStringSubmatch(agent.SystemPrompt+"\n"+agent.userTemplate(), -1) {
+			if !slices.Contains(available, m[1]) {
+				return fmt.Errorf("Agent %s 的模板引用了 %q，它没有由输入或之前的 Agent 提供（可用：%s）",
+					agent.Name, m[1], strings.Join(available, "、"))
+			}
+		}
+		if slices.Contains(available, agent.OutputKey) {
+			return fmt.Errorf("Agent %s 的输出键 %q 与输入或之前的 Agent 重复", agent.Name, agent.OutputKey)
+		}
+		available = append(available, agent.OutputKey)
+	}
+	return nil
+}
+
+// inputs: 运行时提供的输入键
+func (c PipelineConfig) inputs() []string {
+	if len(c.Inputs) == 0 {
+		return []string{"query"}
+	}
+	return slices.Clone(c.Inputs)
+}
+
+// buildPipeline: 按配置为每个 Agent 编译一个 Chain，包装为 Lambda 节点，并按顺序连接成线性 Graph：
+// START -> agents[0] -> agents[1] -> ... -> END；输入和输出都是键到值的 map，包含输入和所有 Agent 的输出
+func buildPipeline(ctx context.Context, cfg PipelineConfig, llm model.BaseChatModel, newModel ModelFactory, out *stageOutput) (compose.Runnable[map[string]any, map[string]any], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("流水线配置无效: %w", err)
+	}
+	if out == nil {
+		out = newStageOutput(os.Stdout, false)
+	}
+
+	graph := compose.NewGraph[map[string]any, map[string]any]()
+	// 名称相同的 Agent 共享同一个模型实例
+	pool := newModelPool(llm, "", newModel)
+	prev := compose.START
+	for _, agent := range cfg.Agents {
+		agent := agent
+		agentModel, _, err := pool.get(ctx, agent.Model)
+		if err != nil {
+			return nil, fmt.Errorf("创建 Agent %s 的模型失败: %w", agent.Name, err)
+		}
+
+		chain, err := newAgentChain(ctx, agentModel, agent.SystemPrompt, agent.userTemplate())
+		if err != nil {
+			return nil, fmt.Errorf("创建 Agent %s 失败: %w", agent.Name, err)
+		}
+
+		// Lambda 节点：模板可以引用当前所有的键，结果写入输出键，其余键原样传给下一个 Agent
+		lambda := compose.InvokableLambda(func(ctx context.Context, state map[string]any) (map[string]any, error) {
+			result, err := out.run(ctx, fmt.Sprintf("🤖 %s Agent 正在处理 %s...%s", agent.Name, agent.InputKey, modelTag(agent.Model)), chain, state)
+			if err != nil {
+				return nil, fmt.Errorf("Agent %s 执行失败: %w", agent.Name, err)
+			}
+			next := make(map[string]any, len(state)+1)
+			for k, v := range state {
+				next[k] = v
+			}
+			next[agent.OutputKey] = result.Content
+			out.printf("✅ %s Agent 完成工作，输出 %s\n", agent.Name, agent.OutputKey)
+			return next, nil
+		})
+		if err := graph.AddLambdaNode(agent.Name, lambda); err != nil {
+			return nil, fmt.Errorf("添加 Agent %s 节点失败: %w", agent.Name, err)
+		}
+		if err := graph.AddEdge(prev, agent.Name); err != nil {
+			return nil, fmt.Errorf("添加 %s->%s 边失败: %w", prev, agent.Name, err)
+		}
+		prev = agent.Name
+	}
+	if err := graph.AddEdge(prev, compose.END); err != nil {
+		return nil, fmt.Errorf("添加 %s->END 边失败: %w", prev, err)
+	}
+
+	compiledGraph, err := graph.Compile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("编译流水线 Graph 失败: %w", err)
+	}
+	return compiledGraph, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+)
+
+func TestDefaultPipelineConfigValid(t *testing.T) {
+	if err := defaultPipelineConfig().Validate(); err != nil {
+		t.Fatalf("默认流水线无效: %v", err)
+	}
+}
+
+func TestPipelineConfigValidate(t *testing.T) {
+	agent := func(name, input, output string) AgentSpec {
+		return AgentSpec{Name: name, SystemPrompt: "你是 " + name, InputKey: input, OutputKey: output}
+	}
+	tests := []struct {
+		name string
+		cfg  PipelineConfig
+		want string
+	}{
+		{"没有 Agent", PipelineConfig{}, "流水线没有 Agent"},
+		{"没有名称", PipelineConfig{Agents: []AgentSpec{agent(" ", "query", "a")}}, "第 1 个 Agent 没有名称"},
+		{"名称重复", PipelineConfig{Agents: []AgentSpec{agent("a", "query", "x"), agent("a", "x", "y")}}, "Agent 名称 a 重复"},
+		{"缺少键", PipelineConfig{Agents: []AgentSpec{agent("a", "query", "")}}, "缺少 input_key 或 output_key"},
+		{"输入键不存在", PipelineConfig{Agents: []AgentSpec{agent("a", "topic", "x")}}, `输入键 "topic" 没有由输入或之前的 Agent 提供（可用：query）`},
+		{"模板引用后面的输出", PipelineConfig{Agents: []AgentSpec{
+			{Name: "a", SystemPrompt: "参考 {y}", InputKey: "query", OutputKey: "x"},
+			agent("b", "x", "y"),
+		}}, `模板引用了 "y"`},
+		{"输出键重复", PipelineConfig{Agents: []AgentSpec{agent("a", "query", "x"), agent("b", "x", "x")}}, `输出键 "x" 与输入或之前的 Agent 重复`},
+		{"输出键覆盖输入", PipelineConfig{Inputs: []string{"query", "audience"}, Agents: []AgentSpec{agent("a", "audience", "query")}}, `输出键 "query"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v，应包含 %q", err, tt.want)
+			}
+		})
+	}
+
+	// 自定义输入键可以在模板中引用
+	cfg := PipelineConfig{Inputs: []string{"query", "audience"}, Agents: []AgentSpec{
+		{Name: "a", SystemPrompt: "面向 {audience}", InputKey: "query", OutputKey: "x"},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("引用自定义输入键时 = %v", err)
+	}
+}
+
+func TestLoadPipelineConfig(t *testing.T) {
+	const yamlConfig = `
+inputs: [query]
+agents:
+  - name: researcher
+    system_prompt: 你是研究员
+    input_key: query
+    output_key: notes
+    model: small-model
+`
+	const jsonConfig = `{"agents": [{"name": "researcher", "system_prompt": "你是研究员", "input_key": "query", "output_key": "notes", "model": "small-model"}]}`
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"roster.yaml": yamlConfig, "roster.yml": yamlConfig, "roster.json": jsonConfig} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := loadPipelineConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.Agents) != 1 || cfg.Agents[0].OutputKey != "notes" || cfg.Agents[0].Model != "small-model" {
+				t.Errorf("配置 = %+v", cfg)
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Validate = %v", err)
+			}
+		})
+	}
+
+	tests := []struct {
+		file, content, want string
+	}{
+		{"roster.toml", "", "不支持的流水线配置格式"},
+		{"bad.json", `{"agents": [`, "解析流水线配置"},
+		{"bad.yaml", "agents: [", "解析流水线配置"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadPipelineConfig(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: 错误 = %v，应包含 %q", tt.file, err, tt.want)
+		}
+	}
+	if _, err := loadPipelineConfig(filepath.Join(dir, "missing.yaml")); err == nil || !strings.Contains(err.Error(), "读取流水线配置失败") {
+		t.Errorf("文件不存在时 = %v", err)
+	}
+}
+
+func TestBuildPipeline(t *testing.T) {
+	cfg := PipelineConfig{Agents: []AgentSpec{
+		{Name: "researcher", SystemPrompt: "你是研究员", InputKey: "query", OutputKey: "notes"},
+		{Name: "writer", SystemPrompt: "你是作家", UserTemplate: "问题：{query}\n笔记：{notes}", InputKey: "notes", OutputKey: "article", Model: "writer-model"},
+	}}
+	llm := newScriptedModel().on("你是研究员", "研究笔记")
+	writerLLM := newScriptedModel().on("你是作家", "一篇文章")
+	var created []string
+	newModel := func(ctx context.Context, name string) (model.BaseChatModel, error) {
+		created = append(created, name)
+		return writerLLM, nil
+	}
+
+	var out bytes.Buffer
+	runnable, err := buildPipeline(context.Background(), cfg, llm, newModel, newStageOutput(&out, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := runnable.Invoke(context.Background(), map[string]any{"query": "AI 趋势"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["query"] != "AI 趋势" || result["notes"] != "研究笔记" || result["article"] != "一篇文章" {
+		t.Errorf("输出 = %v", result)
+	}
+	if got := llm.inputs("你是研究员"); len(got) != 1 || got[0] != "AI 趋势" {
+		t.Errorf("没有 user_template 时应只发送输入键的值，得到 %q", got)
+	}
+	if got := writerLLM.inputs("你是作家"); len(got) != 1 || got[0] != "问题：AI 趋势\n笔记：研究笔记" {
+		t.Errorf("作家收到的消息 = %q", got)
+	}
+	if len(created) != 1 || created[0] != "writer-model" {
+		t.Errorf("模型工厂的调用 = %v", created)
+	}
+	for _, want := range []string{"🤖 writer Agent 正在处理 notes... [writer-model]", "一篇文章", "✅ writer Agent 完成工作，输出 article"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("输出中缺少 %q：\n%s", want, out.String())
+		}
+	}
+
+	if _, err := buildPipeline(context.Background(), PipelineConfig{}, llm, nil, nil); err == nil || !strings.Contains(err.Error(), "流水线配置无效") {
+		t.Errorf("配置无效时 = %v", err)
+	}
+	if _, err := buildPipeline(context.Background(), cfg, llm, nil, nil); err == nil || !strings.Contains(err.Error(), "创建 Agent writer 的模型失败") {
+		t.Errorf("没有模型工厂时 = %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// --- 研究分析师交给作家的结构化研究报告 ---
+
+// reportSchema: 研究报告的 JSON 结构说明（用文字描述而不写 JSON 示例，避免花括号与 FString 模板变量冲突）
+const reportSchema = `只输出一个 JSON 对象，不要输出其他内容。字段如下：
+- trends：趋势数组，每个趋势包含 name（趋势名称）、summary（概述）、evidence（证据数组，每条是一句带出处的数据、案例或事实）和 impact（实际应用和潜在影响）
+- caveats：需要读者注意的局限、争议或不确定之处（字符串数组，可以为空）
+- sources：支持结论的来源（报告、论文、机构或网址，字符串数组）`
+
+// reportRepairSystemPrompt: 研究分析师的输出无法解析时，请模型按结构重新整理一次
+const reportRepairSystemPrompt = `你负责把研究分析师的输出整理为合法的 JSON，不增加、不删减其中的信息。
+` + reportSchema
+
+// reportRepairUserTemplate: 修复请求：解析错误和原始输出
+const reportRepairUserTemplate = "解析错误：{error}\n\n原始输出：\n\n{output}"
+
+// ResearchReport: 研究分析师输出的结构化报告；合并后为整个研究问题的报告
+type ResearchReport struct {
+	Trends  []Trend  `json:"trends"`
+	Caveats []string `json:"caveats,omitempty"`
+	Sources []string `json:"sources,omitempty"`
+}
+
+// Trend: 报告中的一个趋势
+type Trend struct {
+	Name     string     `json:"name"`
+	Summary  string     `json:"summary"`
+	Evidence stringList `json:"evidence,omitempty"`
+	Impact   string     `json:"impact,omitempty"`
+	Subtopic string     `json:"subtopic,omitempty"`  // Subtopic: 合并时填写，趋势来自哪个子主题
+	FreeForm bool       `json:"free_form,omitempty"` // FreeForm: 输出无法解析，Summary 为研究分析师的原文
+}
+
+// stringList: 字符串数组，也接受单个字符串（模型有时把只有一条的数组写成字符串）
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*l = stringList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*l = many
+	return nil
+}
+
+// trailingComma: 对象或数组结尾多余的逗号
+var trailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+
+// parseResearchReport: 解析研究分析师的输出：允许包在 ```json 代码块中、前后有说明文字、结尾有多余的逗号，
+// 字段名不区分大小写；去掉没有名称或概述的趋势，没有剩余趋势时返回错误
+func parseResearchReport(content string) (ResearchReport, error) {
+	var report ResearchReport
+	text, err := extractJSONObject(content)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal([]byte(text), &report); err != nil {
+		if err2 := json.Unmarshal([]byte(trailingComma.ReplaceAllString(text, "$1")), &report); err2 != nil {
+			return report, fmt.Errorf("JSON 无法解析: %w", err)
+		}
+	}
+	report = report.cleaned()
+	if len(report.Trends) == 0 {
+		return report, fmt.Errorf("报告中没有包含名称和概述的趋势")
+	}
+	return report, nil
+}
+
+// extractJSONObject: 找出文本中第一个完整的 JSON 对象（按括号配对，忽略字符串中的括号）
+func extractJSONObject(content string) (string, error) {
+	start := strings.Index(content, "{")
+	if start < 0 {
+		return "", fmt.Errorf("输出中没有 JSON 对象")
+	}
+	depth, inString, escaped := 0, false, false
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return content[start : i+1], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("JSON 对象不完整（可能被截断）")
+}
+
+// cleaned: 去掉空白、空项和没有名称或概述的趋势
+func (r ResearchReport) cleaned() ResearchReport {
+	var out ResearchReport
+	for _, trend := range r.Trends {
+		trend.Name = strings.TrimSpace(trend.Name)
+		trend.Summary = strings.TrimSpace(trend.Summary)
+		trend.Impact = strings.TrimSpace(trend.Impact)
+		trend.Evidence = stringList(nonEmpty(trend.Evidence))
+		if trend.Name == "" || trend.Summary == "" {
+			continue
+		}
+		out.Trends = append(out.Trends, trend)
+	}
+	out.Caveats = nonEmpty(r.Caveats)
+	out.Sources = nonEmpty(r.Sources)
+	return out
+}
+
+// nonEmpty: 去掉空白和空项，按顺序去重
+func nonEmpty(items []string) []string {
+	var out []string
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item != "" && !slices.Contains(out, item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// freeFormReport: 无法解析的输出整体作为一个自由格式的趋势，来源仍从末尾的“来源”一节中拆出
+func freeFormReport(subtopic, content string) ResearchReport {
+	body, sources := splitSources(content)
+	return ResearchReport{
+		Trends:  []Trend{{Name: subtopic, Summary: body, FreeForm: true}},
+		Sources: sources,
+	}
+}
+
+// renderReport: 把报告渲染为作家和编辑看到的研究简报，同样的报告总是得到同样的分节：
+// 每个趋势一节（子主题、概述、证据、影响），最后是注意事项
+func renderReport(query string, report ResearchReport) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 研究简报：%s\n", query))
+	for i, trend := range report.Trends {
+		sb.WriteString(fmt.Sprintf("\n## 趋势 %d：%s\n\n", i+1, trend.Name))
+		if trend.Subtopic != "" {
+			sb.WriteString(fmt.Sprintf("子主题：%s\n\n", trend.Subtopic))
+		}
+		if trend.FreeForm {
+			sb.WriteString("（研究分析师的输出无法解析为结构化报告，以下为原文）\n\n")
+		}
+		sb.WriteString(trend.Summary + "\n")
+		if len(trend.Evidence) > 0 {
+			sb.WriteString("\n证据：\n")
+			for _, evidence := range trend.Evidence {
+				sb.WriteString("- " + evidence + "\n")
+			}
+		}
+		if trend.Impact != "" {
+			sb.WriteString(fmt.Sprintf("\n影响：%s\n", trend.Impact))
+		}
+	}
+	if len(report.Caveats) > 0 {
+		sb.WriteString("\n## 注意事项\n\n")
+		for _, caveat := range report.Caveats {
+			sb.WriteString("- " + caveat + "\n")
+		}
+	}
+	return sb.String()
+}
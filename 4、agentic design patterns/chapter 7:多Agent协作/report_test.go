@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseResearchReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string // want: 各趋势的名称、证据条数和来源，用 | 分隔
+	}{
+		{"标准输出", `{"trends": [{"name": "多模态", "summary": "模型能处理图像", "evidence": ["GPT-4o 发布"], "impact": "交互方式改变"}], "sources": ["https://a.example"]}`,
+			"多模态/1|https://a.example"},
+		{"代码块和说明文字", "研究结果如下：\n```json\n{\"trends\": [{\"name\": \"智能体\", \"summary\": \"自动完成任务\"}]}\n```\n以上。",
+			"智能体/0|"},
+		{"结尾多余的逗号", "{\"trends\": [{\"name\": \"开源\", \"summary\": \"追上闭源\", \"evidence\": [\"Llama 3\",],},],}",
+			"开源/1|"},
+		{"字段名大小写", `{"Trends": [{"Name": "端侧", "Summary": "手机上运行", "Evidence": "Apple Intelligence"}]}`,
+			"端侧/1|"},
+		{"字符串中的括号", `{"trends": [{"name": "成本 {下降}", "summary": "价格 } 降低"}]} 附注 {`,
+			"成本 {下降}/0|"},
+		{"去掉不完整的趋势和重复项", `{"trends": [{"name": " ", "summary": "没有名称"}, {"name": "推理", "summary": " 更强 ", "evidence": ["o1", " ", "o1"]}], "sources": ["s", "s ", ""]}`,
+			"推理/1|s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := parseResearchReport(tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var trends []string
+			for _, trend := range report.Trends {
+				trends = append(trends, fmt.Sprintf("%s/%d", trend.Name, len(trend.Evidence)))
+			}
+			if got := strings.Join(trends, ",") + "|" + strings.Join(report.Sources, ","); got != tt.want {
+				t.Errorf("parseResearchReport = %s，期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResearchReportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"没有 JSON", "模型能力在提升", "输出中没有 JSON 对象"},
+		{"被截断", `{"trends": [{"name": "多模态"`, "JSON 对象不完整"},
+		{"JSON 无效", `{"trends": [{"name": 多模态}]}`, "JSON 无法解析"},
+		{"没有趋势", `{"trends": [{"name": "多模态"}], "caveats": ["数据有限"]}`, "报告中没有包含名称和概述的趋势"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseResearchReport(tt.content); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseResearchReport = %v，应包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeFormReport(t *testing.T) {
+	report := freeFormReport("模型能力", "模型越来越强。\n\n来源：\n- https://a.example\n- https://b.example")
+	if len(report.Trends) != 1 || !report.Trends[0].FreeForm || report.Trends[0].Name != "模型能力" || report.Trends[0].Summary != "模型越来越强。" {
+		t.Errorf("趋势 = %+v", report.Trends)
+	}
+	if strings.Join(report.Sources, ",") != "https://a.example,https://b.example" {
+		t.Errorf("来源 = %v", report.Sources)
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	report := ResearchReport{
+		Trends: []Trend{
+			{Name: "多模态", Summary: "模型能处理图像和语音", Evidence: stringList{"GPT-4o 发布", "Gemini 1.5"}, Impact: "交互方式改变", Subtopic: "模型能力"},
+			{Name: "行业应用", Summary: "原文", FreeForm: true},
+		},
+		Caveats: []string{"数据来自厂商"},
+	}
+	want := `# 研究简报：AI 趋势
+
+## 趋势 1：多模态
+
+子主题：模型能力
+
+模型能处理图像和语音
+
+证据：
+- GPT-4o 发布
+- Gemini 1.5
+
+影响：交互方式改变
+
+## 趋势 2：行业应用
+
+（研究分析师的输出无法解析为结构化报告，以下为原文）
+
+原文
+
+## 注意事项
+
+- 数据来自厂商
+`
+	if got := renderReport("AI 趋势", report); got != want {
+		t.Errorf("renderReport =\n%s\n期望\n%s", got, want)
+	}
+}
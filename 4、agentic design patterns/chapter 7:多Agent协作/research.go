@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// --- 并行研究：拆分子主题，每个子主题一个研究分析师 ---
+
+// decomposeSystemPrompt: 拆分研究问题的提示词，要求只输出 JSON 字符串数组
+const decomposeSystemPrompt = `你是一位研究主管，负责把研究问题拆分给多位研究分析师并行研究。
+请把用户的研究问题拆分为最多 {max_subtopics} 个互不重叠的子主题，每个子主题是一句可以独立研究的描述。
+只输出 JSON 字符串数组，例如 ["子主题一", "子主题二"]，不要输出其他内容。`
+
+// researcherUserTemplate: 研究分析师的用户消息：总体问题提供上下文，只深入研究分到的子主题，按 ResearchReport 的结构输出
+const researcherUserTemplate = "总体研究问题：{query}\n\n请只深入研究其中的这个子主题：{subtopic}\n\n" + reportSchema
+
+// subtopicFinding: 一个子主题的研究结果，失败时 Err 不为空
+type subtopicFinding struct {
+	Report ResearchReport
+	Tokens int
+	Err    error
+}
+
+// parseStringArray: 解析模型输出的 JSON 字符串数组（允许包在 ```json 代码块中），去掉空项和重复项，最多保留 max 个；
+// 用于研究主管拆分的子主题和事实核查员提取的陈述
+func parseStringArray(content string, max int) ([]string, error) {
+	text := strings.TrimSpace(content)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	text = strings.TrimSpace(text)
+
+	var items []string
+	if err := json.Unmarshal([]byte(text), &items); err != nil {
+		return nil, fmt.Errorf("不是 JSON 字符串数组: %w", err)
+	}
+	var result []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		result = append(result, item)
+		if len(result) == max {
+			break
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("列表为空")
+	}
+	return result, nil
+}
+
+// findingKey: 第 i 个子主题研究结果在并行图输出中的键
+func findingKey(i int) string {
+	return fmt.Sprintf("finding_%d", i+1)
+}
+
+// researchInParallel: 与第 3 章的并行图相同的模式：每个子主题一个节点，都从 START 开始、连接到 END，
+// 所有研究分析师共享同一个限流器，瞬时错误按 opts.Retry 重试；子主题失败时记录在结果中，不让整个并行图失败。
+// 并行的研究分析师不实时输出片段（会相互交错），每个完成后整段输出渲染后的报告
+func (t *blogTeam) researchInParallel(ctx context.Context, query string, subtopics []string) ([]subtopicFinding, error) {
+	graph := compose.NewGraph[string, map[string]any]()
+	for i, subtopic := range subtopics {
+		i, subtopic := i, subtopic
+		lambda := compose.InvokableLambda(func(ctx context.Context, query string) (subtopicFinding, error) {
+			agent := analystName(i)
+			t.out.printf("🔍 %s 正在研究%s: %s\n", agent, modelTag(t.researcherModel), subtopic)
+			ctx, done := t.opts.Ledger.track(ctx, "研究分析师", t.researcherModel)
+			report, err := t.researchSubtopic(ctx, agent, query, subtopic)
+			tokens := done().Total()
+			if err != nil {
+				t.out.printf("⚠️ %s 失败: %s\n", agent, errorLine(err))
+				return subtopicFinding{Tokens: tokens, Err: err}, nil
+			}
+			t.out.block(fmt.Sprintf("✅ %s 完成工作: %s", agent, subtopic), renderReport(subtopic, report))
+			t.publish(agent, "研究主管", KindBrief, renderReport(subtopic, report), tokens)
+			return subtopicFinding{Report: report, Tokens: tokens}, nil
+		})
+		key := findingKey(i)
+		if err := graph.AddLambdaNode(key, lambda, compose.WithOutputKey(key)); err != nil {
+			return nil, fmt.Errorf("添加 %s 节点失败: %w", key, err)
+		}
+		if err := graph.AddEdge(compose.START, key); err != nil {
+			return nil, fmt.Errorf("添加 START->%s 边失败: %w", key, err)
+		}
+		if err := graph.AddEdge(key, compose.END); err != nil {
+			return nil, fmt.Errorf("添加 %s->END 边失败: %w", key, err)
+		}
+	}
+
+	// 使用 AllPredecessor 触发模式确保所有研究分析师完成后再返回结果
+	runnable, err := graph.Compile(ctx, compose.WithNodeTriggerMode(compose.AllPredecessor))
+	if err != nil {
+		return nil, fmt.Errorf("编译并行研究图失败: %w", err)
+	}
+	results, err := runnable.Invoke(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("并行研究失败: %w", err)
+	}
+
+	findings := make([]subtopicFinding, len(subtopics))
+	for i := range subtopics {
+		finding, ok := results[findingKey(i)].(subtopicFinding)
+		if !ok {
+			finding = subtopicFinding{Err: fmt.Errorf("没有返回结果")}
+		}
+		findings[i] = finding
+	}
+	return findings, nil
+}
+
+// analystName: 第 i 个（从 0 开始）子主题的研究分析师，也是总线上的收件人
+func analystName(i int) string {
+	return fmt.Sprintf("研究分析师 %d", i+1)
+}
+
+// researchSubtopic: 一个研究分析师研究一个子主题并解析为 ResearchReport；
+// 输出无法解析时请模型按结构修复一次，仍然失败时把原文作为一个自由格式的小节
+func (t *blogTeam) researchSubtopic(ctx context.Context, agent, query, subtopic string) (ResearchReport, error) {
+	// 每次尝试都重新获取限流令牌，重试的等待期间不占用在途名额
+	invoke := func(chain compose.Runnable[map[string]any, *schema.Message], input map[string]any) (*schema.Message, error) {
+		return withRetry(ctx, t.opts.Retry, t.out, agent, func(ctx context.Context) (*schema.Message, error) {
+			release, err := t.opts.Limiter.Acquire(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("等待限流失败: %w", err)
+			}
+			defer release()
+			return chain.Invoke(ctx, input)
+		})
+	}
+	result, err := invoke(t.researcherChain, map[string]any{
+		"query":    query,
+		"subtopic": subtopic,
+	})
+	if err != nil {
+		return ResearchReport{}, err
+	}
+	report, parseErr := parseResearchReport(result.Content)
+	if parseErr == nil {
+		return report, nil
+	}
+
+	t.out.printf("🔧 %s 的输出无法解析（%v），请求按结构修复一次\n", agent, parseErr)
+	repaired, err := invoke(t.repairChain, map[string]any{
+		"error":  parseErr.Error(),
+		"output": result.Content,
+	})
+	if err == nil {
+		if report, parseErr = parseResearchReport(repaired.Content); parseErr == nil {
+			return report, nil
+		}
+	} else {
+		parseErr = err
+	}
+	t.out.printf("⚠️ %s 的输出修复失败（%s），作为自由格式的小节使用\n", agent, errorLine(parseErr))
+	return freeFormReport(subtopic, result.Content), nil
+}
+
+// mergeFindings: 按子主题的顺序合并为整个研究问题的报告：趋势标注所属的子主题，注意事项和来源按相同顺序去重；
+// 失败的子主题记入注意事项，所有子主题都失败时返回错误
+func mergeFindings(subtopics []string, findings []subtopicFinding) (ResearchReport, error) {
+	var merged ResearchReport
+	failed := 0
+	for i, subtopic := range subtopics {
+		if err := findings[i].Err; err != nil {
+			failed++
+			merged.Caveats = append(merged.Caveats, fmt.Sprintf("子主题“%s”研究失败，没有结果：%s", subtopic, errorLine(err)))
+			continue
+		}
+		report := findings[i].Report
+		for _, trend := range report.Trends {
+			trend.Subtopic = subtopic
+			merged.Trends = append(merged.Trends, trend)
+		}
+		merged.Caveats = append(merged.Caveats, report.Caveats...)
+		merged.Sources = append(merged.Sources, report.Sources...)
+	}
+	if failed == len(subtopics) {
+		return ResearchReport{}, fmt.Errorf("所有 %d 个子主题的研究都失败了", failed)
+	}
+	if failed > 0 {
+		merged.Caveats = append(merged.Caveats, fmt.Sprintf("%d/%d 个子主题研究失败，写作时只使用其余子主题的研究结果。", failed, len(subtopics)))
+	}
+	merged.Caveats = nonEmpty(merged.Caveats)
+	merged.Sources = nonEmpty(merged.Sources)
+	return merged, nil
+}
+
+// errorLine: 错误的第一行（Eino 的节点错误后面附有节点路径），用于日志和研究简报
+func errorLine(err error) string {
+	line, _, _ := strings.Cut(err.Error(), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestParseStringArray(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		max     int
+		want    []string
+	}{
+		{"JSON 数组", `["a", "b"]`, 5, []string{"a", "b"}},
+		{"代码块", "```json\n[\"a\"]\n```", 5, []string{"a"}},
+		{"去掉空项和重复项", `[" a ", "", "a", "b"]`, 5, []string{"a", "b"}},
+		{"最多保留 max 个", `["a", "b", "c"]`, 2, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStringArray(tt.content, tt.max)
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("parseStringArray = %v, %v，期望 %v", got, err, tt.want)
+			}
+		})
+	}
+	for _, content := range []string{"子主题一、子主题二", `{"a": 1}`, `["", " "]`} {
+		if _, err := parseStringArray(content, 5); err == nil {
+			t.Errorf("parseStringArray(%q) 应返回错误", content)
+		}
+	}
+}
+
+func TestMergeFindings(t *testing.T) {
+	subtopics := []string{"模型", "应用", "监管"}
+	findings := []subtopicFinding{
+		{Report: ResearchReport{
+			Trends:  []Trend{{Name: "多模态", Summary: "看图听音"}},
+			Caveats: []string{"数据来自厂商"},
+			Sources: []string{"https://a.example"},
+		}},
+		{Err: errors.New("429 Too Many Requests\n节点路径 research")},
+		{Report: ResearchReport{
+			Trends:  []Trend{{Name: "AI 法案", Summary: "按风险分级"}},
+			Caveats: []string{"数据来自厂商"},
+			Sources: []string{"https://a.example", "https://b.example"},
+		}},
+	}
+	merged, err := mergeFindings(subtopics, findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Trends) != 2 || merged.Trends[0].Subtopic != "模型" || merged.Trends[1].Subtopic != "监管" {
+		t.Errorf("趋势应按子主题顺序并标注子主题，得到 %+v", merged.Trends)
+	}
+	if !slices.Equal(merged.Sources, []string{"https://a.example", "https://b.example"}) {
+		t.Errorf("来源应按顺序去重，得到 %v", merged.Sources)
+	}
+	wantCaveats := []string{"数据来自厂商", "子主题“应用”研究失败，没有结果：429 Too Many Requests", "1/3 个子主题研究失败，写作时只使用其余子主题的研究结果。"}
+	if !slices.Equal(merged.Caveats, wantCaveats) {
+		t.Errorf("注意事项 = %q", merged.Caveats)
+	}
+
+	_, err = mergeFindings([]string{"模型"}, []subtopicFinding{{Err: errors.New("超时")}})
+	if err == nil || !strings.Contains(err.Error(), "所有 1 个子主题的研究都失败了") {
+		t.Errorf("所有子主题都失败时 = %v", err)
+	}
+}
+
+func TestErrorLine(t *testing.T) {
+	if got := errorLine(errors.New("  第一行 \n第二行")); got != "第一行" {
+		t.Errorf("errorLine = %q", got)
+	}
+}
+
+// failingSubtopicModel: 研究 failing 子主题时失败，其余调用交给 scriptedModel
+type failingSubtopicModel struct {
+	*scriptedModel
+	failing string
+}
+
+func (m *failingSubtopicModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if last := in[len(in)-1]; strings.Contains(last.Content, "子主题："+m.failing) {
+		return nil, errors.New("503 Service Unavailable")
+	}
+	return m.scriptedModel.Generate(ctx, in, opts...)
+}
+
+func TestResearchInParallel(t *testing.T) {
+	llm := &failingSubtopicModel{scriptedModel: newTeamModel(), failing: "行业应用"}
+	var out bytes.Buffer
+	team := newTestTeam(t, llm, TeamOptions{}, &out)
+
+	state, err := team.research(context.Background(), CollaborationState{Query: "AI 趋势", Subtopics: []string{"模型能力", "行业应用"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := llm.inputs(researchSystemPrompt); len(calls) != 1 || !strings.Contains(calls[0], "总体研究问题：AI 趋势") {
+		t.Errorf("研究分析师收到的消息 = %q", calls)
+	}
+	if state.Report == nil || len(state.Report.Trends) != 1 || state.Report.Trends[0].Subtopic != "模型能力" {
+		t.Fatalf("合并后的报告 = %+v", state.Report)
+	}
+	if caveat := state.Report.Caveats[0]; !strings.HasPrefix(caveat, "子主题“行业应用”研究失败，没有结果：") || !strings.HasSuffix(caveat, "503 Service Unavailable") {
+		t.Errorf("注意事项 = %q", caveat)
+	}
+	if !strings.Contains(state.ResearchBrief, "## 注意事项\n\n- 子主题“行业应用”研究失败") {
+		t.Errorf("失败的子主题应记入研究简报：\n%s", state.ResearchBrief)
+	}
+	if !strings.Contains(out.String(), "⚠️ 研究分析师 2 失败: ") {
+		t.Errorf("输出中缺少失败的研究分析师：\n%s", out.String())
+	}
+	if state.TokensUsed != 15 || !slices.Equal(state.Sources, []string{"https://example.com/ai"}) {
+		t.Errorf("TokensUsed = %d，来源 = %v", state.TokensUsed, state.Sources)
+	}
+
+	team = newTestTeam(t, &failingSubtopicModel{scriptedModel: newTeamModel(), failing: "模型"}, TeamOptions{}, nil)
+	if _, err := team.research(context.Background(), CollaborationState{Query: "AI 趋势", Subtopics: []string{"模型能力"}}); err == nil {
+		t.Error("所有子主题都失败时应返回错误")
+	}
+}
+
+func TestResearchSubtopicRepair(t *testing.T) {
+	tests := []struct {
+		name     string
+		repair   []string
+		freeForm bool
+	}{
+		{"修复成功", []string{testReport}, false},
+		{"修复后仍无法解析", []string{"还是没有 JSON"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := newTeamModel().
+				on(researchSystemPrompt, "多模态是主要趋势。\n\n来源：\n- https://example.com/raw").
+				on(reportRepairSystemPrompt, tt.repair...)
+			team := newTestTeam(t, llm, TeamOptions{}, nil)
+			report, err := team.researchSubtopic(context.Background(), "研究分析师 1", "AI 趋势", "模型能力")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repairs := llm.inputs(reportRepairSystemPrompt); len(repairs) != 1 || !strings.Contains(repairs[0], "多模态是主要趋势") {
+				t.Errorf("修复请求 = %q", repairs)
+			}
+			if got := report.Trends[0].FreeForm; got != tt.freeForm {
+				t.Errorf("FreeForm = %v，期望 %v", got, tt.freeForm)
+			}
+			if tt.freeForm && (report.Trends[0].Name != "模型能力" || !slices.Equal(report.Sources, []string{"https://example.com/raw"})) {
+				t.Errorf("自由格式的报告 = %+v", report)
+			}
+		})
+	}
+}
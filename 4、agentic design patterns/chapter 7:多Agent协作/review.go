@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// --- 人工审核：研究完成后由审核人批准、更正或中止，再决定是否花费写作阶段的调用 ---
+
+// ReviewAction: 审核人对研究简报的决定
+type ReviewAction string
+
+const (
+	ReviewApprove ReviewAction = "approve" // ReviewApprove: 批准，按原简报写作
+	ReviewEdit    ReviewAction = "edit"    // ReviewEdit: 给出更正，作为权威说明附在简报后
+	ReviewAbort   ReviewAction = "abort"   // ReviewAbort: 中止，不再执行之后的阶段
+)
+
+// ReviewDecision: 审核人的一次决定
+type ReviewDecision struct {
+	Action ReviewAction
+	Notes  string // Notes: 选择修改时审核人给出的更正
+}
+
+// Prompter: 向审核人展示研究简报并读取决定
+type Prompter interface {
+	ReviewBrief(ctx context.Context, brief string) (ReviewDecision, error)
+}
+
+// StdinPrompter: 在终端打印研究简报，从标准输入读取决定；修改时逐行读取更正，以单独一行的 . 结束
+type StdinPrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewStdinPrompter: 从 in 读取决定、向 out 打印提示的 Prompter（main 中为 os.Stdin 和 os.Stdout）
+func NewStdinPrompter(in io.Reader, out io.Writer) *StdinPrompter {
+	return &StdinPrompter{in: bufio.NewReader(in), out: out}
+}
+
+func (p *StdinPrompter) ReviewBrief(ctx context.Context, brief string) (ReviewDecision, error) {
+	fmt.Fprintf(p.out, "\n--- ✋ 请审核研究简报 ---\n%s\n--- 审核结束 ---\n", strings.TrimRight(brief, "\n"))
+	for {
+		fmt.Fprint(p.out, "[a] 批准  [e] 修改（粘贴更正）  [x] 中止 > ")
+		line, err := p.readLine()
+		if err != nil {
+			return ReviewDecision{}, err
+		}
+		switch strings.ToLower(line) {
+		case "a", "approve", "批准":
+			return ReviewDecision{Action: ReviewApprove}, nil
+		case "x", "abort", "中止":
+			return ReviewDecision{Action: ReviewAbort}, nil
+		case "e", "edit", "修改":
+			fmt.Fprintln(p.out, "请输入更正，以单独一行的 . 结束：")
+			var notes []string
+			for {
+				line, err := p.readLine()
+				if err != nil && !errors.Is(err, io.EOF) {
+					return ReviewDecision{}, err
+				}
+				if line == "." || errors.Is(err, io.EOF) {
+					break
+				}
+				notes = append(notes, line)
+			}
+			if text := strings.TrimSpace(strings.Join(notes, "\n")); text != "" {
+				return ReviewDecision{Action: ReviewEdit, Notes: text}, nil
+			}
+			fmt.Fprintln(p.out, "更正为空，请重新选择")
+		default:
+			fmt.Fprintln(p.out, "请输入 a、e 或 x")
+		}
+	}
+}
+
+// readLine: 读取一行并去掉首尾空白；输入结束且没有内容时返回错误（修改时 EOF 也表示更正结束）
+func (p *StdinPrompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return strings.TrimSpace(line), fmt.Errorf("读取审核输入失败（非交互运行请使用 -auto-approve）: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// ReviewRecord: 一次人工审核的记录，保存在状态和 run.json 中
+type ReviewRecord struct {
+	Time   time.Time    `json:"time"`
+	Action ReviewAction `json:"action"`
+	Notes  string       `json:"notes,omitempty"`
+	Auto   bool         `json:"auto,omitempty"` // Auto: 由 -auto-approve 自动批准
+}
+
+// ReviewGate: 研究简报的人工审核入口
+type ReviewGate struct {
+	prompter    Prompter
+	autoApprove bool
+}
+
+// NewReviewGate: autoApprove 为 true 时不询问、直接批准（用于非交互运行），但仍然记录
+func NewReviewGate(prompter Prompter, autoApprove bool) *ReviewGate {
+	return &ReviewGate{prompter: prompter, autoApprove: autoApprove}
+}
+
+// Review: 询问审核人并返回记录；读取输入失败时返回错误，不当作批准
+func (g *ReviewGate) Review(ctx context.Context, brief string) (ReviewRecord, error) {
+	if g.autoApprove {
+		return ReviewRecord{Time: time.Now(), Action: ReviewApprove, Auto: true}, nil
+	}
+	decision, err := g.prompter.ReviewBrief(ctx, brief)
+	if err != nil {
+		return ReviewRecord{}, err
+	}
+	switch decision.Action {
+	case ReviewApprove, ReviewEdit, ReviewAbort:
+	default:
+		return ReviewRecord{}, fmt.Errorf("未知的审核决定 %q", decision.Action)
+	}
+	return ReviewRecord{Time: time.Now(), Action: decision.Action, Notes: strings.TrimSpace(decision.Notes)}, nil
+}
+
+// annotateReview: 在简报后附上审核人的更正，并要求以更正为准；没有更正时返回原简报
+func annotateReview(brief, notes string) string {
+	if strings.TrimSpace(notes) == "" {
+		return brief
+	}
+	return strings.TrimRight(brief, "\n") + "\n\n## 审核人的更正\n\n" + notes +
+		"\n\n写作要求：以上是人工审核研究简报时给出的更正，具有最高权威；与研究简报或事实核查不一致时以更正为准。\n"
+}
+
+// writingBrief: agent（作家、评审或编辑）从总线读取的研究简报，附上事实核查结果和审核人的更正
+func (t *blogTeam) writingBrief(agent string, state CollaborationState) string {
+	var reviewNotes string
+	if state.Review != nil {
+		reviewNotes = state.Review.Notes
+	}
+	brief := t.receive("研究团队", agent, KindBrief, state.ResearchBrief)
+	checks := t.receive("事实核查员", agent, KindVerdict, formatClaimChecks(state.FactChecks))
+	return annotateReview(annotateBrief(brief, checks), t.receiveNotes("审核人", agent, reviewNotes))
+}
+
+// review: 人工审核研究简报（配置了 opts.Review 时）：批准后继续；修改时记录更正，写作和审阅时附在简报后；
+// 中止时记录原因，之后的阶段都不再执行（研究报告已经保存）
+func (t *blogTeam) review(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+	if t.opts.Review == nil || state.ResearchBrief == "" || state.StoppedReason != "" {
+		return state, nil
+	}
+	record, err := t.opts.Review.Review(ctx, t.receive("研究团队", "审核人", KindBrief, state.ResearchBrief))
+	if err != nil {
+		return state, fmt.Errorf("人工审核失败: %w", err)
+	}
+	state.Review = &record
+	switch record.Action {
+	case ReviewApprove:
+		if record.Auto {
+			t.out.printf("✅ 研究简报已自动批准（-auto-approve）\n")
+			t.publish("审核人", broadcast, KindVerdict, "批准（-auto-approve 自动批准）", 0)
+		} else {
+			t.out.printf("✅ 审核人批准了研究简报\n")
+			t.publish("审核人", broadcast, KindVerdict, "批准", 0)
+		}
+	case ReviewEdit:
+		t.out.printf("✏️  审核人给出了更正，写作时以更正为准\n")
+		t.publish("审核人", broadcast, KindNotes, record.Notes, 0)
+	case ReviewAbort:
+		state.StoppedReason = "审核人中止了运行，未开始写作"
+		t.out.printf("⛔ %s\n", state.StoppedReason)
+		t.publish("审核人", broadcast, KindVerdict, "中止", 0)
+	}
+	printStage(t.out, "human_review", state)
+	return state, nil
+}
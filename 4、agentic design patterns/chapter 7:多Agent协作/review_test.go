@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// reviewInput: 用 input 作为审核人的输入审核一份简报，返回决定和打印的提示
+func reviewInput(input string) (ReviewDecision, string, error) {
+	var out bytes.Buffer
+	decision, err := NewStdinPrompter(strings.NewReader(input), &out).ReviewBrief(context.Background(), "# 研究简报\n\n")
+	return decision, out.String(), err
+}
+
+func TestStdinPrompter(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		action ReviewAction
+		notes  string
+	}{
+		{"批准", "a\n", ReviewApprove, ""},
+		{"中文和大小写", " 批准 \n", ReviewApprove, ""},
+		{"中止", "X\n", ReviewAbort, ""},
+		{"修改", "e\n欧盟 AI 法案 2024 年生效\n数据以官方为准\n.\n", ReviewEdit, "欧盟 AI 法案 2024 年生效\n数据以官方为准"},
+		{"修改到输入结束", "edit\n只有一行", ReviewEdit, "只有一行"},
+		{"无效输入后重新选择", "y\n\na\n", ReviewApprove, ""},
+		{"更正为空后重新选择", "e\n\n.\nx\n", ReviewAbort, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, _, err := reviewInput(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.Action != tt.action || decision.Notes != tt.notes {
+				t.Errorf("ReviewBrief = %+v，期望 %s %q", decision, tt.action, tt.notes)
+			}
+		})
+	}
+
+	_, out, err := reviewInput("y\ne\n\n.\na\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "\n--- ✋ 请审核研究简报 ---\n# 研究简报\n--- 审核结束 ---\n") ||
+		!strings.Contains(out, "请输入 a、e 或 x") || !strings.Contains(out, "更正为空，请重新选择") {
+		t.Errorf("提示 =\n%s", out)
+	}
+
+	// 输入结束时返回错误，不当作批准
+	for _, input := range []string{"", "y\n"} {
+		if _, _, err := reviewInput(input); err == nil || !strings.Contains(err.Error(), "非交互运行请使用 -auto-approve") {
+			t.Errorf("输入 %q 结束时 = %v", input, err)
+		}
+	}
+}
+
+// fakePrompter: 返回预设决定的 Prompter，记录收到的简报
+type fakePrompter struct {
+	decision ReviewDecision
+	err      error
+	briefs   []string
+}
+
+func (p *fakePrompter) ReviewBrief(ctx context.Context, brief string) (ReviewDecision, error) {
+	p.briefs = append(p.briefs, brief)
+	return p.decision, p.err
+}
+
+func TestReviewGate(t *testing.T) {
+	prompter := &fakePrompter{decision: ReviewDecision{Action: ReviewEdit, Notes: "  更正  "}}
+	record, err := NewReviewGate(prompter, false).Review(context.Background(), "简报")
+	if err != nil || record.Action != ReviewEdit || record.Notes != "更正" || record.Auto || record.Time.IsZero() {
+		t.Errorf("Review = %+v, %v", record, err)
+	}
+
+	record, err = NewReviewGate(prompter, true).Review(context.Background(), "简报")
+	if err != nil || record.Action != ReviewApprove || !record.Auto || len(prompter.briefs) != 1 {
+		t.Errorf("自动批准时不应询问，得到 %+v, %v", record, err)
+	}
+
+	if _, err := NewReviewGate(&fakePrompter{decision: ReviewDecision{Action: "skip"}}, false).Review(context.Background(), "简报"); err == nil || !strings.Contains(err.Error(), `未知的审核决定 "skip"`) {
+		t.Errorf("未知的决定 = %v", err)
+	}
+	if _, err := NewReviewGate(&fakePrompter{err: errors.New("EOF")}, false).Review(context.Background(), "简报"); err == nil {
+		t.Error("读取失败时应返回错误")
+	}
+}
+
+func TestAnnotateReview(t *testing.T) {
+	if got := annotateReview("简报\n", " "); got != "简报\n" {
+		t.Errorf("没有更正时应返回原简报，得到 %q", got)
+	}
+	got := annotateReview("简报\n\n", "法案 2024 年生效")
+	if !strings.HasPrefix(got, "简报\n\n## 审核人的更正\n\n法案 2024 年生效\n\n写作要求：") || !strings.Contains(got, "以更正为准") {
+		t.Errorf("annotateReview =\n%s", got)
+	}
+}
+
+// TestBlogTeamReview: 审核人的更正附在作家和编辑看到的简报后；中止时不再写作
+func TestBlogTeamReview(t *testing.T) {
+	prompter := &fakePrompter{decision: ReviewDecision{Action: ReviewEdit, Notes: "欧盟 AI 法案 2024 年生效"}}
+	llm := newTeamModel()
+	bus := NewMessageBus()
+	state, out, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, Review: NewReviewGate(prompter, false), Bus: bus})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompter.briefs) != 1 || !strings.HasPrefix(prompter.briefs[0], "# 研究简报：AI 趋势") {
+		t.Errorf("审核人收到的简报 = %q", prompter.briefs)
+	}
+	if state.Review == nil || state.Review.Action != ReviewEdit || !strings.Contains(out, "✏️  审核人给出了更正") {
+		t.Errorf("审核记录 = %+v", state.Review)
+	}
+	for _, prompt := range []string{writingSystemPrompt, editorSystemPrompt} {
+		if inputs := llm.inputs(prompt); len(inputs) != 1 || !strings.Contains(inputs[0], "## 审核人的更正\n\n欧盟 AI 法案 2024 年生效") {
+			t.Errorf("%s 看到的简报应附上更正：\n%s", promptKey(prompt), inputs)
+		}
+	}
+	if msg, ok := bus.Latest("审核人", "技术内容作家"); !ok || msg.Kind != KindNotes || msg.To != broadcast {
+		t.Errorf("更正应作为修改意见发给所有成员，得到 %+v", msg)
+	}
+
+	llm = newTeamModel()
+	state, out, err = runTeam(t, llm, TeamOptions{MaxRevisions: 1, Review: NewReviewGate(&fakePrompter{decision: ReviewDecision{Action: ReviewAbort}}, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.StoppedReason != "审核人中止了运行，未开始写作" || state.Draft != "" || len(llm.inputs(writingSystemPrompt)) != 0 {
+		t.Errorf("中止后的状态 = %+v", state)
+	}
+	if !strings.Contains(out, "⛔ 审核人中止了运行，未开始写作") {
+		t.Errorf("输出 =\n%s", out)
+	}
+
+	_, _, err = runTeam(t, newTeamModel(), TeamOptions{Review: NewReviewGate(&fakePrompter{err: errors.New("EOF")}, false)})
+	if err == nil || !strings.Contains(err.Error(), "人工审核失败") {
+		t.Errorf("审核失败时 = %v", err)
+	}
+}
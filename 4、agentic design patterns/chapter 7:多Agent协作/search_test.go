@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// searchRun: 调用搜索工具，参数为 JSON
+func searchRun(t *testing.T, tool *SearchTool, args string) string {
+	t.Helper()
+	out, err := tool.InvokableRun(context.Background(), args)
+	if err != nil {
+		t.Fatalf("InvokableRun 不应返回 error，得到 %v", err)
+	}
+	return out
+}
+
+func TestSearchToolArguments(t *testing.T) {
+	tool := &SearchTool{Offline: true}
+	if out := searchRun(t, tool, `{"query": `); !strings.HasPrefix(out, searchFailure+"参数无效") {
+		t.Errorf("参数无效时 = %q", out)
+	}
+	if out := searchRun(t, tool, `{"query": "  "}`); out != searchFailure+"缺少搜索关键词" {
+		t.Errorf("没有关键词时 = %q", out)
+	}
+	if out := searchRun(t, &SearchTool{}, `{"query": "AI"}`); !strings.Contains(out, "搜索接口未配置") {
+		t.Errorf("没有配置接口时 = %q", out)
+	}
+	if out := searchRun(t, &SearchTool{APIURL: "http://x", Provider: "bing"}, `{"query": "AI"}`); !strings.Contains(out, "未知的搜索接口类型: bing") {
+		t.Errorf("未知接口类型时 = %q", out)
+	}
+}
+
+func TestSearchToolOffline(t *testing.T) {
+	out := searchRun(t, &SearchTool{Offline: true}, `{"query": "AI", "max_results": 2}`)
+	if !strings.HasPrefix(out, "1. Stanford AI Index Report 2024\n   https://aiindex.stanford.edu/report/\n") || strings.Contains(out, "3. ") {
+		t.Errorf("离线结果 = %q", out)
+	}
+
+	fixture := []searchResult{{Title: "夹具", Snippet: "摘要", URL: "https://fixture.example"}}
+	data, _ := json.Marshal(fixture)
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SEARCH_OFFLINE", "1")
+	t.Setenv("SEARCH_FIXTURE", path)
+	t.Setenv("SEARCH_API_KEY", "")
+	t.Setenv("SEARCH_PROVIDER", "")
+	tool, err := NewSearchTool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool.Provider != providerSearxNG {
+		t.Errorf("没有密钥时应使用 searxng，得到 %s", tool.Provider)
+	}
+	if out := searchRun(t, tool, `{"query": "AI"}`); out != "1. 夹具\n   https://fixture.example\n   摘要" {
+		t.Errorf("夹具结果 = %q", out)
+	}
+
+	t.Setenv("SEARCH_FIXTURE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := NewSearchTool(); err == nil || !strings.Contains(err.Error(), "读取搜索夹具失败") {
+		t.Errorf("夹具不存在时 = %v", err)
+	}
+}
+
+func TestSearchToolProviders(t *testing.T) {
+	var got *http.Request
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Query().Get("q") == "fail" {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"results": [
+			{"title": "A", "content": "摘要 A", "url": "https://a.example/"},
+			{"title": "A 重复", "content": "同一个页面", "url": "https://A.example#top"},
+			{"title": "没有链接", "content": "x", "url": ""},
+			{"title": "B", "content": "摘要 B", "url": "https://b.example"}
+		]}`))
+	}))
+	defer server.Close()
+
+	searx := &SearchTool{APIURL: server.URL, Provider: providerSearxNG}
+	out := searchRun(t, searx, `{"query": "AI 趋势"}`)
+	if got.Method != http.MethodGet || got.URL.Query().Get("q") != "AI 趋势" || got.URL.Query().Get("format") != "json" {
+		t.Errorf("SearxNG 请求 = %s %s", got.Method, got.URL)
+	}
+	if out != "1. A\n   https://a.example/\n   摘要 A\n2. B\n   https://b.example\n   摘要 B" {
+		t.Errorf("应按 URL 去重并去掉没有链接的结果，得到 %q", out)
+	}
+	if out := searchRun(t, searx, `{"query": "fail"}`); out != searchFailure+"HTTP 502 Bad Gateway" {
+		t.Errorf("HTTP 错误时 = %q", out)
+	}
+
+	tavily := &SearchTool{APIURL: server.URL, APIKey: "key", Provider: providerTavily}
+	searchRun(t, tavily, `{"query": "AI", "max_results": 50}`)
+	if got.Method != http.MethodPost || got.Header.Get("Authorization") != "Bearer key" ||
+		body["api_key"] != "key" || body["query"] != "AI" || body["max_results"] != float64(maxSearchResults) {
+		t.Errorf("Tavily 请求 = %s，请求体 %v", got.Method, body)
+	}
+}
+
+func TestFormatSearchResults(t *testing.T) {
+	results := []searchResult{
+		{Title: "第一条", URL: "https://a.example", Snippet: "  多行\n摘要  "},
+		{Title: "第二条", URL: "https://b.example", Snippet: strings.Repeat("长", 100)},
+		{Title: "第三条", URL: "https://c.example", Snippet: "短"},
+	}
+	if got := formatSearchResults(results[:1], 0); got != "1. 第一条\n   https://a.example\n   多行 摘要" {
+		t.Errorf("不限制长度时 = %q", got)
+	}
+	got := formatSearchResults(results, 120)
+	if !strings.HasPrefix(got, "1. 第一条") || !strings.Contains(got, "2. 第二条\n   https://b.example\n   长") ||
+		!strings.Contains(got, "…\n（另有 1 条结果因长度限制省略）") {
+		t.Errorf("超出长度时 = %q", got)
+	}
+	if got := formatSearchResults(results, 10); got != "（另有 3 条结果因长度限制省略）" {
+		t.Errorf("放不下任何结果时 = %q", got)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if got := truncateUTF8("中文", 4); got != "中" {
+		t.Errorf("不应切断多字节字符，得到 %q", got)
+	}
+	if got := truncateUTF8("abc", 5); got != "abc" {
+		t.Errorf("不需要截断时 = %q", got)
+	}
+}
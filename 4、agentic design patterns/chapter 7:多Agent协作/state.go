@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CollaborationState: 在团队 Graph 中流动的共享状态（草稿本），每个 Agent 节点只读写自己负责的字段：
+//   - decompose_query:    读 Query，写 Subtopics
+//   - research_subtopics: 读 Query、Subtopics，写 Report、ResearchBrief、Sources
+//   - human_review:       读 ResearchBrief，写 Review（中止时写 StoppedReason）
+//   - fact_check:         读 ResearchBrief，写 FactChecks
+//   - writer_agent:       读 ResearchBrief、FactChecks、Review、Sources、Draft、EditorNotes，写 Variants、Draft、Revisions
+//   - editor_agent:       读 ResearchBrief、FactChecks、Review、Sources、Draft，写 EditorNotes、Approved
+//
+// 这些字段同时作为消息发布到 MessageBus，各节点优先从总线读取输入（子主题、研究简报、核查结果、审核人的更正、草稿和修改意见），
+// 总线上没有对应的消息时（如从没有协作记录的检查点继续）读取状态中的字段。
+// 监督者模式下由监督者决定各成员的执行顺序，调度决定追加到 Routing。
+// 所有调用模型的节点都累加 TokensUsed；超出预算（或监督者达到最大步数、审核人中止）后各阶段不再执行，只传递已有的结果，StoppedReason 记录原因
+type CollaborationState struct {
+	Query         string            `json:"query"`                    // Query: 原始研究问题
+	Subtopics     []string          `json:"subtopics,omitempty"`      // Subtopics: 研究主管拆分的子主题
+	Report        *ResearchReport   `json:"report,omitempty"`         // Report: 研究分析师的结构化报告合并后的结果
+	ResearchBrief string            `json:"research_brief,omitempty"` // ResearchBrief: 由 Report 渲染的研究简报，每个趋势一节
+	Sources       []string          `json:"sources,omitempty"`        // Sources: 研究分析师给出的来源，按子主题顺序去重
+	Review        *ReviewRecord     `json:"review,omitempty"`         // Review: 人工审核的记录，审核人的更正在写作和审阅时附在简报后
+	FactChecks    []ClaimCheck      `json:"fact_checks,omitempty"`    // FactChecks: 事实核查员对简报中事实陈述的核查结果，写作和审阅时附在简报后面
+	Variants      []DraftVariant    `json:"variants,omitempty"`       // Variants: 使用作家变体时各变体初稿的评分和入选结果（草稿内容见 variant-<n>.md）
+	Draft         string            `json:"draft,omitempty"`          // Draft: 当前草稿，最终即为文章
+	EditorNotes   string            `json:"editor_notes,omitempty"`   // EditorNotes: 编辑最后一次的修改意见，通过时为空
+	Approved      bool              `json:"approved"`                 // Approved: 编辑是否通过
+	Revisions     int               `json:"revisions"`                // Revisions: 已经按修改意见修订的次数
+	TokensUsed    int               `json:"tokens_used"`              // TokensUsed: 所有 Agent 消耗的 Token 数（模型返回用量时才有数据）
+	StoppedReason string            `json:"stopped_reason,omitempty"` // StoppedReason: 提前停止的原因（预算超出、监督者达到最大步数），为空表示正常完成
+	Routing       []RoutingDecision `json:"routing,omitempty"`        // Routing: 监督者模式下的调度记录，包括无效的决定
+}
+
+// Summary: 单行状态摘要，在各阶段之间打印
+func (s CollaborationState) Summary() string {
+	notes := "无"
+	if s.EditorNotes != "" {
+		notes = "有"
+	}
+	return fmt.Sprintf("子主题 %d | 简报 %d 字 | 来源 %d | 核查 %d 条 | 草稿 %d 字 | 修订 %d 次 | 编辑意见 %s | Token %d",
+		len(s.Subtopics), articleLength(s.ResearchBrief), len(s.Sources), len(s.FactChecks), articleLength(s.Draft),
+		s.Revisions, notes, s.TokensUsed)
+}
+
+// printStage: 阶段结束时打印状态摘要
+func printStage(out *stageOutput, stage string, s CollaborationState) {
+	out.printf("📦 [%s] %s\n", stage, s.Summary())
+}
+
+// listMarker: 行首的列表符号（- * • 或 1. 1、 1)）
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.、)])\s*`)
+
+// sourcesHeading: “来源”一节的标题行，允许 Markdown 标记，标题后可以直接跟第一条来源
+var sourcesHeading = regexp.MustCompile(`^[#*\s]*(?:参考)?来源[*\s]*(?:[：:][*\s]*(.*))?$`)
+
+// splitSources: 把研究结果末尾的“来源”一节拆出来：返回正文和来源列表（每行一条，去掉列表符号）；
+// 没有“来源”一节时返回原文
+func splitSources(content string) (body string, sources []string) {
+	lines := strings.Split(content, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		m := sourcesHeading.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		if first := strings.TrimSpace(m[1]); first != "" {
+			sources = append(sources, first)
+		}
+		for _, line := range lines[i+1:] {
+			line = strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+			if line != "" {
+				sources = append(sources, line)
+			}
+		}
+		return strings.TrimSpace(strings.Join(lines[:i], "\n")), sources
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// formatSources: 来源列表，放进作家和编辑的消息
+func formatSources(sources []string) string {
+	if len(sources) == 0 {
+		return "（研究分析师没有给出来源）"
+	}
+	var sb strings.Builder
+	for i, source := range sources {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, source))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
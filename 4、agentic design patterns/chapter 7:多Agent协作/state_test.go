@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		body    string
+		sources []string
+	}{
+		{"没有来源", "正文\n", "正文", nil},
+		{"列表", "正文\n\n来源：\n- https://a.example\n2. 报告 B\n\n", "正文", []string{"https://a.example", "报告 B"}},
+		{"Markdown 标题", "正文\n## 参考来源\n* 报告 A", "正文", []string{"报告 A"}},
+		{"标题后直接跟来源", "正文\n**来源**: 报告 A\n报告 B", "正文", []string{"报告 A", "报告 B"}},
+		{"取最后一个来源标题", "来源：第一段\n正文\n来源：\n- 报告 A", "来源：第一段\n正文", []string{"报告 A"}},
+		{"正文中提到来源不算标题", "数据的来源很多。", "数据的来源很多。", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, sources := splitSources(tt.content)
+			if body != tt.body || !slices.Equal(sources, tt.sources) {
+				t.Errorf("splitSources = %q, %q，期望 %q, %q", body, sources, tt.body, tt.sources)
+			}
+		})
+	}
+}
+
+func TestFormatSources(t *testing.T) {
+	if got := formatSources(nil); got != "（研究分析师没有给出来源）" {
+		t.Errorf("没有来源时 = %q", got)
+	}
+	if got := formatSources([]string{"报告 A", "https://b.example"}); got != "1. 报告 A\n2. https://b.example" {
+		t.Errorf("formatSources = %q", got)
+	}
+}
+
+func TestCollaborationStateSummary(t *testing.T) {
+	s := CollaborationState{
+		Subtopics:     []string{"模型", "应用"},
+		ResearchBrief: "多模态模型",
+		Sources:       []string{"报告 A"},
+		FactChecks:    []ClaimCheck{{Claim: "c"}},
+		Draft:         "AI 正在改变世界",
+		Revisions:     1,
+		EditorNotes:   "补充例子",
+		TokensUsed:    120,
+	}
+	want := "子主题 2 | 简报 5 字 | 来源 1 | 核查 1 条 | 草稿 7 字 | 修订 1 次 | 编辑意见 有 | Token 120"
+	if got := s.Summary(); got != want {
+		t.Errorf("Summary = %q，期望 %q", got, want)
+	}
+	if got := (CollaborationState{}).Summary(); got != "子主题 0 | 简报 0 字 | 来源 0 | 核查 0 条 | 草稿 0 字 | 修订 0 次 | 编辑意见 无 | Token 0" {
+		t.Errorf("空状态 = %q", got)
+	}
+}
+
+// TestBlogTeamState: 每个阶段填写自己负责的字段，状态在 Graph 中一直传到最后
+func TestBlogTeamState(t *testing.T) {
+	state, out, err := runTeam(t, newTeamModel(), TeamOptions{MaxRevisions: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Query != "AI 趋势" || !slices.Equal(state.Subtopics, []string{"模型能力", "行业应用"}) {
+		t.Errorf("Query = %q，Subtopics = %v", state.Query, state.Subtopics)
+	}
+	if state.Report == nil || len(state.Report.Trends) != 2 || state.ResearchBrief == "" {
+		t.Errorf("研究结果 = %+v", state.Report)
+	}
+	if !slices.Equal(state.Sources, []string{"https://example.com/ai"}) || state.Draft != "初稿" || !state.Approved {
+		t.Errorf("来源 = %v，草稿 = %q，通过 = %v", state.Sources, state.Draft, state.Approved)
+	}
+	for _, stage := range []string{"decompose_query", "research_subtopics", "writer_agent", "editor_agent"} {
+		if !strings.Contains(out, "📦 ["+stage+"]") {
+			t.Errorf("输出中缺少 %s 的状态摘要：\n%s", stage, out)
+		}
+	}
+}
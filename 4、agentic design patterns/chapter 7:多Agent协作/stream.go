@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// --- 实时输出各 Agent 的内容 ---
+
+// stageOutput: 把各阶段的横幅和 Agent 的输出写到同一个 Writer；
+// 写入期间持有锁，并行的研究分析师和顺序的各阶段都不会交错输出
+type stageOutput struct {
+	mu    sync.Mutex
+	w     io.Writer
+	quiet bool // quiet: 安静模式（如 CI）：只打印横幅，用 Invoke 代替 Stream，不输出 Agent 的内容
+}
+
+// newStageOutput: 创建输出，w 为 nil 时丢弃所有输出
+func newStageOutput(w io.Writer, quiet bool) *stageOutput {
+	if w == nil {
+		w = io.Discard
+	}
+	return &stageOutput{w: w, quiet: quiet}
+}
+
+// run: 打印横幅，用 Stream 执行 Agent 并实时输出每个片段，最后拼接为完整的消息交给下一阶段；
+// 无法建立流（如模型不支持流式输出）或安静模式时退回 Invoke
+func (o *stageOutput) run(ctx context.Context, banner string, chain compose.Runnable[map[string]any, *schema.Message],
+	input map[string]any) (*schema.Message, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintln(o.w, banner)
+	if o.quiet {
+		return chain.Invoke(ctx, input)
+	}
+
+	stream, err := chain.Stream(ctx, input)
+	if err != nil {
+		msg, invokeErr := chain.Invoke(ctx, input)
+		if invokeErr != nil {
+			return nil, invokeErr
+		}
+		fmt.Fprintln(o.w, strings.TrimSpace(msg.Content))
+		return msg, nil
+	}
+	defer stream.Close()
+	return o.collect(stream)
+}
+
+// collect: 逐个读取并输出片段，结束时拼接为完整的消息
+func (o *stageOutput) collect(stream *schema.StreamReader[*schema.Message]) (*schema.Message, error) {
+	var chunks []*schema.Message
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(o.w)
+			return nil, fmt.Errorf("读取流式输出失败: %w", err)
+		}
+		fmt.Fprint(o.w, chunk.Content)
+		chunks = append(chunks, chunk)
+	}
+	fmt.Fprintln(o.w)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("流式输出为空")
+	}
+	return schema.ConcatMessages(chunks)
+}
+
+// block: 一次性输出一段完整的内容（并行阶段各分支的结果），安静模式时只输出横幅
+func (o *stageOutput) block(banner, content string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintln(o.w, banner)
+	if !o.quiet {
+		fmt.Fprintln(o.w, strings.TrimSpace(content))
+	}
+}
+
+// printf: 输出一行进度信息
+func (o *stageOutput) printf(format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintf(o.w, format, args...)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// streamModel: Stream 按 stream 返回（nil 时返回 streamErr），Generate 交给 scriptedModel
+type streamModel struct {
+	*scriptedModel
+	stream    func() *schema.StreamReader[*schema.Message]
+	streamErr error
+}
+
+func (m *streamModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	if m.stream == nil {
+		return nil, m.streamErr
+	}
+	return m.stream(), nil
+}
+
+// runStage: 用 llm 创建一个 Agent 并通过 stageOutput.run 执行
+func runStage(t *testing.T, llm model.BaseChatModel, quiet bool) (*schema.Message, string, error) {
+	t.Helper()
+	chain, err := newAgentChain(context.Background(), llm, "你是作家", "{topic}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	msg, err := newStageOutput(&out, quiet).run(context.Background(), "✍️ 作家", chain, map[string]any{"topic": "AI"})
+	return msg, out.String(), err
+}
+
+func TestStageOutputRunStream(t *testing.T) {
+	msg, out, err := runStage(t, newScriptedModel().on("你是作家", "流式输出的文章"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "流式输出的文章" || msg.ResponseMeta == nil || msg.ResponseMeta.Usage.TotalTokens != 15 {
+		t.Errorf("拼接后的消息 = %+v", msg)
+	}
+	if out != "✍️ 作家\n流式输出的文章\n" {
+		t.Errorf("输出 = %q", out)
+	}
+}
+
+func TestStageOutputRunQuiet(t *testing.T) {
+	llm := &streamModel{scriptedModel: newScriptedModel().on("你是作家", "文章"), streamErr: errors.New("安静模式不应调用 Stream")}
+	msg, out, err := runStage(t, llm, true)
+	if err != nil || msg.Content != "文章" {
+		t.Fatalf("run = %v, %v", msg, err)
+	}
+	if out != "✍️ 作家\n" {
+		t.Errorf("安静模式只输出横幅，得到 %q", out)
+	}
+}
+
+func TestStageOutputRunFallback(t *testing.T) {
+	llm := &streamModel{scriptedModel: newScriptedModel().on("你是作家", "  整段的文章\n"), streamErr: errors.New("不支持流式输出")}
+	msg, out, err := runStage(t, llm, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "  整段的文章\n" || out != "✍️ 作家\n整段的文章\n" {
+		t.Errorf("退回 Invoke 时 = %q，输出 %q", msg.Content, out)
+	}
+
+	llm.failOn("你是作家", errors.New("503"))
+	if _, _, err := runStage(t, llm, false); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Invoke 也失败时 = %v", err)
+	}
+}
+
+func TestStageOutputRunStreamError(t *testing.T) {
+	llm := &streamModel{scriptedModel: newScriptedModel(), stream: func() *schema.StreamReader[*schema.Message] {
+		sr, sw := schema.Pipe[*schema.Message](2)
+		go func() {
+			defer sw.Close()
+			sw.Send(schema.AssistantMessage("写到一半", nil), nil)
+			sw.Send(nil, errors.New("连接断开"))
+		}()
+		return sr
+	}}
+	_, out, err := runStage(t, llm, false)
+	if err == nil || !strings.Contains(err.Error(), "读取流式输出失败") || !strings.Contains(err.Error(), "连接断开") {
+		t.Errorf("流中途失败时 = %v", err)
+	}
+	if out != "✍️ 作家\n写到一半\n" {
+		t.Errorf("已经输出的片段应保留并换行，得到 %q", out)
+	}
+
+	llm.stream = func() *schema.StreamReader[*schema.Message] {
+		return schema.StreamReaderFromArray([]*schema.Message{})
+	}
+	if _, _, err := runStage(t, llm, false); err == nil {
+		t.Error("流式输出为空时应返回错误")
+	}
+}
+
+func TestStageOutputBlock(t *testing.T) {
+	var out bytes.Buffer
+	newStageOutput(&out, false).block("✅ 完成", "  内容\n")
+	newStageOutput(&out, true).block("✅ 安静", "不输出")
+	newStageOutput(&out, false).printf("进度 %d%%\n", 50)
+	if got := out.String(); got != "✅ 完成\n内容\n✅ 安静\n进度 50%\n" {
+		t.Errorf("输出 = %q", got)
+	}
+	// w 为 nil 时丢弃输出
+	newStageOutput(nil, false).block("横幅", "内容")
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+)
+
+// --- 层级模式：监督者动态决定下一步由哪位成员工作 ---
+
+// supervisorFinish: 监督者结束运行时的选择
+const supervisorFinish = "FINISH"
+
+// supervisorSystemPrompt: 监督者的提示词，{workers} 为成员列表；
+// 输出格式用文字描述而不写 JSON 示例，避免花括号与 FString 模板变量冲突
+const supervisorSystemPrompt = `你是博客创作团队的监督者，负责根据当前进度决定下一步由哪位成员工作。
+团队成员：
+{workers}
+
+规则：
+1. 每次只选择一位成员；文章已经完成（编辑通过，或者继续修改已没有必要）时选择 FINISH
+2. 不要连续两次选择同一位成员，除非确实有必要，此时必须在 justification 中说明理由
+3. 最多调度 {max_steps} 步，请尽早完成
+
+只输出一个 JSON 对象，不要输出其他内容。它包含三个字符串字段：
+next 为成员名称或 FINISH；reason 为选择的理由；justification 为连续选择同一位成员的理由，其他情况为空字符串。`
+
+// worker: 监督者可以调度的成员
+type worker struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, state CollaborationState) (CollaborationState, error)
+}
+
+// RoutingDecision: 监督者的一次调度决定
+type RoutingDecision struct {
+	Step          int    `json:"step"`
+	Next          string `json:"next"`                    // Next: 成员名称或 FINISH
+	Reason        string `json:"reason"`                  // Reason: 选择的理由
+	Justification string `json:"justification,omitempty"` // Justification: 连续两次选择同一位成员的理由
+	Rejected      string `json:"rejected,omitempty"`      // Rejected: 决定无效的原因，无效的决定不执行，监督者需要重新选择
+}
+
+// parseRoutingDecision: 解析监督者输出的 JSON 决定（允许包在代码块中或前后有说明文字）；
+// 输出无法解析、成员不存在，或连续两次选择同一位成员（last）却没有给出理由时，决定记为无效
+func parseRoutingDecision(content string, workers []worker, last string) RoutingDecision {
+	var decision RoutingDecision
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		decision.Rejected = "输出中没有 JSON 对象"
+		return decision
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &decision); err != nil {
+		decision.Rejected = fmt.Sprintf("JSON 无法解析: %v", err)
+		return decision
+	}
+	decision.Step = 0
+	decision.Rejected = ""
+	decision.Next = strings.TrimSpace(decision.Next)
+	decision.Reason = strings.TrimSpace(decision.Reason)
+	decision.Justification = strings.TrimSpace(decision.Justification)
+	if strings.EqualFold(decision.Next, supervisorFinish) {
+		decision.Next = supervisorFinish
+		return decision
+	}
+	if findWorker(workers, decision.Next) == nil {
+		decision.Rejected = fmt.Sprintf("没有名为 %q 的成员", decision.Next)
+		return decision
+	}
+	if decision.Next == last && decision.Justification == "" {
+		decision.Rejected = fmt.Sprintf("连续两次选择 %s，但没有在 justification 中说明理由", last)
+	}
+	return decision
+}
+
+// findWorker: 按名称查找成员，不存在时返回 nil
+func findWorker(workers []worker, name string) *worker {
+	for i := range workers {
+		if workers[i].Name == name {
+			return &workers[i]
+		}
+	}
+	return nil
+}
+
+// lastWorker: 最近一次执行的成员（跳过无效的决定），还没有成员执行时为空
+func lastWorker(routing []RoutingDecision) string {
+	for i := len(routing) - 1; i >= 0; i-- {
+		if routing[i].Rejected == "" && routing[i].Next != supervisorFinish {
+			return routing[i].Next
+		}
+	}
+	return ""
+}
+
+// formatRouting: 编号的调度记录，用于最终输出
+func formatRouting(routing []RoutingDecision) string {
+	var sb strings.Builder
+	for _, d := range routing {
+		switch {
+		case d.Rejected != "":
+			sb.WriteString(fmt.Sprintf("%d. %s（无效：%s）\n", d.Step, d.Next, d.Rejected))
+		case d.Justification != "":
+			sb.WriteString(fmt.Sprintf("%d. %s（%s；连续选择的理由：%s）\n", d.Step, d.Next, d.Reason, d.Justification))
+		default:
+			sb.WriteString(fmt.Sprintf("%d. %s（%s）\n", d.Step, d.Next, d.Reason))
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// describeWorkers: 监督者提示词中的成员列表
+func describeWorkers(workers []worker) string {
+	var sb strings.Builder
+	for _, w := range workers {
+		sb.WriteString(fmt.Sprintf("- %s：%s\n", w.Name, w.Description))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// describeProgress: 发给监督者的当前进度：研究、核查、草稿和审阅的状态，以及调度记录
+func describeProgress(state CollaborationState, maxRevisions int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("研究问题：%s\n\n当前进度：\n", state.Query))
+	if state.ResearchBrief == "" {
+		sb.WriteString("- 研究简报：还没有\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("- 研究简报：已完成（%d 个子主题，%d 字，%d 个来源）\n",
+			len(state.Subtopics), articleLength(state.ResearchBrief), len(state.Sources)))
+	}
+	if len(state.FactChecks) == 0 {
+		sb.WriteString("- 事实核查：还没有\n")
+	} else {
+		counts := countVerdicts(state.FactChecks)
+		sb.WriteString(fmt.Sprintf("- 事实核查：已核查 %d 条（已证实 %d，未证实 %d，无法确认 %d）\n",
+			len(state.FactChecks), counts[VerdictSupported], counts[VerdictUnsupported], counts[VerdictUnknown]))
+	}
+	if state.Draft == "" {
+		sb.WriteString("- 草稿：还没有\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("- 草稿：%d 字，已按编辑意见修订 %d 次（建议不超过 %d 次）\n",
+			articleLength(state.Draft), state.Revisions, maxRevisions))
+	}
+	switch {
+	case state.Approved:
+		sb.WriteString("- 编辑审阅：当前草稿已通过\n")
+	case state.EditorNotes != "":
+		sb.WriteString(fmt.Sprintf("- 编辑审阅：有修改意见：%s\n", truncateString(state.EditorNotes, 200)))
+	default:
+		sb.WriteString("- 编辑审阅：还没有审阅当前草稿\n")
+	}
+
+	sb.WriteString("\n调度记录：\n")
+	if len(state.Routing) == 0 {
+		sb.WriteString("（还没有调度任何成员）")
+	}
+	sb.WriteString(formatRouting(state.Routing))
+	return sb.String()
+}
+
+// buildSupervisorTeam: 用监督者代替固定的流程：监督者每一步根据 CollaborationState 选择下一位成员或 FINISH，
+// 选中的成员执行后回到监督者，直到 FINISH、预算用尽或达到 opts.MaxSteps 步：
+// START -> supervisor -> (researcher | fact_checker | writer | editor) -> supervisor -> ... -> END；
+// 调度决定记录在 CollaborationState.Routing 中，无效的决定（如没有理由地连续选择同一位成员）不执行，计一步后重新询问
+func buildSupervisorTeam(ctx context.Context, llm model.BaseChatModel, opts TeamOptions) (compose.Runnable[CollaborationState, CollaborationState], error) {
+	team, err := newBlogTeam(ctx, llm, opts)
+	if err != nil {
+		return nil, err
+	}
+	maxSteps := max(opts.MaxSteps, 1)
+
+	// 成员注册表：研究主管和研究分析师作为一个研究成员，先拆分子主题再并行研究，配置了人工审核时最后由审核人审核简报
+	workers := []worker{
+		{
+			Name:        "researcher",
+			Description: "研究团队：研究主管把研究问题拆分为子主题，研究分析师并行研究，产出研究简报和来源",
+			Run: func(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+				state, err := team.decompose(ctx, state)
+				if err != nil {
+					return state, err
+				}
+				if state, err = team.research(ctx, state); err != nil {
+					return state, err
+				}
+				return team.review(ctx, state)
+			},
+		},
+	}
+	if team.factChecking() {
+		workers = append(workers, worker{
+			Name:        "fact_checker",
+			Description: "事实核查员：用搜索工具核查研究简报中的事实陈述，标注已证实 / 未证实 / 无法确认，需要先有研究简报",
+			Run:         team.factCheck,
+		})
+	}
+	workers = append(workers,
+		worker{
+			Name:        "writer",
+			Description: "技术内容作家：基于研究简报（附事实核查结果）撰写约 500 字的草稿；有编辑意见时按意见修订",
+			Run:         team.write,
+		},
+		worker{
+			Name:        "editor",
+			Description: "编辑：对照研究简报审阅当前草稿，给出通过或修改意见",
+			Run:         team.edit,
+		},
+	)
+
+	supervisorLLM, supervisorModel, err := team.roleModel(ctx, "监督者", opts.Models.Supervisor)
+	if err != nil {
+		return nil, err
+	}
+	supervisorChain, err := newAgentChain(ctx, supervisorLLM, supervisorSystemPrompt, "{progress}")
+	if err != nil {
+		return nil, fmt.Errorf("创建监督者 Agent 失败: %w", err)
+	}
+	fmt.Printf("✅ 监督者 Agent 已创建%s\n", modelTag(supervisorModel))
+
+	graph := compose.NewGraph[CollaborationState, CollaborationState]()
+
+	// 监督者节点：询问监督者直到得到有效的决定；达到最大步数或预算用尽时结束
+	supervisorLambda := compose.InvokableLambda(func(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+		for {
+			if team.stopped("supervisor", &state) {
+				return state, nil
+			}
+			if len(state.Routing) >= maxSteps {
+				state.StoppedReason = fmt.Sprintf("监督者达到最大调度步数 %d", maxSteps)
+				team.out.printf("⚠️ %s，返回已有的结果\n", state.StoppedReason)
+				return state, nil
+			}
+			step := len(state.Routing) + 1
+			stepCtx, done := team.opts.Ledger.track(ctx, "监督者", supervisorModel)
+			result, err := team.runWithRetry(stepCtx, "监督者", fmt.Sprintf("🧭 监督者 Agent 正在决定第 %d/%d 步...%s", step, maxSteps, modelTag(supervisorModel)),
+				supervisorChain, map[string]any{
+					"workers":   describeWorkers(workers),
+					"max_steps": maxSteps,
+					"progress":  describeProgress(state, opts.MaxRevisions),
+				})
+			tokens := done().Total()
+			state.TokensUsed += tokens
+			if err != nil {
+				return state, fmt.Errorf("监督者 Agent 执行失败: %w", err)
+			}
+			decision := parseRoutingDecision(result.Content, workers, lastWorker(state.Routing))
+			decision.Step = step
+			state.Routing = append(state.Routing, decision)
+			switch {
+			case decision.Rejected != "":
+				team.out.printf("⚠️ 监督者第 %d 步的决定无效（%s），重新询问\n", step, decision.Rejected)
+				continue
+			case decision.Justification != "":
+				team.out.printf("🧭 监督者第 %d 步: -> %s（%s；连续选择的理由：%s）\n", step, decision.Next, decision.Reason, decision.Justification)
+			default:
+				team.out.printf("🧭 监督者第 %d 步: -> %s（%s）\n", step, decision.Next, decision.Reason)
+			}
+			// 有效的决定作为消息发给被选中的成员（结束时发给所有成员）
+			to := decision.Next
+			if to == supervisorFinish {
+				to = broadcast
+			}
+			team.publish("监督者", to, KindVerdict, decision.Reason, tokens)
+			return state, nil
+		}
+	})
+	if err := graph.AddLambdaNode("supervisor", supervisorLambda); err != nil {
+		return nil, fmt.Errorf("添加监督者节点失败: %w", err)
+	}
+	if err := graph.AddEdge(compose.START, "supervisor"); err != nil {
+		return nil, fmt.Errorf("添加 START->supervisor 边失败: %w", err)
+	}
+
+	// 每位成员一个节点，执行后回到监督者；成员完成后同样保存检查点（监督者模式不支持从检查点继续）
+	targets := map[string]bool{compose.END: true}
+	for _, w := range workers {
+		run := opts.Store.checkpointed(w.Name, team.out, team.bus, w.Run)
+		if err := graph.AddLambdaNode(w.Name, compose.InvokableLambda(run)); err != nil {
+			return nil, fmt.Errorf("添加成员 %s 节点失败: %w", w.Name, err)
+		}
+		if err := graph.AddEdge(w.Name, "supervisor"); err != nil {
+			return nil, fmt.Errorf("添加 %s->supervisor 边失败: %w", w.Name, err)
+		}
+		targets[w.Name] = true
+	}
+
+	// 监督者之后的分支：按最后一个决定执行成员；FINISH、预算用尽或达到最大步数时结束
+	supervisorBranch := compose.NewGraphBranch(func(ctx context.Context, state CollaborationState) (string, error) {
+		if state.StoppedReason != "" || len(state.Routing) == 0 {
+			return compose.END, nil
+		}
+		if next := state.Routing[len(state.Routing)-1].Next; next != supervisorFinish {
+			return next, nil
+		}
+		team.out.printf("🏁 监督者决定结束\n")
+		return compose.END, nil
+	}, targets)
+	if err := graph.AddBranch("supervisor", supervisorBranch); err != nil {
+		return nil, fmt.Errorf("添加监督者分支失败: %w", err)
+	}
+
+	// 每一步最多走监督者和一位成员两个节点
+	compiledGraph, err := graph.Compile(ctx, compose.WithMaxRunSteps(2*maxSteps+4))
+	if err != nil {
+		return nil, fmt.Errorf("编译监督者 Graph 失败: %w", err)
+	}
+	return compiledGraph, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseRoutingDecision(t *testing.T) {
+	workers := []worker{{Name: "researcher"}, {Name: "writer"}}
+	tests := []struct {
+		name     string
+		content  string
+		last     string
+		next     string
+		rejected string
+	}{
+		{"选择成员", `{"next": " writer ", "reason": "有简报了"}`, "researcher", "writer", ""},
+		{"结束", "```json\n{\"next\": \"finish\", \"reason\": \"完成\"}\n```", "writer", supervisorFinish, ""},
+		{"连续选择并说明理由", `{"next": "writer", "reason": "r", "justification": "上次超时"}`, "writer", "writer", ""},
+		{"连续选择没有理由", `{"next": "writer", "reason": "r", "justification": " "}`, "writer", "writer", "连续两次选择 writer，但没有在 justification 中说明理由"},
+		{"成员不存在", `{"next": "designer"}`, "", "designer", `没有名为 "designer" 的成员`},
+		{"没有 JSON", "下一步让作家写", "", "", "输出中没有 JSON 对象"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRoutingDecision(tt.content, workers, tt.last)
+			if got.Next != tt.next || got.Rejected != tt.rejected {
+				t.Errorf("parseRoutingDecision = %+v，期望 next %q、rejected %q", got, tt.next, tt.rejected)
+			}
+		})
+	}
+	// 模型在 JSON 里写的 step 和 rejected 不采用
+	if got := parseRoutingDecision(`{"next": "writer", "step": 9, "rejected": "x"}`, workers, ""); got.Step != 0 || got.Rejected != "" {
+		t.Errorf("应忽略模型给出的 step 和 rejected，得到 %+v", got)
+	}
+	if got := parseRoutingDecision(`{"next": }`, workers, ""); !strings.HasPrefix(got.Rejected, "JSON 无法解析") {
+		t.Errorf("JSON 无效时 = %+v", got)
+	}
+}
+
+func TestRoutingRecord(t *testing.T) {
+	routing := []RoutingDecision{
+		{Step: 1, Next: "researcher", Reason: "先研究"},
+		{Step: 2, Next: "writer", Reason: "写初稿"},
+		{Step: 3, Next: "writer", Rejected: "连续两次选择 writer"},
+		{Step: 4, Next: "writer", Reason: "重写", Justification: "初稿太短"},
+		{Step: 5, Next: supervisorFinish, Reason: "完成"},
+	}
+	if got := lastWorker(routing); got != "writer" {
+		t.Errorf("lastWorker = %q", got)
+	}
+	if got := lastWorker(routing[:3]); got != "writer" {
+		t.Errorf("应跳过无效的决定，得到 %q", got)
+	}
+	if got := lastWorker(nil); got != "" {
+		t.Errorf("没有调度时 = %q", got)
+	}
+	want := "1. researcher（先研究）\n2. writer（写初稿）\n3. writer（无效：连续两次选择 writer）\n" +
+		"4. writer（重写；连续选择的理由：初稿太短）\n5. FINISH（完成）"
+	if got := formatRouting(routing); got != want {
+		t.Errorf("formatRouting =\n%s\n期望\n%s", got, want)
+	}
+}
+
+func TestDescribeProgress(t *testing.T) {
+	got := describeProgress(CollaborationState{Query: "AI 趋势"}, 2)
+	for _, want := range []string{"研究问题：AI 趋势", "- 研究简报：还没有", "- 草稿：还没有", "- 编辑审阅：还没有审阅当前草稿", "（还没有调度任何成员）"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("开始时的进度缺少 %q：\n%s", want, got)
+		}
+	}
+
+	got = describeProgress(CollaborationState{
+		Query:         "AI 趋势",
+		Subtopics:     []string{"模型"},
+		ResearchBrief: "简报",
+		FactChecks:    []ClaimCheck{{Verdict: VerdictSupported}, {Verdict: VerdictUnknown}},
+		Draft:         "草稿",
+		Revisions:     1,
+		EditorNotes:   "补充例子",
+		Routing:       []RoutingDecision{{Step: 1, Next: "researcher", Reason: "先研究"}},
+	}, 2)
+	for _, want := range []string{"- 研究简报：已完成（1 个子主题，2 字，0 个来源）", "- 事实核查：已核查 2 条（已证实 1，未证实 0，无法确认 1）",
+		"- 草稿：2 字，已按编辑意见修订 1 次（建议不超过 2 次）", "- 编辑审阅：有修改意见：补充例子", "调度记录：\n1. researcher（先研究）"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("进度缺少 %q：\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "还没有调度") {
+		t.Errorf("有调度记录时不应说明还没有调度：\n%s", got)
+	}
+}
+
+// runSupervisor: 用安静模式的输出运行监督者模式的 Graph
+func runSupervisor(t *testing.T, llm *scriptedModel, opts TeamOptions) (CollaborationState, string) {
+	t.Helper()
+	var out bytes.Buffer
+	opts.Output = newStageOutput(&out, true)
+	opts.MaxSubtopics = 3
+	runnable, err := buildSupervisorTeam(context.Background(), llm, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := runnable.Invoke(context.Background(), CollaborationState{Query: "AI 趋势"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state, out.String()
+}
+
+func TestSupervisorTeam(t *testing.T) {
+	llm := newTeamModel().on(supervisorSystemPrompt,
+		`{"next": "researcher", "reason": "先研究"}`,
+		`{"next": "writer", "reason": "写初稿"}`,
+		`{"next": "writer", "reason": "再写一遍"}`,
+		`{"next": "editor", "reason": "审阅"}`,
+		`{"next": "FINISH", "reason": "编辑通过"}`,
+	)
+	bus := NewMessageBus()
+	state, out := runSupervisor(t, llm, TeamOptions{MaxSteps: 6, MaxRevisions: 1, Bus: bus})
+
+	want := "1. researcher（先研究）\n2. writer（写初稿）\n3. writer（无效：连续两次选择 writer，但没有在 justification 中说明理由）\n" +
+		"4. editor（审阅）\n5. FINISH（编辑通过）"
+	if got := formatRouting(state.Routing); got != want {
+		t.Errorf("调度记录 =\n%s\n期望\n%s", got, want)
+	}
+	if !state.Approved || state.Draft != "初稿" || state.StoppedReason != "" {
+		t.Errorf("最终状态 = %+v", state)
+	}
+	if n := len(llm.inputs(writingSystemPrompt)); n != 1 {
+		t.Errorf("无效的决定不应执行，作家调用了 %d 次", n)
+	}
+	progress := llm.inputs(supervisorSystemPrompt)
+	if len(progress) != 5 || !strings.Contains(progress[3], "3. writer（无效：") || !strings.Contains(progress[4], "- 编辑审阅：当前草稿已通过") {
+		t.Errorf("监督者看到的进度 = %q", progress)
+	}
+	if !strings.Contains(out, "⚠️ 监督者第 3 步的决定无效") || !strings.Contains(out, "🏁 监督者决定结束") {
+		t.Errorf("输出 =\n%s", out)
+	}
+	// 有效的决定作为消息发给被选中的成员，结束时发给所有成员
+	var decisions []string
+	for _, msg := range bus.History() {
+		if msg.From == "监督者" {
+			decisions = append(decisions, msg.To+"："+msg.Content)
+		}
+	}
+	if got := strings.Join(decisions, "，"); got != "researcher：先研究，writer：写初稿，editor：审阅，全体：编辑通过" {
+		t.Errorf("监督者的消息 = %s", got)
+	}
+}
+
+func TestSupervisorMaxSteps(t *testing.T) {
+	llm := newTeamModel().on(supervisorSystemPrompt, `{"next": "designer"}`)
+	state, out := runSupervisor(t, llm, TeamOptions{MaxSteps: 3})
+	if state.StoppedReason != "监督者达到最大调度步数 3" || len(state.Routing) != 3 {
+		t.Errorf("StoppedReason = %q，调度 %d 步", state.StoppedReason, len(state.Routing))
+	}
+	if !strings.Contains(out, "⚠️ 监督者达到最大调度步数 3，返回已有的结果") {
+		t.Errorf("输出 =\n%s", out)
+	}
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"shared/ratelimit"
+	"shared/retry"
+	"shared/stats"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+)
+
+// TeamOptions: 团队的配置
+type TeamOptions struct {
+	MaxRevisions int                // MaxRevisions: 编辑退回作家修订的最多次数
+	MaxSubtopics int                // MaxSubtopics: 研究问题最多拆分的子主题数，每个子主题一个研究分析师
+	Limiter      *ratelimit.Limiter // Limiter: 并行的研究分析师和作家变体共享的限流器，nil 表示不限流
+	Output       *stageOutput       // Output: 各阶段的横幅和 Agent 的实时输出，nil 时实时输出到标准输出
+	Ledger       *UsageLedger       // Ledger: 按 Agent 累计用量和费用，并检查预算，nil 表示不统计
+	ModelName    string             // ModelName: 默认模型的名称，用于标注输出和按价格表估算费用
+	Models       AgentModels        // Models: 各角色使用的模型，为空的角色使用默认模型
+	NewModel     ModelFactory       // NewModel: 按名称创建 Models 中与默认模型不同的模型
+	FactCheck    int                // FactCheck: 事实核查员最多核查的陈述数，0 表示不核查
+	Search       tool.BaseTool      // Search: 事实核查员使用的搜索工具，nil 表示不核查
+	MaxSteps     int                // MaxSteps: 监督者模式下最多的调度步数（每次询问监督者计一步）
+	Retry        retry.Policy       // Retry: 每次 Agent 调用的重试策略，零值表示不重试
+	Store        *RunStore          // Store: 每个阶段完成后保存检查点的运行目录，nil 表示不保存
+	Bus          *MessageBus        // Bus: 各 Agent 之间交接的消息总线，nil 时使用新的总线
+	Review       *ReviewGate        // Review: 研究完成后的人工审核，nil 表示不审核
+	Variants     int                // Variants: >1 时初稿由多个作家变体并发撰写，评审选出最好的一篇，修订仍只由一位作家完成
+	ResumeFrom   string             // ResumeFrom: 从这个阶段进入固定流程的 Graph（输入为上一阶段的检查点状态），为空时从头开始
+}
+
+// newAgentChain: 创建一个 Agent Chain：Template -> ChatModel，每个 Chain 代表一个具有自己角色和职责的 Agent
+func newAgentChain(ctx context.Context, llm model.BaseChatModel, systemPrompt, userTemplate string) (compose.Runnable[map[string]any, *schema.Message], error) {
+	template := prompt.FromMessages(
+		schema.FString,
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(userTemplate),
+	)
+	return compose.NewChain[map[string]any, *schema.Message]().
+		AppendChatTemplate(template).
+		AppendChatModel(llm).
+		Compile(ctx)
+}
+
+// ========== 第一个 Agent：研究分析师 ==========
+// 角色：高级研究分析师
+// 目标：深入研究分到的子主题
+// 背景：经验丰富的研究分析师，擅长识别关键趋势和综合信息
+const researchSystemPrompt = `你是一位经验丰富的研究分析师，擅长识别关键趋势和综合信息。
+你的任务是深入研究分到的子主题，重点关注实际应用和潜在影响。
+请提供详细、准确且有价值的研究结果，不要展开其他子主题。`
+
+// ========== 第二个 Agent：技术内容作家 ==========
+// 角色：技术内容作家
+// 目标：基于研究发现撰写清晰且引人入胜的博客文章
+// 背景：熟练的作家，可以将复杂的技术主题转化为易于理解的内容
+const writingSystemPrompt = `你是一位熟练的作家，可以将复杂的技术主题转化为易于理解的内容。
+你的任务是基于研究发现撰写清晰且引人入胜的博客文章。
+文章应该引人入胜且易于普通读者理解。`
+
+// writingUserTemplate: 作家的用户消息；style 为作家变体的风格要求，不使用变体时为空；
+// revision_request 在修订时包含上一版草稿和编辑的修改意见，第一稿时为空
+const writingUserTemplate = "基于以下研究发现，撰写一篇 500 字的博客文章：\n\n{research_results}\n\n可以引用的来源：\n{sources}\n\n请确保文章引人入胜且易于普通读者理解。{style}{revision_request}"
+
+// revisionRequest: 修订时附在作家消息后面的内容；notes 为空（第一稿）时为空
+func revisionRequest(revision int, draft, notes string) string {
+	if notes == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\n这是第 %d 次修订。上一版草稿：\n\n%s\n\n编辑的修改意见：\n\n%s\n\n请根据修改意见修订文章，只输出修订后的完整文章。",
+		revision, draft, notes)
+}
+
+// blogTeam: 团队的各个 Agent 及其模型；每个阶段是一个读写 CollaborationState 的方法，
+// 固定流程的 Graph（buildBlogTeam）和监督者路由（buildSupervisorTeam）都由这些阶段组成
+type blogTeam struct {
+	opts         TeamOptions
+	out          *stageOutput
+	bus          *MessageBus // bus: 各阶段从总线读取输入、把输出作为消息发布
+	maxSubtopics int
+	pool         *modelPool // pool: 各角色的模型，名称相同的角色共享同一个实例
+
+	researcherModel, writerModel, editorModel, factCheckerModel string
+
+	decomposeChain  compose.Runnable[map[string]any, *schema.Message]
+	researcherChain compose.Runnable[map[string]any, *schema.Message]
+	repairChain     compose.Runnable[map[string]any, *schema.Message] // repairChain: 研究分析师的输出无法解析时修复一次
+	claimChain      compose.Runnable[map[string]any, *schema.Message] // claimChain: 不核查时为 nil
+	verifier        *react.Agent                                      // verifier: 不核查时为 nil
+	writerChain     compose.Runnable[map[string]any, *schema.Message]
+	editorChain     compose.Runnable[map[string]any, *schema.Message]
+	judgeChain      compose.Runnable[map[string]any, *schema.Message] // judgeChain: 评审，与编辑使用同一个模型；不使用作家变体时为 nil
+}
+
+// newBlogTeam: 创建研究主管、研究分析师、事实核查员（配置了搜索工具和 opts.FactCheck 时）、技术内容作家和编辑
+func newBlogTeam(ctx context.Context, llm model.BaseChatModel, opts TeamOptions) (*blogTeam, error) {
+	t := &blogTeam{opts: opts, out: opts.Output, maxSubtopics: max(opts.MaxSubtopics, 1)}
+	if t.out == nil {
+		t.out = newStageOutput(os.Stdout, false)
+	}
+	t.pool = newModelPool(llm, opts.ModelName, opts.NewModel)
+	if t.bus = opts.Bus; t.bus == nil {
+		t.bus = NewMessageBus()
+	}
+	researcherLLM, researcherModel, err := t.roleModel(ctx, "研究分析师", opts.Models.Researcher)
+	if err != nil {
+		return nil, err
+	}
+	writerLLM, writerModel, err := t.roleModel(ctx, "技术内容作家", opts.Models.Writer)
+	if err != nil {
+		return nil, err
+	}
+	editorLLM, editorModel, err := t.roleModel(ctx, "编辑", opts.Models.Editor)
+	if err != nil {
+		return nil, err
+	}
+	t.researcherModel, t.writerModel, t.editorModel = researcherModel, writerModel, editorModel
+
+	// 研究主管与研究分析师使用同一个模型
+	if t.decomposeChain, err = newAgentChain(ctx, researcherLLM, decomposeSystemPrompt, "{query}"); err != nil {
+		return nil, fmt.Errorf("创建研究主管 Agent 失败: %w", err)
+	}
+	fmt.Printf("✅ 研究主管 Agent 已创建%s\n", modelTag(researcherModel))
+
+	if t.researcherChain, err = newAgentChain(ctx, researcherLLM, researchSystemPrompt, researcherUserTemplate); err != nil {
+		return nil, fmt.Errorf("创建研究 Agent 失败: %w", err)
+	}
+	if t.repairChain, err = newAgentChain(ctx, researcherLLM, reportRepairSystemPrompt, reportRepairUserTemplate); err != nil {
+		return nil, fmt.Errorf("创建研究 Agent 失败: %w", err)
+	}
+	fmt.Printf("✅ 研究分析师 Agent 已创建%s\n", modelTag(researcherModel))
+
+	// 事实核查员是调用搜索工具的 ReAct Agent，模型需要支持工具调用；不核查时不创建
+	if t.factChecking() {
+		m, resolved, err := t.pool.get(ctx, opts.Models.FactChecker)
+		if err != nil {
+			return nil, fmt.Errorf("创建事实核查员的模型失败: %w", err)
+		}
+		factCheckerLLM, err := newCountingToolModel(m)
+		if err != nil {
+			return nil, fmt.Errorf("事实核查员的模型不可用: %w", err)
+		}
+		t.factCheckerModel = resolved
+		if t.claimChain, err = newAgentChain(ctx, factCheckerLLM, claimExtractSystemPrompt, "{brief}"); err != nil {
+			return nil, fmt.Errorf("创建事实核查 Agent 失败: %w", err)
+		}
+		if t.verifier, err = newClaimVerifier(ctx, factCheckerLLM, opts.Search); err != nil {
+			return nil, fmt.Errorf("创建事实核查 Agent 失败: %w", err)
+		}
+		fmt.Printf("✅ 事实核查员 Agent 已创建%s\n", modelTag(resolved))
+	}
+
+	if t.writerChain, err = newAgentChain(ctx, writerLLM, writingSystemPrompt, writingUserTemplate); err != nil {
+		return nil, fmt.Errorf("创建写作 Agent 失败: %w", err)
+	}
+	fmt.Printf("✅ 技术内容作家 Agent 已创建%s\n", modelTag(writerModel))
+
+	if t.editorChain, err = newAgentChain(ctx, editorLLM, editorSystemPrompt, editorUserTemplate); err != nil {
+		return nil, fmt.Errorf("创建编辑 Agent 失败: %w", err)
+	}
+	fmt.Printf("✅ 编辑 Agent 已创建%s\n", modelTag(editorModel))
+
+	if opts.Variants > 1 {
+		if t.judgeChain, err = newAgentChain(ctx, editorLLM, judgeSystemPrompt, judgeUserTemplate); err != nil {
+			return nil, fmt.Errorf("创建评审 Agent 失败: %w", err)
+		}
+		fmt.Printf("✅ 评审 Agent 已创建%s（%d 个作家变体）\n", modelTag(editorModel), opts.Variants)
+	}
+	return t, nil
+}
+
+// roleModel: 角色的模型和实际使用的名称；用 CountingModel 包装，每个 Agent 的调用用量记到 track 挂在上下文上的累加器
+func (t *blogTeam) roleModel(ctx context.Context, role, name string) (model.BaseChatModel, string, error) {
+	m, resolved, err := t.pool.get(ctx, name)
+	if err != nil {
+		return nil, "", fmt.Errorf("创建%s的模型失败: %w", role, err)
+	}
+	return stats.NewCountingModel(m), resolved, nil
+}
+
+// factChecking: 是否配置了事实核查
+func (t *blogTeam) factChecking() bool {
+	return t.opts.FactCheck > 0 && t.opts.Search != nil
+}
+
+// stopped: 阶段开始前检查预算，超出时记录原因；之后的阶段都直接传递已有的结果
+func (t *blogTeam) stopped(stage string, state *CollaborationState) bool {
+	if state.StoppedReason != "" {
+		return true
+	}
+	reason, exceeded := t.opts.Ledger.Exceeded()
+	if !exceeded {
+		return false
+	}
+	state.StoppedReason = fmt.Sprintf("%s，未开始 %s", reason, stage)
+	t.out.printf("⛔ 预算已用尽（%s），不再开始 %s，返回已有的结果\n", reason, stage)
+	return true
+}
+
+// decompose: 研究主管把研究问题拆分为子主题
+// 输出无法解析时退回为只有一个子主题（原问题），仍由一个研究分析师完成
+func (t *blogTeam) decompose(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+	if t.stopped("decompose_query", &state) {
+		return state, nil
+	}
+	query := t.receive("用户", "研究主管", KindBrief, state.Query)
+	ctx, done := t.opts.Ledger.track(ctx, "研究主管", t.researcherModel)
+	result, err := t.runWithRetry(ctx, "研究主管", "🧭 研究主管 Agent 正在拆分研究问题..."+modelTag(t.researcherModel), t.decomposeChain, map[string]any{
+		"query":         query,
+		"max_subtopics": t.maxSubtopics,
+	})
+	tokens := done().Total()
+	state.TokensUsed += tokens
+	if err != nil {
+		return state, fmt.Errorf("研究主管 Agent 执行失败: %w", err)
+	}
+	subtopics, err := parseStringArray(result.Content, t.maxSubtopics)
+	if err != nil {
+		t.out.printf("⚠️ 拆分研究问题失败（%v），由一位研究分析师研究整个问题\n", err)
+		subtopics = []string{query}
+	}
+	t.out.printf("✅ 研究问题已拆分为 %d 个子主题:\n", len(subtopics))
+	// 每个子主题作为一条消息分派给一位研究分析师，拆分消耗的 token 记在第一条消息上
+	for i, subtopic := range subtopics {
+		t.out.printf("   %d. %s\n", i+1, subtopic)
+		if i > 0 {
+			tokens = 0
+		}
+		t.publish("研究主管", analystName(i), KindBrief, subtopic, tokens)
+	}
+	state.Subtopics = subtopics
+	printStage(t.out, "decompose_query", state)
+	return state, nil
+}
+
+// research: 每个子主题一个研究分析师并行研究，合并为结构化的研究报告，并渲染为分节固定的研究简报
+func (t *blogTeam) research(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+	if t.stopped("research_subtopics", &state) {
+		return state, nil
+	}
+	subtopics := make([]string, len(state.Subtopics))
+	for i, subtopic := range state.Subtopics {
+		subtopics[i] = t.receive("研究主管", analystName(i), KindBrief, subtopic)
+	}
+	findings, err := t.researchInParallel(ctx, state.Query, subtopics)
+	if err != nil {
+		return state, err
+	}
+	for _, finding := range findings {
+		state.TokensUsed += finding.Tokens
+	}
+	report, err := mergeFindings(subtopics, findings)
+	if err != nil {
+		return state, fmt.Errorf("研究 Agent 执行失败: %w", err)
+	}
+	state.Report = &report
+	state.ResearchBrief = renderReport(state.Query, report)
+	state.Sources = report.Sources
+	if err := t.opts.Store.WriteResearch(state); err != nil {
+		t.out.printf("⚠️ 保存研究报告失败: %v\n", err)
+	}
+	t.out.printf("✅ 研究简报已合并\n")
+	t.publish("研究团队", broadcast, KindBrief, state.ResearchBrief, 0)
+	printStage(t.out, "research_subtopics", state)
+	return state, nil
+}
+
+// factCheck: 事实核查员从研究简报中提取事实陈述，用搜索工具逐条核查前 opts.FactCheck 条
+// 提取失败时跳过核查，单条核查失败时判定为无法确认，都不中断运行
+func (t *blogTeam) factCheck(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+	if !t.factChecking() || state.ResearchBrief == "" || t.stopped("fact_check", &state) {
+		return state, nil
+	}
+	tokensBefore := state.TokensUsed
+	extractCtx, done := t.opts.Ledger.track(ctx, "事实核查员", t.factCheckerModel)
+	result, err := t.runWithRetry(extractCtx, "事实核查员", "🔎 事实核查员 Agent 正在提取事实陈述..."+modelTag(t.factCheckerModel), t.claimChain, map[string]any{
+		"brief":      t.receive("研究团队", "事实核查员", KindBrief, state.ResearchBrief),
+		"max_claims": t.opts.FactCheck,
+	})
+	state.TokensUsed += done().Total()
+	if err != nil {
+		t.out.printf("⚠️ 提取事实陈述失败（%s），跳过事实核查\n", errorLine(err))
+		return state, nil
+	}
+	claims, err := parseStringArray(result.Content, t.opts.FactCheck)
+	if err != nil {
+		t.out.printf("⚠️ 提取事实陈述失败（%v），跳过事实核查\n", err)
+		return state, nil
+	}
+	state.FactChecks = nil
+	for i, claim := range claims {
+		// 预算在核查过程中用尽时，其余陈述不再核查
+		if reason, exceeded := t.opts.Ledger.Exceeded(); exceeded {
+			state.FactChecks = append(state.FactChecks, ClaimCheck{Claim: claim, Verdict: VerdictUnknown, Evidence: "预算已用尽，未核查（" + reason + "）"})
+			continue
+		}
+		t.out.printf("🔎 事实核查员正在核查第 %d/%d 条: %s\n", i+1, len(claims), claim)
+		verifyCtx, done := t.opts.Ledger.track(ctx, "事实核查员", t.factCheckerModel)
+		check := verifyClaim(verifyCtx, t.verifier, claim)
+		state.TokensUsed += done().Total()
+		t.out.printf("   %s %s\n", check.Verdict.Label(), check.Evidence)
+		state.FactChecks = append(state.FactChecks, check)
+	}
+	counts := countVerdicts(state.FactChecks)
+	t.out.printf("✅ 事实核查完成：已证实 %d 条，未证实 %d 条，无法确认 %d 条\n",
+		counts[VerdictSupported], counts[VerdictUnsupported], counts[VerdictUnknown])
+	t.publish("事实核查员", broadcast, KindVerdict, formatClaimChecks(state.FactChecks), state.TokensUsed-tokensBefore)
+	printStage(t.out, "fact_check", state)
+	return state, nil
+}
+
+// write: 执行写作 Agent
+// 第一次写初稿；有编辑意见时带上上一版草稿和修改意见进行修订；简报附有事实核查结果
+func (t *blogTeam) write(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+	if t.stopped("writer_agent", &state) {
+		return state, nil
+	}
+	if t.opts.Variants > 1 && state.EditorNotes == "" {
+		return t.writeVariants(ctx, state)
+	}
+	banner := "✍️  技术内容作家 Agent 正在工作..."
+	if state.EditorNotes != "" {
+		banner = fmt.Sprintf("✍️  技术内容作家 Agent 正在按编辑意见修订（第 %d/%d 次）...", state.Revisions+1, t.opts.MaxRevisions)
+	}
+	// 修订时从总线读取上一版草稿和编辑的修改意见
+	var revision string
+	if state.EditorNotes != "" {
+		revision = revisionRequest(state.Revisions+1,
+			t.receive("技术内容作家", "编辑", KindDraft, state.Draft),
+			t.receiveNotes("编辑", "技术内容作家", state.EditorNotes))
+	}
+	ctx, done := t.opts.Ledger.track(ctx, "技术内容作家", t.writerModel)
+	result, err := t.runWithRetry(ctx, "技术内容作家", banner+modelTag(t.writerModel), t.writerChain, map[string]any{
+		"research_results": t.writingBrief("技术内容作家", state),
+		"sources":          formatSources(state.Sources),
+		"style":            "",
+		"revision_request": revision,
+	})
+	tokens := done().Total()
+	state.TokensUsed += tokens
+	if err != nil {
+		return state, fmt.Errorf("写作 Agent 执行失败: %w", err)
+	}
+	if state.EditorNotes != "" {
+		state.Revisions++
+	}
+	state.Draft = result.Content
+	// 新草稿需要重新审阅
+	state.Approved = false
+	t.out.printf("✅ 技术内容作家 Agent 完成工作\n")
+	t.publish("技术内容作家", "编辑", KindDraft, state.Draft, tokens)
+	if _, err := t.opts.Store.WriteDraft(state.Draft); err != nil {
+		t.out.printf("⚠️ 保存草稿失败: %v\n", err)
+	}
+	printStage(t.out, "writer_agent", state)
+	return state, nil
+}
+
+// edit: 执行编辑 Agent
+// 对照研究结果（附事实核查结果）和来源审阅草稿，给出通过或修改意见
+func (t *blogTeam) edit(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+	if t.stopped("editor_agent", &state) {
+		return state, nil
+	}
+	draft := t.receive("技术内容作家", "编辑", KindDraft, state.Draft)
+	ctx, done := t.opts.Ledger.track(ctx, "编辑", t.editorModel)
+	result, err := t.runWithRetry(ctx, "编辑", "🧐 编辑 Agent 正在审阅草稿..."+modelTag(t.editorModel), t.editorChain, map[string]any{
+		"research_results": t.writingBrief("编辑", state),
+		"sources":          formatSources(state.Sources),
+		"draft":            draft,
+		"length":           articleLength(draft),
+	})
+	tokens := done().Total()
+	state.TokensUsed += tokens
+	if err != nil {
+		return state, fmt.Errorf("编辑 Agent 执行失败: %w", err)
+	}
+	state.Approved, state.EditorNotes = parseEditorVerdict(result.Content)
+	if state.Approved {
+		t.out.printf("✅ 编辑 Agent 审阅通过\n")
+		t.publish("编辑", "技术内容作家", KindVerdict, "通过", tokens)
+	} else {
+		t.out.printf("📝 编辑 Agent 提出修改意见: %s\n", truncateString(state.EditorNotes, 100))
+		t.publish("编辑", "技术内容作家", KindNotes, state.EditorNotes, tokens)
+	}
+	printStage(t.out, "editor_agent", state)
+	return state, nil
+}
+
+// buildBlogTeam: 创建研究主管、研究分析师、事实核查员、技术内容作家和编辑，并用固定流程的 Graph 协调它们：
+// START -> decompose_query -> research_subtopics -> fact_check -> writer_agent -> editor_agent -> END，
+// 节点之间传递 CollaborationState；research_subtopics 中每个子主题由一个研究分析师并行研究；
+// fact_check 用搜索工具核查简报中最多 opts.FactCheck 条事实陈述，没有配置时直接传递；
+// 编辑提出修改意见且修订次数未达到 opts.MaxRevisions 时，从 editor_agent 返回 writer_agent；
+// 每个阶段完成后把状态保存到 opts.Store，设置 opts.ResumeFrom 时从该阶段进入 Graph
+func buildBlogTeam(ctx context.Context, llm model.BaseChatModel, opts TeamOptions) (compose.Runnable[CollaborationState, CollaborationState], error) {
+	team, err := newBlogTeam(ctx, llm, opts)
+	if err != nil {
+		return nil, err
+	}
+	maxRevisions := opts.MaxRevisions
+
+	// ========== 创建多 Agent 协作 Graph ==========
+	// 使用 Graph 来协调多个 Agent 的执行，每个阶段一个 Lambda 节点
+	// 输入：只填写了 Query 的 CollaborationState
+	// 输出：各字段都由负责的 Agent 填写的 CollaborationState
+	graph := compose.NewGraph[CollaborationState, CollaborationState]()
+	nodes := []struct {
+		key string
+		run func(context.Context, CollaborationState) (CollaborationState, error)
+	}{
+		{"decompose_query", team.decompose},
+		{"research_subtopics", team.research},
+		{"human_review", team.review},
+		{"fact_check", team.factCheck},
+		{"writer_agent", team.write},
+		{"editor_agent", team.edit},
+	}
+	for _, node := range nodes {
+		run := opts.Store.checkpointed(node.key, team.out, team.bus, node.run)
+		if err := graph.AddLambdaNode(node.key, compose.InvokableLambda(run)); err != nil {
+			return nil, fmt.Errorf("添加 %s 节点失败: %w", node.key, err)
+		}
+	}
+
+	// ========== 定义边的连接 ==========
+	// 执行流程：
+	// START -> decompose_query -> research_subtopics -> human_review -> fact_check -> writer_agent -> editor_agent -> (writer_agent | END)
+	// 研究 Agent 先并行工作，审核人审核研究简报（配置了人工审核时），事实核查员核查研究简报，然后写作 Agent 基于核查后的研究结果工作，编辑 Agent 把关质量，必要时退回作家修订
+	// 从检查点继续时，START 通过分支直接进入失败的阶段
+	if opts.ResumeFrom == "" {
+		if err := graph.AddEdge(compose.START, "decompose_query"); err != nil {
+			return nil, fmt.Errorf("添加 START->decompose_query 边失败: %w", err)
+		}
+	} else {
+		if !slices.Contains(teamStages, opts.ResumeFrom) {
+			return nil, fmt.Errorf("未知的阶段 %q（可选：%s）", opts.ResumeFrom, strings.Join(teamStages, "、"))
+		}
+		targets := map[string]bool{}
+		for _, stage := range teamStages {
+			targets[stage] = true
+		}
+		resumeBranch := compose.NewGraphBranch(func(ctx context.Context, state CollaborationState) (string, error) {
+			return opts.ResumeFrom, nil
+		}, targets)
+		if err := graph.AddBranch(compose.START, resumeBranch); err != nil {
+			return nil, fmt.Errorf("添加继续运行分支失败: %w", err)
+		}
+	}
+	edges := [][2]string{
+		{"decompose_query", "research_subtopics"},
+		{"research_subtopics", "human_review"},
+		{"human_review", "fact_check"},
+		{"fact_check", "writer_agent"},
+		{"writer_agent", "editor_agent"},
+	}
+	for _, e := range edges {
+		if err := graph.AddEdge(e[0], e[1]); err != nil {
+			return nil, fmt.Errorf("添加 %s->%s 边失败: %w", e[0], e[1], err)
+		}
+	}
+
+	// 编辑之后的分支（与第 11 章的 Judge 分支相同的模式）：通过、达到修订上限或预算用尽时结束，否则返回作家修订
+	editorBranch := compose.NewGraphBranch(func(ctx context.Context, state CollaborationState) (string, error) {
+		if state.Approved || state.StoppedReason != "" {
+			return compose.END, nil
+		}
+		if state.Revisions >= maxRevisions {
+			team.out.printf("⚠️ 达到最大修订次数，使用当前草稿。\n")
+			return compose.END, nil
+		}
+		team.out.printf("🔄 退回技术内容作家修订。\n")
+		return "writer_agent", nil
+	}, map[string]bool{
+		"writer_agent": true,
+		compose.END:    true,
+	})
+	if err := graph.AddBranch("editor_agent", editorBranch); err != nil {
+		return nil, fmt.Errorf("添加编辑分支失败: %w", err)
+	}
+
+	// 编译 Graph；每次修订多走 writer_agent 和 editor_agent 两步，按修订上限放宽最大步数
+	compiledGraph, err := graph.Compile(ctx, compose.WithMaxRunSteps(2*maxRevisions+10))
+	if err != nil {
+		return nil, fmt.Errorf("编译 Graph 失败: %w", err)
+	}
+	return compiledGraph, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// scriptedModel: 按系统提示词的第一行区分角色的假模型：每个角色的回复依次使用，最后一条重复使用；
+// 记录每个角色收到的用户消息，每次回复带固定的 token 用量（提示 10、生成 5）
+type scriptedModel struct {
+	mu      sync.Mutex
+	replies map[string][]string
+	errs    map[string]error
+	calls   map[string][]string
+}
+
+var _ model.ToolCallingChatModel = (*scriptedModel)(nil)
+
+func newScriptedModel() *scriptedModel {
+	return &scriptedModel{replies: map[string][]string{}, errs: map[string]error{}, calls: map[string][]string{}}
+}
+
+// promptKey: 系统提示词的第一行，用于区分角色
+func promptKey(prompt string) string {
+	first, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	return first
+}
+
+// on: 设置系统提示词为 prompt 的角色依次返回的回复
+func (m *scriptedModel) on(prompt string, replies ...string) *scriptedModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replies[promptKey(prompt)] = replies
+	return m
+}
+
+// failOn: 系统提示词为 prompt 的角色总是返回 err
+func (m *scriptedModel) failOn(prompt string, err error) *scriptedModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errs[promptKey(prompt)] = err
+	return m
+}
+
+// inputs: 系统提示词为 prompt 的角色收到的用户消息，按调用顺序
+func (m *scriptedModel) inputs(prompt string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls[promptKey(prompt)]...)
+}
+
+func (m *scriptedModel) reply(in []*schema.Message) (*schema.Message, error) {
+	var key, user string
+	for _, msg := range in {
+		switch msg.Role {
+		case schema.System:
+			key = promptKey(msg.Content)
+		case schema.User:
+			user = msg.Content
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[key] = append(m.calls[key], user)
+	if err := m.errs[key]; err != nil {
+		return nil, err
+	}
+	replies := m.replies[key]
+	if len(replies) == 0 {
+		return nil, fmt.Errorf("没有为 %q 准备回复", key)
+	}
+	if len(replies) > 1 {
+		m.replies[key] = replies[1:]
+	}
+	msg := schema.AssistantMessage(replies[0], nil)
+	msg.ResponseMeta = &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}
+	return msg, nil
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.reply(in)
+}
+
+// Stream: 把回复拆成前后两个片段，用量放在最后一个片段上
+func (m *scriptedModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.reply(in)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(msg.Content)
+	head := schema.AssistantMessage(string(runes[:len(runes)/2]), nil)
+	tail := schema.AssistantMessage(string(runes[len(runes)/2:]), nil)
+	tail.ResponseMeta = msg.ResponseMeta
+	return schema.StreamReaderFromArray([]*schema.Message{head, tail}), nil
+}
+
+func (m *scriptedModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// testReport: 研究分析师输出的一份可以解析的研究报告
+const testReport = `{"trends": [{"name": "多模态", "summary": "模型能同时理解图像和语音", "evidence": ["GPT-4o 在 2024 年发布"]}],
+"sources": ["https://example.com/ai"]}`
+
+// newTeamModel: 一次顺利运行的回复：拆成两个子主题，研究报告可以解析，编辑一次通过
+func newTeamModel() *scriptedModel {
+	return newScriptedModel().
+		on(decomposeSystemPrompt, `["模型能力", "行业应用"]`).
+		on(researchSystemPrompt, testReport).
+		on(writingSystemPrompt, "初稿").
+		on(editorSystemPrompt, "通过")
+}
+
+// newTestTeam: 用安静模式的输出创建团队，直接调用各阶段；输出写入 out（可以为 nil）
+func newTestTeam(t *testing.T, llm model.BaseChatModel, opts TeamOptions, out *bytes.Buffer) *blogTeam {
+	t.Helper()
+	if out == nil {
+		out = &bytes.Buffer{}
+	}
+	opts.Output = newStageOutput(out, true)
+	if opts.MaxSubtopics == 0 {
+		opts.MaxSubtopics = 3
+	}
+	team, err := newBlogTeam(context.Background(), llm, opts)
+	if err != nil {
+		t.Fatalf("newBlogTeam: %v", err)
+	}
+	return team
+}
+
+// runTeam: 用安静模式的输出运行固定流程的 Graph，返回最终状态、输出和错误
+func runTeam(t *testing.T, llm model.BaseChatModel, opts TeamOptions) (CollaborationState, string, error) {
+	t.Helper()
+	return runTeamFrom(t, llm, opts, CollaborationState{Query: "AI 趋势"})
+}
+
+// runTeamFrom: 与 runTeam 相同，输入为 input（从检查点继续时为上一阶段的状态）
+func runTeamFrom(t *testing.T, llm model.BaseChatModel, opts TeamOptions, input CollaborationState) (CollaborationState, string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	opts.Output = newStageOutput(&out, true)
+	if opts.MaxSubtopics == 0 {
+		opts.MaxSubtopics = 3
+	}
+	runnable, err := buildBlogTeam(context.Background(), llm, opts)
+	if err != nil {
+		t.Fatalf("buildBlogTeam: %v", err)
+	}
+	state, err := runnable.Invoke(context.Background(), input)
+	return state, out.String(), err
+}
+
+func TestBlogTeamRevisionLoop(t *testing.T) {
+	llm := newTeamModel().
+		on(writingSystemPrompt, "初稿", "修订稿").
+		on(editorSystemPrompt, "修改\n加一个具体的例子", "通过")
+	state, _, err := runTeam(t, llm, TeamOptions{MaxRevisions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Approved || state.Revisions != 1 || state.Draft != "修订稿" || state.EditorNotes != "" {
+		t.Errorf("最终状态 = 通过 %v、修订 %d 次、草稿 %q、意见 %q", state.Approved, state.Revisions, state.Draft, state.EditorNotes)
+	}
+	writes := llm.inputs(writingSystemPrompt)
+	if len(writes) != 2 {
+		t.Fatalf("作家调用 %d 次，期望 2 次", len(writes))
+	}
+	if strings.Contains(writes[0], "修订") {
+		t.Errorf("第一稿不应包含修订要求：\n%s", writes[0])
+	}
+	for _, want := range []string{"这是第 1 次修订", "上一版草稿：\n\n初稿", "编辑的修改意见：\n\n加一个具体的例子"} {
+		if !strings.Contains(writes[1], want) {
+			t.Errorf("修订时作家的消息缺少 %q：\n%s", want, writes[1])
+		}
+	}
+	// 编辑看到程序统计的字数
+	if edits := llm.inputs(editorSystemPrompt); len(edits) != 2 || !strings.Contains(edits[1], "待审阅的草稿（约 3 字）：\n\n修订稿") {
+		t.Errorf("编辑收到的消息 = %q", edits)
+	}
+	// 拆分 1 次、研究 2 次、写作 2 次、审阅 2 次，每次 15 tokens
+	if state.TokensUsed != 7*15 {
+		t.Errorf("TokensUsed = %d，期望 %d", state.TokensUsed, 7*15)
+	}
+}
+
+func TestBlogTeamMaxRevisions(t *testing.T) {
+	llm := newTeamModel().on(editorSystemPrompt, "修改\n还不够好")
+	state, out, err := runTeam(t, llm, TeamOptions{MaxRevisions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Approved || state.Revisions != 2 || state.EditorNotes != "还不够好" {
+		t.Errorf("最终状态 = 通过 %v、修订 %d 次、意见 %q", state.Approved, state.Revisions, state.EditorNotes)
+	}
+	if n := len(llm.inputs(editorSystemPrompt)); n != 3 {
+		t.Errorf("编辑审阅 %d 次，期望 3 次（初稿和两次修订）", n)
+	}
+	if !strings.Contains(out, "达到最大修订次数，使用当前草稿") {
+		t.Errorf("输出中缺少达到上限的说明：\n%s", out)
+	}
+}
+
+func TestRevisionRequest(t *testing.T) {
+	if got := revisionRequest(1, "初稿", ""); got != "" {
+		t.Errorf("没有修改意见时 = %q", got)
+	}
+	got := revisionRequest(2, "初稿", "删掉第二段")
+	if !strings.Contains(got, "这是第 2 次修订") || !strings.Contains(got, "初稿") || !strings.Contains(got, "删掉第二段") {
+		t.Errorf("revisionRequest = %q", got)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// --- 多个作家变体：并发写出多篇初稿，由评审按评分标准打分，选出最好的一篇交给编辑 ---
+
+// judgeSystemPrompt: 评审的提示词，评分标准与编辑的审阅要求一致；JSON 格式用文字描述（避免花括号与 FString 模板变量冲突）
+const judgeSystemPrompt = `你是一位严格的评审，需要比较基于同一份研究简报写出的多篇博客文章草稿。
+请按以下评分标准为每篇草稿的每一项打 1-10 分：
+- accuracy（准确性）：事实和结论来自研究简报，遵守事实核查的标注，不夸大或编造
+- structure（结构）：有吸引人的开头、层次清晰的正文和有力的结尾
+- readability（可读性）：普通读者容易理解，语言流畅
+- length（篇幅）：约 500 字（450～600 字之间为满分）
+只输出一个 JSON 对象，不要输出其他内容：字段 scores 是数组，按草稿编号顺序每篇一项，
+每项包含 draft（草稿编号）、accuracy、structure、readability、length 和 reason（一句话说明评分理由）。`
+
+// judgeUserTemplate: 评审的用户消息，drafts 为编号的草稿列表
+const judgeUserTemplate = "研究简报：\n\n{research_results}\n\n待比较的草稿：\n{drafts}"
+
+// variantStyles: 各变体的写作风格提示，变体多于风格时循环使用
+var variantStyles = []string{
+	"以一个具体的应用场景开头，用讲故事的方式展开",
+	"开门见山地给出观点，按趋势逐条分析，条理分明",
+	"面向一线从业者，突出每个趋势的落地建议和实际影响",
+	"用通俗的类比解释技术概念，语气轻松",
+}
+
+// variantStyle: 第 i 个变体（从 0 开始）的风格提示
+func variantStyle(i int) string {
+	return variantStyles[i%len(variantStyles)]
+}
+
+// styleRequest: 附在作家消息后面的风格要求，没有风格时为空
+func styleRequest(style string) string {
+	if style == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\n写作风格：%s。", style)
+}
+
+// RubricScores: 评审按评分标准给出的各项评分（1-10）
+type RubricScores struct {
+	Accuracy    float64 `json:"accuracy"`
+	Structure   float64 `json:"structure"`
+	Readability float64 `json:"readability"`
+	Length      float64 `json:"length"`
+}
+
+// Total: 各项评分的平均分
+func (s RubricScores) Total() float64 {
+	return (s.Accuracy + s.Structure + s.Readability + s.Length) / 4
+}
+
+// valid: 各项评分都在 0-10 之间
+func (s RubricScores) valid() bool {
+	for _, v := range []float64{s.Accuracy, s.Structure, s.Readability, s.Length} {
+		if v < 0 || v > 10 {
+			return false
+		}
+	}
+	return true
+}
+
+// DraftVariant: 一个作家变体写出的初稿及评审结果
+type DraftVariant struct {
+	Index    int           `json:"index"` // Index: 变体编号（从 1 开始），草稿保存为 variant-<index>.md
+	Style    string        `json:"style,omitempty"`
+	Draft    string        `json:"-"` // Draft: 草稿内容（写入 variant-<index>.md）
+	Length   int           `json:"length,omitempty"`
+	Scores   *RubricScores `json:"scores,omitempty"` // Scores: 评审的评分，评审失败或只有一篇成功时为空
+	Total    float64       `json:"total,omitempty"`
+	Reason   string        `json:"reason,omitempty"`
+	Selected bool          `json:"selected,omitempty"`
+	Error    string        `json:"error,omitempty"` // Error: 写作失败的原因，失败的变体不参与评审
+}
+
+// judgedDraft: 评审对一篇草稿的评分
+type judgedDraft struct {
+	Draft int `json:"draft"`
+	RubricScores
+	Reason string `json:"reason"`
+}
+
+// parseJudgeScores: 解析评审的输出（允许代码块和前后的说明文字），必须恰好包含 n 篇草稿的评分；
+// 没有写 draft 编号时按数组顺序对应
+func parseJudgeScores(content string, n int) ([]judgedDraft, error) {
+	text, err := extractJSONObject(content)
+	if err != nil {
+		return nil, err
+	}
+	var judged struct {
+		Scores []judgedDraft `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(text), &judged); err != nil {
+		return nil, fmt.Errorf("JSON 无法解析: %w", err)
+	}
+	if len(judged.Scores) != n {
+		return nil, fmt.Errorf("评分个数 %d 与草稿个数 %d 不一致", len(judged.Scores), n)
+	}
+	scores := make([]judgedDraft, n)
+	seen := make([]bool, n)
+	for i, s := range judged.Scores {
+		if s.Draft == 0 {
+			s.Draft = i + 1
+		}
+		if s.Draft < 1 || s.Draft > n || seen[s.Draft-1] {
+			return nil, fmt.Errorf("无效或重复的草稿编号 %d", s.Draft)
+		}
+		if !s.RubricScores.valid() {
+			return nil, fmt.Errorf("草稿 %d 的评分超出 0-10", s.Draft)
+		}
+		seen[s.Draft-1] = true
+		scores[s.Draft-1] = s
+	}
+	return scores, nil
+}
+
+// bestVariant: 总分最高的变体下标（并列时取编号小的）；只在成功的变体中选择，没有成功的变体时返回 -1
+func bestVariant(variants []DraftVariant) int {
+	best := -1
+	for i, v := range variants {
+		if v.Error != "" {
+			continue
+		}
+		if best < 0 || v.Total > variants[best].Total {
+			best = i
+		}
+	}
+	return best
+}
+
+// formatVariants: 各变体的评分和是否入选，每行一个
+func formatVariants(variants []DraftVariant) string {
+	lines := make([]string, len(variants))
+	for i, v := range variants {
+		line := fmt.Sprintf("变体 %d（%s）：", v.Index, v.Style)
+		switch {
+		case v.Error != "":
+			line += "失败 " + v.Error
+		case v.Scores == nil:
+			line += fmt.Sprintf("约 %d 字，未评分", v.Length)
+		default:
+			line += fmt.Sprintf("约 %d 字，总分 %.1f（准确性 %g、结构 %g、可读性 %g、篇幅 %g）",
+				v.Length, v.Total, v.Scores.Accuracy, v.Scores.Structure, v.Scores.Readability, v.Scores.Length)
+			if v.Reason != "" {
+				line += "，" + v.Reason
+			}
+		}
+		if v.Selected {
+			line += " ← 入选"
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeVariants: opts.Variants 个作家变体并发写初稿（每次调用经过共享限流器，瞬时错误按 opts.Retry 重试），
+// 评审打分后选出总分最高的一篇；部分变体失败时只在成功的变体中选择，评审失败时使用第一篇成功的草稿
+func (t *blogTeam) writeVariants(ctx context.Context, state CollaborationState) (CollaborationState, error) {
+	n := t.opts.Variants
+	t.out.printf("✍️  %d 位技术内容作家 Agent 正在并行撰写初稿...%s\n", n, modelTag(t.writerModel))
+	brief := t.writingBrief("技术内容作家", state)
+	variants := make([]DraftVariant, n)
+	tokens := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range variants {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v := &variants[i]
+			v.Index, v.Style = i+1, variantStyle(i)
+			agent := fmt.Sprintf("技术内容作家 %d", v.Index)
+			ctx, done := t.opts.Ledger.track(ctx, "技术内容作家", t.writerModel)
+			result, err := withRetry(ctx, t.opts.Retry, t.out, agent, func(ctx context.Context) (*schema.Message, error) {
+				release, err := t.opts.Limiter.Acquire(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("等待限流失败: %w", err)
+				}
+				defer release()
+				return t.writerChain.Invoke(ctx, map[string]any{
+					"research_results": brief,
+					"sources":          formatSources(state.Sources),
+					"style":            styleRequest(v.Style),
+					"revision_request": "",
+				})
+			})
+			tokens[i] = done().Total()
+			if err != nil {
+				v.Error = errorLine(err)
+				t.out.printf("⚠️ %s 失败: %s\n", agent, v.Error)
+				return
+			}
+			v.Draft, v.Length = result.Content, articleLength(result.Content)
+			t.out.printf("✅ %s 完成初稿（%s，约 %d 字）\n", agent, v.Style, v.Length)
+			t.publish(agent, "评审", KindDraft, v.Draft, tokens[i])
+		}(i)
+	}
+	wg.Wait()
+	for _, used := range tokens {
+		state.TokensUsed += used
+	}
+
+	var ok []int // ok: 写作成功的变体下标
+	for i, v := range variants {
+		if v.Error == "" {
+			ok = append(ok, i)
+		}
+	}
+	if len(ok) == 0 {
+		return state, fmt.Errorf("写作 Agent 执行失败: 所有 %d 个变体都失败了", n)
+	}
+	judgeTokens := state.TokensUsed
+	if len(ok) > 1 {
+		if err := t.judge(ctx, &state, brief, variants, ok); err != nil {
+			t.out.printf("⚠️ 评审失败，使用变体 %d: %s\n", variants[ok[0]].Index, errorLine(err))
+		}
+	}
+	judgeTokens = state.TokensUsed - judgeTokens
+	selected := bestVariant(variants)
+	variants[selected].Selected = true
+	t.out.printf("🏆 草稿评审结果:\n%s\n", formatVariants(variants))
+	t.publish("评审", "编辑", KindVerdict, formatVariants(variants), judgeTokens)
+	if err := t.opts.Store.WriteVariants(variants); err != nil {
+		t.out.printf("⚠️ 保存草稿变体失败: %v\n", err)
+	}
+
+	state.Variants = variants
+	state.Draft = variants[selected].Draft
+	state.Approved = false
+	t.publish("技术内容作家", "编辑", KindDraft, state.Draft, 0)
+	if _, err := t.opts.Store.WriteDraft(state.Draft); err != nil {
+		t.out.printf("⚠️ 保存草稿失败: %v\n", err)
+	}
+	printStage(t.out, "writer_agent", state)
+	return state, nil
+}
+
+// judge: 评审为成功的变体打分，评分写入 variants；评审输出无法解析时返回错误，variants 不变
+func (t *blogTeam) judge(ctx context.Context, state *CollaborationState, brief string, variants []DraftVariant, ok []int) error {
+	var drafts strings.Builder
+	for j, i := range ok {
+		drafts.WriteString(fmt.Sprintf("\n### 草稿 %d（约 %d 字）\n\n%s\n", j+1, variants[i].Length, variants[i].Draft))
+	}
+	ctx, done := t.opts.Ledger.track(ctx, "评审", t.editorModel)
+	result, err := t.runWithRetry(ctx, "评审", fmt.Sprintf("⚖️  评审 Agent 正在比较 %d 篇草稿...%s", len(ok), modelTag(t.editorModel)), t.judgeChain, map[string]any{
+		"research_results": brief,
+		"drafts":           drafts.String(),
+	})
+	state.TokensUsed += done().Total()
+	if err != nil {
+		return err
+	}
+	scores, err := parseJudgeScores(result.Content, len(ok))
+	if err != nil {
+		return err
+	}
+	for j, i := range ok {
+		rubric := scores[j].RubricScores
+		variants[i].Scores = &rubric
+		variants[i].Total = rubric.Total()
+		variants[i].Reason = strings.TrimSpace(scores[j].Reason)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestParseJudgeScores(t *testing.T) {
+	scores, err := parseJudgeScores("评分如下：\n```json\n"+`{"scores": [
+		{"draft": 2, "accuracy": 9, "structure": 8, "readability": 8, "length": 7, "reason": "数据准确"},
+		{"draft": 1, "accuracy": 6, "structure": 7, "readability": 9, "length": 10, "reason": "略有夸大"}
+	]}`+"\n```", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores[0].Reason != "略有夸大" || scores[1].Total() != 8 {
+		t.Errorf("应按 draft 编号对应草稿，得到 %+v", scores)
+	}
+	if scores, err := parseJudgeScores(`{"scores": [{"accuracy": 5}, {"accuracy": 6}]}`, 2); err != nil || scores[1].Accuracy != 6 {
+		t.Errorf("没有 draft 编号时应按顺序对应，得到 %+v, %v", scores, err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"没有 JSON", "第一篇更好", "输出中没有 JSON 对象"},
+		{"JSON 无效", `{"scores": [{"draft": "一"}]}`, "JSON 无法解析"},
+		{"个数不一致", `{"scores": [{"draft": 1}]}`, "评分个数 1 与草稿个数 2 不一致"},
+		{"编号重复", `{"scores": [{"draft": 1}, {"draft": 1}]}`, "无效或重复的草稿编号 1"},
+		{"编号越界", `{"scores": [{"draft": 1}, {"draft": 3}]}`, "无效或重复的草稿编号 3"},
+		{"评分超出范围", `{"scores": [{"draft": 1, "accuracy": 11}, {"draft": 2}]}`, "草稿 1 的评分超出 0-10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseJudgeScores(tt.content, 2); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseJudgeScores = %v，应包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestVariant(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []DraftVariant
+		want     int
+	}{
+		{"最高分", []DraftVariant{{Total: 6}, {Total: 8.5}, {Total: 7}}, 1},
+		{"并列取编号小的", []DraftVariant{{Total: 8}, {Total: 8}}, 0},
+		{"跳过失败的变体", []DraftVariant{{Total: 9, Error: "超时"}, {Total: 5}}, 1},
+		{"都没有评分", []DraftVariant{{Error: "超时"}, {}, {}}, 1},
+		{"全部失败", []DraftVariant{{Error: "超时"}}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bestVariant(tt.variants); got != tt.want {
+				t.Errorf("bestVariant = %d，期望 %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatVariants(t *testing.T) {
+	variants := []DraftVariant{
+		{Index: 1, Style: "讲故事", Length: 480, Scores: &RubricScores{Accuracy: 9, Structure: 8, Readability: 8.5, Length: 10}, Total: 8.875, Reason: "数据准确", Selected: true},
+		{Index: 2, Style: "开门见山", Length: 300},
+		{Index: 3, Style: "类比", Error: "429 Too Many Requests"},
+	}
+	want := "变体 1（讲故事）：约 480 字，总分 8.9（准确性 9、结构 8、可读性 8.5、篇幅 10），数据准确 ← 入选\n" +
+		"变体 2（开门见山）：约 300 字，未评分\n" +
+		"变体 3（类比）：失败 429 Too Many Requests"
+	if got := formatVariants(variants); got != want {
+		t.Errorf("formatVariants =\n%s\n期望\n%s", got, want)
+	}
+}
+
+func TestVariantStyle(t *testing.T) {
+	if variantStyle(len(variantStyles)) != variantStyles[0] || variantStyle(1) != variantStyles[1] {
+		t.Error("变体多于风格时应循环使用")
+	}
+	if styleRequest("") != "" || styleRequest("轻松") != "\n\n写作风格：轻松。" {
+		t.Errorf("styleRequest = %q", styleRequest("轻松"))
+	}
+}
+
+// styledWriterModel: 作家按消息中的写作风格返回已知“质量”的草稿，failStyle 风格的变体失败；其他角色交给 scriptedModel
+type styledWriterModel struct {
+	*scriptedModel
+	drafts    map[string]string // drafts: 风格到草稿
+	failStyle string
+}
+
+func (m *styledWriterModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	msg, err := m.scriptedModel.Generate(ctx, in, opts...)
+	if err != nil || promptKey(in[0].Content) != promptKey(writingSystemPrompt) {
+		return msg, err
+	}
+	_, style, ok := strings.Cut(in[len(in)-1].Content, "写作风格：")
+	if !ok {
+		return msg, nil
+	}
+	style = strings.TrimSuffix(style, "。")
+	if style == m.failStyle {
+		return nil, errors.New("429 Too Many Requests")
+	}
+	msg.Content = m.drafts[style]
+	return msg, nil
+}
+
+func newStyledWriterModel(judge ...string) *styledWriterModel {
+	return &styledWriterModel{
+		scriptedModel: newTeamModel().on(judgeSystemPrompt, judge...),
+		drafts: map[string]string{
+			variantStyles[0]: "讲故事的草稿",
+			variantStyles[1]: "开门见山的草稿",
+			variantStyles[2]: "面向从业者的草稿",
+		},
+	}
+}
+
+// TestBlogTeamVariants: 评审给第二篇最高分，第二篇交给编辑；所有草稿和评分都记录在状态和运行目录中
+func TestBlogTeamVariants(t *testing.T) {
+	llm := newStyledWriterModel(`{"scores": [
+		{"draft": 1, "accuracy": 6, "structure": 6, "readability": 6, "length": 6},
+		{"draft": 2, "accuracy": 9, "structure": 9, "readability": 9, "length": 9, "reason": "最好"},
+		{"draft": 3, "accuracy": 7, "structure": 7, "readability": 7, "length": 7}]}`)
+	store := newTestRunStore(t, "hash")
+	state, out, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, Variants: 3, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Draft != "开门见山的草稿" || len(state.Variants) != 3 || !state.Variants[1].Selected || state.Variants[1].Total != 9 {
+		t.Fatalf("草稿 = %q，变体 = %+v", state.Draft, state.Variants)
+	}
+	if edits := llm.inputs(editorSystemPrompt); len(edits) != 1 || !strings.Contains(edits[0], "开门见山的草稿") {
+		t.Errorf("编辑应审阅入选的草稿，得到 %q", edits)
+	}
+	judged := llm.inputs(judgeSystemPrompt)
+	if len(judged) != 1 || !strings.Contains(judged[0], "### 草稿 1（约 6 字）\n\n讲故事的草稿") || !strings.Contains(judged[0], "### 草稿 3（约 8 字）\n\n面向从业者的草稿") {
+		t.Errorf("评审收到的草稿 = %q", judged)
+	}
+	if !strings.Contains(out, "变体 2（"+variantStyles[1]+"）：约 7 字，总分 9.0（准确性 9、结构 9、可读性 9、篇幅 9），最好 ← 入选") {
+		t.Errorf("输出 =\n%s", out)
+	}
+	for _, name := range []string{"variant-1.md", "variant-2.md", "variant-3.md", "draft-1.md"} {
+		if _, err := os.Stat(filepath.Join(store.Dir, name)); err != nil {
+			t.Errorf("运行目录中缺少 %s: %v", name, err)
+		}
+	}
+	// 变体 3 个作家 + 评审 1 次 + 拆分 1 次 + 研究 2 次 + 编辑 1 次
+	if state.TokensUsed != 8*15 {
+		t.Errorf("TokensUsed = %d", state.TokensUsed)
+	}
+}
+
+func TestBlogTeamVariantsFallback(t *testing.T) {
+	// 部分变体失败时只评审成功的变体
+	llm := newStyledWriterModel(`{"scores": [{"accuracy": 5, "structure": 5, "readability": 5, "length": 5}, {"accuracy": 8, "structure": 8, "readability": 8, "length": 8}]}`)
+	llm.failStyle = variantStyles[1]
+	state, _, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, Variants: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Draft != "面向从业者的草稿" || state.Variants[1].Error == "" || state.Variants[1].Selected {
+		t.Errorf("草稿 = %q，变体 = %+v", state.Draft, state.Variants)
+	}
+
+	// 评审的输出无法解析时使用第一篇成功的草稿
+	llm = newStyledWriterModel("第二篇最好")
+	state, out, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, Variants: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Draft != "讲故事的草稿" || state.Variants[0].Scores != nil || !strings.Contains(out, "⚠️ 评审失败，使用变体 1: 输出中没有 JSON 对象") {
+		t.Errorf("草稿 = %q，输出：\n%s", state.Draft, out)
+	}
+
+	// 所有变体都失败时写作失败
+	llm = newStyledWriterModel()
+	llm.failOn(writingSystemPrompt, errors.New("401 Unauthorized"))
+	if _, _, err := runTeam(t, llm, TeamOptions{MaxRevisions: 1, Variants: 2}); err == nil || !strings.Contains(err.Error(), "所有 2 个变体都失败了") {
+		t.Errorf("所有变体都失败时 = %v", err)
+	}
+}
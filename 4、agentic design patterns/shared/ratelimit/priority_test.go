@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// enqueue: 依次以给定优先级排队，每个都确认进入队列后再排下一个，保证到达顺序确定
+func enqueue(t *testing.T, l *Limiter, priorities ...Priority) []<-chan acquireResult {
+	t.Helper()
+	queued := l.Stats().QueueDepth
+	n := queued[0] + queued[1] + queued[2]
+	waits := make([]<-chan acquireResult, len(priorities))
+	for i, p := range priorities {
+		waits[i] = acquireAsync(context.Background(), l, p)
+		n++
+		waitQueued(t, l, n)
+	}
+	return waits
+}
+
+// grantOrder: 每次归还一个名额，记录等待者获得许可的顺序（waits 的下标）
+func grantOrder(t *testing.T, hold func(), waits []<-chan acquireResult) []int {
+	t.Helper()
+	var order []int
+	release := hold
+	for range waits {
+		release()
+		idx, r := nextGranted(t, waits, order)
+		order = append(order, idx)
+		release = r.release
+	}
+	release()
+	return order
+}
+
+// nextGranted: 等待尚未获得许可的等待者中的下一个
+func nextGranted(t *testing.T, waits []<-chan acquireResult, done []int) (int, acquireResult) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		for i, w := range waits {
+			if contains(done, i) {
+				continue
+			}
+			select {
+			case r := <-w:
+				if r.err != nil {
+					t.Fatalf("等待者 %d 获取失败: %v", i, r.err)
+				}
+				return i, r
+			default:
+			}
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("没有等待者获得许可")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func contains(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func sameOrder(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPriorityOrder(t *testing.T) {
+	l := New(Config{MaxInFlight: 1, AgingInterval: time.Hour, Clock: newFakeClock()})
+	hold, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	waits := enqueue(t, l, Low, Normal, High, Normal, High)
+
+	s := l.Stats()
+	if s.QueueDepth != [3]int{2, 2, 1} || s.MaxQueueDepth != [3]int{2, 2, 1} {
+		t.Errorf("排队数 = %v / %v", s.QueueDepth, s.MaxQueueDepth)
+	}
+
+	// 高优先级先于普通和低优先级，同一优先级先到先得
+	if got, want := grantOrder(t, hold, waits), []int{2, 4, 1, 3, 0}; !sameOrder(got, want) {
+		t.Errorf("获得许可的顺序 = %v，want %v", got, want)
+	}
+	s = l.Stats()
+	if s.QueueDepth != [3]int{} || s.Waited != 5 || s.Aged != 0 {
+		t.Errorf("统计 = %+v", s)
+	}
+}
+
+// TestPriorityAging: 低优先级请求等待足够久后有效优先级提升，不会被后到的高优先级请求一直插队
+func TestPriorityAging(t *testing.T) {
+	clock := newFakeClock()
+	l := New(Config{MaxInFlight: 1, AgingInterval: time.Second, Clock: clock})
+	hold, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	waits := enqueue(t, l, Low)
+
+	// 等待 1 秒：低 → 普通，仍排在高优先级之后
+	clock.Advance(time.Second)
+	waits = append(waits, enqueue(t, l, High)...)
+	// 再等 1 秒：低 → 高，与新来的高优先级相同，先到者优先
+	clock.Advance(time.Second)
+	waits = append(waits, enqueue(t, l, High)...)
+
+	if got, want := grantOrder(t, hold, waits), []int{0, 1, 2}; !sameOrder(got, want) {
+		t.Errorf("获得许可的顺序 = %v，want %v", got, want)
+	}
+	s := l.Stats()
+	if s.Aged != 1 {
+		t.Errorf("老化提升次数 = %d，want 1", s.Aged)
+	}
+	if s.MaxWait != 2*time.Second {
+		t.Errorf("最长等待 = %s，want 2s", s.MaxWait)
+	}
+}
+
+func TestPriorityWithoutAgingLowWaits(t *testing.T) {
+	clock := newFakeClock()
+	l := New(Config{MaxInFlight: 1, AgingInterval: time.Second, Clock: clock})
+	hold, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	// 低优先级只等了半个老化间隔，后到的普通优先级先获得许可
+	waits := enqueue(t, l, Low)
+	clock.Advance(500 * time.Millisecond)
+	waits = append(waits, enqueue(t, l, Normal)...)
+	if got, want := grantOrder(t, hold, waits), []int{1, 0}; !sameOrder(got, want) {
+		t.Errorf("获得许可的顺序 = %v，want %v", got, want)
+	}
+}
+
+// TestPriorityWithTokenBucket: 令牌不足时排队，新令牌按优先级分配
+func TestPriorityWithTokenBucket(t *testing.T) {
+	clock := newFakeClock()
+	l := New(Config{RPS: 1, Burst: 1, AgingInterval: time.Hour, Clock: clock})
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	waits := enqueue(t, l, Low, High)
+
+	clock.Advance(time.Second)
+	if i, _ := nextGranted(t, waits, nil); i != 1 {
+		t.Errorf("第一个新令牌给了等待者 %d，want 高优先级", i)
+	}
+	expectBlocked(t, waits[0])
+	clock.Advance(time.Second)
+	expectGranted(t, waits[0])
+}
+
+func TestPriorityString(t *testing.T) {
+	for p, want := range map[Priority]string{High: "高", Normal: "普通", Low: "低", Priority(5): "普通"} {
+		if got := p.String(); got != want {
+			t.Errorf("Priority(%d).String() = %q，want %q", p, got, want)
+		}
+	}
+}
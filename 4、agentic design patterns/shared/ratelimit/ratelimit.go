@@ -0,0 +1,361 @@
+// Package ratelimit: 可复用的请求限流器
+//
+// Limiter 组合了令牌桶（限制每秒请求数，允许一定突发）和在途请求信号量（限制同时进行的请求数），
+// 调用方在每次模型调用前 Acquire、调用结束后 release。等待可被上下文取消，不会阻塞超时和 Ctrl-C。
+// 限流器饱和时，等待者按优先级（高 / 普通 / 低）获取许可，同一优先级先到先得；
+// 等待时间每超过一个 AgingInterval 有效优先级提升一级，低优先级请求不会被一直饿死。
+// 时间来源通过 Clock 注入，便于用假时钟验证排队顺序和限流行为。
+//
+// 本包属于各章共用的 shared 模块，第 3、7 章在 go.mod 中用 replace 指向 ../shared 引用同一份代码。
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultAgingInterval: 默认的老化间隔
+const DefaultAgingInterval = 2 * time.Second
+
+// Priority: 请求优先级，零值为 Normal
+type Priority int
+
+const (
+	Low    Priority = -1 // Low: 锦上添花的请求（如关键术语）
+	Normal Priority = 0  // Normal: 默认优先级
+	High   Priority = 1  // High: 阻塞后续步骤的请求（如综合所需的摘要）
+)
+
+// priorities: 所有优先级，按从高到低排列
+var priorities = []Priority{High, Normal, Low}
+
+// String: 优先级名称
+func (p Priority) String() string {
+	switch p {
+	case High:
+		return "高"
+	case Low:
+		return "低"
+	default:
+		return "普通"
+	}
+}
+
+// index: 优先级在统计数组中的下标（0 高、1 普通、2 低）
+func (p Priority) index() int {
+	switch {
+	case p >= High:
+		return 0
+	case p <= Low:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Clock: 时间来源
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock: 使用系统时间的 Clock
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Config: 限流配置
+type Config struct {
+	RPS           float64       // RPS: 每秒允许的请求数，<=0 表示不限速
+	Burst         int           // Burst: 令牌桶容量（允许的突发请求数），<=0 时取 1
+	MaxInFlight   int           // MaxInFlight: 同时进行的最大请求数，<=0 表示不限制
+	AgingInterval time.Duration // AgingInterval: 等待每超过该时长，有效优先级提升一级，<=0 时取 DefaultAgingInterval
+	Clock         Clock         // Clock: 时间来源，为空时使用系统时间
+}
+
+// Stats: 限流等待统计
+type Stats struct {
+	Acquired      int           // Acquired: 成功获取的次数
+	Waited        int           // Waited: 需要排队等待的次数
+	Cancelled     int           // Cancelled: 等待期间被取消的次数
+	Aged          int           // Aged: 因老化提升优先级后才获得许可的次数
+	TotalWait     time.Duration // TotalWait: 成功获取前的累计等待时间
+	MaxWait       time.Duration // MaxWait: 单次最长等待时间
+	QueueDepth    [3]int        // QueueDepth: 当前各优先级（高、普通、低）的排队数
+	MaxQueueDepth [3]int        // MaxQueueDepth: 各优先级（高、普通、低）的最大排队数
+}
+
+// AvgWait: 每次成功获取的平均等待时间
+func (s Stats) AvgWait() time.Duration {
+	if s.Acquired == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Acquired)
+}
+
+// String: 单行摘要，用于运行汇总
+func (s Stats) String() string {
+	return fmt.Sprintf("限流: 获取 %d 次 | 等待 %d 次 | 取消 %d 次 | 老化提升 %d 次 | 平均等待 %s | 最长等待 %s | 最大排队 高/普通/低 = %d/%d/%d",
+		s.Acquired, s.Waited, s.Cancelled, s.Aged, s.AvgWait().Round(time.Millisecond), s.MaxWait.Round(time.Millisecond),
+		s.MaxQueueDepth[0], s.MaxQueueDepth[1], s.MaxQueueDepth[2])
+}
+
+// waiter: 排队中的请求
+type waiter struct {
+	priority Priority
+	enqueued time.Time
+	ready    chan struct{} // ready: 获得许可时关闭
+	granted  bool
+	aged     bool // aged: 获得许可时的有效优先级高于原始优先级
+}
+
+// Limiter: 带优先级的令牌桶 + 在途信号量限流器，可被多个 goroutine 共享；nil *Limiter 表示不限流
+type Limiter struct {
+	rps         float64
+	burst       float64
+	maxInFlight int
+	aging       time.Duration
+	clock       Clock
+
+	mu           sync.Mutex
+	tokens       float64   // tokens: 当前令牌数
+	last         time.Time // last: 上次补充令牌的时间
+	inFlight     int
+	queues       [3][]*waiter // queues: 各优先级（高、普通、低）的等待队列，队内按到达顺序排列
+	timerPending bool         // timerPending: 已安排在下一个令牌可用时重新调度
+	stats        Stats
+}
+
+// New: 创建限流器
+func New(cfg Config) *Limiter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	if cfg.AgingInterval <= 0 {
+		cfg.AgingInterval = DefaultAgingInterval
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = realClock{}
+	}
+	return &Limiter{
+		rps:         cfg.RPS,
+		burst:       float64(cfg.Burst),
+		maxInFlight: cfg.MaxInFlight,
+		aging:       cfg.AgingInterval,
+		clock:       cfg.Clock,
+		tokens:      float64(cfg.Burst),
+		last:        cfg.Clock.Now(),
+	}
+}
+
+// Acquire: 以普通优先级获取许可，见 AcquireWithPriority
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	return l.AcquireWithPriority(ctx, Normal)
+}
+
+// AcquireWithPriority: 等待在途名额和令牌，成功后返回 release，调用方在请求结束后必须调用它归还在途名额
+// 没有人排队且有余量时立即获得许可；否则进入对应优先级的队列，ctx 取消时离开队列并返回 ctx 的错误
+func (l *Limiter) AcquireWithPriority(ctx context.Context, priority Priority) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	start := l.clock.Now()
+	l.refillLocked(start)
+	if l.queuedLocked() == 0 && l.grantableLocked() {
+		l.takeLocked()
+		l.recordLocked(0, false, false)
+		l.mu.Unlock()
+		return l.releaseFunc(), nil
+	}
+
+	w := &waiter{priority: priority, enqueued: start, ready: make(chan struct{})}
+	i := priority.index()
+	l.queues[i] = append(l.queues[i], w)
+	l.stats.QueueDepth[i] = len(l.queues[i])
+	if l.stats.QueueDepth[i] > l.stats.MaxQueueDepth[i] {
+		l.stats.MaxQueueDepth[i] = l.stats.QueueDepth[i]
+	}
+	l.dispatchLocked()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		l.mu.Lock()
+		l.recordLocked(l.clock.Now().Sub(start), true, w.aged)
+		l.mu.Unlock()
+		return l.releaseFunc(), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if w.granted {
+			// 取消与获得许可同时发生：归还许可，让给下一个等待者
+			l.inFlight--
+			l.dispatchLocked()
+		} else {
+			l.removeLocked(w)
+		}
+		l.stats.Cancelled++
+		return nil, ctx.Err()
+	}
+}
+
+// Stats: 返回当前统计的快照
+func (l *Limiter) Stats() Stats {
+	if l == nil {
+		return Stats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// releaseFunc: 归还在途名额并唤醒下一个等待者，重复调用无效
+func (l *Limiter) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.inFlight--
+			l.dispatchLocked()
+		})
+	}
+}
+
+// refillLocked: 按经过的时间补充令牌
+func (l *Limiter) refillLocked(now time.Time) {
+	if l.rps <= 0 {
+		return
+	}
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rps
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+}
+
+// grantableLocked: 当前是否有在途名额和令牌
+func (l *Limiter) grantableLocked() bool {
+	if l.maxInFlight > 0 && l.inFlight >= l.maxInFlight {
+		return false
+	}
+	return l.rps <= 0 || l.tokens >= 1
+}
+
+// takeLocked: 占用一个在途名额和一个令牌
+func (l *Limiter) takeLocked() {
+	l.inFlight++
+	if l.rps > 0 {
+		l.tokens--
+	}
+}
+
+// queuedLocked: 排队总数
+func (l *Limiter) queuedLocked() int {
+	n := 0
+	for _, q := range l.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// effectiveLocked: 等待者的有效优先级 = 原始优先级 + 已等待的老化间隔数（不超过 High）
+func (l *Limiter) effectiveLocked(w *waiter, now time.Time) Priority {
+	p := w.priority + Priority(now.Sub(w.enqueued)/l.aging)
+	if p > High {
+		p = High
+	}
+	return p
+}
+
+// nextLocked: 选出下一个获得许可的等待者：有效优先级最高者优先，相同时先到先得
+func (l *Limiter) nextLocked(now time.Time) *waiter {
+	var best *waiter
+	var bestPriority Priority
+	for _, priority := range priorities {
+		q := l.queues[priority.index()]
+		if len(q) == 0 {
+			continue
+		}
+		w := q[0] // 同一队列内先到者等待最久，有效优先级不低于后到者
+		p := l.effectiveLocked(w, now)
+		if best == nil || p > bestPriority || (p == bestPriority && w.enqueued.Before(best.enqueued)) {
+			best, bestPriority = w, p
+		}
+	}
+	if best != nil {
+		best.aged = bestPriority > best.priority
+	}
+	return best
+}
+
+// dispatchLocked: 在有余量时依次放行等待者；令牌不足时安排在下一个令牌可用时再调度
+func (l *Limiter) dispatchLocked() {
+	now := l.clock.Now()
+	l.refillLocked(now)
+	for l.queuedLocked() > 0 {
+		if l.maxInFlight > 0 && l.inFlight >= l.maxInFlight {
+			return // 等 release 时再调度
+		}
+		if l.rps > 0 && l.tokens < 1 {
+			l.scheduleLocked(time.Duration((1 - l.tokens) / l.rps * float64(time.Second)))
+			return
+		}
+		w := l.nextLocked(now)
+		l.removeLocked(w)
+		l.takeLocked()
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// scheduleLocked: 在 d 之后重新调度（同一时间只安排一次）
+func (l *Limiter) scheduleLocked(d time.Duration) {
+	if l.timerPending {
+		return
+	}
+	l.timerPending = true
+	timer := l.clock.After(d)
+	go func() {
+		<-timer
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.timerPending = false
+		l.dispatchLocked()
+	}()
+}
+
+// removeLocked: 把等待者移出队列
+func (l *Limiter) removeLocked(w *waiter) {
+	i := w.priority.index()
+	q := l.queues[i]
+	for j, other := range q {
+		if other == w {
+			l.queues[i] = append(q[:j:j], q[j+1:]...)
+			break
+		}
+	}
+	l.stats.QueueDepth[i] = len(l.queues[i])
+}
+
+// recordLocked: 记录一次成功获取
+func (l *Limiter) recordLocked(wait time.Duration, waited, aged bool) {
+	l.stats.Acquired++
+	if waited {
+		l.stats.Waited++
+	}
+	if aged {
+		l.stats.Aged++
+	}
+	l.stats.TotalWait += wait
+	if wait > l.stats.MaxWait {
+		l.stats.MaxWait = wait
+	}
+}
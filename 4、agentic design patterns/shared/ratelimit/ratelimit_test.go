@@ -0,0 +1,247 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock: 手动推进的假时钟，After 返回的通道在 Advance 越过到期时间时触发
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	pending []fakeTimer
+}
+
+// fakeTimer: 等待到期的 After 调用
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.pending = append(c.pending, fakeTimer{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance: 推进时间并触发所有到期的定时器
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	kept := c.pending[:0]
+	for _, t := range c.pending {
+		if t.at.After(c.now) {
+			kept = append(kept, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.pending = kept
+}
+
+// acquireResult: 后台获取的结果
+type acquireResult struct {
+	release func()
+	err     error
+}
+
+// acquireAsync: 在后台以指定优先级获取许可
+func acquireAsync(ctx context.Context, l *Limiter, p Priority) <-chan acquireResult {
+	done := make(chan acquireResult, 1)
+	go func() {
+		release, err := l.AcquireWithPriority(ctx, p)
+		done <- acquireResult{release, err}
+	}()
+	return done
+}
+
+// waitQueued: 等待排队总数达到 n
+func waitQueued(t *testing.T, l *Limiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		s := l.Stats()
+		if s.QueueDepth[0]+s.QueueDepth[1]+s.QueueDepth[2] == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("排队数没有达到 %d: %+v", n, l.Stats())
+}
+
+// expectGranted: 期望在短时间内获得许可
+func expectGranted(t *testing.T, done <-chan acquireResult) func() {
+	t.Helper()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("获取许可失败: %v", r.err)
+		}
+		return r.release
+	case <-time.After(time.Second):
+		t.Fatalf("没有获得许可")
+		return nil
+	}
+}
+
+// expectBlocked: 期望仍在等待
+func expectBlocked(t *testing.T, done <-chan acquireResult) {
+	t.Helper()
+	select {
+	case r := <-done:
+		t.Fatalf("不应获得许可: err=%v", r.err)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("nil 限流器 Acquire: %v", err)
+	}
+	release()
+	if s := l.Stats(); s != (Stats{}) {
+		t.Errorf("nil 限流器统计 = %+v", s)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := newFakeClock()
+	l := New(Config{RPS: 2, Burst: 2, Clock: clock})
+	ctx := context.Background()
+
+	// 突发容量内立即获得许可
+	for i := 0; i < 2; i++ {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			t.Fatalf("第 %d 次 Acquire: %v", i+1, err)
+		}
+		release()
+	}
+
+	// 令牌用完后需要等待 1/RPS
+	done := acquireAsync(ctx, l, Normal)
+	waitQueued(t, l, 1)
+	expectBlocked(t, done)
+	clock.Advance(400 * time.Millisecond)
+	expectBlocked(t, done)
+	clock.Advance(100 * time.Millisecond)
+	expectGranted(t, done)()
+
+	s := l.Stats()
+	if s.Acquired != 3 || s.Waited != 1 {
+		t.Errorf("统计 = %+v，want 获取 3 次、等待 1 次", s)
+	}
+	if s.MaxWait != 500*time.Millisecond {
+		t.Errorf("最长等待 = %s，want 500ms", s.MaxWait)
+	}
+}
+
+func TestTokenBucketCapsAtBurst(t *testing.T) {
+	clock := newFakeClock()
+	l := New(Config{RPS: 1, Burst: 2, Clock: clock})
+	ctx := context.Background()
+
+	// 空闲很久也只积累 Burst 个令牌
+	clock.Advance(time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := l.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+	}
+	done := acquireAsync(ctx, l, Normal)
+	waitQueued(t, l, 1)
+	expectBlocked(t, done)
+	clock.Advance(time.Second)
+	expectGranted(t, done)
+}
+
+func TestMaxInFlight(t *testing.T) {
+	l := New(Config{MaxInFlight: 2, Clock: newFakeClock()})
+	ctx := context.Background()
+
+	r1, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := l.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	done := acquireAsync(ctx, l, Normal)
+	waitQueued(t, l, 1)
+	expectBlocked(t, done)
+
+	// 重复 release 只归还一次名额
+	r1()
+	r1()
+	expectGranted(t, done)
+	if got := l.Stats().Acquired; got != 3 {
+		t.Errorf("获取次数 = %d，want 3", got)
+	}
+
+	done = acquireAsync(ctx, l, Normal)
+	waitQueued(t, l, 1)
+	expectBlocked(t, done)
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	l := New(Config{MaxInFlight: 1, Clock: newFakeClock()})
+	hold, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := acquireAsync(ctx, l, Normal)
+	waitQueued(t, l, 1)
+	cancel()
+
+	select {
+	case r := <-done:
+		if !errors.Is(r.err, context.Canceled) {
+			t.Fatalf("取消后的错误 = %v，want context.Canceled", r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("取消后 Acquire 没有返回")
+	}
+	waitQueued(t, l, 0)
+	if got := l.Stats().Cancelled; got != 1 {
+		t.Errorf("取消次数 = %d，want 1", got)
+	}
+
+	// 被取消的等待者不占用名额
+	hold()
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Errorf("归还后 Acquire: %v", err)
+	}
+}
+
+func TestStatsString(t *testing.T) {
+	s := Stats{Acquired: 2, Waited: 1, TotalWait: time.Second, MaxWait: time.Second, MaxQueueDepth: [3]int{1, 2, 3}}
+	want := "限流: 获取 2 次 | 等待 1 次 | 取消 0 次 | 老化提升 0 次 | 平均等待 500ms | 最长等待 1s | 最大排队 高/普通/低 = 1/2/3"
+	if got := s.String(); got != want {
+		t.Errorf("String() = %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,130 @@
+// Package repl: 命令行交互循环
+//
+// Loop 逐行读取输入：空行忽略，:quit 退出，Commands 中的命令直接执行，其他输入交给 Handle。
+// 输入在独立 goroutine 中读取，等待输入时也能响应 Ctrl-C：空闲时 Ctrl-C 或 EOF 直接退出；
+// Handle 执行中第一次 Ctrl-C 标记"本次结束后退出"，第二次 Ctrl-C 取消 Handle 的上下文。
+//
+// 本包属于各章共用的 shared 模块，第 1、2 章在 go.mod 中用 replace 指向 ../shared 引用同一份代码。
+package repl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// QuitCommand: 退出交互循环的命令
+const QuitCommand = ":quit"
+
+// Config: 交互循环的输入输出和回调
+type Config struct {
+	In       io.Reader         // In: 输入，通常是 os.Stdin
+	Out      io.Writer         // Out: 提示符和中断提示的输出，通常是 os.Stdout
+	Prompt   string            // Prompt: 每次读取输入前打印的提示符
+	Signals  <-chan os.Signal  // Signals: Ctrl-C 信号，为空时由 Loop 订阅 os.Interrupt（测试时可注入）
+	Commands map[string]func() // Commands: 按整行匹配的命令（如 ":stats"），在循环中同步执行
+
+	// Handle: 执行一行输入，在独立 goroutine 中运行；第二次 Ctrl-C 时 ctx 被取消
+	Handle func(ctx context.Context, line string) (string, error)
+	// Done: Handle 返回后在循环中调用，负责打印结果（可以为空）
+	Done func(line, out string, err error)
+}
+
+// result: Handle 的返回值
+type result struct {
+	out string
+	err error
+}
+
+// Loop: 运行交互循环，直到 :quit、EOF 或 Ctrl-C
+func Loop(ctx context.Context, cfg Config) {
+	signals := cfg.Signals
+	if signals == nil {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt)
+		defer signal.Stop(sigCh)
+		signals = sigCh
+	}
+
+	// lines: 在独立 goroutine 中读取输入；stop 关闭后读取 goroutine 不再阻塞在发送上
+	lines := make(chan string)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(cfg.In)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for {
+		fmt.Fprint(cfg.Out, cfg.Prompt)
+
+		var line string
+		select {
+		case l, ok := <-lines:
+			if !ok { // EOF
+				fmt.Fprintln(cfg.Out)
+				return
+			}
+			line = strings.TrimSpace(l)
+		case <-signals:
+			fmt.Fprintln(cfg.Out)
+			return
+		}
+
+		if line == "" {
+			continue
+		}
+		if line == QuitCommand {
+			return
+		}
+		if command, ok := cfg.Commands[line]; ok {
+			command()
+			continue
+		}
+
+		res, quit := run(ctx, cfg, signals, line)
+		if cfg.Done != nil {
+			cfg.Done(line, res.out, res.err)
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// run: 在独立 goroutine 中执行 Handle 并等待结果；quit 表示执行期间收到过 Ctrl-C
+func run(ctx context.Context, cfg Config, signals <-chan os.Signal, line string) (res result, quit bool) {
+	// callCtx: 单次执行的上下文，第二次 Ctrl-C 时取消
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan result, 1)
+	go func() {
+		out, err := cfg.Handle(callCtx, line)
+		done <- result{out, err}
+	}()
+
+	for {
+		select {
+		case res = <-done:
+			return res, quit
+		case <-signals:
+			if !quit {
+				quit = true
+				fmt.Fprintln(cfg.Out, "\n当前调用完成后退出，再按一次 Ctrl-C 立即取消")
+				continue
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loopResult: 一次交互循环中 Handle 和 Done 收到的内容
+type loopResult struct {
+	handled []string
+	errs    []error
+	out     string
+}
+
+// runLoop: 以 in 为输入运行交互循环，Handle 原样返回输入行
+func runLoop(t *testing.T, in io.Reader, signals <-chan os.Signal, commands map[string]func()) loopResult {
+	t.Helper()
+	var res loopResult
+	var out bytes.Buffer
+	Loop(context.Background(), Config{
+		In: in, Out: &out, Prompt: "> ", Signals: signals, Commands: commands,
+		Handle: func(ctx context.Context, line string) (string, error) { return line, nil },
+		Done: func(line, out string, err error) {
+			res.handled = append(res.handled, out)
+			res.errs = append(res.errs, err)
+		},
+	})
+	res.out = out.String()
+	return res
+}
+
+func TestLoopQuit(t *testing.T) {
+	stats := 0
+	res := runLoop(t, strings.NewReader("\n  \n:stats\n  第一行  \n:quit\n第二行\n"), make(chan os.Signal), map[string]func(){":stats": func() { stats++ }})
+	if !slices.Equal(res.handled, []string{"第一行"}) || stats != 1 {
+		t.Errorf("执行了 %q，命令 %d 次；:quit 之后的输入不应执行", res.handled, stats)
+	}
+	if res.out != strings.Repeat("> ", 5) {
+		t.Errorf("输出 = %q", res.out)
+	}
+}
+
+func TestLoopEOF(t *testing.T) {
+	res := runLoop(t, strings.NewReader(":unknown\n最后一行"), make(chan os.Signal), nil)
+	if !slices.Equal(res.handled, []string{":unknown", "最后一行"}) {
+		t.Errorf("执行了 %q，未知命令应作为普通输入", res.handled)
+	}
+	if res.out != "> > > \n" {
+		t.Errorf("EOF 时应换行后退出，输出 = %q", res.out)
+	}
+}
+
+func TestLoopInterruptWhileIdle(t *testing.T) {
+	r, w := io.Pipe()
+	t.Cleanup(func() { w.Close() })
+	signals := make(chan os.Signal, 1)
+	signals <- os.Interrupt
+	if res := runLoop(t, r, signals, nil); len(res.handled) != 0 || res.out != "> \n" {
+		t.Errorf("空闲时 Ctrl-C 应直接退出，得到 %q，输出 %q", res.handled, res.out)
+	}
+}
+
+func TestLoopInterruptWhileRunning(t *testing.T) {
+	for _, cancelRun := range []bool{false, true} {
+		r, w := io.Pipe()
+		t.Cleanup(func() { w.Close() })
+		signals := make(chan os.Signal)
+		started := make(chan struct{})
+		finish := make(chan struct{})
+		var done []error
+		var out bytes.Buffer
+		loopDone := make(chan struct{})
+		go func() {
+			defer close(loopDone)
+			Loop(context.Background(), Config{
+				In: r, Out: &out, Signals: signals,
+				Handle: func(ctx context.Context, line string) (string, error) {
+					close(started)
+					select {
+					case <-finish:
+						return "完成", nil
+					case <-ctx.Done():
+						return "", ctx.Err()
+					}
+				},
+				Done: func(line, out string, err error) { done = append(done, err) },
+			})
+		}()
+		go w.Write([]byte("慢请求\n下一行\n"))
+		<-started
+
+		// 第一次 Ctrl-C 只标记退出，第二次取消当前执行
+		signals <- os.Interrupt
+		if cancelRun {
+			signals <- os.Interrupt
+		} else {
+			close(finish)
+		}
+		select {
+		case <-loopDone:
+		case <-time.After(5 * time.Second):
+			t.Fatal("交互循环没有在本次执行结束后退出")
+		}
+		if cancelRun && (len(done) != 1 || !errors.Is(done[0], context.Canceled)) {
+			t.Errorf("第二次 Ctrl-C 应取消执行，得到 %v", done)
+		}
+		if !cancelRun && (len(done) != 1 || done[0] != nil) {
+			t.Errorf("第一次 Ctrl-C 后应等待执行完成，得到 %v", done)
+		}
+		if !strings.Contains(out.String(), "当前调用完成后退出，再按一次 Ctrl-C 立即取消") {
+			t.Errorf("输出 = %q", out.String())
+		}
+	}
+}
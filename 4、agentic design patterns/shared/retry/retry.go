@@ -0,0 +1,163 @@
+// Package retry: 可复用的重试组件
+//
+// Do 按 Policy 重试一个操作：只重试可重试的错误（如 429 限流、5xx、网络超时），
+// 两次尝试之间按指数退避并加随机抖动，达到次数上限或上下文取消时停止。
+// 每次尝试都会重新调用 fn，因此调用方在 fn 内获取的资源（如限流令牌）会在重试时重新获取。
+//
+// 本包属于各章共用的 shared 模块，第 3、4、5、7 章在 go.mod 中用 replace 指向 ../shared 引用同一份代码。
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts = 3                      // DefaultMaxAttempts: 默认最多尝试次数（含第一次）
+	DefaultBaseDelay   = 500 * time.Millisecond // DefaultBaseDelay: 第一次重试前的基础等待时间
+	DefaultMaxDelay    = 8 * time.Second        // DefaultMaxDelay: 单次等待时间上限
+	DefaultJitter      = 0.2                    // DefaultJitter: 抖动比例，等待时间在 ±20% 范围内随机
+)
+
+// Policy: 重试策略
+type Policy struct {
+	MaxAttempts int                                              // MaxAttempts: 最多尝试次数（含第一次），<=1 表示不重试
+	BaseDelay   time.Duration                                    // BaseDelay: 第 n 次重试前等待 BaseDelay * 2^(n-1)
+	MaxDelay    time.Duration                                    // MaxDelay: 单次等待时间上限，<=0 表示不限制
+	Jitter      float64                                          // Jitter: 抖动比例（0~1）
+	Retryable   func(err error) bool                             // Retryable: 判断错误是否可重试，为空时使用 IsRetryable
+	Sleep       func(ctx context.Context, d time.Duration) error // Sleep: 可取消的等待，为空时使用 time.Timer（便于用假实现验证）
+}
+
+// DefaultPolicy: 默认重试策略
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+		Jitter:      DefaultJitter,
+	}
+}
+
+// Result: 重试过程的记录
+type Result struct {
+	Attempts int     // Attempts: 实际尝试次数
+	Errors   []error // Errors: 每次失败的错误（按尝试顺序）
+}
+
+// IsRetryable: 默认的错误分类
+// 上下文取消和超时不重试；429 / 限流、5xx、网络超时、连接被重置等瞬时错误重试
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if IsRateLimited(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{
+		"500", "502", "503", "504", "bad gateway", "service unavailable", "overloaded",
+		"connection reset", "connection refused", "unexpected eof", "timeout",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRateLimited: 错误是否来自服务商限流（429 / rate limit / too many requests）
+func IsRateLimited(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"429", "rate limit", "too many requests"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff: 第 attempt 次失败后（从 1 开始）重试前的等待时间，不含抖动
+func (p Policy) Backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// Do: 按策略执行 fn，fn 收到的 attempt 从 1 开始
+// 成功时返回结果；不可重试的错误、次数用尽或 ctx 取消时返回最后一次的错误
+func Do[T any](ctx context.Context, p Policy, fn func(ctx context.Context, attempt int) (T, error)) (T, Result, error) {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	sleep := p.Sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var res Result
+	var zero T
+	for attempt := 1; ; attempt++ {
+		res.Attempts = attempt
+		out, err := fn(ctx, attempt)
+		if err == nil {
+			return out, res, nil
+		}
+		res.Errors = append(res.Errors, err)
+		if !retryable(err) || ctx.Err() != nil {
+			return zero, res, err
+		}
+		if attempt >= maxAttempts {
+			return zero, res, fmt.Errorf("尝试 %d 次后仍失败: %w", attempt, err)
+		}
+		if err := sleep(ctx, p.jittered(p.Backoff(attempt))); err != nil {
+			return zero, res, fmt.Errorf("等待重试时取消（已尝试 %d 次）: %w", attempt, err)
+		}
+	}
+}
+
+// jittered: 在 d 上加 ±Jitter 比例的随机抖动，避免多个分支同时重试
+func (p Policy) jittered(d time.Duration) time.Duration {
+	if p.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	delta := (rand.Float64()*2 - 1) * p.Jitter * float64(d)
+	return d + time.Duration(delta)
+}
+
+// sleepContext: 可被 ctx 取消的等待
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
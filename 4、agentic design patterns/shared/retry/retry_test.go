@@ -0,0 +1,197 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timeoutError: 模拟网络超时的 net.Error
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// fakeSleep: 记录每次等待时长而不真正等待
+type fakeSleep struct {
+	delays []time.Duration
+	err    error
+}
+
+func (s *fakeSleep) sleep(ctx context.Context, d time.Duration) error {
+	s.delays = append(s.delays, d)
+	return s.err
+}
+
+// failTimes: 前 n 次返回 err，之后返回 "ok"
+func failTimes(n int, err error) func(ctx context.Context, attempt int) (string, error) {
+	return func(ctx context.Context, attempt int) (string, error) {
+		if attempt <= n {
+			return "", err
+		}
+		return "ok", nil
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"取消", context.Canceled, false},
+		{"包装的截止时间", fmt.Errorf("调用失败: %w", context.DeadlineExceeded), false},
+		{"网络超时", timeoutError{}, true},
+		{"429", errors.New("status code: 429"), true},
+		{"限流", errors.New("Rate limit exceeded"), true},
+		{"503", errors.New("503 Service Unavailable"), true},
+		{"过载", errors.New("model overloaded"), true},
+		{"连接被重置", errors.New("read: connection reset by peer"), true},
+		{"鉴权失败", errors.New("401 unauthorized"), false},
+		{"参数错误", errors.New("invalid request"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v，want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	for _, err := range []error{errors.New("HTTP 429"), errors.New("Too Many Requests")} {
+		if !IsRateLimited(err) {
+			t.Errorf("IsRateLimited(%v) = false", err)
+		}
+	}
+	for _, err := range []error{nil, errors.New("503")} {
+		if IsRateLimited(err) {
+			t.Errorf("IsRateLimited(%v) = true", err)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("Backoff(%d) = %s，want %s", i+1, got, w*time.Millisecond)
+		}
+	}
+	if got := (Policy{BaseDelay: time.Second}).Backoff(5); got != 16*time.Second {
+		t.Errorf("不设上限时 Backoff(5) = %s，want 16s", got)
+	}
+}
+
+func TestDo(t *testing.T) {
+	transient := errors.New("503 service unavailable")
+	permanent := errors.New("invalid request")
+	tests := []struct {
+		name         string
+		fn           func(ctx context.Context, attempt int) (string, error)
+		wantOut      string
+		wantAttempts int
+		wantDelays   []time.Duration
+		wantErr      string
+	}{
+		{"第一次成功", failTimes(0, transient), "ok", 1, nil, ""},
+		{"重试后成功", failTimes(2, transient), "ok", 3, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, ""},
+		{"次数用尽", failTimes(5, transient), "", 3, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, "尝试 3 次后仍失败: 503"},
+		{"不可重试的错误", failTimes(5, permanent), "", 1, nil, "invalid request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sleep := &fakeSleep{}
+			p := Policy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, Sleep: sleep.sleep}
+			out, res, err := Do(context.Background(), p, tt.fn)
+			if out != tt.wantOut {
+				t.Errorf("结果 = %q，want %q", out, tt.wantOut)
+			}
+			if res.Attempts != tt.wantAttempts || len(res.Errors) != tt.wantAttempts-boolToInt(tt.wantErr == "") {
+				t.Errorf("记录 = %+v，want %d 次尝试", res, tt.wantAttempts)
+			}
+			if fmt.Sprint(sleep.delays) != fmt.Sprint(tt.wantDelays) {
+				t.Errorf("等待 = %v，want %v", sleep.delays, tt.wantDelays)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Do: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestDoCustomRetryable(t *testing.T) {
+	sleep := &fakeSleep{}
+	p := Policy{MaxAttempts: 3, Sleep: sleep.sleep, Retryable: func(err error) bool { return true }}
+	out, res, err := Do(context.Background(), p, failTimes(1, errors.New("invalid request")))
+	if err != nil || out != "ok" || res.Attempts != 2 {
+		t.Errorf("自定义 Retryable: out=%q res=%+v err=%v", out, res, err)
+	}
+}
+
+func TestDoSleepCancelled(t *testing.T) {
+	sleep := &fakeSleep{err: context.Canceled}
+	p := Policy{MaxAttempts: 3, BaseDelay: time.Second, Sleep: sleep.sleep}
+	_, res, err := Do(context.Background(), p, failTimes(5, errors.New("429")))
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "已尝试 1 次") {
+		t.Errorf("错误 = %v，want 等待时取消", err)
+	}
+	if res.Attempts != 1 {
+		t.Errorf("尝试次数 = %d，want 1", res.Attempts)
+	}
+}
+
+func TestDoStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sleep := &fakeSleep{}
+	p := Policy{MaxAttempts: 5, Sleep: sleep.sleep}
+	_, res, err := Do(ctx, p, func(ctx context.Context, attempt int) (string, error) {
+		cancel()
+		return "", errors.New("503")
+	})
+	if err == nil || res.Attempts != 1 || len(sleep.delays) != 0 {
+		t.Errorf("ctx 取消后仍重试: res=%+v err=%v delays=%v", res, err, sleep.delays)
+	}
+}
+
+func TestJittered(t *testing.T) {
+	p := Policy{Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		d := p.jittered(time.Second)
+		if d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("jittered(1s) = %s，超出 ±20%%", d)
+		}
+	}
+	if d := (Policy{}).jittered(time.Second); d != time.Second {
+		t.Errorf("无抖动时 = %s", d)
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	if err := sleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("sleepContext: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleepContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("取消后 sleepContext = %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package stats: 可复用的性能测量组件
+//
+// Measurement 记录单个分支的耗时和 token 用量，Run 记录一种执行方式（串行或并行）的整体结果，
+// PrintComparison 打印两种执行方式的对比表和加速比；CountingModel 配合 WithUsage 统计 token 用量。
+//
+// 本包属于各章共用的 shared 模块，第 3、7 章在 go.mod 中用 replace 指向 ../shared 引用同一份代码。
+package stats
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Measurement: 单个分支的测量结果
+type Measurement struct {
+	Name    string        // Name: 分支名称
+	Elapsed time.Duration // Elapsed: 分支耗时
+	Usage   Usage         // Usage: 分支的 token 用量
+}
+
+// Run: 一种执行方式的测量结果
+type Run struct {
+	Mode     string        // Mode: 执行方式（如 串行 / 并行）
+	Branches []Measurement // Branches: 各分支的测量结果
+	Total    time.Duration // Total: 整体墙钟耗时
+}
+
+// SumBranches: 各分支耗时之和（串行执行的理论耗时）
+func (r Run) SumBranches() time.Duration {
+	var sum time.Duration
+	for _, m := range r.Branches {
+		sum += m.Elapsed
+	}
+	return sum
+}
+
+// MaxBranch: 最慢分支的耗时（并行执行的理论耗时）
+func (r Run) MaxBranch() time.Duration {
+	var longest time.Duration
+	for _, m := range r.Branches {
+		if m.Elapsed > longest {
+			longest = m.Elapsed
+		}
+	}
+	return longest
+}
+
+// Usage: 各分支 token 用量之和
+func (r Run) Usage() Usage {
+	var total Usage
+	for _, m := range r.Branches {
+		total = total.Add(m.Usage)
+	}
+	return total
+}
+
+// Branch: 按名称查找分支的测量结果
+func (r Run) Branch(name string) (Measurement, bool) {
+	for _, m := range r.Branches {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Measurement{}, false
+}
+
+// Speedup: 加速比 baseline.Total / candidate.Total，candidate 耗时为 0 时返回 0
+func Speedup(baseline, candidate Run) float64 {
+	if candidate.Total <= 0 {
+		return 0
+	}
+	return float64(baseline.Total) / float64(candidate.Total)
+}
+
+// PrintComparison: 打印 baseline 与 candidate 的对比表（分支按 baseline 的顺序）和加速比
+func PrintComparison(w io.Writer, baseline, candidate Run) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "分支\t%s耗时\t%s耗时\t%s tokens\t%s tokens\n", baseline.Mode, candidate.Mode, baseline.Mode, candidate.Mode)
+	for _, b := range baseline.Branches {
+		c, _ := candidate.Branch(b.Name)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", b.Name, round(b.Elapsed), round(c.Elapsed), b.Usage.Total(), c.Usage.Total())
+	}
+	fmt.Fprintf(tw, "总计\t%s\t%s\t%d\t%d\n", round(baseline.Total), round(candidate.Total), baseline.Usage().Total(), candidate.Usage().Total())
+	tw.Flush()
+
+	fmt.Fprintf(w, "加速比: %.2fx（%s各分支耗时之和 %s，%s最慢分支 %s）\n",
+		Speedup(baseline, candidate), baseline.Mode, round(baseline.SumBranches()), candidate.Mode, round(candidate.MaxBranch()))
+}
+
+// round: 统一的耗时显示精度
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
@@ -0,0 +1,175 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeModel: Generate 返回一条带用量的消息，Stream 依次返回 chunks
+type fakeModel struct {
+	usage  *schema.TokenUsage
+	chunks []*schema.Message
+	err    error
+}
+
+func (m *fakeModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	msg := schema.AssistantMessage("回复", nil)
+	if m.usage != nil {
+		msg.ResponseMeta = &schema.ResponseMeta{Usage: m.usage}
+	}
+	return msg, nil
+}
+
+func (m *fakeModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return schema.StreamReaderFromArray(m.chunks), nil
+}
+
+// withUsage: 带用量的流式分块
+func withUsage(content string, prompt, completion int) *schema.Message {
+	return &schema.Message{Role: schema.Assistant, Content: content, ResponseMeta: &schema.ResponseMeta{
+		Usage: &schema.TokenUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion},
+	}}
+}
+
+// drain: 读完流
+func drain(t *testing.T, sr *schema.StreamReader[*schema.Message]) {
+	t.Helper()
+	defer sr.Close()
+	for {
+		if _, err := sr.Recv(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+	}
+}
+
+func TestCountingModelGenerate(t *testing.T) {
+	ctx, counter := WithUsage(context.Background())
+	m := NewCountingModel(&fakeModel{usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 4}})
+	for i := 0; i < 2; i++ {
+		if _, err := m.Generate(ctx, nil); err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+	}
+	if got := counter.Usage(); got != (Usage{PromptTokens: 20, CompletionTokens: 8}) {
+		t.Errorf("累计用量 = %+v", got)
+	}
+
+	// 上下文中没有累加器、模型没有返回用量或调用失败时都不记录
+	if _, err := m.Generate(context.Background(), nil); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := NewCountingModel(&fakeModel{}).Generate(ctx, nil); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := NewCountingModel(&fakeModel{err: errors.New("故障")}).Generate(ctx, nil); err == nil {
+		t.Fatalf("Generate 应返回内部模型的错误")
+	}
+	if got := counter.Usage().Total(); got != 28 {
+		t.Errorf("总用量 = %d，want 28", got)
+	}
+}
+
+func TestCountingModelStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []*schema.Message
+		want   Usage
+	}{
+		{"用量只在最后一块", []*schema.Message{schema.AssistantMessage("a", nil), withUsage("b", 10, 5)}, Usage{10, 5}},
+		{"每块返回累计用量", []*schema.Message{withUsage("a", 10, 1), withUsage("b", 10, 3), withUsage("c", 10, 5)}, Usage{10, 5}},
+		{"没有用量", []*schema.Message{schema.AssistantMessage("a", nil)}, Usage{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, counter := WithUsage(context.Background())
+			sr, err := NewCountingModel(&fakeModel{chunks: tt.chunks}).Stream(ctx, nil)
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+			drain(t, sr)
+			if got := counter.Usage(); got != tt.want {
+				t.Errorf("用量 = %+v，want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testRuns: 串行和并行各三个分支的测量结果
+func testRuns() (Run, Run) {
+	serial := Run{Mode: "串行", Total: 600 * time.Millisecond, Branches: []Measurement{
+		{Name: "summary", Elapsed: 300 * time.Millisecond, Usage: Usage{10, 20}},
+		{Name: "questions", Elapsed: 200 * time.Millisecond, Usage: Usage{10, 5}},
+		{Name: "key_terms", Elapsed: 100 * time.Millisecond, Usage: Usage{5, 5}},
+	}}
+	parallel := Run{Mode: "并行", Total: 200 * time.Millisecond, Branches: []Measurement{
+		{Name: "questions", Elapsed: 150 * time.Millisecond, Usage: Usage{10, 5}},
+		{Name: "summary", Elapsed: 190 * time.Millisecond, Usage: Usage{10, 20}},
+	}}
+	return serial, parallel
+}
+
+func TestRun(t *testing.T) {
+	serial, parallel := testRuns()
+	if got := serial.SumBranches(); got != 600*time.Millisecond {
+		t.Errorf("SumBranches = %s", got)
+	}
+	if got := parallel.MaxBranch(); got != 190*time.Millisecond {
+		t.Errorf("MaxBranch = %s", got)
+	}
+	if got := serial.Usage(); got != (Usage{25, 30}) {
+		t.Errorf("Usage = %+v", got)
+	}
+	if m, ok := parallel.Branch("summary"); !ok || m.Elapsed != 190*time.Millisecond {
+		t.Errorf("Branch(summary) = %+v, %v", m, ok)
+	}
+	if _, ok := parallel.Branch("key_terms"); ok {
+		t.Errorf("不存在的分支不应找到")
+	}
+	if got := Speedup(serial, parallel); got != 3 {
+		t.Errorf("Speedup = %v，want 3", got)
+	}
+	if got := Speedup(serial, Run{}); got != 0 {
+		t.Errorf("耗时为 0 时 Speedup = %v，want 0", got)
+	}
+}
+
+func TestPrintComparison(t *testing.T) {
+	serial, parallel := testRuns()
+	var sb strings.Builder
+	PrintComparison(&sb, serial, parallel)
+	out := sb.String()
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("输出应为表头、3 个分支、总计和加速比共 6 行:\n%s", out)
+	}
+	for i, fields := range [][]string{
+		{"分支", "串行耗时", "并行耗时"},
+		{"summary", "300ms", "190ms", "30", "30"},
+		{"questions", "200ms", "150ms", "15", "15"},
+		{"key_terms", "100ms", "0s", "10", "0"}, // 并行结果中缺失的分支按零值显示
+		{"总计", "600ms", "200ms", "55", "45"},
+		{"加速比: 3.00x", "串行各分支耗时之和 600ms", "并行最慢分支 190ms"},
+	} {
+		for _, field := range fields {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("第 %d 行 %q 缺少 %q", i+1, lines[i], field)
+			}
+		}
+	}
+}
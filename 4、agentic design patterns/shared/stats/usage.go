@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Usage: token 用量
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Total: 总 token 数
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add: 两个用量之和
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+	}
+}
+
+// UsageCounter: 并发安全的 token 用量累加器
+type UsageCounter struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+// Add: 累加一次用量
+func (c *UsageCounter) Add(u Usage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage = c.usage.Add(u)
+}
+
+// Usage: 当前累计用量
+func (c *UsageCounter) Usage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// usageKey: 上下文中 UsageCounter 的键
+type usageKey struct{}
+
+// WithUsage: 在上下文上挂一个新的累加器，之后用该上下文调用 CountingModel 的用量都记到它上面
+func WithUsage(ctx context.Context) (context.Context, *UsageCounter) {
+	counter := &UsageCounter{}
+	return context.WithValue(ctx, usageKey{}, counter), counter
+}
+
+// AddUsage: 将用量记到上下文中的累加器，上下文中没有累加器时什么都不做
+func AddUsage(ctx context.Context, u Usage) {
+	if counter, ok := ctx.Value(usageKey{}).(*UsageCounter); ok {
+		counter.Add(u)
+	}
+}
+
+// fromMessage: 从模型响应中读取用量（模型未返回用量时为零值）
+func fromMessage(msg *schema.Message) (Usage, bool) {
+	if msg == nil || msg.ResponseMeta == nil || msg.ResponseMeta.Usage == nil {
+		return Usage{}, false
+	}
+	return Usage{
+		PromptTokens:     msg.ResponseMeta.Usage.PromptTokens,
+		CompletionTokens: msg.ResponseMeta.Usage.CompletionTokens,
+	}, true
+}
+
+// CountingModel: 包装 ChatModel，把每次调用返回的 token 用量记到上下文中的累加器（见 WithUsage）
+type CountingModel struct {
+	inner model.BaseChatModel
+}
+
+// NewCountingModel: 创建统计 token 用量的模型包装
+func NewCountingModel(inner model.BaseChatModel) *CountingModel {
+	return &CountingModel{inner: inner}
+}
+
+// Generate: 调用内部模型并记录用量
+func (m *CountingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	msg, err := m.inner.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := fromMessage(msg); ok {
+		AddUsage(ctx, u)
+	}
+	return msg, nil
+}
+
+// Stream: 调用内部模型的流式接口，用量通常在最后一个分块中，记录最后一次出现的用量
+func (m *CountingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	sr, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var last *Usage
+	return schema.StreamReaderWithConvert(sr, func(msg *schema.Message) (*schema.Message, error) {
+		if u, ok := fromMessage(msg); ok {
+			if last != nil {
+				// 部分实现在每个分块中返回累计用量，只记录增量
+				AddUsage(ctx, Usage{
+					PromptTokens:     u.PromptTokens - last.PromptTokens,
+					CompletionTokens: u.CompletionTokens - last.CompletionTokens,
+				})
+			} else {
+				AddUsage(ctx, u)
+			}
+			last = &u
+		}
+		return msg, nil
+	}), nil
+}
@@ -0,0 +1,279 @@
+// Package toolkit: 各章 Agent 共用的工具
+//
+// ExpressionCalculatorTool 计算完整的算术表达式；ReadFileTool、WriteFileTool、ListDirTool 在 Workspace 沙箱目录内读写文件。
+// 第 5 章的工具调用 Agent 和第 6 章的 worker 在 go.mod 中用 replace 指向 ../shared 引用同一份代码。
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// tokenKind: 表达式记号的类型
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokOperator
+	tokLParen
+	tokRParen
+)
+
+// opNegate: 一元负号在运算符栈中的表示（与二元减号区分）
+const opNegate = 'n'
+
+// exprToken: 表达式中的一个记号
+type exprToken struct {
+	kind tokenKind
+	num  float64
+	op   rune
+	pos  int // pos: 记号在表达式中的位置（从 1 开始，按字符计），用于错误信息
+}
+
+// operatorInfo: 运算符的优先级和结合性
+type operatorInfo struct {
+	prec       int
+	rightAssoc bool
+}
+
+// operators: 支持的运算符；一元负号低于乘方，因此 -2^2 = -4，2^-1 = 0.5
+var operators = map[rune]operatorInfo{
+	'+':      {prec: 1},
+	'-':      {prec: 1},
+	'*':      {prec: 2},
+	'/':      {prec: 2},
+	'%':      {prec: 2},
+	opNegate: {prec: 3, rightAssoc: true},
+	'^':      {prec: 4, rightAssoc: true},
+}
+
+// aliases: 全角符号和常见数学符号的等价写法
+var aliases = map[rune]rune{'×': '*', '÷': '/', '（': '(', '）': ')', '－': '-', '＋': '+'}
+
+// tokenize: 把表达式拆成数字、运算符和括号
+func tokenize(expr string) ([]exprToken, error) {
+	runes := []rune(expr)
+	var tokens []exprToken
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if alias, ok := aliases[r]; ok {
+			r = alias
+		}
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("位置 %d 的数字 %q 无效", start+1, text)
+			}
+			tokens = append(tokens, exprToken{kind: tokNumber, num: num, pos: start + 1})
+		case r == '(':
+			tokens = append(tokens, exprToken{kind: tokLParen, pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, exprToken{kind: tokRParen, pos: i + 1})
+			i++
+		case strings.ContainsRune("+-*/%^", r):
+			tokens = append(tokens, exprToken{kind: tokOperator, op: r, pos: i + 1})
+			i++
+		default:
+			return nil, fmt.Errorf("位置 %d 有无法识别的字符 %q", i+1, string(runes[i]))
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("表达式为空")
+	}
+	return tokens, nil
+}
+
+// toRPN: 调度场算法，把中缀记号转换为逆波兰序，同时检查操作数和运算符是否交替出现、括号是否配对
+func toRPN(tokens []exprToken) ([]exprToken, error) {
+	var output, stack []exprToken
+	expectOperand := true // expectOperand: 下一个记号应当是操作数（数字、左括号或一元正负号）
+	for _, tok := range tokens {
+		switch tok.kind {
+		case tokNumber:
+			if !expectOperand {
+				return nil, fmt.Errorf("位置 %d 前缺少运算符", tok.pos)
+			}
+			output = append(output, tok)
+			expectOperand = false
+		case tokLParen:
+			if !expectOperand {
+				return nil, fmt.Errorf("位置 %d 的 ( 前缺少运算符", tok.pos)
+			}
+			stack = append(stack, tok)
+		case tokRParen:
+			if expectOperand {
+				return nil, fmt.Errorf("位置 %d 的 ) 前缺少操作数", tok.pos)
+			}
+			for len(stack) > 0 && stack[len(stack)-1].kind != tokLParen {
+				output = append(output, stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("括号不匹配：位置 %d 的 ) 没有对应的 (", tok.pos)
+			}
+			stack = stack[:len(stack)-1]
+		case tokOperator:
+			if expectOperand {
+				// 一元正负号：前缀运算符直接入栈，不弹出任何运算符
+				switch tok.op {
+				case '+':
+					continue
+				case '-':
+					tok.op = opNegate
+					stack = append(stack, tok)
+					continue
+				default:
+					return nil, fmt.Errorf("位置 %d 的运算符 %c 缺少左操作数", tok.pos, tok.op)
+				}
+			}
+			current := operators[tok.op]
+			for len(stack) > 0 && stack[len(stack)-1].kind == tokOperator {
+				top := operators[stack[len(stack)-1].op]
+				if top.prec < current.prec || (top.prec == current.prec && current.rightAssoc) {
+					break
+				}
+				output = append(output, stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, tok)
+			expectOperand = true
+		}
+	}
+	if expectOperand {
+		return nil, errors.New("表达式不完整：末尾缺少操作数")
+	}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.kind == tokLParen {
+			return nil, fmt.Errorf("括号不匹配：位置 %d 的 ( 缺少对应的 )", top.pos)
+		}
+		output = append(output, top)
+		stack = stack[:len(stack)-1]
+	}
+	return output, nil
+}
+
+// evalRPN: 计算逆波兰序表达式
+func evalRPN(rpn []exprToken) (float64, error) {
+	var stack []float64
+	for _, tok := range rpn {
+		if tok.kind == tokNumber {
+			stack = append(stack, tok.num)
+			continue
+		}
+		if tok.op == opNegate {
+			stack[len(stack)-1] = -stack[len(stack)-1]
+			continue
+		}
+		a, b := stack[len(stack)-2], stack[len(stack)-1]
+		stack = stack[:len(stack)-2]
+		var result float64
+		switch tok.op {
+		case '+':
+			result = a + b
+		case '-':
+			result = a - b
+		case '*':
+			result = a * b
+		case '/':
+			if b == 0 {
+				return 0, fmt.Errorf("除以零错误（位置 %d 的 /）", tok.pos)
+			}
+			result = a / b
+		case '%':
+			if b == 0 {
+				return 0, fmt.Errorf("对零取模错误（位置 %d 的 %%）", tok.pos)
+			}
+			result = math.Mod(a, b)
+		case '^':
+			result = math.Pow(a, b)
+		}
+		if math.IsNaN(result) {
+			return 0, fmt.Errorf("位置 %d 的运算结果无意义（如负数的小数次幂）", tok.pos)
+		}
+		if math.IsInf(result, 0) {
+			return 0, fmt.Errorf("位置 %d 的运算结果超出范围", tok.pos)
+		}
+		stack = append(stack, result)
+	}
+	return stack[0], nil
+}
+
+// EvaluateExpression: 计算算术表达式，支持 + - * / % ^、括号和一元正负号
+func EvaluateExpression(expr string) (float64, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return 0, err
+	}
+	rpn, err := toRPN(tokens)
+	if err != nil {
+		return 0, err
+	}
+	return evalRPN(rpn)
+}
+
+// formatNumber: 去掉浮点误差（保留 10 位小数）后以最短形式输出，如 0.1+0.2 输出 0.3，14 输出 14
+func formatNumber(v float64) string {
+	if math.Abs(v) < 1e15 {
+		v = math.Round(v*1e10) / 1e10
+	}
+	if v == 0 {
+		v = 0 // 去掉 -0
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ExpressionCalculatorTool: 计算完整算术表达式的工具，一次调用即可完成多步运算
+type ExpressionCalculatorTool struct{}
+
+func (c *ExpressionCalculatorTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "evaluate_expression",
+		Desc: "计算算术表达式，支持 + - * / %（取模）^（乘方）、括号和负号，如 (5+3)*2-4/2",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"expression": {
+				Type:     schema.String,
+				Desc:     "要计算的表达式",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+func (c *ExpressionCalculatorTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return "", fmt.Errorf("无效的参数: %w", err)
+	}
+
+	fmt.Printf("\n--- 🛠️ 工具调用：evaluate_expression，表达式：'%s' ---\n", args.Expression)
+
+	result, err := EvaluateExpression(args.Expression)
+	if err != nil {
+		return "", fmt.Errorf("无法计算 %q: %w", args.Expression, err)
+	}
+
+	resultStr := formatNumber(result)
+	fmt.Printf("--- 工具结果：%s ---\n", resultStr)
+	return resultStr, nil
+}
@@ -0,0 +1,121 @@
+package toolkit
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"整数", "42", "42"},
+		{"小数", "3.25", "3.25"},
+		{"以小数点开头", ".5+.5", "1"},
+		{"加法", "5+6", "11"},
+		{"减法得负数", "5-6", "-1"},
+		{"乘法", "5*6", "30"},
+		{"除法", "7/2", "3.5"},
+		{"取模", "10%3", "1"},
+		{"小数取模", "5.5%2", "1.5"},
+		{"乘方", "2^10", "1024"},
+		{"乘法优先于加法", "1+2*3", "7"},
+		{"括号", "(1+2)*3", "9"},
+		{"嵌套括号", "((2+3)*(4-1))^2", "225"},
+		{"减法左结合", "10-4-3", "3"},
+		{"除法左结合", "100/10/5", "2"},
+		{"乘方右结合", "2^3^2", "512"},
+		{"取模与乘除同级", "7%4*3", "9"},
+		{"多步计算", "(5+3)*2-4/2", "14"},
+		{"一元负号", "-5", "-5"},
+		{"一元正号", "+5", "5"},
+		{"连续负号", "--3", "3"},
+		{"减负数", "1 - -1", "2"},
+		{"乘负数", "2*-3", "-6"},
+		{"负号作用于括号", "-(2+3)", "-5"},
+		{"负号低于乘方", "-2^2", "-4"},
+		{"负指数", "2^-1", "0.5"},
+		{"括号内的负数乘方", "(-2)^2", "4"},
+		{"负数取模保留符号", "-5%3", "-2"},
+		{"空白被忽略", " 1 +\t2 ", "3"},
+		{"全角符号", "（1＋2）×3÷3－1", "2"},
+		{"浮点误差被去掉", "0.1+0.2", "0.3"},
+		{"循环小数保留 10 位", "1/3", "0.3333333333"},
+		{"平方根", "2^0.5", "1.4142135624"},
+		{"负零输出为 0", "-0", "0"},
+		{"大数不做舍入", "10^20", "100000000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvaluateExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("EvaluateExpression(%q): %v", tt.expr, err)
+			}
+			if s := formatNumber(got); s != tt.want {
+				t.Errorf("EvaluateExpression(%q) = %s，want %s", tt.expr, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr string
+	}{
+		{"空表达式", "", "表达式为空"},
+		{"只有空白", "  ", "表达式为空"},
+		{"多个小数点", "1.2.3", `位置 1 的数字 "1.2.3" 无效`},
+		{"单独的小数点", ".", `位置 1 的数字 "." 无效`},
+		{"无法识别的字符", "2x", `位置 2 有无法识别的字符 "x"`},
+		{"科学计数法不支持", "1e3", `位置 2 有无法识别的字符 "e"`},
+		{"中文字符的位置按字符计", "（1+a）", `位置 4 有无法识别的字符 "a"`},
+		{"两个数之间缺少运算符", "2 3", "位置 3 前缺少运算符"},
+		{"括号前缺少运算符", "2(3)", "位置 2 的 ( 前缺少运算符"},
+		{"空括号", "()", "位置 2 的 ) 前缺少操作数"},
+		{"多余的右括号", "1)", "括号不匹配：位置 2 的 ) 没有对应的 ("},
+		{"缺少右括号", "(1", "括号不匹配：位置 1 的 ( 缺少对应的 )"},
+		{"内层缺少右括号", "((1+2)", "括号不匹配：位置 1 的 ( 缺少对应的 )"},
+		{"开头的乘号", "*2", "位置 1 的运算符 * 缺少左操作数"},
+		{"连续的二元运算符", "2*/3", "位置 3 的运算符 / 缺少左操作数"},
+		{"开头的乘方", "^2", "位置 1 的运算符 ^ 缺少左操作数"},
+		{"末尾缺少操作数", "1+", "表达式不完整：末尾缺少操作数"},
+		{"只有负号", "-", "表达式不完整：末尾缺少操作数"},
+		{"除以零", "1/0", "除以零错误（位置 2 的 /）"},
+		{"除以结果为零的括号", "1/(2-2)", "除以零错误（位置 2 的 /）"},
+		{"对零取模", "5%0", "对零取模错误（位置 2 的 %）"},
+		{"负数的小数次幂", "(-8)^0.5", "位置 5 的运算结果无意义"},
+		{"结果溢出", "10^400", "位置 3 的运算结果超出范围"},
+		{"乘法溢出", "10^200*10^200", "位置 7 的运算结果超出范围"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvaluateExpression(tt.expr)
+			if err == nil {
+				t.Fatalf("EvaluateExpression(%q) = %v，want 错误", tt.expr, got)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %q，want 包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpressionCalculatorTool(t *testing.T) {
+	c := &ExpressionCalculatorTool{}
+	got, err := c.InvokableRun(context.Background(), `{"expression": "(5+3)*2-4/2"}`)
+	if err != nil || got != "14" {
+		t.Errorf("InvokableRun = %q, %v", got, err)
+	}
+	_, err = c.InvokableRun(context.Background(), `{"expression": "5/0"}`)
+	if err == nil || !strings.Contains(err.Error(), `无法计算 "5/0": 除以零错误`) {
+		t.Errorf("除以零错误 = %v", err)
+	}
+	if _, err := c.InvokableRun(context.Background(), `{"expression": 5}`); err == nil || !strings.Contains(err.Error(), "无效的参数") {
+		t.Errorf("参数无效时错误 = %v", err)
+	}
+}
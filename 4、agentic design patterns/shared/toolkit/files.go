@@ -0,0 +1,268 @@
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// maxFileSize: read_file / write_file 允许的最大文件大小（字节）
+const maxFileSize = 1 << 20
+
+// Workspace: 文件工具的沙箱目录，所有路径都相对于 Root 解析，不能逃逸到 Root 之外
+type Workspace struct {
+	Root string // Root: 绝对路径，已解析符号链接
+}
+
+// NewWorkspace: 以 root 为沙箱目录（不存在时创建）
+func NewWorkspace(root string) (*Workspace, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("解析工作区路径失败: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("创建工作区失败: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("解析工作区路径失败: %w", err)
+	}
+	return &Workspace{Root: resolved}, nil
+}
+
+// Resolve: 把模型给出的相对路径解析为工作区内的绝对路径
+// 拒绝绝对路径和任何 ".." 片段；已存在的部分解析符号链接后必须仍在工作区内（防止通过链接逃逸）
+func (w *Workspace) Resolve(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", errors.New("路径不能为空")
+	}
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\`) || filepath.VolumeName(path) != "" {
+		return "", fmt.Errorf("不允许绝对路径: %s（请使用相对于工作区的路径）", path)
+	}
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return "", fmt.Errorf("不允许使用 \"..\": %s", path)
+		}
+	}
+	target := filepath.Join(w.Root, path)
+
+	// 找到最长的已存在前缀并解析其中的符号链接，尚不存在的部分（如待创建的文件）原样拼接
+	existing, rest := target, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		// 不把工作区外的真实路径透露给模型
+		return "", fmt.Errorf("无法解析路径 %s（可能是失效的符号链接）", path)
+	}
+	resolved = filepath.Join(resolved, rest)
+	if !w.contains(resolved) {
+		return "", fmt.Errorf("路径超出工作区: %s", path)
+	}
+	return resolved, nil
+}
+
+// contains: path 是否是 Root 本身或在 Root 之下
+func (w *Workspace) contains(path string) bool {
+	rel, err := filepath.Rel(w.Root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ReadFileTool: 读取工作区内的文本文件
+type ReadFileTool struct {
+	Workspace *Workspace
+}
+
+func (t *ReadFileTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "read_file",
+		Desc: fmt.Sprintf("读取工作区内的文本文件，返回文件内容（最大 %d 字节）", maxFileSize),
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"path": {
+				Type:     schema.String,
+				Desc:     "相对于工作区的文件路径，如 notes/result.txt",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+func (t *ReadFileTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return "", fmt.Errorf("无效的参数: %w", err)
+	}
+
+	fmt.Printf("\n--- 🛠️ 工具调用：read_file，路径：'%s' ---\n", args.Path)
+
+	path, err := t.Workspace.Resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("文件 %s 不存在", args.Path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s 是目录，请使用 list_dir", args.Path)
+	}
+	if info.Size() > maxFileSize {
+		return "", fmt.Errorf("文件 %s 过大（%d 字节，上限 %d 字节）", args.Path, info.Size(), maxFileSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	fmt.Printf("--- 工具结果：读取 %d 字节 ---\n", len(data))
+	return string(data), nil
+}
+
+// WriteFileTool: 在工作区内写入文本文件，覆盖已存在的文件需要显式设置 overwrite
+type WriteFileTool struct {
+	Workspace *Workspace
+}
+
+func (t *WriteFileTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "write_file",
+		Desc: fmt.Sprintf("在工作区内写入文本文件（自动创建父目录，最大 %d 字节）；文件已存在时必须设置 overwrite=true", maxFileSize),
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"path": {
+				Type:     schema.String,
+				Desc:     "相对于工作区的文件路径，如 result.txt",
+				Required: true,
+			},
+			"content": {
+				Type:     schema.String,
+				Desc:     "要写入的完整内容",
+				Required: true,
+			},
+			"overwrite": {
+				Type: schema.Boolean,
+				Desc: "文件已存在时是否覆盖，默认 false",
+			},
+		}),
+	}, nil
+}
+
+func (t *WriteFileTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		Content   string `json:"content"`
+		Overwrite bool   `json:"overwrite"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return "", fmt.Errorf("无效的参数: %w", err)
+	}
+
+	fmt.Printf("\n--- 🛠️ 工具调用：write_file，路径：'%s'，%d 字节，overwrite=%t ---\n", args.Path, len(args.Content), args.Overwrite)
+
+	if len(args.Content) > maxFileSize {
+		return "", fmt.Errorf("内容过大（%d 字节，上限 %d 字节）", len(args.Content), maxFileSize)
+	}
+	path, err := t.Workspace.Resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		return "", fmt.Errorf("%s 是目录，不能写入", args.Path)
+	case err == nil && !args.Overwrite:
+		return "", fmt.Errorf("文件 %s 已存在，如需覆盖请设置 overwrite=true", args.Path)
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return "", fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(args.Content), 0o644); err != nil {
+		return "", fmt.Errorf("写入文件失败: %w", err)
+	}
+
+	result := fmt.Sprintf("已写入 %s（%d 字节）", args.Path, len(args.Content))
+	fmt.Printf("--- 工具结果：%s ---\n", result)
+	return result, nil
+}
+
+// ListDirTool: 列出工作区内某个目录的内容
+type ListDirTool struct {
+	Workspace *Workspace
+}
+
+func (t *ListDirTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "list_dir",
+		Desc: "列出工作区内某个目录下的文件和子目录（子目录以 / 结尾）",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"path": {
+				Type: schema.String,
+				Desc: "相对于工作区的目录路径，默认为工作区根目录 \".\"",
+			},
+		}),
+	}, nil
+}
+
+func (t *ListDirTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return "", fmt.Errorf("无效的参数: %w", err)
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+
+	fmt.Printf("\n--- 🛠️ 工具调用：list_dir，路径：'%s' ---\n", args.Path)
+
+	path, err := t.Workspace.Resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", fmt.Errorf("列出目录失败: %w", err)
+	}
+	if len(entries) == 0 {
+		return "（空目录）", nil
+	}
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			lines = append(lines, entry.Name()+"/")
+			continue
+		}
+		line := entry.Name()
+		if info, err := entry.Info(); err == nil {
+			line += fmt.Sprintf("（%d 字节）", info.Size())
+		}
+		lines = append(lines, line)
+	}
+
+	fmt.Printf("--- 工具结果：%d 项 ---\n", len(lines))
+	return strings.Join(lines, "\n"), nil
+}
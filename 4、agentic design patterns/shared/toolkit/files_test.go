@@ -0,0 +1,178 @@
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestWorkspace: 临时目录下的工作区，旁边放一个工作区外的文件 outside/secret.txt
+func newTestWorkspace(t *testing.T) (*Workspace, string) {
+	t.Helper()
+	dir := t.TempDir()
+	outside := filepath.Join(dir, "outside")
+	if err := os.MkdirAll(outside, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("机密"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWorkspace(filepath.Join(dir, "ws"))
+	if err != nil {
+		t.Fatalf("NewWorkspace: %v", err)
+	}
+	return w, outside
+}
+
+// fileArgs: 把参数编码为工具调用的 JSON
+func fileArgs(t *testing.T, args map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWorkspaceResolveTraversal(t *testing.T) {
+	w, outside := newTestWorkspace(t)
+	if err := os.Symlink(outside, filepath.Join(w.Root, "escape")); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(w.Root, "secret-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(w.Root, "missing"), filepath.Join(w.Root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(w.Root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(w.Root, "sub"), filepath.Join(w.Root, "inside-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string // want: 解析结果相对于 Root 的路径，为空表示应拒绝
+		wantErr string
+	}{
+		{name: "普通文件", path: "notes/a.txt", want: "notes/a.txt"},
+		{name: "当前目录", path: ".", want: "."},
+		{name: "路径中的 . 被清理", path: "./sub/./b.txt", want: "sub/b.txt"},
+		{name: "工作区内的符号链接", path: "inside-link/c.txt", want: "sub/c.txt"},
+		{name: "空路径", path: " ", wantErr: "路径不能为空"},
+		{name: "绝对路径", path: filepath.Join(outside, "secret.txt"), wantErr: "不允许绝对路径"},
+		{name: "反斜杠开头", path: `\etc\passwd`, wantErr: "不允许绝对路径"},
+		{name: "上级目录", path: "../outside/secret.txt", wantErr: `不允许使用 ".."`},
+		{name: "中间的上级目录", path: "sub/../../outside", wantErr: `不允许使用 ".."`},
+		{name: "反斜杠分隔的上级目录", path: `sub\..\..\outside`, wantErr: `不允许使用 ".."`},
+		{name: "单独的 ..", path: "..", wantErr: `不允许使用 ".."`},
+		{name: "指向工作区外的目录链接", path: "escape/secret.txt", wantErr: "路径超出工作区"},
+		{name: "经目录链接新建文件", path: "escape/new.txt", wantErr: "路径超出工作区"},
+		{name: "指向工作区外的文件链接", path: "secret-link", wantErr: "路径超出工作区"},
+		{name: "失效的符号链接", path: "dangling", wantErr: "失效的符号链接"},
+		{name: "名字以 .. 开头的文件不是上级目录", path: "..hidden", want: "..hidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.Resolve(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve(%q) = %q, %v，want 错误 %q", tt.path, got, err, tt.wantErr)
+				}
+				if err != nil && strings.Contains(err.Error(), outside) && !filepath.IsAbs(tt.path) {
+					t.Errorf("错误信息泄露了工作区外的路径: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q): %v", tt.path, err)
+			}
+			if want := filepath.Join(w.Root, tt.want); got != want {
+				t.Errorf("Resolve(%q) = %q，want %q", tt.path, got, want)
+			}
+		})
+	}
+}
+
+func TestFileToolsRoundTrip(t *testing.T) {
+	w, _ := newTestWorkspace(t)
+	ctx := context.Background()
+	write, read, list := &WriteFileTool{Workspace: w}, &ReadFileTool{Workspace: w}, &ListDirTool{Workspace: w}
+
+	if got, err := list.InvokableRun(ctx, `{}`); err != nil || got != "（空目录）" {
+		t.Errorf("空工作区 list_dir = %q, %v", got, err)
+	}
+	if _, err := write.InvokableRun(ctx, fileArgs(t, map[string]any{"path": "notes/a.txt", "content": "你好"})); err != nil {
+		t.Fatalf("write_file: %v", err)
+	}
+	if got, err := read.InvokableRun(ctx, `{"path": "notes/a.txt"}`); err != nil || got != "你好" {
+		t.Errorf("read_file = %q, %v", got, err)
+	}
+	// 已存在时必须显式覆盖
+	_, err := write.InvokableRun(ctx, fileArgs(t, map[string]any{"path": "notes/a.txt", "content": "新内容"}))
+	if err == nil || !strings.Contains(err.Error(), "overwrite=true") {
+		t.Errorf("未设置 overwrite 时错误 = %v", err)
+	}
+	if _, err := write.InvokableRun(ctx, fileArgs(t, map[string]any{"path": "notes/a.txt", "content": "新内容", "overwrite": true})); err != nil {
+		t.Fatalf("覆盖写入: %v", err)
+	}
+	if got, _ := read.InvokableRun(ctx, `{"path": "notes/a.txt"}`); got != "新内容" {
+		t.Errorf("覆盖后内容 = %q", got)
+	}
+	if got, err := list.InvokableRun(ctx, `{"path": "."}`); err != nil || got != "notes/" {
+		t.Errorf("list_dir . = %q, %v", got, err)
+	}
+	if got, err := list.InvokableRun(ctx, `{"path": "notes"}`); err != nil || got != "a.txt（9 字节）" {
+		t.Errorf("list_dir notes = %q, %v", got, err)
+	}
+}
+
+func TestFileToolsErrors(t *testing.T) {
+	w, _ := newTestWorkspace(t)
+	ctx := context.Background()
+	if err := os.MkdirAll(filepath.Join(w.Root, "dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	big := filepath.Join(w.Root, "big.txt")
+	if err := os.WriteFile(big, make([]byte, maxFileSize+1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	write, read, list := &WriteFileTool{Workspace: w}, &ReadFileTool{Workspace: w}, &ListDirTool{Workspace: w}
+	tests := []struct {
+		name    string
+		run     func() (string, error)
+		wantErr string
+	}{
+		{"读取不存在的文件", func() (string, error) { return read.InvokableRun(ctx, `{"path": "none.txt"}`) }, "不存在"},
+		{"读取目录", func() (string, error) { return read.InvokableRun(ctx, `{"path": "dir"}`) }, "是目录"},
+		{"读取过大的文件", func() (string, error) { return read.InvokableRun(ctx, `{"path": "big.txt"}`) }, "过大"},
+		{"读取工作区外", func() (string, error) { return read.InvokableRun(ctx, `{"path": "../outside/secret.txt"}`) }, `不允许使用 ".."`},
+		{"参数无效", func() (string, error) { return read.InvokableRun(ctx, `{"path": 1}`) }, "无效的参数"},
+		{"写入目录", func() (string, error) {
+			return write.InvokableRun(ctx, `{"path": "dir", "content": "x", "overwrite": true}`)
+		}, "是目录"},
+		{"写入过大的内容", func() (string, error) {
+			return write.InvokableRun(ctx, fileArgs(t, map[string]any{"path": "x.txt", "content": strings.Repeat("a", maxFileSize+1)}))
+		}, "内容过大"},
+		{"写入工作区外", func() (string, error) {
+			return write.InvokableRun(ctx, `{"path": "/tmp/x.txt", "content": "x"}`)
+		}, "不允许绝对路径"},
+		{"列出不存在的目录", func() (string, error) { return list.InvokableRun(ctx, `{"path": "none"}`) }, "列出目录失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.run(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(w.Root, "x.txt")); err == nil {
+		t.Errorf("过大的内容不应写入")
+	}
+}